@@ -0,0 +1,208 @@
+// Package hooks lets deployments run their own logic at fixed points of the task pipeline, e.g.
+// to enrich or redact results before they are stored or to push them to proprietary systems.
+//
+// Hooks are compiled in: a hook package calls Register from its init function, the deployment's
+// build imports it for its side effects, and HOOKS names the hooks to run. The package lives
+// outside internal/ so hook packages of other modules can import it.
+//
+//	import _ "example.com/acme/asm-hooks/cmdb"
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// TaskMessage and TaskResult are the task and its result as hooks receive them. They are aliases,
+// so hook packages of other modules can name them without importing the worker's internal packages.
+type (
+	TaskMessage = models.TaskMessage
+	TaskResult  = models.TaskResult
+)
+
+// Reject returns an error that fails the task without retries, e.g. for a target a hook refuses
+// to scan. Other errors fail the task and let it be retried.
+func Reject(field, message string) error {
+	return common.NewValidationError(field, message)
+}
+
+// Hook is custom pipeline logic. It runs at every point whose interface it implements.
+type Hook interface {
+	Name() string
+}
+
+// PreScanHook runs before the scanner starts. An error fails the task without scanning.
+type PreScanHook interface {
+	Hook
+	PreScan(ctx context.Context, taskMsg *TaskMessage) error
+}
+
+// PostScanHook runs once the scanner returned a completed or partial result. An error fails the task.
+type PostScanHook interface {
+	Hook
+	PostScan(ctx context.Context, taskMsg *TaskMessage, result *TaskResult) error
+}
+
+// PreStoreHook runs right before the result is stored and may change it. An error fails the task.
+type PreStoreHook interface {
+	Hook
+	PreStore(ctx context.Context, taskMsg *TaskMessage, result *TaskResult) error
+}
+
+// PostStoreHook runs after the result is stored. The stored result is authoritative, so errors
+// are logged rather than failing the task.
+type PostStoreHook interface {
+	Hook
+	PostStore(ctx context.Context, taskMsg *TaskMessage, result *TaskResult, blobPath string) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Hook)
+)
+
+// Register makes a hook available under its name. It is meant to be called from init functions
+// and panics when the hook is nil or its name is already taken.
+func Register(hook Hook) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if hook == nil {
+		panic("hooks: Register hook is nil")
+	}
+	if _, dup := registry[hook.Name()]; dup {
+		panic("hooks: Register called twice for hook " + hook.Name())
+	}
+	registry[hook.Name()] = hook
+}
+
+// Registered returns the names of the registered hooks, sorted
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return registeredLocked()
+}
+
+// Pipeline runs the enabled hooks in the order they were named. A nil pipeline runs nothing.
+type Pipeline struct {
+	hooks []Hook
+}
+
+// ParseNames splits a ','-separated list of hook names, dropping empty entries
+func ParseNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// New returns a pipeline of the registered hooks with the given names, or nil when no names are given
+func New(names []string) (*Pipeline, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	pipeline := &Pipeline{}
+	for _, name := range names {
+		hook, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("hook %q is not registered; registered hooks: %v", name, registeredLocked())
+		}
+		pipeline.hooks = append(pipeline.hooks, hook)
+	}
+	return pipeline, nil
+}
+
+// registeredLocked lists the registered hook names while the registry lock is held
+func registeredLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns the names of the hooks in the pipeline, in the order they run
+func (p *Pipeline) Names() []string {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, len(p.hooks))
+	for i, hook := range p.hooks {
+		names[i] = hook.Name()
+	}
+	return names
+}
+
+// PreScan runs the pre-scan hooks, stopping at the first error
+func (p *Pipeline) PreScan(ctx context.Context, taskMsg *TaskMessage) error {
+	if p == nil {
+		return nil
+	}
+	for _, hook := range p.hooks {
+		if h, ok := hook.(PreScanHook); ok {
+			if err := h.PreScan(ctx, taskMsg); err != nil {
+				return fmt.Errorf("pre-scan hook %s: %w", hook.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// PostScan runs the post-scan hooks, stopping at the first error
+func (p *Pipeline) PostScan(ctx context.Context, taskMsg *TaskMessage, result *TaskResult) error {
+	if p == nil {
+		return nil
+	}
+	for _, hook := range p.hooks {
+		if h, ok := hook.(PostScanHook); ok {
+			if err := h.PostScan(ctx, taskMsg, result); err != nil {
+				return fmt.Errorf("post-scan hook %s: %w", hook.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// PreStore runs the pre-store hooks, stopping at the first error
+func (p *Pipeline) PreStore(ctx context.Context, taskMsg *TaskMessage, result *TaskResult) error {
+	if p == nil {
+		return nil
+	}
+	for _, hook := range p.hooks {
+		if h, ok := hook.(PreStoreHook); ok {
+			if err := h.PreStore(ctx, taskMsg, result); err != nil {
+				return fmt.Errorf("pre-store hook %s: %w", hook.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// PostStore runs every post-store hook, logging their errors
+func (p *Pipeline) PostStore(ctx context.Context, taskMsg *TaskMessage, result *TaskResult, blobPath string) {
+	if p == nil {
+		return
+	}
+	for _, hook := range p.hooks {
+		if h, ok := hook.(PostStoreHook); ok {
+			if err := h.PostStore(ctx, taskMsg, result, blobPath); err != nil {
+				gologger.Warning().Msgf("Post-store hook %s failed for domain %s: %v", hook.Name(), taskMsg.Domain, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// recordingHook records the points it ran at and fails where told to
+type recordingHook struct {
+	name   string
+	calls  *[]string
+	failAt string
+}
+
+func (h *recordingHook) Name() string { return h.name }
+
+func (h *recordingHook) record(point string) error {
+	*h.calls = append(*h.calls, h.name+":"+point)
+	if point == h.failAt {
+		return errors.New("refused")
+	}
+	return nil
+}
+
+func (h *recordingHook) PreScan(ctx context.Context, taskMsg *models.TaskMessage) error {
+	return h.record("pre_scan")
+}
+
+func (h *recordingHook) PreStore(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) error {
+	result.Error = "redacted by " + h.name
+	return h.record("pre_store")
+}
+
+func (h *recordingHook) PostStore(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, blobPath string) error {
+	return h.record("post_store")
+}
+
+// postScanOnly only implements the post-scan point
+type postScanOnly struct{ calls *[]string }
+
+func (h postScanOnly) Name() string { return "post-scan-only" }
+
+func (h postScanOnly) PostScan(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) error {
+	*h.calls = append(*h.calls, "post-scan-only:post_scan")
+	return nil
+}
+
+func TestPipelineRunsHooksInOrder(t *testing.T) {
+	var calls []string
+	Register(&recordingHook{name: "test-enrich", calls: &calls})
+	Register(&recordingHook{name: "test-redact", calls: &calls, failAt: "post_store"})
+	Register(postScanOnly{calls: &calls})
+
+	pipeline, err := New([]string{"test-redact", "post-scan-only", "test-enrich"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	ctx := context.Background()
+	taskMsg := &models.TaskMessage{Domain: "example.com"}
+	result := &models.TaskResult{}
+	if err := pipeline.PreScan(ctx, taskMsg); err != nil {
+		t.Fatalf("PreScan failed: %v", err)
+	}
+	if err := pipeline.PostScan(ctx, taskMsg, result); err != nil {
+		t.Fatalf("PostScan failed: %v", err)
+	}
+	if err := pipeline.PreStore(ctx, taskMsg, result); err != nil {
+		t.Fatalf("PreStore failed: %v", err)
+	}
+	// A failing post-store hook does not stop the ones after it
+	pipeline.PostStore(ctx, taskMsg, result, "out/attempt-1.json")
+
+	expected := []string{
+		"test-redact:pre_scan", "test-enrich:pre_scan",
+		"post-scan-only:post_scan",
+		"test-redact:pre_store", "test-enrich:pre_store",
+		"test-redact:post_store", "test-enrich:post_store",
+	}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected calls %v, got %v", expected, calls)
+	}
+	if result.Error != "redacted by test-enrich" {
+		t.Errorf("Expected pre-store hooks to change the result, got %q", result.Error)
+	}
+}
+
+func TestPipelineStopsAtFirstError(t *testing.T) {
+	var calls []string
+	Register(&recordingHook{name: "test-gate", calls: &calls, failAt: "pre_scan"})
+	Register(&recordingHook{name: "test-after-gate", calls: &calls})
+
+	pipeline, err := New([]string{"test-gate", "test-after-gate"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	err = pipeline.PreScan(context.Background(), &models.TaskMessage{})
+	if err == nil || err.Error() != "pre-scan hook test-gate: refused" {
+		t.Errorf("Expected the gate's error, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("Expected the hooks after the failing one to be skipped, got %v", calls)
+	}
+}
+
+func TestNewRejectsUnknownHooks(t *testing.T) {
+	if _, err := New([]string{"missing"}); err == nil {
+		t.Error("Expected an error for an unregistered hook")
+	}
+
+	pipeline, err := New(ParseNames(" , "))
+	if err != nil || pipeline != nil {
+		t.Errorf("Expected no pipeline without names, got %v, %v", pipeline, err)
+	}
+	// A nil pipeline runs nothing
+	if err := pipeline.PreScan(context.Background(), &models.TaskMessage{}); err != nil {
+		t.Errorf("Expected a nil pipeline to run nothing, got %v", err)
+	}
+}
+
+func TestRegisterPanicsOnDuplicateName(t *testing.T) {
+	var calls []string
+	Register(&recordingHook{name: "test-duplicate", calls: &calls})
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Register to panic on a duplicate name")
+		}
+	}()
+	Register(&recordingHook{name: "test-duplicate", calls: &calls})
+}
+
+func TestParseNames(t *testing.T) {
+	if got := ParseNames("enrich, redact,,cmdb "); !reflect.DeepEqual(got, []string{"enrich", "redact", "cmdb"}) {
+		t.Errorf("Unexpected names %v", got)
+	}
+}
+
+func TestRejectReturnsValidationError(t *testing.T) {
+	err := Reject("target", "out of scope")
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeValidation {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if appErr.IsRetryable() {
+		t.Error("Expected a rejection not to be retried")
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/validation"
+)
+
+// Role grants access to a group of API endpoints. Each role includes the access of the roles below it.
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // Reads results and scan state
+	RoleOperator Role = "operator" // Also submits, pauses and resumes scans
+	RoleAdmin    Role = "admin"    // Also manages frozen scopes and quotas
+)
+
+// roleLevels orders the roles from least to most privileged
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Allows reports whether the role grants the access of the required role
+func (r Role) Allows(required Role) bool {
+	level, ok := roleLevels[r]
+	return ok && level >= roleLevels[required]
+}
+
+// AllTenants binds a token to every tenant, and to the scans and control state without one
+const AllTenants = "*"
+
+// Principal is an authenticated API caller
+type Principal struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// Tenant is the tenant the caller's token is bound to: "" for the scans without a tenant, or
+	// AllTenants
+	Tenant string `json:"tenant"`
+}
+
+// CanAccess reports whether the principal may reach the data of a tenant; "" names the scans and
+// control state without a tenant
+func (p *Principal) CanAccess(tenantID string) bool {
+	return p.Tenant == AllTenants || p.Tenant == tenantID
+}
+
+// principalKey is the context key of the authenticated principal
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal a request was authenticated as, or nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Authenticator maps bearer tokens to principals. Only token hashes are kept in memory.
+type Authenticator struct {
+	tokens map[[sha256.Size]byte]*Principal
+}
+
+// ParseTokens parses API tokens as "name[@tenant]:role:token" entries separated by ','. A token
+// without a tenant only reaches the scans without one; "@*" binds it to every tenant.
+func ParseTokens(spec string) (*Authenticator, error) {
+	auth := &Authenticator{tokens: make(map[[sha256.Size]byte]*Principal)}
+	validator := validation.NewValidator()
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid API token entry for %q: expected name:role:token", parts[0])
+		}
+		role := Role(parts[1])
+		if _, ok := roleLevels[role]; !ok {
+			return nil, fmt.Errorf("invalid role %q for API token %s", parts[1], parts[0])
+		}
+		if len(parts[2]) < 16 {
+			return nil, fmt.Errorf("API token %s must be at least 16 characters", parts[0])
+		}
+		name, tenant, bound := strings.Cut(parts[0], "@")
+		if bound && tenant != AllTenants && (tenant == "" || validator.ValidateTenantID(tenant) != nil) {
+			return nil, fmt.Errorf("invalid tenant %q for API token %s", tenant, name)
+		}
+
+		hash := sha256.Sum256([]byte(parts[2]))
+		if _, exists := auth.tokens[hash]; exists {
+			return nil, fmt.Errorf("API token %s is used twice", name)
+		}
+		auth.tokens[hash] = &Principal{Name: name, Role: role, Tenant: tenant}
+	}
+
+	return auth, nil
+}
+
+// Len returns the number of configured tokens
+func (a *Authenticator) Len() int {
+	return len(a.tokens)
+}
+
+// Authenticate returns the principal of the request's bearer token
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, false
+	}
+	principal, ok := a.tokens[sha256.Sum256([]byte(token))]
+	return principal, ok
+}
@@ -0,0 +1,499 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/google/uuid"
+	"github.com/projectdiscovery/gologger"
+)
+
+// exportJobTimeout bounds the time one export job may take to assemble its bundle
+const exportJobTimeout = 2 * time.Hour
+
+// exportJobQueue is how many export jobs wait for a slot before new jobs are turned away
+const exportJobQueue = 16
+
+// exportRequest is the body of an export job request
+type exportRequest struct {
+	TenantID string              `json:"tenant_id,omitempty"`
+	Format   string              `json:"format,omitempty"` // ndjson by default
+	Filter   models.ExportFilter `json:"filter"`
+}
+
+// exportJobView is an export job as the API returns it, with a download link once completed
+type exportJobView struct {
+	*models.ExportJob
+	DownloadURL       string `json:"download_url,omitempty"`
+	DownloadExpiresAt string `json:"download_expires_at,omitempty"`
+}
+
+// exportRunner runs export jobs in the background, a bounded number at a time
+type exportRunner struct {
+	ctx     context.Context
+	slots   chan struct{} // Jobs assembling their bundle
+	jobs    chan struct{} // Jobs running or waiting for a slot
+	linkTTL time.Duration
+	wg      sync.WaitGroup
+}
+
+// SetExportJobs enables export jobs. Jobs run in the background until ctx ends, at most
+// concurrency at a time with at most exportJobQueue more waiting, and the download links of their
+// bundles are valid for linkTTL.
+func (s *Server) SetExportJobs(ctx context.Context, concurrency int, linkTTL time.Duration) {
+	concurrency = max(concurrency, 1)
+	s.exports = &exportRunner{
+		ctx:     ctx,
+		slots:   make(chan struct{}, concurrency),
+		jobs:    make(chan struct{}, concurrency+exportJobQueue),
+		linkTTL: linkTTL,
+	}
+}
+
+// handleCreateExport starts an export job of the stored results a filter selects and answers
+// 202 with the job, which the client polls until its bundle is ready
+func (s *Server) handleCreateExport(w http.ResponseWriter, r *http.Request) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotFound, "export jobs are disabled")
+		return
+	}
+	var request exportRequest
+	if !readJSON(w, r, &request) {
+		return
+	}
+	if err := s.validateExportRequest(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	select {
+	case s.exports.jobs <- struct{}{}:
+	default:
+		writeError(w, http.StatusTooManyRequests, "too many export jobs are queued; try again later")
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	job := &models.ExportJob{
+		ID:          uuid.New().String(),
+		TenantID:    request.TenantID,
+		Format:      request.Format,
+		Filter:      request.Filter,
+		Status:      models.ExportJobPending,
+		RequestedBy: PrincipalFromContext(r.Context()).Name,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.storeExportJob(r.Context(), job); err != nil {
+		<-s.exports.jobs
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// The runner updates its own copy while the response is written
+	queued := *job
+	s.exports.wg.Add(1)
+	go func() {
+		defer s.exports.wg.Done()
+		defer func() { <-s.exports.jobs }()
+		s.runExportJob(&queued)
+	}()
+
+	s.audit(r, "started export %s of %s", job.ID, job.Filter.Domain)
+	w.Header().Set("Location", "/api/v1/exports/"+job.ID)
+	writeJSON(w, http.StatusAccepted, exportJobView{ExportJob: job})
+}
+
+// handleGetExport returns an export job, with a download link of its bundle once it completed
+func (s *Server) handleGetExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.exportJobParam(w, r)
+	if !ok {
+		return
+	}
+	view := exportJobView{ExportJob: job}
+	// Jobs time out, so one that has not moved on for longer was cut short by a restart
+	if updated, err := time.Parse(time.RFC3339, job.UpdatedAt); err == nil && time.Since(updated) > exportJobTimeout &&
+		(job.Status == models.ExportJobPending || job.Status == models.ExportJobRunning) {
+		job.Status, job.Error = models.ExportJobFailed, "the job was interrupted; start a new export"
+	}
+	if job.Status == models.ExportJobCompleted {
+		url, err := s.store.BlobDownloadURL(job.BlobPath, s.exports.linkTTL)
+		if err != nil {
+			// Accounts connected without a key cannot sign links; the API serves the bundle instead
+			gologger.Debug().Msgf("Serving export %s through the API: %v", job.ID, err)
+			url = "/api/v1/exports/" + job.ID + "/download"
+			if job.TenantID != "" {
+				url += "?tenant_id=" + job.TenantID
+			}
+		} else {
+			view.DownloadExpiresAt = time.Now().Add(s.exports.linkTTL).UTC().Format(time.RFC3339)
+		}
+		view.DownloadURL = url
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// handleDownloadExport streams the bundle of a completed export job
+func (s *Server) handleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.exportJobParam(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != models.ExportJobCompleted {
+		writeError(w, http.StatusConflict, "export "+job.ID+" is "+job.Status)
+		return
+	}
+	reader, err := s.store.OpenBlobRange(r.Context(), job.BlobPath, 0)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	defer reader.Close()
+
+	name := job.BlobPath[strings.LastIndex(job.BlobPath, "/")+1:]
+	if job.Format == models.ExportFormatZip {
+		w.Header().Set("Content-Type", "application/zip")
+	} else {
+		w.Header().Set("Content-Type", "application/gzip")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s"`, job.ID, name))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, reader); err != nil {
+		gologger.Warning().Msgf("Failed to stream export %s: %v", job.ID, err)
+	}
+}
+
+// exportJobParam loads the export job named by the path and the tenant_id query parameter
+func (s *Server) exportJobParam(w http.ResponseWriter, r *http.Request) (*models.ExportJob, bool) {
+	if s.exports == nil {
+		writeError(w, http.StatusNotFound, "export jobs are disabled")
+		return nil, false
+	}
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return nil, false
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "id must be an export job ID")
+		return nil, false
+	}
+
+	content, err := s.store.ReadFileFromBlob(r.Context(), models.ExportJobBlobPath(tenantID, id.String()))
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return nil, false
+	}
+	if len(content) == 0 {
+		writeError(w, http.StatusNotFound, "no export "+id.String())
+		return nil, false
+	}
+	var job models.ExportJob
+	if err := json.Unmarshal(content, &job); err != nil {
+		writeError(w, http.StatusBadGateway, "invalid export job: "+err.Error())
+		return nil, false
+	}
+	return &job, true
+}
+
+// validateExportRequest validates an export request and fills in its defaults
+func (s *Server) validateExportRequest(request *exportRequest) error {
+	if err := s.validator.ValidateTenantID(request.TenantID); err != nil {
+		return err
+	}
+	if err := s.validator.ValidateTarget(request.Filter.Domain); err != nil {
+		return err
+	}
+	switch request.Format {
+	case "":
+		request.Format = models.ExportFormatNDJSON
+	case models.ExportFormatNDJSON, models.ExportFormatZip:
+	default:
+		return fmt.Errorf("format must be %s or %s", models.ExportFormatNDJSON, models.ExportFormatZip)
+	}
+	for _, scanID := range request.Filter.ScanIDs {
+		if scanID <= 0 {
+			return fmt.Errorf("scan_ids must be positive integers")
+		}
+	}
+	for i, task := range request.Filter.Tasks {
+		tasks, err := s.validator.ParseTaskTypes(string(task))
+		if err != nil || len(tasks) != 1 {
+			return fmt.Errorf("invalid task type: %s", task)
+		}
+		request.Filter.Tasks[i] = tasks[0]
+	}
+	return nil
+}
+
+// runExportJob assembles the bundle of an export job and records how it ended
+func (s *Server) runExportJob(job *models.ExportJob) {
+	select {
+	case s.exports.slots <- struct{}{}:
+		defer func() { <-s.exports.slots }()
+	case <-s.exports.ctx.Done():
+		return
+	}
+	ctx, cancel := context.WithTimeout(s.exports.ctx, exportJobTimeout)
+	defer cancel()
+
+	job.Status = models.ExportJobRunning
+	if err := s.storeExportJob(ctx, job); err != nil {
+		gologger.Warning().Msgf("Failed to store export %s: %v", job.ID, err)
+	}
+
+	err := s.writeExportBundle(ctx, job)
+	if err != nil {
+		job.Status, job.Error = models.ExportJobFailed, err.Error()
+		gologger.Warning().Msgf("Export %s of %s failed: %v", job.ID, job.Filter.Domain, err)
+	} else {
+		job.Status = models.ExportJobCompleted
+		gologger.Info().Msgf("Export %s of %s completed with %d results", job.ID, job.Filter.Domain, job.Results)
+	}
+
+	// The final state is stored even when the job ran out of time
+	storeCtx, storeCancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer storeCancel()
+	if err := s.storeExportJob(storeCtx, job); err != nil {
+		gologger.Warning().Msgf("Failed to store export %s: %v", job.ID, err)
+	}
+}
+
+// writeExportBundle streams the latest results the job's filter selects into its bundle blob
+func (s *Server) writeExportBundle(ctx context.Context, job *models.ExportJob) error {
+	blobs, err := s.store.ListBlobs(ctx, models.DomainScansPrefix(job.TenantID, job.Filter.Domain))
+	if err != nil {
+		return err
+	}
+
+	blobPath := models.ExportBundleBlobPath(job.TenantID, job.ID, job.Format)
+	stream, err := s.store.StreamArtifact(ctx, blobPath)
+	if err != nil {
+		return err
+	}
+	counter := &byteCounter{w: stream}
+	bundle := newExportBundle(job.Format, counter)
+
+	for _, blob := range blobs {
+		scanID, task, ok := models.LatestResultPointer(job.TenantID, job.Filter.Domain, blob.Name)
+		if !ok || !job.Filter.Matches(scanID, task) {
+			continue
+		}
+		if err := s.addExportResult(ctx, bundle, job, scanID, blob.Name); err != nil {
+			stream.Close()
+			return err
+		}
+		job.Results++
+	}
+
+	if err := bundle.Close(); err != nil {
+		stream.Close()
+		return err
+	}
+	if err := stream.Close(); err != nil {
+		return err
+	}
+	job.BlobPath, job.Size = blobPath, counter.n
+	return nil
+}
+
+// addExportResult adds the result a latest result pointer names to a bundle. Results too large to
+// store whole only hold a sample and streamed DNSX results hold no records, so their full data and
+// records blob are added too: as files of their own to zip bundles, inline to NDJSON lines.
+func (s *Server) addExportResult(ctx context.Context, bundle exportBundle, job *models.ExportJob, scanID int, pointerPath string) error {
+	content, err := s.store.ReadFileFromBlob(ctx, pointerPath)
+	if err != nil {
+		return err
+	}
+	var latest models.LatestResult
+	if err := json.Unmarshal(content, &latest); err != nil {
+		return fmt.Errorf("invalid latest result pointer %s: %w", pointerPath, err)
+	}
+	content, err = s.store.ReadFileFromBlob(ctx, latest.BlobPath)
+	if err != nil {
+		return err
+	}
+
+	var stored struct {
+		Summary *models.ResultSummary `json:"summary,omitempty"`
+		Data    struct {
+			RecordsBlob string `json:"records_blob,omitempty"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return fmt.Errorf("invalid result %s: %w", latest.BlobPath, err)
+	}
+	recordsBlob := stored.Data.RecordsBlob
+	if recordsBlob != "" && !strings.HasPrefix(recordsBlob, models.ScanBlobPrefix(job.TenantID, job.Filter.Domain, scanID)) {
+		return fmt.Errorf("the records blob of %s is outside the scan", latest.BlobPath)
+	}
+
+	if _, ok := bundle.(*zipBundle); ok {
+		if err := bundle.Add(latest.BlobPath, content); err != nil {
+			return fmt.Errorf("failed to add %s to the bundle: %w", latest.BlobPath, err)
+		}
+		var parts []string
+		if stored.Summary != nil {
+			parts = stored.Summary.FullData
+		}
+		if recordsBlob != "" {
+			parts = append(parts, recordsBlob)
+		}
+		for _, part := range parts {
+			content, err := s.store.ReadFileFromBlob(ctx, part)
+			if err != nil {
+				return err
+			}
+			if err := bundle.Add(part, content); err != nil {
+				return fmt.Errorf("failed to add %s to the bundle: %w", part, err)
+			}
+		}
+		return nil
+	}
+
+	if stored.Summary != nil {
+		if content, err = s.readFullResult(ctx, stored.Summary.FullData); err != nil {
+			return fmt.Errorf("failed to read the full data of %s: %w", latest.BlobPath, err)
+		}
+	}
+	if recordsBlob != "" {
+		if content, err = s.inlineRecords(ctx, content, recordsBlob); err != nil {
+			return fmt.Errorf("failed to read the records of %s: %w", latest.BlobPath, err)
+		}
+	}
+	if err := bundle.Add(latest.BlobPath, content); err != nil {
+		return fmt.Errorf("failed to add %s to the bundle: %w", latest.BlobPath, err)
+	}
+	return nil
+}
+
+// readFullResult reads the result JSON stored in full data parts, which together form one gzip stream
+func (s *Server) readFullResult(ctx context.Context, parts []string) ([]byte, error) {
+	var compressed bytes.Buffer
+	for _, part := range parts {
+		content, err := s.store.ReadFileFromBlob(ctx, part)
+		if err != nil {
+			return nil, err
+		}
+		compressed.Write(content)
+	}
+	reader, err := gzip.NewReader(&compressed)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// inlineRecords returns stored result JSON with the records of its records blob under data.records
+func (s *Server) inlineRecords(ctx context.Context, content []byte, recordsBlob string) ([]byte, error) {
+	reader, err := s.store.OpenBlobRange(ctx, recordsBlob, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	records, err := utils.ReadDNSRecords(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]json.RawMessage
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(result["data"], &data); err != nil {
+		return nil, err
+	}
+	if data["records"], err = json.Marshal(records); err != nil {
+		return nil, err
+	}
+	if result["data"], err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// storeExportJob stores the state of an export job
+func (s *Server) storeExportJob(ctx context.Context, job *models.ExportJob) error {
+	job.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	content, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.store.StoreArtifact(ctx, models.ExportJobBlobPath(job.TenantID, job.ID), content)
+}
+
+// exportBundle writes the files of an export in its format
+type exportBundle interface {
+	Add(name string, content []byte) error
+	Close() error
+}
+
+// newExportBundle returns the bundle writer of a format
+func newExportBundle(format string, w io.Writer) exportBundle {
+	if format == models.ExportFormatZip {
+		return &zipBundle{zip: zip.NewWriter(w)}
+	}
+	return &ndjsonBundle{gz: gzip.NewWriter(w)}
+}
+
+// ndjsonBundle writes each result as one line of gzipped NDJSON
+type ndjsonBundle struct {
+	gz *gzip.Writer
+}
+
+func (b *ndjsonBundle) Add(name string, content []byte) error {
+	var line bytes.Buffer
+	if err := json.Compact(&line, content); err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+	_, err := b.gz.Write(line.Bytes())
+	return err
+}
+
+func (b *ndjsonBundle) Close() error {
+	return b.gz.Close()
+}
+
+// zipBundle writes each blob as a file named by its blob path
+type zipBundle struct {
+	zip *zip.Writer
+}
+
+func (b *zipBundle) Add(name string, content []byte) error {
+	file, err := b.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
+
+func (b *zipBundle) Close() error {
+	return b.zip.Close()
+}
+
+// byteCounter counts the bytes written through it
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// handleGetInventory returns the asset inventory of a domain. The optional filters select assets
+// first seen after seen_after ("new this week"), last seen before stale_before (stale assets to
+// clean up), of at least min_confidence and, with missed=true, zone records enumeration missed.
+func (s *Server) handleGetInventory(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	domain := query.Get("domain")
+	if err := s.validator.ValidateTarget(domain); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var seenAfter, staleBefore time.Time
+	for param, value := range map[string]*time.Time{"seen_after": &seenAfter, "stale_before": &staleBefore} {
+		if raw := query.Get(param); raw != "" {
+			parsed, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, param+" must be an RFC 3339 timestamp")
+				return
+			}
+			*value = parsed
+		}
+	}
+	minConfidence := query.Get("min_confidence")
+	if minConfidence != "" && models.ConfidenceRank(minConfidence) == 0 {
+		writeError(w, http.StatusBadRequest, "unknown min_confidence "+minConfidence)
+		return
+	}
+	missedOnly := query.Get("missed") == "true"
+
+	content, err := s.store.ReadFileFromBlob(r.Context(), models.InventoryBlobPath(tenantID, domain))
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if len(content) == 0 {
+		writeError(w, http.StatusNotFound, "no inventory for "+domain)
+		return
+	}
+	var inventory models.Inventory
+	if err := json.Unmarshal(content, &inventory); err != nil {
+		writeError(w, http.StatusBadGateway, "invalid inventory: "+err.Error())
+		return
+	}
+
+	for name, asset := range inventory.Assets {
+		if (!seenAfter.IsZero() && !asset.FirstSeen.After(seenAfter)) ||
+			(!staleBefore.IsZero() && !asset.LastSeen.Before(staleBefore)) ||
+			models.ConfidenceRank(asset.Confidence) < models.ConfidenceRank(minConfidence) ||
+			(missedOnly && !asset.MissedByEnumeration) {
+			delete(inventory.Assets, name)
+		}
+	}
+	writeJSON(w, http.StatusOK, inventory)
+}
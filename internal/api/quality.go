@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// errScanNotDegraded is returned by a review update of a scan that does not wait for one
+var errScanNotDegraded = errors.New("scan is not degraded")
+
+// handleGetScanQuality returns the quality checks of a scan and whether it is degraded
+func (s *Server) handleGetScanQuality(w http.ResponseWriter, r *http.Request) {
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+
+	quality, err := s.store.LoadScanQuality(r.Context(), tenantID, scanID)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	if quality == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no stage of scan %d was checked", scanID))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"degraded": quality.Degraded(), "quality": quality})
+}
+
+// handleReviewScanQuality releases a degraded scan, so the stages held for review start
+func (s *Server) handleReviewScanQuality(w http.ResponseWriter, r *http.Request) {
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+
+	var reviewed models.ScanQuality
+	err := s.store.UpdateScanQuality(r.Context(), tenantID, scanID, func(quality *models.ScanQuality) error {
+		if !quality.Degraded() {
+			return errScanNotDegraded
+		}
+		quality.Review(PrincipalFromContext(r.Context()).Name, time.Now().UTC())
+		reviewed = *quality
+		return nil
+	})
+	if errors.Is(err, errScanNotDegraded) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("scan %d is not degraded", scanID))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	s.audit(r, "reviewed degraded scan %d", scanID)
+	writeJSON(w, http.StatusOK, reviewed)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// Page sizes of record listings
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// recordsPage is a page of the records of a result
+type recordsPage struct {
+	ScanID     int               `json:"scan_id"`
+	Domain     string            `json:"domain"`
+	Offset     int               `json:"offset"`
+	Limit      int               `json:"limit"`
+	Total      int               `json:"total"`
+	NextOffset *int              `json:"next_offset,omitempty"` // Offset of the next page; unset on the last page
+	Records    []json.RawMessage `json:"records"`
+}
+
+// storedDNSXResult is the part of a stored DNSX task result that pages are read from
+type storedDNSXResult struct {
+	Summary *models.ResultSummary `json:"summary,omitempty"`
+	Data    models.DNSXResult     `json:"data"`
+}
+
+// handleDNSXRecords returns a page of the DNS records of a scan's latest DNSX result. Streamed
+// results are read from their NDJSON records blob, starting at the gzip member that holds the
+// page's first record when the blob has an index, so large results are never read whole.
+func (s *Server) handleDNSXRecords(w http.ResponseWriter, r *http.Request) {
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+	domain := r.URL.Query().Get("domain")
+	if err := s.validator.ValidateDomain(domain); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	var result storedDNSXResult
+	if !s.readLatestResult(w, r, tenantID, domain, scanID, models.TaskDNSResolve, &result) {
+		return
+	}
+
+	page := recordsPage{ScanID: scanID, Domain: domain, Offset: offset, Limit: limit}
+	switch {
+	case result.Data.RecordsBlob != "":
+		// The records blob is named by the stored result, but must still belong to the scan
+		if !strings.HasPrefix(result.Data.RecordsBlob, models.ScanBlobPrefix(tenantID, domain, scanID)) {
+			writeError(w, http.StatusBadGateway, "the result's records blob is outside the scan")
+			return
+		}
+		records, err := s.readRecordsPage(r.Context(), result.Data, offset, limit)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		page.Total, page.Records = result.Data.RecordsCount, records
+	case result.Summary != nil:
+		writeError(w, http.StatusConflict, "the result was too large to store whole; read its full_data parts instead")
+		return
+	default:
+		page.Total, page.Records = len(result.Data.Records), inlineRecordsPage(result.Data.Records, offset, limit)
+	}
+
+	if next := offset + len(page.Records); next < page.Total {
+		page.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// readLatestResult reads the result the latest pointer of a task names into v, answering the
+// request itself when there is none
+func (s *Server) readLatestResult(w http.ResponseWriter, r *http.Request, tenantID, domain string, scanID int, task models.Task, v any) bool {
+	var latest models.LatestResult
+	content, err := s.store.ReadFileFromBlob(r.Context(), models.LatestResultBlobPath(tenantID, domain, scanID, string(task)))
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return false
+	}
+	if len(content) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no %s result for %s in scan %d", task, domain, scanID))
+		return false
+	}
+	if err := json.Unmarshal(content, &latest); err != nil {
+		writeError(w, http.StatusBadGateway, "invalid latest result pointer: "+err.Error())
+		return false
+	}
+
+	content, err = s.store.ReadFileFromBlob(r.Context(), latest.BlobPath)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return false
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		writeError(w, http.StatusBadGateway, "invalid result: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// readRecordsPage reads a page of a streamed result's records blob. Without an index the blob is
+// read from its start; an index lets the read start at the gzip member holding the page.
+func (s *Server) readRecordsPage(ctx context.Context, result models.DNSXResult, offset, limit int) ([]json.RawMessage, error) {
+	if offset >= result.RecordsCount {
+		return []json.RawMessage{}, nil
+	}
+
+	var start int64
+	skip := offset
+	if result.RecordsIndex != "" {
+		content, err := s.store.ReadFileFromBlob(ctx, result.RecordsIndex)
+		if err != nil {
+			return nil, err
+		}
+		var index models.RecordsIndex
+		if err := json.Unmarshal(content, &index); err != nil {
+			return nil, fmt.Errorf("invalid records index: %w", err)
+		}
+		if chunk, ok := index.Locate(offset); ok {
+			start, skip = chunk.Offset, offset-chunk.Line
+		}
+	}
+
+	reader, err := s.store.OpenBlobRange(ctx, result.RecordsBlob, start)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return utils.ReadNDJSONLines(reader, skip, limit)
+}
+
+// inlineRecordsPage returns a page of records held in the result itself, ordered by host
+func inlineRecordsPage(records map[string]models.ResolutionInfo, offset, limit int) []json.RawMessage {
+	hosts := make([]string, 0, len(records))
+	for host := range records {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	page := []json.RawMessage{}
+	for _, host := range hosts[min(offset, len(hosts)):min(offset+limit, len(hosts))] {
+		line, _ := json.Marshal(models.DNSRecordLine{Host: host, ResolutionInfo: records[host]})
+		page = append(page, line)
+	}
+	return page
+}
+
+// pageParams reads the offset and limit query parameters of a listing
+func pageParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	query := r.URL.Query()
+	offset, limit := 0, defaultPageLimit
+	if raw := query.Get("offset"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = value
+	}
+	if raw := query.Get("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxPageLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxPageLimit))
+			return 0, 0, false
+		}
+		limit = value
+	}
+	return offset, limit, true
+}
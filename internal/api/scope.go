@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// errUnknownSuggestion is returned by a review of an apex that was never suggested
+var errUnknownSuggestion = errors.New("apex was not suggested")
+
+// handleListScopeSuggestions lists the scope suggestions of a domain, optionally only those with
+// the given status, best supported first
+func (s *Server) handleListScopeSuggestions(w http.ResponseWriter, r *http.Request) {
+	tenantID, domain, ok := s.scopeParams(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	content, err := s.store.ReadFileFromBlob(r.Context(), models.ScopeSuggestionsBlobPath(tenantID, domain))
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	var current models.ScopeSuggestions
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &current); err != nil {
+			writeError(w, http.StatusBadGateway, "invalid scope suggestions: "+err.Error())
+			return
+		}
+	}
+
+	suggestions := []*models.ScopeSuggestion{}
+	for _, suggestion := range current.Suggestions {
+		if status == "" || suggestion.Status == status {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if len(suggestions[i].Evidence) != len(suggestions[j].Evidence) {
+			return len(suggestions[i].Evidence) > len(suggestions[j].Evidence)
+		}
+		return suggestions[i].Apex < suggestions[j].Apex
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{"domain": domain, "suggestions": suggestions})
+}
+
+// handleReviewScopeSuggestion approves or rejects a suggested apex. Approval only records the
+// decision; scanning the apex is still up to the orchestrator.
+func (s *Server) handleReviewScopeSuggestion(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, domain, ok := s.scopeParams(w, r)
+		if !ok {
+			return
+		}
+		apex := r.PathValue("apex")
+
+		var reviewed models.ScopeSuggestion
+		err := s.store.UpdateScopeSuggestions(r.Context(), tenantID, domain, func(current *models.ScopeSuggestions) error {
+			suggestion, ok := current.Suggestions[apex]
+			if !ok {
+				return errUnknownSuggestion
+			}
+			suggestion.Status = status
+			suggestion.DecidedBy = PrincipalFromContext(r.Context()).Name
+			suggestion.DecidedAt = time.Now().UTC()
+			reviewed = *suggestion
+			return nil
+		})
+		if errors.Is(err, errUnknownSuggestion) {
+			writeError(w, http.StatusNotFound, apex+" was not suggested for "+domain)
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		s.audit(r, "%s scope suggestion %s for domain %s", status, apex, domain)
+		writeJSON(w, http.StatusOK, reviewed)
+	}
+}
+
+// scopeParams reads the optional tenant and the required domain from the query
+func (s *Server) scopeParams(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return "", "", false
+	}
+	domain := r.URL.Query().Get("domain")
+	if err := s.validator.ValidateDomain(domain); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", "", false
+	}
+	return tenantID, domain, true
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/allsafeASM/api/internal/search"
+)
+
+// Searcher searches the indexed findings
+type Searcher interface {
+	Search(ctx context.Context, request search.Request) (*search.Result, error)
+}
+
+// searchPage is a page of search hits with the facets of all matches
+type searchPage struct {
+	Query      string                    `json:"query"`
+	Offset     int                       `json:"offset"`
+	Limit      int                       `json:"limit"`
+	Total      int                       `json:"total"`
+	NextOffset *int                      `json:"next_offset,omitempty"` // Offset of the next page; unset on the last page
+	Hits       []json.RawMessage         `json:"hits"`
+	Facets     map[string][]search.Facet `json:"facets"`
+}
+
+// SetSearcher enables the search endpoint
+func (s *Server) SetSearcher(searcher Searcher) {
+	s.searcher = searcher
+}
+
+// handleSearch searches the findings of a tenant, or of scans without a tenant, with a query such
+// as "status:alive tech:wordpress port:443 domain:*.corp.com" in ?q=
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if s.searcher == nil {
+		writeError(w, http.StatusNotFound, "search is disabled")
+		return
+	}
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return
+	}
+	offset, limit, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+	if offset+limit > search.MaxWindow {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("offset and limit must not reach past the first %d hits", search.MaxWindow))
+		return
+	}
+	raw := r.URL.Query().Get("q")
+	query, err := search.ParseQuery(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid query: "+err.Error())
+		return
+	}
+
+	result, err := s.searcher.Search(r.Context(), search.Request{Query: query, TenantID: tenantID, Offset: offset, Limit: limit})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	page := searchPage{Query: raw, Offset: offset, Limit: limit, Total: result.Total, Hits: result.Hits, Facets: result.Facets}
+	if next := offset + len(result.Hits); len(result.Hits) > 0 && next < min(result.Total, search.MaxWindow) {
+		page.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/buildinfo"
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/validation"
+	"github.com/projectdiscovery/gologger"
+)
+
+// maxRequestBody bounds the JSON bodies the API accepts
+const maxRequestBody = 1 << 20
+
+// TaskQueue accepts tasks submitted through the API
+type TaskQueue interface {
+	EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error
+}
+
+// Store is the blob storage the API reads results and manages control state in
+type Store interface {
+	ListBlobs(ctx context.Context, prefix string) ([]azure.BlobInfo, error)
+	ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error)
+	OpenBlobRange(ctx context.Context, blobPath string, offset int64) (io.ReadCloser, error)
+	BlobDownloadURL(blobPath string, ttl time.Duration) (string, error)
+	StoreArtifact(ctx context.Context, blobPath string, data []byte) error
+	StreamArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error)
+	SetScanPaused(ctx context.Context, tenantID string, scanID int, paused bool) error
+	LoadFreezeList(ctx context.Context, blobPath string) (*models.FreezeList, error)
+	UpdateFreezeList(ctx context.Context, blobPath string, update func(*models.FreezeList) error) error
+	UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error
+	LoadScanStatus(ctx context.Context, tenantID string, scanID int) (*models.ScanStatus, error)
+	LoadScanQuality(ctx context.Context, tenantID string, scanID int) (*models.ScanQuality, error)
+	UpdateScanQuality(ctx context.Context, tenantID string, scanID int, update func(*models.ScanQuality) error) error
+	UpdateScopeSuggestions(ctx context.Context, tenantID, domain string, update func(*models.ScopeSuggestions) error) error
+	UpdateWebhookCall(ctx context.Context, signature string, update func(*models.WebhookCall) error) error
+}
+
+// Server is the HTTP API of the worker. Every endpoint under /api/v1 requires a bearer token whose
+// role allows it: viewers read results, operators run scans and admins manage control state.
+// The scan status endpoint is public instead, but needs the scan's status token, and the
+// webhook endpoint needs calls signed with the webhook secret.
+type Server struct {
+	queue     TaskQueue
+	store     Store
+	auth      *Authenticator
+	validator *validation.Validator
+	mux       *http.ServeMux
+
+	statusSecret  []byte
+	statusLimiter *rateLimiter
+	events        *events.Bus
+
+	webhookSecret []byte
+
+	searcher Searcher
+	exports  *exportRunner
+}
+
+// NewServer creates the API server and registers its endpoints
+func NewServer(queue TaskQueue, store Store, auth *Authenticator) *Server {
+	s := &Server{
+		queue:     queue,
+		store:     store,
+		auth:      auth,
+		validator: validation.NewValidator(),
+		mux:       http.NewServeMux(),
+	}
+
+	s.handle("GET /api/v1/whoami", RoleViewer, s.handleWhoAmI)
+	s.handle("GET /api/v1/scans/{scan_id}/results", RoleViewer, s.handleListResults)
+	s.handle("GET /api/v1/results", RoleViewer, s.handleGetResult)
+	s.handle("GET /api/v1/scans/{scan_id}/dnsx", RoleViewer, s.handleDNSXRecords)
+	s.handle("GET /api/v1/scans/{scan_id}/events", RoleViewer, s.handleScanEvents)
+	s.handle("GET /api/v1/scans/{scan_id}/quality", RoleViewer, s.handleGetScanQuality)
+	s.handle("GET /api/v1/inventory", RoleViewer, s.handleGetInventory)
+	s.handle("GET /api/v1/scope/suggestions", RoleViewer, s.handleListScopeSuggestions)
+	s.handle("GET /api/v1/search", RoleViewer, s.handleSearch)
+	s.handle("POST /api/v1/exports", RoleViewer, s.handleCreateExport)
+	s.handle("GET /api/v1/exports/{id}", RoleViewer, s.handleGetExport)
+	s.handle("GET /api/v1/exports/{id}/download", RoleViewer, s.handleDownloadExport)
+
+	s.handle("POST /api/v1/tasks", RoleOperator, s.handleSubmitTask)
+	s.handle("POST /api/v1/scans/{scan_id}/pause", RoleOperator, s.handleSetPaused(true))
+	s.handle("POST /api/v1/scans/{scan_id}/resume", RoleOperator, s.handleSetPaused(false))
+	s.handle("POST /api/v1/scans/{scan_id}/quality/review", RoleOperator, s.handleReviewScanQuality)
+
+	s.handle("GET /api/v1/freeze", RoleAdmin, s.handleGetFreezeList)
+	s.handle("PUT /api/v1/freeze", RoleAdmin, s.handlePutFreezeList)
+	s.handle("GET /api/v1/quotas/{key}", RoleAdmin, s.handleGetQuota)
+	s.handle("PUT /api/v1/quotas/{key}", RoleAdmin, s.handlePutQuota)
+	s.handle("POST /api/v1/scope/suggestions/{apex}/approve", RoleAdmin, s.handleReviewScopeSuggestion(models.ScopeSuggestionApproved))
+	s.handle("POST /api/v1/scope/suggestions/{apex}/reject", RoleAdmin, s.handleReviewScopeSuggestion(models.ScopeSuggestionRejected))
+
+	s.mux.HandleFunc("GET /scans/{scan_id}/status", s.handleScanStatus)
+	s.mux.HandleFunc("POST /webhooks/tasks", s.handleWebhook)
+	s.mux.HandleFunc("GET /version", s.handleVersion)
+
+	return s
+}
+
+// ServeHTTP serves the API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handle registers an endpoint that requires the given role
+func (s *Server) handle(pattern string, role Role, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := s.auth.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="asm"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		if !principal.Role.Allows(role) {
+			gologger.Warning().Msgf("API: %s (%s) denied %s %s", principal.Name, principal.Role, r.Method, r.URL.Path)
+			writeError(w, http.StatusForbidden, "the "+string(role)+" role is required")
+			return
+		}
+		for _, tenantID := range requestTenants(r) {
+			if !principal.CanAccess(tenantID) {
+				gologger.Warning().Msgf("API: %s (tenant %q) denied %s %s for tenant %q", principal.Name, principal.Tenant, r.Method, r.URL.Path, tenantID)
+				writeError(w, http.StatusForbidden, "the token is not bound to tenant "+strconv.Quote(tenantID))
+				return
+			}
+		}
+
+		handler(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// handleWhoAmI returns the caller's principal
+func (s *Server) handleWhoAmI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, PrincipalFromContext(r.Context()))
+}
+
+// handleVersion reports the build of the worker. It needs no token, so deployment
+// tooling can check which build is running.
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+// handleListResults lists the stored results of a scan
+func (s *Server) handleListResults(w http.ResponseWriter, r *http.Request) {
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+	// httpx and nuclei tasks may target a URL or host:port, whose results are stored under it
+	domain := r.URL.Query().Get("domain")
+	if err := s.validator.ValidateTarget(domain); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	blobs, err := s.store.ListBlobs(r.Context(), models.ScanBlobPrefix(tenantID, domain, scanID))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	type resultBlob struct {
+		Path string `json:"path"`
+		Size int64  `json:"size"`
+	}
+	results := []resultBlob{}
+	for _, blob := range blobs {
+		if strings.Contains(blob.Name, "/out/") {
+			results = append(results, resultBlob{Path: blob.Name, Size: blob.Size})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// handleGetResult returns a stored result named by its path
+func (s *Server) handleGetResult(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" || strings.Contains(path, "..") || strings.HasPrefix(path, "/") || !strings.Contains(path, "/out/") {
+		writeError(w, http.StatusBadRequest, "path must name a stored result")
+		return
+	}
+
+	content, err := s.store.ReadFileFromBlob(r.Context(), path)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			writeError(w, http.StatusNotFound, "result not found")
+			return
+		}
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	if strings.HasSuffix(path, ".json") {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(content)
+}
+
+// handleSubmitTask validates a task and sends it to the queue
+func (s *Server) handleSubmitTask(w http.ResponseWriter, r *http.Request) {
+	var taskMsg models.TaskMessage
+	if !readJSON(w, r, &taskMsg) {
+		return
+	}
+	if taskMsg.Action != "" {
+		writeError(w, http.StatusBadRequest, "use the pause and resume endpoints for control actions")
+		return
+	}
+	if err := s.validator.ValidateTaskMessage(&taskMsg); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if taskMsg.CorrelationID == "" {
+		taskMsg.CorrelationID = models.NewCorrelationID()
+	}
+
+	if err := s.queue.EnqueueTask(r.Context(), &taskMsg); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	s.audit(r, "submitted %s for scan %d of %s", taskMsg.Task, taskMsg.ScanID, taskMsg.Domain)
+	writeJSON(w, http.StatusAccepted, map[string]string{"correlation_id": taskMsg.CorrelationID})
+}
+
+// handleSetPaused pauses or resumes a scan; a paused scan stops at its next checkpoint
+func (s *Server) handleSetPaused(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scanID, tenantID, ok := s.scanParams(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.store.SetScanPaused(r.Context(), tenantID, scanID, paused); err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+
+		s.audit(r, "set scan %d paused=%t", scanID, paused)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"scan_id": scanID, "paused": paused})
+	}
+}
+
+// handleGetFreezeList returns the global freeze list, or the tenant's with ?tenant_id=
+func (s *Server) handleGetFreezeList(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return
+	}
+
+	freezeList, err := s.store.LoadFreezeList(r.Context(), models.FreezeListBlobPath(tenantID))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, freezeList)
+}
+
+// errFreezeListChanged rejects a freeze list made on a version that is no longer the stored one
+var errFreezeListChanged = errors.New("the freeze list changed")
+
+// handlePutFreezeList replaces the global freeze list, or the tenant's with ?tenant_id=
+func (s *Server) handlePutFreezeList(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := s.tenantParam(w, r)
+	if !ok {
+		return
+	}
+
+	var freezeList models.FreezeList
+	if !readJSON(w, r, &freezeList) {
+		return
+	}
+	for _, entry := range freezeList.Entries {
+		if strings.TrimSpace(entry.Scope) == "" {
+			writeError(w, http.StatusBadRequest, "every freeze entry needs a scope")
+			return
+		}
+		if entry.Until != "" {
+			if _, err := time.Parse(time.RFC3339, entry.Until); err != nil {
+				writeError(w, http.StatusBadRequest, "until must be an RFC3339 time")
+				return
+			}
+		}
+	}
+
+	// The list replaces the version it was read as, so a change made in the meantime is not lost
+	err := s.store.UpdateFreezeList(r.Context(), models.FreezeListBlobPath(tenantID), func(current *models.FreezeList) error {
+		if current.Version != freezeList.Version {
+			return fmt.Errorf("%w: version %d was replaced by version %d, read the list again and reapply the change",
+				errFreezeListChanged, freezeList.Version, current.Version)
+		}
+		freezeList.Version++
+		*current = freezeList
+		return nil
+	})
+	if errors.Is(err, errFreezeListChanged) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	s.audit(r, "replaced the freeze list of tenant %q with %d entries", tenantID, len(freezeList.Entries))
+	writeJSON(w, http.StatusOK, freezeList)
+}
+
+// handleGetQuota returns the quota state of a passive source API key
+func (s *Server) handleGetQuota(w http.ResponseWriter, r *http.Request) {
+	key, ok := quotaKeyParam(w, r)
+	if !ok {
+		return
+	}
+
+	content, err := s.store.ReadFileFromBlob(r.Context(), models.QuotaStateBlobPath(key))
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			writeError(w, http.StatusNotFound, "no quota state for "+key)
+			return
+		}
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(content)
+}
+
+// handlePutQuota sets the limit or the available tokens of a passive source API key
+func (s *Server) handlePutQuota(w http.ResponseWriter, r *http.Request) {
+	key, ok := quotaKeyParam(w, r)
+	if !ok {
+		return
+	}
+
+	var update struct {
+		Limit  *int     `json:"limit"`
+		Tokens *float64 `json:"tokens"`
+	}
+	if !readJSON(w, r, &update) {
+		return
+	}
+	if (update.Limit != nil && *update.Limit < 0) || (update.Tokens != nil && *update.Tokens < 0) {
+		writeError(w, http.StatusBadRequest, "limit and tokens cannot be negative")
+		return
+	}
+
+	var state models.QuotaState
+	err := s.store.UpdateQuotaState(r.Context(), key, func(current *models.QuotaState) error {
+		current.Key = key
+		if update.Limit != nil {
+			current.Limit = *update.Limit
+		}
+		if update.Tokens != nil {
+			current.Tokens = *update.Tokens
+		}
+		if current.Limit > 0 && current.Tokens > float64(current.Limit) {
+			current.Tokens = float64(current.Limit)
+		}
+		current.UpdatedAt = time.Now().UTC()
+		state = *current
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	s.audit(r, "set quota %s to %d limit and %.0f tokens", key, state.Limit, state.Tokens)
+	writeJSON(w, http.StatusOK, state)
+}
+
+// scanParams reads the scan ID from the path and the optional tenant from the query
+func (s *Server) scanParams(w http.ResponseWriter, r *http.Request) (int, string, bool) {
+	scanID, err := strconv.Atoi(r.PathValue("scan_id"))
+	if err != nil || scanID <= 0 {
+		writeError(w, http.StatusBadRequest, "scan_id must be a positive integer")
+		return 0, "", false
+	}
+	tenantID, ok := s.tenantParam(w, r)
+	return scanID, tenantID, ok
+}
+
+// requestTenants returns the tenants a request reaches: the tenant_id query parameter, "" when it
+// is not set, the tenant_id of a JSON body, and the tenant of a stored result path. The body is
+// put back for the handler to read.
+func requestTenants(r *http.Request) []string {
+	query := r.URL.Query()
+	tenants := []string{query.Get("tenant_id")}
+	if path := query.Get("path"); path != "" {
+		tenants = append(tenants, resultPathTenant(path))
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBody+1))
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		var tenant struct {
+			TenantID *string `json:"tenant_id"`
+		}
+		if err == nil && json.Unmarshal(body, &tenant) == nil && tenant.TenantID != nil {
+			tenants = append(tenants, *tenant.TenantID)
+		}
+	}
+	return tenants
+}
+
+// resultPathTenant returns the tenant of a stored result path. Scans without a tenant store their
+// results under "<domain>-<scan_id>/<task>/", others under "<tenant_id>/<domain>-<scan_id>/".
+func resultPathTenant(path string) string {
+	first, rest, _ := strings.Cut(path, "/")
+	second, _, _ := strings.Cut(rest, "/")
+	if slices.Contains(models.KnownTasks, models.Task(second)) {
+		return ""
+	}
+	return first
+}
+
+// tenantParam reads the optional tenant_id query parameter
+func (s *Server) tenantParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenantID := r.URL.Query().Get("tenant_id")
+	if err := s.validator.ValidateTenantID(tenantID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return tenantID, true
+}
+
+// quotaKeyParam reads the quota key from the path
+func quotaKeyParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.PathValue("key")
+	if key == "" || strings.ContainsAny(key, "/\\.") {
+		writeError(w, http.StatusBadRequest, "invalid quota key")
+		return "", false
+	}
+	return key, true
+}
+
+// audit logs a change made through the API with the principal that made it
+func (s *Server) audit(r *http.Request, format string, args ...interface{}) {
+	principal := PrincipalFromContext(r.Context())
+	gologger.Info().Str("principal", principal.Name).Str("role", string(principal.Role)).Msgf("API: "+format, args...)
+}
+
+// readJSON decodes a request body, answering 400 when it is not valid JSON
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
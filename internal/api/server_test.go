@@ -0,0 +1,897 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/search"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+const (
+	viewerToken   = "viewer-token-0123456789"
+	operatorToken = "operator-token-0123456789"
+	adminToken    = "admin-token-0123456789"
+)
+
+type fakeQueue struct {
+	tasks []*models.TaskMessage
+	fail  int // Enqueues to fail, once the queue holds this many tasks; 0 never fails
+}
+
+func (q *fakeQueue) EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error {
+	if q.fail > 0 && len(q.tasks) == q.fail {
+		q.fail = 0
+		return errors.New("queue unavailable")
+	}
+	q.tasks = append(q.tasks, taskMsg)
+	return nil
+}
+
+type fakeStore struct {
+	status *models.ScanStatus
+	blobs  map[string][]byte
+	paused map[int]bool
+	freeze map[string]*models.FreezeList
+	quotas map[string]*models.QuotaState
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		blobs:  make(map[string][]byte),
+		paused: make(map[int]bool),
+		freeze: make(map[string]*models.FreezeList),
+		quotas: make(map[string]*models.QuotaState),
+	}
+}
+
+func (s *fakeStore) ListBlobs(ctx context.Context, prefix string) ([]azure.BlobInfo, error) {
+	var blobs []azure.BlobInfo
+	for name, content := range s.blobs {
+		if strings.HasPrefix(name, prefix) {
+			blobs = append(blobs, azure.BlobInfo{Name: name, Size: int64(len(content))})
+		}
+	}
+	return blobs, nil
+}
+
+func (s *fakeStore) ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error) {
+	return s.blobs[blobPath], nil
+}
+
+func (s *fakeStore) OpenBlobRange(ctx context.Context, blobPath string, offset int64) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.blobs[blobPath][offset:])), nil
+}
+
+func (s *fakeStore) BlobDownloadURL(blobPath string, ttl time.Duration) (string, error) {
+	return "https://storage.example/" + blobPath + "?sig=test", nil
+}
+
+func (s *fakeStore) StoreArtifact(ctx context.Context, blobPath string, data []byte) error {
+	s.blobs[blobPath] = data
+	return nil
+}
+
+func (s *fakeStore) StreamArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error) {
+	return &fakeStream{store: s, path: blobPath}, nil
+}
+
+// fakeStream stores its content when it is closed
+type fakeStream struct {
+	bytes.Buffer
+	store *fakeStore
+	path  string
+}
+
+func (f *fakeStream) Close() error {
+	f.store.blobs[f.path] = f.Bytes()
+	return nil
+}
+
+func (s *fakeStore) SetScanPaused(ctx context.Context, tenantID string, scanID int, paused bool) error {
+	s.paused[scanID] = paused
+	return nil
+}
+
+func (s *fakeStore) LoadFreezeList(ctx context.Context, blobPath string) (*models.FreezeList, error) {
+	if list, ok := s.freeze[blobPath]; ok {
+		return list, nil
+	}
+	return &models.FreezeList{}, nil
+}
+
+func (s *fakeStore) UpdateFreezeList(ctx context.Context, blobPath string, update func(*models.FreezeList) error) error {
+	list, ok := s.freeze[blobPath]
+	if !ok {
+		list = &models.FreezeList{}
+	}
+	updated := *list
+	if err := update(&updated); err != nil {
+		return err
+	}
+	s.freeze[blobPath] = &updated
+	return nil
+}
+
+func (s *fakeStore) UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error {
+	state, ok := s.quotas[key]
+	if !ok {
+		state = &models.QuotaState{}
+		s.quotas[key] = state
+	}
+	return update(state)
+}
+
+func (s *fakeStore) UpdateWebhookCall(ctx context.Context, signature string, update func(*models.WebhookCall) error) error {
+	path := models.WebhookCallBlobPath(signature)
+	var call models.WebhookCall
+	if content, ok := s.blobs[path]; ok {
+		json.Unmarshal(content, &call)
+	}
+	if err := update(&call); err != nil {
+		return err
+	}
+	s.blobs[path], _ = json.Marshal(call)
+	return nil
+}
+
+func (s *fakeStore) UpdateScopeSuggestions(ctx context.Context, tenantID, domain string, update func(*models.ScopeSuggestions) error) error {
+	path := models.ScopeSuggestionsBlobPath(tenantID, domain)
+	var suggestions models.ScopeSuggestions
+	if content, ok := s.blobs[path]; ok {
+		json.Unmarshal(content, &suggestions)
+	}
+	if err := update(&suggestions); err != nil {
+		return err
+	}
+	s.blobs[path], _ = json.Marshal(suggestions)
+	return nil
+}
+
+func (s *fakeStore) LoadScanStatus(ctx context.Context, tenantID string, scanID int) (*models.ScanStatus, error) {
+	return s.status, nil
+}
+
+func (s *fakeStore) LoadScanQuality(ctx context.Context, tenantID string, scanID int) (*models.ScanQuality, error) {
+	content, ok := s.blobs[models.ScanQualityBlobPath(tenantID, scanID)]
+	if !ok {
+		return nil, nil
+	}
+	var quality models.ScanQuality
+	return &quality, json.Unmarshal(content, &quality)
+}
+
+func (s *fakeStore) UpdateScanQuality(ctx context.Context, tenantID string, scanID int, update func(*models.ScanQuality) error) error {
+	path := models.ScanQualityBlobPath(tenantID, scanID)
+	quality := models.ScanQuality{ScanID: scanID, TenantID: tenantID}
+	if content, ok := s.blobs[path]; ok {
+		json.Unmarshal(content, &quality)
+	}
+	if err := update(&quality); err != nil {
+		return err
+	}
+	s.blobs[path], _ = json.Marshal(quality)
+	return nil
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeQueue, *fakeStore) {
+	t.Helper()
+	auth, err := ParseTokens("ci@*:viewer:" + viewerToken + ",ops@*:operator:" + operatorToken + ",root@*:admin:" + adminToken)
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	queue, store := &fakeQueue{}, newFakeStore()
+	return NewServer(queue, store, auth), queue, store
+}
+
+func doRequest(server *Server, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestParseTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantLen int
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"two tokens", "a:viewer:" + viewerToken + ", b:admin:" + adminToken, 2, false},
+		{"unknown role", "a:owner:" + viewerToken, 0, true},
+		{"short token", "a:viewer:short", 0, true},
+		{"missing token", "a:viewer", 0, true},
+		{"duplicate token", "a:viewer:" + viewerToken + ",b:admin:" + viewerToken, 0, true},
+		{"tenant tokens", "a@acme:viewer:" + viewerToken + ",b@*:admin:" + adminToken, 2, false},
+		{"empty tenant", "a@:viewer:" + viewerToken, 0, true},
+		{"invalid tenant", "a@../acme:viewer:" + viewerToken, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := ParseTokens(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && auth.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", auth.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestServer_RoleEnforcement(t *testing.T) {
+	server, _, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"no token", "GET", "/api/v1/whoami", "", "", http.StatusUnauthorized},
+		{"unknown token", "GET", "/api/v1/whoami", "not-a-configured-token", "", http.StatusUnauthorized},
+		{"viewer reads", "GET", "/api/v1/whoami", viewerToken, "", http.StatusOK},
+		{"viewer cannot pause", "POST", "/api/v1/scans/7/pause", viewerToken, "", http.StatusForbidden},
+		{"operator pauses", "POST", "/api/v1/scans/7/pause", operatorToken, "", http.StatusOK},
+		{"operator cannot manage freeze list", "GET", "/api/v1/freeze", operatorToken, "", http.StatusForbidden},
+		{"admin manages freeze list", "GET", "/api/v1/freeze", adminToken, "", http.StatusOK},
+		{"admin pauses", "POST", "/api/v1/scans/7/resume", adminToken, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(server, tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_TenantBinding(t *testing.T) {
+	auth, err := ParseTokens("acme@acme:operator:" + operatorToken + ",local:viewer:" + viewerToken)
+	if err != nil {
+		t.Fatalf("ParseTokens() error = %v", err)
+	}
+	queue, store := &fakeQueue{}, newFakeStore()
+	server := NewServer(queue, store, auth)
+	store.blobs[models.ScanBlobPrefix("acme", "example.com", 5)+"httpx/out/attempt-1.json"] = []byte(`{}`)
+	store.blobs[models.ScanBlobPrefix("", "example.com", 5)+"httpx/out/attempt-1.json"] = []byte(`{}`)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"own tenant", "GET", "/api/v1/scans/5/results?domain=example.com&tenant_id=acme", operatorToken, "", http.StatusOK},
+		{"other tenant", "GET", "/api/v1/scans/5/results?domain=example.com&tenant_id=globex", operatorToken, "", http.StatusForbidden},
+		{"no tenant", "GET", "/api/v1/scans/5/results?domain=example.com", operatorToken, "", http.StatusForbidden},
+		{"own result path", "GET", "/api/v1/results?path=acme/example.com-5/httpx/out/attempt-1.json&tenant_id=acme", operatorToken, "", http.StatusOK},
+		{"result path without tenant", "GET", "/api/v1/results?path=example.com-5/httpx/out/attempt-1.json&tenant_id=acme", operatorToken, "", http.StatusForbidden},
+		{"task of other tenant", "POST", "/api/v1/tasks?tenant_id=acme", operatorToken, `{"task":"subfinder","scan_id":3,"domain":"example.com","tenant_id":"globex"}`, http.StatusForbidden},
+		{"unbound token without tenant", "GET", "/api/v1/scans/5/results?domain=example.com", viewerToken, "", http.StatusOK},
+		{"unbound token with tenant", "GET", "/api/v1/scans/5/results?domain=example.com&tenant_id=acme", viewerToken, "", http.StatusForbidden},
+		{"unbound token reads tenant path", "GET", "/api/v1/results?path=acme/example.com-5/httpx/out/attempt-1.json", viewerToken, "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(server, tt.method, tt.path, tt.token, tt.body)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+	if len(queue.tasks) != 0 {
+		t.Errorf("Expected no task of another tenant enqueued, got %+v", queue.tasks)
+	}
+
+	rec := doRequest(server, "POST", "/api/v1/tasks?tenant_id=acme", operatorToken, `{"task":"subfinder","scan_id":3,"domain":"example.com","tenant_id":"acme"}`)
+	if rec.Code != http.StatusAccepted || len(queue.tasks) != 1 || queue.tasks[0].TenantID != "acme" {
+		t.Errorf("POST /api/v1/tasks for the own tenant = %d (%s), enqueued %+v", rec.Code, rec.Body.String(), queue.tasks)
+	}
+}
+
+func TestServer_SubmitTask(t *testing.T) {
+	server, queue, _ := newTestServer(t)
+
+	rec := doRequest(server, "POST", "/api/v1/tasks", operatorToken, `{"task":"subfinder","scan_id":3,"domain":"example.com"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST /api/v1/tasks = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if len(queue.tasks) != 1 || queue.tasks[0].Domain != "example.com" || queue.tasks[0].CorrelationID == "" {
+		t.Errorf("Expected the task enqueued with a correlation ID, got %+v", queue.tasks)
+	}
+
+	rec = doRequest(server, "POST", "/api/v1/tasks", operatorToken, `{"task":"portscan","scan_id":3,"domain":"example.com"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid task rejected, got %d", rec.Code)
+	}
+	if len(queue.tasks) != 1 {
+		t.Errorf("Expected the invalid task not enqueued, got %d tasks", len(queue.tasks))
+	}
+}
+
+func TestServer_FreezeListAndResults(t *testing.T) {
+	server, _, store := newTestServer(t)
+
+	rec := doRequest(server, "PUT", "/api/v1/freeze?tenant_id=acme", adminToken, `{"entries":[{"scope":"example.com","reason":"incident"}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PUT /api/v1/freeze = %d (%s)", rec.Code, rec.Body.String())
+	}
+	if list := store.freeze[models.FreezeListBlobPath("acme")]; list == nil || len(list.Entries) != 1 {
+		t.Errorf("Expected the tenant freeze list stored, got %+v", store.freeze)
+	}
+
+	// A list made on an older version would drop the entries added since, so it is rejected
+	rec = doRequest(server, "PUT", "/api/v1/freeze?tenant_id=acme", adminToken, `{"entries":[{"scope":"other.com"}]}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("Expected a stale freeze list rejected with 409, got %d", rec.Code)
+	}
+	rec = doRequest(server, "PUT", "/api/v1/freeze?tenant_id=acme", adminToken, `{"entries":[{"scope":"example.com"},{"scope":"other.com"}],"version":1}`)
+	if list := store.freeze[models.FreezeListBlobPath("acme")]; rec.Code != http.StatusOK || len(list.Entries) != 2 || list.Version != 2 {
+		t.Errorf("Expected the freeze list replaced as version 2, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(server, "PUT", "/api/v1/freeze", adminToken, `{"entries":[{"scope":"example.com","until":"tomorrow"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid expiry rejected, got %d", rec.Code)
+	}
+
+	prefix := models.ScanBlobPrefix("", "example.com", 5)
+	store.blobs[prefix+"subfinder/out/result.json"] = []byte(`{"subdomains":[]}`)
+	store.blobs[prefix+"subfinder/in/hosts.txt"] = []byte("example.com\n")
+
+	rec = doRequest(server, "GET", "/api/v1/scans/5/results?domain=example.com", viewerToken, "")
+	var listing struct {
+		Results []struct {
+			Path string `json:"path"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET results = %d (%s)", rec.Code, rec.Body.String())
+	}
+	if len(listing.Results) != 1 || !strings.Contains(listing.Results[0].Path, "/out/") {
+		t.Errorf("Expected only the output blob listed, got %+v", listing.Results)
+	}
+
+	rec = doRequest(server, "GET", "/api/v1/results?path="+prefix+"subfinder/in/hosts.txt", viewerToken, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected input blobs refused, got %d", rec.Code)
+	}
+}
+
+func TestServer_ScanStatus(t *testing.T) {
+	server, _, store := newTestServer(t)
+	path := "/scans/12/status?tenant_id=acme&token="
+	token := StatusToken([]byte("status-secret"), "acme", 12)
+
+	if rec := doRequest(server, "GET", path+token, "", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the status endpoint disabled without a secret, got %d", rec.Code)
+	}
+
+	server.SetStatusTokens("status-secret", 3)
+	if rec := doRequest(server, "GET", path+StatusToken([]byte("status-secret"), "acme", 13), "", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected the token of another scan rejected, got %d", rec.Code)
+	}
+
+	rec := doRequest(server, "GET", path+token, "", "")
+	var status models.ScanStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || rec.Code != http.StatusOK || status.State != models.ScanStateQueued {
+		t.Errorf("Expected a queued scan before any stage started, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	store.status = &models.ScanStatus{ScanID: 12, TenantID: "acme", State: models.ScanStateRunning, Stage: "httpx", Percent: 40}
+	rec = doRequest(server, "GET", path+token, "", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || status.Stage != "httpx" || status.Percent != 40 {
+		t.Errorf("Expected the stored status, got %d (%s)", rec.Code, rec.Body.String())
+	}
+
+	rec = doRequest(server, "GET", path+token, "", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected the fourth request within a minute limited, got %d", rec.Code)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(60)
+	now := time.Now()
+
+	for i := 0; i < 60; i++ {
+		if allowed, _ := limiter.allow("10.0.0.1", now); !allowed {
+			t.Fatalf("Expected request %d within the burst allowed", i+1)
+		}
+	}
+	allowed, retryAfter := limiter.allow("10.0.0.1", now)
+	if allowed || retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("Expected the client limited for up to a second, got %t and %s", allowed, retryAfter)
+	}
+	if allowed, _ := limiter.allow("10.0.0.2", now); !allowed {
+		t.Error("Expected other clients unaffected")
+	}
+	if allowed, _ := limiter.allow("10.0.0.1", now.Add(time.Second)); !allowed {
+		t.Error("Expected a token refilled after a second")
+	}
+
+	limiter.allow("10.0.0.3", now.Add(3*time.Minute))
+	if _, ok := limiter.clients["10.0.0.1"]; ok {
+		t.Error("Expected refilled clients forgotten")
+	}
+}
+
+func TestServer_ScanEvents(t *testing.T) {
+	server, _, store := newTestServer(t)
+	bus := events.NewBus()
+	server.SetEventBus(bus)
+	store.status = &models.ScanStatus{ScanID: 4, State: models.ScanStateRunning, Stage: "dnsx", UpdatedAt: time.Now()}
+
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	req, _ := http.NewRequest("GET", httpServer.URL+"/api/v1/scans/4/events", nil)
+	req.Header.Set("Authorization", "Bearer "+viewerToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open the event stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() (string, string) {
+		var name, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read the event stream: %v", err)
+			}
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "":
+				return name, data
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	if name, data := readEvent(); name != "status" || !strings.Contains(data, `"stage":"dnsx"`) {
+		t.Errorf("Expected the stored status first, got %s %s", name, data)
+	}
+
+	bus.RecordStep(&models.TaskMessage{Task: models.TaskDNSResolve, ScanID: 5}, "task_started", nil, nil)
+	bus.RecordStep(&models.TaskMessage{Task: models.TaskDNSResolve, ScanID: 4}, "task_completed", nil, nil)
+	if name, data := readEvent(); name != "lifecycle" || !strings.Contains(data, `"step":"task_completed"`) {
+		t.Errorf("Expected only the lifecycle event of scan 4, got %s %s", name, data)
+	}
+}
+
+func TestServer_Inventory(t *testing.T) {
+	server, _, store := newTestServer(t)
+	lastWeek := time.Now().UTC().AddDate(0, 0, -7)
+	inventory := models.Inventory{Domain: "example.com", Assets: map[string]*models.InventoryAsset{
+		"www.example.com": {Type: "host", Confidence: models.ConfidenceAlive, FirstSeen: lastWeek.AddDate(0, -1, 0), LastSeen: time.Now().UTC()},
+		"new.example.com": {Type: "host", Confidence: models.ConfidencePassiveOnly, FirstSeen: lastWeek.Add(time.Hour), LastSeen: time.Now().UTC()},
+		"old.example.com": {Type: "host", Confidence: models.ConfidenceResolved, FirstSeen: lastWeek.AddDate(0, -2, 0), LastSeen: lastWeek.AddDate(0, -1, 0), InZone: true, MissedByEnumeration: true},
+	}}
+	store.blobs[models.InventoryBlobPath("", "example.com")], _ = json.Marshal(inventory)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"new.example.com", "old.example.com", "www.example.com"}},
+		{"&seen_after=" + lastWeek.Format(time.RFC3339), []string{"new.example.com"}},
+		{"&stale_before=" + lastWeek.Format(time.RFC3339), []string{"old.example.com"}},
+		{"&min_confidence=resolved", []string{"old.example.com", "www.example.com"}},
+		{"&missed=true", []string{"old.example.com"}},
+	}
+	for _, tt := range tests {
+		rec := doRequest(server, http.MethodGet, "/api/v1/inventory?domain=example.com"+tt.query, viewerToken, "")
+		var got models.Inventory
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &got) != nil {
+			t.Fatalf("%q: expected an inventory, got %d: %s", tt.query, rec.Code, rec.Body)
+		}
+		if len(got.Assets) != len(tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, got.Assets)
+		}
+		for _, name := range tt.want {
+			if got.Assets[name] == nil {
+				t.Errorf("%q: expected %s in %v", tt.query, name, got.Assets)
+			}
+		}
+	}
+
+	if rec := doRequest(server, http.MethodGet, "/api/v1/inventory?domain=example.com&min_confidence=maybe", viewerToken, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown confidence, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/api/v1/inventory?domain=other.com", viewerToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a domain without inventory, got %d", rec.Code)
+	}
+}
+
+func TestServer_DNSXRecords(t *testing.T) {
+	server, _, store := newTestServer(t)
+	prefix := models.TaskBlobPrefix("", "example.com", 12, string(models.TaskDNSResolve))
+
+	// A streamed result of 25 records in members of 10, with its index
+	var records bytes.Buffer
+	writer := utils.NewIndexedNDJSONWriter(&records, 10)
+	for i := 0; i < 25; i++ {
+		writer.Encode(models.DNSRecordLine{Host: fmt.Sprintf("host%02d.example.com", i), ResolutionInfo: models.ResolutionInfo{Status: models.DNSStatusResolved}})
+	}
+	writer.Close()
+	store.blobs[prefix+"artifacts/records-attempt-1.ndjson.gz"] = records.Bytes()
+	store.blobs[prefix+"artifacts/records-attempt-1.index.json"], _ = json.Marshal(writer.Index())
+	store.blobs[prefix+"out/attempt-1.json"], _ = json.Marshal(models.TaskResult{Data: models.DNSXResult{
+		Domain:       "example.com",
+		RecordsBlob:  prefix + "artifacts/records-attempt-1.ndjson.gz",
+		RecordsCount: 25,
+		RecordsIndex: prefix + "artifacts/records-attempt-1.index.json",
+	}})
+	store.blobs[prefix+"latest.json"], _ = json.Marshal(models.LatestResult{BlobPath: prefix + "out/attempt-1.json", Attempt: 1})
+
+	type page struct {
+		Total      int                    `json:"total"`
+		NextOffset *int                   `json:"next_offset"`
+		Records    []models.DNSRecordLine `json:"records"`
+	}
+	get := func(query string) page {
+		t.Helper()
+		rec := doRequest(server, http.MethodGet, "/api/v1/scans/12/dnsx?domain=example.com"+query, viewerToken, "")
+		var got page
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &got) != nil {
+			t.Fatalf("%q: expected a page, got %d: %s", query, rec.Code, rec.Body)
+		}
+		return got
+	}
+
+	// The page starts inside the second member and runs into the third
+	got := get("&offset=18&limit=5")
+	if got.Total != 25 || len(got.Records) != 5 || got.Records[0].Host != "host18.example.com" || got.Records[4].Host != "host22.example.com" {
+		t.Errorf("Unexpected page %+v", got)
+	}
+	if got.NextOffset == nil || *got.NextOffset != 23 {
+		t.Errorf("Expected the next page at 23, got %v", got.NextOffset)
+	}
+	if last := get("&offset=20&limit=10"); len(last.Records) != 5 || last.NextOffset != nil {
+		t.Errorf("Expected a last page of 5 records, got %+v", last)
+	}
+
+	// Results with inline records are paged by host
+	store.blobs[prefix+"out/attempt-1.json"], _ = json.Marshal(models.TaskResult{Data: models.DNSXResult{Domain: "example.com", Records: map[string]models.ResolutionInfo{
+		"b.example.com": {Status: models.DNSStatusResolved},
+		"a.example.com": {Status: models.DNSStatusNXDomain},
+	}}})
+	if inline := get("&limit=1"); inline.Total != 2 || len(inline.Records) != 1 || inline.Records[0].Host != "a.example.com" {
+		t.Errorf("Unexpected inline page %+v", inline)
+	}
+
+	if rec := doRequest(server, http.MethodGet, "/api/v1/scans/12/dnsx?domain=example.com&limit=5000", viewerToken, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a limit above the maximum, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/api/v1/scans/13/dnsx?domain=example.com", viewerToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a scan without a DNSX result, got %d", rec.Code)
+	}
+}
+
+func TestServer_ExportJobs(t *testing.T) {
+	server, _, store := newTestServer(t)
+	if rec := doRequest(server, http.MethodPost, "/api/v1/exports", viewerToken, `{"filter":{"domain":"example.com"}}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while export jobs are disabled, got %d", rec.Code)
+	}
+	server.SetExportJobs(context.Background(), 1, time.Hour)
+
+	for scanID, tasks := range map[int][]models.Task{3: {models.TaskSubfinder, models.TaskHttpx}, 4: {models.TaskSubfinder}} {
+		for _, task := range tasks {
+			prefix := models.TaskBlobPrefix("acme", "example.com", scanID, string(task))
+			store.blobs[prefix+"out/attempt-1.json"] = []byte(fmt.Sprintf("{\n  \"scan_id\": %d,\n  \"task\": %q\n}", scanID, task))
+			store.blobs[prefix+"latest.json"], _ = json.Marshal(models.LatestResult{BlobPath: prefix + "out/attempt-1.json", Attempt: 1})
+		}
+	}
+	// Another domain whose name starts like the exported one
+	store.blobs["acme/example.com-test.org-5/subfinder/latest.json"] = []byte(`{"blob_path":"acme/example.com-test.org-5/subfinder/out/attempt-1.json"}`)
+
+	body := `{"tenant_id":"acme","filter":{"domain":"example.com","tasks":["Subfinder"]}}`
+	rec := doRequest(server, http.MethodPost, "/api/v1/exports", viewerToken, body)
+	var created models.ExportJob
+	if rec.Code != http.StatusAccepted || json.Unmarshal(rec.Body.Bytes(), &created) != nil || created.Status != models.ExportJobPending || created.Format != models.ExportFormatNDJSON {
+		t.Fatalf("Expected a pending ndjson job, got %d: %s", rec.Code, rec.Body)
+	}
+	server.exports.wg.Wait()
+
+	var job struct {
+		models.ExportJob
+		DownloadURL string `json:"download_url"`
+	}
+	rec = doRequest(server, http.MethodGet, "/api/v1/exports/"+created.ID+"?tenant_id=acme", viewerToken, "")
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &job) != nil || job.Status != models.ExportJobCompleted || job.Results != 2 {
+		t.Fatalf("Expected a completed job with 2 results, got %d: %s", rec.Code, rec.Body)
+	}
+	if job.DownloadURL != "https://storage.example/acme/exports/"+created.ID+"/export.ndjson.gz?sig=test" {
+		t.Errorf("Unexpected download URL %s", job.DownloadURL)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(store.blobs[job.BlobPath]))
+	if err != nil {
+		t.Fatalf("Expected a gzipped bundle: %v", err)
+	}
+	lines, _ := io.ReadAll(gz)
+	if got := strings.Split(strings.TrimSpace(string(lines)), "\n"); len(got) != 2 || !strings.Contains(string(lines), `{"scan_id":4,"task":"subfinder"}`) {
+		t.Errorf("Expected one compact line per subfinder result, got %q", lines)
+	}
+
+	// Streamed DNSX results are exported with their records inline
+	prefix := models.TaskBlobPrefix("acme", "example.com", 4, string(models.TaskDNSResolve))
+	var records bytes.Buffer
+	recordsGz := gzip.NewWriter(&records)
+	recordsGz.Write([]byte(`{"host":"www.example.com","status":"resolved"}` + "\n"))
+	recordsGz.Close()
+	store.blobs[prefix+"artifacts/records-attempt-1.ndjson.gz"] = records.Bytes()
+	store.blobs[prefix+"out/attempt-1.json"], _ = json.Marshal(models.TaskResult{ScanID: 4, Data: models.DNSXResult{
+		Domain: "example.com", RecordsBlob: prefix + "artifacts/records-attempt-1.ndjson.gz", RecordsCount: 1,
+	}})
+	store.blobs[prefix+"latest.json"], _ = json.Marshal(models.LatestResult{BlobPath: prefix + "out/attempt-1.json", Attempt: 1})
+	rec = doRequest(server, http.MethodPost, "/api/v1/exports", viewerToken, `{"tenant_id":"acme","filter":{"domain":"example.com","tasks":["dns_resolve"]}}`)
+	if rec.Code != http.StatusAccepted || json.Unmarshal(rec.Body.Bytes(), &created) != nil {
+		t.Fatalf("Expected a DNSX export job, got %d: %s", rec.Code, rec.Body)
+	}
+	server.exports.wg.Wait()
+	gz, err = gzip.NewReader(bytes.NewReader(store.blobs[models.ExportBundleBlobPath("acme", created.ID, models.ExportFormatNDJSON)]))
+	if err != nil {
+		t.Fatalf("Expected a gzipped DNSX bundle: %v", err)
+	}
+	lines, _ = io.ReadAll(gz)
+	if !strings.Contains(string(lines), `"records":{"www.example.com":{`) {
+		t.Errorf("Expected the DNSX line to carry its records, got %q", lines)
+	}
+
+	if rec := doRequest(server, http.MethodGet, "/api/v1/exports/"+created.ID, viewerToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the job to be hidden from other tenants, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodPost, "/api/v1/exports", viewerToken, `{"format":"csv","filter":{"domain":"example.com"}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown format, got %d", rec.Code)
+	}
+}
+
+type fakeSearcher struct {
+	request search.Request
+}
+
+func (s *fakeSearcher) Search(ctx context.Context, request search.Request) (*search.Result, error) {
+	s.request = request
+	return &search.Result{
+		Total:  150,
+		Hits:   []json.RawMessage{[]byte(`{"host":"blog.corp.com"}`)},
+		Facets: map[string][]search.Facet{"technology": {{Value: "WordPress", Count: 150}}},
+	}, nil
+}
+
+func TestServer_Search(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	if rec := doRequest(server, http.MethodGet, "/api/v1/search?q=tech:wordpress", viewerToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without a searcher, got %d", rec.Code)
+	}
+
+	searcher := &fakeSearcher{}
+	server.SetSearcher(searcher)
+	rec := doRequest(server, http.MethodGet, "/api/v1/search?q=status:alive+tech:wordpress&tenant_id=acme&offset=100&limit=1", viewerToken, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"next_offset":101`) || !strings.Contains(rec.Body.String(), `"technology":[{"value":"WordPress","count":150}]`) {
+		t.Errorf("Expected a page with facets, got %d: %s", rec.Code, rec.Body)
+	}
+	if searcher.request.TenantID != "acme" || searcher.request.Offset != 100 || len(searcher.request.Query.Terms) != 2 {
+		t.Errorf("Unexpected search request: %+v", searcher.request)
+	}
+
+	if rec := doRequest(server, http.MethodGet, "/api/v1/search?q=port:https", viewerToken, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid query, got %d", rec.Code)
+	}
+	if rec := doRequest(server, http.MethodGet, "/api/v1/search?q=tech:wordpress&offset=9950&limit=100", viewerToken, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 past the result window, got %d", rec.Code)
+	}
+}
+
+func TestServer_ScopeSuggestions(t *testing.T) {
+	server, _, store := newTestServer(t)
+	suggestions := models.ScopeSuggestions{Domain: "example.com"}
+	suggestions.Merge([]models.ScopeSuggestion{
+		{Apex: "example.net", Evidence: []models.ScopeEvidence{{Kind: models.ScopeEvidenceCertificateSAN}}},
+		{Apex: "example.org", Evidence: []models.ScopeEvidence{{Kind: models.ScopeEvidenceCertificateSAN}, {Kind: models.ScopeEvidenceWhoisRegistrant}}},
+	}, time.Now())
+	store.blobs[models.ScopeSuggestionsBlobPath("", "example.com")], _ = json.Marshal(suggestions)
+
+	// Reviewing scope is control state and needs an admin
+	if rec := doRequest(server, http.MethodPost, "/api/v1/scope/suggestions/example.org/approve?domain=example.com", operatorToken, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected operators to be denied, got %d", rec.Code)
+	}
+	rec := doRequest(server, http.MethodPost, "/api/v1/scope/suggestions/example.org/approve?domain=example.com", adminToken, "")
+	var reviewed models.ScopeSuggestion
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &reviewed) != nil || reviewed.Status != models.ScopeSuggestionApproved || reviewed.DecidedBy != "root" {
+		t.Fatalf("Expected the suggestion to be approved by root, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(server, http.MethodPost, "/api/v1/scope/suggestions/unknown.com/reject?domain=example.com", adminToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an apex that was never suggested, got %d", rec.Code)
+	}
+
+	var listed struct {
+		Suggestions []models.ScopeSuggestion `json:"suggestions"`
+	}
+	rec = doRequest(server, http.MethodGet, "/api/v1/scope/suggestions?domain=example.com&status=pending", viewerToken, "")
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &listed) != nil || len(listed.Suggestions) != 1 || listed.Suggestions[0].Apex != "example.net" {
+		t.Errorf("Expected only example.net to be pending, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = doRequest(server, http.MethodGet, "/api/v1/scope/suggestions?domain=other.com", viewerToken, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"suggestions":[]`) {
+		t.Errorf("Expected no suggestions for another domain, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServer_Version(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	rec := doRequest(server, http.MethodGet, "/version", "", "")
+	var build models.BuildInfo
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &build) != nil || build.Version == "" {
+		t.Fatalf("Expected the build info without a token, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestServer_ScanQuality(t *testing.T) {
+	server, _, store := newTestServer(t)
+	if rec := doRequest(server, http.MethodGet, "/api/v1/scans/7/quality", viewerToken, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a scan without checks, got %d", rec.Code)
+	}
+
+	quality := models.ScanQuality{ScanID: 7}
+	quality.Record(models.QualityCheck{Gate: models.QualityGateAliveRatio, Task: models.TaskHttpx, Domain: "example.com", Value: 0.1, Threshold: 0.5, CheckedAt: time.Now().UTC()})
+	store.blobs[models.ScanQualityBlobPath("", 7)], _ = json.Marshal(quality)
+
+	rec := doRequest(server, http.MethodGet, "/api/v1/scans/7/quality", viewerToken, "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"degraded":true`) {
+		t.Fatalf("Expected the scan to be degraded, got %d: %s", rec.Code, rec.Body)
+	}
+
+	// Releasing held stages starts scans and needs an operator
+	if rec := doRequest(server, http.MethodPost, "/api/v1/scans/7/quality/review", viewerToken, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected viewers to be denied, got %d", rec.Code)
+	}
+	rec = doRequest(server, http.MethodPost, "/api/v1/scans/7/quality/review", operatorToken, "")
+	var reviewed models.ScanQuality
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &reviewed) != nil || reviewed.Degraded() || reviewed.ReviewedBy != "ops" {
+		t.Fatalf("Expected the scan to be reviewed by ops, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := doRequest(server, http.MethodPost, "/api/v1/scans/7/quality/review", operatorToken, ""); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a scan that is not degraded, got %d", rec.Code)
+	}
+}
+
+func TestServer_Webhook(t *testing.T) {
+	server, queue, store := newTestServer(t)
+	secret := []byte("webhook-secret")
+	body := `{"source":"ci","domain":"example.com","scan_id":9,"tasks":["subfinder","dns_resolve"]}`
+
+	signedRequest := func(body string, signedAt time.Time, signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhooks/tasks", strings.NewReader(body))
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+		if signature == "" {
+			signature = WebhookSignature(secret, signedAt.Unix(), []byte(body))
+		}
+		req.Header.Set(WebhookSignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := signedRequest(body, time.Now(), ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the webhook endpoint disabled without a secret, got %d", rec.Code)
+	}
+
+	server.SetWebhookSecret(string(secret))
+	if rec := signedRequest(body, time.Now(), "sha256=00"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a bad signature rejected, got %d", rec.Code)
+	}
+	if rec := signedRequest(body, time.Now().Add(-time.Hour), ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a stale call rejected, got %d", rec.Code)
+	}
+
+	signedAt := time.Now()
+	rec := signedRequest(body, signedAt, "")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("POST /webhooks/tasks = %d, want %d (%s)", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+	if len(queue.tasks) != 2 || queue.tasks[0].Task != models.TaskSubfinder || queue.tasks[1].Task != models.TaskDNSResolve ||
+		queue.tasks[1].ScanID != 9 || queue.tasks[1].CorrelationID == "" {
+		t.Errorf("Expected both tasks of the scan enqueued, got %+v", queue.tasks)
+	}
+
+	if rec := signedRequest(body, signedAt, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a replayed call rejected, got %d", rec.Code)
+	}
+	if rec := signedRequest(`{"domain":"not a domain"}`, time.Now(), ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid domain rejected, got %d", rec.Code)
+	}
+	if len(queue.tasks) != 2 {
+		t.Errorf("Expected rejected calls not enqueued, got %d tasks", len(queue.tasks))
+	}
+
+	// Replays are recorded in the store, so a restarted server rejects them too
+	restarted := NewServer(queue, store, server.auth)
+	restarted.SetWebhookSecret(string(secret))
+	server = restarted
+	if rec := signedRequest(body, signedAt, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a replayed call rejected after a restart, got %d", rec.Code)
+	}
+}
+
+func TestServer_WebhookRetryAfterPartialEnqueue(t *testing.T) {
+	server, queue, _ := newTestServer(t)
+	secret := []byte("webhook-secret")
+	server.SetWebhookSecret(string(secret))
+	body := `{"source":"ci","domain":"example.com","tasks":["subfinder","dns_resolve","port_scan"]}`
+	signedAt := time.Now()
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/webhooks/tasks", strings.NewReader(body))
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+		req.Header.Set(WebhookSignatureHeader, WebhookSignature(secret, signedAt.Unix(), []byte(body)))
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	queue.fail = 1
+	if rec := send(); rec.Code != http.StatusBadGateway {
+		t.Fatalf("Expected the failed enqueue reported, got %d", rec.Code)
+	}
+	first := queue.tasks[0]
+
+	rec := send()
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected the retried call accepted, got %d (%s)", rec.Code, rec.Body)
+	}
+	if len(queue.tasks) != 3 || queue.tasks[1].Task != models.TaskDNSResolve || queue.tasks[2].Task != models.TaskNaabu {
+		t.Fatalf("Expected only the missing tasks queued on retry, got %+v", queue.tasks)
+	}
+	if queue.tasks[2].ScanID != first.ScanID {
+		t.Errorf("Expected the retry to keep scan %d, got %d", first.ScanID, queue.tasks[2].ScanID)
+	}
+	var response struct {
+		CorrelationIDs []string `json:"correlation_ids"`
+	}
+	if json.Unmarshal(rec.Body.Bytes(), &response) != nil || len(response.CorrelationIDs) != 3 || response.CorrelationIDs[0] != first.CorrelationID {
+		t.Errorf("Expected the correlation IDs of the first attempt, got %s", rec.Body)
+	}
+
+	if rec := send(); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a completed call rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// StatusToken returns the token that opens the status endpoint of a scan: the hex HMAC-SHA256 of
+// "<tenant_id>/<scan_id>" keyed with the shared secret, so the orchestrator can hand out status
+// links without asking the worker
+func StatusToken(secret []byte, tenantID string, scanID int) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(tenantID + "/" + strconv.Itoa(scanID)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetStatusTokens enables the public status endpoint for tokens signed with secret, allowing each
+// client perMinute requests
+func (s *Server) SetStatusTokens(secret string, perMinute int) {
+	s.statusSecret = []byte(secret)
+	s.statusLimiter = newRateLimiter(perMinute)
+}
+
+// handleScanStatus returns the coarse status of a scan to holders of its status token
+func (s *Server) handleScanStatus(w http.ResponseWriter, r *http.Request) {
+	if len(s.statusSecret) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "no-store")
+
+	if allowed, retryAfter := s.statusLimiter.allow(clientAddress(r), time.Now()); !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "too many status requests")
+		return
+	}
+
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+	expected := StatusToken(s.statusSecret, tenantID, scanID)
+	if !hmac.Equal([]byte(r.URL.Query().Get("token")), []byte(expected)) {
+		writeError(w, http.StatusForbidden, "invalid status token")
+		return
+	}
+
+	status, err := s.store.LoadScanStatus(r.Context(), tenantID, scanID)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "scan status is unavailable")
+		return
+	}
+	if status == nil {
+		status = &models.ScanStatus{ScanID: scanID, TenantID: tenantID, State: models.ScanStateQueued}
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// clientAddress returns the address rate limits apply to. Behind the ingress, the last
+// X-Forwarded-For entry is the one the ingress itself appended, so clients cannot spoof it.
+func clientAddress(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		entries := strings.Split(forwarded, ",")
+		return strings.TrimSpace(entries[len(entries)-1])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimiter is a token bucket per client that holds up to a minute of requests
+type rateLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	clients   map[string]*clientBucket
+	swept     time.Time
+}
+
+// clientBucket is the token bucket of one client
+type clientBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRateLimiter creates a limiter that allows perMinute requests per client
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{
+		perSecond: float64(perMinute) / 60,
+		burst:     float64(perMinute),
+		clients:   make(map[string]*clientBucket),
+	}
+}
+
+// allow takes a token from the client's bucket, or returns how long until one is available
+func (l *rateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &clientBucket{tokens: l.burst, updated: now}
+		l.clients[client] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.perSecond)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.perSecond * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// sweep forgets clients whose buckets have refilled, at most once a minute
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+
+	refill := time.Duration(l.burst / l.perSecond * float64(time.Second))
+	for client, bucket := range l.clients {
+		if now.Sub(bucket.updated) >= refill {
+			delete(l.clients, client)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	// streamBuffer is the number of events a slow stream client can fall behind before missing events
+	streamBuffer = 256
+	// streamStatusInterval is how often a stream checks the stored scan status, which also covers
+	// tasks running on other workers, and keeps idle connections alive
+	streamStatusInterval = 15 * time.Second
+)
+
+// SetEventBus enables the live event stream of scans
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// handleScanEvents streams the lifecycle and progress events of a scan as server-sent events.
+// Events of tasks on this worker arrive as they happen; the stored scan status is sent when the
+// stream opens and whenever it changes, so stages running on other workers show up as well.
+func (s *Server) handleScanEvents(w http.ResponseWriter, r *http.Request) {
+	if s.events == nil {
+		writeError(w, http.StatusNotFound, "event streaming is disabled")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	scanID, tenantID, ok := s.scanParams(w, r)
+	if !ok {
+		return
+	}
+
+	sub := s.events.Subscribe(streamBuffer, func(event events.Event) bool {
+		return event.ScanID == scanID && event.TenantID == tenantID
+	})
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(streamStatusInterval)
+	defer ticker.Stop()
+
+	var lastStatus time.Time
+	sendStatus := func() error {
+		status, err := s.store.LoadScanStatus(r.Context(), tenantID, scanID)
+		if err != nil || status == nil || !status.UpdatedAt.After(lastStatus) {
+			// Keep idle connections from being closed by proxies
+			_, err := fmt.Fprint(w, ": keepalive\n\n")
+			return err
+		}
+		lastStatus = status.UpdatedAt
+		return writeEvent(w, "status", status)
+	}
+
+	if err := sendStatus(); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	var dropped int64
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case event, open := <-sub.Events():
+			if !open {
+				return
+			}
+			err = writeEvent(w, string(event.Type), event)
+		case <-ticker.C:
+			err = sendStatus()
+		}
+		if err != nil {
+			gologger.Debug().Msgf("Event stream of scan %d closed: %v", scanID, err)
+			return
+		}
+		flusher.Flush()
+
+		if missed := sub.Dropped(); missed > dropped {
+			gologger.Warning().Msgf("Event stream of scan %d fell behind and missed %d events", scanID, missed-dropped)
+			dropped = missed
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
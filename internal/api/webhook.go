@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Headers of a signed webhook call
+const (
+	WebhookSignatureHeader = "X-ASM-Signature" // "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+	WebhookTimestampHeader = "X-ASM-Timestamp" // Unix seconds the call was signed at
+)
+
+// webhookTolerance is how far the signing time of a webhook call may be from the worker's clock.
+// Signatures seen within it are recorded in blob storage, so a captured call cannot be replayed.
+const webhookTolerance = 5 * time.Minute
+
+// webhookClaimLease is how long a replica queuing the tasks of a call keeps others from doing the
+// same; a replica that died mid-call loses its claim after it
+const webhookClaimLease = time.Minute
+
+// Errors of claiming a webhook call
+var (
+	errWebhookReceived = errors.New("webhook call was already received")
+	errWebhookClaimed  = errors.New("webhook call is being queued by another request")
+)
+
+// maxWebhookTasks bounds the tasks one webhook call can queue
+const maxWebhookTasks = 10
+
+// WebhookEvent is a signed call from a deployment pipeline or CMDB asking for a domain to be scanned
+type WebhookEvent struct {
+	Source     string                 `json:"source,omitempty"` // Caller, e.g. "github-actions"; shown in the audit log
+	TenantID   string                 `json:"tenant_id,omitempty"`
+	Domain     string                 `json:"domain"`
+	ScanID     int                    `json:"scan_id,omitempty"` // Defaults to the Unix time the call was received
+	InstanceID string                 `json:"instance_id,omitempty"`
+	Tasks      []models.Task          `json:"tasks,omitempty"` // Defaults to subfinder
+	Config     map[string]interface{} `json:"config,omitempty"`
+}
+
+// WebhookSignature returns the signature header value of a webhook body signed at timestamp
+func WebhookSignature(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SetWebhookSecret enables the webhook endpoint for calls signed with secret
+func (s *Server) SetWebhookSecret(secret string) {
+	s.webhookSecret = []byte(secret)
+}
+
+// handleWebhook verifies a signed webhook call and queues the tasks it asks for
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if len(s.webhookSecret) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+
+	now := time.Now()
+	if err := s.verifyWebhook(r, body, now); err != nil {
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var event WebhookEvent
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	taskMsgs, err := s.webhookTasks(&event, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source := event.Source
+	if source == "" {
+		source = "unnamed"
+	}
+	r = r.WithContext(context.WithValue(r.Context(), principalKey{}, &Principal{Name: "webhook:" + source, Role: RoleOperator}))
+
+	signature := r.Header.Get(WebhookSignatureHeader)
+	call, err := s.claimWebhookCall(r.Context(), signature, taskMsgs, now)
+	switch {
+	case errors.Is(err, errWebhookReceived):
+		writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	case errors.Is(err, errWebhookClaimed):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	// Tasks queued by an earlier attempt of the same call are not queued again
+	for i := call.Queued; i < len(taskMsgs); i++ {
+		taskMsg := taskMsgs[i]
+		if err := s.queue.EnqueueTask(r.Context(), taskMsg); err != nil {
+			s.recordWebhookProgress(r.Context(), signature, i, true)
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		s.audit(r, "submitted %s for scan %d of %s", taskMsg.Task, taskMsg.ScanID, taskMsg.Domain)
+		s.recordWebhookProgress(r.Context(), signature, i+1, i+1 == len(taskMsgs))
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"scan_id": call.ScanID, "correlation_ids": call.CorrelationIDs})
+}
+
+// claimWebhookCall records the call of a signature, or claims it again when an earlier attempt
+// failed to queue all its tasks. The tasks take the scan and correlation IDs of the first attempt.
+func (s *Server) claimWebhookCall(ctx context.Context, signature string, taskMsgs []*models.TaskMessage, now time.Time) (*models.WebhookCall, error) {
+	var claimed models.WebhookCall
+	err := s.store.UpdateWebhookCall(ctx, signature, func(call *models.WebhookCall) error {
+		if call.Complete() {
+			return errWebhookReceived
+		}
+		if !call.ClaimedAt.IsZero() && now.Sub(call.ClaimedAt) < webhookClaimLease {
+			return errWebhookClaimed
+		}
+		if call.ReceivedAt.IsZero() {
+			call.ReceivedAt = now
+			call.ScanID = taskMsgs[0].ScanID
+			for _, taskMsg := range taskMsgs {
+				call.CorrelationIDs = append(call.CorrelationIDs, taskMsg.CorrelationID)
+			}
+		}
+		call.ClaimedAt = now
+		claimed = *call
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, taskMsg := range taskMsgs {
+		taskMsg.ScanID = claimed.ScanID
+		if i < len(claimed.CorrelationIDs) {
+			taskMsg.CorrelationID = claimed.CorrelationIDs[i]
+		}
+	}
+	return &claimed, nil
+}
+
+// recordWebhookProgress records how many tasks of a call are queued, so that a retry of the call
+// skips them. Releasing the claim lets the caller retry at once after a failed enqueue.
+func (s *Server) recordWebhookProgress(ctx context.Context, signature string, queued int, release bool) {
+	err := s.store.UpdateWebhookCall(context.WithoutCancel(ctx), signature, func(call *models.WebhookCall) error {
+		call.Queued = max(call.Queued, queued)
+		if release {
+			call.ClaimedAt = time.Time{}
+		}
+		return nil
+	})
+	if err != nil {
+		gologger.Warning().Msgf("Failed to record that %d tasks of a webhook call were queued: %v", queued, err)
+	}
+}
+
+// verifyWebhook checks the signature and signing time of a webhook call; replays are rejected
+// when the call is claimed
+func (s *Server) verifyWebhook(r *http.Request, body []byte, now time.Time) error {
+	timestamp, err := strconv.ParseInt(r.Header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return errors.New("missing or invalid " + WebhookTimestampHeader + " header")
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > webhookTolerance || skew < -webhookTolerance {
+		return errors.New("webhook timestamp is outside the allowed window")
+	}
+
+	signature := r.Header.Get(WebhookSignatureHeader)
+	if !hmac.Equal([]byte(signature), []byte(WebhookSignature(s.webhookSecret, timestamp, body))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
+
+// webhookTasks converts a webhook event into validated task messages of one scan
+func (s *Server) webhookTasks(event *WebhookEvent, now time.Time) ([]*models.TaskMessage, error) {
+	tasks := event.Tasks
+	if len(tasks) == 0 {
+		tasks = []models.Task{models.TaskSubfinder}
+	}
+	if len(tasks) > maxWebhookTasks {
+		return nil, errors.New("a webhook call can queue at most " + strconv.Itoa(maxWebhookTasks) + " tasks")
+	}
+	scanID := event.ScanID
+	if scanID == 0 {
+		scanID = int(now.Unix())
+	}
+
+	taskMsgs := make([]*models.TaskMessage, 0, len(tasks))
+	for _, task := range tasks {
+		taskMsg := &models.TaskMessage{
+			Task:          models.Task(strings.ToLower(string(task))),
+			ScanID:        scanID,
+			Domain:        event.Domain,
+			TenantID:      event.TenantID,
+			InstanceID:    event.InstanceID,
+			Config:        event.Config,
+			CorrelationID: models.NewCorrelationID(),
+		}
+		if err := s.validator.ValidateTaskMessage(taskMsg); err != nil {
+			return nil, err
+		}
+		taskMsgs = append(taskMsgs, taskMsg)
+	}
+	return taskMsgs, nil
+}
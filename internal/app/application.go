@@ -2,27 +2,75 @@ package app
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/allsafeASM/api/hooks"
+	"github.com/allsafeASM/api/internal/api"
 	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/buildinfo"
+	"github.com/allsafeASM/api/internal/capacity"
 	"github.com/allsafeASM/api/internal/config"
+	"github.com/allsafeASM/api/internal/connectors"
+	"github.com/allsafeASM/api/internal/devqueue"
+	"github.com/allsafeASM/api/internal/disk"
+	"github.com/allsafeASM/api/internal/exporters"
+	"github.com/allsafeASM/api/internal/faults"
 	"github.com/allsafeASM/api/internal/handlers"
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/allsafeASM/api/internal/models"
 	"github.com/allsafeASM/api/internal/notification"
+	"github.com/allsafeASM/api/internal/quota"
+	"github.com/allsafeASM/api/internal/redaction"
+	"github.com/allsafeASM/api/internal/review"
+	"github.com/allsafeASM/api/internal/scanners"
+	"github.com/allsafeASM/api/internal/schedule"
+	"github.com/allsafeASM/api/internal/search"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/allsafeASM/api/internal/validation"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/levels"
+	"golang.org/x/exp/maps"
 )
 
+// TaskSource delivers task messages to the task handler and accepts new tasks. Service Bus is the
+// task source in production; DEV_MODE uses a queue in a local directory.
+type TaskSource interface {
+	api.TaskQueue
+	metrics.QueueDepthReader
+	HealthCheck(ctx context.Context) error
+	ProcessMessages(ctx context.Context, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, pollInterval time.Duration, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration, scannerTimeout time.Duration) error
+	Close(ctx context.Context) error
+}
+
 // Application represents the main application structure
 type Application struct {
 	config           *config.Config
-	serviceBusClient *azure.ServiceBusClient
+	taskSource       TaskSource
+	serviceBusClient *azure.ServiceBusClient // nil in DEV_MODE
 	blobClient       *azure.BlobStorageClient
+	faults           *faults.Injector // nil unless FAULT_INJECTION is set
 	taskHandler      *handlers.TaskHandler
+	findingRouter    *notification.FindingRouter
+	outbox           *notification.Outbox // nil unless notifications are enabled
+	splunkExporter   *exporters.SplunkExporter
+	statusExporter   *exporters.ScanStatusExporter
+	resultPublisher  azure.ResultPublisher
+	metricsServer    *http.Server
+	queueMonitor     *metrics.QueueMonitor
+	diskJanitor      *disk.Janitor
+	apiServer        *http.Server
+	scopeSyncer      *connectors.Syncer      // nil unless scope sync is configured
+	versionChecker   *buildinfo.DriftChecker // nil unless VERSION_MANIFEST_URL is set
 	ctx              context.Context
 	cancel           context.CancelFunc
 }
@@ -49,6 +97,13 @@ func (app *Application) initialize() error {
 	// Initialize logging
 	app.setupLogging(app.config.App.LogLevel)
 
+	app.faults = faults.New(app.config.Faults.Settings())
+	if app.faults != nil {
+		f := app.config.Faults
+		gologger.Warning().Msgf("FAULT_INJECTION: delaying %d%% of blob operations by up to %dms, failing %d%% of lock renewals, dropping %d%% of notifications",
+			f.BlobDelayPercent, f.BlobDelayMaxMs, f.LockRenewalFailurePercent, f.NotificationDropPercent)
+	}
+
 	// Initialize Azure clients
 	if err := app.initializeAzureClients(); err != nil {
 		return err
@@ -85,24 +140,19 @@ func (app *Application) setupLogging(logLevel string) {
 	}
 }
 
-// initializeAzureClients creates Azure Service Bus and Blob Storage clients
+// initializeAzureClients creates the task source and the Blob Storage client
 func (app *Application) initializeAzureClients() error {
-	var err error
-
-	// Initialize Service Bus client
-	app.serviceBusClient, err = azure.NewServiceBusClient(
-		app.config.Azure.ServiceBusConnectionString,
-		app.config.Azure.QueueName,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to initialize Service Bus client: %w", err)
+	if err := app.initializeTaskSource(); err != nil {
+		return err
 	}
 
-	// Perform a health check on the Service Bus connection
-	if err := app.serviceBusClient.HealthCheck(context.Background()); err != nil {
-		gologger.Warning().Msgf("Service Bus health check failed: %v", err)
+	// Perform a health check on the task source
+	if err := app.taskSource.HealthCheck(context.Background()); err != nil {
+		gologger.Warning().Msgf("Task source health check failed: %v", err)
 	}
 
+	var err error
+
 	// Initialize Blob Storage client
 	app.blobClient, err = azure.NewBlobStorageClient(
 		app.config.Azure.BlobStorageConnectionString,
@@ -111,10 +161,95 @@ func (app *Application) initializeAzureClients() error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize Blob Storage client: %w", err)
 	}
+	app.blobClient.SetResultSizeLimit(app.config.Azure.MaxResultSizeMB*1024*1024, app.config.Azure.ResultSummarySamples)
+	if err := app.configureDataRegions(); err != nil {
+		return err
+	}
+
+	// Results are only uploaded when a scan finishes, so storage problems must surface now
+	if err := app.blobClient.ValidateStorage(context.Background(), app.config.Azure.CreateBlobContainer); err != nil {
+		return fmt.Errorf("blob storage check failed: %w", err)
+	}
+	app.blobClient.SetFaultInjector(app.faults)
 
 	return nil
 }
 
+// configureDataRegions adds the storage accounts of the other data regions and routes tenants to them
+func (app *Application) configureDataRegions() error {
+	azureConfig := app.config.Azure
+	app.blobClient.SetDefaultRegion(azureConfig.BlobStorageRegion)
+	for region, connectionString := range azureConfig.BlobStorageRegions {
+		if err := app.blobClient.AddRegion(region, connectionString); err != nil {
+			return err
+		}
+	}
+
+	routes, err := models.ParseTenantRegions(azureConfig.BlobTenantRegions)
+	if err != nil {
+		return fmt.Errorf("invalid BLOB_TENANT_REGIONS: %w", err)
+	}
+	if err := app.blobClient.SetTenantRegions(routes); err != nil {
+		return err
+	}
+	if len(routes) > 0 {
+		gologger.Info().Msgf("Routing %d tenants across storage regions %s", len(routes), strings.Join(app.blobClient.Regions(), ", "))
+	}
+	return nil
+}
+
+// initializeTaskSource connects to Service Bus, or opens the local directory queue in DEV_MODE
+func (app *Application) initializeTaskSource() error {
+	if app.config.Azure.DevMode {
+		queue, err := devqueue.NewFileQueue(app.config.Azure.DevQueueDir)
+		if err != nil {
+			return fmt.Errorf("failed to open dev queue: %w", err)
+		}
+		queue.SetMaxMessageSize(app.config.Azure.MaxMessageSizeKB << 10)
+		app.taskSource = queue
+		gologger.Warning().Msgf("DEV_MODE: reading tasks from %s instead of Service Bus", app.config.Azure.DevQueueDir)
+		return nil
+	}
+
+	serviceBusClient, err := azure.NewServiceBusClient(
+		app.config.Azure.ServiceBusConnectionString,
+		app.config.Azure.QueueName,
+		app.config.Azure.Subscription,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Service Bus client: %w", err)
+	}
+	serviceBusClient.SetFaultInjector(app.faults)
+	serviceBusClient.SetPrefetchCount(app.config.Azure.PrefetchCount)
+	serviceBusClient.SetMaxMessageSize(app.config.Azure.MaxMessageSizeKB << 10)
+	app.serviceBusClient = serviceBusClient
+	app.taskSource = serviceBusClient
+
+	for _, warning := range app.config.LockWarnings() {
+		gologger.Warning().Msg(warning)
+	}
+	app.checkLockDuration()
+	return nil
+}
+
+// checkLockDuration warns when the lock duration of the queue differs from SERVICEBUS_LOCK_DURATION,
+// which the lock renewal interval was validated against
+func (app *Application) checkLockDuration() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	actual, err := app.serviceBusClient.LockDuration(ctx)
+	if err != nil {
+		gologger.Debug().Msgf("Could not read the lock duration of the queue: %v", err)
+		return
+	}
+	expected := time.Duration(app.config.Azure.LockDuration) * time.Second
+	if actual != expected {
+		gologger.Warning().Msgf("Queue lock duration is %s but SERVICEBUS_LOCK_DURATION expects %s; locks renewed every %ds may expire",
+			actual, expected, app.config.App.LockRenewalInterval)
+	}
+}
+
 // initializeTaskHandler creates the task handler with all dependencies
 func (app *Application) initializeTaskHandler() error {
 	scannerTimeout := time.Duration(app.config.App.ScannerTimeout) * time.Second
@@ -130,6 +265,27 @@ func (app *Application) initializeTaskHandler() error {
 		gologger.Warning().Msgf("Failed to initialize Discord notification service: %v. Discord notifications will be disabled.", err)
 	}
 
+	if notifier != nil {
+		notifier.SetPayloadDetail(app.config.App.NotificationDetail)
+	}
+
+	if discordNotifier != nil {
+		locale, err := notification.ParseLocale(app.config.App.NotificationLocale, app.config.App.NotificationTimezone)
+		if err != nil {
+			return fmt.Errorf("failed to parse notification locale: %w", err)
+		}
+		discordNotifier.SetLocale(locale)
+	}
+
+	if app.faults != nil {
+		if notifier != nil {
+			notifier.SetFaultInjector(app.faults)
+		}
+		if discordNotifier != nil {
+			discordNotifier.SetFaultInjector(app.faults)
+		}
+	}
+
 	app.taskHandler = handlers.NewTaskHandler(
 		app.blobClient,
 		scannerTimeout,
@@ -137,14 +293,504 @@ func (app *Application) initializeTaskHandler() error {
 		discordNotifier,
 	)
 
+	// Completion notifications are stored with the result and delivered after the message completes
+	if notifier != nil && app.blobClient != nil {
+		app.outbox = notification.NewOutbox(app.blobClient, notifier, time.Duration(app.config.App.OutboxSweepInterval)*time.Second)
+		app.taskHandler.SetOutbox(app.outbox)
+	}
+
+	pipeline, err := hooks.New(hooks.ParseNames(app.config.App.Hooks))
+	if err != nil {
+		return fmt.Errorf("failed to set up hooks: %w", err)
+	}
+	if pipeline != nil {
+		gologger.Info().Msgf("Running hooks: %s", strings.Join(pipeline.Names(), ", "))
+		app.taskHandler.SetHooks(pipeline)
+	}
+
+	// The task type that ends a scan, for the digest and the scan status
+	finalTasks, err := validation.NewValidator().ParseTaskTypes(app.config.App.ScanDigestAfter)
+	if err != nil {
+		return fmt.Errorf("failed to parse scan digest task: %w", err)
+	}
+	var finalTask models.Task
+	if len(finalTasks) > 0 {
+		finalTask = finalTasks[0]
+	}
+
+	webhookTimeout := time.Duration(app.config.App.DiscordWebhookTimeout) * time.Second
+	app.findingRouter = notification.NewConfiguredFindingRouter(app.config.App.FindingAlertMinSeverity, webhookTimeout)
+	if app.findingRouter != nil {
+		app.taskHandler.SetFindingRouter(app.findingRouter)
+	}
+	if app.blobClient != nil {
+		if incidents := notification.NewConfiguredIncidentNotifier(app.blobClient, webhookTimeout); incidents != nil {
+			app.taskHandler.SetIncidentNotifier(incidents)
+		}
+		if tickets := notification.NewConfiguredTicketNotifier(app.blobClient, webhookTimeout); tickets != nil {
+			app.taskHandler.SetTicketNotifier(tickets)
+		}
+		if app.config.App.InventoryTracking {
+			app.taskHandler.EnableInventory()
+		}
+		if app.config.App.ScanDigest {
+			app.taskHandler.EnableScanDigest(finalTask)
+		}
+	}
+
+	exportTimeout := time.Duration(app.config.Export.Timeout) * time.Second
+	if app.config.Export.ElasticsearchURL != "" {
+		app.taskHandler.AddResultExporter(exporters.NewElasticsearchExporter(exporters.ElasticsearchConfig{
+			URL:         app.config.Export.ElasticsearchURL,
+			APIKey:      app.config.Export.ElasticsearchAPIKey,
+			Username:    app.config.Export.ElasticsearchUsername,
+			Password:    app.config.Export.ElasticsearchPassword,
+			IndexPrefix: app.config.Export.ElasticsearchIndexPrefix,
+		}, exportTimeout))
+		gologger.Info().Msgf("Exporting results to Elasticsearch at %s", app.config.Export.ElasticsearchURL)
+	}
+	if app.config.Export.LogAnalyticsEndpoint != "" {
+		exporter, err := exporters.NewLogAnalyticsExporter(exporters.LogAnalyticsConfig{
+			Endpoint: app.config.Export.LogAnalyticsEndpoint,
+			RuleID:   app.config.Export.LogAnalyticsRuleID,
+			Stream:   app.config.Export.LogAnalyticsStream,
+		}, exportTimeout)
+		if err != nil {
+			gologger.Warning().Msgf("Failed to initialize Log Analytics export: %v. Log Analytics export will be disabled.", err)
+		} else {
+			app.taskHandler.AddResultExporter(exporter)
+			gologger.Info().Msgf("Exporting results to Log Analytics stream %s", app.config.Export.LogAnalyticsStream)
+		}
+	}
+	if app.config.Export.SplunkHECURL != "" {
+		app.splunkExporter = exporters.NewSplunkExporter(exporters.SplunkConfig{
+			URL:        app.config.Export.SplunkHECURL,
+			Token:      app.config.Export.SplunkHECToken,
+			Index:      app.config.Export.SplunkIndex,
+			Sourcetype: app.config.Export.SplunkSourcetype,
+			BatchSize:  app.config.Export.SplunkBatchSize,
+			FlushEvery: time.Duration(app.config.Export.SplunkFlushInterval) * time.Second,
+		}, exportTimeout)
+		app.taskHandler.AddResultExporter(app.splunkExporter)
+		app.taskHandler.AddLifecycleRecorder(app.splunkExporter)
+		gologger.Info().Msgf("Exporting results and task events to Splunk at %s", app.config.Export.SplunkHECURL)
+	}
+
+	if app.config.App.StatusTokenSecret != "" {
+		app.statusExporter = exporters.NewScanStatusExporter(app.blobClient, exportTimeout)
+		app.statusExporter.SetFinalTask(finalTask)
+		app.taskHandler.AddLifecycleRecorder(app.statusExporter)
+	}
+
+	switch {
+	case app.config.Azure.ResultEventsTopic != "":
+		publisher, err := app.serviceBusClient.NewTopicPublisher(app.config.Azure.ResultEventsTopic)
+		if err != nil {
+			return fmt.Errorf("failed to initialize result event publisher: %w", err)
+		}
+		app.resultPublisher = publisher
+		gologger.Info().Msgf("Publishing result events to Service Bus topic %s", app.config.Azure.ResultEventsTopic)
+	case app.config.Azure.EventGridTopicEndpoint != "":
+		app.resultPublisher = azure.NewEventGridPublisher(app.config.Azure.EventGridTopicEndpoint, app.config.Azure.EventGridTopicKey, exportTimeout)
+		gologger.Info().Msgf("Publishing result events to Event Grid topic %s", app.config.Azure.EventGridTopicEndpoint)
+	}
+	if app.resultPublisher != nil {
+		app.taskHandler.SetResultPublisher(app.resultPublisher)
+	}
+
+	app.taskHandler.SetDNSXDefaults(app.config.DNSX.Settings())
+	app.taskHandler.SetDNSXScalingLimits(app.config.DNSX.MaxWorkers, app.config.DNSX.MaxRateLimit)
+	app.taskHandler.SetDNSXStreamThreshold(app.config.DNSX.StreamThreshold)
+	app.taskHandler.SetNucleiTemplateCacheDir(app.config.Nuclei.TemplateCacheDir)
+	app.taskHandler.SetNucleiInteractsh(app.config.Nuclei.InteractshServer, app.config.Nuclei.InteractshToken, app.config.Nuclei.DisableInteractsh, app.config.Nuclei.InteractshAllowedServers)
+	app.taskHandler.SetNucleiBudgets(time.Duration(app.config.Nuclei.HostBudget)*time.Second, time.Duration(app.config.Nuclei.ScanBudget)*time.Second)
+	app.taskHandler.SetNucleiCheckpoints(app.config.Nuclei.Checkpoints)
+	app.taskHandler.SetNucleiReplay(models.FindingReplay{
+		MinSeverity: app.config.Nuclei.ReplayMinSeverity,
+		Delay:       time.Duration(app.config.Nuclei.ReplayDelay) * time.Second,
+	})
+
+	// Passive source statistics are added up in blob storage, so they outlive the worker
+	app.taskHandler.SetPassiveStatsStore(app.blobClient)
+
+	// Passive source quotas were already validated with the rest of the configuration
+	quotaLimits, err := quota.ParseLimits(app.config.App.PassiveSourceQuotas)
+	if err != nil {
+		return fmt.Errorf("failed to parse passive source quotas: %w", err)
+	}
+	if len(quotaLimits) > 0 {
+		app.taskHandler.SetQuotaTracker(quota.NewTracker(app.blobClient, quotaLimits))
+		gologger.Info().Msgf("Tracking API key quotas of %d passive sources", len(quotaLimits))
+	}
+
+	// Stale workers warn operators, and optionally stop taking tasks, until they are replaced
+	if app.config.App.VersionManifestURL != "" {
+		app.versionChecker = buildinfo.NewDriftChecker(app.config.App.VersionManifestURL, time.Duration(app.config.App.VersionCheckInterval)*time.Second)
+		app.versionChecker.SetTemplatesVersion(scanners.NucleiTemplatesVersion)
+		if discordNotifier != nil {
+			app.versionChecker.SetAlert(func(ctx context.Context, drift models.VersionDrift) {
+				if err := discordNotifier.NotifyVersionDrift(ctx, drift); err != nil {
+					gologger.Warning().Msgf("Failed to send version drift alert: %v", err)
+				}
+			})
+		}
+		if app.config.App.VersionDriftUnready {
+			app.taskHandler.SetVersionCheck(app.versionChecker.Stale)
+		}
+	}
+
+	// Simulated scanners replace the real ones before the enabled task types are picked
+	if app.config.App.SimulationMode {
+		app.taskHandler.SetSimulation(app.config.App.SimulationFixtures)
+		gologger.Warning().Msg("Simulation mode: tasks return fixture results and nothing is scanned")
+	}
+
+	// Enabled task types were already validated with the rest of the configuration
+	enabledTasks, err := validation.NewValidator().ParseTaskTypes(app.config.App.EnabledTasks)
+	if err != nil {
+		return fmt.Errorf("failed to parse enabled tasks: %w", err)
+	}
+	if len(enabledTasks) > 0 {
+		app.taskHandler.EnableTasks(enabledTasks)
+	}
+	app.taskHandler.SetDisabledTaskAction(app.config.App.DisabledTaskAction)
+
+	// Workers advertise the capabilities they detect next to the configured labels
+	capabilities, err := validation.NewValidator().ParseCapabilities(app.config.App.WorkerCapabilities)
+	if err != nil {
+		return fmt.Errorf("failed to parse worker capabilities: %w", err)
+	}
+	capabilities = append(capabilities, models.MissingCapabilities(scanners.DetectCapabilities(), capabilities)...)
+	sort.Strings(capabilities)
+	app.taskHandler.SetCapabilities(capabilities)
+	gologger.Info().Msgf("Worker capabilities: %s", strings.Join(capabilities, ", "))
+
+	// Scanners the host lacks capabilities or files for are disabled before any task arrives
+	if app.config.App.SelfTest {
+		app.runSelfTest()
+	}
+
+	// Scanner weights were already validated with the rest of the configuration
+	scannerWeights, err := capacity.ParseWeights(app.config.App.ScannerWeights)
+	if err != nil {
+		return fmt.Errorf("failed to parse scanner weights: %w", err)
+	}
+	budget := capacity.NewBudget(app.config.App.ScannerCapacity, scannerWeights)
+	app.taskHandler.SetCapacityBudget(budget)
+	app.taskHandler.SetRetryBudget(app.config.App.RetryBudget)
+	app.taskHandler.SetResultCompactionDays(app.config.Azure.ResultCompactionDays)
+	app.taskHandler.SetVerifyTimeout(time.Duration(app.config.App.VerifyTimeout) * time.Second)
+	app.taskHandler.SetCompliance(app.config.App.ComplianceMode, app.config.App.ComplianceUserAgent)
+	app.taskHandler.SetBlockPolicy(models.BlockPolicy{
+		Window:     app.config.App.BlockDetectionWindow,
+		Action:     app.config.App.BlockDetectionAction,
+		Backoff:    time.Duration(app.config.App.BlockBackoff) * time.Second,
+		RetryAfter: time.Duration(app.config.App.BlockRetryAfter) * time.Minute,
+	})
+
+	// Held task types were already validated with the rest of the configuration
+	holdTasks, err := validation.NewValidator().ParseTaskTypes(app.config.App.QualityGateHoldTasks)
+	if err != nil {
+		return fmt.Errorf("failed to parse quality gate hold tasks: %w", err)
+	}
+	app.taskHandler.SetQualityGates(models.QualityGates{
+		MinAliveRatio:   float64(app.config.App.QualityGateMinAlivePercent) / 100,
+		MaxDNSErrorRate: float64(app.config.App.QualityGateMaxDNSErrorPercent) / 100,
+		MinSample:       app.config.App.QualityGateMinSample,
+		MaxHold:         time.Duration(app.config.App.QualityGateMaxHoldHours) * time.Hour,
+		HoldTasks:       holdTasks,
+	})
+	app.taskHandler.SetPauseMaxHold(time.Duration(app.config.App.PauseMaxHoldHours) * time.Hour)
+	app.taskHandler.SetMultiDomainParallelism(app.config.App.MultiDomainParallelism)
+
+	// Scan profiles were already validated with the rest of the configuration
+	scanProfiles, err := models.ConfiguredScanProfiles(app.config.App.ScanProfiles)
+	if err != nil {
+		return fmt.Errorf("failed to parse scan profiles: %w", err)
+	}
+	app.taskHandler.SetScanProfiles(scanProfiles)
+
+	// The template policy was already validated with the rest of the configuration
+	if app.config.Nuclei.TemplatePolicy != "" {
+		templatePolicy, err := models.ParseTemplatePolicy([]byte(app.config.Nuclei.TemplatePolicy))
+		if err != nil {
+			return fmt.Errorf("failed to parse template policy: %w", err)
+		}
+		app.taskHandler.SetTemplatePolicy(templatePolicy)
+	}
+
+	// TLS fingerprints were already validated with the rest of the configuration
+	tlsFingerprints, err := models.ParseTLSFingerprints(app.config.App.HttpxTLSFingerprints)
+	if err != nil {
+		return fmt.Errorf("failed to parse TLS fingerprints: %w", err)
+	}
+	app.taskHandler.SetTLSFingerprints(tlsFingerprints)
+	redactionPolicies, err := redaction.ParsePolicies(app.config.App.RedactionPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to parse redaction policy: %w", err)
+	}
+	app.taskHandler.SetRedaction(redactionPolicies)
+	if app.config.App.ReviewSampleRate > 0 {
+		app.taskHandler.SetReviewSampler(review.NewSampler(float64(app.config.App.ReviewSampleRate), app.config.App.ReviewSampleMax))
+	}
+
+	// Temp files of the scanners and their libraries and the template cache are kept within the
+	// disk budget; entries younger than the scanner timeout may belong to a running task and are
+	// never evicted. Naabu merges its targets into stdin-input files, nuclei keeps its dedupe
+	// store in nuclei-report directories and DNSX spills sorted record runs to ndjson files.
+	app.diskJanitor = disk.NewJanitor(
+		int64(app.config.App.DiskBudgetMB)<<20,
+		int64(app.config.App.DiskMinFreeMB)<<20,
+		time.Duration(app.config.App.ScannerTimeout)*time.Second,
+	)
+	app.diskJanitor.AddArea(disk.Area{Name: "temp", Dir: os.TempDir(), Patterns: []string{
+		"httpx-hosts-*.txt", "httpx-included-*.txt", "stdin-input-*", "nuclei-report-*", "dnsx-records-*.ndjson",
+	}, Ephemeral: true})
+	app.diskJanitor.AddArea(disk.Area{Name: "nuclei_templates", Dir: app.config.Nuclei.TemplateCacheDir, Lock: app.taskHandler.NucleiTemplateCacheLock()})
+	app.taskHandler.SetDiskJanitor(app.diskJanitor)
+
+	// Progress of long scans is published as gauges and sent periodically to Discord and Splunk
+	app.taskHandler.SetProgressInterval(time.Duration(app.config.App.ProgressInterval) * time.Second)
+	if app.config.App.MetricsAddr != "" {
+		registry := metrics.NewRegistry()
+		app.taskHandler.SetMetrics(registry)
+		app.diskJanitor.SetMetrics(registry)
+		durations := metrics.NewTaskDurations()
+		app.taskHandler.SetTaskDurations(durations)
+		if app.serviceBusClient != nil {
+			app.serviceBusClient.SetMetrics(registry)
+		}
+		if queue, ok := app.taskSource.(*devqueue.FileQueue); ok {
+			queue.SetMetrics(registry)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", registry)
+		mux.HandleFunc("/healthz", app.handleHealth)
+		mux.HandleFunc("/version", app.handleVersion)
+		if app.config.App.QueueMetricsInterval > 0 {
+			interval := time.Duration(app.config.App.QueueMetricsInterval) * time.Second
+			app.queueMonitor = metrics.NewQueueMonitor(app.taskSource, registry, interval)
+			// Replicas are hinted for workers like this one, weighing each task type as it does
+			app.queueMonitor.SetAutoscale(metrics.ReplicaPolicy{
+				Capacity:        budget.Capacity(),
+				Weight:          budget.Weight,
+				TargetDrain:     time.Duration(app.config.App.AutoscaleTargetDrain) * time.Second,
+				DefaultDuration: time.Duration(app.config.App.AutoscaleDefaultDuration) * time.Second,
+				MinReplicas:     app.config.App.AutoscaleMinReplicas,
+				MaxReplicas:     app.config.App.AutoscaleMaxReplicas,
+				PeekMessages:    app.config.App.AutoscalePeekMessages,
+			}, durations)
+			mux.Handle("/autoscale", app.queueMonitor)
+		}
+		app.metricsServer = &http.Server{Addr: app.config.App.MetricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	}
+
+	// API tokens were already validated with the rest of the configuration
+	if app.config.App.APIAddr != "" {
+		auth, err := api.ParseTokens(app.config.App.APITokens)
+		if err != nil {
+			return fmt.Errorf("failed to parse API tokens: %w", err)
+		}
+		server := api.NewServer(app.taskSource, app.blobClient, auth)
+		if app.config.App.StatusTokenSecret != "" {
+			server.SetStatusTokens(app.config.App.StatusTokenSecret, app.config.App.StatusRateLimit)
+		}
+		if app.config.App.WebhookSecret != "" {
+			server.SetWebhookSecret(app.config.App.WebhookSecret)
+		}
+		server.SetEventBus(app.taskHandler.EventBus())
+		server.SetExportJobs(app.ctx, app.config.App.ExportJobConcurrency, time.Duration(app.config.App.ExportJobLinkTTL)*time.Minute)
+		if app.config.Export.ElasticsearchURL != "" {
+			server.SetSearcher(search.NewElasticsearch(exporters.ElasticsearchConfig{
+				URL:         app.config.Export.ElasticsearchURL,
+				APIKey:      app.config.Export.ElasticsearchAPIKey,
+				Username:    app.config.Export.ElasticsearchUsername,
+				Password:    app.config.Export.ElasticsearchPassword,
+				IndexPrefix: app.config.Export.ElasticsearchIndexPrefix,
+			}, app.config.Export.ElasticsearchKeywordSuffix, time.Duration(app.config.Export.Timeout)*time.Second))
+		}
+		app.apiServer = &http.Server{
+			Addr:              app.config.App.APIAddr,
+			Handler:           server,
+			ReadHeaderTimeout: 10 * time.Second,
+			// Event streams end with the application instead of holding up the shutdown
+			BaseContext: func(net.Listener) context.Context { return app.ctx },
+		}
+	}
+
+	// Scan windows were already validated with the rest of the configuration
+	scanWindows, err := schedule.ParseWindows(app.config.App.ScanWindows)
+	if err != nil {
+		return fmt.Errorf("failed to parse scan windows: %w", err)
+	}
+	if !scanWindows.Empty() {
+		app.taskHandler.SetScanWindows(scanWindows)
+	}
+
+	if err := app.initializeCloudDNS(); err != nil {
+		return err
+	}
+	if app.config.Connectors.SyncEnabled() {
+		app.initializeScopeSync()
+	}
+
+	return nil
+}
+
+// initializeCloudDNS hands the read-only cloud credentials to the cloud_dns scanner
+func (app *Application) initializeCloudDNS() error {
+	c := app.config.Connectors
+	// The accounts were already validated with the rest of the configuration
+	accounts, err := models.ParseCloudDNSAccounts(c.CloudDNSAccounts)
+	if err != nil {
+		return fmt.Errorf("failed to parse cloud DNS accounts: %w", err)
+	}
+	credentials := scanners.CloudDNSCredentials{
+		AWS: utils.AWSCredentials{
+			AccessKeyID:     c.AWSAccessKeyID,
+			SecretAccessKey: c.AWSSecretAccessKey,
+			SessionToken:    c.AWSSessionToken,
+		},
+		Accounts: accounts,
+	}
+	if c.GoogleCredentialsFile != "" {
+		key, err := os.ReadFile(c.GoogleCredentialsFile)
+		if err != nil {
+			return fmt.Errorf("failed to read Google service account key: %w", err)
+		}
+		credentials.GoogleServiceAccount = key
+	}
+	app.taskHandler.SetCloudDNSCredentials(credentials)
 	return nil
 }
 
+// initializeScopeSync creates the syncer that imports the scan scope from the configured sources
+func (app *Application) initializeScopeSync() {
+	c := app.config.Connectors
+	timeout := time.Duration(c.Timeout) * time.Second
+
+	var sources []connectors.Connector
+	if c.CSVBlob != "" {
+		sources = append(sources, connectors.NewCSVConnector(app.blobClient, c.CSVBlob))
+	}
+	if c.ServiceNowInstanceURL != "" {
+		sources = append(sources, connectors.NewServiceNowConnector(connectors.ServiceNowConfig{
+			InstanceURL: c.ServiceNowInstanceURL,
+			Username:    c.ServiceNowUsername,
+			Password:    c.ServiceNowPassword,
+			Table:       c.ServiceNowTable,
+			Field:       c.ServiceNowField,
+		}, timeout))
+	}
+	if c.CloudflareAPIToken != "" {
+		sources = append(sources, connectors.NewCloudflareConnector(c.CloudflareAPIToken, timeout))
+	}
+	if c.Route53 {
+		sources = append(sources, connectors.NewRoute53Connector(utils.AWSCredentials{
+			AccessKeyID:     c.AWSAccessKeyID,
+			SecretAccessKey: c.AWSSecretAccessKey,
+			SessionToken:    c.AWSSessionToken,
+		}, timeout))
+	}
+
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.Name())
+	}
+	interval := time.Duration(c.SyncInterval) * time.Second
+	app.scopeSyncer = connectors.NewSyncer(app.blobClient, app.taskSource, c.TenantID, interval, sources...)
+	gologger.Info().Msgf("Importing the scan scope from %s every %s", strings.Join(names, ", "), interval)
+}
+
+// runSelfTest disables the scanners that cannot run on this host and logs how to fix each one
+func (app *Application) runSelfTest() {
+	disabled := app.taskHandler.SelfTest()
+	tasks := maps.Keys(disabled)
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i] < tasks[j] })
+	for _, task := range tasks {
+		gologger.Error().Msgf("Self-test disabled %s tasks: %v", task, disabled[task])
+	}
+	gologger.Info().Msgf("Self-test passed for task types: %s", strings.Join(app.taskHandler.AvailableTasks(), ", "))
+}
+
+// handleHealth reports the task types this worker can run; it fails when it can run none, or
+// when VERSION_DRIFT_UNREADY is set and the worker is older than the required minimum version
+func (app *Application) handleHealth(w http.ResponseWriter, r *http.Request) {
+	tasks := app.taskHandler.AvailableTasks()
+	status := http.StatusOK
+	if len(tasks) == 0 {
+		status = http.StatusServiceUnavailable
+	}
+	body := map[string]interface{}{"tasks": tasks, "capabilities": app.taskHandler.Capabilities()}
+	if app.versionChecker != nil {
+		if drift := app.versionChecker.Drift(); drift != nil {
+			body["version_drift"] = drift
+			if drift.Stale && app.config.App.VersionDriftUnready {
+				status = http.StatusServiceUnavailable
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// handleVersion reports the build of the worker
+func (app *Application) handleVersion(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildinfo.Get())
+}
+
 // Start begins the application's main processing loop
 func (app *Application) Start() error {
+	app.startMetricsServer()
+	app.startAPIServer()
+	if app.scopeSyncer != nil {
+		go app.scopeSyncer.Run(app.ctx)
+	}
+	if app.config.App.DiskSweepInterval > 0 {
+		go app.diskJanitor.Run(app.ctx, time.Duration(app.config.App.DiskSweepInterval)*time.Second)
+	}
+	if app.outbox != nil {
+		go app.outbox.Run(app.ctx)
+	}
+	if app.versionChecker != nil {
+		go app.versionChecker.Run(app.ctx)
+	}
 	return app.waitForShutdown()
 }
 
+// startAPIServer serves the HTTP API in the background
+func (app *Application) startAPIServer() {
+	if app.apiServer == nil {
+		return
+	}
+
+	gologger.Info().Msgf("Serving the API on %s/api/v1", app.apiServer.Addr)
+	go func() {
+		if err := app.apiServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			gologger.Error().Msgf("API server stopped: %v", err)
+		}
+	}()
+}
+
+// startMetricsServer serves the Prometheus metrics endpoint in the background
+func (app *Application) startMetricsServer() {
+	if app.metricsServer == nil {
+		return
+	}
+
+	gologger.Info().Msgf("Serving metrics on %s/metrics", app.metricsServer.Addr)
+	if app.queueMonitor != nil {
+		go app.queueMonitor.Run(app.ctx)
+	}
+	go func() {
+		if err := app.metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			gologger.Error().Msgf("Metrics server stopped: %v", err)
+		}
+	}()
+}
+
 // waitForShutdown waits for shutdown signals and handles graceful shutdown
 func (app *Application) waitForShutdown() error {
 	signalChannel := make(chan os.Signal, 1)
@@ -158,7 +804,7 @@ func (app *Application) waitForShutdown() error {
 		maxLockRenewalTime := time.Duration(app.config.App.MaxLockRenewalTime) * time.Second
 		scannerTimeout := time.Duration(app.config.App.ScannerTimeout) * time.Second
 
-		err := app.serviceBusClient.ProcessMessages(
+		err := app.taskSource.ProcessMessages(
 			app.ctx,
 			app.taskHandler.HandleTask,
 			pollInterval,
@@ -185,9 +831,28 @@ func (app *Application) handleGracefulShutdown() error {
 	// Cancel the main context to stop all goroutines
 	app.cancel()
 
-	// Close Azure clients
-	if app.serviceBusClient != nil {
-		app.serviceBusClient.Close(context.Background())
+	// Work off the events still queued for notifications and exports before their clients close
+	closeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	app.taskHandler.EventBus().Close(closeCtx)
+
+	// Close the task source and Azure clients
+	if app.resultPublisher != nil {
+		app.resultPublisher.Close(context.Background())
+	}
+	if app.taskSource != nil {
+		app.taskSource.Close(context.Background())
+	}
+
+	// Deliver finding alerts and Splunk events that are still queued
+	app.findingRouter.Close(closeCtx)
+	app.splunkExporter.Close(closeCtx)
+	app.statusExporter.Close(closeCtx)
+	if app.metricsServer != nil {
+		app.metricsServer.Shutdown(closeCtx)
+	}
+	if app.apiServer != nil {
+		app.apiServer.Shutdown(closeCtx)
 	}
 
 	gologger.Info().Msg("Shutdown complete")
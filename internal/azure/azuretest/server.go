@@ -0,0 +1,349 @@
+// Package azuretest runs an in-memory blob service for tests, so code that takes a
+// BlobStorageClient can be tested without Azure or Azurite.
+package azuretest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// account is the account name of the emulated blob service, as in Azurite
+const account = "devstoreaccount1"
+
+// accountKey is Azurite's well-known account key; requests are not authenticated
+const accountKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+// blob is a stored blob and its properties
+type blob struct {
+	content      []byte
+	etag         string
+	lastModified time.Time
+}
+
+// Server is an in-memory blob service. It supports the blob operations BlobStorageClient uses:
+// uploads in one request or in blocks, conditional uploads, downloads with a range, properties,
+// deletes and flat listings. Containers always exist.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	blobs   map[string]*blob             // By "<container>/<name>"
+	blocks  map[string]map[string][]byte // Uncommitted blocks by blob and block ID
+	version int
+	now     func() time.Time
+	failing func(container, name string) bool // Writes it matches fail
+}
+
+// NewServer starts an in-memory blob service. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		blobs:  make(map[string]*blob),
+		blocks: make(map[string]map[string][]byte),
+		now:    time.Now,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// ConnectionString returns a connection string of the service for NewBlobStorageClient
+func (s *Server) ConnectionString() string {
+	return fmt.Sprintf("DefaultEndpointsProtocol=http;AccountName=%s;AccountKey=%s;BlobEndpoint=%s/%s;",
+		account, accountKey, s.URL, account)
+}
+
+// SetClock sets the time blobs are stamped with when they are written
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// FailWrites makes uploads and deletes of the blobs match reports true for fail as unauthorized,
+// which the SDK does not retry; nil lets every write succeed again
+func (s *Server) FailWrites(match func(container, name string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = match
+}
+
+// Blob returns the content of a blob and whether it exists
+func (s *Server) Blob(container, name string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.blobs[container+"/"+name]
+	if !ok {
+		return nil, false
+	}
+	return append([]byte(nil), b.content...), true
+}
+
+// PutBlob stores a blob directly, as if a client had uploaded it
+func (s *Server) PutBlob(container, name string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store(container+"/"+name, content)
+}
+
+// Names returns the names of the blobs of a container that start with prefix, sorted
+func (s *Server) Names(container, prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for key := range s.blobs {
+		if name, ok := strings.CutPrefix(key, container+"/"); ok && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// store writes a blob with a new ETag; the caller holds the lock
+func (s *Server) store(key string, content []byte) *blob {
+	s.version++
+	b := &blob{
+		content:      append([]byte(nil), content...),
+		etag:         fmt.Sprintf("\"0x%X\"", s.version),
+		lastModified: s.now().UTC().Truncate(time.Second),
+	}
+	s.blobs[key] = b
+	return b
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Paths are "/<account>/<container>[/<blob name>]"
+	path := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/"), account+"/")
+	container, name, _ := strings.Cut(path, "/")
+	query := r.URL.Query()
+
+	if r.Method == http.MethodPut || r.Method == http.MethodDelete {
+		if name != "" && s.failing != nil && s.failing(container, name) {
+			writeError(w, http.StatusForbidden, "AuthorizationFailure")
+			return
+		}
+	}
+
+	switch {
+	case query.Get("restype") == "container" && query.Get("comp") == "list":
+		s.list(w, container, query.Get("prefix"), query.Get("delimiter"))
+	case query.Get("restype") == "container":
+		// Containers always exist, so creating one succeeds and so does reading its properties
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPut && query.Get("comp") == "block":
+		s.putBlock(w, r, container+"/"+name, query.Get("blockid"))
+	case r.Method == http.MethodPut && query.Get("comp") == "blocklist":
+		s.putBlockList(w, r, container+"/"+name)
+	case r.Method == http.MethodPut:
+		s.put(w, r, container+"/"+name)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.get(w, r, container+"/"+name)
+	case r.Method == http.MethodDelete:
+		if _, ok := s.blobs[container+"/"+name]; !ok {
+			writeError(w, http.StatusNotFound, "BlobNotFound")
+			return
+		}
+		delete(s.blobs, container+"/"+name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+// checkConditions applies If-Match and If-None-Match to a write, reporting false once it has
+// answered the request with the failed condition
+func (s *Server) checkConditions(w http.ResponseWriter, r *http.Request, key string) bool {
+	existing, exists := s.blobs[key]
+	if match := r.Header.Get("If-None-Match"); match != "" && exists && (match == "*" || match == existing.etag) {
+		writeError(w, http.StatusConflict, "BlobAlreadyExists")
+		return false
+	}
+	if match := r.Header.Get("If-Match"); match != "" && (!exists || (match != "*" && match != existing.etag)) {
+		writeError(w, http.StatusPreconditionFailed, "ConditionNotMet")
+		return false
+	}
+	return true
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, key string) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidInput")
+		return
+	}
+	if !s.checkConditions(w, r, key) {
+		return
+	}
+	writeCreated(w, s.store(key, content))
+}
+
+func (s *Server) putBlock(w http.ResponseWriter, r *http.Request, key, blockID string) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil || blockID == "" {
+		writeError(w, http.StatusBadRequest, "InvalidInput")
+		return
+	}
+	if s.blocks[key] == nil {
+		s.blocks[key] = make(map[string][]byte)
+	}
+	s.blocks[key][blockID] = content
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) putBlockList(w http.ResponseWriter, r *http.Request, key string) {
+	var list struct {
+		IDs []struct {
+			ID string `xml:",chardata"`
+		} `xml:",any"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidXmlDocument")
+		return
+	}
+	if !s.checkConditions(w, r, key) {
+		return
+	}
+	var content []byte
+	for _, id := range list.IDs {
+		block, ok := s.blocks[key][id.ID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "InvalidBlockList")
+			return
+		}
+		content = append(content, block...)
+	}
+	delete(s.blocks, key)
+	writeCreated(w, s.store(key, content))
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	b, ok := s.blobs[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+
+	content, status := b.content, http.StatusOK
+	if rangeHeader := r.Header.Get("x-ms-range"); rangeHeader != "" || r.Header.Get("Range") != "" {
+		if rangeHeader == "" {
+			rangeHeader = r.Header.Get("Range")
+		}
+		start, end, _ := strings.Cut(strings.TrimPrefix(rangeHeader, "bytes="), "-")
+		offset, _ := strconv.Atoi(start)
+		last := len(content) - 1
+		if end != "" {
+			if n, err := strconv.Atoi(end); err == nil && n < last {
+				last = n
+			}
+		}
+		if offset > len(content) {
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, last, len(content)))
+		content, status = content[offset:last+1], http.StatusPartialContent
+	}
+
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.lastModified.Format(http.TimeFormat))
+	w.Header().Set("x-ms-blob-type", "BlockBlob")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(content)
+	}
+}
+
+// listBlob is a blob of a List Blobs response
+type listBlob struct {
+	Name       string `xml:"Name"`
+	Properties struct {
+		LastModified  string `xml:"Last-Modified"`
+		ETag          string `xml:"Etag"`
+		ContentLength int    `xml:"Content-Length"`
+		BlobType      string `xml:"BlobType"`
+	} `xml:"Properties"`
+}
+
+// listPrefix is a folder of a hierarchical listing
+type listPrefix struct {
+	Name string `xml:"Name"`
+}
+
+// list answers a listing of the blobs under prefix. With a delimiter, the blobs under a folder
+// below prefix are listed as the folder once.
+func (s *Server) list(w http.ResponseWriter, container, prefix, delimiter string) {
+	response := struct {
+		XMLName       xml.Name     `xml:"EnumerationResults"`
+		ContainerName string       `xml:"ContainerName,attr"`
+		Prefix        string       `xml:"Prefix"`
+		Blobs         []listBlob   `xml:"Blobs>Blob"`
+		Prefixes      []listPrefix `xml:"Blobs>BlobPrefix"`
+		NextMarker    string       `xml:"NextMarker"`
+	}{ContainerName: container, Prefix: prefix}
+	folders := make(map[string]bool)
+
+	keys := make([]string, 0, len(s.blobs))
+	for key := range s.blobs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name, ok := strings.CutPrefix(key, container+"/")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(name[len(prefix):], delimiter); i >= 0 {
+				folder := name[:len(prefix)+i+len(delimiter)]
+				if !folders[folder] {
+					folders[folder] = true
+					response.Prefixes = append(response.Prefixes, listPrefix{Name: folder})
+				}
+				continue
+			}
+		}
+		b := s.blobs[key]
+		item := listBlob{Name: name}
+		item.Properties.LastModified = b.lastModified.Format(http.TimeFormat)
+		item.Properties.ETag = b.etag
+		item.Properties.ContentLength = len(b.content)
+		item.Properties.BlobType = "BlockBlob"
+		response.Blobs = append(response.Blobs, item)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(response)
+}
+
+// writeCreated answers a successful upload
+func writeCreated(w http.ResponseWriter, b *blob) {
+	w.Header().Set("ETag", b.etag)
+	w.Header().Set("Last-Modified", b.lastModified.Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// writeError answers a request with a storage error code, which the SDK reads from the header
+// for bodyless responses and from the body otherwise
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s<Error><Code>%s</Code><Message>%s</Message></Error>", xml.Header, code, code)
+}
@@ -1,65 +1,1004 @@
 package azure
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/allsafeASM/api/internal/faults"
 	"github.com/allsafeASM/api/internal/models"
-	"github.com/google/uuid"
+	"github.com/allsafeASM/api/internal/utils"
 	"github.com/projectdiscovery/gologger"
 )
 
-// BlobStorageClient wraps Azure Blob Storage operations
+// DefaultRegion names the data region of the primary storage account unless it is configured
+const DefaultRegion = "default"
+
+// blobAccount is a storage account that keeps the blobs of one data region
+type blobAccount struct {
+	region string
+	client *azblob.Client
+	writer *OptimisticBlobWriter // Conditional writes of blobs that several workers update
+}
+
+// BlobStorageClient wraps Azure Blob Storage operations. Blobs are kept in the storage account of
+// their tenant's data region; every account uses the same container name.
 type BlobStorageClient struct {
-	client        *azblob.Client
 	containerName string
+	region        string                  // Data region of the primary account
+	accounts      map[string]*blobAccount // Storage accounts by data region, the primary one included
+	tenantRegions models.TenantRegions
+	faults        *blobFaultPolicy // Injects blob delays once a fault injector is set
+	// Results whose JSON exceeds maxResultSize bytes are stored compressed with a summary in their place
+	maxResultSize  int
+	summarySamples int
 }
 
 // NewBlobStorageClient creates a new Blob Storage client
 func NewBlobStorageClient(connectionString, containerName string) (*BlobStorageClient, error) {
-	client, err := azblob.NewClientFromConnectionString(connectionString, nil)
+	b := &BlobStorageClient{
+		containerName: containerName,
+		region:        DefaultRegion,
+		accounts:      make(map[string]*blobAccount),
+		faults:        &blobFaultPolicy{},
+	}
+	if err := b.AddRegion(DefaultRegion, connectionString); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// AddRegion adds the storage account that keeps the blobs of a data region
+func (b *BlobStorageClient) AddRegion(region, connectionString string) error {
+	client, err := azblob.NewClientFromConnectionString(connectionString, &azblob.ClientOptions{
+		ClientOptions: policy.ClientOptions{PerCallPolicies: []policy.Policy{b.faults}},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create blob storage client: %w", err)
+		return fmt.Errorf("failed to create blob storage client for region %s: %w", region, err)
 	}
 
-	return &BlobStorageClient{
-		client:        client,
-		containerName: containerName,
-	}, nil
+	b.accounts[region] = &blobAccount{
+		region: region,
+		client: client,
+		writer: NewOptimisticBlobWriter(client, b.containerName),
+	}
+	return nil
+}
+
+// SetDefaultRegion names the data region of the primary storage account, which keeps the blobs
+// of tenants without a routing rule and the blobs shared by all tenants
+func (b *BlobStorageClient) SetDefaultRegion(region string) {
+	if region == b.region {
+		return
+	}
+	account := b.accounts[b.region]
+	delete(b.accounts, b.region)
+	account.region = region
+	b.accounts[region] = account
+	b.region = region
+}
+
+// SetTenantRegions routes the blobs of tenants to the storage accounts of their data regions.
+// Every region must have an account, so no tenant falls back to the primary one by mistake.
+func (b *BlobStorageClient) SetTenantRegions(regions models.TenantRegions) error {
+	for tenant, region := range regions {
+		if _, ok := b.accounts[region]; !ok {
+			return fmt.Errorf("tenant %s is routed to region %s, which has no storage account", tenant, region)
+		}
+	}
+	b.tenantRegions = regions
+	return nil
+}
+
+// Regions returns the data regions with a storage account, sorted
+func (b *BlobStorageClient) Regions() []string {
+	regions := make([]string, 0, len(b.accounts))
+	for region := range b.accounts {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// TenantRegion returns the data region a tenant's blobs are kept in
+func (b *BlobStorageClient) TenantRegion(tenantID string) string {
+	return b.tenantRegions.Lookup(tenantID, b.region)
 }
 
-// StoreTaskResult stores a task result in blob storage
-func (b *BlobStorageClient) StoreTaskResult(ctx context.Context, result *models.TaskResult) error {
-	// Create a unique blob name using timestamp and task ID
-	randomID := uuid.New().String()
-	blobName := fmt.Sprintf("%s-%d/%s/out/%s.json", result.Domain, result.ScanID, result.Task, randomID)
+// accountFor returns the storage account of a blob, picked by the tenant its path starts with
+func (b *BlobStorageClient) accountFor(blobPath string) *blobAccount {
+	region := b.tenantRegions.Lookup(models.BlobPathTenant(b.CleanBlobPath(blobPath)), b.region)
+	return b.accounts[region]
+}
+
+// clientFor returns the client of the storage account a blob is kept in
+func (b *BlobStorageClient) clientFor(blobPath string) *azblob.Client {
+	return b.accountFor(blobPath).client
+}
+
+// writerFor returns the conditional writer of the storage account a blob is kept in
+func (b *BlobStorageClient) writerFor(blobPath string) *OptimisticBlobWriter {
+	return b.accountFor(blobPath).writer
+}
+
+// checkResidency verifies that a tenant's blob is routed to the tenant's data region. Blob paths
+// start with their tenant, so a mismatch means the path was built without it.
+func (b *BlobStorageClient) checkResidency(tenantID, blobPath string) error {
+	expected := b.TenantRegion(tenantID)
+	if actual := b.accountFor(blobPath).region; actual != expected {
+		return fmt.Errorf("blob %s of tenant %s would be stored in region %s instead of %s", blobPath, tenantID, actual, expected)
+	}
+	return nil
+}
+
+// SetFaultInjector delays blob operations as the injector decides, for resilience testing
+func (b *BlobStorageClient) SetFaultInjector(injector *faults.Injector) {
+	b.faults.injector.Store(injector)
+}
+
+// storageCheckTimeout bounds the startup storage check so an unreachable account cannot stall startup
+const storageCheckTimeout = 30 * time.Second
+
+// ValidateStorage verifies the container exists in every storage account, creating it when create
+// is set, and that the worker can write and delete blobs in it, so a misconfigured account fails
+// startup instead of the first result upload hours into a scan
+func (b *BlobStorageClient) ValidateStorage(ctx context.Context, create bool) error {
+	ctx, cancel := context.WithTimeout(ctx, storageCheckTimeout)
+	defer cancel()
+
+	for _, region := range b.Regions() {
+		if err := b.validateAccount(ctx, b.accounts[region], create); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// validateAccount checks the container of one storage account
+func (b *BlobStorageClient) validateAccount(ctx context.Context, account *blobAccount, create bool) error {
+	_, err := account.client.ServiceClient().NewContainerClient(b.containerName).GetProperties(ctx, nil)
+	switch {
+	case err == nil:
+	case bloberror.HasCode(err, bloberror.ContainerNotFound) && create:
+		if _, err := account.client.CreateContainer(ctx, b.containerName, nil); err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+			return fmt.Errorf("failed to create blob container %s: %w", b.containerName, err)
+		}
+		gologger.Info().Msgf("Created blob container %s in region %s", b.containerName, account.region)
+	case bloberror.HasCode(err, bloberror.ContainerNotFound):
+		return fmt.Errorf("blob container %s does not exist; create it or set BLOB_CREATE_CONTAINER=true", b.containerName)
+	default:
+		return fmt.Errorf("failed to read blob container %s: %w", b.containerName, err)
+	}
+
+	hostname, _ := os.Hostname()
+	probeName := fmt.Sprintf("control/probes/%s-%d.txt", hostname, time.Now().UnixNano())
+	if _, err := account.client.UploadBuffer(ctx, b.containerName, probeName, []byte("ok"), nil); err != nil {
+		return fmt.Errorf("failed to write probe blob to container %s: %w", b.containerName, err)
+	}
+	if _, err := account.client.DeleteBlob(ctx, b.containerName, probeName, nil); err != nil {
+		return fmt.Errorf("failed to delete probe blob %s: %w", probeName, err)
+	}
+
+	gologger.Info().Msgf("Blob container %s is writable in region %s", b.containerName, account.region)
+	return nil
+}
+
+// SetResultSizeLimit sets the result JSON size above which results are summarized, keeping
+// samples entries in the stored result. A limit of 0 stores every result whole.
+func (b *BlobStorageClient) SetResultSizeLimit(maxBytes, samples int) {
+	b.maxResultSize = maxBytes
+	b.summarySamples = samples
+}
+
+// StoreTaskResult stores a task result in blob storage, points the task's latest result at it and
+// returns its blob path. The blob is named after the attempt, so a retry replaces its own result.
+// A result larger than the size limit is stored compressed under full/ and a summary is stored in its place.
+// Verify results hold only the targets new since their baseline, so they never become the latest result.
+func (b *BlobStorageClient) StoreTaskResult(ctx context.Context, result *models.TaskResult) (string, error) {
+	taskPrefix := models.TaskBlobPrefix(result.TenantID, result.Domain, result.ScanID, string(result.Task))
+	blobName := taskPrefix + "out/" + models.AttemptBlobName(result.Attempt, ".json")
 
 	// Clean the blob path
-	cleanPath := b.cleanBlobPath(blobName)
+	cleanPath := b.CleanBlobPath(blobName)
+	if err := b.checkResidency(result.TenantID, cleanPath); err != nil {
+		return "", err
+	}
 
 	// Convert result to JSON
 	jsonData, err := json.Marshal(result)
 	if err != nil {
-		return fmt.Errorf("failed to marshal task result: %w", err)
+		return "", fmt.Errorf("failed to marshal task result: %w", err)
+	}
+
+	if b.maxResultSize > 0 && len(jsonData) > b.maxResultSize {
+		fullData, err := b.storeFullResult(ctx, b.CleanBlobPath(taskPrefix+"full/"+models.AttemptBlobName(result.Attempt, "")), jsonData)
+		if err != nil {
+			return "", err
+		}
+
+		summarized := models.SummarizeResult(result, len(jsonData), b.summarySamples)
+		summarized.Summary.FullData = fullData
+		if jsonData, err = json.Marshal(summarized); err != nil {
+			return "", fmt.Errorf("failed to marshal task result summary: %w", err)
+		}
+		gologger.Warning().Msgf("Task result for %s is %d bytes, over the %d byte limit; stored a summary with the full result in %d parts",
+			result.Domain, summarized.Summary.OriginalSize, b.maxResultSize, len(fullData))
 	}
 
 	// Upload to blob storage
-	_, err = b.client.UploadBuffer(ctx, b.containerName, cleanPath, jsonData, &azblob.UploadBufferOptions{})
+	_, err = b.clientFor(cleanPath).UploadBuffer(ctx, b.containerName, cleanPath, jsonData, uploadOptions(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to upload task result to blob storage: %w", err)
+		return "", fmt.Errorf("failed to upload task result to blob storage: %w", err)
 	}
 
 	gologger.Debug().Msgf("Stored task result in blob: %s/%s", b.containerName, blobName)
+	if result.Mode == models.TaskModeVerify {
+		return cleanPath, nil
+	}
+	if err := b.storeLatestResult(ctx, result.TenantID, result.Domain, result.ScanID, string(result.Task), result.Attempt, result.Duration, cleanPath); err != nil {
+		return "", err
+	}
+	return cleanPath, nil
+}
+
+// storeLatestResult points the latest result of a task at the blob an attempt stored. The pointer
+// only moves forward: a slow earlier attempt finishing after a later one leaves it unchanged.
+func (b *BlobStorageClient) storeLatestResult(ctx context.Context, tenantID, domain string, scanID int, task string, attempt int, duration, blobPath string) error {
+	pointerName := models.LatestResultBlobPath(tenantID, domain, scanID, task)
+	attempt = max(attempt, 1)
+
+	err := UpdateBlobJSON(ctx, b.writerFor(pointerName), pointerName, func(latest *models.LatestResult, exists bool) error {
+		if exists && latest.Attempt > attempt {
+			gologger.Debug().Msgf("Kept %s at attempt %d, attempt %d is older", pointerName, latest.Attempt, attempt)
+			return ErrBlobUnchanged
+		}
+		*latest = models.LatestResult{
+			BlobPath:      blobPath,
+			Attempt:       attempt,
+			CorrelationID: models.CorrelationIDFromContext(ctx),
+			Duration:      duration,
+			UpdatedAt:     time.Now().UTC().Format(time.RFC3339),
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update latest result pointer %s: %w", pointerName, err)
+	}
+	gologger.Debug().Msgf("Pointed %s/%s at %s", b.containerName, pointerName, blobPath)
+	return nil
+}
+
+// LoadLatestResult reads the latest result pointer of a task, returning nil when no attempt stored a result
+func (b *BlobStorageClient) LoadLatestResult(ctx context.Context, tenantID, domain string, scanID int, task string) (*models.LatestResult, error) {
+	pointerName := models.LatestResultBlobPath(tenantID, domain, scanID, task)
+	content, err := b.ReadFileFromBlob(ctx, pointerName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var latest models.LatestResult
+	if err := json.Unmarshal(content, &latest); err != nil {
+		return nil, fmt.Errorf("failed to parse latest result pointer %s: %w", pointerName, err)
+	}
+	return &latest, nil
+}
+
+// storeFullResult gzips result JSON and stores it in parts of at most maxResultSize bytes,
+// returning the part paths in order
+func (b *BlobStorageClient) storeFullResult(ctx context.Context, prefix string, jsonData []byte) ([]string, error) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	if _, err := writer.Write(jsonData); err != nil {
+		return nil, fmt.Errorf("failed to compress task result: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress task result: %w", err)
+	}
+
+	data := compressed.Bytes()
+	var parts []string
+	for offset := 0; offset < len(data); offset += b.maxResultSize {
+		end := offset + b.maxResultSize
+		if end > len(data) {
+			end = len(data)
+		}
+		partName := fmt.Sprintf("%s/part-%04d.json.gz", prefix, len(parts))
+		if _, err := b.clientFor(partName).UploadBuffer(ctx, b.containerName, partName, data[offset:end], uploadOptions(ctx)); err != nil {
+			return nil, fmt.Errorf("failed to upload full task result part %s: %w", partName, err)
+		}
+		parts = append(parts, partName)
+	}
+	return parts, nil
+}
+
+// StoreErrorArtifact stores a failed task result, including its structured error details, next to the task's results
+func (b *BlobStorageClient) StoreErrorArtifact(ctx context.Context, result *models.TaskResult) error {
+	blobName := models.TaskBlobPrefix(result.TenantID, result.Domain, result.ScanID, string(result.Task)) + "errors/" + models.AttemptBlobName(result.Attempt, ".json")
+
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error artifact: %w", err)
+	}
+
+	_, err = b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, jsonData, uploadOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload error artifact to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored error artifact in blob: %s/%s", b.containerName, blobName)
 	return nil
 }
 
-// cleanBlobPath removes the container name from the path if it's already included
-func (b *BlobStorageClient) cleanBlobPath(blobPath string) string {
+// StoreReviewSamples stores the exchanges of a task attempt kept for manual review
+func (b *BlobStorageClient) StoreReviewSamples(ctx context.Context, samples *models.ReviewSamples, attempt int) (string, error) {
+	blobName := models.ReviewSamplesBlobPath(samples.TenantID, samples.Domain, samples.ScanID, string(samples.Task), attempt)
+
+	jsonData, err := json.Marshal(samples)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal review samples: %w", err)
+	}
+
+	_, err = b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, jsonData, uploadOptions(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to upload review samples to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored review samples in blob: %s/%s", b.containerName, blobName)
+	return blobName, nil
+}
+
+// SetScanPaused creates or removes the pause marker for a scan
+func (b *BlobStorageClient) SetScanPaused(ctx context.Context, tenantID string, scanID int, paused bool) error {
+	blobName := models.ScanControlBlobPath(tenantID, scanID)
+
+	if !paused {
+		_, err := b.clientFor(blobName).DeleteBlob(ctx, b.containerName, blobName, nil)
+		if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return fmt.Errorf("failed to remove pause marker %s: %w", blobName, err)
+		}
+		gologger.Debug().Msgf("Removed pause marker: %s/%s", b.containerName, blobName)
+		return nil
+	}
+
+	_, err := b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, []byte(time.Now().Format(time.RFC3339)), uploadOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload pause marker %s: %w", blobName, err)
+	}
+
+	gologger.Debug().Msgf("Stored pause marker: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// IsScanPaused reports whether a pause marker exists for a scan
+func (b *BlobStorageClient) IsScanPaused(ctx context.Context, tenantID string, scanID int) (bool, error) {
+	blobName := models.ScanControlBlobPath(tenantID, scanID)
+	blobClient := b.clientFor(blobName).ServiceClient().NewContainerClient(b.containerName).NewBlobClient(blobName)
+
+	if _, err := blobClient.GetProperties(ctx, nil); err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check pause marker %s: %w", blobName, err)
+	}
+	return true, nil
+}
+
+// LoadFreezeList reads the freeze list stored at the given path, returning an empty list when none exists
+func (b *BlobStorageClient) LoadFreezeList(ctx context.Context, blobPath string) (*models.FreezeList, error) {
+	content, err := b.ReadFileFromBlob(ctx, blobPath)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return &models.FreezeList{}, nil
+		}
+		return nil, err
+	}
+
+	var freezeList models.FreezeList
+	if err := json.Unmarshal(content, &freezeList); err != nil {
+		return nil, fmt.Errorf("failed to parse freeze list %s: %w", blobPath, err)
+	}
+	return &freezeList, nil
+}
+
+// UpdateFreezeList applies an update to the freeze list at the given path. The list is written
+// only if nobody changed it since it was read, and the update is retried on the fresh list otherwise.
+func (b *BlobStorageClient) UpdateFreezeList(ctx context.Context, blobPath string, update func(*models.FreezeList) error) error {
+	err := UpdateBlobJSON(ctx, b.writerFor(blobPath), blobPath, func(freezeList *models.FreezeList, exists bool) error {
+		return update(freezeList)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store freeze list %s: %w", blobPath, err)
+	}
+
+	gologger.Debug().Msgf("Stored freeze list in blob: %s/%s", b.containerName, blobPath)
+	return nil
+}
+
+// LoadScanProfiles reads the scan profiles stored at the given path, returning none when none exist
+func (b *BlobStorageClient) LoadScanProfiles(ctx context.Context, blobPath string) (models.ScanProfiles, error) {
+	content, err := b.ReadFileFromBlob(ctx, blobPath)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	profiles, err := models.ParseScanProfiles(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scan profiles %s: %w", blobPath, err)
+	}
+	return profiles, nil
+}
+
+// LoadTemplatePolicy reads the template policy stored at the given path, returning nil when none exists
+func (b *BlobStorageClient) LoadTemplatePolicy(ctx context.Context, blobPath string) (*models.TemplatePolicy, error) {
+	content, err := b.ReadFileFromBlob(ctx, blobPath)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	policy, err := models.ParseTemplatePolicy(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template policy %s: %w", blobPath, err)
+	}
+	return policy, nil
+}
+
+// StoreScanStatus merges the stages of status into the stored status of the scan, keeping the
+// stages another worker stored a more recent status of in the meantime
+func (b *BlobStorageClient) StoreScanStatus(ctx context.Context, status *models.ScanStatus) error {
+	blobName := models.ScanStatusBlobPath(status.TenantID, status.ScanID)
+
+	err := UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(stored *models.ScanStatus, exists bool) error {
+		if !stored.Merge(status) {
+			return ErrBlobUnchanged
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store scan status %s: %w", blobName, err)
+	}
+
+	gologger.Debug().Msgf("Stored scan status: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// LoadScanStatus reads the status of a scan, returning nil when none was stored yet
+func (b *BlobStorageClient) LoadScanStatus(ctx context.Context, tenantID string, scanID int) (*models.ScanStatus, error) {
+	blobName := models.ScanStatusBlobPath(tenantID, scanID)
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var status models.ScanStatus
+	if err := json.Unmarshal(content, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse scan status %s: %w", blobName, err)
+	}
+	return &status, nil
+}
+
+// LoadIncidentState reads the incident state of a domain, returning an empty state when none exists
+func (b *BlobStorageClient) LoadIncidentState(ctx context.Context, tenantID, domain string) (*models.IncidentState, error) {
+	blobName := models.IncidentStateBlobPath(tenantID, domain)
+
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return &models.IncidentState{TenantID: tenantID, Domain: domain, Open: map[string]models.OpenIncident{}}, nil
+		}
+		return nil, err
+	}
+
+	var state models.IncidentState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse incident state %s: %w", blobName, err)
+	}
+	if state.Open == nil {
+		state.Open = map[string]models.OpenIncident{}
+	}
+	return &state, nil
+}
+
+// UpdateIncidentState applies an update to the incident state of a domain. The state is written
+// only if no other worker changed it since it was read, and the update is retried on the fresh
+// state otherwise, so incidents opened or resolved by concurrent tasks of the domain are kept.
+func (b *BlobStorageClient) UpdateIncidentState(ctx context.Context, tenantID, domain string, update func(*models.IncidentState) error) error {
+	blobName := models.IncidentStateBlobPath(tenantID, domain)
+
+	err := UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(state *models.IncidentState, exists bool) error {
+		state.TenantID, state.Domain = tenantID, domain
+		if state.Open == nil {
+			state.Open = map[string]models.OpenIncident{}
+		}
+		return update(state)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store incident state %s: %w", blobName, err)
+	}
+
+	gologger.Debug().Msgf("Stored incident state in blob: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// LoadTicketingConfig reads a tenant's ticketing configuration, returning nil when the tenant has none
+func (b *BlobStorageClient) LoadTicketingConfig(ctx context.Context, tenantID string) (*models.TicketingConfig, error) {
+	blobName := models.TicketingConfigBlobPath(tenantID)
+
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var config models.TicketingConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse ticketing config %s: %w", blobName, err)
+	}
+	return &config, nil
+}
+
+// LoadTicketState reads the ticket state of a domain, returning an empty state when none exists
+func (b *BlobStorageClient) LoadTicketState(ctx context.Context, tenantID, domain string) (*models.TicketState, error) {
+	blobName := models.TicketStateBlobPath(tenantID, domain)
+
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return &models.TicketState{TenantID: tenantID, Domain: domain, Tickets: map[string]models.OpenTicket{}}, nil
+		}
+		return nil, err
+	}
+
+	var state models.TicketState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse ticket state %s: %w", blobName, err)
+	}
+	if state.Tickets == nil {
+		state.Tickets = map[string]models.OpenTicket{}
+	}
+	return &state, nil
+}
+
+// UpdateTicketState applies an update to the ticket state of a domain. The state is written only
+// if no other worker changed it since it was read, and the update is retried on the fresh state
+// otherwise, so tickets created or closed by concurrent tasks of the domain are kept.
+func (b *BlobStorageClient) UpdateTicketState(ctx context.Context, tenantID, domain string, update func(*models.TicketState) error) error {
+	blobName := models.TicketStateBlobPath(tenantID, domain)
+
+	err := UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(state *models.TicketState, exists bool) error {
+		state.TenantID, state.Domain = tenantID, domain
+		if state.Tickets == nil {
+			state.Tickets = map[string]models.OpenTicket{}
+		}
+		return update(state)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store ticket state %s: %w", blobName, err)
+	}
+
+	gologger.Debug().Msgf("Stored ticket state in blob: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// UpdateQuotaState applies an update to an API key's quota state. The state is written only if no
+// other worker changed it since it was read, and the update is retried on the fresh state otherwise.
+func (b *BlobStorageClient) UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error {
+	blobName := models.QuotaStateBlobPath(key)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(state *models.QuotaState, exists bool) error {
+		if !exists {
+			state.Key = key
+		}
+		return update(state)
+	})
+}
+
+// UpdatePassiveSourceTotals applies an update to the running totals of a passive source. The
+// totals are written only if no other worker changed them since they were read, and the update
+// is retried on the fresh totals otherwise.
+func (b *BlobStorageClient) UpdatePassiveSourceTotals(ctx context.Context, source string, update func(*models.PassiveSourceTotals) error) error {
+	blobName := models.PassiveSourceTotalsBlobPath(source)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(totals *models.PassiveSourceTotals, exists bool) error {
+		if !exists {
+			totals.Source = source
+		}
+		return update(totals)
+	})
+}
+
+// UpdateWebhookCall applies an update to the record of a signed webhook call. API replicas
+// receiving the same call at once retry on the fresh record, so only one of them claims it.
+func (b *BlobStorageClient) UpdateWebhookCall(ctx context.Context, signature string, update func(*models.WebhookCall) error) error {
+	blobName := models.WebhookCallBlobPath(signature)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(call *models.WebhookCall, exists bool) error {
+		return update(call)
+	})
+}
+
+// UpdateInventory applies an update to a domain's asset inventory. Workers indexing results of the
+// same domain at once do not lose each other's assets, as the update is retried on the fresh state.
+func (b *BlobStorageClient) UpdateInventory(ctx context.Context, tenantID, domain string, update func(*models.Inventory) error) error {
+	blobName := models.InventoryBlobPath(tenantID, domain)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(inventory *models.Inventory, exists bool) error {
+		if !exists {
+			inventory.TenantID, inventory.Domain = tenantID, domain
+		}
+		return update(inventory)
+	})
+}
+
+// UpdateScopeSuggestions applies an update to the scope suggestions of a domain, retrying it on
+// the fresh state when a scan or a reviewer changed them concurrently
+func (b *BlobStorageClient) UpdateScopeSuggestions(ctx context.Context, tenantID, domain string, update func(*models.ScopeSuggestions) error) error {
+	blobName := models.ScopeSuggestionsBlobPath(tenantID, domain)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(suggestions *models.ScopeSuggestions, exists bool) error {
+		if !exists {
+			suggestions.TenantID, suggestions.Domain = tenantID, domain
+		}
+		return update(suggestions)
+	})
+}
+
+// UpdateScanScope applies an update to the scope imported from asset sources. Workers syncing
+// at once retry on the fresh state, so an asset is reported as new by one of them only.
+func (b *BlobStorageClient) UpdateScanScope(ctx context.Context, tenantID string, update func(*models.ScanScope) error) error {
+	blobName := models.ScanScopeBlobPath(tenantID)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(scope *models.ScanScope, exists bool) error {
+		if !exists {
+			scope.TenantID = tenantID
+		}
+		return update(scope)
+	})
+}
+
+// UpdateScanRetries applies an update to the retries charged to a scan. Workers retrying tasks
+// of the same scan at once retry on the fresh count, so no retry is lost.
+func (b *BlobStorageClient) UpdateScanRetries(ctx context.Context, tenantID string, scanID int, update func(*models.ScanRetries) error) error {
+	blobName := models.ScanRetriesBlobPath(tenantID, scanID)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(retries *models.ScanRetries, exists bool) error {
+		if !exists {
+			retries.ScanID = scanID
+			retries.TenantID = tenantID
+		}
+		return update(retries)
+	})
+}
+
+// UpdateScanQuality applies an update to the quality gate record of a scan. Workers checking
+// stages of the same scan at once update the fresh record, so no check is lost.
+func (b *BlobStorageClient) UpdateScanQuality(ctx context.Context, tenantID string, scanID int, update func(*models.ScanQuality) error) error {
+	blobName := models.ScanQualityBlobPath(tenantID, scanID)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(quality *models.ScanQuality, exists bool) error {
+		if !exists {
+			quality.ScanID = scanID
+			quality.TenantID = tenantID
+		}
+		return update(quality)
+	})
+}
+
+// UpdateMultiDomainProgress applies an update to the progress of a multi-domain message. The
+// domains of a message finish concurrently, so each update is retried on the fresh progress.
+func (b *BlobStorageClient) UpdateMultiDomainProgress(ctx context.Context, taskMsg *models.TaskMessage, update func(*models.MultiDomainProgress) error) error {
+	blobName := models.MultiDomainProgressBlobPath(taskMsg)
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(progress *models.MultiDomainProgress, exists bool) error {
+		if !exists {
+			progress.ID = models.MultiDomainProgressID(taskMsg)
+			progress.TenantID, progress.ScanID, progress.Task = taskMsg.TenantID, taskMsg.ScanID, taskMsg.Task
+			progress.Domains = make(map[string]models.DomainOutcome)
+		}
+		return update(progress)
+	})
+}
+
+// LoadMultiDomainProgress reads the progress of a multi-domain message, returning nil when no
+// earlier delivery finished any of its domains
+func (b *BlobStorageClient) LoadMultiDomainProgress(ctx context.Context, taskMsg *models.TaskMessage) (*models.MultiDomainProgress, error) {
+	blobName := models.MultiDomainProgressBlobPath(taskMsg)
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var progress models.MultiDomainProgress
+	if err := json.Unmarshal(content, &progress); err != nil {
+		return nil, fmt.Errorf("failed to parse multi-domain progress %s: %w", blobName, err)
+	}
+	return &progress, nil
+}
+
+// LoadScanQuality reads the quality gate record of a scan, returning nil when no stage was checked yet
+func (b *BlobStorageClient) LoadScanQuality(ctx context.Context, tenantID string, scanID int) (*models.ScanQuality, error) {
+	blobName := models.ScanQualityBlobPath(tenantID, scanID)
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var quality models.ScanQuality
+	if err := json.Unmarshal(content, &quality); err != nil {
+		return nil, fmt.Errorf("failed to parse scan quality %s: %w", blobName, err)
+	}
+	return &quality, nil
+}
+
+// ResetScanRetries clears the retries charged to a scan, giving it its full budget again
+func (b *BlobStorageClient) ResetScanRetries(ctx context.Context, tenantID string, scanID int) error {
+	blobName := models.ScanRetriesBlobPath(tenantID, scanID)
+	_, err := b.clientFor(blobName).DeleteBlob(ctx, b.containerName, blobName, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to reset scan retries %s: %w", blobName, err)
+	}
+	return nil
+}
+
+// checkpointBlobName returns the fixed blob name of a task's checkpoint
+func checkpointBlobName(tenantID, domain string, scanID int, task models.Task) string {
+	return fmt.Sprintf("%s%s/checkpoint.json", models.ScanBlobPrefix(tenantID, domain, scanID), task)
+}
+
+// StoreCheckpoint stores the checkpoint of a paused task, replacing any previous one
+func (b *BlobStorageClient) StoreCheckpoint(ctx context.Context, checkpoint *models.ScanCheckpoint) error {
+	blobName := checkpointBlobName(checkpoint.TenantID, checkpoint.Domain, checkpoint.ScanID, checkpoint.Task)
+
+	jsonData, err := json.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	_, err = b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, jsonData, uploadOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload checkpoint to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored checkpoint in blob: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// LoadCheckpoint reads the checkpoint of a task, returning nil when the task has none
+func (b *BlobStorageClient) LoadCheckpoint(ctx context.Context, tenantID, domain string, scanID int, task models.Task) (*models.ScanCheckpoint, error) {
+	blobName := checkpointBlobName(tenantID, domain, scanID, task)
+
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var checkpoint models.ScanCheckpoint
+	if err := json.Unmarshal(content, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", blobName, err)
+	}
+	return &checkpoint, nil
+}
+
+// DeleteCheckpoint removes the checkpoint of a task once it has been resumed to completion
+func (b *BlobStorageClient) DeleteCheckpoint(ctx context.Context, tenantID, domain string, scanID int, task models.Task) error {
+	blobName := checkpointBlobName(tenantID, domain, scanID, task)
+
+	_, err := b.clientFor(blobName).DeleteBlob(ctx, b.containerName, blobName, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to delete checkpoint %s: %w", blobName, err)
+	}
+	return nil
+}
+
+// StoreOutboxEntry stores a completion notification in its tenant's outbox, replacing an entry with its ID
+func (b *BlobStorageClient) StoreOutboxEntry(ctx context.Context, entry *models.OutboxEntry) error {
+	blobName := entry.BlobPath()
+	if err := b.checkResidency(entry.TaskMessage.TenantID, blobName); err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox entry: %w", err)
+	}
+
+	_, err = b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, jsonData, uploadOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload outbox entry to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored outbox entry in blob: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// UpdateOutboxEntry applies an update to the outbox entry at a blob path, retrying it on the fresh
+// entry when another worker changed it concurrently. An entry that no longer exists is left alone.
+func (b *BlobStorageClient) UpdateOutboxEntry(ctx context.Context, blobName string, update func(entry *models.OutboxEntry) bool) error {
+	return UpdateBlobJSON(ctx, b.writerFor(blobName), blobName, func(entry *models.OutboxEntry, exists bool) error {
+		if !exists || !update(entry) {
+			return ErrBlobUnchanged
+		}
+		return nil
+	})
+}
+
+// LoadOutboxEntry reads the outbox entry at a blob path, returning nil when it does not exist
+func (b *BlobStorageClient) LoadOutboxEntry(ctx context.Context, blobName string) (*models.OutboxEntry, error) {
+	content, err := b.ReadFileFromBlob(ctx, blobName)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entry models.OutboxEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse outbox entry %s: %w", blobName, err)
+	}
+	return &entry, nil
+}
+
+// ListOutboxEntries returns the blob paths of the entries in the outboxes of every tenant. Each
+// storage account is searched for the tenant folders it holds, so entries are found in the
+// account they were stored in even after their tenant was routed elsewhere.
+func (b *BlobStorageClient) ListOutboxEntries(ctx context.Context) ([]string, error) {
+	var paths []string
+	for _, region := range b.Regions() {
+		client := b.accounts[region].client
+		folders, err := b.listTenantFolders(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		prefixes := []string{models.OutboxBlobPrefix("")}
+		for _, tenantID := range folders {
+			prefixes = append(prefixes, models.OutboxBlobPrefix(tenantID))
+		}
+		for _, prefix := range prefixes {
+			blobs, err := b.listBlobsIn(ctx, client, prefix)
+			if err != nil {
+				return nil, err
+			}
+			for _, blob := range blobs {
+				if models.IsOutboxBlobPath(blob.Name) {
+					paths = append(paths, blob.Name)
+				}
+			}
+		}
+	}
+	return paths, nil
+}
+
+// DeleteOutboxEntry removes the outbox entry at a blob path
+func (b *BlobStorageClient) DeleteOutboxEntry(ctx context.Context, blobName string) error {
+	_, err := b.clientFor(blobName).DeleteBlob(ctx, b.containerName, blobName, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to delete outbox entry %s: %w", blobName, err)
+	}
+	return nil
+}
+
+// DeleteBlob removes a blob; a blob that does not exist is not an error
+func (b *BlobStorageClient) DeleteBlob(ctx context.Context, blobPath string) error {
+	cleanPath := b.CleanBlobPath(blobPath)
+	_, err := b.clientFor(cleanPath).DeleteBlob(ctx, b.containerName, cleanPath, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("failed to delete blob %s: %w", cleanPath, err)
+	}
+	return nil
+}
+
+// BlobInfo describes a blob returned by a listing
+type BlobInfo struct {
+	Name         string
+	ETag         string
+	ContentMD5   []byte
+	Size         int64
+	LastModified time.Time
+}
+
+// ListBlobs lists the blobs whose names start with the given prefix
+func (b *BlobStorageClient) ListBlobs(ctx context.Context, prefix string) ([]BlobInfo, error) {
+	cleanPrefix := b.CleanBlobPath(prefix)
+	return b.listBlobsIn(ctx, b.clientFor(cleanPrefix), cleanPrefix)
+}
+
+// listTenantFolders returns the top-level folders of a storage account that may belong to tenants
+func (b *BlobStorageClient) listTenantFolders(ctx context.Context, client *azblob.Client) ([]string, error) {
+	pager := client.ServiceClient().NewContainerClient(b.containerName).NewListBlobsHierarchyPager("/", nil)
+
+	var folders []string
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the folders of blob container %s: %w", b.containerName, err)
+		}
+		for _, prefix := range page.Segment.BlobPrefixes {
+			if prefix.Name == nil {
+				continue
+			}
+			if folder := strings.TrimSuffix(*prefix.Name, "/"); models.IsTenantFolder(folder) {
+				folders = append(folders, folder)
+			}
+		}
+	}
+	return folders, nil
+}
+
+// listBlobsIn lists the blobs of a storage account whose names start with the given prefix
+func (b *BlobStorageClient) listBlobsIn(ctx context.Context, client *azblob.Client, cleanPrefix string) ([]BlobInfo, error) {
+	pager := client.NewListBlobsFlatPager(b.containerName, &azblob.ListBlobsFlatOptions{Prefix: &cleanPrefix})
+
+	var blobs []BlobInfo
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs under %s: %w", cleanPrefix, err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			info := BlobInfo{Name: *item.Name}
+			if props := item.Properties; props != nil {
+				if props.ETag != nil {
+					info.ETag = string(*props.ETag)
+				}
+				if props.ContentLength != nil {
+					info.Size = *props.ContentLength
+				}
+				info.ContentMD5 = props.ContentMD5
+				if props.LastModified != nil {
+					info.LastModified = *props.LastModified
+				}
+			}
+			blobs = append(blobs, info)
+		}
+	}
+
+	gologger.Debug().Msgf("Listed %d blobs under %s/%s", len(blobs), b.containerName, cleanPrefix)
+	return blobs, nil
+}
+
+// uploadOptions tags an upload with the correlation ID of the task it is made for, so blobs can be
+// traced back to the task that wrote them
+func uploadOptions(ctx context.Context) *azblob.UploadBufferOptions {
+	correlationID := models.CorrelationIDFromContext(ctx)
+	if correlationID == "" {
+		return &azblob.UploadBufferOptions{}
+	}
+	return &azblob.UploadBufferOptions{Metadata: map[string]*string{"correlation_id": &correlationID}}
+}
+
+// CleanBlobPath removes the container name from the path if it's already included
+func (b *BlobStorageClient) CleanBlobPath(blobPath string) string {
 	// If the path starts with the container name, remove it
 	if strings.HasPrefix(blobPath, b.containerName+"/") {
 		return strings.TrimPrefix(blobPath, b.containerName+"/")
@@ -70,10 +1009,10 @@ func (b *BlobStorageClient) cleanBlobPath(blobPath string) string {
 // ReadFileFromBlob reads a file from blob storage
 func (b *BlobStorageClient) ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error) {
 	// Clean the blob path
-	cleanPath := b.cleanBlobPath(blobPath)
+	cleanPath := b.CleanBlobPath(blobPath)
 
 	// Download from blob storage
-	response, err := b.client.DownloadStream(ctx, b.containerName, cleanPath, &azblob.DownloadStreamOptions{})
+	response, err := b.clientFor(cleanPath).DownloadStream(ctx, b.containerName, cleanPath, &azblob.DownloadStreamOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file from blob storage: %w", err)
 	}
@@ -89,44 +1028,221 @@ func (b *BlobStorageClient) ReadFileFromBlob(ctx context.Context, blobPath strin
 	return content, nil
 }
 
-// ReadHostsFileFromBlob reads a hosts file from blob storage and returns the content as string
-func (b *BlobStorageClient) ReadHostsFileFromBlob(ctx context.Context, blobPath string) (string, error) {
+// OpenBlobRange opens a blob for reading from offset to its end, so a reader of a large blob can
+// start where the data it needs begins
+func (b *BlobStorageClient) OpenBlobRange(ctx context.Context, blobPath string, offset int64) (io.ReadCloser, error) {
+	cleanPath := b.CleanBlobPath(blobPath)
+	response, err := b.clientFor(cleanPath).DownloadStream(ctx, b.containerName, cleanPath, &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{Offset: offset},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob %s from offset %d: %w", cleanPath, offset, err)
+	}
+	return response.Body, nil
+}
+
+// BlobDownloadURL returns a read-only SAS URL of a blob that expires after ttl. The storage
+// account must be connected with an account key to sign it.
+func (b *BlobStorageClient) BlobDownloadURL(blobPath string, ttl time.Duration) (string, error) {
+	cleanPath := b.CleanBlobPath(blobPath)
+	blobClient := b.clientFor(cleanPath).ServiceClient().NewContainerClient(b.containerName).NewBlobClient(cleanPath)
+	url, err := blobClient.GetSASURL(sas.BlobPermissions{Read: true}, time.Now().Add(ttl), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign download URL of blob %s: %w", cleanPath, err)
+	}
+	return url, nil
+}
+
+// ReadHostsFileFromBlob reads and parses a hosts file from blob storage. Plain text, gzip and
+// JSON files, including previous task results, are accepted.
+func (b *BlobStorageClient) ReadHostsFileFromBlob(ctx context.Context, blobPath string) (*utils.HostsFile, error) {
 	// Clean the blob path to prevent double container names
-	cleanPath := b.cleanBlobPath(blobPath)
+	cleanPath := b.CleanBlobPath(blobPath)
 
 	content, err := b.ReadFileFromBlob(ctx, cleanPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read hosts file from blob %s: %w", cleanPath, err)
+		return nil, fmt.Errorf("failed to read hosts file from blob %s: %w", cleanPath, err)
+	}
+
+	hostsFile, err := utils.ParseHostsFile(cleanPath, content)
+	if err != nil {
+		return nil, err
+	}
+	if hostsFile.RecordsBlob != "" {
+		if err := b.readDNSRecords(ctx, hostsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	gologger.Debug().Msgf("Parsed hosts file %s as %s (compressed: %t) with %d hosts", cleanPath, hostsFile.Format, hostsFile.Compressed, len(hostsFile.Hosts))
+	return hostsFile, nil
+}
+
+// readDNSRecords reads the hosts of a streamed DNSX result from its records blob as it downloads
+func (b *BlobStorageClient) readDNSRecords(ctx context.Context, hostsFile *utils.HostsFile) error {
+	recordsPath := b.CleanBlobPath(hostsFile.RecordsBlob)
+	response, err := b.clientFor(recordsPath).DownloadStream(ctx, b.containerName, recordsPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download DNSX records %s: %w", recordsPath, err)
+	}
+	defer response.Body.Close()
+
+	if err := utils.ReadDNSRecordLines(response.Body, hostsFile); err != nil {
+		return fmt.Errorf("failed to read DNSX records %s: %w", recordsPath, err)
+	}
+	hostsFile.Compressed = true
+	return nil
+}
+
+// ExpandDNSRecords returns a streamed DNSX result with the records of its records blob inline, for
+// consumers that index every record. Results with inline records are returned as they are.
+func (b *BlobStorageClient) ExpandDNSRecords(ctx context.Context, result models.DNSXResult) (models.DNSXResult, error) {
+	if result.RecordsBlob == "" || result.Records != nil {
+		return result, nil
+	}
+	recordsPath := b.CleanBlobPath(result.RecordsBlob)
+	response, err := b.clientFor(recordsPath).DownloadStream(ctx, b.containerName, recordsPath, nil)
+	if err != nil {
+		return result, fmt.Errorf("failed to download DNSX records %s: %w", recordsPath, err)
 	}
+	defer response.Body.Close()
 
-	return string(content), nil
+	records, err := utils.ReadDNSRecords(response.Body)
+	if err != nil {
+		return result, fmt.Errorf("failed to read DNSX records %s: %w", recordsPath, err)
+	}
+	result.Records = records
+	return result, nil
 }
 
-// StoreSubfinderTextResult stores a plain text file of subfinder subdomains in blob storage
-func (b *BlobStorageClient) StoreSubfinderTextResult(ctx context.Context, result *models.SubfinderResult, scanID int, task string) error {
-	randomID := uuid.New().String()
-	blobName := fmt.Sprintf("%s-%d/%s/out/%s.txt", result.Domain, scanID, task, randomID)
+// StoreSubfinderTextResult stores a plain text file of subfinder subdomains in blob storage, points
+// the task's latest result at it and returns its blob path
+func (b *BlobStorageClient) StoreSubfinderTextResult(ctx context.Context, result *models.SubfinderResult, tenantID string, scanID int, task string, attempt int, duration string) (string, error) {
+	blobName := models.TaskBlobPrefix(tenantID, result.Domain, scanID, task) + "out/" + models.AttemptBlobName(attempt, ".txt")
 	txtContent := strings.Join(result.Subdomains, "\n")
+	if err := b.checkResidency(tenantID, blobName); err != nil {
+		return "", err
+	}
 
-	_, err := b.client.UploadBuffer(ctx, b.containerName, blobName, []byte(txtContent), &azblob.UploadBufferOptions{})
+	_, err := b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, []byte(txtContent), uploadOptions(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to upload subfinder text result to blob storage: %w", err)
+		return "", fmt.Errorf("failed to upload subfinder text result to blob storage: %w", err)
 	}
 
 	gologger.Debug().Msgf("Stored subfinder txt result in blob: %s/%s", b.containerName, blobName)
+	if err := b.storeLatestResult(ctx, tenantID, result.Domain, scanID, task, attempt, duration, blobName); err != nil {
+		return "", err
+	}
+	return blobName, nil
+}
+
+// StoreSubfinderSourcesResult stores the JSON subfinder result, with the sources of every
+// subdomain, next to its text result
+func (b *BlobStorageClient) StoreSubfinderSourcesResult(ctx context.Context, result *models.SubfinderResult, tenantID string, scanID int, task string, attempt int) error {
+	blobName := models.TaskBlobPrefix(tenantID, result.Domain, scanID, task) + "out/" + models.AttemptBlobName(attempt, ".sources.json")
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subfinder sources: %w", err)
+	}
+
+	if _, err := b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, jsonData, uploadOptions(ctx)); err != nil {
+		return fmt.Errorf("failed to upload subfinder sources to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored subfinder sources in blob: %s/%s", b.containerName, blobName)
+	return nil
+}
+
+// StoreArtifact stores a scanner artifact at the given blob path
+func (b *BlobStorageClient) StoreArtifact(ctx context.Context, blobPath string, data []byte) error {
+	cleanPath := b.CleanBlobPath(blobPath)
+	if _, err := b.clientFor(cleanPath).UploadBuffer(ctx, b.containerName, cleanPath, data, uploadOptions(ctx)); err != nil {
+		return fmt.Errorf("failed to upload artifact %s to blob storage: %w", cleanPath, err)
+	}
+
+	gologger.Debug().Msgf("Stored artifact in blob: %s/%s", b.containerName, cleanPath)
+	return nil
+}
+
+// StreamArtifact starts uploading a scanner artifact at the given blob path and returns the writer
+// its content goes to. Content is uploaded in blocks as it is written, so the artifact is never
+// held in memory. Close completes the upload and returns its error.
+func (b *BlobStorageClient) StreamArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error) {
+	return b.streamArtifact(ctx, blobPath, azblob.UploadStreamOptions{})
+}
+
+// StreamNewArtifact streams an artifact like StreamArtifact, but only creates it: Close fails
+// with a BlobAlreadyExists error and leaves the blob as it was if it already exists
+func (b *BlobStorageClient) StreamNewArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error) {
+	return b.streamArtifact(ctx, blobPath, azblob.UploadStreamOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)},
+		},
+	})
+}
+
+func (b *BlobStorageClient) streamArtifact(ctx context.Context, blobPath string, options azblob.UploadStreamOptions) (io.WriteCloser, error) {
+	cleanPath := b.CleanBlobPath(blobPath)
+	reader, writer := io.Pipe()
+	stream := &blobStream{writer: writer, done: make(chan error, 1)}
+
+	if correlationID := models.CorrelationIDFromContext(ctx); correlationID != "" {
+		options.Metadata = map[string]*string{"correlation_id": &correlationID}
+	}
+	go func() {
+		_, err := b.clientFor(cleanPath).UploadStream(ctx, b.containerName, cleanPath, reader, &options)
+		if err != nil {
+			err = fmt.Errorf("failed to upload artifact %s to blob storage: %w", cleanPath, err)
+		} else {
+			gologger.Debug().Msgf("Stored streamed artifact in blob: %s/%s", b.containerName, cleanPath)
+		}
+		// Unblock writes if the upload gave up early
+		reader.CloseWithError(err)
+		stream.done <- err
+	}()
+	return stream, nil
+}
+
+// blobStream is the writing end of a streamed blob upload
+type blobStream struct {
+	writer *io.PipeWriter
+	done   chan error
+}
+
+// Write hands content to the upload, blocking until the upload has taken it
+func (s *blobStream) Write(p []byte) (int, error) {
+	return s.writer.Write(p)
+}
+
+// Close ends the content and waits for the upload to complete
+func (s *blobStream) Close() error {
+	s.writer.Close()
+	return <-s.done
+}
+
+// StoreNaabuXMLResult stores an nmap XML report of naabu results next to the JSON result
+func (b *BlobStorageClient) StoreNaabuXMLResult(ctx context.Context, xmlData []byte, tenantID, domain string, scanID int, task string, attempt int) error {
+	blobName := models.TaskBlobPrefix(tenantID, domain, scanID, task) + "out/" + models.AttemptBlobName(attempt, ".xml")
+
+	_, err := b.clientFor(blobName).UploadBuffer(ctx, b.containerName, blobName, xmlData, uploadOptions(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to upload naabu XML result to blob storage: %w", err)
+	}
+
+	gologger.Debug().Msgf("Stored naabu XML result in blob: %s/%s", b.containerName, blobName)
 	return nil
 }
 
 // DownloadFile downloads a blob from Azure Blob Storage and saves it to a local file path
 func (b *BlobStorageClient) DownloadFile(ctx context.Context, blobPath string, localPath string) error {
-	cleanPath := b.cleanBlobPath(blobPath)
+	cleanPath := b.CleanBlobPath(blobPath)
 	file, err := os.Create(localPath)
 	if err != nil {
 		return fmt.Errorf("failed to create local file %s: %w", localPath, err)
 	}
 	defer file.Close()
 
-	response, err := b.client.DownloadStream(ctx, b.containerName, cleanPath, &azblob.DownloadStreamOptions{})
+	response, err := b.clientFor(cleanPath).DownloadStream(ctx, b.containerName, cleanPath, &azblob.DownloadStreamOptions{})
 	if err != nil {
 		return fmt.Errorf("failed to download blob %s: %w", cleanPath, err)
 	}
@@ -0,0 +1,141 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/azure/azuretest"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func newTestBlobClient(t *testing.T) (*BlobStorageClient, *azuretest.Server) {
+	t.Helper()
+	server := azuretest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := NewBlobStorageClient(server.ConnectionString(), "scans")
+	if err != nil {
+		t.Fatalf("NewBlobStorageClient failed: %v", err)
+	}
+	return client, server
+}
+
+func TestBlobStorageClientAgainstTestServer(t *testing.T) {
+	client, server := newTestBlobClient(t)
+	ctx := context.Background()
+
+	if err := client.ValidateStorage(ctx, true); err != nil {
+		t.Fatalf("ValidateStorage failed: %v", err)
+	}
+
+	// Pause markers are probed with the blob's properties
+	if paused, err := client.IsScanPaused(ctx, "acme", 7); err != nil || paused {
+		t.Fatalf("IsScanPaused() = %t, %v before pausing", paused, err)
+	}
+	if err := client.SetScanPaused(ctx, "acme", 7, true); err != nil {
+		t.Fatal(err)
+	}
+	if paused, err := client.IsScanPaused(ctx, "acme", 7); err != nil || !paused {
+		t.Fatalf("IsScanPaused() = %t, %v after pausing", paused, err)
+	}
+	if err := client.SetScanPaused(ctx, "acme", 7, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetScanPaused(ctx, "acme", 7, false); err != nil {
+		t.Errorf("Resuming a scan that is not paused failed: %v", err)
+	}
+
+	checkpoint := &models.ScanCheckpoint{Task: models.TaskHttpx, ScanID: 7, Domain: "example.com", TenantID: "acme", ProcessedTargets: []string{"www.example.com"}}
+	if err := client.StoreCheckpoint(ctx, checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := client.LoadCheckpoint(ctx, "acme", "example.com", 7, models.TaskHttpx)
+	if err != nil || loaded == nil || len(loaded.ProcessedTargets) != 1 {
+		t.Fatalf("LoadCheckpoint() = %+v, %v", loaded, err)
+	}
+	if err := client.DeleteCheckpoint(ctx, "acme", "example.com", 7, models.TaskHttpx); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := client.LoadCheckpoint(ctx, "acme", "example.com", 7, models.TaskHttpx); err != nil || loaded != nil {
+		t.Errorf("LoadCheckpoint() = %+v, %v after deleting it", loaded, err)
+	}
+
+	// Streamed artifacts are uploaded in blocks
+	writer, err := client.StreamArtifact(ctx, "acme/example.com-7/httpx/out/attempt-1.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(writer, strings.Repeat("line\n", 1000))
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Closing the streamed artifact failed: %v", err)
+	}
+	content, ok := server.Blob("scans", "acme/example.com-7/httpx/out/attempt-1.jsonl")
+	if !ok || len(content) != 5000 {
+		t.Errorf("Streamed artifact has %d bytes, want 5000", len(content))
+	}
+
+	reader, err := client.OpenBlobRange(ctx, "acme/example.com-7/httpx/out/attempt-1.jsonl", 4995)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tail, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(tail, []byte("line\n")) {
+		t.Errorf("OpenBlobRange() read %q, want the last line", tail)
+	}
+
+	blobs, err := client.ListBlobs(ctx, "acme/example.com-")
+	if err != nil || len(blobs) != 1 || blobs[0].Size != 5000 || blobs[0].LastModified.IsZero() {
+		t.Errorf("ListBlobs() = %+v, %v", blobs, err)
+	}
+}
+
+func TestStoreTaskResultLeavesLatestForVerifyResults(t *testing.T) {
+	client, server := newTestBlobClient(t)
+	ctx := context.Background()
+
+	result := &models.TaskResult{Task: models.TaskHttpx, ScanID: 7, Domain: "example.com", Status: models.TaskStatusCompleted, Attempt: 1}
+	if _, err := client.StoreTaskResult(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+	verify := *result
+	verify.Attempt, verify.Mode = 2, models.TaskModeVerify
+	blobPath, err := client.StoreTaskResult(ctx, &verify)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Blob("scans", blobPath); !ok {
+		t.Errorf("verify result %s not stored", blobPath)
+	}
+
+	latest, err := client.LoadLatestResult(ctx, "", "example.com", 7, string(models.TaskHttpx))
+	if err != nil || latest == nil || latest.Attempt != 1 {
+		t.Errorf("LoadLatestResult() = %+v, %v, want attempt 1", latest, err)
+	}
+}
+
+func TestListOutboxEntriesOfEveryTenant(t *testing.T) {
+	client, server := newTestBlobClient(t)
+	ctx := context.Background()
+
+	for _, tenantID := range []string{"", "acme"} {
+		entry := &models.OutboxEntry{ID: "entry", TaskMessage: models.TaskMessage{TenantID: tenantID}}
+		if err := client.StoreOutboxEntry(ctx, entry); err != nil {
+			t.Fatalf("StoreOutboxEntry(%q) failed: %v", tenantID, err)
+		}
+	}
+	server.PutBlob("scans", "example.com-7/subfinder/latest.json", []byte(`{}`))
+
+	paths, err := client.ListOutboxEntries(ctx)
+	if err != nil {
+		t.Fatalf("ListOutboxEntries failed: %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "control/outbox/entry.json" || paths[1] != "acme/control/outbox/entry.json" {
+		t.Fatalf("ListOutboxEntries() = %v, want the entries of both tenants", paths)
+	}
+	if entry, err := client.LoadOutboxEntry(ctx, paths[1]); err != nil || entry == nil || entry.TaskMessage.TenantID != "acme" {
+		t.Errorf("LoadOutboxEntry(%s) = %+v, %v", paths[1], entry, err)
+	}
+}
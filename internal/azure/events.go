@@ -0,0 +1,136 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// eventGridEventType is the Event Grid event type of result events
+const eventGridEventType = "AllSafe.ASM.ResultAvailable"
+
+// ResultPublisher announces stored task results to downstream consumers
+type ResultPublisher interface {
+	PublishResult(ctx context.Context, event models.ResultEvent) error
+	Close(ctx context.Context) error
+}
+
+// TopicPublisher publishes result events to a Service Bus topic. The scan, task, tenant and
+// status are set as application properties so subscriptions can filter on them.
+type TopicPublisher struct {
+	sender *azservicebus.Sender
+	topic  string
+}
+
+// NewTopicPublisher creates a publisher for a topic in the client's namespace
+func (s *ServiceBusClient) NewTopicPublisher(topic string) (*TopicPublisher, error) {
+	sender, err := s.client.NewSender(topic, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sender for topic %s: %w", topic, err)
+	}
+	return &TopicPublisher{sender: sender, topic: topic}, nil
+}
+
+// PublishResult sends a result event to the topic. The event ID is used as the message ID, so
+// topics with duplicate detection drop events repeated by redelivered tasks.
+func (p *TopicPublisher) PublishResult(ctx context.Context, event models.ResultEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result event: %w", err)
+	}
+
+	contentType := "application/json"
+	subject := event.Subject()
+	messageID := event.ID()
+	message := &azservicebus.Message{
+		Body:          body,
+		ContentType:   &contentType,
+		Subject:       &subject,
+		MessageID:     &messageID,
+		CorrelationID: nonEmpty(event.CorrelationID),
+		ApplicationProperties: map[string]interface{}{
+			"event_type": event.EventType,
+			"scan_id":    event.ScanID,
+			"task":       string(event.Task),
+			"tenant_id":  event.TenantID,
+			"status":     string(event.Status),
+		},
+	}
+
+	if err := p.sender.SendMessage(ctx, message, nil); err != nil {
+		return fmt.Errorf("failed to send result event to topic %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Close closes the topic sender
+func (p *TopicPublisher) Close(ctx context.Context) error {
+	return p.sender.Close(ctx)
+}
+
+// eventGridEvent is an event in the Event Grid schema
+type eventGridEvent struct {
+	ID          string             `json:"id"`
+	EventType   string             `json:"eventType"`
+	Subject     string             `json:"subject"`
+	EventTime   string             `json:"eventTime"`
+	Data        models.ResultEvent `json:"data"`
+	DataVersion string             `json:"dataVersion"`
+}
+
+// EventGridPublisher publishes result events to an Event Grid custom topic
+type EventGridPublisher struct {
+	endpoint   string
+	key        string
+	httpClient *http.Client
+}
+
+// NewEventGridPublisher creates a publisher for the topic endpoint, authenticating with its access key
+func NewEventGridPublisher(endpoint, key string, timeout time.Duration) *EventGridPublisher {
+	return &EventGridPublisher{endpoint: endpoint, key: key, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// PublishResult sends a result event to the topic
+func (p *EventGridPublisher) PublishResult(ctx context.Context, event models.ResultEvent) error {
+	body, err := json.Marshal([]eventGridEvent{{
+		ID:          event.ID(),
+		EventType:   eventGridEventType,
+		Subject:     event.Subject(),
+		EventTime:   event.Timestamp,
+		Data:        event,
+		DataVersion: "1.0",
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal result event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create Event Grid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("aeg-sas-key", p.key)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Event Grid request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close releases the publisher; Event Grid requests hold no connection state
+func (p *EventGridPublisher) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/allsafeASM/api/internal/faults"
+)
+
+// blobFaultPolicy delays blob requests before they are sent. The pipeline is built with the
+// client, so the injector is set on the policy afterwards.
+type blobFaultPolicy struct {
+	injector atomic.Pointer[faults.Injector]
+}
+
+// Do delays the request as the injector decides and then sends it
+func (p *blobFaultPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if err := p.injector.Load().DelayBlob(req.Raw().Context()); err != nil {
+		return nil, err
+	}
+	return req.Next()
+}
+
+// faultyRenewer fails lock renewals as the injector decides before renewing for real
+type faultyRenewer struct {
+	lockRenewer
+	faults *faults.Injector
+}
+
+// RenewMessageLock renews the lock unless the injector fails the renewal
+func (r *faultyRenewer) RenewMessageLock(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error {
+	if err := r.faults.FailLockRenewal(); err != nil {
+		return err
+	}
+	return r.lockRenewer.RenewMessageLock(ctx, msg, options)
+}
@@ -0,0 +1,87 @@
+package azure
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// lockRenewCallTimeout bounds a single lock renewal request
+const lockRenewCallTimeout = 30 * time.Second
+
+// lockRenewer renews message locks; *azservicebus.Receiver implements it
+type lockRenewer interface {
+	RenewMessageLock(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error
+}
+
+// lockRenewal keeps the lock of a message alive in the background. It runs on its own context,
+// so it keeps renewing after the handler's context ends while the message is abandoned or
+// completed, and stops only when stop is called or renewFor has passed.
+type lockRenewal struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	failed chan error
+}
+
+// startLockRenewal renews the message lock right away and then every interval, recording each
+// renewal on lock. A failed renewal marks lock as lost, is reported on Failed and ends the renewal.
+func startLockRenewal(ctx context.Context, renewer lockRenewer, message *azservicebus.ReceivedMessage, lock *models.MessageLock, interval, renewFor time.Duration) *lockRenewal {
+	renewCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), renewFor)
+	r := &lockRenewal{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		failed: make(chan error, 1),
+	}
+	go r.run(renewCtx, renewer, message, lock, interval)
+	return r
+}
+
+// run renews the lock until ctx ends or a renewal fails
+func (r *lockRenewal) run(ctx context.Context, renewer lockRenewer, message *azservicebus.ReceivedMessage, lock *models.MessageLock, interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, lockRenewCallTimeout)
+		err := renewer.RenewMessageLock(callCtx, message, nil)
+		cancel()
+
+		if ctx.Err() != nil {
+			gologger.Debug().Msg("Lock renewal stopped")
+			return
+		}
+		if err != nil {
+			gologger.Warning().Msgf("Failed to renew message lock: %v", err)
+			lock.MarkLost()
+			r.failed <- err
+			return
+		}
+		if message.LockedUntil != nil {
+			lock.Renewed(*message.LockedUntil)
+		}
+		gologger.Debug().Msg("Message lock renewed successfully")
+
+		select {
+		case <-ctx.Done():
+			gologger.Debug().Msg("Lock renewal stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Failed returns a channel that receives the error of the renewal that failed
+func (r *lockRenewal) Failed() <-chan error {
+	return r.failed
+}
+
+// stop ends the renewal and waits for its goroutine to exit
+func (r *lockRenewal) stop() {
+	r.cancel()
+	<-r.done
+}
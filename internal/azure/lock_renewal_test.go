@@ -0,0 +1,206 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// fakeRenewer extends message locks by a minute, failing from the failAt-th call on when set
+type fakeRenewer struct {
+	mu     sync.Mutex
+	calls  int
+	failAt int
+}
+
+func (f *fakeRenewer) RenewMessageLock(ctx context.Context, msg *azservicebus.ReceivedMessage, options *azservicebus.RenewMessageLockOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failAt > 0 && f.calls >= f.failAt {
+		return errors.New("lock lost")
+	}
+	lockedUntil := time.Now().Add(time.Minute)
+	msg.LockedUntil = &lockedUntil
+	return nil
+}
+
+func (f *fakeRenewer) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// testTaskMessage is the decoded body of newTestMessage
+var testTaskMessage = models.TaskMessage{Task: models.TaskSubfinder, ScanID: 1, Domain: "example.com"}
+
+func newTestMessage(lockedFor time.Duration) *azservicebus.ReceivedMessage {
+	lockedUntil := time.Now().Add(lockedFor)
+	return &azservicebus.ReceivedMessage{
+		MessageID:   "m-1",
+		Body:        []byte(`{"task":"subfinder","scan_id":1,"domain":"example.com"}`),
+		LockedUntil: &lockedUntil,
+	}
+}
+
+func TestLockRenewal_OutlivesParentContext(t *testing.T) {
+	renewer := &fakeRenewer{}
+	message := newTestMessage(time.Second)
+	lock := models.NewMessageLock(*message.LockedUntil, time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	renewal := startLockRenewal(ctx, renewer, message, lock, 10*time.Millisecond, time.Hour)
+	cancel()
+
+	time.Sleep(60 * time.Millisecond)
+	renewal.stop()
+
+	calls := renewer.Calls()
+	if calls < 3 {
+		t.Errorf("Expected renewals to continue after the parent context ended, got %d", calls)
+	}
+	if lock.LockedUntil().Before(time.Now().Add(50 * time.Second)) {
+		t.Errorf("Expected the lock to record the renewed expiry, got %s", lock.LockedUntil())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if renewer.Calls() != calls {
+		t.Error("Expected no renewals after stop returned")
+	}
+}
+
+func TestLockRenewal_Failure(t *testing.T) {
+	renewer := &fakeRenewer{failAt: 2}
+	message := newTestMessage(time.Second)
+	lock := models.NewMessageLock(*message.LockedUntil, time.Now().Add(time.Hour))
+
+	renewal := startLockRenewal(context.Background(), renewer, message, lock, 10*time.Millisecond, time.Hour)
+	defer renewal.stop()
+
+	select {
+	case err := <-renewal.Failed():
+		if err == nil || !lock.IsLost() {
+			t.Errorf("Expected the failure reported and the lock lost, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the failed renewal to be reported")
+	}
+}
+
+func TestHoldPrefetched(t *testing.T) {
+	renewer := &fakeRenewer{}
+	messages := []*azservicebus.ReceivedMessage{newTestMessage(time.Second), newTestMessage(time.Second)}
+
+	renewals := holdPrefetched(context.Background(), renewer, messages, 10*time.Millisecond, time.Hour)
+	time.Sleep(60 * time.Millisecond)
+	renewals[0].stop()
+	first := renewer.Calls()
+	time.Sleep(30 * time.Millisecond)
+	stopRenewals(renewals)
+
+	if first < 6 {
+		t.Errorf("Expected both waiting messages renewed, got %d renewals", first)
+	}
+	if renewer.Calls() == first {
+		t.Error("Expected the second message still renewed after the first was taken")
+	}
+	for _, message := range messages {
+		if time.Until(*message.LockedUntil) < 50*time.Second {
+			t.Errorf("Expected the waiting lock extended, got %s", message.LockedUntil)
+		}
+	}
+}
+
+func TestProcessMessageWithRenewal_RenewsWhileHandlerStops(t *testing.T) {
+	renewer := &fakeRenewer{}
+	processor := &MessageProcessor{receiver: renewer, stopTimeout: 5 * time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	handler := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		<-ctx.Done()
+		time.Sleep(1200 * time.Millisecond) // Winding down past the next renewal
+		return &models.MessageProcessingResult{Success: false, Error: ctx.Err()}
+	}
+
+	result := processor.processMessageWithRenewal(ctx, newTestMessage(time.Minute), testTaskMessage, handler, time.Second, time.Hour)
+	if result.Success || !result.Retryable {
+		t.Errorf("Expected a retryable failure for the timed-out handler, got %+v", result)
+	}
+
+	calls := renewer.Calls()
+	if calls < 2 {
+		t.Errorf("Expected the lock renewed while the handler stopped, got %d renewals", calls)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if renewer.Calls() != calls {
+		t.Error("Expected the renewal goroutine to have exited on return")
+	}
+}
+
+func TestProcessMessageWithRenewal_LockLost(t *testing.T) {
+	renewer := &fakeRenewer{failAt: 1}
+	processor := &MessageProcessor{receiver: renewer, stopTimeout: time.Second}
+
+	sawLoss := false
+	handler := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		select {
+		case <-models.MessageLockFromContext(ctx).Lost():
+			sawLoss = true
+		case <-time.After(time.Second):
+		}
+		return &models.MessageProcessingResult{Success: false, Error: errors.New("checkpointed")}
+	}
+
+	result := processor.processMessageWithRenewal(context.Background(), newTestMessage(500*time.Millisecond), testTaskMessage, handler, time.Second, time.Hour)
+	if !sawLoss {
+		t.Error("Expected the handler to see the lost lock through its context")
+	}
+	if result.Success || !strings.Contains(result.Error.Error(), "lock renewal failed") {
+		t.Errorf("Expected a lock renewal failure, got %+v", result)
+	}
+}
+
+func TestMessageAttempt(t *testing.T) {
+	message := &azservicebus.ReceivedMessage{DeliveryCount: 2}
+	if got := messageAttempt(message); got != 2 {
+		t.Errorf("Expected the second delivery to be attempt 2, got %d", got)
+	}
+
+	message = &azservicebus.ReceivedMessage{DeliveryCount: 1, ApplicationProperties: map[string]any{attemptsProperty: int64(3)}}
+	if got := messageAttempt(message); got != 4 {
+		t.Errorf("Expected deliveries before the retry to count, got attempt %d", got)
+	}
+
+	if got := messageAttempt(&azservicebus.ReceivedMessage{}); got != 1 {
+		t.Errorf("Expected a message without delivery count to be attempt 1, got %d", got)
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT1M":    time.Minute,
+		"PT30S":   30 * time.Second,
+		"PT2M30S": 150 * time.Second,
+		"PT5M":    5 * time.Minute,
+	}
+	for value, want := range tests {
+		got, err := parseISODuration(value)
+		if err != nil || got != want {
+			t.Errorf("parseISODuration(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "PT", "P1D", "1m"} {
+		if _, err := parseISODuration(value); err == nil {
+			t.Errorf("parseISODuration(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	// optimisticWriteAttempts bounds the retries of an update that keeps losing to other workers
+	optimisticWriteAttempts = 5
+	// optimisticWriteBackoff is the base of the jittered wait before retrying a lost update
+	optimisticWriteBackoff = 50 * time.Millisecond
+)
+
+// ErrBlobUnchanged is returned by an update function to leave the blob as it is
+var ErrBlobUnchanged = errors.New("blob unchanged")
+
+// ErrBlobContended is returned when a blob kept changing for every attempt of an update
+var ErrBlobContended = errors.New("blob kept changing concurrently")
+
+// conditionalBlobs reads and writes blobs; *azblob.Client implements it
+type conditionalBlobs interface {
+	DownloadStream(ctx context.Context, containerName, blobName string, o *azblob.DownloadStreamOptions) (azblob.DownloadStreamResponse, error)
+	UploadBuffer(ctx context.Context, containerName, blobName string, buffer []byte, o *azblob.UploadBufferOptions) (azblob.UploadBufferResponse, error)
+}
+
+// OptimisticBlobWriter updates blobs that several workers write, such as latest result pointers,
+// scan statuses and quota states. An update is written only if the blob's ETag is still the one
+// that was read, and is retried on the fresh content otherwise, so no worker's update is lost.
+type OptimisticBlobWriter struct {
+	blobs         conditionalBlobs
+	containerName string
+	attempts      int
+	backoff       time.Duration
+}
+
+// NewOptimisticBlobWriter creates a writer for the blobs of a container
+func NewOptimisticBlobWriter(client *azblob.Client, containerName string) *OptimisticBlobWriter {
+	return &OptimisticBlobWriter{
+		blobs:         client,
+		containerName: containerName,
+		attempts:      optimisticWriteAttempts,
+		backoff:       optimisticWriteBackoff,
+	}
+}
+
+// Update reads a blob, passes its content to update (nil when the blob does not exist) and writes
+// what update returns unless the blob changed in the meantime, in which case it starts over.
+// When update returns ErrBlobUnchanged the blob is left as it is and Update returns nil.
+func (w *OptimisticBlobWriter) Update(ctx context.Context, blobName string, update func(current []byte) ([]byte, error)) error {
+	for attempt := 0; attempt < w.attempts; attempt++ {
+		if attempt > 0 {
+			if err := w.wait(ctx, attempt); err != nil {
+				return err
+			}
+		}
+
+		current, etag, err := w.read(ctx, blobName)
+		if err != nil {
+			return err
+		}
+
+		updated, err := update(current)
+		if errors.Is(err, ErrBlobUnchanged) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// A missing blob must still be missing, an existing one must be unchanged
+		conditions := &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}
+		if etag != nil {
+			conditions = &blob.ModifiedAccessConditions{IfMatch: etag}
+		}
+		options := uploadOptions(ctx)
+		options.AccessConditions = &blob.AccessConditions{ModifiedAccessConditions: conditions}
+
+		_, err = w.blobs.UploadBuffer(ctx, w.containerName, blobName, updated, options)
+		if err == nil {
+			return nil
+		}
+		if !bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return fmt.Errorf("failed to upload %s: %w", blobName, err)
+		}
+		gologger.Debug().Msgf("Blob %s changed concurrently, retrying", blobName)
+	}
+
+	return fmt.Errorf("%w: %s after %d attempts", ErrBlobContended, blobName, w.attempts)
+}
+
+// read returns the content and ETag of a blob, or nil for both when it does not exist
+func (w *OptimisticBlobWriter) read(ctx context.Context, blobName string) ([]byte, *azcore.ETag, error) {
+	response, err := w.blobs.DownloadStream(ctx, w.containerName, blobName, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to read %s: %w", blobName, err)
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", blobName, err)
+	}
+	return content, response.ETag, nil
+}
+
+// wait sleeps a jittered, doubling backoff before a retry so contending workers spread out
+func (w *OptimisticBlobWriter) wait(ctx context.Context, attempt int) error {
+	delay := w.backoff << (attempt - 1)
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UpdateBlobJSON updates a JSON blob through w. update receives the decoded blob, or the zero value
+// with exists false when there is none, and changes it in place.
+func UpdateBlobJSON[T any](ctx context.Context, w *OptimisticBlobWriter, blobName string, update func(value *T, exists bool) error) error {
+	return w.Update(ctx, blobName, func(current []byte) ([]byte, error) {
+		var value T
+		if current != nil {
+			if err := json.Unmarshal(current, &value); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", blobName, err)
+			}
+		}
+		if err := update(&value, current != nil); err != nil {
+			return nil, err
+		}
+		jsonData, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", blobName, err)
+		}
+		return jsonData, nil
+	})
+}
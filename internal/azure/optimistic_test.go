@@ -0,0 +1,115 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// memoryBlobs is an in-memory container that honours ETag conditions like blob storage does
+type memoryBlobs struct {
+	mu      sync.Mutex
+	content map[string][]byte
+	etags   map[string]azcore.ETag
+	version int
+}
+
+func newMemoryBlobs() *memoryBlobs {
+	return &memoryBlobs{content: map[string][]byte{}, etags: map[string]azcore.ETag{}}
+}
+
+func (m *memoryBlobs) DownloadStream(ctx context.Context, containerName, blobName string, o *azblob.DownloadStreamOptions) (azblob.DownloadStreamResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	content, ok := m.content[blobName]
+	if !ok {
+		return azblob.DownloadStreamResponse{}, &azcore.ResponseError{ErrorCode: string(bloberror.BlobNotFound), StatusCode: http.StatusNotFound}
+	}
+	etag := m.etags[blobName]
+	return azblob.DownloadStreamResponse{DownloadResponse: blob.DownloadResponse{
+		Body: io.NopCloser(bytes.NewReader(content)),
+		ETag: &etag,
+	}}, nil
+}
+
+func (m *memoryBlobs) UploadBuffer(ctx context.Context, containerName, blobName string, buffer []byte, o *azblob.UploadBufferOptions) (azblob.UploadBufferResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	etag, exists := m.etags[blobName]
+	if o != nil && o.AccessConditions != nil && o.AccessConditions.ModifiedAccessConditions != nil {
+		conditions := o.AccessConditions.ModifiedAccessConditions
+		if conditions.IfNoneMatch != nil && exists {
+			return azblob.UploadBufferResponse{}, &azcore.ResponseError{ErrorCode: string(bloberror.BlobAlreadyExists), StatusCode: http.StatusConflict}
+		}
+		if conditions.IfMatch != nil && (!exists || *conditions.IfMatch != etag) {
+			return azblob.UploadBufferResponse{}, &azcore.ResponseError{ErrorCode: string(bloberror.ConditionNotMet), StatusCode: http.StatusPreconditionFailed}
+		}
+	}
+	m.version++
+	m.content[blobName] = append([]byte(nil), buffer...)
+	m.etags[blobName] = azcore.ETag(strconv.Itoa(m.version))
+	return azblob.UploadBufferResponse{}, nil
+}
+
+type counterBlob struct {
+	Count int `json:"count"`
+}
+
+func TestOptimisticBlobWriter_ConcurrentUpdates(t *testing.T) {
+	blobs := newMemoryBlobs()
+	writer := &OptimisticBlobWriter{blobs: blobs, containerName: "test", attempts: 100}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := UpdateBlobJSON(context.Background(), writer, "counter.json", func(counter *counterBlob, exists bool) error {
+				counter.Count++
+				return nil
+			})
+			if err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := string(blobs.content["counter.json"]); got != `{"count":20}` {
+		t.Errorf("Expected no update lost, got %s", got)
+	}
+}
+
+func TestOptimisticBlobWriter_Conflicts(t *testing.T) {
+	blobs := newMemoryBlobs()
+	writer := &OptimisticBlobWriter{blobs: blobs, containerName: "test", attempts: 3}
+	ctx := context.Background()
+
+	// Another worker writes between every read and write
+	calls := 0
+	err := writer.Update(ctx, "pointer.json", func(current []byte) ([]byte, error) {
+		calls++
+		blobs.UploadBuffer(ctx, "test", "pointer.json", []byte(strconv.Itoa(calls)), nil)
+		return []byte("mine"), nil
+	})
+	if !errors.Is(err, ErrBlobContended) || calls != 3 {
+		t.Errorf("Expected the update to give up after 3 attempts, got %d attempts and %v", calls, err)
+	}
+
+	err = writer.Update(ctx, "pointer.json", func(current []byte) ([]byte, error) {
+		return nil, ErrBlobUnchanged
+	})
+	if err != nil || string(blobs.content["pointer.json"]) != "3" {
+		t.Errorf("Expected the blob left unchanged, got %q and %v", blobs.content["pointer.json"], err)
+	}
+}
@@ -0,0 +1,111 @@
+package azure
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/go-amqp"
+	"github.com/allsafeASM/api/internal/metrics"
+)
+
+// Backoff between attempts to recreate a receiver whose link or connection dropped
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// reconnectStormThreshold receiver recreations within reconnectStormWindow are a reconnection
+// storm, which usually means the namespace or the network path to it is unhealthy
+const (
+	reconnectStormThreshold = 5
+	reconnectStormWindow    = 10 * time.Minute
+)
+
+// isConnectionError reports whether a receive error means the AMQP link or connection is gone,
+// so the receiver must be recreated rather than asked again
+func isConnectionError(err error) bool {
+	var sbErr *azservicebus.Error
+	if errors.As(err, &sbErr) && sbErr.Code == azservicebus.CodeConnectionLost {
+		return true
+	}
+
+	var connErr *amqp.ConnError
+	var sessionErr *amqp.SessionError
+	var linkErr *amqp.LinkError
+	return errors.As(err, &connErr) || errors.As(err, &sessionErr) || errors.As(err, &linkErr)
+}
+
+// reconnectTracker counts receiver recreations and detects reconnection storms
+type reconnectTracker struct {
+	threshold int
+	window    time.Duration
+
+	mu     sync.Mutex
+	recent []time.Time // Recreations within the window, oldest first
+	delay  time.Duration
+
+	reconnects *metrics.Counter
+	storms     *metrics.Counter
+}
+
+// newReconnectTracker creates a tracker that reports a storm at threshold recreations in window
+func newReconnectTracker(threshold int, window time.Duration) *reconnectTracker {
+	return &reconnectTracker{threshold: threshold, window: window}
+}
+
+// setMetrics sets the registry the reconnection counters are published in
+func (t *reconnectTracker) setMetrics(registry *metrics.Registry) {
+	t.reconnects = registry.Counter("asm_servicebus_reconnects_total", "Service Bus receivers recreated after a dropped link or connection, by outcome.", "queue", "outcome")
+	t.storms = registry.Counter("asm_servicebus_reconnect_storms_total", "Service Bus reconnection storms detected.", "queue")
+}
+
+// nextDelay returns how long to wait before the next recreation attempt, doubling on every call
+// until reset
+func (t *reconnectTracker) nextDelay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.delay == 0 {
+		t.delay = minReconnectDelay
+	} else {
+		t.delay = min(t.delay*2, maxReconnectDelay)
+	}
+	return t.delay
+}
+
+// reset starts the backoff over once a receive succeeded on the recreated receiver
+func (t *reconnectTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delay = 0
+}
+
+// record counts a recreation attempt and reports whether it makes the recent recreations a
+// storm. A storm is reported once when it starts, not for every recreation during it.
+func (t *reconnectTracker) record(queue string, at time.Time, err error) bool {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	t.reconnects.Inc(queue, outcome)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := at.Add(-t.window)
+	kept := t.recent[:0]
+	for _, recent := range t.recent {
+		if recent.After(cutoff) {
+			kept = append(kept, recent)
+		}
+	}
+	t.recent = append(kept, at)
+
+	if len(t.recent) != t.threshold {
+		return false
+	}
+	t.storms.Inc(queue)
+	return true
+}
@@ -0,0 +1,77 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/go-amqp"
+)
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"link detached", fmt.Errorf("failed to receive message: %w", &amqp.LinkError{}), true},
+		{"connection closed", &amqp.ConnError{}, true},
+		{"session ended", &amqp.SessionError{}, true},
+		{"deadline", context.DeadlineExceeded, false},
+		{"other", errors.New("failed to parse message"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isConnectionError(tt.err); got != tt.want {
+				t.Errorf("isConnectionError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconnectTracker_BacksOffUntilReset(t *testing.T) {
+	tracker := newReconnectTracker(reconnectStormThreshold, reconnectStormWindow)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for _, delay := range want {
+		if got := tracker.nextDelay(); got != delay {
+			t.Fatalf("nextDelay() = %v, want %v", got, delay)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		tracker.nextDelay()
+	}
+	if got := tracker.nextDelay(); got != maxReconnectDelay {
+		t.Errorf("nextDelay() = %v, want it capped at %v", got, maxReconnectDelay)
+	}
+
+	tracker.reset()
+	if got := tracker.nextDelay(); got != minReconnectDelay {
+		t.Errorf("nextDelay() after reset = %v, want %v", got, minReconnectDelay)
+	}
+}
+
+func TestReconnectTracker_ReportsStormOnce(t *testing.T) {
+	tracker := newReconnectTracker(3, time.Minute)
+	start := time.Now()
+
+	var storms int
+	for i := 0; i < 5; i++ {
+		if tracker.record("tasks", start.Add(time.Duration(i)*time.Second), nil) {
+			storms++
+		}
+	}
+	if storms != 1 {
+		t.Errorf("reported %d storms, want 1", storms)
+	}
+
+	// Recreations spread wider than the window are not a storm
+	tracker = newReconnectTracker(3, time.Minute)
+	for i := 0; i < 5; i++ {
+		if tracker.record("tasks", start.Add(time.Duration(i)*time.Minute), errors.New("unreachable")) {
+			t.Fatalf("recreation %d reported a storm", i)
+		}
+	}
+}
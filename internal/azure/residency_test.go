@@ -0,0 +1,80 @@
+package azure
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Connection strings are only parsed when clients are created, so no account is contacted
+const (
+	testConnectionString   = "DefaultEndpointsProtocol=https;AccountName=asmprimary;AccountKey=a2V5;EndpointSuffix=core.windows.net"
+	testEUConnectionString = "DefaultEndpointsProtocol=https;AccountName=asmeu;AccountKey=a2V5;EndpointSuffix=core.windows.net"
+)
+
+func newTestRegionalClient(t *testing.T) *BlobStorageClient {
+	t.Helper()
+	client, err := NewBlobStorageClient(testConnectionString, "scans")
+	if err != nil {
+		t.Fatalf("NewBlobStorageClient failed: %v", err)
+	}
+	client.SetDefaultRegion("us")
+	if err := client.AddRegion("eu", testEUConnectionString); err != nil {
+		t.Fatalf("AddRegion failed: %v", err)
+	}
+	if err := client.SetTenantRegions(models.TenantRegions{"acme": "eu"}); err != nil {
+		t.Fatalf("SetTenantRegions failed: %v", err)
+	}
+	return client
+}
+
+func TestBlobsAreRoutedByTenant(t *testing.T) {
+	client := newTestRegionalClient(t)
+
+	tests := map[string]string{
+		"acme/scans/1/subfinder/example.com/out.json":   "eu",
+		"globex/scans/2/subfinder/example.com/out.json": "us",
+		"control/quotas.json":                           "us",
+	}
+	for blobPath, expected := range tests {
+		if got := client.accountFor(blobPath).region; got != expected {
+			t.Errorf("Expected %s in region %s, got %s", blobPath, expected, got)
+		}
+	}
+	if got := client.Regions(); len(got) != 2 || got[0] != "eu" || got[1] != "us" {
+		t.Errorf("Unexpected regions %v", got)
+	}
+}
+
+func TestCheckResidency(t *testing.T) {
+	client := newTestRegionalClient(t)
+
+	if err := client.checkResidency("acme", "acme/scans/1/out.json"); err != nil {
+		t.Errorf("Expected acme's blob to stay in its region, got %v", err)
+	}
+	// A path built without the tenant would leave the tenant's region
+	if err := client.checkResidency("acme", "scans/1/out.json"); err == nil {
+		t.Error("Expected a blob outside acme's region to be rejected")
+	}
+
+	if err := client.SetTenantRegions(models.TenantRegions{"globex": "apac"}); err == nil {
+		t.Error("Expected routing to a region without an account to fail")
+	}
+}
+
+func TestBlobDownloadURLIsSignedByTheTenantAccount(t *testing.T) {
+	client := newTestRegionalClient(t)
+
+	url, err := client.BlobDownloadURL("acme/exports/job-1/export.zip", time.Hour)
+	if err != nil {
+		t.Fatalf("BlobDownloadURL failed: %v", err)
+	}
+	if !strings.HasPrefix(url, "https://asmeu.blob.core.windows.net/scans/") {
+		t.Errorf("Expected a URL of the eu account, got %s", url)
+	}
+	if !strings.Contains(url, "sp=r&") || !strings.Contains(url, "sig=") {
+		t.Errorf("Expected a signed read-only URL, got %s", url)
+	}
+}
@@ -3,24 +3,88 @@ package azure
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus/admin"
+	"github.com/allsafeASM/api/internal/faults"
+	"github.com/allsafeASM/api/internal/metrics"
 	"github.com/allsafeASM/api/internal/models"
 	"github.com/projectdiscovery/gologger"
 )
 
+// maxDeferredRetries is how often a failed task is re-scheduled for a later retry before it is dead-lettered
+const maxDeferredRetries = 5
+
+// deferredRetriesProperty counts the deferred retries of a message in its application properties
+const deferredRetriesProperty = "deferred_retries"
+
+// attemptsProperty counts the deliveries of a message before it was re-scheduled, since the
+// re-scheduled copy starts over with a delivery count of one
+const attemptsProperty = "attempts"
+
+// retryCountProperty counts the in-process retries of a message's earlier deliveries, which the
+// re-scheduled copy would otherwise lose
+const retryCountProperty = "retry_count"
+
+// deadLetterReasonProperty records the dead-letter reason a re-scheduled message's failure was
+// given, so it is not lost when the copy is re-scheduled rather than dead-lettered
+const deadLetterReasonProperty = "dead_letter_reason"
+
+// handOverDelay is how long a message left to other workers waits before it is delivered again,
+// so the worker that left it does not receive it straight back in a loop
+const handOverDelay = 30 * time.Second
+
+// maxHandOverDeliveries dead-letters a message no worker took after as many deliveries as
+// Service Bus allows by default
+const maxHandOverDeliveries = 10
+
+// handlerStopTimeout is how long a cancelled handler gets to return before its message is released
+const handlerStopTimeout = 30 * time.Second
+
+// healthCheckTimeout bounds the health check so an unreachable namespace cannot stall startup
+const healthCheckTimeout = 30 * time.Second
+
 // ServiceBusClient handles Azure Service Bus operations
 type ServiceBusClient struct {
-	client   *azservicebus.Client
-	queue    string
-	receiver *azservicebus.Receiver
+	client       *azservicebus.Client
+	admin        *admin.Client
+	queue        string
+	subscription string // Set when tasks are received from a subscription of the queue topic
+	sender       messageSender
+	faults       *faults.Injector // Fails lock renewals for resilience testing; nil injects nothing
+	reconnects   *reconnectTracker
+	prefetch     int // Messages received at once
+	// Messages larger than maxMessageSize bytes are dead-lettered unread
+	maxMessageSize int
+	rejected       *metrics.Counter
+
+	mu       sync.Mutex
+	receiver *azservicebus.Receiver // Recreated when its link or connection drops
+}
+
+// messageSender sends and schedules task messages; *azservicebus.Sender implements it
+type messageSender interface {
+	SendMessage(ctx context.Context, message *azservicebus.Message, options *azservicebus.SendMessageOptions) error
+	ScheduleMessages(ctx context.Context, messages []*azservicebus.Message, scheduledEnqueueTime time.Time, options *azservicebus.ScheduleMessagesOptions) ([]int64, error)
+	Close(ctx context.Context) error
 }
 
-// NewServiceBusClient creates a new Service Bus client
-func NewServiceBusClient(connectionString, queueName string) (*ServiceBusClient, error) {
+// messageSettler settles received messages; *azservicebus.Receiver implements it
+type messageSettler interface {
+	CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error
+	AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error
+	DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error
+}
+
+// NewServiceBusClient creates a new Service Bus client. With a subscription, queueName names a
+// topic: tasks are sent to the topic and received from the subscription, whose filter decides
+// which tasks the worker gets.
+func NewServiceBusClient(connectionString, queueName, subscription string) (*ServiceBusClient, error) {
 	// Create client with options for better resilience
 	client, err := azservicebus.NewClientFromConnectionString(connectionString, &azservicebus.ClientOptions{
 		RetryOptions: azservicebus.RetryOptions{
@@ -33,28 +97,89 @@ func NewServiceBusClient(connectionString, queueName string) (*ServiceBusClient,
 		return nil, fmt.Errorf("failed to create Service Bus client: %w", err)
 	}
 
-	// Create receiver with options for better performance
-	receiver, err := client.NewReceiverForQueue(queueName, &azservicebus.ReceiverOptions{
-		ReceiveMode: azservicebus.ReceiveModePeekLock,
-	})
+	receiver, err := newReceiver(client, queueName, subscription)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create receiver: %w", err)
+		return nil, err
+	}
+
+	// Create sender used to re-schedule deferred messages onto the same queue
+	sender, err := client.NewSender(queueName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sender: %w", err)
+	}
+
+	// The admin client reads queue properties over HTTPS without touching the messages
+	adminClient, err := admin.NewClientFromConnectionString(connectionString, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Service Bus admin client: %w", err)
 	}
 
 	return &ServiceBusClient{
-		client:   client,
-		queue:    queueName,
-		receiver: receiver,
+		client:       client,
+		admin:        adminClient,
+		queue:        queueName,
+		subscription: subscription,
+		receiver:     receiver,
+		sender:       sender,
+		reconnects:   newReconnectTracker(reconnectStormThreshold, reconnectStormWindow),
+		prefetch:     1,
+
+		maxMessageSize: models.DefaultMaxMessageSize,
 	}, nil
 }
 
+// newReceiver creates a peek-lock receiver for the queue, or for the subscription when one is set
+func newReceiver(client *azservicebus.Client, queueName, subscription string) (*azservicebus.Receiver, error) {
+	receiverOptions := &azservicebus.ReceiverOptions{
+		ReceiveMode: azservicebus.ReceiveModePeekLock,
+	}
+	var receiver *azservicebus.Receiver
+	var err error
+	if subscription != "" {
+		receiver, err = client.NewReceiverForSubscription(queueName, subscription, receiverOptions)
+	} else {
+		receiver, err = client.NewReceiverForQueue(queueName, receiverOptions)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receiver: %w", err)
+	}
+	return receiver, nil
+}
+
+// SetMetrics sets the registry the receiver reconnection and rejected message counters are published in
+func (s *ServiceBusClient) SetMetrics(registry *metrics.Registry) {
+	s.reconnects.setMetrics(registry)
+	s.rejected = registry.Counter("asm_servicebus_rejected_messages_total", "Malformed messages dead-lettered before processing, by reason.", "queue", "reason")
+}
+
+// SetMaxMessageSize sets the size in bytes above which messages are dead-lettered without being decoded
+func (s *ServiceBusClient) SetMaxMessageSize(size int) {
+	s.maxMessageSize = size
+}
+
+// SetPrefetchCount sets how many messages are received at once. Messages after the first wait for
+// the ones before them to be processed, and their locks are not renewed while they wait.
+func (s *ServiceBusClient) SetPrefetchCount(count int) {
+	s.prefetch = max(count, 1)
+}
+
+// SetFaultInjector fails message lock renewals as the injector decides, for resilience testing
+func (s *ServiceBusClient) SetFaultInjector(injector *faults.Injector) {
+	s.faults = injector
+}
+
 // Close closes the Service Bus client
 func (s *ServiceBusClient) Close(ctx context.Context) error {
-	if s.receiver != nil {
-		if err := s.receiver.Close(ctx); err != nil {
+	if receiver := s.currentReceiver(); receiver != nil {
+		if err := receiver.Close(ctx); err != nil {
 			return fmt.Errorf("failed to close receiver: %w", err)
 		}
 	}
+	if s.sender != nil {
+		if err := s.sender.Close(ctx); err != nil {
+			return fmt.Errorf("failed to close sender: %w", err)
+		}
+	}
 	if s.client != nil {
 		if err := s.client.Close(ctx); err != nil {
 			return fmt.Errorf("failed to close client: %w", err)
@@ -63,19 +188,167 @@ func (s *ServiceBusClient) Close(ctx context.Context) error {
 	return nil
 }
 
-// HealthCheck verifies the Service Bus connection is working
+// HealthCheck verifies the queue is reachable without receiving or locking any of its messages.
+// It reads the queue's runtime properties, which needs Manage rights; with a Listen-only
+// connection string it peeks at the queue instead, which does not lock messages either.
 func (s *ServiceBusClient) HealthCheck(ctx context.Context) error {
-	// Try to get the receiver to test the connection
-	receiver, err := s.client.NewReceiverForQueue(s.queue, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create receiver for health check: %w", err)
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	depth, err := s.QueueDepth(ctx)
+	if err == nil {
+		gologger.Debug().Msgf("Service Bus health check passed - queue %s has %d active messages", depth.Queue, depth.Active)
+		return nil
+	}
+	gologger.Debug().Msgf("Failed to read runtime properties of queue %s, peeking instead: %v", s.entityName(), err)
+
+	if _, err := s.currentReceiver().PeekMessages(ctx, 1, nil); err != nil {
+		return fmt.Errorf("failed to peek queue %s: %w", s.entityName(), err)
 	}
-	defer receiver.Close(ctx)
 
 	gologger.Debug().Msg("Service Bus health check passed - connection is working")
 	return nil
 }
 
+// PeekTaskTypes counts the task types of up to max messages at the head of the queue. Peeking
+// does not lock the messages; messages scheduled for later and control messages are skipped.
+func (s *ServiceBusClient) PeekTaskTypes(ctx context.Context, max int) (map[models.Task]int, error) {
+	from := int64(0)
+	messages, err := s.currentReceiver().PeekMessages(ctx, max, &azservicebus.PeekMessagesOptions{FromSequenceNumber: &from})
+	if err != nil {
+		return nil, fmt.Errorf("failed to peek queue %s: %w", s.entityName(), err)
+	}
+
+	now := time.Now()
+	tasks := make(map[models.Task]int)
+	for _, message := range messages {
+		if message.ScheduledEnqueueTime != nil && message.ScheduledEnqueueTime.After(now) {
+			continue
+		}
+		var taskMsg models.TaskMessage
+		if json.Unmarshal(message.Body, &taskMsg) != nil || taskMsg.Task == "" {
+			continue
+		}
+		tasks[taskMsg.Task]++
+	}
+	return tasks, nil
+}
+
+// QueueDepth reads the message counts of the queue, or of the subscription, from its runtime
+// properties. Subscriptions have no scheduled messages; those wait in the topic.
+func (s *ServiceBusClient) QueueDepth(ctx context.Context) (models.QueueDepth, error) {
+	if s.subscription != "" {
+		props, err := s.admin.GetSubscriptionRuntimeProperties(ctx, s.queue, s.subscription, nil)
+		if err != nil {
+			return models.QueueDepth{}, fmt.Errorf("failed to get runtime properties of subscription %s: %w", s.entityName(), err)
+		}
+		if props == nil {
+			return models.QueueDepth{}, fmt.Errorf("subscription %s does not exist", s.entityName())
+		}
+		return models.QueueDepth{
+			Queue:      s.entityName(),
+			Active:     int(props.ActiveMessageCount),
+			DeadLetter: int(props.DeadLetterMessageCount),
+			SampledAt:  time.Now().UTC(),
+		}, nil
+	}
+
+	props, err := s.admin.GetQueueRuntimeProperties(ctx, s.queue, nil)
+	if err != nil {
+		return models.QueueDepth{}, fmt.Errorf("failed to get runtime properties of queue %s: %w", s.queue, err)
+	}
+	if props == nil {
+		return models.QueueDepth{}, fmt.Errorf("queue %s does not exist", s.queue)
+	}
+
+	return models.QueueDepth{
+		Queue:      s.queue,
+		Active:     int(props.ActiveMessageCount),
+		Scheduled:  int(props.ScheduledMessageCount),
+		DeadLetter: int(props.DeadLetterMessageCount),
+		SampledAt:  time.Now().UTC(),
+	}, nil
+}
+
+// LockDuration reads the lock duration of the queue, or of the subscription, which needs Manage rights
+func (s *ServiceBusClient) LockDuration(ctx context.Context) (time.Duration, error) {
+	var lockDuration *string
+	if s.subscription != "" {
+		props, err := s.admin.GetSubscription(ctx, s.queue, s.subscription, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get properties of subscription %s: %w", s.entityName(), err)
+		}
+		if props == nil {
+			return 0, fmt.Errorf("subscription %s does not exist", s.entityName())
+		}
+		lockDuration = props.LockDuration
+	} else {
+		props, err := s.admin.GetQueue(ctx, s.queue, nil)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get properties of queue %s: %w", s.queue, err)
+		}
+		if props == nil {
+			return 0, fmt.Errorf("queue %s does not exist", s.queue)
+		}
+		lockDuration = props.LockDuration
+	}
+
+	if lockDuration == nil {
+		return 0, fmt.Errorf("%s has no lock duration", s.entityName())
+	}
+	return parseISODuration(*lockDuration)
+}
+
+// parseISODuration parses the ISO 8601 durations Service Bus reports, such as PT1M or PT30S
+func parseISODuration(value string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(value, "PT")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("unsupported duration %q", value)
+	}
+	duration, err := time.ParseDuration(strings.ToLower(rest))
+	if err != nil {
+		return 0, fmt.Errorf("unsupported duration %q: %w", value, err)
+	}
+	return duration, nil
+}
+
+// entityName names the queue, or the topic and subscription, tasks are received from
+func (s *ServiceBusClient) entityName() string {
+	if s.subscription != "" {
+		return s.queue + "/subscriptions/" + s.subscription
+	}
+	return s.queue
+}
+
+// EnqueueTask sends a task to the queue with the routing properties subscription filters select
+// workers by. A task with a not_before time is scheduled for then, so it is not delivered to a
+// worker early.
+func (s *ServiceBusClient) EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error {
+	body, err := json.Marshal(taskMsg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task message: %w", err)
+	}
+
+	contentType := "application/json"
+	message := &azservicebus.Message{
+		Body:                  body,
+		ContentType:           &contentType,
+		CorrelationID:         nonEmpty(taskMsg.CorrelationID),
+		ApplicationProperties: taskMsg.RoutingProperties(),
+	}
+	if taskMsg.NotBefore != nil && taskMsg.NotBefore.After(time.Now()) {
+		if _, err := s.sender.ScheduleMessages(ctx, []*azservicebus.Message{message}, *taskMsg.NotBefore, nil); err != nil {
+			return fmt.Errorf("failed to schedule task message: %w", err)
+		}
+		return nil
+	}
+
+	if err := s.sender.SendMessage(ctx, message, nil); err != nil {
+		return fmt.Errorf("failed to send task message: %w", err)
+	}
+	return nil
+}
+
 // ProcessMessages continuously processes messages from the queue
 func (s *ServiceBusClient) ProcessMessages(ctx context.Context, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, pollInterval time.Duration, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration, scannerTimeout time.Duration) error {
 	gologger.Info().Msg("Starting message processing loop")
@@ -89,14 +362,61 @@ func (s *ServiceBusClient) ProcessMessages(ctx context.Context, handler func(con
 		}
 
 		// Process next message
-		err := s.processNextMessage(ctx, s.receiver, handler, pollInterval, lockRenewalInterval, maxLockRenewalTime, scannerTimeout)
-		if err != nil {
-			gologger.Error().Msgf("Error processing message: %v", err)
-			// Continue processing other messages
+		err := s.processNextMessage(ctx, s.currentReceiver(), handler, pollInterval, lockRenewalInterval, maxLockRenewalTime, scannerTimeout)
+		if err == nil {
+			s.reconnects.reset()
+			continue
 		}
+		if ctx.Err() == nil && isConnectionError(err) {
+			gologger.Warning().Msgf("Service Bus link to %s dropped: %v", s.entityName(), err)
+			s.reconnect(ctx)
+			continue
+		}
+		gologger.Error().Msgf("Error processing message: %v", err)
+		// Continue processing other messages
 	}
 }
 
+// currentReceiver returns the receiver messages are currently received with
+func (s *ServiceBusClient) currentReceiver() *azservicebus.Receiver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.receiver
+}
+
+// reconnect waits out the backoff and replaces the receiver with a new one. A failed attempt
+// keeps the old receiver, so the next receive fails again and the next attempt waits longer.
+func (s *ServiceBusClient) reconnect(ctx context.Context) {
+	delay := s.reconnects.nextDelay()
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+
+	receiver, err := newReceiver(s.client, s.queue, s.subscription)
+	if s.reconnects.record(s.entityName(), time.Now(), err) {
+		gologger.Error().Msgf("Service Bus reconnection storm: receiver for %s recreated %d times within %s", s.entityName(), reconnectStormThreshold, reconnectStormWindow)
+	}
+	if err != nil {
+		gologger.Error().Msgf("Failed to recreate receiver for %s, retrying: %v", s.entityName(), err)
+		return
+	}
+
+	s.mu.Lock()
+	old := s.receiver
+	s.receiver = receiver
+	s.mu.Unlock()
+
+	// The dropped receiver is closed in the background, since closing a dead link can block
+	go func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		old.Close(closeCtx)
+	}()
+	gologger.Info().Msgf("Recreated Service Bus receiver for %s after %s", s.entityName(), delay)
+}
+
 // isTimeoutError checks if an error is a timeout error
 func (s *ServiceBusClient) isTimeoutError(err error) bool {
 	return err != nil && (strings.Contains(err.Error(), "context deadline exceeded") ||
@@ -115,7 +435,7 @@ func (s *ServiceBusClient) processNextMessage(ctx context.Context, receiver *azs
 	receiveCtx, cancel := context.WithTimeout(ctx, receiveTimeout)
 	defer cancel()
 
-	messages, err := receiver.ReceiveMessages(receiveCtx, 1, nil)
+	messages, err := receiver.ReceiveMessages(receiveCtx, s.prefetch, nil)
 	if err != nil {
 		if s.isTimeoutError(err) {
 			gologger.Debug().Msgf("Receive timeout after %v - this is normal when no messages are available", receiveTimeout)
@@ -124,31 +444,104 @@ func (s *ServiceBusClient) processNextMessage(ctx context.Context, receiver *azs
 		return fmt.Errorf("failed to receive message: %w", err)
 	}
 
-	if len(messages) == 0 {
-		return nil
+	// Prefetched messages keep their locks while they wait for the ones before them
+	waiting := holdPrefetched(ctx, s.lockRenewer(receiver), messages[min(len(messages), 1):], lockRenewalInterval, maxLockRenewalTime)
+	defer stopRenewals(waiting)
+
+	var errs []error
+	for i, message := range messages {
+		if i > 0 {
+			waiting[i-1].stop()
+		}
+
+		// Prefetched messages nobody will process are handed back to the queue right away
+		if ctx.Err() != nil {
+			stopRenewals(waiting)
+			s.releaseMessages(receiver, messages[i:])
+			break
+		}
+
+		gologger.Debug().Msgf("Received message: %s", message.MessageID)
+
+		// Create message processor and handle the message
+		processor := s.newMessageProcessor(receiver)
+		result := processor.ProcessMessage(ctx, message, handler, lockRenewalInterval, maxLockRenewalTime, scannerTimeout)
+		var rejection *models.MessageRejection
+		if errors.As(result.Error, &rejection) {
+			gologger.Warning().Msgf("Rejected malformed message %s: %v", message.MessageID, rejection)
+			s.rejected.Inc(s.entityName(), rejection.Reason)
+		}
+
+		// Handle the result
+		if err := s.handleMessageResult(ctx, receiver, message, result); err != nil {
+			errs = append(errs, err)
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	message := messages[0]
+// releaseMessages abandons received messages that will not be processed, so another worker gets
+// them without waiting for their locks to expire
+func (s *ServiceBusClient) releaseMessages(receiver *azservicebus.Receiver, messages []*azservicebus.ReceivedMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), handlerStopTimeout)
+	defer cancel()
 
-	gologger.Debug().Msgf("Received message: %s", message.MessageID)
+	for _, message := range messages {
+		if err := receiver.AbandonMessage(ctx, message, nil); err != nil {
+			gologger.Warning().Msgf("Failed to release prefetched message %s: %v", message.MessageID, err)
+		}
+	}
+}
 
-	// Create message processor and handle the message
-	processor := s.newMessageProcessor(receiver)
-	result := processor.ProcessMessage(ctx, message, handler, lockRenewalInterval, maxLockRenewalTime, scannerTimeout)
+// holdPrefetched renews the locks of received messages while they wait for their turn, for at
+// most renewFor, so a long task before them does not let their locks expire
+func holdPrefetched(ctx context.Context, renewer lockRenewer, messages []*azservicebus.ReceivedMessage, interval, renewFor time.Duration) []*lockRenewal {
+	renewals := make([]*lockRenewal, len(messages))
+	for i, message := range messages {
+		lockedUntil := time.Now().Add(interval)
+		if message.LockedUntil != nil {
+			lockedUntil = *message.LockedUntil
+		}
+		lock := models.NewMessageLock(lockedUntil, time.Now().Add(renewFor))
+		renewals[i] = startLockRenewal(ctx, renewer, message, lock, interval, renewFor)
+	}
+	return renewals
+}
+
+// stopRenewals ends lock renewals; stopping one that already ended does nothing
+func stopRenewals(renewals []*lockRenewal) {
+	for _, renewal := range renewals {
+		renewal.stop()
+	}
+}
 
-	// Handle the result
-	return s.handleMessageResult(ctx, receiver, message, result)
+// lockRenewer returns what renews the message locks of receiver, failing renewals on purpose
+// when fault injection is configured
+func (s *ServiceBusClient) lockRenewer(receiver *azservicebus.Receiver) lockRenewer {
+	if s.faults != nil {
+		return &faultyRenewer{lockRenewer: receiver, faults: s.faults}
+	}
+	return receiver
 }
 
 // newMessageProcessor creates a new message processor
 func (s *ServiceBusClient) newMessageProcessor(receiver *azservicebus.Receiver) *MessageProcessor {
 	return &MessageProcessor{
-		receiver: receiver,
+		receiver:       s.lockRenewer(receiver),
+		stopTimeout:    handlerStopTimeout,
+		maxMessageSize: s.maxMessageSize,
 	}
 }
 
 // handleMessageResult handles the result of message processing
-func (s *ServiceBusClient) handleMessageResult(ctx context.Context, receiver *azservicebus.Receiver, message *azservicebus.ReceivedMessage, result *models.MessageProcessingResult) error {
+func (s *ServiceBusClient) handleMessageResult(ctx context.Context, receiver messageSettler, message *azservicebus.ReceivedMessage, result *models.MessageProcessingResult) error {
+	if !result.DeferUntil.IsZero() {
+		if !result.Success {
+			return s.retryLater(ctx, receiver, message, result)
+		}
+		return s.deferMessage(ctx, receiver, message, result, result.DeferUntil, message.ApplicationProperties)
+	}
+
 	if result.Success {
 		// Complete the message
 		err := receiver.CompleteMessage(ctx, message, nil)
@@ -159,6 +552,10 @@ func (s *ServiceBusClient) handleMessageResult(ctx context.Context, receiver *az
 		return nil
 	}
 
+	if result.Abandon {
+		return s.handOver(ctx, receiver, message, result)
+	}
+
 	// Handle failure
 	if s.shouldRetryMessage(result) {
 		// Abandon the message for retry
@@ -171,7 +568,7 @@ func (s *ServiceBusClient) handleMessageResult(ctx context.Context, receiver *az
 	}
 
 	// Dead letter the message
-	err := receiver.DeadLetterMessage(ctx, message, nil)
+	err := receiver.DeadLetterMessage(ctx, message, deadLetterOptions(result))
 	if err != nil {
 		return fmt.Errorf("failed to dead letter message: %w", err)
 	}
@@ -179,6 +576,124 @@ func (s *ServiceBusClient) handleMessageResult(ctx context.Context, receiver *az
 	return nil
 }
 
+// retryLater re-schedules a failed message for the time its result asks for, dead-lettering it
+// once it was re-scheduled maxDeferredRetries times
+func (s *ServiceBusClient) retryLater(ctx context.Context, receiver messageSettler, message *azservicebus.ReceivedMessage, result *models.MessageProcessingResult) error {
+	retries := 0
+	if count, ok := message.ApplicationProperties[deferredRetriesProperty].(int64); ok {
+		retries = int(count)
+	}
+	if retries >= maxDeferredRetries {
+		options := deadLetterOptions(result)
+		if options == nil {
+			reason := "MaxDeferredRetriesExceeded"
+			if earlier, ok := message.ApplicationProperties[deadLetterReasonProperty].(string); ok && earlier != "" {
+				reason = earlier
+			}
+			options = &azservicebus.DeadLetterOptions{Reason: &reason}
+			if result.Error != nil {
+				options.ErrorDescription = nonEmpty(result.Error.Error())
+			}
+		}
+		if err := receiver.DeadLetterMessage(ctx, message, options); err != nil {
+			return fmt.Errorf("failed to dead letter message: %w", err)
+		}
+		gologger.Error().Msgf("Message dead lettered after %d deferred retries: %s, error: %v", retries, message.MessageID, result.Error)
+		return nil
+	}
+
+	properties := make(map[string]any, len(message.ApplicationProperties)+1)
+	for key, value := range message.ApplicationProperties {
+		properties[key] = value
+	}
+	properties[deferredRetriesProperty] = int64(retries + 1)
+	properties[attemptsProperty] = int64(messageAttempt(message))
+
+	gologger.Warning().Msgf("Message %s failed, retrying at %s: %v", message.MessageID, result.DeferUntil.Format(time.RFC3339), result.Error)
+	return s.deferMessage(ctx, receiver, message, result, result.DeferUntil, properties)
+}
+
+// handOver leaves a message this worker does not run to other workers. A copy is scheduled after
+// handOverDelay rather than the message being abandoned, which would hand it straight back to
+// this worker, and it is dead-lettered once it was delivered maxHandOverDeliveries times.
+func (s *ServiceBusClient) handOver(ctx context.Context, receiver messageSettler, message *azservicebus.ReceivedMessage, result *models.MessageProcessingResult) error {
+	attempts := messageAttempt(message)
+	if attempts >= maxHandOverDeliveries {
+		reason := "MaxDeliveryCountExceeded"
+		options := &azservicebus.DeadLetterOptions{Reason: &reason}
+		if result.Error != nil {
+			options.ErrorDescription = nonEmpty(result.Error.Error())
+		}
+		if err := receiver.DeadLetterMessage(ctx, message, options); err != nil {
+			return fmt.Errorf("failed to dead letter message: %w", err)
+		}
+		gologger.Error().Msgf("Message dead lettered after %d deliveries no worker took: %s, error: %v", attempts, message.MessageID, result.Error)
+		return nil
+	}
+
+	properties := make(map[string]any, len(message.ApplicationProperties)+1)
+	for key, value := range message.ApplicationProperties {
+		properties[key] = value
+	}
+	properties[attemptsProperty] = int64(attempts)
+
+	gologger.Info().Msgf("Leaving message %s to another worker: %v", message.MessageID, result.Error)
+	return s.deferMessage(ctx, receiver, message, result, time.Now().Add(handOverDelay), properties)
+}
+
+// deferMessage schedules a copy of the message with the given application properties for later
+// delivery and completes the original. The copy carries the in-process retries of the result and
+// the dead-letter reason it was given, if any. If scheduling fails the message is abandoned so it is
+// not lost.
+func (s *ServiceBusClient) deferMessage(ctx context.Context, receiver messageSettler, message *azservicebus.ReceivedMessage, result *models.MessageProcessingResult, deferUntil time.Time, properties map[string]any) error {
+	properties = withRoutingProperties(message.Body, properties)
+	if result.RetryCount > 0 {
+		prior, _ := properties[retryCountProperty].(int64)
+		properties[retryCountProperty] = prior + int64(result.RetryCount)
+	}
+	if result.DeadLetterReason != "" {
+		properties[deadLetterReasonProperty] = result.DeadLetterReason
+	}
+	scheduled := &azservicebus.Message{
+		Body:                  message.Body,
+		ApplicationProperties: properties,
+		ContentType:           message.ContentType,
+		CorrelationID:         message.CorrelationID,
+		Subject:               message.Subject,
+	}
+
+	if _, err := s.sender.ScheduleMessages(ctx, []*azservicebus.Message{scheduled}, deferUntil, nil); err != nil {
+		if abandonErr := receiver.AbandonMessage(ctx, message, nil); abandonErr != nil {
+			return fmt.Errorf("failed to schedule deferred message: %w (abandon also failed: %v)", err, abandonErr)
+		}
+		return fmt.Errorf("failed to schedule deferred message, abandoned instead: %w", err)
+	}
+
+	if err := receiver.CompleteMessage(ctx, message, nil); err != nil {
+		return fmt.Errorf("failed to complete deferred message: %w", err)
+	}
+
+	gologger.Info().Msgf("Message %s deferred until %s", message.MessageID, deferUntil.Format(time.RFC3339))
+	return nil
+}
+
+// withRoutingProperties returns a copy of the properties with the routing properties of the task
+// in body, so a message whose sender did not set them, such as the orchestrator, is routed by the
+// subscription filters once the worker sends it again
+func withRoutingProperties(body []byte, properties map[string]any) map[string]any {
+	routed := make(map[string]any, len(properties)+2)
+	for key, value := range properties {
+		routed[key] = value
+	}
+	var taskMsg models.TaskMessage
+	if err := json.Unmarshal(body, &taskMsg); err == nil && taskMsg.Task != "" {
+		for key, value := range taskMsg.RoutingProperties() {
+			routed[key] = value
+		}
+	}
+	return routed
+}
+
 // shouldRetryMessage determines if a message should be retried
 func (s *ServiceBusClient) shouldRetryMessage(result *models.MessageProcessingResult) bool {
 	return result.Retryable && result.RetryCount < 3
@@ -186,14 +701,23 @@ func (s *ServiceBusClient) shouldRetryMessage(result *models.MessageProcessingRe
 
 // MessageProcessor handles message processing logic
 type MessageProcessor struct {
-	receiver *azservicebus.Receiver
+	receiver       lockRenewer
+	stopTimeout    time.Duration // How long a cancelled handler gets to return
+	maxMessageSize int           // Bytes; 0 for no limit
 }
 
-// ProcessMessage processes a single message with retry logic and auto-renewal
+// ProcessMessage processes a single message with retry logic and auto-renewal. A message that is
+// too large or malformed is rejected before the handler sees it and is not retried.
 func (p *MessageProcessor) ProcessMessage(ctx context.Context, message *azservicebus.ReceivedMessage, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration, scannerTimeout time.Duration) *models.MessageProcessingResult {
 	maxRetries := 3
 	baseDelay := 1 * time.Second
 
+	var rejection *models.MessageRejection
+	taskMsg, err := models.DecodeTaskMessage(message.Body, p.maxMessageSize)
+	if errors.As(err, &rejection) {
+		return rejection.Result()
+	}
+
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		// Check if context is cancelled
 		select {
@@ -211,7 +735,7 @@ func (p *MessageProcessor) ProcessMessage(ctx context.Context, message *azservic
 		handlerCtx, cancel := context.WithTimeout(ctx, scannerTimeout)
 
 		// Process the message with auto-renewal
-		result := p.processMessageWithRenewal(handlerCtx, message, handler, lockRenewalInterval, maxLockRenewalTime)
+		result := p.processMessageWithRenewal(handlerCtx, message, *taskMsg, handler, lockRenewalInterval, maxLockRenewalTime)
 		cancel()
 
 		result.RetryCount = attempt
@@ -220,6 +744,12 @@ func (p *MessageProcessor) ProcessMessage(ctx context.Context, message *azservic
 			return result
 		}
 
+		// A failure with a retry time is re-scheduled on the queue rather than retried here, and an
+		// abandoned message is left to another worker
+		if !result.DeferUntil.IsZero() || result.Abandon {
+			return result
+		}
+
 		// If not retryable or max retries reached, return the result
 		if !result.Retryable || attempt == maxRetries {
 			return result
@@ -251,85 +781,125 @@ func (p *MessageProcessor) ProcessMessage(ctx context.Context, message *azservic
 	}
 }
 
-// processMessageWithRenewal processes a message with automatic lock renewal
-func (p *MessageProcessor) processMessageWithRenewal(ctx context.Context, message *azservicebus.ReceivedMessage, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration) *models.MessageProcessingResult {
+// processMessageWithRenewal processes a decoded message with automatic lock renewal. Every attempt
+// gets its own copy of the task message. The lock is renewed
+// on its own context until the handler has returned, so it stays valid while a timed-out handler
+// winds down and the message is abandoned, and the renewal goroutine has always exited on return.
+func (p *MessageProcessor) processMessageWithRenewal(ctx context.Context, message *azservicebus.ReceivedMessage, taskMsg models.TaskMessage, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration) *models.MessageProcessingResult {
 	// Validate lock renewal interval (should be at least 1 second to avoid overwhelming the service)
 	if lockRenewalInterval < time.Second {
 		gologger.Warning().Msgf("Lock renewal interval too short (%v), using minimum of 1 second", lockRenewalInterval)
 		lockRenewalInterval = time.Second
 	}
 
-	// Parse the message first
-	var taskMsg models.TaskMessage
-	if err := json.Unmarshal(message.Body, &taskMsg); err != nil {
-		return &models.MessageProcessingResult{
-			Success:   false,
-			Error:     fmt.Errorf("failed to parse message as JSON: %w", err),
-			Retryable: false,
-		}
+	if taskMsg.CorrelationID == "" {
+		taskMsg.CorrelationID = messageCorrelationID(message)
+	}
+	taskMsg.Attempt = messageAttempt(message)
+
+	// The handler sees the lock through its context so it can stop before the lock is lost
+	lockedUntil := time.Now().Add(lockRenewalInterval)
+	if message.LockedUntil != nil {
+		lockedUntil = *message.LockedUntil
 	}
+	lock := models.NewMessageLock(lockedUntil, time.Now().Add(maxLockRenewalTime))
+
+	renewal := startLockRenewal(ctx, p.receiver, message, lock, lockRenewalInterval, maxLockRenewalTime)
+	defer renewal.stop()
 
 	// Create a context with timeout for the entire operation
 	operationCtx, cancelOperation := context.WithTimeout(ctx, maxLockRenewalTime)
 	defer cancelOperation()
 
-	// Create a channel to signal completion
 	done := make(chan *models.MessageProcessingResult, 1)
-	renewalError := make(chan error, 1)
-
-	// Start the handler in a goroutine
 	go func() {
-		result := handler(operationCtx, &taskMsg)
-		done <- result
-	}()
-
-	// Start lock renewal goroutine
-	go func() {
-		ticker := time.NewTicker(lockRenewalInterval)
-		defer ticker.Stop()
-
-		// Renew lock immediately after receiving the message
-		if err := p.receiver.RenewMessageLock(operationCtx, message, nil); err != nil {
-			gologger.Warning().Msgf("Failed to renew message lock initially: %v", err)
-			renewalError <- err
-			return
-		}
-		gologger.Debug().Msg("Initial message lock renewal successful")
-
-		for {
-			select {
-			case <-operationCtx.Done():
-				gologger.Debug().Msg("Lock renewal stopped due to operation completion or cancellation")
-				return
-			case <-ticker.C:
-				// Renew the message lock
-				if err := p.receiver.RenewMessageLock(operationCtx, message, nil); err != nil {
-					gologger.Warning().Msgf("Failed to renew message lock: %v", err)
-					renewalError <- err
-					return
-				}
-				gologger.Debug().Msg("Message lock renewed successfully")
-			}
-		}
+		done <- handler(models.WithMessageLock(operationCtx, lock), &taskMsg)
 	}()
 
-	// Wait for either completion, context cancellation, or renewal error
 	select {
+	case result := <-done:
+		return result
+
 	case <-operationCtx.Done():
+		// Keep the lock while the handler winds down, so the message is abandoned rather than lost
+		err := operationCtx.Err()
+		p.awaitHandler(done, p.stopTimeout)
 		return &models.MessageProcessingResult{
 			Success:   false,
-			Error:     operationCtx.Err(),
+			Error:     err,
 			Retryable: true, // Context cancellation is usually retryable
 		}
-	case err := <-renewalError:
-		// Cancel the operation if lock renewal fails
-		cancelOperation()
+
+	case err := <-renewal.Failed():
+		// Give the handler until the lock expires to checkpoint and stop, then cancel it
+		gologger.Warning().Msgf("Message lock lost, handler has until %s to stop", lock.LockedUntil().Format(time.RFC3339))
+		result := p.awaitHandler(done, time.Until(lock.LockedUntil()))
+		if result == nil {
+			cancelOperation()
+			p.awaitHandler(done, p.stopTimeout)
+		} else if result.Success {
+			return result
+		}
 		return &models.MessageProcessingResult{
 			Success:   false,
 			Error:     fmt.Errorf("lock renewal failed: %w", err),
 			Retryable: true, // Lock renewal failures are usually retryable
 		}
+	}
+}
+
+// messageCorrelationID returns the correlation ID set on the message, or the trace ID of its
+// traceparent application property; "" when it has neither
+func messageCorrelationID(message *azservicebus.ReceivedMessage) string {
+	if message.CorrelationID != nil && *message.CorrelationID != "" {
+		return *message.CorrelationID
+	}
+	if traceparent, ok := message.ApplicationProperties["traceparent"].(string); ok {
+		return models.TraceIDFromTraceparent(traceparent)
+	}
+	return ""
+}
+
+// messageAttempt returns which attempt at its task a delivery of the message is, counting the
+// deliveries of the copies it was re-scheduled from
+func messageAttempt(message *azservicebus.ReceivedMessage) int {
+	prior := 0
+	if count, ok := message.ApplicationProperties[attemptsProperty].(int64); ok {
+		prior = int(count)
+	}
+	return prior + max(int(message.DeliveryCount), 1)
+}
+
+// deadLetterOptions records the reason of a result that has one on its dead-lettered message
+func deadLetterOptions(result *models.MessageProcessingResult) *azservicebus.DeadLetterOptions {
+	if result.DeadLetterReason == "" {
+		return nil
+	}
+	options := &azservicebus.DeadLetterOptions{Reason: &result.DeadLetterReason}
+	if result.Error != nil {
+		options.ErrorDescription = nonEmpty(result.Error.Error())
+	}
+	return options
+}
+
+// nonEmpty returns a pointer to s, or nil when s is empty
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// awaitHandler waits up to timeout for the handler's result, returning nil if it has not returned
+func (p *MessageProcessor) awaitHandler(done <-chan *models.MessageProcessingResult, timeout time.Duration) *models.MessageProcessingResult {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
 	case result := <-done:
 		return result
+	case <-timer.C:
+		gologger.Warning().Msgf("Handler did not stop within %s", timeout)
+		return nil
 	}
 }
@@ -0,0 +1,204 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/messaging/azservicebus"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestProcessMessage_RejectsMalformedMessage(t *testing.T) {
+	processor := &MessageProcessor{receiver: &fakeRenewer{}, stopTimeout: time.Second, maxMessageSize: models.DefaultMaxMessageSize}
+	message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: []byte(`{"task":"masscan","scan_id":1,"domain":"example.com"}`)}
+
+	called := false
+	handler := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		called = true
+		return &models.MessageProcessingResult{Success: true}
+	}
+	result := processor.ProcessMessage(context.Background(), message, handler, time.Second, time.Minute, time.Minute)
+
+	if called {
+		t.Error("Expected the handler not to see a message of an unknown task type")
+	}
+	if result.Success || result.Retryable || result.RetryCount != 0 || result.DeadLetterReason != models.RejectUnknownTask {
+		t.Errorf("Expected an immediate dead-letter for an unknown task type, got %+v", result)
+	}
+}
+
+// fakeQueue records how messages were settled and which copies were scheduled
+type fakeQueue struct {
+	completed, abandoned, deadLettered int
+	deadLetterReason                   string
+	scheduled                          []*azservicebus.Message
+	scheduledFor                       time.Time
+	scheduleErr                        error
+}
+
+func (q *fakeQueue) CompleteMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.CompleteMessageOptions) error {
+	q.completed++
+	return nil
+}
+
+func (q *fakeQueue) AbandonMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.AbandonMessageOptions) error {
+	q.abandoned++
+	return nil
+}
+
+func (q *fakeQueue) DeadLetterMessage(ctx context.Context, message *azservicebus.ReceivedMessage, options *azservicebus.DeadLetterOptions) error {
+	q.deadLettered++
+	if options != nil && options.Reason != nil {
+		q.deadLetterReason = *options.Reason
+	}
+	return nil
+}
+
+func (q *fakeQueue) SendMessage(ctx context.Context, message *azservicebus.Message, options *azservicebus.SendMessageOptions) error {
+	return nil
+}
+
+func (q *fakeQueue) ScheduleMessages(ctx context.Context, messages []*azservicebus.Message, scheduledEnqueueTime time.Time, options *azservicebus.ScheduleMessagesOptions) ([]int64, error) {
+	if q.scheduleErr != nil {
+		return nil, q.scheduleErr
+	}
+	q.scheduled = append(q.scheduled, messages...)
+	q.scheduledFor = scheduledEnqueueTime
+	return make([]int64, len(messages)), nil
+}
+
+func (q *fakeQueue) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestHandleMessageResult_RetriesLater(t *testing.T) {
+	retryAt := time.Now().Add(2 * time.Hour)
+	failure := &models.MessageProcessingResult{Success: false, Error: errors.New("banned"), DeferUntil: retryAt, RetryCount: 2, DeadLetterReason: "Banned"}
+
+	tests := []struct {
+		name             string
+		properties       map[string]any
+		deliveries       uint32
+		wantDeadLettered bool
+		wantRetries      int64
+		wantAttempts     int64
+	}{
+		{name: "first deferred retry", deliveries: 1, wantRetries: 1, wantAttempts: 1},
+		{name: "counts earlier retries and attempts", properties: map[string]any{deferredRetriesProperty: int64(2), attemptsProperty: int64(3), "traceparent": "kept"}, deliveries: 2, wantRetries: 3, wantAttempts: 5},
+		{name: "dead-letters after the last retry", properties: map[string]any{deferredRetriesProperty: int64(maxDeferredRetries)}, deliveries: 1, wantDeadLettered: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &fakeQueue{}
+			client := &ServiceBusClient{sender: queue}
+			message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: []byte(`{}`), ApplicationProperties: tt.properties, DeliveryCount: tt.deliveries}
+
+			if err := client.handleMessageResult(context.Background(), queue, message, failure); err != nil {
+				t.Fatalf("handleMessageResult failed: %v", err)
+			}
+			if tt.wantDeadLettered {
+				if queue.deadLettered != 1 || len(queue.scheduled) != 0 || queue.deadLetterReason != failure.DeadLetterReason {
+					t.Errorf("Expected the message dead-lettered for %q and nothing scheduled, got %+v", failure.DeadLetterReason, queue)
+				}
+				return
+			}
+			if queue.completed != 1 || len(queue.scheduled) != 1 || !queue.scheduledFor.Equal(retryAt) {
+				t.Fatalf("Expected one copy scheduled for %s and the original completed, got %+v", retryAt, queue)
+			}
+			properties := queue.scheduled[0].ApplicationProperties
+			if properties[deferredRetriesProperty] != tt.wantRetries || properties[attemptsProperty] != tt.wantAttempts {
+				t.Errorf("Scheduled copy has %v deferred retries and %v attempts, want %d and %d",
+					properties[deferredRetriesProperty], properties[attemptsProperty], tt.wantRetries, tt.wantAttempts)
+			}
+			if properties[retryCountProperty] != int64(failure.RetryCount) || properties[deadLetterReasonProperty] != failure.DeadLetterReason {
+				t.Errorf("Scheduled copy has retry count %v and dead-letter reason %v, want %d and %q",
+					properties[retryCountProperty], properties[deadLetterReasonProperty], failure.RetryCount, failure.DeadLetterReason)
+			}
+			if tt.properties["traceparent"] != nil && properties["traceparent"] != "kept" {
+				t.Error("Expected the other application properties copied to the scheduled message")
+			}
+			if tt.properties != nil && tt.properties[deferredRetriesProperty] == tt.wantRetries {
+				t.Error("Expected the received message's properties left alone")
+			}
+		})
+	}
+}
+
+func TestHandleMessageResult_DeferralIsNotARetry(t *testing.T) {
+	queue := &fakeQueue{}
+	client := &ServiceBusClient{sender: queue}
+	properties := map[string]any{deferredRetriesProperty: int64(maxDeferredRetries)}
+	message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: []byte(`{}`), ApplicationProperties: properties}
+
+	deferral := &models.MessageProcessingResult{Success: true, DeferUntil: time.Now().Add(time.Hour)}
+	if err := client.handleMessageResult(context.Background(), queue, message, deferral); err != nil {
+		t.Fatalf("handleMessageResult failed: %v", err)
+	}
+	if queue.deadLettered != 0 || len(queue.scheduled) != 1 {
+		t.Fatalf("Expected a deferral scheduled whatever its retry count, got %+v", queue)
+	}
+	if got := queue.scheduled[0].ApplicationProperties[deferredRetriesProperty]; got != int64(maxDeferredRetries) {
+		t.Errorf("Deferral changed the deferred retry count to %v", got)
+	}
+}
+
+func TestHandleMessageResult_AbandonsWhenSchedulingFails(t *testing.T) {
+	queue := &fakeQueue{scheduleErr: errors.New("unavailable")}
+	client := &ServiceBusClient{sender: queue}
+	message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: []byte(`{}`)}
+
+	failure := &models.MessageProcessingResult{Success: false, Error: errors.New("banned"), DeferUntil: time.Now().Add(time.Hour)}
+	if err := client.handleMessageResult(context.Background(), queue, message, failure); err == nil {
+		t.Error("Expected the scheduling failure reported")
+	}
+	if queue.abandoned != 1 || queue.completed != 0 {
+		t.Errorf("Expected the message abandoned rather than lost, got %+v", queue)
+	}
+}
+
+func TestHandleMessageResult_HandsOverLater(t *testing.T) {
+	abandon := &models.MessageProcessingResult{Error: errors.New("task type naabu is disabled"), Abandon: true}
+
+	queue := &fakeQueue{}
+	client := &ServiceBusClient{sender: queue}
+	message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: []byte(`{}`), ApplicationProperties: map[string]any{attemptsProperty: int64(2)}, DeliveryCount: 1}
+	if err := client.handleMessageResult(context.Background(), queue, message, abandon); err != nil {
+		t.Fatalf("handleMessageResult failed: %v", err)
+	}
+	if queue.abandoned != 0 || queue.completed != 1 || len(queue.scheduled) != 1 || time.Until(queue.scheduledFor) < handOverDelay/2 {
+		t.Fatalf("Expected a delayed copy instead of an abandon, got %+v", queue)
+	}
+	if got := queue.scheduled[0].ApplicationProperties[attemptsProperty]; got != int64(3) {
+		t.Errorf("Scheduled copy has %v attempts, want 3", got)
+	}
+
+	queue = &fakeQueue{}
+	message.ApplicationProperties = map[string]any{attemptsProperty: int64(maxHandOverDeliveries - 1)}
+	if err := client.handleMessageResult(context.Background(), queue, message, abandon); err != nil {
+		t.Fatalf("handleMessageResult failed: %v", err)
+	}
+	if queue.deadLettered != 1 || len(queue.scheduled) != 0 {
+		t.Errorf("Expected the message dead-lettered once no worker took it, got %+v", queue)
+	}
+}
+
+func TestHandleMessageResult_RoutesResentMessages(t *testing.T) {
+	queue := &fakeQueue{}
+	client := &ServiceBusClient{sender: queue}
+	body := []byte(`{"task":"port_scan","scan_id":1,"domain":"example.com","requires":["gpu"]}`)
+	message := &azservicebus.ReceivedMessage{MessageID: "m-1", Body: body, ApplicationProperties: map[string]any{"traceparent": "kept"}, DeliveryCount: 1}
+
+	abandon := &models.MessageProcessingResult{Error: errors.New("worker lacks the capabilities gpu"), Abandon: true}
+	if err := client.handleMessageResult(context.Background(), queue, message, abandon); err != nil {
+		t.Fatalf("handleMessageResult failed: %v", err)
+	}
+	properties := queue.scheduled[0].ApplicationProperties
+	if properties["task"] != "port_scan" || properties["requires_gpu"] != true || properties["traceparent"] != "kept" {
+		t.Errorf("Expected the routing properties added to the re-sent message, got %v", properties)
+	}
+	if _, ok := message.ApplicationProperties["task"]; ok {
+		t.Error("Expected the received message's properties left alone")
+	}
+}
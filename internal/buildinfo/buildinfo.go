@@ -0,0 +1,72 @@
+// Package buildinfo reports the version of the running worker. Release builds set the version,
+// commit and build date with ldflags:
+//
+//	go build -ldflags "-X github.com/allsafeASM/api/internal/buildinfo.Version=v1.4.0 \
+//	  -X github.com/allsafeASM/api/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/allsafeASM/api/internal/buildinfo.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package buildinfo
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Set with -ldflags "-X ..." at build time
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// scannerModules maps each scanner to the module its library version is read from
+var scannerModules = map[string]string{
+	"subfinder": "github.com/projectdiscovery/subfinder/v2",
+	"httpx":     "github.com/projectdiscovery/httpx",
+	"dnsx":      "github.com/projectdiscovery/dnsx",
+	"naabu":     "github.com/projectdiscovery/naabu/v2",
+	"nuclei":    "github.com/projectdiscovery/nuclei/v3",
+}
+
+// Get returns the build info of the running worker
+var Get = sync.OnceValue(func() models.BuildInfo {
+	info, _ := debug.ReadBuildInfo()
+	return fromBuildInfo(info)
+})
+
+// fromBuildInfo combines the ldflags values with the module versions of the binary. Builds
+// without ldflags fall back to the commit and time the Go toolchain stamped from version control.
+func fromBuildInfo(info *debug.BuildInfo) models.BuildInfo {
+	build := models.BuildInfo{Version: Version, Commit: Commit, BuildDate: BuildDate}
+	if info == nil {
+		return build
+	}
+
+	build.GoVersion = info.GoVersion
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && build.Commit == "":
+			build.Commit = setting.Value
+		case setting.Key == "vcs.time" && build.BuildDate == "":
+			build.BuildDate = setting.Value
+		}
+	}
+
+	versions := make(map[string]string, len(info.Deps))
+	for _, dep := range info.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		versions[dep.Path] = dep.Version
+	}
+	for scanner, module := range scannerModules {
+		if version, ok := versions[module]; ok {
+			if build.Scanners == nil {
+				build.Scanners = make(map[string]string, len(scannerModules))
+			}
+			build.Scanners[scanner] = version
+		}
+	}
+	return build
+}
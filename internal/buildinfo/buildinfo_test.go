@@ -0,0 +1,43 @@
+package buildinfo
+
+import (
+	"reflect"
+	"runtime/debug"
+	"testing"
+)
+
+func TestFromBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.24.4",
+		Deps: []*debug.Module{
+			{Path: "github.com/projectdiscovery/nuclei/v3", Version: "v3.4.7"},
+			{Path: "github.com/projectdiscovery/httpx", Version: "v1.7.0", Replace: &debug.Module{Path: "github.com/projectdiscovery/httpx", Version: "v1.7.1"}},
+			{Path: "golang.org/x/net", Version: "v0.40.0"},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "1a2b3c4d5e6f"},
+			{Key: "vcs.time", Value: "2025-01-01T10:00:00Z"},
+		},
+	}
+
+	build := fromBuildInfo(info)
+	if build.Version != "dev" || build.Commit != "1a2b3c4d5e6f" || build.BuildDate != "2025-01-01T10:00:00Z" || build.GoVersion != "go1.24.4" {
+		t.Errorf("fromBuildInfo() = %+v, want the VCS stamp of a dev build", build)
+	}
+	if want := map[string]string{"nuclei": "v3.4.7", "httpx": "v1.7.1"}; !reflect.DeepEqual(build.Scanners, want) {
+		t.Errorf("Scanners = %v, want %v", build.Scanners, want)
+	}
+	if got := build.String(); got != "dev (commit 1a2b3c4, built 2025-01-01T10:00:00Z)" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// Values set with ldflags win over the VCS stamp
+	Commit, BuildDate = "ffffffffffff", "2025-02-02T00:00:00Z"
+	defer func() { Commit, BuildDate = "", "" }()
+	if build := fromBuildInfo(info); build.Commit != "ffffffffffff" || build.BuildDate != "2025-02-02T00:00:00Z" {
+		t.Errorf("fromBuildInfo() = %+v, want the ldflags values", build)
+	}
+	if build := fromBuildInfo(nil); build.Commit != "ffffffffffff" || build.Scanners != nil {
+		t.Errorf("fromBuildInfo(nil) = %+v", build)
+	}
+}
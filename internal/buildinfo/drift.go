@@ -0,0 +1,154 @@
+package buildinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+// maxManifestSize bounds the version manifest read from the configured URL
+const maxManifestSize = 64 << 10
+
+// DriftChecker periodically compares the running worker with a version manifest, so workers
+// left on an old image do not keep producing results with outdated templates and tools
+type DriftChecker struct {
+	url       string
+	build     models.BuildInfo // Running worker and scanner versions, compared with the manifest
+	templates func() string    // Reads the installed nuclei templates release; nil when unknown
+	interval  time.Duration
+	client    *http.Client
+	alert     func(ctx context.Context, drift models.VersionDrift)
+
+	mu      sync.Mutex
+	drift   *models.VersionDrift // nil until the first successful check
+	alerted string               // Drift the last stale alert was sent for
+}
+
+// NewDriftChecker creates a checker that reads the manifest from url every interval
+func NewDriftChecker(url string, interval time.Duration) *DriftChecker {
+	return &DriftChecker{
+		url:      url,
+		build:    Get(),
+		interval: interval,
+		client:   utils.NewTracingClient(30 * time.Second),
+	}
+}
+
+// SetTemplatesVersion sets the function that reads the release of the installed nuclei
+// templates, compared with the manifest's minimum_templates
+func (c *DriftChecker) SetTemplatesVersion(templates func() string) {
+	c.templates = templates
+}
+
+// SetAlert sets the function notified when the worker becomes stale. It is called once for
+// every distinct way the worker is stale, not on every check.
+func (c *DriftChecker) SetAlert(alert func(ctx context.Context, drift models.VersionDrift)) {
+	c.alert = alert
+}
+
+// Run checks the manifest right away and then every interval until the context is done
+func (c *DriftChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Check(ctx); err != nil && ctx.Err() == nil {
+			gologger.Warning().Msgf("Version check failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check reads the manifest and compares the running version with it. A failed read keeps the
+// result of the last successful check.
+func (c *DriftChecker) Check(ctx context.Context) (models.VersionDrift, error) {
+	manifest, err := c.fetchManifest(ctx)
+	if err != nil {
+		return models.VersionDrift{}, err
+	}
+	running := models.RunningVersions{Worker: c.build.Version, Scanners: c.build.Scanners}
+	if c.templates != nil {
+		running.Templates = c.templates()
+	}
+	drift := manifest.Compare(running, time.Now().UTC())
+
+	c.mu.Lock()
+	c.drift = &drift
+	alert := drift.Stale && c.alerted != drift.String()
+	if alert {
+		c.alerted = drift.String()
+	}
+	c.mu.Unlock()
+
+	switch {
+	case drift.Stale:
+		gologger.Warning().Msgf("Version drift: %s", drift)
+	case drift.Behind:
+		gologger.Info().Msgf("Version drift: %s", drift)
+	default:
+		gologger.Debug().Msgf("Version check: %s", drift)
+	}
+	if alert && c.alert != nil {
+		c.alert(ctx, drift)
+	}
+	return drift, nil
+}
+
+// Drift returns the result of the last successful check, or nil before the first one
+func (c *DriftChecker) Drift() *models.VersionDrift {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.drift == nil {
+		return nil
+	}
+	drift := *c.drift
+	return &drift
+}
+
+// Stale returns an error describing the drift when the worker, a scanner or the templates are
+// older than the required minimum, and nil otherwise or before the first successful check
+func (c *DriftChecker) Stale() error {
+	if drift := c.Drift(); drift != nil && drift.Stale {
+		return errors.New(drift.String())
+	}
+	return nil
+}
+
+// fetchManifest reads and parses the version manifest
+func (c *DriftChecker) fetchManifest(ctx context.Context) (models.VersionManifest, error) {
+	var manifest models.VersionManifest
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return manifest, fmt.Errorf("invalid version manifest URL: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read version manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return manifest, fmt.Errorf("failed to read version manifest: status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("invalid version manifest: %w", err)
+	}
+	if manifest.MinimumVersion == "" {
+		return manifest, errors.New("invalid version manifest: minimum_version is required")
+	}
+	return manifest, nil
+}
@@ -0,0 +1,104 @@
+package buildinfo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// manifestServer serves the manifest the returned pointer currently holds
+func manifestServer(t *testing.T, manifest string) (*httptest.Server, *string) {
+	t.Helper()
+	current := &manifest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(*current))
+	}))
+	t.Cleanup(server.Close)
+	return server, current
+}
+
+func TestDriftChecker(t *testing.T) {
+	server, manifest := manifestServer(t, `{"minimum_version": "v1.4.0", "latest_version": "v1.5.0"}`)
+
+	checker := NewDriftChecker(server.URL, 0)
+	if checker.build.Version != "dev" {
+		t.Fatalf("Running version = %q, want the test binary's dev", checker.build.Version)
+	}
+	if err := checker.Stale(); err != nil || checker.Drift() != nil {
+		t.Fatalf("Stale() before the first check = %v", err)
+	}
+	drift, err := checker.Check(context.Background())
+	if err != nil || !drift.Stale || checker.Stale() == nil {
+		t.Fatalf("Check() = %+v, %v, want a dev build to be stale", drift, err)
+	}
+
+	*manifest = `{"minimum_version": "v1.4.0", "allow_unversioned": true}`
+	if drift, err := checker.Check(context.Background()); err != nil || drift.Stale {
+		t.Fatalf("Check() = %+v, %v, want a dev build allowed", drift, err)
+	}
+
+	*manifest = `{"latest_version": "v1.5.0"}`
+	if _, err := checker.Check(context.Background()); err == nil {
+		t.Error("Check() accepted a manifest without a minimum version")
+	}
+	if checker.Drift() == nil {
+		t.Error("A failed check dropped the last result")
+	}
+}
+
+func TestDriftChecker_Alert(t *testing.T) {
+	server, manifest := manifestServer(t, `{"minimum_version": "v1.4.0", "message": "new nuclei templates"}`)
+
+	checker := NewDriftChecker(server.URL, 0)
+	checker.build.Version = "v1.3.0"
+	var alerts []models.VersionDrift
+	checker.SetAlert(func(ctx context.Context, drift models.VersionDrift) { alerts = append(alerts, drift) })
+
+	// Alerts fire once per required minimum, not on every check
+	for _, minimum := range []string{"v1.4.0", "v1.4.0", "v1.5.0"} {
+		*manifest = `{"minimum_version": "` + minimum + `"}`
+		if _, err := checker.Check(context.Background()); err != nil {
+			t.Fatalf("Check() error = %v", err)
+		}
+	}
+	if len(alerts) != 2 || alerts[1].Minimum != "v1.5.0" {
+		t.Errorf("Alerts = %+v, want one per minimum version", alerts)
+	}
+	if err := checker.Stale(); err == nil || err.Error() != "worker v1.3.0 is older than the required minimum v1.5.0" {
+		t.Errorf("Stale() = %v", err)
+	}
+
+	*manifest = `{"minimum_version": "v1.2.0", "latest_version": "v1.5.0"}`
+	if drift, err := checker.Check(context.Background()); err != nil || drift.Stale || !drift.Behind || checker.Stale() != nil {
+		t.Errorf("Check() = %+v, %v, want a worker behind the latest version but not stale", drift, err)
+	}
+}
+
+func TestDriftChecker_Scanners(t *testing.T) {
+	server, manifest := manifestServer(t, `{"minimum_version": "v1.4.0", "minimum_scanners": {"nuclei": "v3.4.7"}, "minimum_templates": "v10.1.0"}`)
+
+	checker := NewDriftChecker(server.URL, 0)
+	checker.build = models.BuildInfo{Version: "v1.4.0", Scanners: map[string]string{"nuclei": "v3.4.2"}}
+	templates := "v10.0.9"
+	checker.SetTemplatesVersion(func() string { return templates })
+
+	drift, err := checker.Check(context.Background())
+	want := "nuclei v3.4.2 is older than the required minimum v3.4.7; nuclei templates v10.0.9 is older than the required minimum v10.1.0"
+	if err != nil || !drift.Stale || drift.String() != want {
+		t.Fatalf("Check() = %q, %v, want %q", drift, err, want)
+	}
+
+	checker.build.Scanners["nuclei"] = "v3.4.7"
+	templates = ""
+	if drift, _ := checker.Check(context.Background()); !drift.Stale || len(drift.Reasons) != 1 {
+		t.Errorf("Check() = %+v, want templates of unknown release to be stale", drift)
+	}
+
+	*manifest = `{"minimum_version": "v1.4.0", "minimum_templates": "v10.1.0", "allow_unversioned": true}`
+	if drift, _ := checker.Check(context.Background()); drift.Stale {
+		t.Errorf("Check() = %+v, want unknown templates allowed", drift)
+	}
+}
@@ -0,0 +1,88 @@
+package capacity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+	"golang.org/x/sync/semaphore"
+)
+
+// DefaultCapacity is the number of units the scanners of a worker share
+const DefaultCapacity = 4
+
+// DefaultWeights are the units each scanner takes while it runs. Nuclei and httpx start hundreds
+// of goroutines and connections, so two of them would saturate the host; scanners not listed take
+// a single unit. With the default capacity, a nuclei scan runs next to one light scanner at most.
+var DefaultWeights = map[models.Task]int{
+	models.TaskNuclei: 3,
+	models.TaskHttpx:  2,
+	models.TaskNaabu:  2,
+}
+
+// Budget shares a worker's capacity between the scanners of its concurrent tasks with a
+// weighted semaphore, so heavy scanners take turns while light ones keep running
+type Budget struct {
+	capacity int
+	weights  map[models.Task]int
+	sem      *semaphore.Weighted
+}
+
+// NewBudget creates a budget of capacity units. Weights override DefaultWeights per task, and
+// weights above the capacity are lowered to it, so such a scanner runs alone.
+func NewBudget(capacity int, weights map[models.Task]int) *Budget {
+	merged := make(map[models.Task]int, len(DefaultWeights)+len(weights))
+	for task, weight := range DefaultWeights {
+		merged[task] = weight
+	}
+	for task, weight := range weights {
+		merged[task] = weight
+	}
+	return &Budget{capacity: capacity, weights: merged, sem: semaphore.NewWeighted(int64(capacity))}
+}
+
+// ParseWeights parses weights in the form "task=units,task=units"
+func ParseWeights(spec string) (map[models.Task]int, error) {
+	weights := make(map[models.Task]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		task, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(task) == "" {
+			return nil, fmt.Errorf("invalid weight %q: expected task=units", entry)
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || weight <= 0 {
+			return nil, fmt.Errorf("invalid weight %q: units must be a positive integer", entry)
+		}
+		weights[models.Task(strings.ToLower(strings.TrimSpace(task)))] = weight
+	}
+	return weights, nil
+}
+
+// Weight returns the units a task's scanner takes
+func (b *Budget) Weight(task models.Task) int {
+	weight, ok := b.weights[task]
+	if !ok {
+		weight = 1
+	}
+	return min(weight, b.capacity)
+}
+
+// Capacity returns the units the budget shares
+func (b *Budget) Capacity() int {
+	return b.capacity
+}
+
+// TryAcquire takes the task's units if they are free right away. The returned function gives
+// them back; it is nil when the units were not taken.
+func (b *Budget) TryAcquire(task models.Task) func() {
+	weight := int64(b.Weight(task))
+	if !b.sem.TryAcquire(weight) {
+		return nil
+	}
+	return func() { b.sem.Release(weight) }
+}
@@ -0,0 +1,61 @@
+package capacity
+
+import (
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestParseWeights(t *testing.T) {
+	weights, err := ParseWeights(" Nuclei=4 , httpx=1,")
+	if err != nil {
+		t.Fatalf("ParseWeights failed: %v", err)
+	}
+	if weights[models.TaskNuclei] != 4 || weights[models.TaskHttpx] != 1 || len(weights) != 2 {
+		t.Errorf("Unexpected weights %v", weights)
+	}
+
+	for _, spec := range []string{"nuclei", "=2", "nuclei=0", "nuclei=many"} {
+		if _, err := ParseWeights(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestBudget_Weight(t *testing.T) {
+	budget := NewBudget(4, map[models.Task]int{models.TaskHttpx: 1, models.TaskNuclei: 10})
+	tests := map[models.Task]int{
+		models.TaskNuclei:    4, // Lowered to the capacity
+		models.TaskHttpx:     1, // Overridden
+		models.TaskNaabu:     2, // Default
+		models.TaskSubfinder: 1, // Not listed
+	}
+	for task, expected := range tests {
+		if got := budget.Weight(task); got != expected {
+			t.Errorf("Weight(%s) = %d, expected %d", task, got, expected)
+		}
+	}
+}
+
+// TestBudget_HeavyScannersWait tests that a second nuclei scan is turned away while light scanners still run
+func TestBudget_HeavyScannersWait(t *testing.T) {
+	budget := NewBudget(DefaultCapacity, nil)
+
+	releaseNuclei := budget.TryAcquire(models.TaskNuclei)
+	if releaseNuclei == nil {
+		t.Fatal("Expected the first nuclei scan to start")
+	}
+	if budget.TryAcquire(models.TaskNuclei) != nil {
+		t.Error("Expected a second nuclei scan to wait")
+	}
+	releaseDNSX := budget.TryAcquire(models.TaskDNSResolve)
+	if releaseDNSX == nil {
+		t.Fatal("Expected DNSX to run next to nuclei")
+	}
+	releaseDNSX()
+
+	releaseNuclei()
+	if release := budget.TryAcquire(models.TaskNuclei); release == nil {
+		t.Error("Expected the second nuclei scan to start once the first one finished")
+	}
+}
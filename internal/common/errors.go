@@ -1,8 +1,11 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"syscall"
+	"time"
 )
 
 // Error types for better error classification
@@ -17,6 +20,8 @@ const (
 	ErrorTypeNotFound      ErrorType = "not_found"
 	ErrorTypeInternal      ErrorType = "internal"
 	ErrorTypeScanner       ErrorType = "scanner"
+	ErrorTypeRateLimited   ErrorType = "rate_limited"
+	ErrorTypeDiskFull      ErrorType = "disk_full"
 )
 
 // AppError represents a structured application error
@@ -25,6 +30,9 @@ type AppError struct {
 	Message string
 	Field   string
 	Err     error
+	Stack   string // Stack trace, only captured for recovered panics
+	// RetryAfter is how long to wait before retrying, e.g. until a rate-limit ban is lifted
+	RetryAfter time.Duration
 }
 
 func (e *AppError) Error() string {
@@ -41,7 +49,7 @@ func (e *AppError) Unwrap() error {
 // IsRetryable determines if an error should be retried
 func (e *AppError) IsRetryable() bool {
 	switch e.Type {
-	case ErrorTypeNetwork, ErrorTypeTimeout, ErrorTypeScanner:
+	case ErrorTypeNetwork, ErrorTypeTimeout, ErrorTypeScanner, ErrorTypeRateLimited, ErrorTypeDiskFull:
 		return true
 	case ErrorTypeValidation, ErrorTypeConfiguration, ErrorTypePermission, ErrorTypeNotFound:
 		return false
@@ -115,6 +123,54 @@ func NewScannerError(message string, err error) *AppError {
 	}
 }
 
+// NewRateLimitError creates an error for a target or service that throttled or banned the
+// scanner; the task should be retried after retryAfter instead of right away
+func NewRateLimitError(message string, retryAfter time.Duration, err error) *AppError {
+	return &AppError{
+		Type:       ErrorTypeRateLimited,
+		Message:    message,
+		Err:        err,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewDiskFullError creates an error for a task that could not run or write its files because
+// the worker's disk is full; another worker, or this one after cleanup, can run it
+func NewDiskFullError(message string, err error) *AppError {
+	return &AppError{
+		Type:    ErrorTypeDiskFull,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// IsDiskFull reports whether an error is a disk full error or was caused by a full disk
+func IsDiskFull(err error) bool {
+	var appErr *AppError
+	if errors.As(err, &appErr) && appErr.Type == ErrorTypeDiskFull {
+		return true
+	}
+	return errors.Is(err, syscall.ENOSPC)
+}
+
+// RetryAfter returns how long to wait before retrying the failure, or 0 to retry right away
+func RetryAfter(err error) time.Duration {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr.RetryAfter
+	}
+	return 0
+}
+
+// NewPanicError creates an internal error for a recovered panic, keeping the stack trace
+func NewPanicError(message string, stack []byte) *AppError {
+	return &AppError{
+		Type:    ErrorTypeInternal,
+		Message: message,
+		Stack:   string(stack),
+	}
+}
+
 // ErrorClassifier provides centralized error classification
 type ErrorClassifier struct{}
 
@@ -133,6 +189,11 @@ func (c *ErrorClassifier) ClassifyError(err error) *AppError {
 		return appErr
 	}
 
+	// A full disk is checked first, as writing a file names it and its path may contain anything
+	if errors.Is(err, syscall.ENOSPC) {
+		return NewDiskFullError("no space left on the worker's disk", err)
+	}
+
 	errStr := strings.ToLower(err.Error())
 
 	// Permanent errors (non-retryable)
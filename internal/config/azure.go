@@ -2,31 +2,108 @@ package config
 
 import (
 	"fmt"
+	"os"
 	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
 )
 
+// azuriteConnectionString is the well-known connection string of a local Azurite emulator
+const azuriteConnectionString = "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;" +
+	"AccountKey=Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==;" +
+	"BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;"
+
 // AzureConfig holds Azure-specific configuration
 type AzureConfig struct {
-	ServiceBusConnectionString  string
-	ServiceBusNamespace         string
-	QueueName                   string
+	// DevMode reads tasks from a local directory queue instead of Service Bus and stores blobs in
+	// Azurite unless a connection string is set
+	DevMode                    bool
+	DevQueueDir                string
+	ServiceBusConnectionString string
+	ServiceBusNamespace        string
+	QueueName                  string
+	// Receive tasks from this subscription of the QueueName topic instead of from a queue
+	Subscription string
+	// Messages received at once; those after the first wait, locked, until it is processed
+	PrefetchCount int
+	// Messages larger than this are dead-lettered without being decoded
+	MaxMessageSizeKB int
+	// Lock duration the queue or subscription is configured with on Service Bus
+	LockDuration                int // seconds
 	BlobStorageConnectionString string
 	BlobContainerName           string
+	CreateBlobContainer         bool // Create the blob container at startup when it does not exist
+	// Data region of the BlobStorageConnectionString account, which keeps unrouted tenants
+	BlobStorageRegion string
+	// Connection strings of the other regions' accounts, from BLOB_STORAGE_CONNECTION_STRING_<REGION>
+	BlobStorageRegions map[string]string
+	// Tenant routing rules in the form "tenant=region,tenant=region"
+	BlobTenantRegions string
+	// Results larger than MaxResultSizeMB are stored compressed with a summary in their place; 0 disables
+	MaxResultSizeMB      int
+	ResultSummarySamples int // Entries kept in a summarized result
+	// Age in days past which compact control messages archive a domain's scans
+	ResultCompactionDays int
+	// Result events - publish to a Service Bus topic or an Event Grid topic, never both
+	ResultEventsTopic      string
+	EventGridTopicEndpoint string
+	EventGridTopicKey      string
 }
 
 // LoadAzureConfig loads Azure configuration from environment variables
 func LoadAzureConfig() AzureConfig {
+	devMode := getEnvAsBool("DEV_MODE", false)
+	blobConnectionString := ""
+	if devMode {
+		blobConnectionString = azuriteConnectionString
+	}
+
+	blobRegions := make(map[string]string)
+	for _, region := range strings.Split(getEnv("BLOB_STORAGE_REGIONS", ""), ",") {
+		if region = strings.ToLower(strings.TrimSpace(region)); region != "" {
+			blobRegions[region] = os.Getenv(regionConnectionStringEnv(region))
+		}
+	}
+
 	return AzureConfig{
+		DevMode:                     devMode,
+		DevQueueDir:                 getEnv("DEV_QUEUE_DIR", "./devqueue"),
 		ServiceBusConnectionString:  getEnv("SERVICEBUS_CONNECTION_STRING", ""),
 		ServiceBusNamespace:         getEnv("SERVICEBUS_NAMESPACE", "asm-queue"),
 		QueueName:                   getEnv("SERVICEBUS_QUEUE_NAME", "tasks"),
-		BlobStorageConnectionString: getEnv("BLOB_STORAGE_CONNECTION_STRING", ""),
+		Subscription:                getEnv("SERVICEBUS_SUBSCRIPTION", ""),
+		PrefetchCount:               getEnvAsInt("SERVICEBUS_PREFETCH_COUNT", 1),
+		LockDuration:                getEnvAsInt("SERVICEBUS_LOCK_DURATION", 60), // 1 minute, the Service Bus default
+		MaxMessageSizeKB:            getEnvAsInt("MAX_MESSAGE_SIZE_KB", models.DefaultMaxMessageSize>>10),
+		BlobStorageConnectionString: getEnv("BLOB_STORAGE_CONNECTION_STRING", blobConnectionString),
 		BlobContainerName:           getEnv("BLOB_CONTAINER_NAME", "scans"),
+		CreateBlobContainer:         getEnvAsBool("BLOB_CREATE_CONTAINER", devMode),
+		BlobStorageRegion:           strings.ToLower(getEnv("BLOB_STORAGE_REGION", "default")),
+		BlobStorageRegions:          blobRegions,
+		BlobTenantRegions:           getEnv("BLOB_TENANT_REGIONS", ""),
+		MaxResultSizeMB:             getEnvAsInt("MAX_RESULT_SIZE_MB", 100),
+		ResultSummarySamples:        getEnvAsInt("RESULT_SUMMARY_SAMPLES", 100),
+		ResultCompactionDays:        getEnvAsInt("RESULT_COMPACTION_DAYS", 90),
+		ResultEventsTopic:           getEnv("RESULT_EVENTS_TOPIC", ""),
+		EventGridTopicEndpoint:      getEnv("EVENT_GRID_TOPIC_ENDPOINT", ""),
+		EventGridTopicKey:           getEnv("EVENT_GRID_TOPIC_KEY", ""),
 	}
 }
 
 // ValidateAzureConfig validates Azure-specific configuration
 func (c *AzureConfig) ValidateAzureConfig() error {
+	// Service Bus premium allows messages of up to 100 MB
+	if c.MaxMessageSizeKB < 1 || c.MaxMessageSizeKB > 102400 {
+		return &ConfigError{
+			Field:   "MAX_MESSAGE_SIZE_KB",
+			Message: "Maximum message size must be between 1 and 102400 KB",
+		}
+	}
+
+	if c.DevMode {
+		return c.validateDevMode()
+	}
+
 	validations := []struct {
 		field   string
 		value   string
@@ -50,10 +127,149 @@ func (c *AzureConfig) ValidateAzureConfig() error {
 		return err
 	}
 
+	if len(c.Subscription) > 50 {
+		return &ConfigError{
+			Field:   "SERVICEBUS_SUBSCRIPTION",
+			Message: "Subscription name must be at most 50 characters",
+		}
+	}
+
+	if c.PrefetchCount < 1 || c.PrefetchCount > 100 {
+		return &ConfigError{
+			Field:   "SERVICEBUS_PREFETCH_COUNT",
+			Message: "Prefetch count must be between 1 and 100 messages",
+		}
+	}
+
+	// Service Bus allows lock durations of 5 seconds to 5 minutes
+	if err := validateRange("SERVICEBUS_LOCK_DURATION", c.LockDuration, 5, 300, "Lock duration"); err != nil {
+		return err
+	}
+
+	return c.validateStorage()
+}
+
+// validateDevMode validates the configuration of a worker without Service Bus
+func (c *AzureConfig) validateDevMode() error {
+	if strings.TrimSpace(c.DevQueueDir) == "" {
+		return &ConfigError{
+			Field:   "DEV_QUEUE_DIR",
+			Message: "DEV_QUEUE_DIR cannot be empty in DEV_MODE",
+		}
+	}
+	if c.ResultEventsTopic != "" {
+		return &ConfigError{
+			Field:   "RESULT_EVENTS_TOPIC",
+			Message: "RESULT_EVENTS_TOPIC needs Service Bus and cannot be used in DEV_MODE",
+		}
+	}
+	if err := validateRequiredField("BLOB_STORAGE_CONNECTION_STRING", c.BlobStorageConnectionString, "Blob Storage connection string is required"); err != nil {
+		return err
+	}
+	return c.validateStorage()
+}
+
+// validateStorage validates the blob container and result settings
+func (c *AzureConfig) validateStorage() error {
 	if err := validateContainerName(c.BlobContainerName); err != nil {
 		return err
 	}
 
+	if err := validateRange("MAX_RESULT_SIZE_MB", c.MaxResultSizeMB, 0, 4096, "Max result size"); err != nil {
+		return err
+	}
+
+	if err := validateRange("RESULT_SUMMARY_SAMPLES", c.ResultSummarySamples, 1, 10000, "Result summary samples"); err != nil {
+		return err
+	}
+
+	if c.ResultCompactionDays < 1 || c.ResultCompactionDays > 3650 {
+		return &ConfigError{
+			Field:   "RESULT_COMPACTION_DAYS",
+			Message: "RESULT_COMPACTION_DAYS must be between 1 and 3650 days",
+		}
+	}
+
+	if err := c.validateRegions(); err != nil {
+		return err
+	}
+
+	return c.validateResultEvents()
+}
+
+// validateRegions validates the storage accounts of the data regions and the tenant routing rules
+func (c *AzureConfig) validateRegions() error {
+	if strings.TrimSpace(c.BlobStorageRegion) == "" {
+		return &ConfigError{
+			Field:   "BLOB_STORAGE_REGION",
+			Message: "Blob storage region cannot be empty",
+		}
+	}
+	for region, connectionString := range c.BlobStorageRegions {
+		if region == c.BlobStorageRegion {
+			return &ConfigError{
+				Field:   "BLOB_STORAGE_REGIONS",
+				Message: fmt.Sprintf("region %s is already the region of BLOB_STORAGE_CONNECTION_STRING", region),
+			}
+		}
+		field := regionConnectionStringEnv(region)
+		if err := validateRequiredField(field, connectionString, fmt.Sprintf("Blob Storage connection string of region %s is required", region)); err != nil {
+			return err
+		}
+	}
+
+	routes, err := models.ParseTenantRegions(c.BlobTenantRegions)
+	if err != nil {
+		return &ConfigError{
+			Field:   "BLOB_TENANT_REGIONS",
+			Message: err.Error(),
+		}
+	}
+	// A tenant routed to a region without an account would have nowhere to store its results
+	for tenant, region := range routes {
+		if _, ok := c.BlobStorageRegions[region]; !ok && region != c.BlobStorageRegion {
+			return &ConfigError{
+				Field:   "BLOB_TENANT_REGIONS",
+				Message: fmt.Sprintf("tenant %s is routed to region %s, which is not in BLOB_STORAGE_REGIONS", tenant, region),
+			}
+		}
+	}
+	return nil
+}
+
+// regionConnectionStringEnv returns the variable holding the connection string of a region's account
+func regionConnectionStringEnv(region string) string {
+	return "BLOB_STORAGE_CONNECTION_STRING_" + strings.ToUpper(strings.ReplaceAll(region, "-", "_"))
+}
+
+// validateResultEvents validates the result event destination
+func (c *AzureConfig) validateResultEvents() error {
+	if c.ResultEventsTopic != "" && c.EventGridTopicEndpoint != "" {
+		return &ConfigError{
+			Field:   "RESULT_EVENTS_TOPIC",
+			Message: "set either RESULT_EVENTS_TOPIC or EVENT_GRID_TOPIC_ENDPOINT, not both",
+		}
+	}
+	if len(c.ResultEventsTopic) > 260 {
+		return &ConfigError{
+			Field:   "RESULT_EVENTS_TOPIC",
+			Message: "Topic name must be between 1 and 260 characters",
+		}
+	}
+	if c.EventGridTopicEndpoint != "" {
+		if !strings.HasPrefix(c.EventGridTopicEndpoint, "https://") {
+			return &ConfigError{
+				Field:   "EVENT_GRID_TOPIC_ENDPOINT",
+				Message: "EVENT_GRID_TOPIC_ENDPOINT must be an https URL",
+			}
+		}
+		if c.EventGridTopicKey == "" {
+			return &ConfigError{
+				Field:   "EVENT_GRID_TOPIC_KEY",
+				Message: "EVENT_GRID_TOPIC_KEY is required when EVENT_GRID_TOPIC_ENDPOINT is set",
+			}
+		}
+	}
 	return nil
 }
 
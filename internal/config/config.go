@@ -3,14 +3,30 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/allsafeASM/api/internal/api"
+	"github.com/allsafeASM/api/internal/capacity"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/allsafeASM/api/internal/quota"
+	"github.com/allsafeASM/api/internal/redaction"
+	"github.com/allsafeASM/api/internal/schedule"
+	"github.com/allsafeASM/api/internal/validation"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Azure AzureConfig
-	App   AppConfig
+	Azure  AzureConfig
+	App    AppConfig
+	DNSX   DNSXConfig
+	Nuclei NucleiConfig
+	Export ExportConfig
+	Faults FaultConfig
+	// Asset sources the scan scope is imported from
+	Connectors ConnectorConfig
 }
 
 // AppConfig holds application-specific configuration
@@ -18,36 +34,218 @@ type AppConfig struct {
 	LogLevel            string
 	PollInterval        int // seconds
 	ScannerTimeout      int // seconds
+	VerifyTimeout       int // seconds - timeout of verify tasks, which re-scan only new targets
 	LockRenewalInterval int // seconds - how often to renew message locks
 	MaxLockRenewalTime  int // seconds - maximum time to keep renewing locks
 	// Notification settings
 	EnableNotifications bool
 	NotificationTimeout int // seconds - timeout for notification requests
+	// How much of the result the orchestrator's completion payload carries: summary, counts or full
+	NotificationDetail string
+	// The notification outbox is swept for undelivered completion notifications this often
+	OutboxSweepInterval int // seconds
 	// Discord webhook settings
 	EnableDiscordNotifications bool
 	DiscordWebhookTimeout      int // seconds - timeout for Discord webhook requests
+	// Language and IANA timezone notification times and durations are rendered in
+	NotificationLocale   string
+	NotificationTimezone string
+	// Send a Discord digest of the whole scan after its final task; tasks of ScanDigestAfter
+	// count as final in addition to those flagged final_stage
+	ScanDigest      bool
+	ScanDigestAfter string
+	// Lowest nuclei severity pushed to the FINDING_ALERT_* channels while a scan runs
+	FindingAlertMinSeverity string
+	// Scan windows - "scope=HH:MM-HH:MM@Time/Zone" rules separated by ';'
+	ScanWindows string
+	// Passive source API key quotas - "source=requests" per 30 days separated by ','
+	PassiveSourceQuotas string
+	// Progress of running scanners is sent to Discord and Splunk this often; 0 disables it
+	ProgressInterval int // seconds
+	// Address the Prometheus metrics endpoint listens on; empty disables it
+	MetricsAddr string
+	// Queue depth is sampled for metrics and the autoscale signal this often; 0 disables it
+	QueueMetricsInterval int // seconds
+	// The replica hint served on /autoscale asks for the workers that clear the backlog within
+	// AutoscaleTargetDrain, between the minimum and maximum replicas. The workers serve the hint
+	// themselves, so the minimum is at least 1: a fleet scaled to zero has no one to ask.
+	AutoscaleTargetDrain     int // seconds
+	AutoscaleDefaultDuration int // seconds, assumed for task types no task of which finished yet
+	AutoscaleMinReplicas     int
+	AutoscaleMaxReplicas     int
+	// Queued messages peeked at to estimate the task types of the backlog; 0 disables it
+	AutoscalePeekMessages int
+	// Address the HTTP API listens on; empty disables it
+	APIAddr string
+	// API bearer tokens - "name:role:token" entries separated by ','
+	APITokens string
+	// Secret the status tokens of the public scan status endpoint are signed with; empty disables it
+	StatusTokenSecret string
+	// Secret webhook calls that queue tasks are signed with; empty disables the webhook endpoint
+	WebhookSecret string
+	// Status requests allowed per client and minute
+	StatusRateLimit int
+	// Export jobs assembled at once, and minutes the download links of their bundles are valid
+	ExportJobConcurrency int
+	ExportJobLinkTTL     int // minutes
+	// Index stored results into the per-domain asset inventory
+	InventoryTracking bool
+	// Units of worker capacity the scanners of concurrent tasks share
+	ScannerCapacity int
+	// Units each scanner takes - "task=units" entries separated by ','
+	ScannerWeights string
+	// TLS fingerprints httpx probes with - "tenant=fingerprint" entries separated by ','
+	HttpxTLSFingerprints string
+	// Redaction rules applied per tenant - "tenant=rule|rule" entries separated by ','
+	RedactionPolicy string
+	// Percentage of unusual httpx responses kept for manual review; 0 disables it
+	ReviewSampleRate int
+	// Responses kept for review per task at most
+	ReviewSampleMax int
+	// Retries all tasks of a scan may use together before the scan is halted; 0 disables it
+	RetryBudget int
+	// Percentage of probed hosts that must answer httpx before the scan is marked degraded; 0 disables it
+	QualityGateMinAlivePercent int
+	// Percentage of DNS queries that may fail before the scan is marked degraded; 0 disables it
+	QualityGateMaxDNSErrorPercent int
+	// Hosts or names a result needs before the quality gates judge it
+	QualityGateMinSample int
+	// Task types held while a scan is degraded and not reviewed - separated by ','
+	QualityGateHoldTasks string
+	// Hours a held task waits for a review before it fails
+	QualityGateMaxHoldHours int
+	// Hours a task of a paused scan waits for the scan to resume before it fails
+	PauseMaxHoldHours int
+	// Blocked responses in a row after which a host group is taken as banned; 0 disables it
+	BlockDetectionWindow int
+	// What happens to a banned host group: "backoff" or "abort"
+	BlockDetectionAction string
+	// Seconds to wait before each request to a backed-off host group
+	BlockBackoff int
+	// Minutes after which an httpx or nuclei task that ran into a ban is retried; 0 completes it
+	BlockRetryAfter int
+	// Run every nuclei task in compliance mode, honoring the robots.txt of its targets
+	ComplianceMode bool
+	// User agent compliance mode scans as; its product token picks the robots.txt rules
+	ComplianceUserAgent string
+	// Domains of a multi-domain task that run at once
+	MultiDomainParallelism int
+	// Scan profiles as a JSON object of profile names, layered over the built-in profiles
+	ScanProfiles string
+	// MB the temp files and caches of the worker may use together; 0 disables the budget
+	DiskBudgetMB int
+	// MB of free disk below which tasks are not started; 0 disables the check
+	DiskMinFreeMB int
+	// Temp files and caches are swept this often; 0 sweeps only before tasks on a full disk
+	DiskSweepInterval int // seconds
+	// Check scanner capabilities and paths at startup and disable the scanners that cannot run
+	SelfTest bool
+	// Task types this worker runs, separated by ','; empty runs all of them
+	EnabledTasks string
+	// What happens to messages of task types this worker does not run: "abandon" or "dead_letter"
+	DisabledTaskAction string
+	// Capability labels this worker advertises besides the detected ones, separated by ','
+	WorkerCapabilities string
+	// Compiled-in hooks to run, in order, separated by ','; empty runs none
+	Hooks string
+	// URL of the version manifest the worker compares itself with; empty disables the check
+	VersionManifestURL string
+	// Seconds between version manifest checks
+	VersionCheckInterval int
+	// Report unready and leave tasks to other workers while older than the required minimum version
+	VersionDriftUnready bool
+	// Return fixture results instead of scanning, for demos and staging
+	SimulationMode bool
+	// Blob prefix of fixture results that override the built-in ones, e.g. "fixtures/simulation"
+	SimulationFixtures string
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		Azure: LoadAzureConfig(),
-		App:   LoadAppConfig(),
+		Azure:  LoadAzureConfig(),
+		App:    LoadAppConfig(),
+		DNSX:   LoadDNSXConfig(),
+		Nuclei: LoadNucleiConfig(),
+		Export: LoadExportConfig(),
+		Faults: LoadFaultConfig(),
+
+		Connectors: LoadConnectorConfig(),
 	}
 }
 
 // LoadAppConfig loads application-specific configuration
 func LoadAppConfig() AppConfig {
 	return AppConfig{
-		LogLevel:                   getEnv("LOG_LEVEL", "info"),
-		PollInterval:               getEnvAsInt("POLL_INTERVAL", 5),
-		ScannerTimeout:             getEnvAsInt("SCANNER_TIMEOUT", 7200),       // 2 hours
-		LockRenewalInterval:        getEnvAsInt("LOCK_RENEWAL_INTERVAL", 30),   // 30 seconds
-		MaxLockRenewalTime:         getEnvAsInt("MAX_LOCK_RENEWAL_TIME", 3600), // 1 hour
-		EnableNotifications:        getEnvAsBool("ENABLE_NOTIFICATIONS", true),
-		NotificationTimeout:        getEnvAsInt("NOTIFICATION_TIMEOUT", 30), // 30 seconds
-		EnableDiscordNotifications: getEnvAsBool("ENABLE_DISCORD_NOTIFICATIONS", true),
-		DiscordWebhookTimeout:      getEnvAsInt("DISCORD_WEBHOOK_TIMEOUT", 30), // 30 seconds
+		LogLevel:                      getEnv("LOG_LEVEL", "info"),
+		PollInterval:                  getEnvAsInt("POLL_INTERVAL", 5),
+		ScannerTimeout:                getEnvAsInt("SCANNER_TIMEOUT", 7200),       // 2 hours
+		VerifyTimeout:                 getEnvAsInt("VERIFY_TIMEOUT", 600),         // 10 minutes
+		LockRenewalInterval:           getEnvAsInt("LOCK_RENEWAL_INTERVAL", 30),   // 30 seconds
+		MaxLockRenewalTime:            getEnvAsInt("MAX_LOCK_RENEWAL_TIME", 3600), // 1 hour
+		EnableNotifications:           getEnvAsBool("ENABLE_NOTIFICATIONS", true),
+		NotificationTimeout:           getEnvAsInt("NOTIFICATION_TIMEOUT", 30), // 30 seconds
+		NotificationDetail:            getEnv("NOTIFICATION_DETAIL", notification.PayloadDetailCounts),
+		OutboxSweepInterval:           getEnvAsInt("OUTBOX_SWEEP_INTERVAL", 60), // 1 minute
+		EnableDiscordNotifications:    getEnvAsBool("ENABLE_DISCORD_NOTIFICATIONS", true),
+		DiscordWebhookTimeout:         getEnvAsInt("DISCORD_WEBHOOK_TIMEOUT", 30), // 30 seconds
+		NotificationLocale:            getEnv("NOTIFICATION_LOCALE", "en"),
+		NotificationTimezone:          getEnv("NOTIFICATION_TIMEZONE", "UTC"),
+		ScanDigest:                    getEnvAsBool("SCAN_DIGEST", false),
+		ScanDigestAfter:               getEnv("SCAN_DIGEST_AFTER", "nuclei"),
+		FindingAlertMinSeverity:       getEnv("FINDING_ALERT_MIN_SEVERITY", "high"),
+		ScanWindows:                   getEnv("SCAN_WINDOWS", ""),
+		PassiveSourceQuotas:           getEnv("PASSIVE_SOURCE_QUOTAS", ""),
+		ProgressInterval:              getEnvAsInt("PROGRESS_INTERVAL", 600), // 10 minutes
+		MetricsAddr:                   getEnv("METRICS_ADDR", "127.0.0.1:9090"),
+		QueueMetricsInterval:          getEnvAsInt("QUEUE_METRICS_INTERVAL", 30), // 30 seconds
+		AutoscaleTargetDrain:          getEnvAsInt("AUTOSCALE_TARGET_DRAIN", 900),
+		AutoscaleDefaultDuration:      getEnvAsInt("AUTOSCALE_DEFAULT_TASK_DURATION", 300),
+		AutoscaleMinReplicas:          getEnvAsInt("AUTOSCALE_MIN_REPLICAS", 1),
+		AutoscaleMaxReplicas:          getEnvAsInt("AUTOSCALE_MAX_REPLICAS", 30),
+		AutoscalePeekMessages:         getEnvAsInt("AUTOSCALE_PEEK_MESSAGES", 100),
+		APIAddr:                       getEnv("API_ADDR", ""),
+		APITokens:                     getEnv("API_TOKENS", ""),
+		StatusTokenSecret:             getEnv("STATUS_TOKEN_SECRET", ""),
+		StatusRateLimit:               getEnvAsInt("STATUS_RATE_LIMIT", 30),
+		WebhookSecret:                 getEnv("WEBHOOK_SECRET", ""),
+		ExportJobConcurrency:          getEnvAsInt("EXPORT_JOB_CONCURRENCY", 2),
+		ExportJobLinkTTL:              getEnvAsInt("EXPORT_JOB_LINK_TTL", 60), // 1 hour
+		InventoryTracking:             getEnvAsBool("INVENTORY_TRACKING", true),
+		ScannerCapacity:               getEnvAsInt("SCANNER_CAPACITY", capacity.DefaultCapacity),
+		ScannerWeights:                getEnv("SCANNER_WEIGHTS", ""),
+		HttpxTLSFingerprints:          getEnv("HTTPX_TLS_FINGERPRINT", ""),
+		RedactionPolicy:               getEnv("REDACTION_POLICY", "*=all"),
+		ReviewSampleRate:              getEnvAsInt("REVIEW_SAMPLE_RATE", 0),
+		ReviewSampleMax:               getEnvAsInt("REVIEW_SAMPLE_MAX", 50),
+		RetryBudget:                   getEnvAsInt("RETRY_BUDGET", 20),
+		QualityGateMinAlivePercent:    getEnvAsInt("QUALITY_GATE_MIN_ALIVE_PERCENT", 0),
+		QualityGateMaxDNSErrorPercent: getEnvAsInt("QUALITY_GATE_MAX_DNS_ERROR_PERCENT", 0),
+		QualityGateMinSample:          getEnvAsInt("QUALITY_GATE_MIN_SAMPLE", 20),
+		QualityGateHoldTasks:          getEnv("QUALITY_GATE_HOLD_TASKS", ""),
+		QualityGateMaxHoldHours:       getEnvAsInt("QUALITY_GATE_MAX_HOLD_HOURS", 72),
+		PauseMaxHoldHours:             getEnvAsInt("PAUSE_MAX_HOLD_HOURS", 168),
+		BlockDetectionWindow:          getEnvAsInt("BLOCK_DETECTION_WINDOW", 25),
+		BlockDetectionAction:          getEnv("BLOCK_DETECTION_ACTION", "backoff"),
+		BlockBackoff:                  getEnvAsInt("BLOCK_BACKOFF", 2),
+		BlockRetryAfter:               getEnvAsInt("BLOCK_RETRY_AFTER", 120),
+		ComplianceMode:                getEnvAsBool("COMPLIANCE_MODE", false),
+		ComplianceUserAgent:           getEnv("COMPLIANCE_USER_AGENT", "allsafe-asm"),
+		MultiDomainParallelism:        getEnvAsInt("MULTI_DOMAIN_PARALLELISM", 4),
+		ScanProfiles:                  getEnv("SCAN_PROFILES", ""),
+		DiskBudgetMB:                  getEnvAsInt("DISK_BUDGET_MB", 2048),
+		DiskMinFreeMB:                 getEnvAsInt("DISK_MIN_FREE_MB", 256),
+		DiskSweepInterval:             getEnvAsInt("DISK_SWEEP_INTERVAL", 300),
+		SelfTest:                      getEnvAsBool("SELF_TEST", true),
+		EnabledTasks:                  getEnv("ENABLED_TASKS", ""),
+		DisabledTaskAction:            getEnv("DISABLED_TASK_ACTION", "abandon"),
+		WorkerCapabilities:            getEnv("WORKER_CAPABILITIES", ""),
+		Hooks:                         getEnv("HOOKS", ""),
+		VersionManifestURL:            getEnv("VERSION_MANIFEST_URL", ""),
+		VersionCheckInterval:          getEnvAsInt("VERSION_CHECK_INTERVAL", 3600),
+		VersionDriftUnready:           getEnvAsBool("VERSION_DRIFT_UNREADY", false),
+		SimulationMode:                getEnvAsBool("SIMULATION_MODE", false),
+		SimulationFixtures:            getEnv("SIMULATION_FIXTURES", ""),
 	}
 }
 
@@ -61,9 +259,56 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if err := c.DNSX.ValidateDNSXConfig(); err != nil {
+		return err
+	}
+
+	if err := c.Nuclei.ValidateNucleiConfig(); err != nil {
+		return err
+	}
+
+	if err := c.Export.ValidateExportConfig(); err != nil {
+		return err
+	}
+
+	if err := c.Faults.ValidateFaultConfig(); err != nil {
+		return err
+	}
+
+	if err := c.Connectors.ValidateConnectorConfig(); err != nil {
+		return err
+	}
+
+	// A lock renewed less often than it lasts expires between renewals
+	if !c.Azure.DevMode && c.App.LockRenewalInterval >= c.Azure.LockDuration {
+		return &ConfigError{
+			Field:   "LOCK_RENEWAL_INTERVAL",
+			Message: fmt.Sprintf("Lock renewal interval (%ds) must be shorter than SERVICEBUS_LOCK_DURATION (%ds)", c.App.LockRenewalInterval, c.Azure.LockDuration),
+		}
+	}
+
 	return nil
 }
 
+// LockWarnings describes the settings that are valid but risk message locks expiring while
+// their tasks are processed
+func (c *Config) LockWarnings() []string {
+	if c.Azure.DevMode {
+		return nil
+	}
+
+	var warnings []string
+	if c.App.LockRenewalInterval*2 > c.Azure.LockDuration {
+		warnings = append(warnings, fmt.Sprintf("LOCK_RENEWAL_INTERVAL (%ds) is more than half of SERVICEBUS_LOCK_DURATION (%ds), so a single slow or failed renewal lets the lock expire",
+			c.App.LockRenewalInterval, c.Azure.LockDuration))
+	}
+	if c.Azure.PrefetchCount > 1 && c.App.ScannerTimeout > c.App.MaxLockRenewalTime {
+		warnings = append(warnings, fmt.Sprintf("SERVICEBUS_PREFETCH_COUNT is %d, but prefetched messages are only renewed for MAX_LOCK_RENEWAL_TIME (%ds) while they wait, less than a scan of up to %ds before them may take",
+			c.Azure.PrefetchCount, c.App.MaxLockRenewalTime, c.App.ScannerTimeout))
+	}
+	return warnings
+}
+
 // ValidateAppConfig validates application-specific configuration
 func (c *AppConfig) ValidateAppConfig() error {
 	// Define validation rules
@@ -74,9 +319,11 @@ func (c *AppConfig) ValidateAppConfig() error {
 		fieldName string
 	}{
 		{"SCANNER_TIMEOUT", c.ScannerTimeout, 30, 7200, "Scanner timeout"},
+		{"VERIFY_TIMEOUT", c.VerifyTimeout, 30, 7200, "Verify timeout"},
 		{"POLL_INTERVAL", c.PollInterval, 1, 60, "Poll interval"},
 		{"LOCK_RENEWAL_INTERVAL", c.LockRenewalInterval, 10, 300, "Lock renewal interval"},
 		{"MAX_LOCK_RENEWAL_TIME", c.MaxLockRenewalTime, 60, 7200, "Max lock renewal time"},
+		{"OUTBOX_SWEEP_INTERVAL", c.OutboxSweepInterval, 10, 3600, "Outbox sweep interval"},
 	}
 
 	for _, v := range validations {
@@ -85,10 +332,271 @@ func (c *AppConfig) ValidateAppConfig() error {
 		}
 	}
 
+	// Progress updates can be turned off, but frequent ones would flood the Discord channel
+	if c.ProgressInterval != 0 {
+		if err := validateRange("PROGRESS_INTERVAL", c.ProgressInterval, 30, 86400, "Progress interval"); err != nil {
+			return err
+		}
+	}
+
+	if c.QueueMetricsInterval != 0 {
+		if err := validateRange("QUEUE_METRICS_INTERVAL", c.QueueMetricsInterval, 5, 3600, "Queue metrics interval"); err != nil {
+			return err
+		}
+	}
+	if err := validateRange("AUTOSCALE_TARGET_DRAIN", c.AutoscaleTargetDrain, 60, 86400, "Autoscale target drain time"); err != nil {
+		return err
+	}
+	if err := validateRange("AUTOSCALE_DEFAULT_TASK_DURATION", c.AutoscaleDefaultDuration, 1, 86400, "Autoscale default task duration"); err != nil {
+		return err
+	}
+	if c.AutoscaleMaxReplicas < 1 || c.AutoscaleMaxReplicas > 1000 {
+		return &ConfigError{
+			Field:   "AUTOSCALE_MAX_REPLICAS",
+			Message: "Autoscale maximum replicas must be between 1 and 1000",
+		}
+	}
+	if c.AutoscaleMinReplicas < 1 || c.AutoscaleMinReplicas > c.AutoscaleMaxReplicas {
+		return &ConfigError{
+			Field:   "AUTOSCALE_MIN_REPLICAS",
+			Message: fmt.Sprintf("Autoscale minimum replicas must be between 1 and AUTOSCALE_MAX_REPLICAS (%d), as the workers serve the hint themselves", c.AutoscaleMaxReplicas),
+		}
+	}
+	if c.AutoscalePeekMessages < 0 || c.AutoscalePeekMessages > 1000 {
+		return &ConfigError{
+			Field:   "AUTOSCALE_PEEK_MESSAGES",
+			Message: "Autoscale peeked messages must be between 0 and 1000",
+		}
+	}
+
 	if err := validateLogLevel(c.LogLevel); err != nil {
 		return err
 	}
 
+	if err := validateSeverity("FINDING_ALERT_MIN_SEVERITY", c.FindingAlertMinSeverity); err != nil {
+		return err
+	}
+
+	if !slices.Contains(notification.PayloadDetails, c.NotificationDetail) {
+		return &ConfigError{
+			Field:   "NOTIFICATION_DETAIL",
+			Message: fmt.Sprintf("Invalid detail level '%s'. Valid levels are: %s", c.NotificationDetail, strings.Join(notification.PayloadDetails, ", ")),
+		}
+	}
+
+	if _, err := notification.ParseLocale(c.NotificationLocale, ""); err != nil {
+		return &ConfigError{
+			Field:   "NOTIFICATION_LOCALE",
+			Message: err.Error(),
+		}
+	}
+	if _, err := notification.ParseLocale("", c.NotificationTimezone); err != nil {
+		return &ConfigError{
+			Field:   "NOTIFICATION_TIMEZONE",
+			Message: err.Error(),
+		}
+	}
+
+	if tasks, err := validation.NewValidator().ParseTaskTypes(c.ScanDigestAfter); err != nil || len(tasks) > 1 {
+		return &ConfigError{
+			Field:   "SCAN_DIGEST_AFTER",
+			Message: "Scan digest task must be empty or a single task type",
+		}
+	}
+
+	if _, err := schedule.ParseWindows(c.ScanWindows); err != nil {
+		return &ConfigError{
+			Field:   "SCAN_WINDOWS",
+			Message: err.Error(),
+		}
+	}
+
+	if _, err := quota.ParseLimits(c.PassiveSourceQuotas); err != nil {
+		return &ConfigError{
+			Field:   "PASSIVE_SOURCE_QUOTAS",
+			Message: err.Error(),
+		}
+	}
+
+	if c.ScannerCapacity < 1 || c.ScannerCapacity > 64 {
+		return &ConfigError{
+			Field:   "SCANNER_CAPACITY",
+			Message: "Scanner capacity must be between 1 and 64 units",
+		}
+	}
+	if _, err := capacity.ParseWeights(c.ScannerWeights); err != nil {
+		return &ConfigError{
+			Field:   "SCANNER_WEIGHTS",
+			Message: err.Error(),
+		}
+	}
+	if _, err := models.ParseTLSFingerprints(c.HttpxTLSFingerprints); err != nil {
+		return &ConfigError{
+			Field:   "HTTPX_TLS_FINGERPRINT",
+			Message: err.Error(),
+		}
+	}
+	if _, err := redaction.ParsePolicies(c.RedactionPolicy); err != nil {
+		return &ConfigError{
+			Field:   "REDACTION_POLICY",
+			Message: err.Error(),
+		}
+	}
+	if err := validateRange("REVIEW_SAMPLE_RATE", c.ReviewSampleRate, 0, 100, "Review sample rate"); err != nil {
+		return err
+	}
+	if err := validateRange("REVIEW_SAMPLE_MAX", c.ReviewSampleMax, 1, 1000, "Review sample limit"); err != nil {
+		return err
+	}
+	if err := validateRange("RETRY_BUDGET", c.RetryBudget, 0, 1000, "Retry budget"); err != nil {
+		return err
+	}
+	if err := validateRange("QUALITY_GATE_MIN_ALIVE_PERCENT", c.QualityGateMinAlivePercent, 0, 100, "Minimum alive percentage"); err != nil {
+		return err
+	}
+	if err := validateRange("QUALITY_GATE_MAX_DNS_ERROR_PERCENT", c.QualityGateMaxDNSErrorPercent, 0, 100, "Maximum DNS error percentage"); err != nil {
+		return err
+	}
+	if err := validateRange("QUALITY_GATE_MIN_SAMPLE", c.QualityGateMinSample, 1, 100000, "Quality gate minimum sample"); err != nil {
+		return err
+	}
+	if err := validateRange("QUALITY_GATE_MAX_HOLD_HOURS", c.QualityGateMaxHoldHours, 1, 720, "Quality gate maximum hold"); err != nil {
+		return err
+	}
+	if err := validateRange("PAUSE_MAX_HOLD_HOURS", c.PauseMaxHoldHours, 1, 2160, "Paused task maximum hold"); err != nil {
+		return err
+	}
+	if _, err := validation.NewValidator().ParseTaskTypes(c.QualityGateHoldTasks); err != nil {
+		return &ConfigError{
+			Field:   "QUALITY_GATE_HOLD_TASKS",
+			Message: err.Error(),
+		}
+	}
+	if err := validateRange("BLOCK_DETECTION_WINDOW", c.BlockDetectionWindow, 0, 1000, "Block detection window"); err != nil {
+		return err
+	}
+	if c.BlockDetectionAction != "backoff" && c.BlockDetectionAction != "abort" {
+		return &ConfigError{
+			Field:   "BLOCK_DETECTION_ACTION",
+			Message: "Block detection action must be backoff or abort",
+		}
+	}
+	if err := validateRange("BLOCK_BACKOFF", c.BlockBackoff, 1, 60, "Block backoff"); err != nil {
+		return err
+	}
+	if err := validateRange("BLOCK_RETRY_AFTER", c.BlockRetryAfter, 0, 1440, "Block retry delay"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(c.ComplianceUserAgent) == "" || strings.ContainsAny(c.ComplianceUserAgent, "\r\n") {
+		return &ConfigError{
+			Field:   "COMPLIANCE_USER_AGENT",
+			Message: "Compliance user agent must be a single non-empty line",
+		}
+	}
+	if c.VersionManifestURL != "" {
+		if !strings.Contains(c.VersionManifestURL, "://") || !isValidServerURL(c.VersionManifestURL) {
+			return &ConfigError{
+				Field:   "VERSION_MANIFEST_URL",
+				Message: "Version manifest URL must be an http or https URL",
+			}
+		}
+		if err := validateRange("VERSION_CHECK_INTERVAL", c.VersionCheckInterval, 60, 86400, "Version check interval"); err != nil {
+			return err
+		}
+	}
+	if err := validateRange("MULTI_DOMAIN_PARALLELISM", c.MultiDomainParallelism, 1, 64, "Multi-domain parallelism"); err != nil {
+		return err
+	}
+	if c.DiskBudgetMB < 0 || c.DiskBudgetMB > 1<<20 {
+		return &ConfigError{
+			Field:   "DISK_BUDGET_MB",
+			Message: "Disk budget must be between 0 and 1048576 MB",
+		}
+	}
+	if c.DiskMinFreeMB < 0 || c.DiskMinFreeMB > 1<<20 {
+		return &ConfigError{
+			Field:   "DISK_MIN_FREE_MB",
+			Message: "Minimum free disk must be between 0 and 1048576 MB",
+		}
+	}
+	if c.DiskSweepInterval != 0 {
+		if err := validateRange("DISK_SWEEP_INTERVAL", c.DiskSweepInterval, 10, 86400, "Disk sweep interval"); err != nil {
+			return err
+		}
+	}
+	if _, err := models.ConfiguredScanProfiles(c.ScanProfiles); err != nil {
+		return &ConfigError{
+			Field:   "SCAN_PROFILES",
+			Message: err.Error(),
+		}
+	}
+
+	if _, err := validation.NewValidator().ParseTaskTypes(c.EnabledTasks); err != nil {
+		return &ConfigError{
+			Field:   "ENABLED_TASKS",
+			Message: err.Error(),
+		}
+	}
+	if _, err := validation.NewValidator().ParseCapabilities(c.WorkerCapabilities); err != nil {
+		return &ConfigError{
+			Field:   "WORKER_CAPABILITIES",
+			Message: err.Error(),
+		}
+	}
+	if c.DisabledTaskAction != "abandon" && c.DisabledTaskAction != "dead_letter" {
+		return &ConfigError{
+			Field:   "DISABLED_TASK_ACTION",
+			Message: "Disabled task action must be abandon or dead_letter",
+		}
+	}
+
+	auth, err := api.ParseTokens(c.APITokens)
+	if err != nil {
+		return &ConfigError{
+			Field:   "API_TOKENS",
+			Message: err.Error(),
+		}
+	}
+	if c.APIAddr != "" && auth.Len() == 0 && c.StatusTokenSecret == "" && c.WebhookSecret == "" {
+		return &ConfigError{
+			Field:   "API_TOKENS",
+			Message: "at least one token, STATUS_TOKEN_SECRET or WEBHOOK_SECRET is required when API_ADDR is set",
+		}
+	}
+
+	// Status tokens are only as strong as the secret they are signed with
+	if c.StatusTokenSecret != "" && len(c.StatusTokenSecret) < 32 {
+		return &ConfigError{
+			Field:   "STATUS_TOKEN_SECRET",
+			Message: "Status token secret must be at least 32 characters",
+		}
+	}
+	if c.WebhookSecret != "" && len(c.WebhookSecret) < 32 {
+		return &ConfigError{
+			Field:   "WEBHOOK_SECRET",
+			Message: "Webhook secret must be at least 32 characters",
+		}
+	}
+	if c.StatusRateLimit < 1 || c.StatusRateLimit > 600 {
+		return &ConfigError{
+			Field:   "STATUS_RATE_LIMIT",
+			Message: "Status rate limit must be between 1 and 600 requests per minute",
+		}
+	}
+	if c.ExportJobConcurrency < 1 || c.ExportJobConcurrency > 16 {
+		return &ConfigError{
+			Field:   "EXPORT_JOB_CONCURRENCY",
+			Message: "Export job concurrency must be between 1 and 16",
+		}
+	}
+	// Download links are signed with the account key and cannot be revoked, so they stay short-lived
+	if c.ExportJobLinkTTL < 5 || c.ExportJobLinkTTL > 1440 {
+		return &ConfigError{
+			Field:   "EXPORT_JOB_LINK_TTL",
+			Message: "Export job link TTL must be between 5 and 1440 minutes",
+		}
+	}
+
 	return nil
 }
 
@@ -106,6 +614,21 @@ func validateRange(field string, value, min, max int, fieldName string) error {
 	return nil
 }
 
+// validateSeverity validates a nuclei severity
+func validateSeverity(field, severity string) error {
+	validSeverities := []string{"info", "low", "medium", "high", "critical"}
+	for _, valid := range validSeverities {
+		if strings.ToLower(severity) == valid {
+			return nil
+		}
+	}
+
+	return &ConfigError{
+		Field:   field,
+		Message: fmt.Sprintf("Invalid severity '%s'. Valid severities are: %s", severity, strings.Join(validSeverities, ", ")),
+	}
+}
+
 // validateLogLevel validates that the log level is valid
 func validateLogLevel(logLevel string) error {
 	validLevels := []string{"debug", "info", "warning", "warn", "error", "fatal"}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/validation"
+)
+
+// serviceNowNamePattern matches ServiceNow table and field names
+var serviceNowNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// ConnectorConfig holds the settings of the asset sources the scan scope is imported from.
+// Nothing is imported unless SCOPE_SYNC_INTERVAL is set and at least one source is configured.
+// cloud_dns tasks read Route 53 and Google Cloud DNS zones with the same read-only credentials.
+type ConnectorConfig struct {
+	SyncInterval int    // seconds - how often the scope is imported; 0 disables it
+	TenantID     string // Tenant the imported scope and queued scans belong to
+	Timeout      int    // seconds - timeout for source API requests
+	// CSV blob with one asset per line - disabled unless SCOPE_SYNC_CSV_BLOB is set
+	CSVBlob string
+	// ServiceNow CMDB - disabled unless SERVICENOW_INSTANCE_URL is set
+	ServiceNowInstanceURL string
+	ServiceNowUsername    string
+	ServiceNowPassword    string
+	ServiceNowTable       string
+	ServiceNowField       string
+	// Cloudflare zones - disabled unless CLOUDFLARE_API_TOKEN is set
+	CloudflareAPIToken string
+	// AWS Route 53 hosted zones - disabled unless SCOPE_SYNC_ROUTE53 is set
+	Route53            bool
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+	// Google Cloud service account key file cloud_dns tasks read Cloud DNS zones with
+	GoogleCredentialsFile string
+	// Cloud DNS accounts each tenant's cloud_dns tasks may read, as "tenant=account|account,..."
+	CloudDNSAccounts string
+}
+
+// LoadConnectorConfig loads asset source configuration from environment variables
+func LoadConnectorConfig() ConnectorConfig {
+	return ConnectorConfig{
+		SyncInterval:          getEnvAsInt("SCOPE_SYNC_INTERVAL", 0),
+		TenantID:              getEnv("SCOPE_SYNC_TENANT_ID", ""),
+		Timeout:               getEnvAsInt("SCOPE_SYNC_TIMEOUT", 30),
+		CSVBlob:               getEnv("SCOPE_SYNC_CSV_BLOB", ""),
+		ServiceNowInstanceURL: getEnv("SERVICENOW_INSTANCE_URL", ""),
+		ServiceNowUsername:    getEnv("SERVICENOW_USERNAME", ""),
+		ServiceNowPassword:    getEnv("SERVICENOW_PASSWORD", ""),
+		ServiceNowTable:       getEnv("SERVICENOW_TABLE", "cmdb_ci_dns_name"),
+		ServiceNowField:       getEnv("SERVICENOW_FIELD", "name"),
+		CloudflareAPIToken:    getEnv("CLOUDFLARE_API_TOKEN", ""),
+		Route53:               getEnvAsBool("SCOPE_SYNC_ROUTE53", false),
+		AWSAccessKeyID:        getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey:    getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:       getEnv("AWS_SESSION_TOKEN", ""),
+		GoogleCredentialsFile: getEnv("GOOGLE_APPLICATION_CREDENTIALS", ""),
+		CloudDNSAccounts:      getEnv("CLOUD_DNS_ACCOUNTS", ""),
+	}
+}
+
+// SyncEnabled reports whether the scope is imported from at least one source
+func (c *ConnectorConfig) SyncEnabled() bool {
+	return c.SyncInterval > 0 && (c.CSVBlob != "" || c.ServiceNowInstanceURL != "" || c.CloudflareAPIToken != "" || c.Route53)
+}
+
+// ValidateConnectorConfig validates asset source configuration
+func (c *ConnectorConfig) ValidateConnectorConfig() error {
+	// Sources are queried on every sync, so a short interval would hammer their APIs
+	if c.SyncInterval != 0 {
+		if err := validateRange("SCOPE_SYNC_INTERVAL", c.SyncInterval, 300, 604800, "Scope sync interval"); err != nil {
+			return err
+		}
+	}
+	if err := validateRange("SCOPE_SYNC_TIMEOUT", c.Timeout, 1, 300, "Scope sync timeout"); err != nil {
+		return err
+	}
+	if c.TenantID != "" {
+		if err := validation.NewValidator().ValidateTenantID(c.TenantID); err != nil {
+			return &ConfigError{
+				Field:   "SCOPE_SYNC_TENANT_ID",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	if err := c.validateServiceNow(); err != nil {
+		return err
+	}
+
+	if c.Route53 && (c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+		return &ConfigError{
+			Field:   "AWS_ACCESS_KEY_ID",
+			Message: "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when SCOPE_SYNC_ROUTE53 is set",
+		}
+	}
+	if c.GoogleCredentialsFile != "" {
+		if _, err := os.Stat(c.GoogleCredentialsFile); err != nil {
+			return &ConfigError{
+				Field:   "GOOGLE_APPLICATION_CREDENTIALS",
+				Message: fmt.Sprintf("cannot read the service account key file: %v", err),
+			}
+		}
+	}
+	if _, err := models.ParseCloudDNSAccounts(c.CloudDNSAccounts); err != nil {
+		return &ConfigError{
+			Field:   "CLOUD_DNS_ACCOUNTS",
+			Message: err.Error(),
+		}
+	}
+	return nil
+}
+
+// validateServiceNow validates the ServiceNow CMDB settings
+func (c *ConnectorConfig) validateServiceNow() error {
+	if c.ServiceNowInstanceURL == "" {
+		return nil
+	}
+	if !strings.HasPrefix(c.ServiceNowInstanceURL, "https://") || !isValidServerURL(c.ServiceNowInstanceURL) {
+		return &ConfigError{
+			Field:   "SERVICENOW_INSTANCE_URL",
+			Message: "SERVICENOW_INSTANCE_URL must be the https URL of the instance",
+		}
+	}
+	if c.ServiceNowUsername == "" || c.ServiceNowPassword == "" {
+		return &ConfigError{
+			Field:   "SERVICENOW_USERNAME",
+			Message: "SERVICENOW_USERNAME and SERVICENOW_PASSWORD are required when SERVICENOW_INSTANCE_URL is set",
+		}
+	}
+	if !serviceNowNamePattern.MatchString(c.ServiceNowTable) || !serviceNowNamePattern.MatchString(c.ServiceNowField) {
+		return &ConfigError{
+			Field:   "SERVICENOW_TABLE",
+			Message: "SERVICENOW_TABLE and SERVICENOW_FIELD must be lowercase letters, digits or '_'",
+		}
+	}
+	return nil
+}
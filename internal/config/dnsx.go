@@ -0,0 +1,94 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/validation"
+)
+
+// DNSXConfig holds the default DNS settings of the DNSX scanner
+type DNSXConfig struct {
+	Retries       int    // attempts per DNS question
+	TimeoutMs     int    // milliseconds - per-attempt timeout
+	QuestionTypes string // comma separated record types
+	Hostsfile     bool
+	RetryPass     bool // re-query failed names with alternate resolvers
+	MaxWorkers    int  // ceiling for the adaptive worker count
+	MaxRateLimit  int  // queries per second - ceiling for the adaptive rate limit
+	// Runs with more names than this stream their records to blob storage; 0 never streams
+	StreamThreshold int
+}
+
+// LoadDNSXConfig loads DNSX configuration from environment variables
+func LoadDNSXConfig() DNSXConfig {
+	questionTypes := getEnv("DNSX_QUESTION_TYPES", "A,CNAME")
+	// The hosts file only provides addresses, so it is on by default only when they are queried
+	queriesAddresses := models.DNSSettings{QuestionTypes: splitQuestionTypes(questionTypes)}.QueriesAddresses()
+	return DNSXConfig{
+		Retries:         getEnvAsInt("DNSX_RETRIES", 1),
+		TimeoutMs:       getEnvAsInt("DNSX_TIMEOUT_MS", 3000), // 3 seconds
+		QuestionTypes:   questionTypes,
+		Hostsfile:       getEnvAsBool("DNSX_HOSTSFILE", queriesAddresses),
+		RetryPass:       getEnvAsBool("DNSX_RETRY_PASS", true),
+		MaxWorkers:      getEnvAsInt("DNSX_MAX_WORKERS", 200),
+		MaxRateLimit:    getEnvAsInt("DNSX_MAX_RATE_LIMIT", 2000),
+		StreamThreshold: getEnvAsInt("DNSX_STREAM_THRESHOLD", 100000),
+	}
+}
+
+// Settings converts the configuration into DNS settings
+func (c *DNSXConfig) Settings() models.DNSSettings {
+	hostsfile, retryPass := c.Hostsfile, c.RetryPass
+	return models.DNSSettings{
+		Retries:       c.Retries,
+		TimeoutMs:     c.TimeoutMs,
+		QuestionTypes: splitQuestionTypes(c.QuestionTypes),
+		Hostsfile:     &hostsfile,
+		RetryPass:     &retryPass,
+	}
+}
+
+// splitQuestionTypes parses a comma separated list of record types
+func splitQuestionTypes(list string) []string {
+	var questionTypes []string
+	for _, questionType := range strings.Split(list, ",") {
+		if questionType = strings.ToUpper(strings.TrimSpace(questionType)); questionType != "" {
+			questionTypes = append(questionTypes, questionType)
+		}
+	}
+	return questionTypes
+}
+
+// ValidateDNSXConfig validates DNSX configuration
+func (c *DNSXConfig) ValidateDNSXConfig() error {
+	if err := validation.NewValidator().ValidateDNSSettings(c.Settings()); err != nil {
+		return &ConfigError{
+			Field:   "DNSX",
+			Message: "invalid DNSX configuration: " + err.Error(),
+		}
+	}
+
+	if c.MaxWorkers <= 0 || c.MaxWorkers > 5000 {
+		return &ConfigError{
+			Field:   "DNSX_MAX_WORKERS",
+			Message: "DNSX max workers must be between 1 and 5000",
+		}
+	}
+
+	if c.MaxRateLimit <= 0 || c.MaxRateLimit > 100000 {
+		return &ConfigError{
+			Field:   "DNSX_MAX_RATE_LIMIT",
+			Message: "DNSX max rate limit must be between 1 and 100000 queries per second",
+		}
+	}
+
+	if c.StreamThreshold < 0 {
+		return &ConfigError{
+			Field:   "DNSX_STREAM_THRESHOLD",
+			Message: "DNSX stream threshold must be 0 or a positive number of names",
+		}
+	}
+
+	return nil
+}
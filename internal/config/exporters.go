@@ -0,0 +1,147 @@
+package config
+
+import (
+	"regexp"
+	"strings"
+)
+
+// indexPrefixPattern matches index name prefixes accepted by Elasticsearch and OpenSearch
+var indexPrefixPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// ExportConfig holds the settings of the optional result exporters
+type ExportConfig struct {
+	Timeout int // seconds - timeout for export requests
+	// Elasticsearch / OpenSearch - disabled unless ELASTICSEARCH_URL is set
+	ElasticsearchURL           string
+	ElasticsearchAPIKey        string
+	ElasticsearchUsername      string
+	ElasticsearchPassword      string
+	ElasticsearchIndexPrefix   string
+	ElasticsearchKeywordSuffix string // Suffix of the keyword sub-fields the search API matches on
+	// Log Analytics / Sentinel - disabled unless LOG_ANALYTICS_ENDPOINT is set
+	LogAnalyticsEndpoint string
+	LogAnalyticsRuleID   string
+	LogAnalyticsStream   string
+	// Splunk HTTP Event Collector - disabled unless SPLUNK_HEC_URL is set
+	SplunkHECURL        string
+	SplunkHECToken      string
+	SplunkIndex         string
+	SplunkSourcetype    string
+	SplunkBatchSize     int
+	SplunkFlushInterval int // seconds
+}
+
+// LoadExportConfig loads result exporter configuration from environment variables
+func LoadExportConfig() ExportConfig {
+	return ExportConfig{
+		Timeout:                    getEnvAsInt("EXPORT_TIMEOUT", 30),
+		ElasticsearchURL:           getEnv("ELASTICSEARCH_URL", ""),
+		ElasticsearchAPIKey:        getEnv("ELASTICSEARCH_API_KEY", ""),
+		ElasticsearchUsername:      getEnv("ELASTICSEARCH_USERNAME", ""),
+		ElasticsearchPassword:      getEnv("ELASTICSEARCH_PASSWORD", ""),
+		ElasticsearchIndexPrefix:   getEnv("ELASTICSEARCH_INDEX_PREFIX", "asm"),
+		ElasticsearchKeywordSuffix: getEnv("ELASTICSEARCH_KEYWORD_SUFFIX", ".keyword"),
+		LogAnalyticsEndpoint:       getEnv("LOG_ANALYTICS_ENDPOINT", ""),
+		LogAnalyticsRuleID:         getEnv("LOG_ANALYTICS_DCR_ID", ""),
+		LogAnalyticsStream:         getEnv("LOG_ANALYTICS_STREAM", "Custom-ASMFindings_CL"),
+		SplunkHECURL:               getEnv("SPLUNK_HEC_URL", ""),
+		SplunkHECToken:             getEnv("SPLUNK_HEC_TOKEN", ""),
+		SplunkIndex:                getEnv("SPLUNK_INDEX", ""),
+		SplunkSourcetype:           getEnv("SPLUNK_SOURCETYPE", "allsafe:asm"),
+		SplunkBatchSize:            getEnvAsInt("SPLUNK_BATCH_SIZE", 100),
+		SplunkFlushInterval:        getEnvAsInt("SPLUNK_FLUSH_INTERVAL", 5),
+	}
+}
+
+// ValidateExportConfig validates result exporter configuration
+func (c *ExportConfig) ValidateExportConfig() error {
+	if err := validateRange("EXPORT_TIMEOUT", c.Timeout, 1, 300, "Export timeout"); err != nil {
+		return err
+	}
+
+	if err := c.validateElasticsearch(); err != nil {
+		return err
+	}
+
+	if err := c.validateLogAnalytics(); err != nil {
+		return err
+	}
+
+	return c.validateSplunk()
+}
+
+// validateElasticsearch validates the Elasticsearch exporter settings
+func (c *ExportConfig) validateElasticsearch() error {
+	if c.ElasticsearchURL == "" {
+		return nil
+	}
+	if !strings.Contains(c.ElasticsearchURL, "://") || !isValidServerURL(c.ElasticsearchURL) {
+		return &ConfigError{
+			Field:   "ELASTICSEARCH_URL",
+			Message: "ELASTICSEARCH_URL must be an http(s) URL",
+		}
+	}
+	if c.ElasticsearchAPIKey != "" && c.ElasticsearchUsername != "" {
+		return &ConfigError{
+			Field:   "ELASTICSEARCH_API_KEY",
+			Message: "set either ELASTICSEARCH_API_KEY or ELASTICSEARCH_USERNAME, not both",
+		}
+	}
+	if !indexPrefixPattern.MatchString(c.ElasticsearchIndexPrefix) {
+		return &ConfigError{
+			Field:   "ELASTICSEARCH_INDEX_PREFIX",
+			Message: "ELASTICSEARCH_INDEX_PREFIX must be lowercase letters, digits, '_', '-' or '.'",
+		}
+	}
+
+	return nil
+}
+
+// validateLogAnalytics validates the Log Analytics exporter settings
+func (c *ExportConfig) validateLogAnalytics() error {
+	if c.LogAnalyticsEndpoint == "" {
+		return nil
+	}
+	if !strings.HasPrefix(c.LogAnalyticsEndpoint, "https://") || !isValidServerURL(c.LogAnalyticsEndpoint) {
+		return &ConfigError{
+			Field:   "LOG_ANALYTICS_ENDPOINT",
+			Message: "LOG_ANALYTICS_ENDPOINT must be the https URL of a data collection endpoint",
+		}
+	}
+	if c.LogAnalyticsRuleID == "" {
+		return &ConfigError{
+			Field:   "LOG_ANALYTICS_DCR_ID",
+			Message: "LOG_ANALYTICS_DCR_ID is required when LOG_ANALYTICS_ENDPOINT is set",
+		}
+	}
+	if !strings.HasPrefix(c.LogAnalyticsStream, "Custom-") {
+		return &ConfigError{
+			Field:   "LOG_ANALYTICS_STREAM",
+			Message: "LOG_ANALYTICS_STREAM must name a custom stream (Custom-<name>)",
+		}
+	}
+	return nil
+}
+
+// validateSplunk validates the Splunk exporter settings
+func (c *ExportConfig) validateSplunk() error {
+	if c.SplunkHECURL == "" {
+		return nil
+	}
+	if !strings.Contains(c.SplunkHECURL, "://") || !isValidServerURL(c.SplunkHECURL) {
+		return &ConfigError{
+			Field:   "SPLUNK_HEC_URL",
+			Message: "SPLUNK_HEC_URL must be an http(s) URL",
+		}
+	}
+	if c.SplunkHECToken == "" {
+		return &ConfigError{
+			Field:   "SPLUNK_HEC_TOKEN",
+			Message: "SPLUNK_HEC_TOKEN is required when SPLUNK_HEC_URL is set",
+		}
+	}
+	if err := validateRange("SPLUNK_BATCH_SIZE", c.SplunkBatchSize, 1, 10000, "Splunk batch size"); err != nil {
+		return err
+	}
+	return validateRange("SPLUNK_FLUSH_INTERVAL", c.SplunkFlushInterval, 1, 300, "Splunk flush interval")
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/allsafeASM/api/internal/faults"
+)
+
+// FaultConfig holds the fault injection settings used to exercise retries and dead-lettering in
+// staging. Nothing is injected unless FAULT_INJECTION is set, which is only accepted by builds
+// with the faults tag.
+type FaultConfig struct {
+	Enabled                   bool
+	BlobDelayPercent          int // percent of blob operations that are delayed
+	BlobDelayMaxMs            int // milliseconds - longest injected blob delay
+	LockRenewalFailurePercent int // percent of lock renewals that fail
+	NotificationDropPercent   int // percent of orchestrator and Discord calls that are dropped
+}
+
+// LoadFaultConfig loads fault injection configuration from environment variables
+func LoadFaultConfig() FaultConfig {
+	return FaultConfig{
+		Enabled:                   getEnvAsBool("FAULT_INJECTION", false),
+		BlobDelayPercent:          getEnvAsInt("FAULT_BLOB_DELAY_PERCENT", 0),
+		BlobDelayMaxMs:            getEnvAsInt("FAULT_BLOB_DELAY_MAX_MS", 5000), // 5 seconds
+		LockRenewalFailurePercent: getEnvAsInt("FAULT_LOCK_RENEWAL_FAILURE_PERCENT", 0),
+		NotificationDropPercent:   getEnvAsInt("FAULT_NOTIFICATION_DROP_PERCENT", 0),
+	}
+}
+
+// Settings converts the configuration into fault injector settings
+func (c *FaultConfig) Settings() faults.Config {
+	if !c.Enabled {
+		return faults.Config{}
+	}
+	return faults.Config{
+		BlobDelayPercent:          c.BlobDelayPercent,
+		BlobDelayMax:              time.Duration(c.BlobDelayMaxMs) * time.Millisecond,
+		LockRenewalFailurePercent: c.LockRenewalFailurePercent,
+		NotificationDropPercent:   c.NotificationDropPercent,
+	}
+}
+
+// ValidateFaultConfig validates fault injection configuration
+func (c *FaultConfig) ValidateFaultConfig() error {
+	if !c.Enabled {
+		return nil
+	}
+	if !faults.Enabled {
+		return &ConfigError{
+			Field:   "FAULT_INJECTION",
+			Message: "Fault injection requires a build with the faults tag (go build -tags faults)",
+		}
+	}
+
+	percentages := []struct {
+		field string
+		value int
+	}{
+		{"FAULT_BLOB_DELAY_PERCENT", c.BlobDelayPercent},
+		{"FAULT_LOCK_RENEWAL_FAILURE_PERCENT", c.LockRenewalFailurePercent},
+		{"FAULT_NOTIFICATION_DROP_PERCENT", c.NotificationDropPercent},
+	}
+	for _, p := range percentages {
+		if p.value < 0 || p.value > 100 {
+			return &ConfigError{
+				Field:   p.field,
+				Message: fmt.Sprintf("%s must be a percentage between 0 and 100", p.field),
+			}
+		}
+	}
+
+	if c.BlobDelayMaxMs < 1 || c.BlobDelayMaxMs > 600000 {
+		return &ConfigError{
+			Field:   "FAULT_BLOB_DELAY_MAX_MS",
+			Message: "Maximum blob delay must be between 1 and 600000 milliseconds",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// NucleiConfig holds the defaults of the nuclei scanner
+type NucleiConfig struct {
+	TemplateCacheDir string // local directory tenant custom templates are synced to
+	InteractshServer string // interactsh server for OOB templates - empty uses nuclei's public servers
+	InteractshToken  string // authorization token of the interactsh server
+	// InteractshAllowedServers are the host names tasks may choose as their interactsh server
+	InteractshAllowedServers []string
+	DisableInteractsh        bool   // disable OOB interactions for every scan
+	HostBudget               int    // seconds a scan spends on one target; 0 disables the budget
+	ScanBudget               int    // seconds a scan runs before returning its findings so far; 0 disables the budget
+	ReplayMinSeverity        string // lowest severity of findings replayed before they are reported; "none" disables replays
+	ReplayDelay              int    // least seconds between finding and replaying a finding
+	TemplatePolicy           string // JSON template policy of tenants without a stored one - empty runs every template
+	Checkpoints              bool   // checkpoint every scan's resume state while it runs; tasks can turn it on themselves
+}
+
+// LoadNucleiConfig loads nuclei configuration from environment variables
+func LoadNucleiConfig() NucleiConfig {
+	return NucleiConfig{
+		TemplateCacheDir:         getEnv("NUCLEI_TEMPLATE_CACHE_DIR", "/tmp/nuclei-custom-templates"),
+		InteractshServer:         getEnv("NUCLEI_INTERACTSH_SERVER", ""),
+		InteractshToken:          getEnv("NUCLEI_INTERACTSH_TOKEN", ""),
+		InteractshAllowedServers: parseServerList(getEnv("NUCLEI_INTERACTSH_ALLOWED_SERVERS", "")),
+		DisableInteractsh:        getEnvAsBool("NUCLEI_DISABLE_INTERACTSH", false),
+		HostBudget:               getEnvAsInt("NUCLEI_HOST_BUDGET", 0),
+		ScanBudget:               getEnvAsInt("NUCLEI_SCAN_BUDGET", 0),
+		ReplayMinSeverity:        getEnv("NUCLEI_REPLAY_MIN_SEVERITY", "high"),
+		ReplayDelay:              getEnvAsInt("NUCLEI_REPLAY_DELAY", 30),
+		TemplatePolicy:           getEnv("NUCLEI_TEMPLATE_POLICY", ""),
+		Checkpoints:              getEnvAsBool("NUCLEI_CHECKPOINTS", false),
+	}
+}
+
+// ValidateNucleiConfig validates nuclei configuration
+func (c *NucleiConfig) ValidateNucleiConfig() error {
+	if c.InteractshServer != "" && !isValidServerURL(c.InteractshServer) {
+		return &ConfigError{
+			Field:   "NUCLEI_INTERACTSH_SERVER",
+			Message: "NUCLEI_INTERACTSH_SERVER must be a host name or an http(s) URL",
+		}
+	}
+
+	if c.InteractshToken != "" && c.InteractshServer == "" {
+		return &ConfigError{
+			Field:   "NUCLEI_INTERACTSH_TOKEN",
+			Message: "NUCLEI_INTERACTSH_TOKEN requires NUCLEI_INTERACTSH_SERVER so the token is not sent to public servers",
+		}
+	}
+
+	for _, server := range c.InteractshAllowedServers {
+		if !isValidServerURL(server) {
+			return &ConfigError{
+				Field:   "NUCLEI_INTERACTSH_ALLOWED_SERVERS",
+				Message: "NUCLEI_INTERACTSH_ALLOWED_SERVERS must be a comma-separated list of host names or http(s) URLs",
+			}
+		}
+	}
+
+	if c.HostBudget < 0 {
+		return &ConfigError{
+			Field:   "NUCLEI_HOST_BUDGET",
+			Message: "NUCLEI_HOST_BUDGET must not be negative",
+		}
+	}
+
+	if c.ScanBudget < 0 {
+		return &ConfigError{
+			Field:   "NUCLEI_SCAN_BUDGET",
+			Message: "NUCLEI_SCAN_BUDGET must not be negative",
+		}
+	}
+
+	if c.ReplayMinSeverity != "none" {
+		if err := validateSeverity("NUCLEI_REPLAY_MIN_SEVERITY", c.ReplayMinSeverity); err != nil {
+			return err
+		}
+	}
+
+	if err := validateRange("NUCLEI_REPLAY_DELAY", c.ReplayDelay, 0, 3600, "Nuclei replay delay"); err != nil {
+		return err
+	}
+
+	if c.TemplatePolicy != "" {
+		if _, err := models.ParseTemplatePolicy([]byte(c.TemplatePolicy)); err != nil {
+			return &ConfigError{
+				Field:   "NUCLEI_TEMPLATE_POLICY",
+				Message: err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// isValidServerURL reports whether a value is a host name or an http(s) URL with a host
+func isValidServerURL(value string) bool {
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Hostname() != ""
+}
+
+// parseServerList splits a comma-separated list of servers, dropping empty entries
+func parseServerList(value string) []string {
+	var servers []string
+	for _, server := range strings.Split(value, ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
@@ -0,0 +1,68 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// DefaultCloudflareURL is the base URL of the Cloudflare API
+const DefaultCloudflareURL = "https://api.cloudflare.com/client/v4"
+
+// CloudflareConnector imports the zones an API token can read from Cloudflare
+type CloudflareConnector struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewCloudflareConnector creates a connector for the zones of an API token
+func NewCloudflareConnector(token string, timeout time.Duration) *CloudflareConnector {
+	return &CloudflareConnector{baseURL: DefaultCloudflareURL, token: token, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// Name returns the connector name
+func (c *CloudflareConnector) Name() string {
+	return "cloudflare"
+}
+
+// Fetch pages through the zone list and returns the zone names
+func (c *CloudflareConnector) Fetch(ctx context.Context) ([]models.ScopeEntry, error) {
+	var values []string
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/zones?per_page=50&page="+strconv.Itoa(page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Cloudflare request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+
+		var zones struct {
+			Success bool `json:"success"`
+			Result  []struct {
+				Name string `json:"name"`
+			} `json:"result"`
+			ResultInfo struct {
+				TotalPages int `json:"total_pages"`
+			} `json:"result_info"`
+		}
+		if err := doJSON(c.httpClient, req, &zones); err != nil {
+			return nil, fmt.Errorf("Cloudflare zones: %w", err)
+		}
+		if !zones.Success {
+			return nil, fmt.Errorf("Cloudflare zones: request was not successful")
+		}
+
+		for _, zone := range zones.Result {
+			values = append(values, zone.Name)
+		}
+		if page >= zones.ResultInfo.TotalPages {
+			break
+		}
+	}
+	return normalizeEntries(c.Name(), values), nil
+}
@@ -0,0 +1,185 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/validation"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Connector imports the assets an external source such as a CMDB or DNS provider holds
+type Connector interface {
+	Name() string
+	Fetch(ctx context.Context) ([]models.ScopeEntry, error)
+}
+
+// ScopeStore applies updates to the imported scan scope
+type ScopeStore interface {
+	UpdateScanScope(ctx context.Context, tenantID string, update func(*models.ScanScope) error) error
+}
+
+// TaskQueue accepts the tasks queued for newly imported assets
+type TaskQueue interface {
+	EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error
+}
+
+// NormalizeEntry turns an asset reported by a source into a scope entry: a lower-case domain of
+// at least two labels without the trailing dot, or a CIDR. Single IP addresses become /32 or
+// /128 ranges.
+func NormalizeEntry(value string) (models.ScopeEntry, error) {
+	value = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(value)), ".")
+
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return models.ScopeEntry{Value: network.String(), Type: models.ScopeAssetIPRange}, nil
+	}
+	if ip := net.ParseIP(value); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		network := &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		return models.ScopeEntry{Value: network.String(), Type: models.ScopeAssetIPRange}, nil
+	}
+
+	value = strings.TrimPrefix(value, "*.")
+	if err := validation.NewValidator().ValidateDomain(value); err != nil {
+		return models.ScopeEntry{}, err
+	}
+	if !strings.Contains(value, ".") {
+		return models.ScopeEntry{}, fmt.Errorf("%s is not a fully qualified domain", value)
+	}
+	return models.ScopeEntry{Value: value, Type: models.ScopeAssetDomain}, nil
+}
+
+// normalizeEntries normalizes the values reported by a source, skipping the invalid ones
+func normalizeEntries(source string, values []string) []models.ScopeEntry {
+	entries := make([]models.ScopeEntry, 0, len(values))
+	for _, value := range values {
+		entry, err := NormalizeEntry(value)
+		if err != nil {
+			gologger.Debug().Msgf("Skipping asset %q from %s: %v", value, source, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// scanClaimLease is how long a sync has to queue the scans it claimed before another sync
+// queues them instead
+const scanClaimLease = 15 * time.Minute
+
+// Syncer periodically imports the scope of a tenant from its connectors and queues a subfinder
+// task for every domain that enters the scope. IP ranges are recorded in the scope only.
+type Syncer struct {
+	connectors []Connector
+	store      ScopeStore
+	queue      TaskQueue
+	tenantID   string
+	interval   time.Duration
+}
+
+// NewSyncer creates a syncer that imports the scope of a tenant every interval
+func NewSyncer(store ScopeStore, queue TaskQueue, tenantID string, interval time.Duration, connectors ...Connector) *Syncer {
+	return &Syncer{
+		connectors: connectors,
+		store:      store,
+		queue:      queue,
+		tenantID:   tenantID,
+		interval:   interval,
+	}
+}
+
+// Run syncs the scope until ctx is done
+func (s *Syncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SyncOnce(ctx); err != nil && ctx.Err() == nil {
+			gologger.Warning().Msgf("Scope sync failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce imports the scope from every connector and queues tasks for the new domains. A
+// connector that fails to fetch keeps its assets in scope until its next successful sync.
+func (s *Syncer) SyncOnce(ctx context.Context) error {
+	var failed []string
+	for _, connector := range s.connectors {
+		entries, err := connector.Fetch(ctx)
+		if err != nil {
+			gologger.Warning().Msgf("Failed to import scope from %s: %v", connector.Name(), err)
+			failed = append(failed, connector.Name())
+			continue
+		}
+
+		var added, claimed []models.ScopeEntry
+		err = s.store.UpdateScanScope(ctx, s.tenantID, func(scope *models.ScanScope) error {
+			now := time.Now().UTC()
+			added = scope.Reconcile(connector.Name(), entries, now)
+			claimed = scope.ClaimScans(now, scanClaimLease)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update scan scope from %s: %w", connector.Name(), err)
+		}
+		gologger.Info().Msgf("Imported %d assets from %s, %d new", len(entries), connector.Name(), len(added))
+
+		// The scope records which scans were queued, so a failed enqueue is retried by the next
+		// sync and a scan queued once is not queued again
+		queued, queueErr := s.queueScans(ctx, connector.Name(), claimed)
+		if len(queued) > 0 {
+			err = s.store.UpdateScanScope(ctx, s.tenantID, func(scope *models.ScanScope) error {
+				scope.ScansQueued(queued)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to record the scans queued from %s: %w", connector.Name(), err)
+			}
+		}
+		if queueErr != nil {
+			return queueErr
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to import scope from %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// queueScans queues a subfinder task for each new domain and returns the domains it queued
+func (s *Syncer) queueScans(ctx context.Context, source string, added []models.ScopeEntry) ([]string, error) {
+	scanID := int(time.Now().Unix())
+	var queued []string
+	for _, entry := range added {
+		if entry.Type != models.ScopeAssetDomain {
+			continue
+		}
+		taskMsg := &models.TaskMessage{
+			Task:          models.TaskSubfinder,
+			ScanID:        scanID,
+			Domain:        entry.Value,
+			TenantID:      s.tenantID,
+			CorrelationID: models.NewCorrelationID(),
+		}
+		if err := s.queue.EnqueueTask(ctx, taskMsg); err != nil {
+			return queued, fmt.Errorf("failed to queue scan of %s: %w", entry.Value, err)
+		}
+		queued = append(queued, entry.Value)
+		gologger.Info().Msgf("Queued scan %d of %s imported from %s", scanID, entry.Value, source)
+	}
+	return queued, nil
+}
@@ -0,0 +1,239 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+func TestNormalizeEntry(t *testing.T) {
+	tests := []struct {
+		value string
+		want  models.ScopeEntry
+	}{
+		{" Example.COM. ", models.ScopeEntry{Value: "example.com", Type: models.ScopeAssetDomain}},
+		{"*.example.com", models.ScopeEntry{Value: "example.com", Type: models.ScopeAssetDomain}},
+		{"192.0.2.7/24", models.ScopeEntry{Value: "192.0.2.0/24", Type: models.ScopeAssetIPRange}},
+		{"192.0.2.7", models.ScopeEntry{Value: "192.0.2.7/32", Type: models.ScopeAssetIPRange}},
+		{"2001:db8::1", models.ScopeEntry{Value: "2001:db8::1/128", Type: models.ScopeAssetIPRange}},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeEntry(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeEntry(%q) = %+v, %v, want %+v", tt.value, got, err, tt.want)
+		}
+	}
+
+	if _, err := NormalizeEntry("not a domain"); err == nil {
+		t.Error("Expected an invalid asset to fail")
+	}
+}
+
+type fakeBlobReader map[string]string
+
+func (f fakeBlobReader) ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error) {
+	content, ok := f[blobPath]
+	if !ok {
+		return nil, errors.New("blob not found")
+	}
+	return []byte(content), nil
+}
+
+func TestCSVConnector(t *testing.T) {
+	reader := fakeBlobReader{"scope/assets.csv": "asset,owner\n# retired\nexample.com,web team\n\n198.51.100.0/24,network\nbad value,nobody\n"}
+
+	entries, err := NewCSVConnector(reader, "scope/assets.csv").Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Value != "example.com" || entries[1].Type != models.ScopeAssetIPRange {
+		t.Errorf("Expected the domain and the range, got %+v", entries)
+	}
+}
+
+func TestServiceNowConnector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "asm" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/now/table/cmdb_ci_dns_name" || r.URL.Query().Get("sysparm_fields") != "name" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"result":[{"name":"example.com"},{"name":"shop.example.org"},{"name":""}]}`))
+	}))
+	defer server.Close()
+
+	connector := NewServiceNowConnector(ServiceNowConfig{
+		InstanceURL: server.URL + "/",
+		Username:    "asm",
+		Password:    "secret",
+		Table:       "cmdb_ci_dns_name",
+		Field:       "name",
+	}, 5*time.Second)
+	entries, err := connector.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Value != "shop.example.org" {
+		t.Errorf("Expected two domains, got %+v", entries)
+	}
+}
+
+func TestCloudflareConnector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("page") == "1" {
+			w.Write([]byte(`{"success":true,"result":[{"name":"example.com"}],"result_info":{"total_pages":2}}`))
+			return
+		}
+		w.Write([]byte(`{"success":true,"result":[{"name":"example.net"}],"result_info":{"total_pages":2}}`))
+	}))
+	defer server.Close()
+
+	connector := NewCloudflareConnector("token", 5*time.Second)
+	connector.baseURL = server.URL
+	entries, err := connector.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Value != "example.net" {
+		t.Errorf("Expected the zones of both pages, got %+v", entries)
+	}
+
+	connector.token = "wrong"
+	if _, err := connector.Fetch(context.Background()); err == nil {
+		t.Error("Expected a rejected token to fail")
+	}
+}
+
+func TestRoute53Connector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("Expected a SigV4 signed request, got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("marker") == "" {
+			w.Write([]byte(`<ListHostedZonesResponse><HostedZones>
+				<HostedZone><Name>example.com.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>
+				<HostedZone><Name>internal.example.com.</Name><Config><PrivateZone>true</PrivateZone></Config></HostedZone>
+				</HostedZones><IsTruncated>true</IsTruncated><NextMarker>Z2</NextMarker></ListHostedZonesResponse>`))
+			return
+		}
+		w.Write([]byte(`<ListHostedZonesResponse><HostedZones>
+			<HostedZone><Name>example.org.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>
+			</HostedZones><IsTruncated>false</IsTruncated></ListHostedZonesResponse>`))
+	}))
+	defer server.Close()
+
+	connector := NewRoute53Connector(utils.AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, 5*time.Second)
+	connector.baseURL = server.URL
+	entries, err := connector.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Value != "example.com" || entries[1].Value != "example.org" {
+		t.Errorf("Expected the public zones of both pages, got %+v", entries)
+	}
+}
+
+type fakeConnector struct {
+	name    string
+	entries []models.ScopeEntry
+	err     error
+}
+
+func (f *fakeConnector) Name() string { return f.name }
+
+func (f *fakeConnector) Fetch(ctx context.Context) ([]models.ScopeEntry, error) {
+	return f.entries, f.err
+}
+
+type fakeScopeStore struct {
+	scope models.ScanScope
+}
+
+func (f *fakeScopeStore) UpdateScanScope(ctx context.Context, tenantID string, update func(*models.ScanScope) error) error {
+	f.scope.TenantID = tenantID
+	return update(&f.scope)
+}
+
+type fakeQueue struct {
+	tasks []*models.TaskMessage
+	err   error
+}
+
+func (f *fakeQueue) EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.tasks = append(f.tasks, taskMsg)
+	return nil
+}
+
+func TestSyncer_SyncOnce(t *testing.T) {
+	store := &fakeScopeStore{}
+	queue := &fakeQueue{}
+	cmdb := &fakeConnector{name: "servicenow", entries: []models.ScopeEntry{
+		{Value: "example.com", Type: models.ScopeAssetDomain},
+		{Value: "192.0.2.0/24", Type: models.ScopeAssetIPRange},
+	}}
+	syncer := NewSyncer(store, queue, "acme", time.Hour, cmdb)
+
+	if err := syncer.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("SyncOnce failed: %v", err)
+	}
+	if len(queue.tasks) != 1 || queue.tasks[0].Domain != "example.com" || queue.tasks[0].Task != models.TaskSubfinder || queue.tasks[0].TenantID != "acme" {
+		t.Fatalf("Expected one subfinder task for the new domain, got %+v", queue.tasks)
+	}
+	if queue.tasks[0].CorrelationID == "" {
+		t.Error("Expected queued tasks to carry a correlation ID")
+	}
+
+	// Nothing is queued for assets already in scope, and a failing source keeps its assets
+	if err := syncer.SyncOnce(context.Background()); err != nil || len(queue.tasks) != 1 {
+		t.Errorf("Expected no new tasks, got %d (%v)", len(queue.tasks), err)
+	}
+	cmdb.err = errors.New("unavailable")
+	if err := syncer.SyncOnce(context.Background()); err == nil {
+		t.Error("Expected the failing source to be reported")
+	}
+	if asset := store.scope.Assets["example.com"]; !asset.RemovedAt.IsZero() {
+		t.Errorf("Expected the assets of a failing source to stay in scope, got %+v", asset)
+	}
+}
+
+func TestSyncer_RetriesFailedEnqueue(t *testing.T) {
+	store := &fakeScopeStore{}
+	queue := &fakeQueue{err: errors.New("unavailable")}
+	cmdb := &fakeConnector{name: "servicenow", entries: []models.ScopeEntry{{Value: "example.com", Type: models.ScopeAssetDomain}}}
+	syncer := NewSyncer(store, queue, "acme", time.Hour, cmdb)
+
+	if err := syncer.SyncOnce(context.Background()); err == nil {
+		t.Fatal("Expected the failed enqueue to be reported")
+	}
+	if asset := store.scope.Assets["example.com"]; !asset.ScanPending {
+		t.Fatalf("Expected the scan left pending, got %+v", asset)
+	}
+
+	// The claim of the failed sync lapses and the next sync queues the scan, once
+	store.scope.Assets["example.com"].ScanClaimedAt = time.Now().Add(-scanClaimLease)
+	queue.err = nil
+	for range 2 {
+		if err := syncer.SyncOnce(context.Background()); err != nil {
+			t.Fatalf("SyncOnce failed: %v", err)
+		}
+	}
+	if len(queue.tasks) != 1 || store.scope.Assets["example.com"].ScanPending {
+		t.Errorf("Expected the pending scan queued once, got %d tasks and %+v", len(queue.tasks), store.scope.Assets["example.com"])
+	}
+}
@@ -0,0 +1,62 @@
+package connectors
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// BlobReader reads blobs from the storage container
+type BlobReader interface {
+	ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error)
+}
+
+// CSVConnector imports the assets listed in the first column of a CSV blob. Empty lines, lines
+// starting with '#' and a header row whose first column is not an asset are skipped.
+type CSVConnector struct {
+	reader   BlobReader
+	blobPath string
+}
+
+// NewCSVConnector creates a connector for the CSV blob at blobPath
+func NewCSVConnector(reader BlobReader, blobPath string) *CSVConnector {
+	return &CSVConnector{reader: reader, blobPath: blobPath}
+}
+
+// Name returns the connector name
+func (c *CSVConnector) Name() string {
+	return "csv"
+}
+
+// Fetch reads the CSV blob and returns its assets
+func (c *CSVConnector) Fetch(ctx context.Context) ([]models.ScopeEntry, error) {
+	content, err := c.reader.ReadFileFromBlob(ctx, c.blobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.blobPath, err)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", c.blobPath, err)
+	}
+
+	values := make([]string, 0, len(records))
+	for i, record := range records {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if _, err := NormalizeEntry(record[0]); err != nil && i == 0 {
+			continue // Header row
+		}
+		values = append(values, record[0])
+	}
+	return normalizeEntries(c.Name(), values), nil
+}
@@ -0,0 +1,93 @@
+package connectors
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// DefaultRoute53URL is the base URL of the Route 53 API, which is served from us-east-1 only
+const DefaultRoute53URL = "https://route53.amazonaws.com/2013-04-01"
+
+// Route53Connector imports the public hosted zones of an AWS account
+type Route53Connector struct {
+	baseURL     string
+	credentials utils.AWSCredentials
+	httpClient  *http.Client
+}
+
+// NewRoute53Connector creates a connector for the hosted zones the credentials can list
+func NewRoute53Connector(credentials utils.AWSCredentials, timeout time.Duration) *Route53Connector {
+	return &Route53Connector{baseURL: DefaultRoute53URL, credentials: credentials, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// Name returns the connector name
+func (c *Route53Connector) Name() string {
+	return "route53"
+}
+
+// listHostedZonesResponse is the part of the ListHostedZones response needed to page through zones
+type listHostedZonesResponse struct {
+	HostedZones []struct {
+		Name        string `xml:"Name"`
+		PrivateZone bool   `xml:"Config>PrivateZone"`
+	} `xml:"HostedZones>HostedZone"`
+	IsTruncated bool   `xml:"IsTruncated"`
+	NextMarker  string `xml:"NextMarker"`
+}
+
+// Fetch pages through the hosted zones and returns the names of the public ones
+func (c *Route53Connector) Fetch(ctx context.Context) ([]models.ScopeEntry, error) {
+	var values []string
+	marker := ""
+	for {
+		endpoint := c.baseURL + "/hostedzone"
+		if marker != "" {
+			endpoint += "?marker=" + url.QueryEscape(marker)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Route 53 request: %w", err)
+		}
+		utils.SignAWSRequest(req, nil, c.credentials, "us-east-1", "route53", time.Now())
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("Route 53 hosted zones: %w", err)
+		}
+		var zones listHostedZonesResponse
+		err = decodeXML(resp, &zones)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("Route 53 hosted zones: %w", err)
+		}
+
+		for _, zone := range zones.HostedZones {
+			if !zone.PrivateZone {
+				values = append(values, zone.Name)
+			}
+		}
+		if !zones.IsTruncated || zones.NextMarker == "" {
+			break
+		}
+		marker = zones.NextMarker
+	}
+	return normalizeEntries(c.Name(), values), nil
+}
+
+// decodeXML decodes the XML body of a successful response into v
+func decodeXML(resp *http.Response, v interface{}) error {
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// serviceNowPageSize is the number of records requested per Table API call
+const serviceNowPageSize = 1000
+
+// ServiceNowConfig holds the settings of a ServiceNow CMDB import
+type ServiceNowConfig struct {
+	InstanceURL string // e.g. https://acme.service-now.com
+	Username    string
+	Password    string
+	Table       string // CMDB table holding the assets, e.g. cmdb_ci_dns_name
+	Field       string // Field of the table holding the domain or IP range
+}
+
+// ServiceNowConnector imports assets from a ServiceNow CMDB table through the Table API
+type ServiceNowConnector struct {
+	config     ServiceNowConfig
+	httpClient *http.Client
+}
+
+// NewServiceNowConnector creates a connector for a ServiceNow instance
+func NewServiceNowConnector(config ServiceNowConfig, timeout time.Duration) *ServiceNowConnector {
+	config.InstanceURL = strings.TrimSuffix(config.InstanceURL, "/")
+	return &ServiceNowConnector{config: config, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// Name returns the connector name
+func (c *ServiceNowConnector) Name() string {
+	return "servicenow"
+}
+
+// Fetch pages through the CMDB table and returns the assets of the configured field
+func (c *ServiceNowConnector) Fetch(ctx context.Context) ([]models.ScopeEntry, error) {
+	var values []string
+	for offset := 0; ; offset += serviceNowPageSize {
+		query := url.Values{
+			"sysparm_fields": {c.config.Field},
+			"sysparm_limit":  {strconv.Itoa(serviceNowPageSize)},
+			"sysparm_offset": {strconv.Itoa(offset)},
+		}
+		endpoint := c.config.InstanceURL + "/api/now/table/" + url.PathEscape(c.config.Table) + "?" + query.Encode()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ServiceNow request: %w", err)
+		}
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+		req.Header.Set("Accept", "application/json")
+
+		var page struct {
+			Result []map[string]interface{} `json:"result"`
+		}
+		if err := doJSON(c.httpClient, req, &page); err != nil {
+			return nil, fmt.Errorf("ServiceNow table %s: %w", c.config.Table, err)
+		}
+
+		for _, record := range page.Result {
+			if value, ok := record[c.config.Field].(string); ok && value != "" {
+				values = append(values, value)
+			}
+		}
+		if len(page.Result) < serviceNowPageSize {
+			break
+		}
+	}
+	return normalizeEntries(c.Name(), values), nil
+}
+
+// doJSON sends a request and decodes its JSON response into v
+func doJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,451 @@
+package devqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/google/uuid"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	// maxDeliveries dead-letters a message after as many deliveries as Service Bus does by default
+	maxDeliveries = 10
+	// maxDeferredRetries is how often a failed task is re-scheduled for a later retry before it is dead-lettered
+	maxDeferredRetries = 5
+	// handOverDelay is how long a message left to other workers waits before it is delivered again
+	handOverDelay = 30 * time.Second
+	// devQueueName is the queue label of the dev queue's metrics
+	devQueueName = "dev"
+)
+
+// Subdirectories of the queue directory
+const (
+	inboxDir      = "inbox"      // Task message JSON dropped in by hand, one task per file
+	pendingDir    = "pending"    // Messages waiting for delivery
+	processingDir = "processing" // The message being handled
+	deadLetterDir = "deadletter" // Messages that failed for good
+)
+
+// envelope is a queued task message with the delivery state Service Bus would keep for it
+type envelope struct {
+	ID              string          `json:"id"`
+	EnqueuedAt      time.Time       `json:"enqueued_at"`
+	NotBefore       time.Time       `json:"not_before,omitzero"`
+	DeliveryCount   int             `json:"delivery_count"`
+	Attempts        int             `json:"attempts,omitempty"` // Deliveries before the message was re-scheduled
+	DeferredRetries int             `json:"deferred_retries,omitempty"`
+	RetryCount      int             `json:"retry_count,omitempty"` // In-process retries of earlier deliveries
+	Error           string          `json:"error,omitempty"`       // Why a dead-lettered message failed
+	Reason          string          `json:"reason,omitempty"`      // Dead-letter reason given by the handler, kept across retries
+	Body            json.RawMessage `json:"body"`
+}
+
+// FileQueue is a task queue kept in a local directory, standing in for Service Bus in DEV_MODE.
+// Messages are JSON files that move from pending/ to processing/ while they are handled and are
+// deleted once completed, re-queued for retries or moved to deadletter/ like Service Bus would.
+// A directory is meant for one worker process.
+type FileQueue struct {
+	dir            string
+	maxMessageSize int // Bytes; larger messages are dead-lettered unread
+	rejected       *metrics.Counter
+	mu             sync.Mutex
+}
+
+// NewFileQueue opens the queue in dir, creating its directories, and re-queues a message that was
+// still being handled when the previous worker stopped
+func NewFileQueue(dir string) (*FileQueue, error) {
+	for _, sub := range []string{inboxDir, pendingDir, processingDir, deadLetterDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory: %w", err)
+		}
+	}
+
+	q := &FileQueue{dir: dir, maxMessageSize: models.DefaultMaxMessageSize}
+	interrupted, err := q.list(processingDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range interrupted {
+		if err := os.Rename(q.path(processingDir, name), q.path(pendingDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to re-queue interrupted message %s: %w", name, err)
+		}
+		gologger.Warning().Msgf("Re-queued message %s that was interrupted by the last shutdown", name)
+	}
+	return q, nil
+}
+
+// HealthCheck verifies the queue directories are still there
+func (q *FileQueue) HealthCheck(ctx context.Context) error {
+	for _, sub := range []string{inboxDir, pendingDir, processingDir, deadLetterDir} {
+		if _, err := os.Stat(filepath.Join(q.dir, sub)); err != nil {
+			return fmt.Errorf("queue directory is unusable: %w", err)
+		}
+	}
+	return nil
+}
+
+// QueueDepth counts the pending, scheduled and dead-lettered messages
+func (q *FileQueue) QueueDepth(ctx context.Context) (models.QueueDepth, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	depth := models.QueueDepth{Queue: q.dir, SampledAt: time.Now().UTC()}
+	pending, err := q.list(pendingDir)
+	if err != nil {
+		return models.QueueDepth{}, err
+	}
+	for _, name := range pending {
+		env, err := q.read(pendingDir, name)
+		if err != nil {
+			continue
+		}
+		if env.NotBefore.After(depth.SampledAt) {
+			depth.Scheduled++
+		} else {
+			depth.Active++
+		}
+	}
+	deadLettered, err := q.list(deadLetterDir)
+	if err != nil {
+		return models.QueueDepth{}, err
+	}
+	depth.DeadLetter = len(deadLettered)
+	return depth, nil
+}
+
+// PeekTaskTypes counts the task types of up to max pending messages that are due
+func (q *FileQueue) PeekTaskTypes(ctx context.Context, max int) (map[models.Task]int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending, err := q.list(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tasks := make(map[models.Task]int)
+	for _, name := range pending[:min(max, len(pending))] {
+		env, err := q.read(pendingDir, name)
+		if err != nil || env.NotBefore.After(now) {
+			continue
+		}
+		var taskMsg models.TaskMessage
+		if json.Unmarshal(env.Body, &taskMsg) != nil || taskMsg.Task == "" {
+			continue
+		}
+		tasks[taskMsg.Task]++
+	}
+	return tasks, nil
+}
+
+// EnqueueTask adds a task to the queue. A task with a not_before time is not delivered before then.
+func (q *FileQueue) EnqueueTask(ctx context.Context, taskMsg *models.TaskMessage) error {
+	body, err := json.Marshal(taskMsg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task message: %w", err)
+	}
+
+	env := newEnvelope(body)
+	if taskMsg.NotBefore != nil && taskMsg.NotBefore.After(time.Now()) {
+		env.NotBefore = *taskMsg.NotBefore
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.write(pendingDir, env)
+}
+
+// Close does nothing; the queue lives on disk
+func (q *FileQueue) Close(ctx context.Context) error {
+	return nil
+}
+
+// ProcessMessages hands queued tasks to handler one at a time until ctx is cancelled, checking the
+// inbox and the pending messages every pollInterval while the queue is empty. Each task gets
+// scannerTimeout to finish; the lock settings do not apply, as nothing else can take the message.
+func (q *FileQueue) ProcessMessages(ctx context.Context, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, pollInterval time.Duration, lockRenewalInterval time.Duration, maxLockRenewalTime time.Duration, scannerTimeout time.Duration) error {
+	gologger.Info().Msgf("Starting message processing loop on queue directory %s", q.dir)
+	pollInterval = max(pollInterval, 100*time.Millisecond)
+
+	for {
+		processed, err := q.processNext(ctx, handler, scannerTimeout)
+		if err != nil {
+			gologger.Error().Msgf("Error processing message: %v", err)
+		}
+		if processed {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			gologger.Info().Msg("Message processing stopped due to context cancellation")
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// SetMaxMessageSize sets the size in bytes above which messages are dead-lettered without being decoded
+func (q *FileQueue) SetMaxMessageSize(size int) {
+	q.maxMessageSize = size
+}
+
+// SetMetrics sets the registry rejected messages are counted in, under the same counter as the
+// Service Bus client so dashboards work in dev mode
+func (q *FileQueue) SetMetrics(registry *metrics.Registry) {
+	q.rejected = registry.Counter("asm_servicebus_rejected_messages_total", "Malformed messages dead-lettered before processing, by reason.", "queue", "reason")
+}
+
+// processNext handles the next due message, reporting whether there was one
+func (q *FileQueue) processNext(ctx context.Context, handler func(context.Context, *models.TaskMessage) *models.MessageProcessingResult, scannerTimeout time.Duration) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if err := q.ingestInbox(); err != nil {
+		return false, err
+	}
+
+	name, env, err := q.claim()
+	if err != nil || env == nil {
+		return false, err
+	}
+	gologger.Debug().Msgf("Received message: %s", env.ID)
+
+	var result *models.MessageProcessingResult
+	var rejection *models.MessageRejection
+	taskMsg, err := models.DecodeTaskMessage(env.Body, q.maxMessageSize)
+	if errors.As(err, &rejection) {
+		gologger.Warning().Msgf("Rejected malformed message %s: %v", env.ID, rejection)
+		q.rejected.Inc(devQueueName, rejection.Reason)
+		result = rejection.Result()
+	} else {
+		if taskMsg.CorrelationID == "" {
+			taskMsg.CorrelationID = env.ID
+		}
+		taskMsg.Attempt = env.Attempts + env.DeliveryCount
+
+		handlerCtx, cancel := context.WithTimeout(ctx, scannerTimeout)
+		result = handler(handlerCtx, taskMsg)
+		cancel()
+	}
+
+	return true, q.settle(name, env, result)
+}
+
+// settle completes, re-queues or dead-letters a handled message by its result, the way the
+// Service Bus client does
+func (q *FileQueue) settle(name string, env *envelope, result *models.MessageProcessingResult) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch {
+	case !result.DeferUntil.IsZero() && result.Success:
+		env.NotBefore = result.DeferUntil
+		env.DeliveryCount = 0
+		return q.requeue(name, env)
+
+	case !result.DeferUntil.IsZero():
+		if result.DeadLetterReason != "" {
+			env.Reason = result.DeadLetterReason
+		}
+		if env.DeferredRetries >= maxDeferredRetries {
+			gologger.Error().Msgf("Message dead lettered after %d deferred retries: %s, error: %v", env.DeferredRetries, env.ID, result.Error)
+			if env.Reason == "" {
+				env.Reason = "MaxDeferredRetriesExceeded"
+			}
+			return q.deadLetter(name, env, result.Error)
+		}
+		gologger.Warning().Msgf("Message %s failed, retrying at %s: %v", env.ID, result.DeferUntil.Format(time.RFC3339), result.Error)
+		env.RetryCount += result.RetryCount
+		env.Attempts += env.DeliveryCount
+		env.DeliveryCount = 0
+		env.DeferredRetries++
+		env.NotBefore = result.DeferUntil
+		return q.requeue(name, env)
+
+	case result.Success:
+		if err := os.Remove(q.path(processingDir, name)); err != nil {
+			return fmt.Errorf("failed to complete message: %w", err)
+		}
+		gologger.Debug().Msgf("Message completed successfully: %s", env.ID)
+		return nil
+
+	case result.Abandon && env.DeliveryCount < maxDeliveries:
+		gologger.Info().Msgf("Leaving message %s to another worker: %v", env.ID, result.Error)
+		env.NotBefore = time.Now().Add(handOverDelay)
+		return q.requeue(name, env)
+
+	case result.Retryable && env.DeliveryCount < maxDeliveries:
+		gologger.Warning().Msgf("Message abandoned for retry: %s, error: %v", env.ID, result.Error)
+		return q.requeue(name, env)
+
+	default:
+		gologger.Error().Msgf("Message dead lettered: %s, error: %v", env.ID, result.Error)
+		env.Reason = result.DeadLetterReason
+		return q.deadLetter(name, env, result.Error)
+	}
+}
+
+// claim moves the oldest due pending message to processing/ and counts its delivery.
+// It returns a nil envelope when no message is due.
+func (q *FileQueue) claim() (string, *envelope, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending, err := q.list(pendingDir)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	for _, name := range pending {
+		env, err := q.read(pendingDir, name)
+		if err != nil {
+			gologger.Warning().Msgf("Moving unreadable message %s to the dead-letter directory: %v", name, err)
+			if err := os.Rename(q.path(pendingDir, name), q.path(deadLetterDir, name)); err != nil {
+				return "", nil, fmt.Errorf("failed to dead-letter unreadable message %s: %w", name, err)
+			}
+			continue
+		}
+		if env.NotBefore.After(now) {
+			continue
+		}
+
+		env.DeliveryCount++
+		if err := q.write(processingDir, env); err != nil {
+			return "", nil, err
+		}
+		if err := os.Remove(q.path(pendingDir, name)); err != nil {
+			return "", nil, fmt.Errorf("failed to claim message %s: %w", name, err)
+		}
+		return fileName(env), env, nil
+	}
+	return "", nil, nil
+}
+
+// ingestInbox queues the task messages dropped into the inbox
+func (q *FileQueue) ingestInbox() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	names, err := q.list(inboxDir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		path := q.path(inboxDir, name)
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read inbox message %s: %w", name, err)
+		}
+		if !json.Valid(body) {
+			gologger.Warning().Msgf("Inbox file %s is not JSON, moving it to the dead-letter directory", name)
+			if err := os.Rename(path, q.path(deadLetterDir, name)); err != nil {
+				return fmt.Errorf("failed to dead-letter inbox file %s: %w", name, err)
+			}
+			continue
+		}
+
+		env := newEnvelope(body)
+		if err := q.write(pendingDir, env); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove inbox message %s: %w", name, err)
+		}
+		gologger.Info().Msgf("Queued %s as message %s", name, env.ID)
+	}
+	return nil
+}
+
+// requeue moves a message from processing/ back to pending/ with its updated delivery state
+func (q *FileQueue) requeue(name string, env *envelope) error {
+	if err := q.write(pendingDir, env); err != nil {
+		return err
+	}
+	return os.Remove(q.path(processingDir, name))
+}
+
+// deadLetter moves a message from processing/ to deadletter/, recording why it failed
+func (q *FileQueue) deadLetter(name string, env *envelope, cause error) error {
+	if cause != nil {
+		env.Error = cause.Error()
+	}
+	if err := q.write(deadLetterDir, env); err != nil {
+		return err
+	}
+	return os.Remove(q.path(processingDir, name))
+}
+
+// list returns the JSON files of a subdirectory, oldest first
+func (q *FileQueue) list(sub string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(q.dir, sub))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queue directory %s: %w", sub, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read parses a queued message
+func (q *FileQueue) read(sub, name string) (*envelope, error) {
+	content, err := os.ReadFile(q.path(sub, name))
+	if err != nil {
+		return nil, err
+	}
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return nil, err
+	}
+	if env.ID == "" || len(env.Body) == 0 {
+		return nil, errors.New("not a queued message")
+	}
+	return &env, nil
+}
+
+// write stores a message in a subdirectory through a temporary file, so a crash never leaves half a message
+func (q *FileQueue) write(sub string, env *envelope) error {
+	content, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal message %s: %w", env.ID, err)
+	}
+	path := q.path(sub, fileName(env))
+	if err := os.WriteFile(path+".tmp", content, 0o644); err != nil {
+		return fmt.Errorf("failed to write message %s: %w", env.ID, err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write message %s: %w", env.ID, err)
+	}
+	return nil
+}
+
+// path returns the path of a file in a subdirectory
+func (q *FileQueue) path(sub, name string) string {
+	return filepath.Join(q.dir, sub, name)
+}
+
+// newEnvelope wraps a message body for queueing
+func newEnvelope(body []byte) *envelope {
+	return &envelope{ID: uuid.New().String(), EnqueuedAt: time.Now().UTC(), Body: body}
+}
+
+// fileName returns the file name of a message, which sorts messages by enqueue time
+func fileName(env *envelope) string {
+	return fmt.Sprintf("%020d-%s.json", env.EnqueuedAt.UnixNano(), env.ID)
+}
@@ -0,0 +1,150 @@
+package devqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestFileQueue_Settlement(t *testing.T) {
+	queue, err := NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := queue.EnqueueTask(ctx, &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 1, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A retryable failure is redelivered as the next attempt
+	var attempts []int
+	handler := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		attempts = append(attempts, taskMsg.Attempt)
+		if len(attempts) == 1 {
+			return &models.MessageProcessingResult{Error: errors.New("flaky"), Retryable: true}
+		}
+		return &models.MessageProcessingResult{Success: true}
+	}
+	for i := 0; i < 2; i++ {
+		if processed, err := queue.processNext(ctx, handler, time.Minute); !processed || err != nil {
+			t.Fatalf("Expected a message to be processed, got %v and %v", processed, err)
+		}
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("Expected attempts 1 and 2, got %v", attempts)
+	}
+
+	depth, err := queue.QueueDepth(ctx)
+	if err != nil || depth.Active != 0 || depth.DeadLetter != 0 {
+		t.Errorf("Expected the completed message removed, got %+v and %v", depth, err)
+	}
+	if processed, _ := queue.processNext(ctx, handler, time.Minute); processed {
+		t.Error("Expected an empty queue")
+	}
+}
+
+func TestFileQueue_InboxDeferralAndDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := NewFileQueue(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	task := `{"task": "dns_resolve", "scan_id": 7, "domain": "example.com"}`
+	if err := os.WriteFile(filepath.Join(dir, inboxDir, "task.json"), []byte(task), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failure with a retry time is scheduled for then and counts the attempt
+	deferred := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		return &models.MessageProcessingResult{Error: errors.New("banned"), DeferUntil: time.Now().Add(time.Hour), RetryCount: 2, DeadLetterReason: "Banned"}
+	}
+	if processed, err := queue.processNext(ctx, deferred, time.Minute); !processed || err != nil {
+		t.Fatalf("Expected the inbox task to be processed, got %v and %v", processed, err)
+	}
+	depth, _ := queue.QueueDepth(ctx)
+	if depth.Scheduled != 1 || depth.Active != 0 {
+		t.Errorf("Expected the retry scheduled, got %+v", depth)
+	}
+	if processed, _ := queue.processNext(ctx, deferred, time.Minute); processed {
+		t.Error("Expected the scheduled retry not to be delivered early")
+	}
+
+	// Make the retry due; a permanent failure dead-letters it
+	names, _ := queue.list(pendingDir)
+	env, _ := queue.read(pendingDir, names[0])
+	if env.RetryCount != 2 || env.Reason != "Banned" {
+		t.Errorf("Expected the retry to keep the retry count and dead-letter reason, got %d and %q", env.RetryCount, env.Reason)
+	}
+	env.NotBefore = time.Time{}
+	queue.write(pendingDir, env)
+
+	var attempt int
+	failed := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		attempt = taskMsg.Attempt
+		return &models.MessageProcessingResult{Error: errors.New("invalid domain")}
+	}
+	queue.processNext(ctx, failed, time.Minute)
+	if attempt != 2 {
+		t.Errorf("Expected the deferred retry to be attempt 2, got %d", attempt)
+	}
+	depth, _ = queue.QueueDepth(ctx)
+	if depth.DeadLetter != 1 || depth.Scheduled != 0 {
+		t.Errorf("Expected the message dead-lettered, got %+v", depth)
+	}
+	names, _ = queue.list(deadLetterDir)
+	if env, err := queue.read(deadLetterDir, names[0]); err != nil || env.Error != "invalid domain" {
+		t.Errorf("Expected the dead-lettered message to record its error, got %+v and %v", env, err)
+	}
+}
+
+func TestFileQueue_AbandonAndDeadLetterReason(t *testing.T) {
+	queue, err := NewFileQueue(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := queue.EnqueueTask(ctx, &models.TaskMessage{Task: models.TaskNaabu, ScanID: 1, Domain: "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An abandoned message goes back to the queue for another worker
+	abandoned := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		return &models.MessageProcessingResult{Error: errors.New("not enabled"), Abandon: true}
+	}
+	queue.processNext(ctx, abandoned, time.Minute)
+	if depth, _ := queue.QueueDepth(ctx); depth.Scheduled != 1 || depth.DeadLetter != 0 {
+		t.Errorf("Expected the abandoned message back in the queue for later, got %+v", depth)
+	}
+	if handled, _ := queue.processNext(ctx, abandoned, time.Minute); handled {
+		t.Fatal("Expected the abandoned message held back instead of delivered straight again")
+	}
+	names, _ := queue.list(pendingDir)
+	env, err := queue.read(pendingDir, names[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.NotBefore = time.Time{}
+	if err := queue.write(pendingDir, env); err != nil {
+		t.Fatal(err)
+	}
+
+	// A dead-letter reason is recorded next to the error
+	rejected := func(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+		return &models.MessageProcessingResult{Error: errors.New("not enabled"), DeadLetterReason: "TaskTypeDisabled"}
+	}
+	queue.processNext(ctx, rejected, time.Minute)
+	names, _ = queue.list(deadLetterDir)
+	if len(names) != 1 {
+		t.Fatalf("Expected the message dead-lettered, found %d", len(names))
+	}
+	if env, err := queue.read(deadLetterDir, names[0]); err != nil || env.Reason != "TaskTypeDisabled" || env.Error != "not enabled" {
+		t.Errorf("Expected the dead-letter reason and error recorded, got %+v and %v", env, err)
+	}
+}
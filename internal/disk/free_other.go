@@ -0,0 +1,8 @@
+//go:build !unix
+
+package disk
+
+// freeSpace cannot read the free space on this platform, so only the budget is enforced
+func freeSpace(path string) (int64, bool) {
+	return 0, false
+}
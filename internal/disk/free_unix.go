@@ -0,0 +1,14 @@
+//go:build unix
+
+package disk
+
+import "syscall"
+
+// freeSpace returns the bytes available to the worker on the filesystem of a path
+func freeSpace(path string) (int64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, false
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), true
+}
@@ -0,0 +1,298 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Area is a directory of files the worker downloads or creates. Every entry directly inside Dir
+// that matches one of Patterns, or every entry without patterns, is accounted for and evicted as
+// a whole; its last use is the newest modification time of its files.
+type Area struct {
+	Name     string
+	Dir      string
+	Patterns []string
+	// Ephemeral entries belong to a single task, which removes them when it ends. One unused for
+	// longer than the grace period was left behind by a crash and is removed even under budget.
+	Ephemeral bool
+	// Lock, when set, is held while the area is swept, so a cache that reuses its files under the
+	// same lock never has them removed between checking and touching them.
+	Lock sync.Locker
+}
+
+// entry is one evictable entry of an area
+type entry struct {
+	area     string
+	path     string
+	size     int64
+	lastUsed time.Time
+}
+
+// Janitor keeps the areas of the worker within a disk budget, evicting the least recently used
+// entries first, and reports a full disk before a task starts writing to it. Entries used within
+// the grace period may belong to a running task and are never evicted.
+type Janitor struct {
+	areas   []Area
+	budget  int64 // Bytes the areas may use together; 0 disables the budget
+	minFree int64 // Free bytes the disk of every area keeps; 0 disables the check
+	grace   time.Duration
+
+	mu        sync.Mutex // Serializes sweeps
+	usage     *metrics.Gauge
+	free      *metrics.Gauge
+	evictions *metrics.Counter
+}
+
+// NewJanitor creates a janitor for a budget and a minimum of free space in bytes
+func NewJanitor(budget, minFree int64, grace time.Duration) *Janitor {
+	return &Janitor{budget: budget, minFree: minFree, grace: grace}
+}
+
+// AddArea adds a directory for the janitor to account for
+func (j *Janitor) AddArea(area Area) {
+	j.areas = append(j.areas, area)
+}
+
+// SetMetrics sets the registry the disk usage gauges and eviction counter are published in
+func (j *Janitor) SetMetrics(registry *metrics.Registry) {
+	j.usage = registry.Gauge("asm_disk_usage_bytes", "Bytes used by the temp files and caches of the worker.", "area")
+	j.free = registry.Gauge("asm_disk_free_bytes", "Free bytes on the disk of each area.", "area")
+	j.evictions = registry.Counter("asm_disk_evictions_total", "Entries removed to keep the disk budget or as orphans of crashed tasks.", "area", "reason")
+}
+
+// Run sweeps the areas at a fixed interval until ctx is done
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Sweep(); err != nil {
+			gologger.Warning().Msgf("Disk sweep failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep removes orphaned ephemeral entries, then evicts the least recently used entries until the
+// areas fit the budget, and returns the bytes the areas use afterwards
+func (j *Janitor) Sweep() (int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, area := range j.areas {
+		if area.Lock != nil {
+			area.Lock.Lock()
+			defer area.Lock.Unlock()
+		}
+	}
+
+	entries, err := j.scan()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var kept []entry
+	for _, e := range entries {
+		if j.area(e.area).Ephemeral && now.Sub(e.lastUsed) > j.grace {
+			if j.remove(e, "orphaned") {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+
+	total := int64(0)
+	for _, e := range kept {
+		total += e.size
+	}
+	if j.budget > 0 && total > j.budget {
+		sort.Slice(kept, func(a, b int) bool { return kept[a].lastUsed.Before(kept[b].lastUsed) })
+		for _, e := range kept {
+			if total <= j.budget {
+				break
+			}
+			if now.Sub(e.lastUsed) <= j.grace {
+				continue
+			}
+			if j.remove(e, "budget") {
+				total -= e.size
+			}
+		}
+	}
+
+	j.publish()
+	return total, nil
+}
+
+// CheckSpace returns a disk full error when a new task would not have room to write its files:
+// when the disk of an area has less than the minimum free space, or the areas use more than the
+// budget, even after sweeping
+func (j *Janitor) CheckSpace() error {
+	if err := j.checkSpace(); err == nil {
+		return nil
+	}
+	if _, err := j.Sweep(); err != nil {
+		gologger.Warning().Msgf("Disk sweep failed: %v", err)
+	}
+	return j.checkSpace()
+}
+
+// checkSpace checks the free space and the budget without sweeping
+func (j *Janitor) checkSpace() error {
+	for _, area := range j.areas {
+		free, ok := freeSpace(area.Dir)
+		if ok && j.minFree > 0 && free < j.minFree {
+			return common.NewDiskFullError(fmt.Sprintf("only %s free on the disk of %s (%s), %s required",
+				FormatBytes(free), area.Name, area.Dir, FormatBytes(j.minFree)), nil)
+		}
+	}
+	if j.budget == 0 {
+		return nil
+	}
+
+	used := int64(0)
+	for _, area := range j.areas {
+		size, _ := dirSize(area.Dir, area.Patterns)
+		used += size
+	}
+	if used > j.budget {
+		return common.NewDiskFullError(fmt.Sprintf("temp files and caches use %s of the %s disk budget, and the rest is in use by running tasks",
+			FormatBytes(used), FormatBytes(j.budget)), nil)
+	}
+	return nil
+}
+
+// scan lists the entries of every area
+func (j *Janitor) scan() ([]entry, error) {
+	var entries []entry
+	for _, area := range j.areas {
+		children, err := os.ReadDir(area.Dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to list %s: %w", area.Dir, err)
+		}
+		for _, child := range children {
+			if !matches(child.Name(), area.Patterns) {
+				continue
+			}
+			e := entry{area: area.Name, path: filepath.Join(area.Dir, child.Name())}
+			_ = filepath.WalkDir(e.path, func(path string, d fs.DirEntry, err error) error {
+				// Directories change when the janitor or a cache removes files, which is not a use
+				if err != nil || d.IsDir() {
+					return nil
+				}
+				info, err := d.Info()
+				if err != nil {
+					return nil
+				}
+				e.size += info.Size()
+				if info.ModTime().After(e.lastUsed) {
+					e.lastUsed = info.ModTime()
+				}
+				return nil
+			})
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// remove deletes an entry and reports whether it is gone
+func (j *Janitor) remove(e entry, reason string) bool {
+	if err := os.RemoveAll(e.path); err != nil {
+		gologger.Warning().Msgf("Failed to remove %s from %s: %v", e.path, e.area, err)
+		return false
+	}
+	gologger.Info().Msgf("Removed %s (%s, last used %s) from %s: %s", e.path, FormatBytes(e.size), e.lastUsed.Format(time.RFC3339), e.area, reason)
+	j.evictions.Inc(e.area, reason)
+	return true
+}
+
+// publish updates the usage and free space gauges
+func (j *Janitor) publish() {
+	for _, area := range j.areas {
+		size, _ := dirSize(area.Dir, area.Patterns)
+		j.usage.Set(float64(size), area.Name)
+		if free, ok := freeSpace(area.Dir); ok {
+			j.free.Set(float64(free), area.Name)
+		}
+	}
+}
+
+// area returns the area of a name
+func (j *Janitor) area(name string) Area {
+	for _, area := range j.areas {
+		if area.Name == name {
+			return area
+		}
+	}
+	return Area{}
+}
+
+// matches reports whether an entry name matches one of the patterns, or there are none
+func matches(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dirSize returns the bytes of the entries of a directory that match the patterns
+func dirSize(dir string, patterns []string) (int64, error) {
+	children, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(0)
+	for _, child := range children {
+		if !matches(child.Name(), patterns) {
+			continue
+		}
+		_ = filepath.WalkDir(filepath.Join(dir, child.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+			return nil
+		})
+	}
+	return size, nil
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "1.5 GiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
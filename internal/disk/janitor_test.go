@@ -0,0 +1,122 @@
+package disk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+)
+
+// writeEntry writes a file of size bytes last modified age ago
+func writeEntry(t *testing.T, path string, size int, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJanitorSweep(t *testing.T) {
+	cache, temp := t.TempDir(), t.TempDir()
+	writeEntry(t, filepath.Join(cache, "old", "a.yaml"), 400, 5*time.Hour)
+	writeEntry(t, filepath.Join(cache, "recent", "b.yaml"), 400, 3*time.Hour)
+	writeEntry(t, filepath.Join(cache, "recent", "c.yaml"), 100, 10*time.Minute) // Synced during a running task
+	writeEntry(t, filepath.Join(temp, "httpx-hosts-1.txt"), 50, 2*time.Hour)
+	writeEntry(t, filepath.Join(temp, "httpx-hosts-2.txt"), 50, time.Minute)
+	writeEntry(t, filepath.Join(temp, "unrelated.txt"), 5000, 2*time.Hour)
+
+	janitor := NewJanitor(600, 0, time.Hour)
+	janitor.AddArea(Area{Name: "temp", Dir: temp, Patterns: []string{"httpx-hosts-*.txt"}, Ephemeral: true})
+	janitor.AddArea(Area{Name: "nuclei_templates", Dir: cache})
+
+	used, err := janitor.Sweep()
+	if err != nil {
+		t.Fatalf("Sweep() error = %v", err)
+	}
+	if used != 550 {
+		t.Errorf("Sweep() = %d bytes used, want 550", used)
+	}
+	for path, kept := range map[string]bool{
+		filepath.Join(cache, "old"):              false, // Least recently used
+		filepath.Join(cache, "recent"):           true,  // In use within the grace period
+		filepath.Join(temp, "httpx-hosts-1.txt"): false, // Orphan of a crashed task
+		filepath.Join(temp, "httpx-hosts-2.txt"): true,
+		filepath.Join(temp, "unrelated.txt"):     true, // Not the worker's
+	} {
+		if _, err := os.Stat(path); (err == nil) != kept {
+			t.Errorf("%s kept = %t, want %t", path, err == nil, kept)
+		}
+	}
+}
+
+func TestJanitorSweepHoldsAreaLock(t *testing.T) {
+	cache := t.TempDir()
+	writeEntry(t, filepath.Join(cache, "tenant", "a.yaml"), 1000, 5*time.Hour)
+
+	var lock sync.Mutex
+	janitor := NewJanitor(500, 0, time.Hour)
+	janitor.AddArea(Area{Name: "nuclei_templates", Dir: cache, Lock: &lock})
+
+	// A sync in progress keeps the sweep from evicting the templates it is reusing
+	lock.Lock()
+	swept := make(chan struct{})
+	go func() {
+		janitor.Sweep()
+		close(swept)
+	}()
+	select {
+	case <-swept:
+		t.Fatal("Sweep() ran while the area lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	now := time.Now()
+	os.Chtimes(filepath.Join(cache, "tenant", "a.yaml"), now, now)
+	lock.Unlock()
+	<-swept
+
+	if _, err := os.Stat(filepath.Join(cache, "tenant")); err != nil {
+		t.Errorf("Expected templates reused during the sweep to be kept, got %v", err)
+	}
+}
+
+func TestJanitorCheckSpace(t *testing.T) {
+	cache := t.TempDir()
+	writeEntry(t, filepath.Join(cache, "tenant", "a.yaml"), 1000, time.Minute)
+
+	janitor := NewJanitor(500, 0, time.Hour)
+	janitor.AddArea(Area{Name: "nuclei_templates", Dir: cache})
+
+	// The entry is in use, so the sweep cannot bring the cache within the budget
+	err := janitor.CheckSpace()
+	if !common.IsDiskFull(err) || !strings.Contains(err.Error(), "1000 B of the 500 B disk budget") {
+		t.Errorf("CheckSpace() = %v, want a disk full error naming the budget", err)
+	}
+
+	janitor = NewJanitor(0, 1<<62, time.Hour)
+	janitor.AddArea(Area{Name: "nuclei_templates", Dir: cache})
+	if err := janitor.CheckSpace(); !common.IsDiskFull(err) || !strings.Contains(err.Error(), "free on the disk of nuclei_templates") {
+		t.Errorf("CheckSpace() = %v, want a disk full error naming the free space", err)
+	}
+
+	if err := NewJanitor(0, 0, time.Hour).CheckSpace(); err != nil {
+		t.Errorf("CheckSpace() without limits = %v", err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 2 << 30: "2.0 GiB"} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// Type is the kind of an event
+type Type string
+
+const (
+	TypeLifecycle      Type = "lifecycle"       // A task step, e.g. task_started or task_completed
+	TypeProgress       Type = "progress"        // Live progress of a running scanner, at most once a second
+	TypeProgressReport Type = "progress_report" // Latest progress of a running scanner, every PROGRESS_INTERVAL
+	TypeResult         Type = "result"          // A task result was stored
+)
+
+// Event is a task lifecycle step, progress update or stored result published on the bus
+type Event struct {
+	Type          Type                 `json:"type"`
+	Step          string               `json:"step,omitempty"`
+	Task          string               `json:"task"`
+	ScanID        int                  `json:"scan_id"`
+	TenantID      string               `json:"tenant_id,omitempty"`
+	Domain        string               `json:"domain"`
+	Status        string               `json:"status,omitempty"`
+	Error         string               `json:"error,omitempty"`
+	Progress      *models.ScanProgress `json:"progress,omitempty"`
+	BlobPath      string               `json:"blob_path,omitempty"`
+	CorrelationID string               `json:"correlation_id,omitempty"`
+	Time          time.Time            `json:"time"`
+
+	// Subscribers get copies of the task message and result as they were when the event was published
+	TaskMessage *models.TaskMessage `json:"-"`
+	Result      *models.TaskResult  `json:"-"`
+	Err         error               `json:"-"`
+	Elapsed     time.Duration       `json:"-"` // Time since the task started, for progress reports
+}
+
+// Bus fans events out to handlers and subscriptions. Publishing never blocks: every handler
+// has a bounded queue worked off by its own goroutine, and a full queue drops events by the
+// handler's policy, so a slow webhook cannot hold up the task that published the event.
+type Bus struct {
+	mu       sync.RWMutex
+	subs     map[*Subscription]struct{}
+	handlers []*handler
+	closed   bool
+
+	dropped *metrics.Counter
+}
+
+// Subscription receives the events that match its filter until it is closed
+type Subscription struct {
+	bus     *Bus
+	events  chan Event
+	filter  func(Event) bool
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// SetMetrics sets the registry the dropped event counter is published in
+func (b *Bus) SetMetrics(registry *metrics.Registry) {
+	b.dropped = registry.Counter("asm_event_bus_dropped_total", "Events dropped because a subscriber's queue was full.", "subscriber")
+}
+
+// Subscribe receives the events for which filter returns true, or all events for a nil filter,
+// buffering up to buffer of them
+func (b *Bus) Subscribe(buffer int, filter func(Event) bool) *Subscription {
+	sub := &Subscription{bus: b, events: make(chan Event, buffer), filter: filter}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish delivers an event to the matching handlers and subscriptions
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+
+	for _, h := range b.handlers {
+		if h.options.Filter == nil || h.options.Filter(event) {
+			h.enqueue(event)
+		}
+	}
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// RecordStep publishes a task step as a lifecycle event
+func (b *Bus) RecordStep(taskMsg *models.TaskMessage, step string, result *models.TaskResult, err error) {
+	event := newEvent(TypeLifecycle, taskMsg)
+	event.Step = step
+	if result != nil {
+		resultCopy := *result
+		event.Result = &resultCopy
+		event.Status = string(result.Status)
+	}
+	if err != nil {
+		event.Err = err
+		event.Error = err.Error()
+	}
+	b.Publish(event)
+}
+
+// RecordProgress publishes the live progress of a running scanner
+func (b *Bus) RecordProgress(taskMsg *models.TaskMessage, progress models.ScanProgress) {
+	event := newEvent(TypeProgress, taskMsg)
+	event.Progress = &progress
+	b.Publish(event)
+}
+
+// RecordProgressReport publishes the periodic progress report of a running scanner
+func (b *Bus) RecordProgressReport(taskMsg *models.TaskMessage, progress models.ScanProgress, elapsed time.Duration) {
+	event := newEvent(TypeProgressReport, taskMsg)
+	event.Progress = &progress
+	event.Elapsed = elapsed
+	b.Publish(event)
+}
+
+// RecordResult publishes a stored task result
+func (b *Bus) RecordResult(taskMsg *models.TaskMessage, result *models.TaskResult, blobPath string) {
+	b.Publish(NewResultEvent(taskMsg, result, blobPath))
+}
+
+// NewResultEvent returns the event of a stored task result, for consumers that receive results
+// without the bus
+func NewResultEvent(taskMsg *models.TaskMessage, result *models.TaskResult, blobPath string) Event {
+	event := newEvent(TypeResult, taskMsg)
+	resultCopy := *result
+	event.Result = &resultCopy
+	event.Status = string(result.Status)
+	event.BlobPath = blobPath
+	return event
+}
+
+// newEvent creates an event about a task
+func newEvent(eventType Type, taskMsg *models.TaskMessage) Event {
+	msgCopy := *taskMsg
+	return Event{
+		Type:          eventType,
+		Task:          string(taskMsg.Task),
+		ScanID:        taskMsg.ScanID,
+		TenantID:      taskMsg.TenantID,
+		Domain:        taskMsg.Domain,
+		CorrelationID: taskMsg.CorrelationID,
+		TaskMessage:   &msgCopy,
+	}
+}
+
+// Close stops accepting events and waits until the handlers have worked off their queues,
+// giving up when ctx expires
+func (b *Bus) Close(ctx context.Context) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	handlers := b.handlers
+	for _, h := range handlers {
+		close(h.queue)
+	}
+	b.mu.Unlock()
+
+	for _, h := range handlers {
+		select {
+		case <-h.done:
+		case <-ctx.Done():
+			gologger.Warning().Msgf("Gave up waiting for %d queued %s events", len(h.queue), h.name)
+			return
+		}
+	}
+}
+
+// Events returns the channel the subscription receives on; it is closed by Close
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events missed because the buffer was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Close stops the subscription and closes its channel
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.events)
+	})
+}
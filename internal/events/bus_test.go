@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestBus_PublishFiltersAndDrops(t *testing.T) {
+	bus := NewBus()
+	scan7 := bus.Subscribe(1, func(event Event) bool { return event.ScanID == 7 })
+	all := bus.Subscribe(10, nil)
+
+	bus.RecordStep(&models.TaskMessage{Task: models.TaskSubfinder, ScanID: 7, Domain: "example.com"}, "task_started", nil, nil)
+	bus.RecordStep(&models.TaskMessage{Task: models.TaskSubfinder, ScanID: 8, Domain: "example.org"}, "task_failed", nil, errors.New("boom"))
+	bus.RecordProgress(&models.TaskMessage{Task: models.TaskSubfinder, ScanID: 7, Domain: "example.com"}, models.ScanProgress{Phase: "subfinder", Processed: 1, Total: 2})
+
+	event := <-scan7.Events()
+	if event.Type != TypeLifecycle || event.Step != "task_started" || event.Time.IsZero() {
+		t.Errorf("Expected the started step of scan 7, got %+v", event)
+	}
+	if scan7.Dropped() != 1 {
+		t.Errorf("Expected the progress event dropped from the full buffer, got %d dropped", scan7.Dropped())
+	}
+	if len(all.Events()) != 3 {
+		t.Errorf("Expected the unfiltered subscriber to get all 3 events, got %d", len(all.Events()))
+	}
+
+	scan7.Close()
+	scan7.Close()
+	if _, open := <-scan7.Events(); open {
+		t.Error("Expected the closed subscription's channel closed")
+	}
+	bus.RecordStep(&models.TaskMessage{ScanID: 7}, "task_completed", nil, nil)
+}
+
+func TestBus_HandlersRunAsync(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var handled []string
+	bus.Handle("slow", HandlerOptions{Buffer: 2}, func(ctx context.Context, event Event) {
+		<-release
+		mu.Lock()
+		handled = append(handled, event.Step)
+		mu.Unlock()
+	})
+	bus.Handle("latest", HandlerOptions{Buffer: 1, Policy: DropOldest}, func(ctx context.Context, event Event) {
+		<-release
+		mu.Lock()
+		handled = append(handled, "latest:"+event.Step)
+		mu.Unlock()
+	})
+	bus.Handle("panics", HandlerOptions{}, func(ctx context.Context, event Event) {
+		if models.CorrelationIDFromContext(ctx) != "corr-1" {
+			t.Errorf("Expected the handler context to carry the correlation ID")
+		}
+		panic("boom")
+	})
+
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 1, Domain: "example.com", CorrelationID: "corr-1"}
+	start := time.Now()
+	for _, step := range []string{"a", "b", "c", "d", "e"} {
+		bus.RecordStep(taskMsg, step, nil, nil)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("Expected publishing not to wait for blocked handlers")
+	}
+
+	close(release)
+	bus.Close(context.Background())
+	bus.RecordStep(taskMsg, "after-close", nil, nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var slow, latest []string
+	for _, step := range handled {
+		if rest, ok := strings.CutPrefix(step, "latest:"); ok {
+			latest = append(latest, rest)
+		} else {
+			slow = append(slow, step)
+		}
+	}
+	// The slow handler keeps the two events that fit its queue, plus "a" if it took it off the queue before blocking
+	if got := strings.Join(slow, ""); got != "ab" && got != "abc" {
+		t.Errorf("Expected the drop-newest handler to keep the earliest events in order, got %v", slow)
+	}
+	if len(latest) == 0 || latest[len(latest)-1] != "e" {
+		t.Errorf("Expected the drop-oldest handler to keep the latest event, got %v", latest)
+	}
+}
@@ -0,0 +1,123 @@
+package events
+
+import (
+	"context"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	defaultHandlerBuffer  = 256
+	defaultHandlerTimeout = 2 * time.Minute
+)
+
+// DropPolicy decides which event a handler loses when its queue is full
+type DropPolicy int
+
+const (
+	DropNewest DropPolicy = iota // The new event is dropped, keeping the queued ones
+	DropOldest                   // The oldest queued event is dropped to make room for the new one
+)
+
+// HandlerOptions configure how a handler receives events
+type HandlerOptions struct {
+	Filter  func(Event) bool // Events the handler receives; nil receives all events
+	Buffer  int              // Events queued before the drop policy applies; default 256
+	Policy  DropPolicy
+	Timeout time.Duration // Bound of a single handler call; default 2 minutes
+}
+
+// handler is an asynchronous subscriber with its own queue and goroutine
+type handler struct {
+	bus     *Bus
+	name    string
+	options HandlerOptions
+	handle  func(ctx context.Context, event Event)
+
+	queue   chan Event
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// Handle registers a handler that receives events one at a time, in publish order, on its own
+// goroutine. The context it is called with carries the event's correlation ID.
+func (b *Bus) Handle(name string, options HandlerOptions, handle func(ctx context.Context, event Event)) {
+	if options.Buffer <= 0 {
+		options.Buffer = defaultHandlerBuffer
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultHandlerTimeout
+	}
+
+	h := &handler{
+		bus:     b,
+		name:    name,
+		options: options,
+		handle:  handle,
+		queue:   make(chan Event, options.Buffer),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(h.queue)
+	}
+	b.handlers = append(b.handlers, h)
+	go h.run()
+}
+
+// enqueue queues an event, dropping one by the handler's policy when the queue is full.
+// The caller holds the bus's read lock, so the queue is not closed concurrently.
+func (h *handler) enqueue(event Event) {
+	select {
+	case h.queue <- event:
+		return
+	default:
+	}
+
+	if h.options.Policy == DropOldest {
+		select {
+		case <-h.queue:
+		default:
+		}
+		select {
+		case h.queue <- event:
+		default:
+		}
+	}
+	h.drop()
+}
+
+// drop counts a dropped event, warning on the first and every hundredth
+func (h *handler) drop() {
+	h.bus.dropped.Inc(h.name)
+	if dropped := h.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+		gologger.Warning().Msgf("Event handler %s is falling behind, %d events dropped so far", h.name, dropped)
+	}
+}
+
+// run handles queued events until the queue is closed
+func (h *handler) run() {
+	defer close(h.done)
+	for event := range h.queue {
+		h.call(event)
+	}
+}
+
+// call handles one event, recovering from panics so one bad event does not stop the handler
+func (h *handler) call(event Event) {
+	ctx, cancel := context.WithTimeout(models.WithCorrelationID(context.Background(), event.CorrelationID), h.options.Timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			gologger.Error().Msgf("Event handler %s panicked on %s event: %v\n%s", h.name, event.Type, r, debug.Stack())
+		}
+	}()
+
+	h.handle(ctx, event)
+}
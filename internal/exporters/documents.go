@@ -0,0 +1,192 @@
+package exporters
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Document kinds produced when flattening scan results
+const (
+	KindSubdomain     = "subdomain"
+	KindPort          = "port"
+	KindHTTPService   = "http_service"
+	KindVulnerability = "vulnerability"
+)
+
+// ResultExporter ships stored task results to an external system
+type ResultExporter interface {
+	Name() string
+	Export(ctx context.Context, result *models.TaskResult) error
+}
+
+// Document is one asset or finding of a task result, flattened so that search and SIEM
+// backends can index it without understanding the scanner specific result layout
+type Document struct {
+	Timestamp string `json:"@timestamp"`
+	Kind      string `json:"kind"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	ScanID    int    `json:"scan_id"`
+	Domain    string `json:"domain"`
+	Task      string `json:"task"`
+	Partial   bool   `json:"partial,omitempty"` // True when the scan was cut short
+
+	Host string `json:"host,omitempty"`
+	IP   string `json:"ip,omitempty"`
+
+	// Subdomain resolution
+	DNSStatus string   `json:"dns_status,omitempty"`
+	A         []string `json:"a,omitempty"`
+	AAAA      []string `json:"aaaa,omitempty"`
+	CNAME     []string `json:"cname,omitempty"`
+	Dangling  bool     `json:"dangling,omitempty"`
+
+	// Open ports
+	Port     int    `json:"port,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Service  string `json:"service,omitempty"`
+
+	// HTTP services
+	URL           string   `json:"url,omitempty"`
+	StatusCode    int      `json:"status_code,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	WebServer     string   `json:"web_server,omitempty"`
+	ContentType   string   `json:"content_type,omitempty"`
+	ContentLength int      `json:"content_length,omitempty"`
+	Technologies  []string `json:"technologies,omitempty"`
+	ASN           string   `json:"asn,omitempty"`
+
+	// Vulnerabilities
+	TemplateID   string   `json:"template_id,omitempty"`
+	TemplateName string   `json:"template_name,omitempty"`
+	Severity     string   `json:"severity,omitempty"`
+	MatchedAt    string   `json:"matched_at,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	CVEIDs       []string `json:"cve_ids,omitempty"`
+	CWEIDs       []string `json:"cwe_ids,omitempty"`
+	CVSSScore    float64  `json:"cvss_score,omitempty"`
+	EPSSScore    float64  `json:"epss_score,omitempty"`
+
+	key string // Identifies the asset or finding within its scan
+}
+
+// ID returns a stable identifier of the document, so re-exporting a result overwrites
+// the documents of the first export instead of duplicating them
+func (d Document) ID() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{d.TenantID, fmt.Sprintf("%d", d.ScanID), d.Task, d.Kind, d.key}, "|")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// FlattenResult turns a task result into one document per subdomain, open port, HTTP service
+// or vulnerability. Results without a known scanner payload produce no documents.
+func FlattenResult(result *models.TaskResult) []Document {
+	base := Document{
+		Timestamp: resultTime(result).Format(time.RFC3339),
+		TenantID:  result.TenantID,
+		ScanID:    result.ScanID,
+		Domain:    result.Domain,
+		Task:      string(result.Task),
+		Partial:   result.Status == models.TaskStatusPartial,
+	}
+
+	var docs []Document
+	switch data := result.Data.(type) {
+	case models.SubfinderResult:
+		for _, subdomain := range data.Subdomains {
+			doc := base
+			doc.Kind, doc.Host, doc.key = KindSubdomain, subdomain, subdomain
+			docs = append(docs, doc)
+		}
+
+	case models.DNSXResult:
+		for _, host := range sortedKeys(data.Records) {
+			record := data.Records[host]
+			doc := base
+			doc.Kind, doc.Host, doc.key = KindSubdomain, host, host
+			doc.DNSStatus = string(record.Status)
+			doc.A, doc.AAAA, doc.CNAME = record.A, record.AAAA, record.CNAME
+			doc.Dangling = record.Dangling
+			docs = append(docs, doc)
+		}
+
+	case models.NaabuResult:
+		for _, ip := range sortedKeys(data.Ports) {
+			for _, port := range data.Ports[ip] {
+				doc := base
+				doc.Kind, doc.IP = KindPort, ip
+				doc.Port, doc.Protocol, doc.Service = port.Port, port.Protocol, port.Service
+				doc.key = fmt.Sprintf("%s/%s/%d", ip, port.Protocol, port.Port)
+				docs = append(docs, doc)
+			}
+		}
+
+	case models.HttpxResult:
+		for _, service := range data.Results {
+			doc := base
+			doc.Kind, doc.Host, doc.key = KindHTTPService, service.Host, service.URL
+			doc.URL, doc.StatusCode, doc.Title = service.URL, service.StatusCode, service.Title
+			doc.Port = urlPort(service.URL)
+			doc.WebServer, doc.ContentType, doc.ContentLength = service.WebServer, service.ContentType, service.ContentLength
+			doc.Technologies, doc.ASN = service.Technologies, service.ASN
+			docs = append(docs, doc)
+		}
+
+	case models.NucleiResult:
+		for _, vuln := range data.Vulnerabilities {
+			doc := base
+			doc.Kind, doc.Host = KindVulnerability, vuln.Host
+			doc.TemplateID, doc.TemplateName, doc.Severity = vuln.TemplateID, vuln.Name, vuln.Severity
+			doc.MatchedAt, doc.Tags = vuln.MatchedAt, vuln.Tags
+			doc.CVEIDs, doc.CWEIDs = vuln.CVEIDs, vuln.CWEIDs
+			doc.CVSSScore, doc.EPSSScore = vuln.CVSSScore, vuln.EPSSScore
+			doc.key = vuln.TemplateID + "|" + vuln.MatchedAt
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs
+}
+
+// resultTime returns when a result was produced, falling back to now for unparsable timestamps
+func resultTime(result *models.TaskResult) time.Time {
+	if ts, err := time.Parse(time.RFC3339, result.Timestamp); err == nil {
+		return ts.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// urlPort returns the port a URL points at, or 0 when it has none
+func urlPort(rawURL string) int {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	if port, err := strconv.Atoi(parsed.Port()); err == nil {
+		return port
+	}
+	switch parsed.Scheme {
+	case "http":
+		return 80
+	case "https":
+		return 443
+	}
+	return 0
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,139 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// elasticsearchBulkSize is the number of documents sent per bulk request
+const elasticsearchBulkSize = 500
+
+// ElasticsearchConfig holds the connection settings of an Elasticsearch or OpenSearch cluster
+type ElasticsearchConfig struct {
+	URL         string // Base URL of the cluster
+	APIKey      string // Encoded API key; takes precedence over basic auth
+	Username    string
+	Password    string
+	IndexPrefix string // Indices are named <prefix>-<kind>-YYYY.MM
+}
+
+// ElasticsearchExporter indexes flattened results into Elasticsearch or OpenSearch through the
+// bulk API. Documents go to one index per kind and month, so index templates and ILM policies
+// can match on "<prefix>-<kind>-*" and old months can be rolled off as a whole.
+type ElasticsearchExporter struct {
+	config     ElasticsearchConfig
+	httpClient *http.Client
+}
+
+// NewElasticsearchExporter creates an exporter for the given cluster
+func NewElasticsearchExporter(config ElasticsearchConfig, timeout time.Duration) *ElasticsearchExporter {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.IndexPrefix == "" {
+		config.IndexPrefix = "asm"
+	}
+	return &ElasticsearchExporter{config: config, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// Name returns the exporter name
+func (e *ElasticsearchExporter) Name() string {
+	return "elasticsearch"
+}
+
+// Export indexes the documents of a task result
+func (e *ElasticsearchExporter) Export(ctx context.Context, result *models.TaskResult) error {
+	docs := FlattenResult(result)
+	for start := 0; start < len(docs); start += elasticsearchBulkSize {
+		end := min(start+elasticsearchBulkSize, len(docs))
+		if err := e.bulk(ctx, docs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexName returns the monthly index a document is written to
+func (e *ElasticsearchExporter) IndexName(doc Document) string {
+	month := doc.Timestamp
+	if ts, err := time.Parse(time.RFC3339, doc.Timestamp); err == nil {
+		month = ts.UTC().Format("2006.01")
+	}
+	return fmt.Sprintf("%s-%s-%s", e.config.IndexPrefix, doc.Kind, month)
+}
+
+// bulkResponse is the part of the bulk API response needed to detect rejected documents
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error,omitempty"`
+	} `json:"items"`
+}
+
+// bulk sends one batch of documents and fails when any of them is rejected
+func (e *ElasticsearchExporter) bulk(ctx context.Context, docs []Document) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		action := map[string]map[string]string{"index": {"_index": e.IndexName(doc), "_id": doc.ID()}}
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return fmt.Errorf("failed to encode document: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL+"/_bulk", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	switch {
+	case e.config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.config.APIKey)
+	case e.config.Username != "":
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send bulk request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request failed with status %d", resp.StatusCode)
+	}
+
+	var parsed bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !parsed.Errors {
+		return nil
+	}
+
+	failed, reason := 0, ""
+	for _, item := range parsed.Items {
+		for _, status := range item {
+			if status.Error != nil {
+				failed++
+				if reason == "" {
+					reason = status.Error.Type + ": " + status.Error.Reason
+				}
+			}
+		}
+	}
+	return fmt.Errorf("%d of %d documents were rejected (%s)", failed, len(docs), reason)
+}
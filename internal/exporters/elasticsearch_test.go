@@ -0,0 +1,122 @@
+package exporters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestFlattenResult(t *testing.T) {
+	result := &models.TaskResult{
+		Task:      models.TaskNaabu,
+		ScanID:    7,
+		Domain:    "example.com",
+		TenantID:  "acme",
+		Status:    models.TaskStatusPartial,
+		Timestamp: "2024-03-31T23:30:00-02:00",
+		Data: models.NaabuResult{
+			Domain: "example.com",
+			Ports: map[string][]models.PortInfo{
+				"192.0.2.2": {{Port: 443, Protocol: "tcp"}},
+				"192.0.2.1": {{Port: 22, Protocol: "tcp", Service: "ssh"}, {Port: 80, Protocol: "tcp"}},
+			},
+		},
+	}
+
+	docs := FlattenResult(result)
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 documents, got %d", len(docs))
+	}
+	first := docs[0]
+	if first.Kind != KindPort || first.IP != "192.0.2.1" || first.Port != 22 || first.Service != "ssh" {
+		t.Errorf("Unexpected first document: %+v", first)
+	}
+	if first.Timestamp != "2024-04-01T01:30:00Z" {
+		t.Errorf("Expected timestamp in UTC, got %s", first.Timestamp)
+	}
+	if !first.Partial || first.TenantID != "acme" || first.ScanID != 7 || first.Task != string(models.TaskNaabu) {
+		t.Errorf("Expected task metadata on every document, got %+v", first)
+	}
+
+	again := FlattenResult(result)
+	if docs[1].ID() != again[1].ID() {
+		t.Error("Expected document IDs to be stable across exports")
+	}
+	if docs[0].ID() == docs[1].ID() {
+		t.Error("Expected different ports to get different document IDs")
+	}
+}
+
+func TestElasticsearchExporter_Export(t *testing.T) {
+	var lines []string
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("Expected bulk endpoint, got %s", r.URL.Path)
+		}
+		auth = r.Header.Get("Authorization")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	exporter := NewElasticsearchExporter(ElasticsearchConfig{URL: server.URL + "/", APIKey: "secret"}, 5*time.Second)
+	result := &models.TaskResult{
+		Task:      models.TaskNuclei,
+		ScanID:    1,
+		Domain:    "example.com",
+		Timestamp: "2024-03-04T12:00:00Z",
+		Data: models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{
+			{TemplateID: "CVE-2021-44228", Host: "app.example.com", MatchedAt: "https://app.example.com", Severity: "critical", CVEIDs: []string{"CVE-2021-44228"}},
+		}},
+	}
+
+	if err := exporter.Export(context.Background(), result); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if auth != "ApiKey secret" {
+		t.Errorf("Expected API key authorization, got %q", auth)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("Expected an action and a document line, got %d lines", len(lines))
+	}
+
+	var action map[string]map[string]string
+	if err := json.Unmarshal([]byte(lines[0]), &action); err != nil {
+		t.Fatalf("Failed to parse bulk action: %v", err)
+	}
+	if index := action["index"]["_index"]; index != "asm-vulnerability-2024.03" {
+		t.Errorf("Expected monthly vulnerability index, got %s", index)
+	}
+	if !strings.Contains(lines[1], `"template_id":"CVE-2021-44228"`) || !strings.Contains(lines[1], `"@timestamp":"2024-03-04T12:00:00Z"`) {
+		t.Errorf("Unexpected document: %s", lines[1])
+	}
+}
+
+func TestElasticsearchExporter_RejectedDocuments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}]}`))
+	}))
+	defer server.Close()
+
+	exporter := NewElasticsearchExporter(ElasticsearchConfig{URL: server.URL}, 5*time.Second)
+	result := &models.TaskResult{
+		Task: models.TaskSubfinder,
+		Data: models.SubfinderResult{Subdomains: []string{"a.example.com"}},
+	}
+
+	err := exporter.Export(context.Background(), result)
+	if err == nil || !strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("Expected rejected document error, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+const (
+	logAnalyticsAPIVersion = "2023-01-01"
+	logAnalyticsScope      = "https://monitor.azure.com/.default"
+	// logAnalyticsMaxBatchBytes keeps each upload under the 1 MB limit of the Logs Ingestion API
+	logAnalyticsMaxBatchBytes = 900 * 1024
+)
+
+// LogAnalyticsConfig identifies the data collection rule stream findings are sent to
+type LogAnalyticsConfig struct {
+	Endpoint string // Data collection endpoint, e.g. https://asm-dce.westeurope-1.ingest.monitor.azure.com
+	RuleID   string // Immutable ID of the data collection rule
+	Stream   string // Stream declared in the rule, e.g. Custom-ASMFindings_CL
+}
+
+// logAnalyticsRecord is a document with the TimeGenerated column every Log Analytics table requires
+type logAnalyticsRecord struct {
+	TimeGenerated string `json:"TimeGenerated"`
+	Document
+}
+
+// LogAnalyticsExporter sends flattened results to a Log Analytics custom table through the Logs
+// Ingestion API, so Sentinel analytic rules and workbooks can query the ASM data. The data
+// collection rule maps the records onto the table and can drop or transform columns.
+type LogAnalyticsExporter struct {
+	config     LogAnalyticsConfig
+	credential azcore.TokenCredential
+	httpClient *http.Client
+}
+
+// NewLogAnalyticsExporter creates an exporter authenticating with the default Azure credential
+// chain (environment, workload identity or managed identity)
+func NewLogAnalyticsExporter(config LogAnalyticsConfig, timeout time.Duration) (*LogAnalyticsExporter, error) {
+	credential, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	return newLogAnalyticsExporter(config, credential, timeout), nil
+}
+
+// newLogAnalyticsExporter creates an exporter with the given credential
+func newLogAnalyticsExporter(config LogAnalyticsConfig, credential azcore.TokenCredential, timeout time.Duration) *LogAnalyticsExporter {
+	config.Endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	return &LogAnalyticsExporter{config: config, credential: credential, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// Name returns the exporter name
+func (e *LogAnalyticsExporter) Name() string {
+	return "loganalytics"
+}
+
+// Export uploads the documents of a task result in batches that fit the ingestion limits
+func (e *LogAnalyticsExporter) Export(ctx context.Context, result *models.TaskResult) error {
+	docs := FlattenResult(result)
+	if len(docs) == 0 {
+		return nil
+	}
+
+	token, err := e.credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{logAnalyticsScope}})
+	if err != nil {
+		return fmt.Errorf("failed to get Azure Monitor token: %w", err)
+	}
+
+	var batch []json.RawMessage
+	size := 0
+	for _, doc := range docs {
+		record, err := json.Marshal(logAnalyticsRecord{TimeGenerated: doc.Timestamp, Document: doc})
+		if err != nil {
+			return fmt.Errorf("failed to encode record: %w", err)
+		}
+		if len(batch) > 0 && size+len(record)+1 > logAnalyticsMaxBatchBytes {
+			if err := e.upload(ctx, token.Token, batch); err != nil {
+				return err
+			}
+			batch, size = nil, 0
+		}
+		batch = append(batch, record)
+		size += len(record) + 1
+	}
+	return e.upload(ctx, token.Token, batch)
+}
+
+// upload sends one batch of records to the data collection rule stream
+func (e *LogAnalyticsExporter) upload(ctx context.Context, token string, records []json.RawMessage) error {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode records: %w", err)
+	}
+
+	uploadURL := fmt.Sprintf("%s/dataCollectionRules/%s/streams/%s?api-version=%s",
+		e.config.Endpoint, url.PathEscape(e.config.RuleID), url.PathEscape(e.config.Stream), logAnalyticsAPIVersion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send upload request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("upload request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
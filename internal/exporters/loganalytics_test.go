@@ -0,0 +1,94 @@
+package exporters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// staticCredential returns a fixed token
+type staticCredential struct{}
+
+func (staticCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestLogAnalyticsExporter_Export(t *testing.T) {
+	var records []map[string]interface{}
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if r.URL.Query().Get("api-version") != logAnalyticsAPIVersion {
+			t.Errorf("Expected api-version %s, got %s", logAnalyticsAPIVersion, r.URL.RawQuery)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			t.Errorf("Failed to decode records: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exporter := newLogAnalyticsExporter(LogAnalyticsConfig{
+		Endpoint: server.URL,
+		RuleID:   "dcr-123",
+		Stream:   "Custom-ASMFindings_CL",
+	}, staticCredential{}, 5*time.Second)
+
+	result := &models.TaskResult{
+		Task:      models.TaskHttpx,
+		ScanID:    3,
+		Domain:    "example.com",
+		Timestamp: "2024-03-04T12:00:00Z",
+		Data: models.HttpxResult{Results: []models.HttpxHostResult{
+			{Host: "app.example.com", URL: "https://app.example.com", StatusCode: 200, Technologies: []string{"nginx"}},
+		}},
+	}
+
+	if err := exporter.Export(context.Background(), result); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if path != "/dataCollectionRules/dcr-123/streams/Custom-ASMFindings_CL" {
+		t.Errorf("Unexpected ingestion path %s", path)
+	}
+	if auth != "Bearer token" {
+		t.Errorf("Expected bearer token, got %q", auth)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if records[0]["TimeGenerated"] != "2024-03-04T12:00:00Z" || records[0]["kind"] != KindHTTPService {
+		t.Errorf("Unexpected record: %v", records[0])
+	}
+}
+
+func TestLogAnalyticsExporter_Batches(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	exporter := newLogAnalyticsExporter(LogAnalyticsConfig{Endpoint: server.URL, RuleID: "dcr", Stream: "Custom-T_CL"}, staticCredential{}, 5*time.Second)
+
+	subdomains := make([]string, 5000)
+	for i := range subdomains {
+		subdomains[i] = strings.Repeat("a", 200) + ".example.com"
+	}
+	result := &models.TaskResult{Task: models.TaskSubfinder, Data: models.SubfinderResult{Subdomains: subdomains}}
+
+	if err := exporter.Export(context.Background(), result); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if requests < 2 {
+		t.Errorf("Expected the records to be split over several uploads, got %d", requests)
+	}
+}
@@ -0,0 +1,197 @@
+package exporters
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// nmapXMLHeader precedes the nmaprun element in files written by nmap
+const nmapXMLHeader = xml.Header + "<!DOCTYPE nmaprun>\n"
+
+// nmapRun is the root element of an nmap XML report
+type nmapRun struct {
+	XMLName          xml.Name     `xml:"nmaprun"`
+	Scanner          string       `xml:"scanner,attr"`
+	Args             string       `xml:"args,attr"`
+	Start            int64        `xml:"start,attr"`
+	StartStr         string       `xml:"startstr,attr"`
+	Version          string       `xml:"version,attr"`
+	XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+	ScanInfo         nmapScanInfo `xml:"scaninfo"`
+	Hosts            []nmapHost   `xml:"host"`
+	RunStats         nmapRunStats `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapHost struct {
+	StartTime int64       `xml:"starttime,attr"`
+	EndTime   int64       `xml:"endtime,attr"`
+	Status    nmapStatus  `xml:"status"`
+	Address   nmapAddress `xml:"address"`
+	Ports     nmapPorts   `xml:"ports"`
+}
+
+type nmapStatus struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapPorts struct {
+	Ports []nmapPort `xml:"port"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapStatus   `xml:"state"`
+	Service  *nmapService `xml:"service,omitempty"`
+}
+
+type nmapService struct {
+	Name   string `xml:"name,attr"`
+	Method string `xml:"method,attr"`
+	Conf   int    `xml:"conf,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time     int64   `xml:"time,attr"`
+	TimeStr  string  `xml:"timestr,attr"`
+	Elapsed  float64 `xml:"elapsed,attr"`
+	Summary  string  `xml:"summary,attr"`
+	Exit     string  `xml:"exit,attr"`
+	ErrorMsg string  `xml:"errormsg,attr,omitempty"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// nmapTimeFormat is the layout nmap uses for its human readable timestamps
+const nmapTimeFormat = "Mon Jan 2 15:04:05 2006"
+
+// NaabuToNmapXML renders a naabu result as an nmap XML report, so that nmap parsers,
+// Metasploit's db_import and vulnerability scanners can consume the port data.
+// Hosts and ports are sorted to keep the output stable.
+func NaabuToNmapXML(result models.NaabuResult, startedAt, finishedAt time.Time) ([]byte, error) {
+	ips := make([]string, 0, len(result.Ports))
+	for ip := range result.Ports {
+		ips = append(ips, ip)
+	}
+	sort.Strings(ips)
+
+	services := make(map[int]bool)
+	run := nmapRun{
+		Scanner:          "naabu",
+		Args:             "naabu -scan-type s",
+		Start:            startedAt.Unix(),
+		StartStr:         startedAt.UTC().Format(nmapTimeFormat),
+		XMLOutputVersion: "1.05",
+	}
+
+	for _, ip := range ips {
+		ports := append([]models.PortInfo(nil), result.Ports[ip]...)
+		sort.Slice(ports, func(i, j int) bool {
+			if ports[i].Port != ports[j].Port {
+				return ports[i].Port < ports[j].Port
+			}
+			return ports[i].Protocol < ports[j].Protocol
+		})
+
+		host := nmapHost{
+			StartTime: startedAt.Unix(),
+			EndTime:   finishedAt.Unix(),
+			Status:    nmapStatus{State: "up", Reason: "syn-ack"},
+			Address:   nmapAddress{Addr: ip, AddrType: addressType(ip)},
+		}
+		for _, port := range ports {
+			services[port.Port] = true
+			entry := nmapPort{
+				Protocol: strings.ToLower(port.Protocol),
+				PortID:   port.Port,
+				State:    nmapStatus{State: "open", Reason: "syn-ack"},
+			}
+			if entry.Protocol == "" {
+				entry.Protocol = "tcp"
+			}
+			if port.Service != "" {
+				entry.Service = &nmapService{Name: port.Service, Method: "table", Conf: 3}
+			}
+			host.Ports.Ports = append(host.Ports.Ports, entry)
+		}
+		run.Hosts = append(run.Hosts, host)
+	}
+
+	run.ScanInfo = nmapScanInfo{Type: "syn", Protocol: "tcp", NumServices: len(services), Services: portList(services)}
+
+	elapsed := finishedAt.Sub(startedAt).Seconds()
+	run.RunStats = nmapRunStats{
+		Finished: nmapFinished{
+			Time:    finishedAt.Unix(),
+			TimeStr: finishedAt.UTC().Format(nmapTimeFormat),
+			Elapsed: elapsed,
+			Summary: fmt.Sprintf("naabu done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds",
+				finishedAt.UTC().Format(nmapTimeFormat), len(ips)+result.UnresponsiveHosts, len(ips), elapsed),
+			Exit: "success",
+		},
+		Hosts: nmapHostStats{Up: len(ips), Down: result.UnresponsiveHosts, Total: len(ips) + result.UnresponsiveHosts},
+	}
+	if result.Partial {
+		run.RunStats.Finished.Exit = "error"
+		run.RunStats.Finished.ErrorMsg = "scan interrupted before completion"
+	}
+
+	body, err := xml.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal nmap XML: %w", err)
+	}
+	return append([]byte(nmapXMLHeader), append(body, '\n')...), nil
+}
+
+// addressType returns the nmap address type of an IP
+func addressType(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
+// portList formats a set of ports as a sorted comma separated list
+func portList(ports map[int]bool) string {
+	sorted := make([]int, 0, len(ports))
+	for port := range ports {
+		sorted = append(sorted, port)
+	}
+	sort.Ints(sorted)
+
+	parts := make([]string, len(sorted))
+	for i, port := range sorted {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ",")
+}
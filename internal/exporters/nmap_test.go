@@ -0,0 +1,76 @@
+package exporters
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestNaabuToNmapXML(t *testing.T) {
+	startedAt := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(90 * time.Second)
+
+	result := models.NaabuResult{
+		Domain: "example.com",
+		Ports: map[string][]models.PortInfo{
+			"192.0.2.20":  {{Port: 443, Protocol: "tcp"}, {Port: 22, Protocol: "tcp", Service: "ssh"}},
+			"2001:db8::1": {{Port: 80, Protocol: "tcp"}},
+		},
+		UnresponsiveHosts: 3,
+	}
+
+	data, err := NaabuToNmapXML(result, startedAt, finishedAt)
+	if err != nil {
+		t.Fatalf("NaabuToNmapXML() error = %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header+"<!DOCTYPE nmaprun>") {
+		t.Errorf("Expected XML header and nmaprun doctype, got: %.80s", data)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		t.Fatalf("Failed to parse generated XML: %v", err)
+	}
+
+	if len(run.Hosts) != 2 {
+		t.Fatalf("Expected 2 hosts, got %d", len(run.Hosts))
+	}
+	host := run.Hosts[0]
+	if host.Address.Addr != "192.0.2.20" || host.Address.AddrType != "ipv4" {
+		t.Errorf("Unexpected first host address: %+v", host.Address)
+	}
+	if len(host.Ports.Ports) != 2 || host.Ports.Ports[0].PortID != 22 || host.Ports.Ports[0].Service == nil {
+		t.Errorf("Expected ports sorted with the ssh service first, got %+v", host.Ports.Ports)
+	}
+	if run.Hosts[1].Address.AddrType != "ipv6" {
+		t.Errorf("Expected IPv6 address type, got %s", run.Hosts[1].Address.AddrType)
+	}
+	if run.ScanInfo.Services != "22,80,443" || run.ScanInfo.NumServices != 3 {
+		t.Errorf("Unexpected scaninfo: %+v", run.ScanInfo)
+	}
+	if run.RunStats.Hosts.Up != 2 || run.RunStats.Hosts.Down != 3 || run.RunStats.Finished.Exit != "success" {
+		t.Errorf("Unexpected runstats: %+v", run.RunStats)
+	}
+	if run.RunStats.Finished.Elapsed != 90 {
+		t.Errorf("Expected 90 seconds elapsed, got %v", run.RunStats.Finished.Elapsed)
+	}
+}
+
+func TestNaabuToNmapXMLPartial(t *testing.T) {
+	now := time.Now()
+	data, err := NaabuToNmapXML(models.NaabuResult{Domain: "example.com", Partial: true}, now, now)
+	if err != nil {
+		t.Fatalf("NaabuToNmapXML() error = %v", err)
+	}
+
+	var run nmapRun
+	if err := xml.Unmarshal(data, &run); err != nil {
+		t.Fatalf("Failed to parse generated XML: %v", err)
+	}
+	if run.RunStats.Finished.Exit != "error" || run.RunStats.Finished.ErrorMsg == "" {
+		t.Errorf("Expected partial scan to finish with an error exit, got %+v", run.RunStats.Finished)
+	}
+}
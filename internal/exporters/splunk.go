@@ -0,0 +1,307 @@
+package exporters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	splunkSource            = "allsafe-asm-worker"
+	defaultSplunkSourcetype = "allsafe:asm"
+	defaultSplunkBatchSize  = 100
+	defaultSplunkFlushEvery = 5 * time.Second
+)
+
+// LifecycleRecorder receives task lifecycle steps as they happen
+type LifecycleRecorder interface {
+	RecordStep(taskMsg *models.TaskMessage, step string, result *models.TaskResult, err error)
+}
+
+// ProgressRecorder is implemented by lifecycle recorders that also receive periodic scanner progress
+type ProgressRecorder interface {
+	RecordProgress(taskMsg *models.TaskMessage, progress models.ScanProgress)
+}
+
+// SplunkConfig holds the HTTP Event Collector settings
+type SplunkConfig struct {
+	URL        string // Base URL of the collector, e.g. https://splunk.example.com:8088
+	Token      string
+	Index      string // Target index; empty uses the token's default index
+	Sourcetype string
+	BatchSize  int           // Events sent per request
+	FlushEvery time.Duration // Longest time a lifecycle event waits for its batch to fill
+}
+
+// splunkEvent is one event in the HEC JSON format
+type splunkEvent struct {
+	Time       float64     `json:"time"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source"`
+	Sourcetype string      `json:"sourcetype"`
+	Index      string      `json:"index,omitempty"`
+	Event      interface{} `json:"event"`
+}
+
+// lifecycleEvent describes a task step
+type lifecycleEvent struct {
+	EventType string `json:"event_type"`
+	Step      string `json:"step"`
+	Task      string `json:"task"`
+	ScanID    int    `json:"scan_id"`
+	Domain    string `json:"domain"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Duration  string `json:"duration,omitempty"`
+	Count     int    `json:"count,omitempty"`
+	Error     string `json:"error,omitempty"`
+	// CorrelationID ties the event to the task's logs, blobs and requests
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// progressEvent describes the progress of a running scanner
+type progressEvent struct {
+	EventType string  `json:"event_type"`
+	Task      string  `json:"task"`
+	ScanID    int     `json:"scan_id"`
+	Domain    string  `json:"domain"`
+	TenantID  string  `json:"tenant_id,omitempty"`
+	Phase     string  `json:"phase,omitempty"`
+	Processed int     `json:"processed"`
+	Total     int     `json:"total,omitempty"`
+	Ratio     float64 `json:"ratio,omitempty"`
+}
+
+// findingEvent is a flattened result document tagged with its event type
+type findingEvent struct {
+	EventType string `json:"event_type"`
+	Document
+}
+
+// SplunkExporter sends task lifecycle events and flattened results to a Splunk HTTP Event
+// Collector. Results are sent in batches when they are stored. Lifecycle events are queued and
+// flushed in the background once a batch is full or FlushEvery has passed, so recording a step
+// never waits on Splunk.
+type SplunkExporter struct {
+	config     SplunkConfig
+	host       string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	pending []splunkEvent
+
+	flush     chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSplunkExporter creates an exporter for the given collector and starts its flush loop
+func NewSplunkExporter(config SplunkConfig, timeout time.Duration) *SplunkExporter {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.Sourcetype == "" {
+		config.Sourcetype = defaultSplunkSourcetype
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultSplunkBatchSize
+	}
+	if config.FlushEvery <= 0 {
+		config.FlushEvery = defaultSplunkFlushEvery
+	}
+	host, _ := os.Hostname()
+
+	exporter := &SplunkExporter{
+		config:     config,
+		host:       host,
+		httpClient: utils.NewTracingClient(timeout),
+		flush:      make(chan struct{}, 1),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go exporter.run()
+	return exporter
+}
+
+// Name returns the exporter name
+func (e *SplunkExporter) Name() string {
+	return "splunk"
+}
+
+// Export sends the documents of a task result as finding events
+func (e *SplunkExporter) Export(ctx context.Context, result *models.TaskResult) error {
+	docs := FlattenResult(result)
+	events := make([]splunkEvent, len(docs))
+	for i, doc := range docs {
+		events[i] = e.event(resultTime(result), findingEvent{EventType: "asm_" + doc.Kind, Document: doc})
+	}
+
+	for start := 0; start < len(events); start += e.config.BatchSize {
+		end := min(start+e.config.BatchSize, len(events))
+		if err := e.send(ctx, events[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordStep queues a lifecycle event for a task step
+func (e *SplunkExporter) RecordStep(taskMsg *models.TaskMessage, step string, result *models.TaskResult, err error) {
+	event := lifecycleEvent{
+		EventType: "task_lifecycle",
+		Step:      step,
+		Task:      string(taskMsg.Task),
+		ScanID:    taskMsg.ScanID,
+		Domain:    taskMsg.Domain,
+		TenantID:  taskMsg.TenantID,
+
+		CorrelationID: taskMsg.CorrelationID,
+	}
+	if result != nil {
+		event.Status = string(result.Status)
+		event.Duration = result.Duration
+		if scannerResult, ok := result.Data.(models.ScannerResult); ok {
+			event.Count = scannerResult.GetCount()
+		}
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	e.queue(event)
+}
+
+// RecordProgress queues a progress event for a running scanner
+func (e *SplunkExporter) RecordProgress(taskMsg *models.TaskMessage, progress models.ScanProgress) {
+	event := progressEvent{
+		EventType: "task_progress",
+		Task:      string(taskMsg.Task),
+		ScanID:    taskMsg.ScanID,
+		Domain:    taskMsg.Domain,
+		TenantID:  taskMsg.TenantID,
+		Phase:     progress.Phase,
+		Processed: progress.Processed,
+		Total:     progress.Total,
+	}
+	if ratio := progress.Ratio(); ratio >= 0 {
+		event.Ratio = ratio
+	}
+
+	e.queue(event)
+}
+
+// queue adds an event to the pending batch, flushing early once the batch is full
+func (e *SplunkExporter) queue(event interface{}) {
+	e.mu.Lock()
+	e.pending = append(e.pending, e.event(time.Now(), event))
+	full := len(e.pending) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close stops the flush loop and sends the queued lifecycle events, giving up when ctx expires
+func (e *SplunkExporter) Close(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.closeOnce.Do(func() { close(e.done) })
+
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		gologger.Warning().Msg("Gave up waiting for queued Splunk events")
+	}
+}
+
+// run flushes the queued lifecycle events until the exporter is closed
+func (e *SplunkExporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.config.FlushEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.flushPending()
+		case <-e.flush:
+			e.flushPending()
+		case <-e.done:
+			e.flushPending()
+			return
+		}
+	}
+}
+
+// flushPending sends the queued lifecycle events in batches
+func (e *SplunkExporter) flushPending() {
+	e.mu.Lock()
+	events := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	for start := 0; start < len(events); start += e.config.BatchSize {
+		end := min(start+e.config.BatchSize, len(events))
+		ctx, cancel := context.WithTimeout(context.Background(), e.httpClient.Timeout)
+		if err := e.send(ctx, events[start:end]); err != nil {
+			gologger.Warning().Msgf("Failed to send %d lifecycle events to Splunk: %v", end-start, err)
+		}
+		cancel()
+	}
+}
+
+// event wraps an event body in the HEC envelope
+func (e *SplunkExporter) event(at time.Time, body interface{}) splunkEvent {
+	return splunkEvent{
+		Time:       float64(at.UnixMilli()) / 1000,
+		Host:       e.host,
+		Source:     splunkSource,
+		Sourcetype: e.config.Sourcetype,
+		Index:      e.config.Index,
+		Event:      body,
+	}
+}
+
+// send posts a batch of events to the collector
+func (e *SplunkExporter) send(ctx context.Context, events []splunkEvent) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL+"/services/collector/event", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create HEC request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+e.config.Token)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send HEC request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HEC request failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
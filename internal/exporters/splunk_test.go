@@ -0,0 +1,130 @@
+package exporters
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// hecServer records the events posted to a fake HTTP Event Collector
+type hecServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	events   []splunkEvent
+	auth     string
+}
+
+func newHECServer(t *testing.T) *hecServer {
+	s := &hecServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" {
+			t.Errorf("Expected HEC event endpoint, got %s", r.URL.Path)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		s.auth = r.Header.Get("Authorization")
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var event splunkEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Errorf("Failed to parse event: %v", err)
+			}
+			s.events = append(s.events, event)
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	return s
+}
+
+func TestSplunkExporter_ExportBatches(t *testing.T) {
+	server := newHECServer(t)
+	defer server.Close()
+
+	exporter := NewSplunkExporter(SplunkConfig{URL: server.URL, Token: "hec", Index: "asm", Sourcetype: "asm:scan", BatchSize: 2}, 5*time.Second)
+	defer exporter.Close(context.Background())
+
+	result := &models.TaskResult{
+		Task:      models.TaskSubfinder,
+		ScanID:    9,
+		Domain:    "example.com",
+		Timestamp: "2024-03-04T12:00:00Z",
+		Data:      models.SubfinderResult{Subdomains: []string{"a.example.com", "b.example.com", "c.example.com"}},
+	}
+	if err := exporter.Export(context.Background(), result); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	if server.requests != 2 {
+		t.Errorf("Expected 3 events in 2 batches, got %d requests", server.requests)
+	}
+	if server.auth != "Splunk hec" {
+		t.Errorf("Expected HEC token authorization, got %q", server.auth)
+	}
+	if len(server.events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(server.events))
+	}
+	event := server.events[0]
+	if event.Index != "asm" || event.Sourcetype != "asm:scan" || event.Time != 1709553600 {
+		t.Errorf("Unexpected event envelope: %+v", event)
+	}
+	body := event.Event.(map[string]interface{})
+	if body["event_type"] != "asm_subdomain" || body["host"] != "a.example.com" {
+		t.Errorf("Unexpected event body: %v", body)
+	}
+}
+
+func TestSplunkExporter_RecordStep(t *testing.T) {
+	server := newHECServer(t)
+	defer server.Close()
+
+	exporter := NewSplunkExporter(SplunkConfig{URL: server.URL, Token: "hec", FlushEvery: time.Hour}, 5*time.Second)
+
+	taskMsg := &models.TaskMessage{Task: models.TaskNaabu, ScanID: 4, Domain: "example.com"}
+	exporter.RecordStep(taskMsg, "task_received", nil, nil)
+	exporter.RecordStep(taskMsg, "task_failed", &models.TaskResult{Status: models.TaskStatusFailed}, errors.New("boom"))
+
+	server.mu.Lock()
+	if server.requests != 0 {
+		t.Errorf("Expected lifecycle events to wait for their batch, got %d requests", server.requests)
+	}
+	server.mu.Unlock()
+
+	exporter.Close(context.Background())
+
+	if len(server.events) != 2 {
+		t.Fatalf("Expected queued events to be flushed on close, got %d", len(server.events))
+	}
+	body := server.events[1].Event.(map[string]interface{})
+	if body["event_type"] != "task_lifecycle" || body["step"] != "task_failed" || body["error"] != "boom" || body["status"] != string(models.TaskStatusFailed) {
+		t.Errorf("Unexpected lifecycle event: %v", body)
+	}
+}
+
+func TestSplunkExporter_RecordProgress(t *testing.T) {
+	server := newHECServer(t)
+	defer server.Close()
+
+	exporter := NewSplunkExporter(SplunkConfig{URL: server.URL, Token: "hec", FlushEvery: time.Hour}, 5*time.Second)
+
+	taskMsg := &models.TaskMessage{Task: models.TaskDNSResolve, ScanID: 4, Domain: "example.com"}
+	exporter.RecordProgress(taskMsg, models.ScanProgress{Phase: "resolve", Processed: 500, Total: 2000})
+	exporter.Close(context.Background())
+
+	if len(server.events) != 1 {
+		t.Fatalf("Expected one progress event, got %d", len(server.events))
+	}
+	body := server.events[0].Event.(map[string]interface{})
+	if body["event_type"] != "task_progress" || body["phase"] != "resolve" || body["processed"] != float64(500) || body["ratio"] != 0.25 {
+		t.Errorf("Unexpected progress event: %v", body)
+	}
+}
@@ -0,0 +1,199 @@
+package exporters
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// ScanStatusStore persists the status of scans, merging the stages of status into the stored ones
+type ScanStatusStore interface {
+	StoreScanStatus(ctx context.Context, status *models.ScanStatus) error
+}
+
+// ScanStatusExporter keeps the coarse status of scans current for the public status endpoint.
+// Each update carries the status of one stage, and the store derives the state of the scan from
+// all of its stages. Updates are written in the background; when a stage changes faster than its
+// status can be stored, only its latest status is written.
+type ScanStatusExporter struct {
+	store     ScanStatusStore
+	timeout   time.Duration
+	finalTask models.Task // Task type that ends a scan, besides tasks flagged final_stage
+
+	mu      sync.Mutex
+	started map[string]time.Time          // Start of the running stages, by scan and task
+	pending map[string]*models.ScanStatus // Stage updates waiting to be stored, by scan and task
+
+	wake      chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewScanStatusExporter creates an exporter that stores statuses with the given timeout and starts its write loop
+func NewScanStatusExporter(store ScanStatusStore, timeout time.Duration) *ScanStatusExporter {
+	exporter := &ScanStatusExporter{
+		store:   store,
+		timeout: timeout,
+		started: make(map[string]time.Time),
+		pending: make(map[string]*models.ScanStatus),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go exporter.run()
+	return exporter
+}
+
+// SetFinalTask sets the task type whose completion ends a scan, in addition to tasks whose
+// message sets final_stage; an empty task relies on final_stage alone
+func (e *ScanStatusExporter) SetFinalTask(task models.Task) {
+	e.finalTask = task
+}
+
+// RecordStep updates the status of a stage when it starts or ends
+func (e *ScanStatusExporter) RecordStep(taskMsg *models.TaskMessage, step string, result *models.TaskResult, err error) {
+	now := time.Now().UTC()
+	status := &models.StageStatus{
+		Final:     taskMsg.FinalStage || (e.finalTask != "" && taskMsg.Task == e.finalTask),
+		UpdatedAt: now,
+	}
+	stageKey := e.stageKey(taskMsg)
+
+	e.mu.Lock()
+	switch step {
+	case "task_started":
+		e.started[stageKey] = now
+		status.State = models.ScanStateRunning
+		status.StartedAt = now
+	case "task_completed":
+		status.State = models.ScanStateCompleted
+		status.Percent = 100
+	case "task_failed":
+		status.State = models.ScanStateFailed
+	case "task_skipped":
+		status.State = models.ScanStateSkipped
+	case "scan_halted":
+		status.State = models.ScanStateNeedsIntervention
+	case "scan_degraded":
+		status.State = models.ScanStateDegraded
+	default:
+		e.mu.Unlock()
+		return
+	}
+	if status.State != models.ScanStateRunning {
+		status.StartedAt = e.started[stageKey]
+		delete(e.started, stageKey)
+	}
+	e.pending[stageKey] = e.stageUpdate(taskMsg, status)
+	e.mu.Unlock()
+
+	e.notify()
+}
+
+// RecordProgress updates the percentage and estimated time left of a running stage
+func (e *ScanStatusExporter) RecordProgress(taskMsg *models.TaskMessage, progress models.ScanProgress) {
+	now := time.Now().UTC()
+
+	// Checking the stage under the lock keeps progress from replacing the status of a stage that just ended
+	e.mu.Lock()
+	stageKey := e.stageKey(taskMsg)
+	startedAt, running := e.started[stageKey]
+	if !running {
+		e.mu.Unlock()
+		return
+	}
+
+	status := &models.StageStatus{
+		State:     models.ScanStateRunning,
+		Final:     taskMsg.FinalStage || (e.finalTask != "" && taskMsg.Task == e.finalTask),
+		StartedAt: startedAt,
+		UpdatedAt: now,
+	}
+	if ratio := progress.Ratio(); ratio > 0 {
+		// A running stage never shows as done, even once its last phase has processed everything
+		status.Percent = min(int(ratio*100), 99)
+		elapsed := now.Sub(startedAt)
+		status.ETA = int((time.Duration(float64(elapsed) * (1 - ratio) / ratio)).Seconds())
+	}
+	e.pending[stageKey] = e.stageUpdate(taskMsg, status)
+	e.mu.Unlock()
+
+	e.notify()
+}
+
+// stageUpdate wraps the status of the task's stage in an update of the scan status
+func (e *ScanStatusExporter) stageUpdate(taskMsg *models.TaskMessage, stage *models.StageStatus) *models.ScanStatus {
+	return &models.ScanStatus{
+		ScanID:    taskMsg.ScanID,
+		TenantID:  taskMsg.TenantID,
+		UpdatedAt: stage.UpdatedAt,
+		Stages:    map[string]*models.StageStatus{string(taskMsg.Task): stage},
+	}
+}
+
+// statusKey identifies the status of a scan
+func (e *ScanStatusExporter) statusKey(taskMsg *models.TaskMessage) string {
+	return models.ScanStatusBlobPath(taskMsg.TenantID, taskMsg.ScanID)
+}
+
+// stageKey identifies a stage of a scan
+func (e *ScanStatusExporter) stageKey(taskMsg *models.TaskMessage) string {
+	return e.statusKey(taskMsg) + "#" + string(taskMsg.Task)
+}
+
+// notify wakes the write loop
+func (e *ScanStatusExporter) notify() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Close stops the write loop after storing the pending statuses, giving up when ctx expires
+func (e *ScanStatusExporter) Close(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	e.closeOnce.Do(func() { close(e.done) })
+
+	select {
+	case <-e.stopped:
+	case <-ctx.Done():
+		gologger.Warning().Msg("Gave up waiting for pending scan statuses")
+	}
+}
+
+// run stores pending statuses until the exporter is closed
+func (e *ScanStatusExporter) run() {
+	defer close(e.stopped)
+
+	for {
+		select {
+		case <-e.wake:
+			e.storePending()
+		case <-e.done:
+			e.storePending()
+			return
+		}
+	}
+}
+
+// storePending stores the pending statuses
+func (e *ScanStatusExporter) storePending() {
+	e.mu.Lock()
+	statuses := e.pending
+	e.pending = make(map[string]*models.ScanStatus)
+	e.mu.Unlock()
+
+	for path, status := range statuses {
+		ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+		if err := e.store.StoreScanStatus(ctx, status); err != nil {
+			gologger.Warning().Msgf("Failed to store scan status %s: %v", path, err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,104 @@
+package exporters
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// fakeStatusStore merges updates into a single status, like the blob store does
+type fakeStatusStore struct {
+	mu     sync.Mutex
+	status models.ScanStatus
+}
+
+func (s *fakeStatusStore) StoreScanStatus(ctx context.Context, status *models.ScanStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Merge(status)
+	return nil
+}
+
+func (s *fakeStatusStore) last() models.ScanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func TestScanStatusExporter(t *testing.T) {
+	store := &fakeStatusStore{}
+	exporter := NewScanStatusExporter(store, time.Second)
+	taskMsg := &models.TaskMessage{Task: models.TaskDNSResolve, ScanID: 9, TenantID: "acme", Domain: "example.com"}
+
+	// Progress of a stage that was never started is ignored
+	exporter.RecordProgress(taskMsg, models.ScanProgress{Phase: "resolve", Processed: 1, Total: 2})
+
+	exporter.RecordStep(taskMsg, "task_started", nil, nil)
+	exporter.mu.Lock()
+	exporter.started[exporter.stageKey(taskMsg)] = time.Now().Add(-time.Minute)
+	exporter.mu.Unlock()
+	exporter.RecordProgress(taskMsg, models.ScanProgress{Phase: "resolve", Processed: 25, Total: 100})
+	exporter.Close(context.Background())
+
+	running := store.last()
+	if running.State != models.ScanStateRunning || running.Stage != string(models.TaskDNSResolve) || running.Percent != 25 {
+		t.Fatalf("Expected dnsx running at 25%%, got %+v", running)
+	}
+	if running.ETA < 170 || running.ETA > 190 {
+		t.Errorf("Expected about 3 minutes left after 1 minute at 25%%, got %ds", running.ETA)
+	}
+
+	store = &fakeStatusStore{}
+	exporter = NewScanStatusExporter(store, time.Second)
+	exporter.RecordStep(taskMsg, "task_started", nil, nil)
+	exporter.RecordStep(taskMsg, "task_completed", nil, nil)
+	exporter.RecordProgress(taskMsg, models.ScanProgress{Phase: "resolve", Processed: 50, Total: 100})
+	exporter.RecordStep(taskMsg, "result_stored", nil, nil)
+	exporter.Close(context.Background())
+
+	between := store.last()
+	if between.State != models.ScanStateRunning || between.Percent != 0 || between.Stages[string(models.TaskDNSResolve)].Percent != 100 {
+		t.Errorf("Expected the scan running between stages with dnsx at 100%%, got %+v", between)
+	}
+
+	// The scan completes with its final stage, and a stage ending elsewhere does not change that
+	finalMsg := &models.TaskMessage{Task: models.TaskNuclei, ScanID: 9, TenantID: "acme", Domain: "example.com"}
+	exporter = NewScanStatusExporter(store, time.Second)
+	exporter.SetFinalTask(models.TaskNuclei)
+	exporter.RecordStep(finalMsg, "task_started", nil, nil)
+	exporter.RecordStep(finalMsg, "task_completed", nil, nil)
+	exporter.Close(context.Background())
+
+	completed := store.last()
+	if completed.State != models.ScanStateCompleted || completed.Stage != string(models.TaskNuclei) || completed.Percent != 100 || completed.StartedAt.IsZero() {
+		t.Errorf("Expected the scan completed with its final stage, got %+v", completed)
+	}
+	if len(completed.Stages) != 2 {
+		t.Errorf("Expected both stages kept, got %+v", completed.Stages)
+	}
+
+	store = &fakeStatusStore{}
+	exporter = NewScanStatusExporter(store, time.Second)
+	exporter.RecordStep(taskMsg, "task_started", nil, nil)
+	exporter.RecordStep(taskMsg, "task_failed", nil, nil)
+	exporter.RecordStep(taskMsg, "scan_halted", nil, nil)
+	exporter.Close(context.Background())
+
+	if halted := store.last(); halted.State != models.ScanStateNeedsIntervention {
+		t.Errorf("Expected a halted scan to need intervention, got %+v", halted)
+	}
+
+	store = &fakeStatusStore{}
+	exporter = NewScanStatusExporter(store, time.Second)
+	exporter.RecordStep(taskMsg, "task_started", nil, nil)
+	exporter.RecordStep(taskMsg, "task_completed", nil, nil)
+	exporter.RecordStep(taskMsg, "scan_degraded", nil, nil)
+	exporter.Close(context.Background())
+
+	if degraded := store.last(); degraded.State != models.ScanStateDegraded {
+		t.Errorf("Expected a scan failing a quality gate to be degraded, got %+v", degraded)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !faults
+
+package faults
+
+import (
+	"context"
+	"net/http"
+)
+
+// Enabled reports whether the binary was built with the faults tag
+const Enabled = false
+
+// Injector injects nothing in builds without the faults tag
+type Injector struct{}
+
+// New returns nil, as faults cannot be injected without the faults tag
+func New(config Config) *Injector {
+	return nil
+}
+
+// DelayBlob never delays
+func (i *Injector) DelayBlob(ctx context.Context) error {
+	return nil
+}
+
+// FailLockRenewal never fails
+func (i *Injector) FailLockRenewal() error {
+	return nil
+}
+
+// DropNotification never drops
+func (i *Injector) DropNotification() bool {
+	return false
+}
+
+// Transport returns base unchanged
+func (i *Injector) Transport(base http.RoundTripper) http.RoundTripper {
+	return base
+}
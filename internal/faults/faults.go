@@ -0,0 +1,20 @@
+// Package faults injects failures into blob operations, lock renewals and notifications so
+// retries, lock loss and dead-lettering can be exercised in staging. The injector is only
+// compiled into builds with the faults tag; other builds get a stub that injects nothing.
+package faults
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInjected marks a failure that was injected rather than real
+var ErrInjected = errors.New("injected fault")
+
+// Config sets how often each fault is injected, as percentages of the operations it applies to
+type Config struct {
+	BlobDelayPercent          int
+	BlobDelayMax              time.Duration // Delays are picked uniformly up to this
+	LockRenewalFailurePercent int
+	NotificationDropPercent   int
+}
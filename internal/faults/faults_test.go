@@ -0,0 +1,68 @@
+//go:build faults
+
+package faults
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNew_NothingToInject(t *testing.T) {
+	if injector := New(Config{BlobDelayPercent: 50}); injector != nil {
+		t.Error("Expected no injector for blob delays without a maximum delay")
+	}
+
+	var injector *Injector
+	if injector.DelayBlob(context.Background()) != nil || injector.FailLockRenewal() != nil || injector.DropNotification() {
+		t.Error("Expected a nil injector to inject nothing")
+	}
+	if transport := injector.Transport(http.DefaultTransport); transport != http.DefaultTransport {
+		t.Error("Expected a nil injector to keep the transport")
+	}
+}
+
+func TestInjector_Percentages(t *testing.T) {
+	injector := New(Config{LockRenewalFailurePercent: 30, NotificationDropPercent: 100})
+
+	failures := 0
+	for _, roll := range []float64{0, 0.29, 0.3, 0.99} {
+		injector.roll = func() float64 { return roll }
+		if err := injector.FailLockRenewal(); err != nil {
+			if !errors.Is(err, ErrInjected) {
+				t.Errorf("Expected an injected error, got %v", err)
+			}
+			failures++
+		}
+	}
+	if failures != 2 {
+		t.Errorf("Expected the rolls below 0.3 to fail, got %d failures", failures)
+	}
+
+	var sent bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { sent = true }))
+	defer server.Close()
+	client := &http.Client{Transport: injector.Transport(nil)}
+	if _, err := client.Get(server.URL); !errors.Is(err, ErrInjected) || sent {
+		t.Errorf("Expected the call dropped before it was sent, got %v", err)
+	}
+}
+
+func TestInjector_DelayBlob(t *testing.T) {
+	injector := New(Config{BlobDelayPercent: 100, BlobDelayMax: time.Hour})
+	injector.roll = func() float64 { return 0.5 }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := injector.DelayBlob(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the delay to end with the context, got %v", err)
+	}
+
+	injector.config.BlobDelayMax = time.Millisecond
+	if err := injector.DelayBlob(context.Background()); err != nil {
+		t.Errorf("Expected a short delay, got %v", err)
+	}
+}
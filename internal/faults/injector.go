@@ -0,0 +1,104 @@
+//go:build faults
+
+package faults
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// Enabled reports whether the binary was built with the faults tag
+const Enabled = true
+
+// Injector injects faults into blob operations, lock renewals and notifications so retries,
+// lock loss and dead-lettering can be exercised in staging. A nil Injector injects nothing,
+// so callers hold one unconditionally.
+type Injector struct {
+	config Config
+	roll   func() float64 // Returns a number in [0, 1)
+}
+
+// New returns an injector for config, or nil when config injects no faults
+func New(config Config) *Injector {
+	if config.LockRenewalFailurePercent <= 0 && config.NotificationDropPercent <= 0 &&
+		(config.BlobDelayPercent <= 0 || config.BlobDelayMax <= 0) {
+		return nil
+	}
+	return &Injector{config: config, roll: rand.Float64}
+}
+
+// DelayBlob sleeps before a blob operation for BlobDelayPercent of the calls, returning early
+// with the context's error when ctx ends first
+func (i *Injector) DelayBlob(ctx context.Context) error {
+	if i == nil || !i.hit(i.config.BlobDelayPercent) || i.config.BlobDelayMax <= 0 {
+		return nil
+	}
+
+	delay := time.Duration(i.roll() * float64(i.config.BlobDelayMax))
+	gologger.Debug().Msgf("Fault injection: delaying blob operation by %s", delay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// FailLockRenewal returns an error for LockRenewalFailurePercent of the lock renewals
+func (i *Injector) FailLockRenewal() error {
+	if i == nil || !i.hit(i.config.LockRenewalFailurePercent) {
+		return nil
+	}
+	gologger.Warning().Msg("Fault injection: failing message lock renewal")
+	return fmt.Errorf("lock renewal failed: %w", ErrInjected)
+}
+
+// DropNotification reports whether a notification call should be dropped
+func (i *Injector) DropNotification() bool {
+	if i == nil || !i.hit(i.config.NotificationDropPercent) {
+		return false
+	}
+	gologger.Warning().Msg("Fault injection: dropping notification call")
+	return true
+}
+
+// Transport wraps base so that dropped notification calls fail without being sent. It returns
+// base itself for a nil Injector.
+func (i *Injector) Transport(base http.RoundTripper) http.RoundTripper {
+	if i == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &dropTransport{injector: i, base: base}
+}
+
+// hit reports whether an operation falls into the given percentage
+func (i *Injector) hit(percent int) bool {
+	return percent > 0 && i.roll()*100 < float64(percent)
+}
+
+// dropTransport fails the requests its injector drops
+type dropTransport struct {
+	injector *Injector
+	base     http.RoundTripper
+}
+
+// RoundTrip sends the request unless the injector drops it
+func (t *dropTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.injector.DropNotification() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%s %s dropped: %w", req.Method, req.URL.Host, ErrInjected)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+)
+
+// SetBlockPolicy sets how httpx and nuclei react when the responses of a host group shift to a
+// WAF or rate-limit ban mid-scan
+func (h *TaskHandler) SetBlockPolicy(policy models.BlockPolicy) {
+	h.blockPolicy = policy
+}
+
+// reportBlock alerts operators as soon as a scanner finds a host group banned. The event is also
+// stored with the stage's result.
+func (h *TaskHandler) reportBlock(taskCtx *models.TaskContext, taskMsg *models.TaskMessage, event models.BlockEvent) {
+	taskCtx.Warning().Msgf("Host group of %s looks banned: %s", taskMsg.Domain, event)
+	h.publishStep(taskMsg, nil, errors.New(event.String()), notification.StepTargetsBlocked)
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// pausePollInterval is how often a running scan checks for its pause marker
+const pausePollInterval = 30 * time.Second
+
+// pausedTaskRecheck is how long a task of a paused scan waits on the queue before it checks
+// whether the scan was resumed
+const pausedTaskRecheck = 5 * time.Minute
+
+// errScanPaused is the cancellation cause used when a pause control message stops a running scan
+var errScanPaused = errors.New("scan paused by control message")
+
+// errLockLost is the cancellation cause used when the message lock of a running scan can no longer be renewed
+var errLockLost = errors.New("message lock lost")
+
+// handleControlMessage applies a pause or resume control message to a scan
+func (h *TaskHandler) handleControlMessage(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if err := h.validator.ValidateControlMessage(taskMsg); err != nil {
+		return h.createFailureResult(err, false)
+	}
+	if taskMsg.Action == models.TaskActionCompact {
+		return h.compactResults(ctx, taskMsg)
+	}
+
+	if h.blobClient == nil {
+		gologger.Warning().Msgf("Ignoring %s control message for scan %d: blob storage is not configured", taskMsg.Action, taskMsg.ScanID)
+		return &models.MessageProcessingResult{Success: true}
+	}
+
+	paused := taskMsg.Action == models.TaskActionPause
+	if err := h.blobClient.SetScanPaused(ctx, taskMsg.TenantID, taskMsg.ScanID, paused); err != nil {
+		gologger.Error().Msgf("Failed to apply %s control message for scan %d: %v", taskMsg.Action, taskMsg.ScanID, err)
+		return h.createFailureResult(err, true)
+	}
+
+	// A resumed scan gets its full retry budget back
+	if !paused {
+		if err := h.blobClient.ResetScanRetries(ctx, taskMsg.TenantID, taskMsg.ScanID); err != nil {
+			gologger.Warning().Msgf("Failed to reset retries of scan %d: %v", taskMsg.ScanID, err)
+		}
+	}
+
+	gologger.Info().Msgf("Applied %s control message for scan %d", taskMsg.Action, taskMsg.ScanID)
+	return &models.MessageProcessingResult{Success: true}
+}
+
+// isScanPaused reports whether the task's scan is paused; lookup errors are treated as not paused
+func (h *TaskHandler) isScanPaused(ctx context.Context, taskMsg *models.TaskMessage) bool {
+	if h.blobClient == nil {
+		return false
+	}
+
+	paused, err := h.blobClient.IsScanPaused(ctx, taskMsg.TenantID, taskMsg.ScanID)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to check pause state for scan %d: %v", taskMsg.ScanID, err)
+		return false
+	}
+	return paused
+}
+
+// watchForPause cancels the scanner context with errScanPaused once the scan's pause marker appears
+func (h *TaskHandler) watchForPause(ctx context.Context, taskMsg *models.TaskMessage, pause context.CancelCauseFunc) {
+	if h.blobClient == nil {
+		return
+	}
+
+	ticker := time.NewTicker(pausePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if h.isScanPaused(ctx, taskMsg) {
+				gologger.Info().Msgf("Pause requested for scan %d, stopping %s for domain %s", taskMsg.ScanID, taskMsg.Task, taskMsg.Domain)
+				pause(errScanPaused)
+				return
+			}
+		}
+	}
+}
+
+// watchForLockLoss cancels the scanner context with errLockLost once the message lock can no longer
+// be renewed, so the scan is checkpointed before another worker receives the redelivered message
+func (h *TaskHandler) watchForLockLoss(ctx context.Context, taskMsg *models.TaskMessage, lock *models.MessageLock, pause context.CancelCauseFunc) {
+	if h.blobClient == nil || lock == nil {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-lock.Lost():
+		gologger.Warning().Msgf("Message lock lost for scan %d, checkpointing %s for domain %s before it expires at %s",
+			taskMsg.ScanID, taskMsg.Task, taskMsg.Domain, lock.LockedUntil().Format(time.RFC3339))
+		pause(errLockLost)
+	}
+}
+
+// loadCheckpoint returns the checkpoint left by a paused run of this task, if any
+func (h *TaskHandler) loadCheckpoint(ctx context.Context, taskMsg *models.TaskMessage) *models.ScanCheckpoint {
+	if h.blobClient == nil {
+		return nil
+	}
+
+	checkpoint, err := h.blobClient.LoadCheckpoint(ctx, taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID, taskMsg.Task)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to load checkpoint for scan %d, starting from scratch: %v", taskMsg.ScanID, err)
+		return nil
+	}
+	if checkpoint != nil {
+		gologger.Info().Msgf("Resuming %s for domain %s from checkpoint with %d processed targets",
+			taskMsg.Task, taskMsg.Domain, len(checkpoint.ProcessedTargets))
+	}
+	return checkpoint
+}
+
+// pauseTask checkpoints what the scanner finished before it was stopped. A task stopped by a pause
+// control message, or held before it started, waits on the queue until the scan is resumed, and
+// the orchestrator is told it is paused. A task that lost its message lock is redelivered anyway.
+func (h *TaskHandler) pauseTask(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, scannerResult models.ScannerResult, cause error) *models.MessageProcessingResult {
+	result.Status = models.TaskStatusPaused
+	result.Data = scannerResult
+
+	// A task stopped before its scanner ran keeps the checkpoint of an earlier run. A checkpoint of
+	// an earlier pause also means the orchestrator already knows the task is paused, while one a
+	// running nuclei scan took is only marked paused, so its resume state is kept.
+	notified := false
+	var pausedAt time.Time
+	if scannerResult == nil {
+		existing, err := h.blobClient.LoadCheckpoint(ctx, taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID, taskMsg.Task)
+		switch {
+		case err != nil:
+			gologger.Warning().Msgf("Failed to load checkpoint for scan %d, keeping it as it is: %v", taskMsg.ScanID, err)
+			notified = true
+		case existing != nil && existing.PausedAt != "":
+			notified = true
+			pausedAt, _ = time.Parse(time.RFC3339, existing.PausedAt)
+		case existing != nil:
+			existing.PausedAt = time.Now().Format(time.RFC3339)
+			if err := h.blobClient.StoreCheckpoint(ctx, existing); err != nil {
+				gologger.Error().Msgf("Failed to store checkpoint for scan %d: %v", taskMsg.ScanID, err)
+				return h.createFailureResult(err, true)
+			}
+		default:
+			if failure := h.storePauseCheckpoint(ctx, taskMsg, nil); failure != nil {
+				return failure
+			}
+		}
+	} else if failure := h.storePauseCheckpoint(ctx, taskMsg, scannerResult); failure != nil {
+		return failure
+	}
+
+	if !errors.Is(cause, errScanPaused) {
+		return &models.MessageProcessingResult{Success: true}
+	}
+	if !notified {
+		h.publishStep(taskMsg, result, cause, notification.StepTaskPaused)
+		if h.notifier != nil && taskMsg.InstanceID != "" {
+			if err := h.notifier.NotifyPausedWithRetry(ctx, taskMsg.InstanceID, string(taskMsg.Task), result); err != nil {
+				gologger.Warning().Msgf("Failed to send pause notification for domain %s: %v", taskMsg.Domain, err)
+			}
+		}
+	}
+
+	// A scan nobody resumes would hold its tasks on the queue forever, so they eventually fail
+	// and are dead-lettered with their checkpoint kept
+	if h.pauseMaxHold > 0 && !pausedAt.IsZero() && time.Since(pausedAt) > h.pauseMaxHold {
+		err := common.NewValidationError("scan", fmt.Sprintf("scan %d was paused for more than %s", taskMsg.ScanID, h.pauseMaxHold))
+		gologger.Error().Msgf("Failing %s for domain %s: %v", taskMsg.Task, taskMsg.Domain, err)
+		result.Status = models.TaskStatusFailed
+		result.Error = err.Error()
+		h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+		return h.createFailureResult(err, false)
+	}
+
+	recheck := time.Now().Add(pausedTaskRecheck)
+	gologger.Info().Msgf("Scan %d is paused, holding %s for domain %s until %s",
+		taskMsg.ScanID, taskMsg.Task, taskMsg.Domain, recheck.Format(time.RFC3339))
+	return &models.MessageProcessingResult{Success: true, DeferUntil: recheck}
+}
+
+// storePauseCheckpoint stores the checkpoint of a stopped task, returning a failure result when it
+// cannot be stored
+func (h *TaskHandler) storePauseCheckpoint(ctx context.Context, taskMsg *models.TaskMessage, scannerResult models.ScannerResult) *models.MessageProcessingResult {
+	checkpoint := &models.ScanCheckpoint{
+		Task:             taskMsg.Task,
+		ScanID:           taskMsg.ScanID,
+		Domain:           taskMsg.Domain,
+		TenantID:         taskMsg.TenantID,
+		ProcessedTargets: checkpointProcessedTargets(scannerResult),
+		PausedAt:         time.Now().Format(time.RFC3339),
+	}
+
+	if scannerResult != nil {
+		data, err := json.Marshal(h.redactionPolicy(taskMsg).Result(scannerResult))
+		if err != nil {
+			return h.createFailureResult(err, false)
+		}
+		checkpoint.Result = data
+	}
+	if nucleiResult, ok := scannerResult.(models.NucleiResult); ok {
+		checkpoint.ResumeState = nucleiResult.ResumeState
+	}
+
+	if err := h.blobClient.StoreCheckpoint(ctx, checkpoint); err != nil {
+		gologger.Error().Msgf("Failed to store checkpoint for scan %d: %v", taskMsg.ScanID, err)
+		return h.createFailureResult(err, true)
+	}
+
+	gologger.Info().Msgf("Paused %s for domain %s with %d processed targets checkpointed",
+		taskMsg.Task, taskMsg.Domain, len(checkpoint.ProcessedTargets))
+	return nil
+}
+
+// storeProgressCheckpoint checkpoints the progress of a running scan so that a redelivered task
+// resumes from it. Failures are logged; the scan carries on without the checkpoint.
+func (h *TaskHandler) storeProgressCheckpoint(ctx context.Context, taskMsg *models.TaskMessage, previous *models.ScanCheckpoint, progress models.NucleiResult) {
+	checkpoint := &models.ScanCheckpoint{
+		Task:        taskMsg.Task,
+		ScanID:      taskMsg.ScanID,
+		Domain:      taskMsg.Domain,
+		TenantID:    taskMsg.TenantID,
+		ResumeState: progress.ResumeState,
+	}
+
+	var merged models.ScannerResult = progress
+	if previous != nil {
+		merged = mergeCheckpoint(previous, progress)
+	}
+	data, err := json.Marshal(h.redactionPolicy(taskMsg).Result(merged))
+	if err != nil {
+		gologger.Warning().Msgf("Failed to encode progress checkpoint for scan %d: %v", taskMsg.ScanID, err)
+		return
+	}
+	checkpoint.Result = data
+
+	if err := h.blobClient.StoreCheckpoint(ctx, checkpoint); err != nil {
+		gologger.Warning().Msgf("Failed to store progress checkpoint for scan %d: %v", taskMsg.ScanID, err)
+		return
+	}
+	gologger.Debug().Msgf("Checkpointed %s progress for domain %s with %d findings", taskMsg.Task, taskMsg.Domain, merged.GetCount())
+}
+
+// clearCheckpoint removes the task's checkpoint once its results have been stored
+func (h *TaskHandler) clearCheckpoint(ctx context.Context, taskMsg *models.TaskMessage) {
+	if h.blobClient == nil {
+		return
+	}
+
+	if err := h.blobClient.DeleteCheckpoint(ctx, taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID, taskMsg.Task); err != nil {
+		gologger.Warning().Msgf("Failed to delete checkpoint for scan %d: %v", taskMsg.ScanID, err)
+	}
+}
+
+// nucleiCheckpoints reports whether a nuclei scan checkpoints its resume state while it runs:
+// when the worker or the task turns checkpoints on, or the scan resumes a checkpointed run.
+// Resumable scans use template-spray, so the others keep host-spray.
+func (h *TaskHandler) nucleiCheckpoints(config models.ScannerConfig, checkpoint *models.ScanCheckpoint) bool {
+	if h.nucleiCheckpoint {
+		return true
+	}
+	if config, ok := config.(*models.NucleiConfig); ok && config.Checkpoints {
+		return true
+	}
+	return checkpoint != nil && len(checkpoint.ResumeState) > 0
+}
+
+// applyCheckpoint narrows the scanner input to the targets the checkpointed run did not finish
+func (h *TaskHandler) applyCheckpoint(input models.ScannerInput, checkpoint *models.ScanCheckpoint) models.ScannerInput {
+	switch in := input.(type) {
+	case models.NucleiInput:
+		// Nuclei tracks its own progress per template instead of per target
+		in.ResumeState = checkpoint.ResumeState
+		return in
+	case models.DNSXInput:
+		if len(checkpoint.ProcessedTargets) > 0 {
+			in.SkipTargets = checkpoint.ProcessedTargets
+		}
+		return in
+	case models.HttpxInput:
+		if in.InputPath != "" && len(checkpoint.ProcessedTargets) > 0 {
+			if err := removeLinesFromFile(in.InputPath, checkpoint.ProcessedTargets); err != nil {
+				gologger.Warning().Msgf("Failed to skip checkpointed hosts, probing all hosts again: %v", err)
+			}
+		}
+		return in
+	}
+
+	return input
+}
+
+// checkpointProcessedTargets lists the targets a partial result has finished.
+// Naabu only reports hosts with open ports, so its runs are resumed from the start.
+func checkpointProcessedTargets(result models.ScannerResult) []string {
+	var targets []string
+
+	switch r := result.(type) {
+	case models.DNSXResult:
+		for subdomain := range r.Records {
+			targets = append(targets, subdomain)
+		}
+	case models.HttpxResult:
+		for _, host := range r.Results {
+			targets = append(targets, host.Host)
+		}
+	}
+
+	return targets
+}
+
+// mergeCheckpoint combines the results stored in a checkpoint with those of the resumed run
+func mergeCheckpoint(checkpoint *models.ScanCheckpoint, result models.ScannerResult) models.ScannerResult {
+	if len(checkpoint.Result) == 0 || result == nil {
+		return result
+	}
+
+	switch r := result.(type) {
+	case models.DNSXResult:
+		// Streamed runs resolve every name again, their blob already holds the complete records
+		if r.RecordsBlob != "" {
+			return r
+		}
+		var previous models.DNSXResult
+		if err := json.Unmarshal(checkpoint.Result, &previous); err != nil {
+			return result
+		}
+		if r.Records == nil {
+			r.Records = make(map[string]models.ResolutionInfo)
+		}
+		for subdomain, info := range previous.Records {
+			if _, ok := r.Records[subdomain]; !ok {
+				r.Records[subdomain] = info
+			}
+		}
+		// Recount over the merged records, keeping the resumed run's effective settings
+		metadata := models.SummarizeDNSRecords(r.Records)
+		if r.Metadata != nil {
+			metadata.Workers, metadata.RateLimit, metadata.Settings = r.Metadata.Workers, r.Metadata.RateLimit, r.Metadata.Settings
+			metadata.ResolverHealth = r.Metadata.ResolverHealth
+		}
+		r.Metadata = metadata
+		return r
+	case models.HttpxResult:
+		var previous models.HttpxResult
+		if err := json.Unmarshal(checkpoint.Result, &previous); err != nil {
+			return result
+		}
+		r.Results = append(previous.Results, r.Results...)
+		r.Blocks = append(previous.Blocks, r.Blocks...)
+		return r
+	case models.NaabuResult:
+		var previous models.NaabuResult
+		if err := json.Unmarshal(checkpoint.Result, &previous); err != nil {
+			return result
+		}
+		if r.Ports == nil {
+			r.Ports = make(map[string][]models.PortInfo)
+		}
+		for ip, ports := range previous.Ports {
+			for _, port := range ports {
+				if !containsPort(r.Ports[ip], port) {
+					r.Ports[ip] = append(r.Ports[ip], port)
+				}
+			}
+		}
+		return r
+	case models.NucleiResult:
+		var previous models.NucleiResult
+		if err := json.Unmarshal(checkpoint.Result, &previous); err != nil {
+			return result
+		}
+		// Templates running when the checkpoint was taken are executed again, so their findings can repeat
+		seen := make(map[string]struct{}, len(r.Vulnerabilities))
+		for _, vuln := range r.Vulnerabilities {
+			seen[findingKey(vuln)] = struct{}{}
+		}
+		merged := make([]models.NucleiVulnerability, 0, len(previous.Vulnerabilities)+len(r.Vulnerabilities))
+		for _, vuln := range previous.Vulnerabilities {
+			if _, ok := seen[findingKey(vuln)]; !ok {
+				merged = append(merged, vuln)
+			}
+		}
+		r.Vulnerabilities = append(merged, r.Vulnerabilities...)
+		r.Blocks = append(previous.Blocks, r.Blocks...)
+		return r
+	}
+
+	return result
+}
+
+// findingKey identifies a nuclei finding across runs
+func findingKey(vuln models.NucleiVulnerability) string {
+	return vuln.TemplateID + "|" + vuln.Host + "|" + vuln.MatchedAt
+}
+
+// containsPort reports whether the port list already has the given port and protocol
+func containsPort(ports []models.PortInfo, port models.PortInfo) bool {
+	for _, p := range ports {
+		if p.Port == port.Port && p.Protocol == port.Protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLinesFromFile rewrites a hosts file without the given entries
+func removeLinesFromFile(path string, entries []string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	skip := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		skip[entry] = struct{}{}
+	}
+
+	var kept []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if _, done := skip[strings.TrimSpace(line)]; !done {
+			kept = append(kept, line)
+		}
+	}
+
+	// Written aside and renamed, so a full disk leaves the whole file instead of a truncated one
+	if err := os.WriteFile(path+".tmp", []byte(strings.Join(kept, "\n")), 0o600); err != nil {
+		os.Remove(path + ".tmp")
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
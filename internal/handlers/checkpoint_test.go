@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/azure/azuretest"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+)
+
+// newTestHandler returns a handler that stores blobs in an in-memory blob service and runs
+// simulated scanners
+func newTestHandler(t *testing.T, notifier *notification.Notifier) (*TaskHandler, *azuretest.Server) {
+	t.Helper()
+	server := azuretest.NewServer()
+	t.Cleanup(server.Close)
+	blobClient, err := azure.NewBlobStorageClient(server.ConnectionString(), "scans")
+	if err != nil {
+		t.Fatalf("NewBlobStorageClient failed: %v", err)
+	}
+	h := NewTaskHandler(blobClient, time.Minute, notifier, nil)
+	h.SetSimulation("")
+	return h, server
+}
+
+// newTestOrchestrator returns a notifier whose orchestrator records the names of the events raised
+func newTestOrchestrator(t *testing.T) (*notification.Notifier, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var raised []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		raised = append(raised, filepath.Base(r.URL.Path))
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("DURABLE_API_ENDPOINT", server.URL)
+	t.Setenv("DURABLE_API_KEY", "key")
+	notifier, err := notification.NewNotifier()
+	if err != nil {
+		t.Fatalf("NewNotifier failed: %v", err)
+	}
+	return notifier, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(raised)
+	}
+}
+
+func TestPausedTaskWaitsForResume(t *testing.T) {
+	notifier, raised := newTestOrchestrator(t)
+	h, server := newTestHandler(t, notifier)
+	ctx := context.Background()
+	task := func() *models.TaskMessage {
+		return &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 9, Domain: "example.com", TenantID: "acme", InstanceID: "instance", Attempt: 1}
+	}
+	checkpointPath := "acme/example.com-9/subfinder/checkpoint.json"
+
+	if result := h.HandleTask(ctx, &models.TaskMessage{Action: models.TaskActionPause, ScanID: 9, TenantID: "acme"}); !result.Success {
+		t.Fatalf("Pause failed: %v", result.Error)
+	}
+
+	// A task of the paused scan is held on the queue with a checkpoint, and the orchestrator is told once
+	for range 2 {
+		result := h.HandleTask(ctx, task())
+		if !result.Success || result.DeferUntil.IsZero() {
+			t.Fatalf("Task of a paused scan = %+v, want it deferred", result)
+		}
+		if delay := time.Until(result.DeferUntil); delay <= 0 || delay > pausedTaskRecheck {
+			t.Errorf("Task deferred by %s, want at most %s", delay, pausedTaskRecheck)
+		}
+	}
+	if _, ok := server.Blob("scans", checkpointPath); !ok {
+		t.Error("The held task has no checkpoint")
+	}
+	if latest := server.Names("scans", "acme/example.com-9/subfinder/latest"); len(latest) != 0 {
+		t.Errorf("The held task stored a result: %v", latest)
+	}
+	if events := raised(); !reflect.DeepEqual(events, []string{"subfinder_paused"}) {
+		t.Errorf("Raised events %v, want one pause event", events)
+	}
+
+	// Once resumed, the redelivered task runs from its checkpoint and completes
+	if result := h.HandleTask(ctx, &models.TaskMessage{Action: models.TaskActionResume, ScanID: 9, TenantID: "acme"}); !result.Success {
+		t.Fatalf("Resume failed: %v", result.Error)
+	}
+	result := h.HandleTask(ctx, task())
+	if !result.Success || !result.DeferUntil.IsZero() {
+		t.Fatalf("Task of the resumed scan = %+v, want it completed", result)
+	}
+	if _, ok := server.Blob("scans", "acme/example.com-9/subfinder/latest.json"); !ok {
+		t.Error("The resumed task stored no result")
+	}
+	if _, ok := server.Blob("scans", checkpointPath); ok {
+		t.Error("The checkpoint was kept after the task completed")
+	}
+	if events := raised(); !reflect.DeepEqual(events, []string{"subfinder_paused", "subfinder_completed"}) {
+		t.Errorf("Raised events %v, want the pause and then the completion", events)
+	}
+}
+
+func TestPauseKeepsEarlierCheckpoint(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	ctx := context.Background()
+	taskMsg := &models.TaskMessage{Task: models.TaskHttpx, ScanID: 4, Domain: "example.com"}
+	checkpoint := &models.ScanCheckpoint{Task: models.TaskHttpx, ScanID: 4, Domain: "example.com", ProcessedTargets: []string{"a.example.com"},
+		PausedAt: time.Now().Add(-time.Hour).Format(time.RFC3339)}
+	content, _ := json.Marshal(checkpoint)
+	server.PutBlob("scans", "example.com-4/httpx/checkpoint.json", content)
+
+	// Stopped before the scanner ran, e.g. while its input was read
+	result := h.pauseTask(ctx, taskMsg, h.createTaskResult(taskMsg), nil, errScanPaused)
+	if !result.Success || result.DeferUntil.IsZero() {
+		t.Fatalf("pauseTask() = %+v, want the task deferred", result)
+	}
+	if stored, _ := server.Blob("scans", "example.com-4/httpx/checkpoint.json"); string(stored) != string(content) {
+		t.Errorf("Checkpoint = %s, want the earlier one %s", stored, content)
+	}
+
+	// A task whose message lock was lost is redelivered by the queue, so it is not deferred
+	if result := h.pauseTask(ctx, taskMsg, h.createTaskResult(taskMsg), nil, errLockLost); !result.Success || !result.DeferUntil.IsZero() {
+		t.Errorf("pauseTask() after losing the lock = %+v, want it completed without deferral", result)
+	}
+
+	// A task paused for longer than the maximum hold fails instead of waiting forever
+	h.SetPauseMaxHold(30 * time.Minute)
+	result = h.pauseTask(ctx, taskMsg, h.createTaskResult(taskMsg), nil, errScanPaused)
+	if result.Success || result.Retryable {
+		t.Errorf("pauseTask() past the maximum hold = %+v, want a failure without retries", result)
+	}
+	if _, ok := server.Blob("scans", "example.com-4/httpx/checkpoint.json"); !ok {
+		t.Error("The checkpoint of the failed task was removed")
+	}
+}
+
+func TestPauseMarksProgressCheckpoint(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	ctx := context.Background()
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, ScanID: 4, Domain: "example.com"}
+	checkpoint := &models.ScanCheckpoint{Task: models.TaskNuclei, ScanID: 4, Domain: "example.com", ResumeState: json.RawMessage(`{"resume_from":{}}`)}
+	content, _ := json.Marshal(checkpoint)
+	server.PutBlob("scans", "example.com-4/nuclei/checkpoint.json", content)
+
+	// A checkpoint taken while nuclei ran keeps its resume state and records when the task was paused
+	if result := h.pauseTask(ctx, taskMsg, h.createTaskResult(taskMsg), nil, errScanPaused); !result.Success || result.DeferUntil.IsZero() {
+		t.Fatalf("pauseTask() = %+v, want the task deferred", result)
+	}
+	stored, _ := server.Blob("scans", "example.com-4/nuclei/checkpoint.json")
+	var marked models.ScanCheckpoint
+	if err := json.Unmarshal(stored, &marked); err != nil || marked.PausedAt == "" || string(marked.ResumeState) != `{"resume_from":{}}` {
+		t.Errorf("Checkpoint = %s, want the resume state kept and the pause recorded", stored)
+	}
+}
+
+func TestNucleiCheckpoints(t *testing.T) {
+	h := &TaskHandler{}
+	resumed := &models.ScanCheckpoint{ResumeState: json.RawMessage(`{"resume_from":{}}`)}
+	tests := []struct {
+		name       string
+		worker     bool
+		config     models.ScannerConfig
+		checkpoint *models.ScanCheckpoint
+		want       bool
+	}{
+		{"off by default", false, &models.NucleiConfig{}, nil, false},
+		{"paused without resume state", false, &models.NucleiConfig{}, &models.ScanCheckpoint{}, false},
+		{"task asks for it", false, &models.NucleiConfig{Checkpoints: true}, nil, true},
+		{"worker turns it on", true, &models.NucleiConfig{}, nil, true},
+		{"resumed scan", false, &models.NucleiConfig{}, resumed, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.nucleiCheckpoint = tt.worker
+			if got := h.nucleiCheckpoints(tt.config, tt.checkpoint); got != tt.want {
+				t.Errorf("nucleiCheckpoints() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckpointProcessedTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		result models.ScannerResult
+		want   []string
+	}{
+		{"dnsx", models.DNSXResult{Records: map[string]models.ResolutionInfo{"b.example.com": {}, "a.example.com": {}}}, []string{"a.example.com", "b.example.com"}},
+		{"httpx", models.HttpxResult{Results: []models.HttpxHostResult{{Host: "a.example.com"}, {Host: "b.example.com"}}}, []string{"a.example.com", "b.example.com"}},
+		{"naabu starts over", models.NaabuResult{Ports: map[string][]models.PortInfo{"192.0.2.1": {{Port: 80}}}}, nil},
+		{"nothing ran", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkpointProcessedTargets(tt.result)
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkpointProcessedTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeCheckpoint(t *testing.T) {
+	checkpointOf := func(result models.ScannerResult) *models.ScanCheckpoint {
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &models.ScanCheckpoint{Result: data}
+	}
+	resolved := func(ip string) models.ResolutionInfo {
+		return models.ResolutionInfo{Status: models.DNSStatusResolved, A: []string{ip}}
+	}
+	finding := func(template, host string) models.NucleiVulnerability {
+		return models.NucleiVulnerability{TemplateID: template, Host: host, MatchedAt: host}
+	}
+	blocked := models.BlockEvent{Group: "example.com", Signal: "status_429"}
+
+	tests := []struct {
+		name       string
+		checkpoint *models.ScanCheckpoint
+		result     models.ScannerResult
+		want       models.ScannerResult
+	}{
+		{
+			name:       "dnsx keeps the resumed run's answer for a name",
+			checkpoint: checkpointOf(models.DNSXResult{Records: map[string]models.ResolutionInfo{"a.example.com": resolved("192.0.2.1"), "b.example.com": resolved("192.0.2.2")}}),
+			result:     models.DNSXResult{Records: map[string]models.ResolutionInfo{"b.example.com": resolved("192.0.2.3")}},
+			want: models.DNSXResult{
+				Records:  map[string]models.ResolutionInfo{"a.example.com": resolved("192.0.2.1"), "b.example.com": resolved("192.0.2.3")},
+				Metadata: models.SummarizeDNSRecords(map[string]models.ResolutionInfo{"a.example.com": resolved("192.0.2.1"), "b.example.com": resolved("192.0.2.3")}),
+			},
+		},
+		{
+			name:       "streamed dnsx is not merged",
+			checkpoint: checkpointOf(models.DNSXResult{Records: map[string]models.ResolutionInfo{"a.example.com": resolved("192.0.2.1")}}),
+			result:     models.DNSXResult{RecordsBlob: "example.com-1/dns_resolve/records.ndjson.gz", RecordsCount: 2},
+			want:       models.DNSXResult{RecordsBlob: "example.com-1/dns_resolve/records.ndjson.gz", RecordsCount: 2},
+		},
+		{
+			name:       "httpx appends the checkpointed hosts and blocks",
+			checkpoint: checkpointOf(models.HttpxResult{Results: []models.HttpxHostResult{{Host: "a.example.com"}}, Blocks: []models.BlockEvent{blocked}}),
+			result:     models.HttpxResult{Results: []models.HttpxHostResult{{Host: "b.example.com"}}},
+			want:       models.HttpxResult{Results: []models.HttpxHostResult{{Host: "a.example.com"}, {Host: "b.example.com"}}, Blocks: []models.BlockEvent{blocked}},
+		},
+		{
+			name:       "naabu adds the ports it did not find again",
+			checkpoint: checkpointOf(models.NaabuResult{Ports: map[string][]models.PortInfo{"192.0.2.1": {{Port: 80, Protocol: "tcp"}, {Port: 443, Protocol: "tcp"}}, "192.0.2.2": {{Port: 22, Protocol: "tcp"}}}}),
+			result:     models.NaabuResult{Ports: map[string][]models.PortInfo{"192.0.2.1": {{Port: 443, Protocol: "tcp"}}}},
+			want:       models.NaabuResult{Ports: map[string][]models.PortInfo{"192.0.2.1": {{Port: 443, Protocol: "tcp"}, {Port: 80, Protocol: "tcp"}}, "192.0.2.2": {{Port: 22, Protocol: "tcp"}}}},
+		},
+		{
+			name:       "nuclei drops findings the resumed run found again",
+			checkpoint: checkpointOf(models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{finding("git-config", "https://a.example.com"), finding("exposed-panel", "https://a.example.com")}}),
+			result:     models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{finding("exposed-panel", "https://a.example.com")}},
+			want:       models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{finding("git-config", "https://a.example.com"), finding("exposed-panel", "https://a.example.com")}},
+		},
+		{
+			name:       "subfinder starts over",
+			checkpoint: checkpointOf(models.SubfinderResult{Subdomains: []string{"a.example.com"}}),
+			result:     models.SubfinderResult{Subdomains: []string{"b.example.com"}},
+			want:       models.SubfinderResult{Subdomains: []string{"b.example.com"}},
+		},
+		{
+			name:       "held task without a result",
+			checkpoint: &models.ScanCheckpoint{},
+			result:     models.HttpxResult{Results: []models.HttpxHostResult{{Host: "b.example.com"}}},
+			want:       models.HttpxResult{Results: []models.HttpxHostResult{{Host: "b.example.com"}}},
+		},
+		{
+			name:       "unreadable checkpoint",
+			checkpoint: &models.ScanCheckpoint{Result: json.RawMessage(`"not a result"`)},
+			result:     models.HttpxResult{Results: []models.HttpxHostResult{{Host: "b.example.com"}}},
+			want:       models.HttpxResult{Results: []models.HttpxHostResult{{Host: "b.example.com"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeCheckpoint(tt.checkpoint, tt.result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeCheckpoint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveLinesFromFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		entries []string
+		want    string
+	}{
+		{"removes finished hosts", "a.example.com\nb.example.com\nc.example.com\n", []string{"a.example.com", "c.example.com"}, "b.example.com\n"},
+		{"ignores surrounding spaces", "a.example.com \r\n b.example.com", []string{"a.example.com"}, " b.example.com"},
+		{"nothing finished", "a.example.com\n", nil, "a.example.com\n"},
+		{"everything finished", "a.example.com", []string{"a.example.com"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hosts.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := removeLinesFromFile(path, tt.entries); err != nil {
+				t.Fatalf("removeLinesFromFile() error = %v", err)
+			}
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.want {
+				t.Errorf("File = %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+				t.Error("The temporary file was left behind")
+			}
+		})
+	}
+
+	if err := removeLinesFromFile(filepath.Join(t.TempDir(), "missing.txt"), []string{"a"}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetResultCompactionDays sets the age in days past which compact control messages that name no
+// age of their own archive a domain's scans
+func (h *TaskHandler) SetResultCompactionDays(days int) {
+	h.compactionDays = days
+}
+
+// compactedScan is a scan of a domain due for compaction and the blobs it stored
+type compactedScan struct {
+	id          int
+	blobs       []azure.BlobInfo
+	completedAt time.Time            // Last write to any of its blobs
+	tasks       map[models.Task]bool // Tasks with blobs in the scan
+}
+
+// compactResults archives the scans of a compact message's domain whose blobs were all written
+// before the compaction age and whose results later scans superseded, one archive per month, and
+// deletes the archived blobs
+func (h *TaskHandler) compactResults(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if h.blobClient == nil {
+		gologger.Warning().Msgf("Ignoring compact message for %s: blob storage is not configured", taskMsg.Domain)
+		return &models.MessageProcessingResult{Success: true}
+	}
+	days := taskMsg.OlderThanDays
+	if days == 0 {
+		days = h.compactionDays
+	}
+	if days <= 0 {
+		return h.createFailureResult(common.NewValidationError("older_than_days", "older_than_days is required when no compaction age is configured"), false)
+	}
+	cutoff := time.Now().AddDate(0, 0, -days)
+
+	scans, err := h.compactionCandidates(ctx, taskMsg.TenantID, taskMsg.Domain, cutoff)
+	if err != nil {
+		gologger.Error().Msgf("Failed to list the scans of %s to compact: %v", taskMsg.Domain, err)
+		return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+	}
+	if len(scans) == 0 {
+		gologger.Info().Msgf("No scans of %s older than %d days to compact", taskMsg.Domain, days)
+		return &models.MessageProcessingResult{Success: true}
+	}
+
+	months := make(map[string][]compactedScan)
+	for _, scan := range scans {
+		month := models.ArchiveMonth(scan.completedAt)
+		months[month] = append(months[month], scan)
+	}
+	previous, err := h.archivedItemsBefore(ctx, taskMsg.TenantID, taskMsg.Domain, slices.Min(slices.Collect(maps.Keys(months))))
+	if err != nil {
+		gologger.Error().Msgf("Failed to read the result archives of %s: %v", taskMsg.Domain, err)
+		return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+	}
+
+	deleted := 0
+	for _, month := range slices.Sorted(maps.Keys(months)) {
+		var count int
+		previous, count, err = h.compactMonth(ctx, taskMsg.TenantID, taskMsg.Domain, month, months[month], previous)
+		deleted += count
+		if err != nil {
+			gologger.Error().Msgf("Failed to compact the %s scans of %s: %v", month, taskMsg.Domain, err)
+			return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+		}
+	}
+
+	gologger.Info().Msgf("Compacted %d scans of %s older than %d days into %d monthly archives, removing %d blobs",
+		len(scans), taskMsg.Domain, days, len(months), deleted)
+	return &models.MessageProcessingResult{Success: true}
+}
+
+// compactionCandidates returns the scans of a domain whose blobs were all written before the
+// cutoff, oldest first. Scans with a later blob may still be running and are left alone. The
+// newest scan of each task holds the task's latest result, so a scan is only returned when every
+// task it ran has a newer scan.
+func (h *TaskHandler) compactionCandidates(ctx context.Context, tenantID, domain string, cutoff time.Time) ([]compactedScan, error) {
+	blobs, err := h.blobClient.ListBlobs(ctx, models.DomainScansPrefix(tenantID, domain))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*compactedScan)
+	for _, blob := range blobs {
+		scanID, name, ok := models.ScanBlob(tenantID, domain, blob.Name)
+		if !ok {
+			continue
+		}
+		scan := byID[scanID]
+		if scan == nil {
+			scan = &compactedScan{id: scanID, tasks: make(map[models.Task]bool)}
+			byID[scanID] = scan
+		}
+		scan.blobs = append(scan.blobs, blob)
+		if blob.LastModified.After(scan.completedAt) {
+			scan.completedAt = blob.LastModified
+		}
+		if task, _, ok := strings.Cut(name, "/"); ok {
+			scan.tasks[models.Task(task)] = true
+		}
+	}
+
+	newest := make(map[models.Task]int)
+	for _, scan := range byID {
+		for task := range scan.tasks {
+			newest[task] = max(newest[task], scan.id)
+		}
+	}
+
+	var scans []compactedScan
+	for _, scan := range byID {
+		if scan.completedAt.Before(cutoff) && superseded(scan, newest) {
+			scans = append(scans, *scan)
+		}
+	}
+	slices.SortFunc(scans, func(a, b compactedScan) int {
+		if c := a.completedAt.Compare(b.completedAt); c != 0 {
+			return c
+		}
+		return a.id - b.id
+	})
+	return scans, nil
+}
+
+// superseded reports whether a newer scan ran every task of a scan, given the newest scan of
+// each task
+func superseded(scan *compactedScan, newest map[models.Task]int) bool {
+	for task := range scan.tasks {
+		if newest[task] == scan.id {
+			return false
+		}
+	}
+	return true
+}
+
+// archivedItemsBefore returns the result items of the last archived scans before a month, for the
+// changes of the first scan compacted into it
+func (h *TaskHandler) archivedItemsBefore(ctx context.Context, tenantID, domain, month string) (map[models.Task][]string, error) {
+	archives, err := h.blobClient.ListBlobs(ctx, models.ResultArchivePrefix(tenantID, domain))
+	if err != nil {
+		return nil, err
+	}
+	latest := ""
+	for _, archive := range archives {
+		if archived, ok := models.ResultArchiveMonth(tenantID, domain, archive.Name); ok && archived < month && archived > latest {
+			latest = archived
+		}
+	}
+	if latest == "" {
+		return nil, nil
+	}
+
+	archived, err := h.loadResultArchive(ctx, tenantID, domain, latest)
+	if err != nil {
+		return nil, err
+	}
+	var items map[models.Task][]string
+	for i := range archived {
+		items = archived[i].Compare(items)
+	}
+	return items, nil
+}
+
+// compactMonth writes the scans of a month that are not archived yet to a new part of the month's
+// archive, then deletes the blobs of all its scans. It takes the result items of the last archived
+// scans and returns them moved past the month's, with the number of deleted blobs.
+func (h *TaskHandler) compactMonth(ctx context.Context, tenantID, domain, month string, scans []compactedScan, previous map[models.Task][]string) (map[models.Task][]string, int, error) {
+	archived, err := h.loadResultArchive(ctx, tenantID, domain, month)
+	if err != nil {
+		return previous, 0, err
+	}
+
+	// A compaction interrupted after writing its part left scans that are archived already
+	done := make(map[int]bool, len(archived))
+	for i := range archived {
+		done[archived[i].ScanID] = true
+		previous = archived[i].Compare(previous)
+	}
+	var pending []compactedScan
+	for _, scan := range scans {
+		if !done[scan.id] {
+			pending = append(pending, scan)
+		}
+	}
+
+	if len(pending) > 0 {
+		archivePath := models.ResultArchiveBlobPath(tenantID, domain, month, pending[0].id)
+		if previous, err = h.writeResultArchive(ctx, archivePath, tenantID, domain, pending, previous); err != nil {
+			return previous, 0, err
+		}
+		gologger.Info().Msgf("Archived %d scans of %s to %s", len(pending), domain, archivePath)
+	}
+
+	// The blobs are only deleted once the archive holding them is stored
+	deleted := 0
+	for _, scan := range scans {
+		for _, blob := range scan.blobs {
+			if err := h.blobClient.DeleteBlob(ctx, blob.Name); err != nil {
+				return previous, deleted, err
+			}
+			deleted++
+		}
+	}
+	return previous, deleted, nil
+}
+
+// loadResultArchive reads the parts of a month's result archive, returning their scans in the
+// order they were compacted. A month without an archive has none.
+func (h *TaskHandler) loadResultArchive(ctx context.Context, tenantID, domain, month string) ([]models.ArchivedScan, error) {
+	parts, err := h.blobClient.ListBlobs(ctx, models.ResultArchiveMonthPrefix(tenantID, domain, month))
+	if err != nil {
+		return nil, err
+	}
+	var scans []models.ArchivedScan
+	for _, part := range parts {
+		if _, ok := models.ResultArchiveMonth(tenantID, domain, part.Name); !ok {
+			continue
+		}
+		content, err := h.blobClient.ReadFileFromBlob(ctx, part.Name)
+		if err != nil {
+			return nil, err
+		}
+		partScans, err := models.ReadResultArchive(content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid result archive %s: %w", part.Name, err)
+		}
+		scans = append(scans, partScans...)
+	}
+	slices.SortFunc(scans, func(a, b models.ArchivedScan) int {
+		if c := a.CompletedAt.Compare(b.CompletedAt); c != 0 {
+			return c
+		}
+		return a.ScanID - b.ScanID
+	})
+	return scans, nil
+}
+
+// writeResultArchive streams scans to a new archive part, reading their blobs one at a time, and
+// returns the result items of earlier scans moved past them. The part is only created, never
+// overwritten, so a concurrent compaction of the same scans cannot replace the part another stored.
+func (h *TaskHandler) writeResultArchive(ctx context.Context, blobPath, tenantID, domain string, scans []compactedScan, previous map[models.Task][]string) (map[models.Task][]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := h.blobClient.StreamNewArtifact(ctx, blobPath)
+	if err != nil {
+		return previous, err
+	}
+
+	archive := models.NewResultArchiveWriter(stream)
+	for _, scan := range scans {
+		if previous, err = h.writeArchivedScan(ctx, archive, tenantID, domain, scan, previous); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		// Cancelling the upload before ending it keeps the incomplete part from being stored
+		cancel()
+		stream.Close()
+		return previous, err
+	}
+
+	if err := stream.Close(); err != nil {
+		if bloberror.HasCode(err, bloberror.BlobAlreadyExists) {
+			return previous, common.NewInternalError("a concurrent compaction stored "+blobPath+" first", err)
+		}
+		return previous, err
+	}
+	return previous, nil
+}
+
+// writeArchivedScan writes the line of a compacted scan to an archive. Its changes are found from
+// its latest.json pointers and the results they name before its blobs are copied one at a time.
+func (h *TaskHandler) writeArchivedScan(ctx context.Context, archive *models.ResultArchiveWriter, tenantID, domain string, scan compactedScan, previous map[models.Task][]string) (map[models.Task][]string, error) {
+	current, excluded, err := h.compactedItems(ctx, tenantID, domain, scan)
+	if err != nil {
+		return previous, err
+	}
+	changes, updated := models.CompareItems(previous, current, excluded)
+
+	err = archive.StartScan(&models.ArchivedScan{
+		TenantID:    tenantID,
+		Domain:      domain,
+		ScanID:      scan.id,
+		CompletedAt: scan.completedAt.UTC(),
+		ArchivedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return previous, err
+	}
+	for _, blob := range scan.blobs {
+		content, err := h.blobClient.ReadFileFromBlob(ctx, blob.Name)
+		if err != nil {
+			return previous, err
+		}
+		if err := archive.WriteBlob(models.NewArchivedBlob(blob.Name, blob.LastModified, content)); err != nil {
+			return previous, err
+		}
+	}
+	if err := archive.EndScan(changes); err != nil {
+		return previous, err
+	}
+	return updated, nil
+}
+
+// compactedItems returns the items of the latest result of each task of a compacted scan and the
+// templates its nuclei result excluded, as ArchivedScan.Items and ExcludedTemplates find them once
+// archived
+func (h *TaskHandler) compactedItems(ctx context.Context, tenantID, domain string, scan compactedScan) (map[models.Task][]string, []string, error) {
+	stored := make(map[string]bool, len(scan.blobs))
+	for _, blob := range scan.blobs {
+		stored[blob.Name] = true
+	}
+
+	items := make(map[models.Task][]string)
+	var excluded []string
+	for _, blob := range scan.blobs {
+		_, name, _ := models.ScanBlob(tenantID, domain, blob.Name)
+		task, file, _ := strings.Cut(name, "/")
+		if file != "latest.json" {
+			continue
+		}
+		content, err := h.blobClient.ReadFileFromBlob(ctx, blob.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		var latest models.LatestResult
+		if err := json.Unmarshal(content, &latest); err != nil || !stored[latest.BlobPath] {
+			continue
+		}
+		content, err = h.blobClient.ReadFileFromBlob(ctx, latest.BlobPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if resultItems, ok := models.StoredResultItems(models.Task(task), latest.BlobPath, content); ok {
+			items[models.Task(task)] = resultItems
+		}
+		if models.Task(task) == models.TaskNuclei {
+			excluded = models.StoredExcludedTemplates(content)
+		}
+	}
+	return items, excluded, nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestCompactResultsKeepsNewestScanOfEachTask(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	server.SetClock(func() time.Time { return time.Now().AddDate(0, 0, -60) })
+	blobs := []string{
+		"example.com-1/subfinder/latest.json",
+		"example.com-1/httpx/latest.json",
+		"example.com-2/subfinder/latest.json",
+		"example.com-3/subfinder/latest.json",
+	}
+	for _, name := range blobs {
+		server.PutBlob("scans", name, []byte(`{}`))
+	}
+
+	result := h.compactResults(context.Background(), &models.TaskMessage{Domain: "example.com", OlderThanDays: 30})
+	if !result.Success {
+		t.Fatalf("compactResults() failed: %v", result.Error)
+	}
+
+	// Scan 1 has the latest httpx result and scan 3 the latest subfinder result
+	want := []string{"example.com-1/httpx/latest.json", "example.com-1/subfinder/latest.json", "example.com-3/subfinder/latest.json"}
+	if got := server.Names("scans", "example.com-"); !slices.Equal(got, want) {
+		t.Errorf("blobs left = %v, want %v", got, want)
+	}
+	part := models.ResultArchiveBlobPath("", "example.com", models.ArchiveMonth(time.Now().AddDate(0, 0, -60)), 2)
+	content, ok := server.Blob("scans", part)
+	if !ok {
+		t.Fatalf("archive part %s not stored, have %v", part, server.Names("scans", "archive/"))
+	}
+	scans, err := models.ReadResultArchive(content)
+	if err != nil || len(scans) != 1 || scans[0].ScanID != 2 || len(scans[0].Blobs) != 1 {
+		t.Fatalf("archived scans = %+v, %v, want scan 2 with its blob", scans, err)
+	}
+
+	// A redelivered message finds the scan archived and stores no other part
+	server.PutBlob("scans", "example.com-2/subfinder/latest.json", []byte(`{}`))
+	if result := h.compactResults(context.Background(), &models.TaskMessage{Domain: "example.com", OlderThanDays: 30}); !result.Success {
+		t.Fatalf("compactResults() failed on redelivery: %v", result.Error)
+	}
+	if got := server.Names("scans", "archive/"); !slices.Equal(got, []string{part}) {
+		t.Errorf("archive parts = %v, want only %s", got, part)
+	}
+	if got := server.Names("scans", "example.com-2/"); len(got) != 0 {
+		t.Errorf("blobs of the archived scan left = %v", got)
+	}
+}
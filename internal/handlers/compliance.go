@@ -0,0 +1,13 @@
+package handlers
+
+import "github.com/allsafeASM/api/internal/models"
+
+// SetCompliance sets whether every nuclei task runs in compliance mode, honoring the robots.txt
+// of its targets, and the user agent compliance mode scans as; an empty user agent uses the default
+func (h *TaskHandler) SetCompliance(enforced bool, userAgent string) {
+	if userAgent == "" {
+		userAgent = models.DefaultComplianceUserAgent
+	}
+	h.complianceMode = enforced
+	h.complianceUserAgent = userAgent
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// EnableScanDigest sends a Discord digest of the whole scan once its final task stored a result.
+// A task is final when its message sets final_stage or its type is finalTask; an empty finalTask
+// relies on final_stage alone.
+func (h *TaskHandler) EnableScanDigest(finalTask models.Task) {
+	if h.discordNotifier == nil || !h.discordNotifier.IsEnabled() {
+		return
+	}
+
+	h.handleResults("digest", func(ctx context.Context, event events.Event) error {
+		taskMsg := event.TaskMessage
+		if !taskMsg.FinalStage && (finalTask == "" || taskMsg.Task != finalTask) {
+			return nil
+		}
+
+		digest, err := h.buildScanDigest(ctx, taskMsg)
+		if err != nil {
+			return err
+		}
+		gologger.Info().Msgf("Sending digest of scan %d for domain %s covering %d stages", taskMsg.ScanID, taskMsg.Domain, len(digest.Tasks))
+		return h.discordNotifier.NotifyDigest(ctx, taskMsg, digest)
+	})
+}
+
+// buildScanDigest assembles the digest of a scan from the latest stored result of every stage
+func (h *TaskHandler) buildScanDigest(ctx context.Context, taskMsg *models.TaskMessage) (*models.ScanDigest, error) {
+	digest := models.NewScanDigest(taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID)
+
+	for _, task := range models.DigestTasks {
+		latest, err := h.blobClient.LoadLatestResult(ctx, taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID, string(task))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load latest %s result: %w", task, err)
+		}
+		if latest == nil {
+			digest.Missing = append(digest.Missing, task)
+			continue
+		}
+
+		content, err := h.blobClient.ReadFileFromBlob(ctx, latest.BlobPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s result %s: %w", task, latest.BlobPath, err)
+		}
+		digest.AddSpan(latest.UpdatedAt, latest.Duration)
+
+		// Subfinder results are stored as text, one subdomain per line
+		if task == models.TaskSubfinder {
+			digest.AddSubdomains(content)
+			continue
+		}
+		if err := digest.AddResult(content); err != nil {
+			return nil, fmt.Errorf("failed to summarize %s result %s: %w", task, latest.BlobPath, err)
+		}
+	}
+
+	return digest, nil
+}
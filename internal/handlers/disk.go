@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/disk"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetDiskJanitor sets the janitor that keeps temp files and caches within the disk budget
+func (h *TaskHandler) SetDiskJanitor(janitor *disk.Janitor) {
+	h.disk = janitor
+}
+
+// checkDiskSpace returns a failure result when the disk has no room for the task's files. The
+// task is retried, by another worker or by this one once running tasks cleaned up.
+func (h *TaskHandler) checkDiskSpace(taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if h.disk == nil {
+		return nil
+	}
+	err := h.disk.CheckSpace()
+	if err == nil {
+		return nil
+	}
+
+	gologger.Error().Msgf("Not starting task %s for domain %s: %v", taskMsg.Task, taskMsg.Domain, err)
+	h.publishStep(taskMsg, nil, err, notification.StepTaskFailed)
+	return h.createFailureResult(err, true)
+}
+
+// reclaimDisk sweeps the disk after a task failed on a full disk, so the retry finds room
+func (h *TaskHandler) reclaimDisk(processingResult *models.MessageProcessingResult) {
+	if h.disk == nil || !common.IsDiskFull(processingResult.Error) {
+		return
+	}
+	if _, err := h.disk.Sweep(); err != nil {
+		gologger.Warning().Msgf("Disk sweep after a full disk failed: %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/exporters"
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// resultHandlerTimeout bounds the call of one result handler for one stored result
+const resultHandlerTimeout = 2 * time.Minute
+
+// resultHandler is a consumer that keeps state from stored results, such as incidents or an
+// exporter, and must not miss any of them
+type resultHandler struct {
+	name   string
+	handle func(ctx context.Context, event events.Event) error
+}
+
+// handleDiscordEvents sends task steps and progress reports to Discord. A backlog of stale step
+// notifications is worth less than the latest ones, so the oldest are dropped first.
+func (h *TaskHandler) handleDiscordEvents() {
+	options := events.HandlerOptions{
+		Filter: func(event events.Event) bool {
+			return event.Type == events.TypeLifecycle || event.Type == events.TypeProgressReport
+		},
+		Buffer: 100,
+		Policy: events.DropOldest,
+	}
+
+	h.events.Handle("discord", options, func(ctx context.Context, event events.Event) {
+		if event.Type == events.TypeProgressReport {
+			if err := h.discordNotifier.NotifyProgress(ctx, event.TaskMessage, *event.Progress, event.Elapsed); err != nil {
+				gologger.Warning().Msgf("Failed to send Discord progress notification for %s: %v", event.Task, err)
+			}
+			return
+		}
+
+		step := notification.NotificationStep(event.Step)
+		if err := h.discordNotifier.NotifyStep(ctx, step, event.TaskMessage, event.Result, event.Err); err != nil {
+			gologger.Warning().Msgf("Failed to send Discord notification for step %s: %v", step, err)
+		}
+	})
+}
+
+// handleLifecycleEvents sends task steps, and progress reports when it accepts them, to a lifecycle recorder
+func (h *TaskHandler) handleLifecycleEvents(recorder exporters.LifecycleRecorder) {
+	progressRecorder, recordsProgress := recorder.(exporters.ProgressRecorder)
+	options := events.HandlerOptions{
+		Filter: func(event events.Event) bool {
+			return event.Type == events.TypeLifecycle || (recordsProgress && event.Type == events.TypeProgressReport)
+		},
+	}
+
+	h.events.Handle(fmt.Sprintf("lifecycle:%T", recorder), options, func(ctx context.Context, event events.Event) {
+		if event.Type == events.TypeProgressReport {
+			progressRecorder.RecordProgress(event.TaskMessage, *event.Progress)
+			return
+		}
+		recorder.RecordStep(event.TaskMessage, event.Step, event.Result, event.Err)
+	})
+}
+
+// handleResults passes every stored task result to a side channel such as an exporter. Unlike the
+// bus subscribers, result handlers are called by the task itself, see deliverResult.
+func (h *TaskHandler) handleResults(name string, handle func(ctx context.Context, event events.Event) error) {
+	h.resultHandlers = append(h.resultHandlers, resultHandler{name: name, handle: handle})
+}
+
+// deliverResult hands a stored result to the result handlers one after the other, before the
+// message is completed. The bus would drop results for a handler that falls behind; this way a
+// slow handler slows its task down instead, and a crash before the outbox entry is stored
+// delivers the result again with the next run of the task. A failing handler is logged and does
+// not fail the task, whose result is already stored.
+func (h *TaskHandler) deliverResult(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, blobPath string) {
+	if len(h.resultHandlers) == 0 {
+		return
+	}
+	event := events.NewResultEvent(taskMsg, h.expandRecords(ctx, result), blobPath)
+	for _, handler := range h.resultHandlers {
+		if err := callResultHandler(ctx, handler, event); err != nil {
+			gologger.Warning().Msgf("Failed to handle result for domain %s in %s: %v", event.Domain, handler.name, err)
+		}
+	}
+}
+
+// expandRecords returns the result with the records of a streamed DNSX result inline, so that
+// documents, the inventory and the other result handlers see every record. The stored result
+// keeps them in its records blob. Without them, the handlers only see the result's summary.
+func (h *TaskHandler) expandRecords(ctx context.Context, result *models.TaskResult) *models.TaskResult {
+	data, ok := result.Data.(models.DNSXResult)
+	if !ok || data.RecordsBlob == "" || h.blobClient == nil {
+		return result
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resultHandlerTimeout)
+	defer cancel()
+	expanded, err := h.blobClient.ExpandDNSRecords(ctx, data)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to read the DNS records of domain %s for the result handlers: %v", result.Domain, err)
+		return result
+	}
+	resultCopy := *result
+	resultCopy.Data = expanded
+	return &resultCopy
+}
+
+// callResultHandler calls a result handler with its own timeout, turning a panic into an error
+func callResultHandler(ctx context.Context, handler resultHandler, event events.Event) (err error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resultHandlerTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handler.handle(ctx, event)
+}
+
+// handleMetricEvents counts task steps and stored results
+func (h *TaskHandler) handleMetricEvents(registry *metrics.Registry) {
+	counter := registry.Counter("asm_task_events_total", "Task steps and stored results published by the worker.", "task", "type", "step")
+	options := events.HandlerOptions{
+		Filter: func(event events.Event) bool {
+			return event.Type == events.TypeLifecycle || event.Type == events.TypeResult
+		},
+	}
+
+	h.events.Handle("metrics", options, func(ctx context.Context, event events.Event) {
+		counter.Inc(event.Task, string(event.Type), event.Step)
+	})
+}
+
+// SetTaskDurations keeps the moving average duration of each task type up to date from the
+// stored results, for the replica hint of the autoscaler
+func (h *TaskHandler) SetTaskDurations(durations *metrics.TaskDurations) {
+	options := events.HandlerOptions{
+		Filter: func(event events.Event) bool { return event.Type == events.TypeResult && event.Result != nil },
+	}
+
+	h.events.Handle("task_durations", options, func(ctx context.Context, event events.Event) {
+		// Results stored without running a scanner, such as skipped tasks, have no duration
+		duration, err := time.ParseDuration(event.Result.Duration)
+		if err != nil || duration <= 0 {
+			return
+		}
+		durations.Observe(event.Result.Task, duration)
+	})
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// checkFreezeList returns the freeze entry covering the task's domain, checking the global list and then the tenant's.
+// Lookup failures are logged and treated as not frozen so a storage hiccup does not stall every scan.
+func (h *TaskHandler) checkFreezeList(ctx context.Context, taskMsg *models.TaskMessage) *models.FreezeEntry {
+	if h.blobClient == nil {
+		return nil
+	}
+
+	paths := []string{models.FreezeListBlobPath("")}
+	if taskMsg.TenantID != "" {
+		paths = append(paths, models.FreezeListBlobPath(taskMsg.TenantID))
+	}
+
+	now := time.Now()
+	for _, path := range paths {
+		freezeList, err := h.blobClient.LoadFreezeList(ctx, path)
+		if err != nil {
+			gologger.Warning().Msgf("Failed to load freeze list %s: %v", path, err)
+			continue
+		}
+		if entry := freezeList.Match(taskMsg.Domain, now); entry != nil {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// skipFrozenTask completes a task for a frozen domain without scanning it, storing a skipped result and notifying
+func (h *TaskHandler) skipFrozenTask(ctx context.Context, taskMsg *models.TaskMessage, entry *models.FreezeEntry) *models.MessageProcessingResult {
+	result := h.createTaskResult(taskMsg)
+	result.Status = models.TaskStatusSkippedFrozen
+	result.Error = fmt.Sprintf("domain is frozen by scope %s", entry.Scope)
+	if entry.Reason != "" {
+		result.Error += ": " + entry.Reason
+	}
+
+	gologger.Info().Msgf("Skipping task %s for domain %s: %s", taskMsg.Task, taskMsg.Domain, result.Error)
+	h.publishStep(taskMsg, result, nil, notification.StepTaskSkipped)
+
+	// The orchestrator event carries no payload, so the skipped status is recorded as the task's result
+	if _, err := h.blobClient.StoreTaskResult(ctx, result); err != nil {
+		gologger.Error().Msgf("Failed to store skipped result for domain %s: %v", taskMsg.Domain, err)
+		return h.createFailureResult(err, true)
+	}
+
+	if h.notifier != nil {
+		if err := h.sendCompletionNotification(ctx, taskMsg, result); err != nil {
+			gologger.Warning().Msgf("Failed to send skip notification for domain %s: %v", taskMsg.Domain, err)
+		}
+	}
+
+	return &models.MessageProcessingResult{Success: true}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetMultiDomainParallelism sets how many domains of a multi-domain task run at once
+func (h *TaskHandler) SetMultiDomainParallelism(parallelism int) {
+	h.multiDomainParallelism = parallelism
+}
+
+// handleMultiDomainTask runs a task that lists its domains for each of them, as if every domain
+// had arrived as its own message with the same scan_id. Each domain stores its own result and
+// raises its own completion event. Domains that fail for good are recorded in their error
+// artifacts. The message is retried or deferred when any domain must run again; the domains that
+// finished are recorded in the message's progress, so a later delivery only runs the others.
+func (h *TaskHandler) handleMultiDomainTask(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	domains, err := h.messageDomains(ctx, taskMsg)
+	if err != nil {
+		gologger.Error().Msgf("Rejected multi-domain %s task for scan %d: %v", taskMsg.Task, taskMsg.ScanID, err)
+		h.publishStep(taskMsg, nil, err, notification.StepTaskFailed)
+		return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+	}
+
+	parallelism := max(h.multiDomainParallelism, 1)
+	gologger.Info().Str("correlation_id", taskMsg.CorrelationID).Msgf("Processing %s task for %d domains of scan %d, %d at a time",
+		taskMsg.Task, len(domains), taskMsg.ScanID, parallelism)
+
+	var (
+		wg       sync.WaitGroup
+		slots    = make(chan struct{}, parallelism)
+		results  = make([]*models.MessageProcessingResult, len(domains))
+		progress = h.loadDomainProgress(ctx, taskMsg)
+	)
+	for i, domain := range domains {
+		if outcome, ok := progress[domain]; ok {
+			results[i] = finishedDomainResult(outcome)
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, domain string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = h.HandleTask(ctx, taskMsg.ForDomain(domain))
+			h.recordDomainOutcome(ctx, taskMsg, domain, results[i])
+		}(i, domain)
+	}
+	wg.Wait()
+
+	if skipped := len(progress); skipped > 0 {
+		gologger.Info().Msgf("Skipped %d domains of multi-domain %s task for scan %d that an earlier delivery finished",
+			skipped, taskMsg.Task, taskMsg.ScanID)
+	}
+	result := h.combineDomainResults(taskMsg, domains, results)
+	if result.DeferUntil.IsZero() && (result.Success || !result.Retryable) {
+		h.clearDomainProgress(ctx, taskMsg)
+	}
+	return result
+}
+
+// loadDomainProgress returns the outcomes of the domains an earlier delivery of a multi-domain
+// message finished. Without them every domain runs again, which only repeats work.
+func (h *TaskHandler) loadDomainProgress(ctx context.Context, taskMsg *models.TaskMessage) map[string]models.DomainOutcome {
+	if h.blobClient == nil {
+		return nil
+	}
+	progress, err := h.blobClient.LoadMultiDomainProgress(ctx, taskMsg)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to load the progress of multi-domain %s task for scan %d, running all domains: %v", taskMsg.Task, taskMsg.ScanID, err)
+		return nil
+	}
+	if progress == nil {
+		return nil
+	}
+	return progress.Domains
+}
+
+// recordDomainOutcome records a domain of a multi-domain message that finished for good. Domains
+// to retry or defer are not recorded, so the next delivery of the message runs them again.
+func (h *TaskHandler) recordDomainOutcome(ctx context.Context, taskMsg *models.TaskMessage, domain string, result *models.MessageProcessingResult) {
+	if h.blobClient == nil || result == nil || result.DeadLetterReason != "" || !result.DeferUntil.IsZero() {
+		return
+	}
+	outcome := models.DomainOutcome{Status: models.DomainCompleted}
+	switch {
+	case !result.Success && result.Retryable:
+		return
+	case !result.Success:
+		outcome.Status = models.DomainFailed
+		if result.Error != nil {
+			outcome.Error = result.Error.Error()
+		}
+	}
+
+	// The domain's result is already stored, so a lost record only makes a retry run it again
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), partialResultStoreTimeout)
+	defer cancel()
+	err := h.blobClient.UpdateMultiDomainProgress(ctx, taskMsg, func(progress *models.MultiDomainProgress) error {
+		progress.Domains[domain] = outcome
+		progress.UpdatedAt = time.Now().UTC()
+		return nil
+	})
+	if err != nil {
+		gologger.Warning().Msgf("Failed to record domain %s of multi-domain %s task for scan %d as %s: %v", domain, taskMsg.Task, taskMsg.ScanID, outcome.Status, err)
+	}
+}
+
+// clearDomainProgress removes the progress of a multi-domain message once it is not delivered again
+func (h *TaskHandler) clearDomainProgress(ctx context.Context, taskMsg *models.TaskMessage) {
+	if h.blobClient == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), partialResultStoreTimeout)
+	defer cancel()
+	if err := h.blobClient.DeleteBlob(ctx, models.MultiDomainProgressBlobPath(taskMsg)); err != nil {
+		gologger.Warning().Msgf("Failed to remove the progress of multi-domain %s task for scan %d: %v", taskMsg.Task, taskMsg.ScanID, err)
+	}
+}
+
+// finishedDomainResult returns the result of a domain that an earlier delivery finished
+func finishedDomainResult(outcome models.DomainOutcome) *models.MessageProcessingResult {
+	if outcome.Status == models.DomainFailed {
+		return &models.MessageProcessingResult{Success: false, Error: errors.New(outcome.Error)}
+	}
+	return &models.MessageProcessingResult{Success: true}
+}
+
+// messageDomains returns the domains of a multi-domain task, read from its domain list blob when
+// it has one, after checking that the task may run for many domains
+func (h *TaskHandler) messageDomains(ctx context.Context, taskMsg *models.TaskMessage) ([]string, error) {
+	switch {
+	case !slices.Contains(models.MultiDomainTasks, taskMsg.Task):
+		return nil, common.NewValidationError("domains", fmt.Sprintf("task type %s cannot run for a list of domains", taskMsg.Task))
+	case taskMsg.Domain != "":
+		return nil, common.NewValidationError("domains", "set either domain or a domain list, not both")
+	case len(taskMsg.Domains) > 0 && taskMsg.DomainsBlobPath != "":
+		return nil, common.NewValidationError("domains", "set either domains or domains_blob_path, not both")
+	case taskMsg.FilePath != "" || taskMsg.InputResultPath != "":
+		return nil, common.NewValidationError("domains", "multi-domain tasks cannot take an input blob")
+	case taskMsg.ScanID == 0:
+		return nil, common.NewValidationError("scan_id", "scan_id is required")
+	}
+
+	domains := taskMsg.Domains
+	if taskMsg.DomainsBlobPath != "" {
+		if h.blobClient == nil {
+			return nil, common.NewConfigurationError("domains_blob_path", "blob storage is required to read a domain list")
+		}
+		// Domain lists are not tied to one domain, so they live under the scan's input prefix
+		blobPath := h.blobClient.CleanBlobPath(taskMsg.DomainsBlobPath)
+		if err := h.validator.ValidateBlobPath(blobPath, models.ScanInputPrefix(taskMsg.TenantID, taskMsg.ScanID)); err != nil {
+			return nil, err
+		}
+		list, err := h.blobClient.ReadHostsFileFromBlob(ctx, blobPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the domain list: %w", err)
+		}
+		domains = list.Hosts
+	}
+
+	domains = models.UniqueDomains(domains)
+	if len(domains) == 0 {
+		return nil, common.NewValidationError("domains", "the domain list is empty")
+	}
+	if len(domains) > models.MaxMessageDomains {
+		return nil, common.NewValidationError("domains", fmt.Sprintf("%d domains exceed the limit of %d per message", len(domains), models.MaxMessageDomains))
+	}
+	return domains, nil
+}
+
+// combineDomainResults turns the results of a multi-domain task's domains into the result of its
+// message. A halted scan dead-letters the message, then any domain to retry or not yet run
+// retries it, then any deferred domain defers it.
+func (h *TaskHandler) combineDomainResults(taskMsg *models.TaskMessage, domains []string, results []*models.MessageProcessingResult) *models.MessageProcessingResult {
+	var (
+		completed, failed, retry, notRun int
+		deferUntil                       time.Time
+		lastErr                          error
+	)
+	for _, result := range results {
+		switch {
+		case result == nil:
+			notRun++
+		case result.DeadLetterReason != "":
+			return result
+		case !result.Success && result.Retryable:
+			retry++
+			lastErr = result.Error
+		case !result.Success:
+			failed++
+			lastErr = result.Error
+		case !result.DeferUntil.IsZero():
+			if deferUntil.IsZero() || result.DeferUntil.Before(deferUntil) {
+				deferUntil = result.DeferUntil
+			}
+		default:
+			completed++
+		}
+	}
+
+	gologger.Info().Msgf("Multi-domain %s task for scan %d: %d of %d domains completed, %d failed, %d to retry, %d not run",
+		taskMsg.Task, taskMsg.ScanID, completed, len(domains), failed, retry, notRun)
+
+	switch {
+	case retry > 0 || notRun > 0:
+		err := fmt.Errorf("%d of %d domains must be retried and %d were not run", retry, len(domains), notRun)
+		if lastErr != nil {
+			err = fmt.Errorf("%w, last error: %v", err, lastErr)
+		}
+		return h.createFailureResult(err, true)
+	case !deferUntil.IsZero():
+		return &models.MessageProcessingResult{Success: true, DeferUntil: deferUntil}
+	case completed == 0:
+		return h.createFailureResult(fmt.Errorf("all %d domains failed, last error: %w", len(domains), lastErr), false)
+	}
+	return &models.MessageProcessingResult{Success: true}
+}
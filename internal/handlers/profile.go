@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetScanProfiles sets the scan profiles tasks reference by name, before the profiles stored
+// in blob storage are layered on top
+func (h *TaskHandler) SetScanProfiles(profiles models.ScanProfiles) {
+	h.scanProfiles = profiles
+}
+
+// applyScanProfile fills the config keys the task leaves unset from the scan profile it names.
+// The global profiles in storage override the configured ones, and the tenant's override both.
+// It returns a failure result when the profile is unknown or the profiles cannot be read.
+func (h *TaskHandler) applyScanProfile(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if taskMsg.Profile == "" {
+		return nil
+	}
+
+	profiles, err := h.loadScanProfiles(ctx, taskMsg.TenantID)
+	if err != nil {
+		gologger.Error().Msgf("Failed to load scan profiles for task %s for domain %s: %v", taskMsg.Task, taskMsg.Domain, err)
+		h.publishStep(taskMsg, nil, err, notification.StepTaskFailed)
+		return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+	}
+
+	config, err := profiles.Apply(taskMsg.Profile, taskMsg.Task, taskMsg.Config)
+	if err == nil {
+		// The profile's keys are checked against the task type together with the task's own
+		_, err = models.ParseTaskConfig(taskMsg.Task, config)
+	}
+	if err != nil {
+		validationErr := common.NewValidationError("profile", err.Error())
+		gologger.Error().Msgf("Task %s for domain %s: %v", taskMsg.Task, taskMsg.Domain, validationErr)
+		h.publishStep(taskMsg, nil, validationErr, notification.StepTaskFailed)
+		return h.createFailureResult(validationErr, false)
+	}
+
+	gologger.Info().Msgf("Applying scan profile %s to task %s for domain %s", taskMsg.Profile, taskMsg.Task, taskMsg.Domain)
+	taskMsg.Config = config
+	// The config now holds the profile's keys, so the per-domain tasks of a multi-domain task do not apply it again
+	taskMsg.Profile = ""
+	return nil
+}
+
+// loadScanProfiles returns the configured profiles with the global and then the tenant's stored profiles layered on top
+func (h *TaskHandler) loadScanProfiles(ctx context.Context, tenantID string) (models.ScanProfiles, error) {
+	profiles := models.DefaultScanProfiles
+	if h.scanProfiles != nil {
+		profiles = h.scanProfiles
+	}
+	if h.blobClient == nil {
+		return profiles, nil
+	}
+
+	paths := []string{models.ScanProfilesBlobPath("")}
+	if tenantID != "" {
+		paths = append(paths, models.ScanProfilesBlobPath(tenantID))
+	}
+	for _, path := range paths {
+		stored, err := h.blobClient.LoadScanProfiles(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scan profiles %s: %w", path, err)
+		}
+		profiles = profiles.Merge(stored)
+	}
+	return profiles, nil
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/exporters"
+	"github.com/allsafeASM/api/internal/metrics"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// liveProgressInterval is the shortest time between progress events of a task on the event bus
+const liveProgressInterval = time.Second
+
+// taskMetrics are the gauges running tasks report their progress through
+type taskMetrics struct {
+	inProgress *metrics.Gauge
+	processed  *metrics.Gauge
+	total      *metrics.Gauge
+	ratio      *metrics.Gauge
+}
+
+// newTaskMetrics registers the task progress gauges
+func newTaskMetrics(registry *metrics.Registry) *taskMetrics {
+	progressLabels := []string{"task", "scan_id", "tenant", "phase"}
+	return &taskMetrics{
+		inProgress: registry.Gauge("asm_tasks_in_progress", "Tasks whose scanner is running.", "task"),
+		processed:  registry.Gauge("asm_task_progress_processed", "Items processed in the current phase of a running task.", progressLabels...),
+		total:      registry.Gauge("asm_task_progress_total", "Items in the current phase of a running task, 0 when unknown.", progressLabels...),
+		ratio:      registry.Gauge("asm_task_progress_ratio", "Completed fraction of the current phase of a running task.", progressLabels...),
+	}
+}
+
+// progressReporter collects the progress a scanner reports, keeps the progress gauges current,
+// publishes it live on the event bus and sends the latest progress to Discord and the lifecycle
+// recorders at a fixed interval
+type progressReporter struct {
+	handler *TaskHandler
+	taskMsg *models.TaskMessage
+	started time.Time
+	labels  []string // task, scan_id and tenant labels of the progress gauges
+
+	mu       sync.Mutex
+	latest   models.ScanProgress
+	updated  bool      // Whether latest changed since it was last sent
+	reported bool      // Whether any progress was reported, so the gauges have a series to delete
+	live     time.Time // When progress was last published on the event bus
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startProgressReporter starts reporting the progress of a task until stop is called
+func (h *TaskHandler) startProgressReporter(ctx context.Context, taskMsg *models.TaskMessage) *progressReporter {
+	r := &progressReporter{
+		handler: h,
+		taskMsg: taskMsg,
+		started: time.Now(),
+		labels:  []string{string(taskMsg.Task), strconv.Itoa(taskMsg.ScanID), taskMsg.TenantID},
+		done:    make(chan struct{}),
+	}
+	if h.metrics != nil {
+		h.metrics.inProgress.Add(1, string(taskMsg.Task))
+	}
+
+	if h.progressInterval <= 0 || !h.sendsProgressUpdates() {
+		close(r.done)
+		r.cancel = func() {}
+		return r
+	}
+
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.run(ctx, h.progressInterval)
+	return r
+}
+
+// sendsProgressUpdates reports whether anything receives periodic progress updates
+func (h *TaskHandler) sendsProgressUpdates() bool {
+	if h.discordNotifier != nil && h.discordNotifier.IsEnabled() {
+		return true
+	}
+	for _, recorder := range h.lifecycle {
+		if _, ok := recorder.(exporters.ProgressRecorder); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// report records the latest progress of the scanner and updates the gauges
+func (r *progressReporter) report(progress models.ScanProgress) {
+	r.mu.Lock()
+	previousPhase, hadProgress := r.latest.Phase, r.reported
+	r.latest, r.updated, r.reported = progress, true, true
+	publish := time.Since(r.live) >= liveProgressInterval || previousPhase != progress.Phase
+	if publish {
+		r.live = time.Now()
+	}
+	r.mu.Unlock()
+
+	if publish {
+		r.handler.events.RecordProgress(r.taskMsg, progress)
+	}
+
+	m := r.handler.metrics
+	if m == nil {
+		return
+	}
+	if hadProgress && previousPhase != progress.Phase {
+		r.deleteGauges(previousPhase)
+	}
+	labels := append(r.labels[:len(r.labels):len(r.labels)], progress.Phase)
+	m.processed.Set(float64(progress.Processed), labels...)
+	m.total.Set(float64(progress.Total), labels...)
+	if ratio := progress.Ratio(); ratio >= 0 {
+		m.ratio.Set(ratio, labels...)
+	}
+}
+
+// run sends the latest progress every interval while the scanner keeps reporting new progress
+func (r *progressReporter) run(ctx context.Context, interval time.Duration) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			progress, updated := r.latest, r.updated
+			r.updated = false
+			r.mu.Unlock()
+
+			if updated {
+				r.send(progress)
+			}
+		}
+	}
+}
+
+// send publishes a progress report, which the event bus sends on to Discord and the lifecycle
+// recorders that accept progress
+func (r *progressReporter) send(progress models.ScanProgress) {
+	r.handler.events.RecordProgressReport(r.taskMsg, progress, time.Since(r.started))
+}
+
+// stop ends the periodic updates and removes the task's gauges
+func (r *progressReporter) stop() {
+	r.cancel()
+	<-r.done
+
+	m := r.handler.metrics
+	if m == nil {
+		return
+	}
+	m.inProgress.Add(-1, string(r.taskMsg.Task))
+
+	r.mu.Lock()
+	phase, reported := r.latest.Phase, r.reported
+	r.mu.Unlock()
+	if reported {
+		r.deleteGauges(phase)
+	}
+}
+
+// deleteGauges removes the progress gauges of a phase
+func (r *progressReporter) deleteGauges(phase string) {
+	m := r.handler.metrics
+	labels := append(r.labels[:len(r.labels):len(r.labels)], phase)
+	m.processed.Delete(labels...)
+	m.total.Delete(labels...)
+	m.ratio.Delete(labels...)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// qualityHoldRecheck is how long a task held for review waits before it checks the scan again
+const qualityHoldRecheck = 15 * time.Minute
+
+// SetQualityGates sets the thresholds stage results are checked against and the tasks that wait
+// for review while a scan is degraded
+func (h *TaskHandler) SetQualityGates(gates models.QualityGates) {
+	h.qualityGates = gates
+}
+
+// checkQualityGates checks a completed stage's result against the quality gates. The check that
+// marks the scan degraded notifies operators; held tasks of the scan then wait for a review.
+func (h *TaskHandler) checkQualityGates(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, scannerResult models.ScannerResult) {
+	if !h.qualityGates.Enabled() || h.blobClient == nil {
+		return
+	}
+	check := h.qualityGates.Check(taskMsg.Task, scannerResult, time.Now().UTC())
+	if check == nil {
+		return
+	}
+
+	degraded := false
+	err := h.blobClient.UpdateScanQuality(ctx, taskMsg.TenantID, taskMsg.ScanID, func(quality *models.ScanQuality) error {
+		degraded = quality.Record(*check)
+		return nil
+	})
+	if err != nil {
+		// The gates only flag suspicious results, so the task completes as usual
+		gologger.Warning().Msgf("Failed to record quality check of scan %d: %v", taskMsg.ScanID, err)
+		return
+	}
+	if check.Passed {
+		gologger.Debug().Msgf("Scan %d passed its quality gate: %s", taskMsg.ScanID, check)
+		return
+	}
+
+	gologger.Warning().Msgf("Scan %d failed its quality gate: %s", taskMsg.ScanID, check)
+	if degraded {
+		h.publishStep(taskMsg, result, errors.New(check.String()), notification.StepScanDegraded)
+	}
+}
+
+// checkQualityHold returns a deferral result for a task that waits for review because its scan is
+// degraded, or a failure result once it waited longer than the gates allow. Lookup errors let the
+// task run, like an unreadable pause marker.
+func (h *TaskHandler) checkQualityHold(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if !h.qualityGates.Holds(taskMsg.Task) || h.blobClient == nil {
+		return nil
+	}
+	quality, err := h.blobClient.LoadScanQuality(ctx, taskMsg.TenantID, taskMsg.ScanID)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to check the quality of scan %d: %v", taskMsg.ScanID, err)
+		return nil
+	}
+	if !quality.Degraded() {
+		return nil
+	}
+	if h.qualityGates.HoldExpired(quality, time.Now()) {
+		err := common.NewValidationError("quality", fmt.Sprintf("scan %d was degraded and not reviewed for more than %s", taskMsg.ScanID, h.qualityGates.MaxHold))
+		gologger.Error().Msgf("Failing %s for domain %s: %v", taskMsg.Task, taskMsg.Domain, err)
+		return h.createFailureResult(err, false)
+	}
+
+	recheck := time.Now().Add(qualityHoldRecheck)
+	gologger.Info().Msgf("Scan %d is degraded and not reviewed, holding %s for domain %s until %s",
+		taskMsg.ScanID, taskMsg.Task, taskMsg.Domain, recheck.Format(time.RFC3339))
+	return &models.MessageProcessingResult{Success: true, DeferUntil: recheck}
+}
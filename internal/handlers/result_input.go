@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// resultInputTargets reads the earlier stage result at the task's input_result_path and returns the
+// targets the task takes from it, so the orchestrator does not have to convert results to hosts files
+func (h *TaskHandler) resultInputTargets(ctx context.Context, taskMsg *models.TaskMessage) ([]string, error) {
+	return h.resultTargets(ctx, taskMsg.Task, taskMsg.InputResultPath, "input_result_path")
+}
+
+// resultTargets reads the earlier stage result at blobPath and returns the targets a task of the
+// given type takes from it. Errors name the message field the path came from.
+func (h *TaskHandler) resultTargets(ctx context.Context, task models.Task, blobPath, field string) ([]string, error) {
+	if h.blobClient == nil {
+		return nil, common.NewValidationError(field, "result path provided but blob client is not initialized")
+	}
+
+	hostsFile, err := h.blobClient.ReadHostsFileFromBlob(ctx, blobPath)
+	if err != nil {
+		return nil, common.NewScannerError("failed to read result from blob storage", err)
+	}
+	if !hostsFile.IsTaskResult() {
+		return nil, common.NewValidationError(field, "result must be the output of an earlier stage, got a "+hostsFile.Format+" file")
+	}
+
+	targets, err := hostsFile.TargetsFor(task)
+	if err != nil {
+		return nil, common.NewValidationError(field, err.Error())
+	}
+
+	gologger.Info().Msgf("Took %d targets for %s from %s result %s", len(targets), task, hostsFile.Format, blobPath)
+	return targets, nil
+}
+
+// writeHostsTempFile writes hosts one per line to a temp file for tools that only read files
+func writeHostsTempFile(pattern string, hosts []string) (string, error) {
+	tmpFile, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tmpFile.WriteString(strings.Join(hosts, "\n"))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		if common.IsDiskFull(err) {
+			return "", common.NewDiskFullError("no space left on the worker's disk for the hosts file", err)
+		}
+		return "", err
+	}
+	return tmpFile.Name(), nil
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// retryBudgetDeadLetterReason is recorded on messages dead-lettered because their scan used up its retry budget
+const retryBudgetDeadLetterReason = "RetryBudgetExhausted"
+
+// SetRetryBudget sets how many retries all tasks of a scan may use together before the scan is
+// halted for manual intervention; 0 disables the budget
+func (h *TaskHandler) SetRetryBudget(budget int) {
+	h.retryBudget = budget
+}
+
+// chargeRetryBudget charges a retryable failure to the task's scan. Once the scan has used up its
+// budget, the failed message is dead-lettered instead of retried. The retry that uses it up also
+// pauses the scan, so its other tasks are not started, and notifies operators. A failure deferred
+// until a rate limit or ban is lifted is not charged: the scan is throttled, not broken.
+func (h *TaskHandler) chargeRetryBudget(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, processingResult *models.MessageProcessingResult) {
+	if h.retryBudget <= 0 || h.blobClient == nil || !processingResult.Retryable || !processingResult.DeferUntil.IsZero() {
+		return
+	}
+
+	lastError := ""
+	if processingResult.Error != nil {
+		lastError = processingResult.Error.Error()
+	}
+	var retries models.ScanRetries
+	halted := false
+	err := h.blobClient.UpdateScanRetries(ctx, taskMsg.TenantID, taskMsg.ScanID, func(stored *models.ScanRetries) error {
+		halted = stored.Charge(taskMsg.Task, lastError, h.retryBudget, time.Now().UTC())
+		retries = *stored
+		return nil
+	})
+	if err != nil {
+		// The budget only guards against poison scans, so the task is retried as usual
+		gologger.Warning().Msgf("Failed to charge retry of %s to scan %d: %v", taskMsg.Task, taskMsg.ScanID, err)
+		return
+	}
+	if retries.HaltedAt.IsZero() {
+		gologger.Info().Msgf("Scan %d used %d of %d retries", taskMsg.ScanID, retries.Retries, h.retryBudget)
+		return
+	}
+
+	processingResult.Retryable = false
+	processingResult.DeferUntil = time.Time{}
+	processingResult.DeadLetterReason = retryBudgetDeadLetterReason
+	if !halted {
+		gologger.Warning().Msgf("Scan %d is halted, dead-lettering %s for domain %s instead of retrying it", taskMsg.ScanID, taskMsg.Task, taskMsg.Domain)
+		return
+	}
+
+	if err := h.blobClient.SetScanPaused(ctx, taskMsg.TenantID, taskMsg.ScanID, true); err != nil {
+		gologger.Error().Msgf("Failed to pause scan %d after it used up its retry budget: %v", taskMsg.ScanID, err)
+	}
+	haltErr := fmt.Errorf("scan %d used %d retries, more than its budget of %d; last error: %s",
+		taskMsg.ScanID, retries.Retries, h.retryBudget, lastError)
+	gologger.Error().Msgf("Halting scan %d for domain %s until an operator resumes it: %v", taskMsg.ScanID, taskMsg.Domain, haltErr)
+	h.publishStep(taskMsg, result, haltErr, notification.StepScanHalted)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestChargeRetryBudget(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	h.SetRetryBudget(1)
+	ctx := context.Background()
+	taskMsg := &models.TaskMessage{Task: models.TaskHttpx, ScanID: 12, Domain: "example.com"}
+	failure := func(deferUntil time.Time) *models.MessageProcessingResult {
+		return &models.MessageProcessingResult{Error: errors.New("boom"), Retryable: true, DeferUntil: deferUntil}
+	}
+
+	// Retries waiting for a rate limit to be lifted are free
+	for range 3 {
+		throttled := failure(time.Now().Add(time.Minute))
+		h.chargeRetryBudget(ctx, taskMsg, h.createTaskResult(taskMsg), throttled)
+		if !throttled.Retryable || throttled.DeadLetterReason != "" {
+			t.Fatalf("Rate-limited retry = %+v, want it retried without charge", throttled)
+		}
+	}
+
+	first := failure(time.Time{})
+	h.chargeRetryBudget(ctx, taskMsg, h.createTaskResult(taskMsg), first)
+	if !first.Retryable {
+		t.Errorf("First retry = %+v, want it within the budget", first)
+	}
+	second := failure(time.Time{})
+	h.chargeRetryBudget(ctx, taskMsg, h.createTaskResult(taskMsg), second)
+	if second.Retryable || second.DeadLetterReason != retryBudgetDeadLetterReason {
+		t.Errorf("Second retry = %+v, want it dead-lettered once the budget is used up", second)
+	}
+}
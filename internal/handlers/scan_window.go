@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/schedule"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetScanWindows sets the per-tenant and per-domain windows during which scanning is allowed
+func (h *TaskHandler) SetScanWindows(windows *schedule.WindowSet) {
+	h.scanWindows = windows
+}
+
+// checkNotBefore returns a deferral result when the task must not start yet
+func (h *TaskHandler) checkNotBefore(taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	if taskMsg.NotBefore == nil || !time.Now().Before(*taskMsg.NotBefore) {
+		return nil
+	}
+
+	gologger.Info().Msgf("Task %s for domain %s must not start before %s, deferring",
+		taskMsg.Task, taskMsg.Domain, taskMsg.NotBefore.Format(time.RFC3339))
+	return &models.MessageProcessingResult{Success: true, DeferUntil: *taskMsg.NotBefore}
+}
+
+// checkScanWindow returns a deferral result when the task arrived outside its allowed scan window.
+// A "scan_window" entry in the task config overrides the configured windows.
+func (h *TaskHandler) checkScanWindow(taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	window := h.scanWindows.Lookup(taskMsg.TenantID, taskMsg.Domain)
+
+	// The config was checked when the message was validated
+	if config, err := models.ParseTaskConfig(taskMsg.Task, taskMsg.Config); err == nil && config.Common().ScanWindow != "" {
+		override, err := schedule.ParseWindow(config.Common().ScanWindow)
+		if err != nil {
+			return h.createFailureResult(common.NewValidationError("scan_window", err.Error()), false)
+		}
+		window = override
+	}
+
+	if window == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if window.Contains(now) {
+		return nil
+	}
+
+	next := window.NextOpen(now)
+	gologger.Info().Msgf("Task %s for domain %s is outside its scan window %s, deferring until %s",
+		taskMsg.Task, taskMsg.Domain, window, next.Format(time.RFC3339))
+
+	return &models.MessageProcessingResult{Success: true, DeferUntil: next}
+}
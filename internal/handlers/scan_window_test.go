@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestCheckNotBefore(t *testing.T) {
+	h := NewTaskHandler(nil, time.Minute, nil, nil)
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(2*time.Hour)
+
+	tests := []struct {
+		name      string
+		notBefore *time.Time
+		wantDefer bool
+	}{
+		{name: "no start time", notBefore: nil},
+		{name: "start time passed", notBefore: &past},
+		{name: "start time ahead", notBefore: &future, wantDefer: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskMsg := &models.TaskMessage{Task: models.TaskNuclei, ScanID: 1, Domain: "example.com", NotBefore: tt.notBefore}
+			result := h.checkNotBefore(taskMsg)
+			if !tt.wantDefer {
+				if result != nil {
+					t.Errorf("checkNotBefore() = %+v, want the task to start", result)
+				}
+				return
+			}
+			// Waiting for the start time is a deferral, not a failure that counts as a retry
+			if result == nil || !result.Success || !result.DeferUntil.Equal(*tt.notBefore) {
+				t.Errorf("checkNotBefore() = %+v, want a deferral until %s", result, tt.notBefore)
+			}
+		})
+	}
+}
+
+func TestTaskBeforeItsStartTimeIsNotRun(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	notBefore := time.Now().Add(time.Hour)
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 3, Domain: "example.com", NotBefore: &notBefore}
+
+	result := h.HandleTask(context.Background(), taskMsg)
+	if !result.Success || !result.DeferUntil.Equal(notBefore) {
+		t.Fatalf("HandleTask() = %+v, want a deferral until %s", result, notBefore)
+	}
+	if names := server.Names("scans", "example.com-3/"); len(names) != 0 {
+		t.Errorf("The deferred task stored %v", names)
+	}
+}
@@ -2,20 +2,56 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/allsafeASM/api/hooks"
 	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/buildinfo"
+	"github.com/allsafeASM/api/internal/capacity"
 	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/disk"
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/exporters"
+	"github.com/allsafeASM/api/internal/metrics"
 	"github.com/allsafeASM/api/internal/models"
 	"github.com/allsafeASM/api/internal/notification"
+	"github.com/allsafeASM/api/internal/quota"
+	"github.com/allsafeASM/api/internal/redaction"
+	"github.com/allsafeASM/api/internal/review"
 	"github.com/allsafeASM/api/internal/scanners"
+	"github.com/allsafeASM/api/internal/schedule"
 	"github.com/allsafeASM/api/internal/utils"
 	"github.com/allsafeASM/api/internal/validation"
 	"github.com/projectdiscovery/gologger"
 )
 
+// Actions for messages of task types this worker does not run
+const (
+	DisabledTaskAbandon    = "abandon"     // Give the message back to the queue for another worker
+	DisabledTaskDeadLetter = "dead_letter" // Dead-letter the message with the reason
+)
+
+// staleWorkerRecheck is how long a task waits on the queue while the worker is out of date, so a
+// replaced worker can take it without the stale ones receiving it in a loop
+const staleWorkerRecheck = 10 * time.Minute
+
+// capacityRecheck is how long a task waits on the queue while the worker has no capacity left
+// for its scanner, so the message goes to a worker with room instead of waiting on this one
+const capacityRecheck = time.Minute
+
+// partialResultStoreTimeout bounds storing and reporting a partial result after the task context is gone
+const partialResultStoreTimeout = 2 * time.Minute
+
+// maxErrorTextSize bounds the error message, cause and stack trace kept in an error artifact, so a
+// scanner error that carries a whole tool output does not bloat the artifact
+const maxErrorTextSize = 16 << 10
+
 // TaskHandler handles task processing and result storage
 type TaskHandler struct {
 	blobClient      *azure.BlobStorageClient
@@ -24,12 +60,49 @@ type TaskHandler struct {
 	errorClassifier *common.ErrorClassifier
 	scannerFactory  *scanners.ScannerFactory
 	notifier        *notification.Notifier
+	outbox          *notification.Outbox
 	discordNotifier *notification.DiscordNotifier
+	findingRouter   *notification.FindingRouter
+	lifecycle       []exporters.LifecycleRecorder
+	scanWindows     *schedule.WindowSet
+	capacity        *capacity.Budget
+	tlsFingerprints models.TLSFingerprints
+	reviewSampler   *review.Sampler
+	hooks           *hooks.Pipeline
+	redaction       redaction.Policies
+	retryBudget     int           // Retries all tasks of a scan may use together; 0 disables the budget
+	verifyTimeout   time.Duration // Timeout of verify tasks; 0 uses the scanner timeout
+	blockPolicy     models.BlockPolicy
+	qualityGates    models.QualityGates
+	pauseMaxHold    time.Duration       // How long a task of a paused scan waits to be resumed; 0 waits forever
+	scanProfiles    models.ScanProfiles // Profiles before the stored ones; nil uses the default profiles
+	disk            *disk.Janitor
+	// Compliance mode for every nuclei task, and the user agent compliance mode scans as
+	complianceMode      bool
+	complianceUserAgent string
+	// Time budgets of nuclei scans per target and per scan; 0 disables a budget
+	nucleiHostBudget time.Duration
+	nucleiScanBudget time.Duration
+	nucleiReplay     models.FindingReplay   // Findings replayed before they are reported
+	nucleiCheckpoint bool                   // Checkpoint every nuclei scan, not only those that ask for it
+	templatePolicy   *models.TemplatePolicy // Template policy of tenants without a stored one
+	compactionDays   int                    // Age in days past which compact messages archive scans
+	// Domains of a multi-domain task that run at once
+	multiDomainParallelism int
+
+	disabledTaskAction string
+	capabilities       []string     // Advertised capabilities; nil skips the capability check
+	versionCheck       func() error // Reports why the worker build is too old to run tasks; nil skips it
+
+	metrics          *taskMetrics
+	progressInterval time.Duration
+	events           *events.Bus
+	resultHandlers   []resultHandler // Consumers of stored results, called before the message is completed
 }
 
 // NewTaskHandler creates a new task handler
 func NewTaskHandler(blobClient *azure.BlobStorageClient, scannerTimeout time.Duration, notifier *notification.Notifier, discordNotifier *notification.DiscordNotifier) *TaskHandler {
-	return &TaskHandler{
+	h := &TaskHandler{
 		blobClient:      blobClient,
 		scannerTimeout:  scannerTimeout,
 		validator:       validation.NewValidator(),
@@ -37,69 +110,268 @@ func NewTaskHandler(blobClient *azure.BlobStorageClient, scannerTimeout time.Dur
 		scannerFactory:  scanners.NewScannerFactoryWithBlobClient(blobClient),
 		notifier:        notifier,
 		discordNotifier: discordNotifier,
+		events:          events.NewBus(),
 	}
+	if discordNotifier != nil && discordNotifier.IsEnabled() {
+		h.handleDiscordEvents()
+	}
+	return h
 }
 
 // HandleTask processes a task and stores the result
 func (h *TaskHandler) HandleTask(ctx context.Context, taskMsg *models.TaskMessage) *models.MessageProcessingResult {
-	gologger.Info().Msgf("Processing task: %s for domain: %s", taskMsg.Task, taskMsg.Domain)
+	// Control messages pause or resume a scan instead of running a scanner
+	if taskMsg.Action != "" {
+		return h.handleControlMessage(ctx, taskMsg)
+	}
+
+	// Everything done for the task, including its outgoing requests, carries its correlation ID
+	if taskMsg.CorrelationID == "" {
+		taskMsg.CorrelationID = models.NewCorrelationID()
+	}
+	ctx = models.WithCorrelationID(ctx, taskMsg.CorrelationID)
+
+	// Tasks this worker cannot run are left to the workers that can
+	if rejection := h.checkTaskRunnable(taskMsg); rejection != nil {
+		return rejection
+	}
+
+	// A task naming a scan profile takes the profile's config for the keys it leaves unset
+	if failure := h.applyScanProfile(ctx, taskMsg); failure != nil {
+		return h.failBeforeStart(ctx, taskMsg, failure)
+	}
+
+	// Tasks listing many domains run once per domain
+	if taskMsg.IsMultiDomain() {
+		return h.handleMultiDomainTask(ctx, taskMsg)
+	}
+
+	// Heavy scanners of concurrent tasks take turns: a task is only admitted once its scanner's
+	// units are taken, which are given back as soon as the scanner returns
+	release, deferral := h.admitCapacity(taskMsg)
+	if deferral != nil {
+		return deferral
+	}
+	defer release()
+
+	gologger.Info().Str("correlation_id", taskMsg.CorrelationID).Msgf("Processing task: %s for domain: %s", taskMsg.Task, taskMsg.Domain)
 
 	// Track start time for duration calculation
 	startTime := time.Now()
 
 	// Send initial Discord notification
-	h.sendDiscordNotification(ctx, taskMsg, nil, nil, notification.StepTaskReceived)
+	h.publishStep(taskMsg, nil, nil, notification.StepTaskReceived)
 
 	// Validate task message
 	if validationResult := h.validateTaskMessage(taskMsg); !validationResult.Success {
-		h.sendDiscordNotification(ctx, taskMsg, nil, validationResult.Error, notification.StepTaskFailed)
-		return validationResult
+		h.publishStep(taskMsg, nil, validationResult.Error, notification.StepTaskFailed)
+		return h.failBeforeStart(ctx, taskMsg, validationResult)
+	}
+
+	// A redelivered message whose earlier delivery stored its result only needs completing
+	if h.alreadyFinalized(ctx, taskMsg) {
+		gologger.Info().Msgf("Task %s for domain %s was already stored by an earlier delivery, completing the message", taskMsg.Task, taskMsg.Domain)
+		return &models.MessageProcessingResult{Success: true}
+	}
+
+	// Frozen domains are not scanned at all, even inside their scan window
+	if entry := h.checkFreezeList(ctx, taskMsg); entry != nil {
+		return h.skipFrozenTask(ctx, taskMsg, entry)
+	}
+
+	// Defer tasks that must not start yet, then tasks outside their allowed scan window
+	if deferral := h.checkNotBefore(taskMsg); deferral != nil {
+		return deferral
+	}
+	if deferral := h.checkScanWindow(taskMsg); deferral != nil {
+		if !deferral.Success {
+			return h.failBeforeStart(ctx, taskMsg, deferral)
+		}
+		return deferral
+	}
+	if deferral := h.checkQualityHold(ctx, taskMsg); deferral != nil {
+		if !deferral.Success {
+			return h.failBeforeStart(ctx, taskMsg, deferral)
+		}
+		return deferral
+	}
+	if failure := h.checkDiskSpace(taskMsg); failure != nil {
+		return h.failBeforeStart(ctx, taskMsg, failure)
 	}
 
 	// Create task result
 	result := h.createTaskResult(taskMsg)
-	h.sendDiscordNotification(ctx, taskMsg, result, nil, notification.StepTaskStarted)
+	if err := h.hooks.PreScan(ctx, taskMsg); err != nil {
+		return h.failHook(ctx, taskMsg, result, err)
+	}
+	h.publishStep(taskMsg, result, nil, notification.StepTaskStarted)
 
 	// Process the task
-	if processingResult := h.processTask(ctx, taskMsg, result); !processingResult.Success {
+	processingResult := h.processTask(ctx, taskMsg, result, release)
+	if !processingResult.Success {
 		// Set duration even for failed tasks
 		result.Duration = time.Since(startTime).String()
 		gologger.Error().Msgf("Task %s for domain %s failed after %s", taskMsg.Task, taskMsg.Domain, result.Duration)
+		h.storeErrorArtifact(ctx, taskMsg, result, processingResult)
+		h.chargeRetryBudget(ctx, taskMsg, result, processingResult)
+		h.reclaimDisk(processingResult)
+		return processingResult
+	}
+
+	// Paused tasks were checkpointed and have nothing to store yet; they wait on the queue for the resume
+	if result.Status == models.TaskStatusPaused {
+		gologger.Info().Msgf("Task %s for domain %s paused after %s", taskMsg.Task, taskMsg.Domain, time.Since(startTime))
 		return processingResult
 	}
 
 	// Set duration for successful tasks
 	result.Duration = time.Since(startTime).String()
 
+	if err := h.hooks.PostScan(ctx, taskMsg, result); err != nil {
+		return h.failHook(ctx, taskMsg, result, err)
+	}
+
+	// A partial result may come from a cancelled context, so store it on a detached one
+	if result.Status == models.TaskStatusPartial && ctx.Err() != nil {
+		storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), partialResultStoreTimeout)
+		defer cancel()
+		return h.finalizeTask(storeCtx, taskMsg, result)
+	}
+
 	// Store result and send notifications
 	return h.finalizeTask(ctx, taskMsg, result)
 }
 
 // validateTaskMessage validates the task message and returns appropriate result
 func (h *TaskHandler) validateTaskMessage(taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	// The baseline of a verify task lies outside the scan's prefix, so its path is checked cleaned
+	if taskMsg.BaselineResultPath != "" && h.blobClient != nil {
+		taskMsg.BaselineResultPath = h.blobClient.CleanBlobPath(taskMsg.BaselineResultPath)
+	}
 	if err := h.validator.ValidateTaskMessage(taskMsg); err != nil {
 		return h.createFailureResult(err, false)
 	}
+	// ValidateTaskMessage decoded the config, so it decodes without errors
+	taskConfig, _ := models.ParseTaskConfig(taskMsg.Task, taskMsg.Config)
+
+	// Input blobs must live under the scan's own prefix so a crafted message cannot read other scans' artifacts
+	if taskMsg.FilePath != "" {
+		blobPath := taskMsg.FilePath
+		if h.blobClient != nil {
+			blobPath = h.blobClient.CleanBlobPath(blobPath)
+		}
+		scanPrefix := models.ScanBlobPrefix(taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID)
+		if err := h.validator.ValidateBlobPath(blobPath, scanPrefix); err != nil {
+			gologger.Warning().Msgf("Rejected input blob path for scan %d: %v", taskMsg.ScanID, err)
+			return h.createFailureResult(err, false)
+		}
+	}
+	if taskMsg.InputResultPath != "" {
+		if taskMsg.FilePath != "" {
+			return h.createFailureResult(common.NewValidationError("input_result_path", "set either input_blob_path or input_result_path, not both"), false)
+		}
+		blobPath := taskMsg.InputResultPath
+		if h.blobClient != nil {
+			blobPath = h.blobClient.CleanBlobPath(blobPath)
+		}
+		scanPrefix := models.ScanBlobPrefix(taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID)
+		if err := h.validator.ValidateBlobPath(blobPath, scanPrefix); err != nil {
+			gologger.Warning().Msgf("Rejected input result path for scan %d: %v", taskMsg.ScanID, err)
+			return h.createFailureResult(err, false)
+		}
+	}
+
+	// Exclusions are checked up front so a bad entry fails the task before anything is scanned
+	if exclusions := taskConfig.Common().Exclusions(); !exclusions.IsZero() {
+		if exclusions.BlobPath != "" && h.blobClient != nil {
+			exclusions.BlobPath = h.blobClient.CleanBlobPath(exclusions.BlobPath)
+		}
+		if err := h.validator.ValidateExclusions(exclusions, taskMsg.TenantID); err != nil {
+			gologger.Warning().Msgf("Rejected exclusions for scan %d: %v", taskMsg.ScanID, err)
+			return h.createFailureResult(err, false)
+		}
+	}
+
+	// Results must land in the region the orchestrator expects for the tenant, so a task whose
+	// tenant is routed elsewhere is not run at all
+	if taskMsg.DataRegion != "" && h.blobClient != nil {
+		if region := h.blobClient.TenantRegion(taskMsg.TenantID); !strings.EqualFold(region, taskMsg.DataRegion) {
+			err := common.NewConfigurationError("data_region", fmt.Sprintf("tenant %q is stored in region %s, not %s", taskMsg.TenantID, region, taskMsg.DataRegion))
+			gologger.Error().Msgf("Rejected task for scan %d: %v", taskMsg.ScanID, err)
+			return h.createFailureResult(err, false)
+		}
+	}
 
 	return &models.MessageProcessingResult{Success: true}
 }
 
+// checkTaskRunnable returns the result for a task this worker cannot run, and nil otherwise. A
+// task cannot run when ENABLED_TASKS or the self-test disabled its type, or when it requires a
+// capability the worker does not advertise. The message is abandoned for another worker without
+// notifications, or dead-lettered with the reason when the disabled task action says so. While the
+// worker build is older than the required minimum version the task is deferred instead, since
+// every worker on the same image would abandon it straight back.
+func (h *TaskHandler) checkTaskRunnable(taskMsg *models.TaskMessage) *models.MessageProcessingResult {
+	var err error
+	if reason := h.scannerFactory.DisabledReason(taskMsg.Task); reason != nil {
+		err = fmt.Errorf("task type %s is disabled on this worker: %w", taskMsg.Task, reason)
+	} else if missing := models.MissingCapabilities(taskMsg.RequiredCapabilities(), h.capabilities); h.capabilities != nil && len(missing) > 0 {
+		err = fmt.Errorf("worker lacks the capabilities %s required by the task", strings.Join(missing, ", "))
+	} else if h.versionCheck != nil {
+		if stale := h.versionCheck(); stale != nil {
+			recheck := time.Now().Add(staleWorkerRecheck)
+			gologger.Warning().Msgf("Worker is out of date, deferring %s task for domain %s until %s: %v",
+				taskMsg.Task, taskMsg.Domain, recheck.Format(time.RFC3339), stale)
+			return &models.MessageProcessingResult{Success: true, DeferUntil: recheck}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	if h.disabledTaskAction == DisabledTaskDeadLetter {
+		gologger.Error().Msgf("Dead-lettering task for domain %s: %v", taskMsg.Domain, err)
+		h.publishStep(taskMsg, nil, err, notification.StepTaskFailed)
+		return &models.MessageProcessingResult{Error: err, DeadLetterReason: "TaskTypeDisabled"}
+	}
+	gologger.Info().Msgf("Leaving %s task for domain %s to another worker: %v", taskMsg.Task, taskMsg.Domain, err)
+	return &models.MessageProcessingResult{Error: err, Abandon: true}
+}
+
 // createTaskResult creates a new task result with initial status
 func (h *TaskHandler) createTaskResult(taskMsg *models.TaskMessage) *models.TaskResult {
+	worker := buildinfo.Get()
 	return &models.TaskResult{
 		ScanID:    taskMsg.ScanID,
 		Task:      models.Task(taskMsg.Task),
 		Domain:    taskMsg.Domain,
+		TenantID:  taskMsg.TenantID,
 		Status:    models.TaskStatusRunning,
 		Timestamp: time.Now().Format(time.RFC3339),
+
+		CorrelationID: taskMsg.CorrelationID,
+		Attempt:       taskMsg.Attempt,
+		Worker:        &worker,
+		Mode:          taskMsg.Mode,
 	}
 }
 
-// processTask executes the task based on its type
-func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) *models.MessageProcessingResult {
-	scannerCtx, cancel := context.WithTimeout(ctx, h.scannerTimeout)
+// processTask executes the task based on its type. releaseCapacity gives back the capacity units
+// of the task's scanner once it returns.
+func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, releaseCapacity func()) *models.MessageProcessingResult {
+	scannerCtx, cancel := context.WithTimeout(ctx, h.taskTimeout(taskMsg))
 	defer cancel()
 
+	// The config was checked when the message was validated, so this only fails for direct calls
+	taskConfig, err := models.ParseTaskConfig(taskMsg.Task, taskMsg.Config)
+	if err != nil {
+		err = common.NewValidationError("config", err.Error())
+		result.Status = models.TaskStatusFailed
+		result.Error = err.Error()
+		h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+		return h.createFailureResult(err, false)
+	}
+
 	scanner, err := h.scannerFactory.GetScanner(models.Task(taskMsg.Task))
 	if err != nil {
 		// Fallback to subfinder if scanner not found
@@ -107,42 +379,95 @@ func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessa
 		scanner, _ = h.scannerFactory.GetScanner(models.TaskSubfinder)
 	}
 
+	// Targets handed over as an earlier stage's result are adapted to this task's input
+	var resultTargets []string
+	if taskMsg.InputResultPath != "" {
+		if resultTargets, err = h.resultInputTargets(ctx, taskMsg); err != nil {
+			result.Status = models.TaskStatusFailed
+			result.Error = err.Error()
+			gologger.Error().Msgf("Failed to take input from result %s: %v", taskMsg.InputResultPath, err)
+			h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+			return h.createFailureResult(err, false)
+		}
+	}
+
+	// Verify tasks scan only the targets missing from the baseline, and finish at once without any
+	if taskMsg.Mode == models.TaskModeVerify {
+		if resultTargets, err = h.verifyTargets(ctx, taskMsg, resultTargets, result); err != nil {
+			result.Status = models.TaskStatusFailed
+			result.Error = err.Error()
+			gologger.Error().Msgf("Failed to take baseline from result %s: %v", taskMsg.BaselineResultPath, err)
+			h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+			return h.createFailureResult(err, false)
+		}
+		if len(resultTargets) == 0 {
+			return h.completeWithoutTargets(taskMsg, result)
+		}
+	}
+
+	// Targets the scanner skips while collecting its input
+	exclusions, err := h.loadExclusions(ctx, taskMsg, taskConfig.Common().Exclusions())
+	if err != nil {
+		result.Status = models.TaskStatusFailed
+		result.Error = err.Error()
+		gologger.Error().Msgf("Failed to load exclusions: %v", err)
+		h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+		return h.createFailureResult(err, false)
+	}
+
 	// Create appropriate input structure based on scanner type
 	var scannerInput models.ScannerInput
 	switch models.Task(taskMsg.Task) {
 	case models.TaskSubfinder:
-		scannerInput = models.SubfinderInput{Domain: result.Domain}
+		config := taskConfig.(*models.SubfinderConfig)
+		subfinderInput := models.SubfinderInput{Domain: result.Domain, Exclude: exclusions}
+		subfinderInput.Filter.Include = config.IncludePatterns
+		subfinderInput.Filter.Exclude = config.ExcludePatterns
+		if !subfinderInput.Filter.IsZero() {
+			gologger.Info().Msgf("Subfinder task with include patterns %v and exclude patterns %v", subfinderInput.Filter.Include, subfinderInput.Filter.Exclude)
+		}
+		scannerInput = subfinderInput
 	case models.TaskHttpx:
-		httpxInput := models.HttpxInput{Domain: result.Domain}
+		httpxInput := models.HttpxInput{Domain: result.Domain, Exclude: exclusions}
+		var hosts []string
 		var tempFilePath string
 		if taskMsg.FilePath != "" {
 			gologger.Info().Msgf("Httpx task with hosts file (file_path): %s", taskMsg.FilePath)
-			// Download hosts file from blob and save as temp file using blobClient.DownloadFile
+			// Read and parse the hosts file from blob; httpx reads it from a plain text temp file below
 			if h.blobClient != nil {
-				tmpFile, err := os.CreateTemp("", "httpx-hosts-*.txt")
-				if err != nil {
-					result.Status = models.TaskStatusFailed
-					result.Error = err.Error()
-					gologger.Error().Msgf("Failed to create temp file for hosts: %v", err)
-					h.sendDiscordNotification(ctx, taskMsg, result, err, notification.StepTaskFailed)
-					return h.createFailureResult(err, false)
-				}
-				tmpFile.Close()
-				tempFilePath = tmpFile.Name()
-				err = h.blobClient.DownloadFile(ctx, taskMsg.FilePath, tempFilePath)
+				hostsFile, err := h.blobClient.ReadHostsFileFromBlob(ctx, taskMsg.FilePath)
 				if err != nil {
 					result.Status = models.TaskStatusFailed
 					result.Error = err.Error()
-					gologger.Error().Msgf("Failed to download hosts file from blob: %v", err)
-					h.sendDiscordNotification(ctx, taskMsg, result, err, notification.StepTaskFailed)
+					gologger.Error().Msgf("Failed to read hosts file from blob: %v", err)
+					h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
 					return h.createFailureResult(err, false)
 				}
-				httpxInput.InputPath = tempFilePath
-				gologger.Info().Msgf("Saved hosts file to temp path: %s", tempFilePath)
+				hosts = hostsFile.Hosts
 			}
+		} else if resultTargets != nil {
+			gologger.Info().Msgf("Httpx task with input result (input_result_path): %s", taskMsg.InputResultPath)
+			hosts = resultTargets
 		} else {
 			gologger.Info().Msgf("Httpx task without hosts file, domain: %s", result.Domain)
 		}
+		if hosts != nil {
+			var err error
+			if tempFilePath, err = writeHostsTempFile("httpx-hosts-*.txt", hosts); err != nil {
+				result.Status = models.TaskStatusFailed
+				result.Error = err.Error()
+				gologger.Error().Msgf("Failed to write hosts to temp file: %v", err)
+				h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+				return h.createFailureResult(err, common.IsDiskFull(err))
+			}
+			httpxInput.InputPath = tempFilePath
+			gologger.Info().Msgf("Saved %d hosts to temp path: %s", len(hosts), tempFilePath)
+		}
+		config := taskConfig.(*models.HttpxConfig)
+		httpxInput.TLSFingerprint = h.tlsFingerprints.Resolve(taskMsg.TenantID, config.TLSFingerprint)
+		if config.TLSFingerprint != "" && config.TLSFingerprint != httpxInput.TLSFingerprint {
+			gologger.Warning().Msgf("Ignoring tls_fingerprint %s of scan %d: tenant %s is pinned to %s", config.TLSFingerprint, taskMsg.ScanID, taskMsg.TenantID, httpxInput.TLSFingerprint)
+		}
 		scannerInput = httpxInput
 		// After scan, delete the temp file if it was created using blobClient.DeleteLocalFile
 		defer func() {
@@ -159,7 +484,8 @@ func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessa
 		subdomains := utils.ReadSubdomainsFromString(result.Domain)
 
 		dnsxInput := models.DNSXInput{
-			Domain: result.Domain,
+			Domain:  result.Domain,
+			Exclude: exclusions,
 		}
 
 		if len(subdomains) > 1 {
@@ -173,19 +499,29 @@ func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessa
 
 		gologger.Info().Msgf("DNSX input message: %+v", taskMsg)
 
-		// Add hosts file location if provided in the task message
+		if resultTargets != nil {
+			dnsxInput.Subdomains = append(dnsxInput.Subdomains, resultTargets...)
+			gologger.Info().Msgf("DNSX task with %d names from input result: %s", len(resultTargets), taskMsg.InputResultPath)
+		}
+
+		// Add the input blob of names if provided in the task message. It is not the local hosts
+		// file, which the hostsfile setting turns on.
 		if taskMsg.FilePath != "" {
 			dnsxInput.HostsFileLocation = taskMsg.FilePath
-			gologger.Info().Msgf("DNSX task with hosts file (file_path): %s", taskMsg.FilePath)
+			gologger.Info().Msgf("DNSX task with input blob (file_path): %s", taskMsg.FilePath)
 		} else {
-			gologger.Info().Msgf("DNSX task without hosts file, domain: %s", result.Domain)
+			gologger.Info().Msgf("DNSX task without input blob, domain: %s", result.Domain)
 		}
 
+		// Add DNS settings overrides from config if provided
+		dnsxInput.Settings = taskConfig.(*models.DNSXConfig).Settings()
+
 		scannerInput = dnsxInput
 	case models.TaskNaabu:
 		// For Naabu port scanning
 		naabuInput := models.NaabuInput{
-			Domain: result.Domain,
+			Domain:  result.Domain,
+			Exclude: exclusions,
 		}
 
 		// Add hosts file location if provided in the task message
@@ -195,81 +531,108 @@ func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessa
 		} else {
 			gologger.Info().Msgf("Naabu task without hosts file, domain: %s", result.Domain)
 		}
-
-		// Add naabu-specific parameters from config if provided
-		if taskMsg.Config != nil {
-			if topPorts, ok := taskMsg.Config["top_ports"]; ok && topPorts != "" {
-				switch v := topPorts.(type) {
-				case string:
-					naabuInput.TopPorts = v
-				case float64:
-					// Convert numeric values to string format that naabu expects
-					switch v {
-					case 100:
-						naabuInput.TopPorts = "100"
-					case 1000:
-						naabuInput.TopPorts = "1000"
-					default:
-						gologger.Warning().Msgf("Invalid top_ports numeric value: %.0f (must be 100 or 1000), using default", v)
-						naabuInput.TopPorts = "100" // Default fallback
-					}
-				case int:
-					// Convert numeric values to string format that naabu expects
-					switch v {
-					case 100:
-						naabuInput.TopPorts = "100"
-					case 1000:
-						naabuInput.TopPorts = "1000"
-					default:
-						gologger.Warning().Msgf("Invalid top_ports numeric value: %d (must be 100 or 1000), using default", v)
-						naabuInput.TopPorts = "100" // Default fallback
-					}
-				default:
-					gologger.Warning().Msgf("Invalid top_ports type: %T, value: %v, using default", topPorts, topPorts)
-					naabuInput.TopPorts = "100" // Default fallback
-				}
-				gologger.Info().Msgf("Naabu task with top ports: %s", naabuInput.TopPorts)
-			}
-			if ports, ok := taskMsg.Config["ports"].([]interface{}); ok && len(ports) > 0 {
-				naabuInput.Ports = make([]int, len(ports))
-				for i, port := range ports {
-					if portNum, ok := port.(float64); ok {
-						naabuInput.Ports[i] = int(portNum)
-					}
-				}
-				gologger.Info().Msgf("Naabu task with specific ports: %v", naabuInput.Ports)
-			}
-			if portRange, ok := taskMsg.Config["port_range"].(string); ok && portRange != "" {
-				naabuInput.PortRange = portRange
-				gologger.Info().Msgf("Naabu task with port range: %s", portRange)
-			}
-			if rateLimit, ok := taskMsg.Config["rate_limit"].(float64); ok && rateLimit > 0 {
-				naabuInput.RateLimit = int(rateLimit)
-				gologger.Info().Msgf("Naabu task with rate limit: %d", naabuInput.RateLimit)
-			}
-			if concurrency, ok := taskMsg.Config["concurrency"].(float64); ok && concurrency > 0 {
-				naabuInput.Concurrency = int(concurrency)
-				gologger.Info().Msgf("Naabu task with concurrency: %d", naabuInput.Concurrency)
-			}
-			if timeout, ok := taskMsg.Config["timeout"].(float64); ok && timeout > 0 {
-				naabuInput.Timeout = int(timeout)
-				gologger.Info().Msgf("Naabu task with timeout: %d seconds", naabuInput.Timeout)
-			}
+		if resultTargets != nil {
+			naabuInput.IPs = resultTargets
+			gologger.Info().Msgf("Naabu task with %d IPs from input result: %s", len(resultTargets), taskMsg.InputResultPath)
 		}
 
+		// Add naabu-specific parameters from config
+		config := taskConfig.(*models.NaabuConfig)
+		naabuInput.TopPorts = config.TopPorts
+		naabuInput.Ports = config.Ports
+		naabuInput.PortRange = config.PortRange
+		naabuInput.RateLimit = config.RateLimit
+		naabuInput.Concurrency = config.Concurrency
+		naabuInput.Timeout = config.Timeout
+		naabuInput.HostDiscovery = config.HostDiscovery
+		naabuInput.PreFilter = config.PreFilter
+		naabuInput.DiscoveryProbes = config.DiscoveryProbes
+		naabuInput.SourcePort = config.SourcePort
+		naabuInput.SourceIP = config.SourceIP
+		naabuInput.Interface = config.Interface
+		naabuInput.CDNPorts = config.CDNPorts
+		naabuInput.ScanCDN = config.ScanCDN
+		gologger.Info().Msgf("Naabu task with config: %+v", *config)
+
 		scannerInput = naabuInput
 	case models.TaskNuclei:
-		nucleiInput := models.NucleiInput{Domain: result.Domain}
+		nucleiInput := models.NucleiInput{Domain: result.Domain, Exclude: exclusions}
 		if taskMsg.FilePath != "" {
 			nucleiInput.HostsFileLocation = taskMsg.FilePath
 			gologger.Info().Msgf("Nuclei task with hosts file (file_path): %s", taskMsg.FilePath)
 		} else {
 			gologger.Info().Msgf("Nuclei task without hosts file, domain: %s", result.Domain)
 		}
+		if resultTargets != nil {
+			nucleiInput.Hosts = resultTargets
+			gologger.Info().Msgf("Nuclei task with %d targets from input result: %s", len(resultTargets), taskMsg.InputResultPath)
+		}
 		if taskMsg.Type != "" {
 			nucleiInput.Type = taskMsg.Type
 		}
+		config := taskConfig.(*models.NucleiConfig)
+		if config.CustomTemplates != "" {
+			nucleiInput.CustomTemplates = config.CustomTemplates
+			nucleiInput.TemplatesPrefix = models.TenantTemplatesPrefix(taskMsg.TenantID, config.CustomTemplates)
+			gologger.Info().Msgf("Nuclei task with custom templates: %s", nucleiInput.TemplatesPrefix)
+		}
+		if config.InteractshServer != "" {
+			nucleiInput.InteractshServer = config.InteractshServer
+			gologger.Info().Msgf("Nuclei task with interactsh server: %s", config.InteractshServer)
+		}
+		if config.DisableInteractsh {
+			nucleiInput.DisableInteractsh = true
+			gologger.Info().Msgf("Nuclei task with interactsh disabled")
+		}
+		// Tasks can turn compliance mode on, but not off where the worker enforces it
+		if config.Compliance || h.complianceMode {
+			nucleiInput.Compliance = true
+			nucleiInput.ComplianceUserAgent = h.complianceUserAgent
+			gologger.Info().Msgf("Nuclei task in compliance mode as %s", nucleiInput.ComplianceUserAgent)
+		}
+		nucleiInput.HostBudget, nucleiInput.ScanBudget = h.nucleiHostBudget, h.nucleiScanBudget
+		if config.HostBudget > 0 {
+			nucleiInput.HostBudget = time.Duration(config.HostBudget) * time.Second
+		}
+		if config.ScanBudget > 0 {
+			nucleiInput.ScanBudget = time.Duration(config.ScanBudget) * time.Second
+		}
+		policy, err := h.loadTemplatePolicy(ctx, taskMsg.TenantID)
+		if err != nil {
+			result.Status = models.TaskStatusFailed
+			result.Error = err.Error()
+			gologger.Error().Msgf("Failed to load the nuclei template policy for domain %s: %v", taskMsg.Domain, err)
+			h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+			return h.createFailureResult(err, h.errorClassifier.IsRetryableError(err))
+		}
+		nucleiInput.TemplatePolicy = policy
+		nucleiInput.Replay = h.nucleiReplay
+		if config.ReplayMinSeverity != "" {
+			nucleiInput.Replay.MinSeverity = config.ReplayMinSeverity
+		}
 		scannerInput = nucleiInput
+	case models.TaskScopeExpansion:
+		scopeInput := models.ScopeExpansionInput{Domain: result.Domain, Exclude: exclusions}
+		if taskMsg.FilePath != "" && h.blobClient != nil {
+			gologger.Info().Msgf("Scope expansion task with hosts file (file_path): %s", taskMsg.FilePath)
+			hostsFile, err := h.blobClient.ReadHostsFileFromBlob(ctx, taskMsg.FilePath)
+			if err != nil {
+				result.Status = models.TaskStatusFailed
+				result.Error = err.Error()
+				gologger.Error().Msgf("Failed to read hosts file from blob: %v", err)
+				h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+				return h.createFailureResult(err, false)
+			}
+			scopeInput.Hosts = hostsFile.Hosts
+		} else if resultTargets != nil {
+			gologger.Info().Msgf("Scope expansion task with %d hosts from input result: %s", len(resultTargets), taskMsg.InputResultPath)
+			scopeInput.Hosts = resultTargets
+		}
+		scannerInput = scopeInput
+	case models.TaskCloudDNS:
+		cloudInput := models.CloudDNSInput{Domain: result.Domain, Exclude: exclusions, Sources: taskConfig.(*models.CloudDNSConfig).Sources}
+		gologger.Info().Msgf("Cloud DNS task with %d zone sources", len(cloudInput.Sources))
+		scannerInput = cloudInput
 	default:
 		scannerInput = models.SubfinderInput{Domain: result.Domain}
 	}
@@ -283,79 +646,697 @@ func (h *TaskHandler) processTask(ctx context.Context, taskMsg *models.TaskMessa
 				result.Status = models.TaskStatusFailed
 				result.Error = fmt.Sprintf("invalid input: %v", err)
 				gologger.Error().Msgf("Input validation failed for domain %s: %v", taskMsg.Domain, err)
-				h.sendDiscordNotification(ctx, taskMsg, result, err, notification.StepTaskFailed)
+				h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
 				return h.createFailureResult(err, false)
 			}
 		}
 	}
 
-	scannerResult, err := scanner.Execute(scannerCtx, scannerInput)
+	// A paused scan is not started; the task waits on the queue until the scan is resumed
+	if h.isScanPaused(ctx, taskMsg) {
+		return h.pauseTask(ctx, taskMsg, result, nil, errScanPaused)
+	}
+
+	// Continue from where a paused run stopped
+	checkpoint := h.loadCheckpoint(ctx, taskMsg)
+	if checkpoint != nil {
+		scannerInput = h.applyCheckpoint(scannerInput, checkpoint)
+	}
+	if nucleiInput, ok := scannerInput.(models.NucleiInput); ok && h.blobClient != nil && h.nucleiCheckpoints(taskConfig, checkpoint) {
+		// Nuclei runs for hours, so its progress is checkpointed while it runs and a redelivered task resumes from there
+		nucleiInput.OnCheckpoint = func(progress models.NucleiResult) {
+			h.storeProgressCheckpoint(ctx, taskMsg, checkpoint, progress)
+		}
+		scannerInput = nucleiInput
+	}
+	var reviewSamples *review.Collection
+	if httpxInput, ok := scannerInput.(models.HttpxInput); ok && h.reviewSampler != nil && h.blobClient != nil {
+		// A share of the unusual responses is kept so reviewers can check the automated results against them
+		reviewSamples = h.reviewSampler.NewCollection()
+		httpxInput.OnResponse = reviewSamples.Offer
+		scannerInput = httpxInput
+	}
+	if nucleiInput, ok := scannerInput.(models.NucleiInput); ok && h.findingRouter != nil {
+		// Severe findings are alerted on right away instead of waiting for the scan to end
+		policy := h.redactionPolicy(taskMsg)
+		nucleiInput.OnResult = func(finding models.NucleiVulnerability) {
+			h.findingRouter.Route(taskMsg, policy.Finding(finding))
+		}
+		scannerInput = nucleiInput
+	}
+
+	scannerCtx, pause := context.WithCancelCause(scannerCtx)
+	defer pause(nil)
+	go h.watchForPause(scannerCtx, taskMsg, pause)
+	go h.watchForLockLoss(scannerCtx, taskMsg, models.MessageLockFromContext(ctx), pause)
+
+	progress := h.startProgressReporter(scannerCtx, taskMsg)
+	scannerResult, err := h.executeScanner(scannerCtx, scanner, h.newTaskContext(scannerCtx, taskMsg, progress), scannerInput)
+	progress.stop()
+	releaseCapacity()
+	if reviewSamples != nil {
+		h.storeReviewSamples(ctx, result, reviewSamples)
+	}
+	if checkpoint != nil {
+		scannerResult = mergeCheckpoint(checkpoint, scannerResult)
+	}
+	// Identical scans store identical results, whatever order the scanner found things in
+	scannerResult = models.SortResult(scannerResult)
+	if cause := context.Cause(scannerCtx); err != nil && (errors.Is(cause, errScanPaused) || errors.Is(cause, errLockLost)) {
+		return h.pauseTask(ctx, taskMsg, result, scannerResult, cause)
+	}
+	if err != nil && models.IsPartialResult(scannerResult) {
+		// Keep what the scanner finished before timing out instead of discarding the whole scan
+		result.Status = models.TaskStatusPartial
+		result.Error = err.Error()
+		result.Data = scannerResult
+		gologger.Warning().Msgf("Task for domain %s was interrupted, keeping %d partial results from %s: %v",
+			taskMsg.Domain, scannerResult.GetCount(), scanner.GetName(), err)
+
+		h.publishStep(taskMsg, result, nil, notification.StepTaskCompleted)
+		return &models.MessageProcessingResult{Success: true}
+	}
 	if err != nil {
 		result.Status = models.TaskStatusFailed
 		result.Error = err.Error()
+		if scannerResult != nil {
+			result.Data = scannerResult
+		}
 		gologger.Error().Msgf("Task failed for domain %s: %v", taskMsg.Domain, err)
 
-		h.sendDiscordNotification(ctx, taskMsg, result, err, notification.StepTaskFailed)
+		h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+
+		// A throttled or banned scanner is retried once the ban is expected to be lifted
+		if retryAfter := common.RetryAfter(err); retryAfter > 0 {
+			gologger.Warning().Msgf("Retrying %s for domain %s in %s", taskMsg.Task, taskMsg.Domain, retryAfter)
+			failure := h.createFailureResult(err, true)
+			failure.DeferUntil = time.Now().Add(retryAfter)
+			return failure
+		}
 
 		retryable := h.errorClassifier.IsRetryableError(err)
 		return h.createFailureResult(err, retryable)
 	}
 
 	result.Status = models.TaskStatusCompleted
+	if models.IsPartialResult(scannerResult) {
+		// The scanner skipped targets without failing, e.g. host groups aborted after a ban
+		result.Status = models.TaskStatusPartial
+	}
 	result.Data = scannerResult
 	gologger.Info().Msgf("Task completed successfully for domain: %s using %s, found %d results",
 		taskMsg.Domain, scanner.GetName(), scannerResult.GetCount())
 
-	h.sendDiscordNotification(ctx, taskMsg, result, nil, notification.StepTaskCompleted)
+	h.publishStep(taskMsg, result, nil, notification.StepTaskCompleted)
+	h.checkQualityGates(ctx, taskMsg, result, scannerResult)
 	return &models.MessageProcessingResult{Success: true}
 }
 
-// finalizeTask stores the result and sends completion notifications
+// loadExclusions returns the task's exclusions with the entries of the exclusion blob merged into
+// the inline ones, so scanners only need the compiled list
+func (h *TaskHandler) loadExclusions(ctx context.Context, taskMsg *models.TaskMessage, exclusions models.TargetExclusions) (models.TargetExclusions, error) {
+	if exclusions.BlobPath != "" {
+		if h.blobClient == nil {
+			return exclusions, common.NewConfigurationError("exclude_blob_path", "blob client is required to read the exclusion list")
+		}
+		exclusions.BlobPath = h.blobClient.CleanBlobPath(exclusions.BlobPath)
+		content, err := h.blobClient.ReadFileFromBlob(ctx, exclusions.BlobPath)
+		if err != nil {
+			return exclusions, fmt.Errorf("failed to read exclusion list %s: %w", exclusions.BlobPath, err)
+		}
+		entries, err := models.ParseExclusionList(content)
+		if err != nil {
+			return exclusions, common.NewValidationError("exclude_blob_path", err.Error())
+		}
+		exclusions.Targets = append(exclusions.Targets, entries...)
+	}
+	if _, err := exclusions.Excluder(); err != nil {
+		return exclusions, common.NewValidationError("exclude", err.Error())
+	}
+	if len(exclusions.Targets) > 0 {
+		gologger.Info().Msgf("%s task with %d exclusions", taskMsg.Task, len(exclusions.Targets))
+	}
+	return exclusions, nil
+}
+
+// SetDNSXDefaults sets the default DNS settings of the DNSX scanner
+func (h *TaskHandler) SetDNSXDefaults(settings models.DNSSettings) {
+	h.scannerFactory.SetDNSXDefaults(settings)
+}
+
+// SetDNSXScalingLimits sets the worker and rate limit ceilings of the DNSX scanner
+func (h *TaskHandler) SetDNSXScalingLimits(maxWorkers, maxRateLimit int) {
+	h.scannerFactory.SetDNSXScalingLimits(maxWorkers, maxRateLimit)
+}
+
+// SetDNSXStreamThreshold sets the name count above which DNSX streams its records to blob storage
+func (h *TaskHandler) SetDNSXStreamThreshold(threshold int) {
+	h.scannerFactory.SetDNSXStreamThreshold(threshold)
+}
+
+// SetNucleiTemplateCacheDir sets the directory custom nuclei templates are synced to
+func (h *TaskHandler) SetNucleiTemplateCacheDir(dir string) {
+	h.scannerFactory.SetNucleiTemplateCacheDir(dir)
+}
+
+// NucleiTemplateCacheLock returns the lock the custom nuclei template cache holds while it syncs
+func (h *TaskHandler) NucleiTemplateCacheLock() sync.Locker {
+	return h.scannerFactory.NucleiTemplateCacheLock()
+}
+
+// SetSimulation makes every task return a fixture result instead of scanning. Fixture blobs are
+// read from blobPrefix when it is set.
+func (h *TaskHandler) SetSimulation(blobPrefix string) {
+	h.scannerFactory.Simulate(blobPrefix)
+}
+
+// SetHooks sets the deployment hooks that run before and after scanning and storing
+func (h *TaskHandler) SetHooks(pipeline *hooks.Pipeline) {
+	h.hooks = pipeline
+}
+
+// SetRedaction sets the per-tenant policies that scrub sensitive values from results before
+// they are stored, checkpointed or sent
+func (h *TaskHandler) SetRedaction(policies redaction.Policies) {
+	h.redaction = policies
+}
+
+// redactionPolicy returns the redaction policy of the task's tenant
+func (h *TaskHandler) redactionPolicy(taskMsg *models.TaskMessage) *redaction.Policy {
+	return h.redaction.Lookup(taskMsg.TenantID)
+}
+
+// SetOutbox makes completion notifications go through the outbox, which delivers them after the
+// message is completed
+func (h *TaskHandler) SetOutbox(outbox *notification.Outbox) {
+	h.outbox = outbox
+	outbox.OnDelivered(func(entry *models.OutboxEntry) {
+		h.publishStep(&entry.TaskMessage, nil, nil, notification.StepNotificationSent)
+	})
+}
+
+// SetFindingRouter sets the router that alerts on severe nuclei findings while scans run
+func (h *TaskHandler) SetFindingRouter(router *notification.FindingRouter) {
+	h.findingRouter = router
+}
+
+// SetIncidentNotifier sets the notifier that opens and resolves incidents for critical findings
+func (h *TaskHandler) SetIncidentNotifier(incidents *notification.IncidentNotifier) {
+	h.handleResults("incidents", func(ctx context.Context, event events.Event) error {
+		return incidents.Process(ctx, event.TaskMessage, event.Result)
+	})
+}
+
+// SetTicketNotifier sets the notifier that keeps issue tracker tickets in sync with findings
+func (h *TaskHandler) SetTicketNotifier(tickets *notification.TicketNotifier) {
+	h.handleResults("tickets", func(ctx context.Context, event events.Event) error {
+		return tickets.Process(ctx, event.TaskMessage, event.Result)
+	})
+}
+
+// EnableInventory indexes every stored task result into the asset inventory of its domain
+func (h *TaskHandler) EnableInventory() {
+	h.handleResults("inventory", func(ctx context.Context, event events.Event) error {
+		seenAt, err := time.Parse(time.RFC3339, event.Result.Timestamp)
+		if err != nil {
+			seenAt = time.Now()
+		}
+		return h.blobClient.UpdateInventory(ctx, event.Result.TenantID, event.Result.Domain, func(inventory *models.Inventory) error {
+			if added := inventory.Index(event.Result, seenAt.UTC()); added > 0 {
+				gologger.Info().Msgf("Added %d new assets to the inventory of domain %s", added, event.Result.Domain)
+			}
+			return nil
+		})
+	})
+}
+
+// AddResultExporter adds an exporter that receives every stored task result
+func (h *TaskHandler) AddResultExporter(exporter exporters.ResultExporter) {
+	h.handleResults("export:"+exporter.Name(), func(ctx context.Context, event events.Event) error {
+		return exporter.Export(ctx, event.Result)
+	})
+}
+
+// AddLifecycleRecorder adds a recorder that receives every task step
+func (h *TaskHandler) AddLifecycleRecorder(recorder exporters.LifecycleRecorder) {
+	h.lifecycle = append(h.lifecycle, recorder)
+	h.handleLifecycleEvents(recorder)
+}
+
+// SetResultPublisher sets the publisher that announces stored results to downstream consumers
+func (h *TaskHandler) SetResultPublisher(publisher azure.ResultPublisher) {
+	h.handleResults("result_events", func(ctx context.Context, event events.Event) error {
+		if event.BlobPath == "" {
+			return nil
+		}
+		return publisher.PublishResult(ctx, models.NewResultEvent(event.Result, event.BlobPath))
+	})
+}
+
+// SetNucleiInteractsh sets the interactsh server used by OOB nuclei templates
+func (h *TaskHandler) SetNucleiInteractsh(serverURL, token string, disabled bool, allowedServers []string) {
+	h.scannerFactory.SetNucleiInteractsh(serverURL, token, disabled, allowedServers)
+}
+
+// SetNucleiBudgets sets the time a nuclei scan spends on one target and in total; 0 disables a budget
+func (h *TaskHandler) SetNucleiBudgets(host, scan time.Duration) {
+	h.nucleiHostBudget = host
+	h.nucleiScanBudget = scan
+}
+
+// SetPauseMaxHold sets how long a task of a paused scan waits to be resumed before it fails
+func (h *TaskHandler) SetPauseMaxHold(maxHold time.Duration) {
+	h.pauseMaxHold = maxHold
+}
+
+// SetNucleiCheckpoints makes every nuclei scan checkpoint its resume state, not only the tasks that ask for it
+func (h *TaskHandler) SetNucleiCheckpoints(enabled bool) {
+	h.nucleiCheckpoint = enabled
+}
+
+// SetNucleiReplay sets the nuclei findings that are replayed before they are reported
+func (h *TaskHandler) SetNucleiReplay(replay models.FindingReplay) {
+	h.nucleiReplay = replay
+}
+
+// SetTLSFingerprints sets the per-tenant TLS fingerprints httpx probes with
+func (h *TaskHandler) SetTLSFingerprints(fingerprints models.TLSFingerprints) {
+	h.tlsFingerprints = fingerprints
+}
+
+// SetReviewSampler sets the sampler that keeps unusual httpx responses for manual review
+func (h *TaskHandler) SetReviewSampler(sampler *review.Sampler) {
+	h.reviewSampler = sampler
+}
+
+// SetCloudDNSCredentials sets the read-only credentials cloud_dns tasks read zones with
+func (h *TaskHandler) SetCloudDNSCredentials(credentials scanners.CloudDNSCredentials) {
+	h.scannerFactory.SetCloudDNSCredentials(credentials)
+}
+
+// SetQuotaTracker sets the tracker that limits passive subdomain sources to their API key quotas
+func (h *TaskHandler) SetQuotaTracker(tracker *quota.Tracker) {
+	h.scannerFactory.SetQuotaTracker(tracker)
+}
+
+// SetPassiveStatsStore sets the store the passive subdomain source statistics are added to
+func (h *TaskHandler) SetPassiveStatsStore(store scanners.PassiveStatsStore) {
+	h.scannerFactory.SetPassiveStatsStore(store)
+}
+
+// SetMetrics sets the registry the task progress gauges and event counters are published in
+func (h *TaskHandler) SetMetrics(registry *metrics.Registry) {
+	h.metrics = newTaskMetrics(registry)
+	h.events.SetMetrics(registry)
+	h.handleMetricEvents(registry)
+}
+
+// SetProgressInterval sets how often the progress of a running scanner is sent to Discord and
+// the lifecycle recorders; 0 disables the updates
+func (h *TaskHandler) SetProgressInterval(interval time.Duration) {
+	h.progressInterval = interval
+}
+
+// SelfTest checks that the host has what each scanner needs and disables the task types whose
+// scanner cannot run, returning the reasons by task type
+func (h *TaskHandler) SelfTest() map[models.Task]error {
+	return h.scannerFactory.SelfTest()
+}
+
+// EnableTasks restricts the worker to the given task types
+func (h *TaskHandler) EnableTasks(tasks []models.Task) {
+	h.scannerFactory.Enable(tasks)
+}
+
+// SetCapabilities sets the capabilities the worker advertises; tasks requiring others are left
+// to other workers
+func (h *TaskHandler) SetCapabilities(capabilities []string) {
+	h.capabilities = append([]string{}, capabilities...)
+}
+
+// SetVersionCheck sets the check that defers tasks while this worker's build is older than the
+// required minimum version
+func (h *TaskHandler) SetVersionCheck(check func() error) {
+	h.versionCheck = check
+}
+
+// Capabilities returns the capabilities the worker advertises
+func (h *TaskHandler) Capabilities() []string {
+	return h.capabilities
+}
+
+// SetDisabledTaskAction sets what happens to messages of task types this worker does not run,
+// DisabledTaskAbandon (the default) or DisabledTaskDeadLetter
+func (h *TaskHandler) SetDisabledTaskAction(action string) {
+	h.disabledTaskAction = action
+}
+
+// AvailableTasks returns the task types this worker can run
+func (h *TaskHandler) AvailableTasks() []string {
+	tasks := h.scannerFactory.GetAvailableScanners()
+	sort.Strings(tasks)
+	return tasks
+}
+
+// SetCapacityBudget sets the budget the scanners of concurrent tasks share; without one every
+// scanner starts right away
+func (h *TaskHandler) SetCapacityBudget(budget *capacity.Budget) {
+	h.capacity = budget
+}
+
+// admitCapacity takes the units of the task's scanner from the capacity budget. When other
+// tasks' scanners use them, the task is deferred instead of waiting, since it would hold its
+// message and a concurrency slot without doing anything. The returned function gives the units
+// back and may be called more than once.
+func (h *TaskHandler) admitCapacity(taskMsg *models.TaskMessage) (func(), *models.MessageProcessingResult) {
+	if h.capacity == nil {
+		return func() {}, nil
+	}
+	release := h.capacity.TryAcquire(taskMsg.Task)
+	if release == nil {
+		recheck := time.Now().Add(capacityRecheck)
+		gologger.Info().Msgf("No room for the %d of %d capacity units %s needs, deferring the task for domain %s until %s",
+			h.capacity.Weight(taskMsg.Task), h.capacity.Capacity(), taskMsg.Task, taskMsg.Domain, recheck.Format(time.RFC3339))
+		return nil, &models.MessageProcessingResult{Success: true, DeferUntil: recheck}
+	}
+	return sync.OnceFunc(release), nil
+}
+
+// EventBus returns the bus task steps, scanner progress and stored results are published on
+func (h *TaskHandler) EventBus() *events.Bus {
+	return h.events
+}
+
+// executeScanner runs the scanner, converting panics into internal errors that keep the stack trace
+func (h *TaskHandler) executeScanner(ctx context.Context, scanner models.Scanner, taskCtx *models.TaskContext, input models.ScannerInput) (scannerResult models.ScannerResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			scannerResult = nil
+			err = common.NewPanicError(fmt.Sprintf("%s scanner panicked: %v", scanner.GetName(), r), debug.Stack())
+		}
+	}()
+
+	return scanner.Execute(ctx, taskCtx, input)
+}
+
+// newTaskContext creates the context the scanner runs with, bounded by the scanner context's deadline.
+// Progress reported by the scanner goes to the progress reporter.
+func (h *TaskHandler) newTaskContext(ctx context.Context, taskMsg *models.TaskMessage, progress *progressReporter) *models.TaskContext {
+	deadline, _ := ctx.Deadline()
+	taskCtx := models.NewTaskContext(taskMsg, deadline)
+	taskCtx.Lock = models.MessageLockFromContext(ctx)
+	if h.blobClient != nil {
+		taskCtx.Artifacts = h.blobClient
+	}
+	taskCtx.OnProgress = func(p models.ScanProgress) {
+		taskCtx.Debug().Msgf("Progress for domain %s: %s", taskMsg.Domain, p)
+		progress.report(p)
+	}
+	taskCtx.BlockPolicy = h.blockPolicy
+	taskCtx.OnBlock = func(event models.BlockEvent) {
+		h.reportBlock(taskCtx, taskMsg, event)
+	}
+	return taskCtx
+}
+
+// storeErrorArtifact attaches structured error details to a failed result and stores it in blob storage
+func (h *TaskHandler) storeErrorArtifact(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, processingResult *models.MessageProcessingResult) {
+	if h.blobClient == nil || processingResult.Error == nil {
+		return
+	}
+	// A task rejected for its domain list or tenant ID has no scan prefix to store the artifact under
+	if taskMsg.Domain == "" || (taskMsg.TenantID != "" && h.validator.ValidateTenantID(taskMsg.TenantID) != nil) {
+		gologger.Warning().Msgf("Not storing the error artifact of task %s for scan %d: no valid blob path", taskMsg.Task, taskMsg.ScanID)
+		return
+	}
+
+	result.Status = models.TaskStatusFailed
+	result.Error, _ = limitErrorText(processingResult.Error.Error())
+	h.redactionPolicy(taskMsg).TaskResult(result)
+	result.ErrorDetails = h.buildTaskError(taskMsg, result, processingResult)
+
+	if err := h.blobClient.StoreErrorArtifact(ctx, result); err != nil {
+		gologger.Warning().Msgf("Failed to store error artifact for domain %s: %v", taskMsg.Domain, err)
+	}
+}
+
+// buildTaskError classifies a processing failure into a structured error description
+func (h *TaskHandler) buildTaskError(taskMsg *models.TaskMessage, result *models.TaskResult, processingResult *models.MessageProcessingResult) *models.TaskError {
+	appErr := h.errorClassifier.ClassifyError(processingResult.Error)
+
+	taskError := &models.TaskError{
+		Type:      string(appErr.Type),
+		Message:   appErr.Message,
+		Field:     appErr.Field,
+		Retryable: processingResult.Retryable,
+		Scanner:   string(taskMsg.Task),
+		Stack:     appErr.Stack,
+		Context: map[string]string{
+			"scan_id":     fmt.Sprintf("%d", taskMsg.ScanID),
+			"domain":      taskMsg.Domain,
+			"instance_id": taskMsg.InstanceID,
+			"duration":    result.Duration,
+			"timeout":     h.scannerTimeout.String(),
+		},
+		PartialResults: result.Data,
+	}
+
+	if appErr.Err != nil {
+		taskError.Cause = appErr.Err.Error()
+	}
+	for _, text := range []*string{&taskError.Message, &taskError.Cause, &taskError.Stack} {
+		var truncated bool
+		if *text, truncated = limitErrorText(*text); truncated {
+			taskError.Truncated = true
+		}
+	}
+	if taskMsg.FilePath != "" {
+		taskError.Context["input_blob_path"] = taskMsg.FilePath
+	}
+	if !processingResult.DeferUntil.IsZero() {
+		taskError.Context["retry_at"] = processingResult.DeferUntil.UTC().Format(time.RFC3339)
+	}
+
+	return taskError
+}
+
+// limitErrorText cuts text to maxErrorTextSize bytes, reporting whether it was cut
+func limitErrorText(text string) (string, bool) {
+	if len(text) <= maxErrorTextSize {
+		return text, false
+	}
+	return strings.ToValidUTF8(text[:maxErrorTextSize], ""), true
+}
+
+// finalizeTask stores the result and hands the completion notification to the outbox. The
+// message is only completed once both are durable; everything after that may be lost in a crash
+// and is delivered at least once by the outbox or not at all by the side channels.
 func (h *TaskHandler) finalizeTask(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) *models.MessageProcessingResult {
 	// Log the task duration
 	gologger.Info().Msgf("Task %s for domain %s completed in %s", taskMsg.Task, taskMsg.Domain, result.Duration)
 
+	if err := h.hooks.PreStore(ctx, taskMsg, result); err != nil {
+		return h.failHook(ctx, taskMsg, result, err)
+	}
+
+	// Nothing sensitive is stored or leaves the worker in the completion notification or side channels
+	h.redactionPolicy(taskMsg).TaskResult(result)
+
 	// For subfinder, only store as text file, not JSON
+	var blobPath string
 	if result.Task == models.TaskSubfinder {
 		if subfinderResult, ok := result.Data.(models.SubfinderResult); ok {
-			err := h.blobClient.StoreSubfinderTextResult(ctx, &subfinderResult, result.ScanID, string(result.Task))
+			var err error
+			blobPath, err = h.blobClient.StoreSubfinderTextResult(ctx, &subfinderResult, result.TenantID, result.ScanID, string(result.Task), result.Attempt, result.Duration)
 			if err != nil {
 				gologger.Error().Msgf("Failed to store subfinder txt result for domain %s: %v", taskMsg.Domain, err)
-				return h.createFailureResult(err, true) // Storage errors are usually retryable
+				return h.failStore(ctx, taskMsg, result, err)
 			}
 			gologger.Info().Msgf("Stored subfinder text result for domain %s", taskMsg.Domain)
+			h.storeSubfinderSources(ctx, result, subfinderResult)
 		}
 	} else {
+		// Scope suggestions wait for review next to, not in, the scan's targets. They are merged
+		// before the result is stored, so a stored result always has its suggestions.
+		if expansion, ok := result.Data.(models.ScopeExpansionResult); ok {
+			if err := h.storeScopeSuggestions(ctx, result, expansion); err != nil {
+				gologger.Error().Msgf("Failed to store scope suggestions for domain %s: %v", taskMsg.Domain, err)
+				return h.failStore(ctx, taskMsg, result, err)
+			}
+		}
+
 		// For other tasks, store as JSON
-		if storeErr := h.blobClient.StoreTaskResult(ctx, result); storeErr != nil {
+		var storeErr error
+		if blobPath, storeErr = h.blobClient.StoreTaskResult(ctx, result); storeErr != nil {
 			gologger.Error().Msgf("Failed to store task result for domain %s: %v", taskMsg.Domain, storeErr)
-			return h.createFailureResult(storeErr, true) // Storage errors are usually retryable
+			return h.failStore(ctx, taskMsg, result, storeErr)
+		}
+
+		// Naabu results are also exported as nmap XML for existing tooling
+		if naabuResult, ok := result.Data.(models.NaabuResult); ok {
+			h.storeNmapXML(ctx, result, naabuResult)
 		}
 	}
 
-	h.sendDiscordNotification(ctx, taskMsg, result, nil, notification.StepResultStored)
+	h.publishStep(taskMsg, result, nil, notification.StepResultStored)
+	h.hooks.PostStore(ctx, taskMsg, result, blobPath)
+
+	// Incidents, tickets, exports and result events see the result before its outbox entry is
+	// stored, so a redelivered message that finds the entry has nothing left to hand them
+	h.deliverResult(ctx, taskMsg, result, blobPath)
 
-	// Send completion notification if enabled
-	if h.notifier != nil {
+	// The outbox entry must be durable before the message is completed, or a crash would lose the
+	// notification. Without an outbox the notification is sent before completing instead.
+	if h.outbox != nil {
+		if taskMsg.InstanceID == "" {
+			gologger.Warning().Msgf("No instance_id for task %s on domain %s, the orchestrator is not notified", taskMsg.Task, taskMsg.Domain)
+		} else if err := h.outbox.Add(ctx, taskMsg, result); err != nil {
+			gologger.Error().Msgf("Failed to add completion notification for domain %s to the outbox: %v", taskMsg.Domain, err)
+			return h.failStore(ctx, taskMsg, result, err)
+		}
+	} else if h.notifier != nil {
 		if notifyErr := h.sendCompletionNotification(ctx, taskMsg, result); notifyErr != nil {
 			gologger.Warning().Msgf("Failed to send completion notification for domain %s: %v", taskMsg.Domain, notifyErr)
 		} else {
-			h.sendDiscordNotification(ctx, taskMsg, result, nil, notification.StepNotificationSent)
+			h.publishStep(taskMsg, result, nil, notification.StepNotificationSent)
 		}
 	}
 
+	h.clearCheckpoint(ctx, taskMsg)
+
+	// Metrics and live streams pick the result up from the bus
+	h.events.RecordResult(taskMsg, result, blobPath)
+
 	return &models.MessageProcessingResult{Success: true}
 }
 
-// sendDiscordNotification sends a Discord notification for a specific step
-func (h *TaskHandler) sendDiscordNotification(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, err error, step notification.NotificationStep) {
-	if h.discordNotifier == nil {
+// failStore fails a task whose result or completion notification could not be stored. Storage
+// errors are usually retryable.
+func (h *TaskHandler) failStore(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, err error) *models.MessageProcessingResult {
+	failure := h.createFailureResult(err, true)
+	h.storeErrorArtifact(ctx, taskMsg, result, failure)
+	return failure
+}
+
+// failBeforeStart stores the error artifact of a task that failed before its scanner was started
+func (h *TaskHandler) failBeforeStart(ctx context.Context, taskMsg *models.TaskMessage, failure *models.MessageProcessingResult) *models.MessageProcessingResult {
+	h.storeErrorArtifact(ctx, taskMsg, h.createTaskResult(taskMsg), failure)
+	return failure
+}
+
+// failHook fails a task whose hook returned an error. Hooks decide whether the task is retried
+// by returning a classified error; other errors are retried.
+func (h *TaskHandler) failHook(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult, err error) *models.MessageProcessingResult {
+	gologger.Error().Msgf("Task %s for domain %s stopped by a hook: %v", taskMsg.Task, taskMsg.Domain, err)
+	result.Status = models.TaskStatusFailed
+	result.Error = err.Error()
+	h.publishStep(taskMsg, result, err, notification.StepTaskFailed)
+
+	retryable := true
+	var appErr *common.AppError
+	if errors.As(err, &appErr) {
+		retryable = appErr.IsRetryable()
+	}
+	failure := h.createFailureResult(err, retryable)
+	h.storeErrorArtifact(ctx, taskMsg, result, failure)
+	return failure
+}
+
+// alreadyFinalized reports whether an earlier delivery of a redelivered message stored its result
+// and completion notification, which happens when the worker stopped before completing it. The
+// outbox entry is added last, so finding it means nothing is left to do.
+func (h *TaskHandler) alreadyFinalized(ctx context.Context, taskMsg *models.TaskMessage) bool {
+	if h.outbox == nil || taskMsg.Attempt <= 1 || taskMsg.InstanceID == "" {
+		return false
+	}
+
+	found, err := h.outbox.Contains(ctx, taskMsg)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to check the outbox for task %s on domain %s, running it again: %v", taskMsg.Task, taskMsg.Domain, err)
+		return false
+	}
+	return found
+}
+
+// storeNmapXML stores the nmap XML export of a naabu result. The JSON result is authoritative,
+// so export failures are logged rather than failing the task.
+func (h *TaskHandler) storeNmapXML(ctx context.Context, result *models.TaskResult, naabuResult models.NaabuResult) {
+	finishedAt := time.Now()
+	startedAt := finishedAt
+	if duration, err := time.ParseDuration(result.Duration); err == nil {
+		startedAt = finishedAt.Add(-duration)
+	}
+
+	xmlData, err := exporters.NaabuToNmapXML(naabuResult, startedAt, finishedAt)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to export naabu result for domain %s as nmap XML: %v", result.Domain, err)
 		return
 	}
 
-	if notifyErr := h.discordNotifier.NotifyStep(ctx, step, taskMsg, result, err); notifyErr != nil {
-		gologger.Warning().Msgf("Failed to send Discord notification for step %s: %v", step, notifyErr)
+	if err := h.blobClient.StoreNaabuXMLResult(ctx, xmlData, result.TenantID, result.Domain, result.ScanID, string(result.Task), result.Attempt); err != nil {
+		gologger.Warning().Msgf("Failed to store nmap XML result for domain %s: %v", result.Domain, err)
+		return
 	}
+	gologger.Info().Msgf("Stored nmap XML result for domain %s", result.Domain)
+}
+
+// storeReviewSamples stores the exchanges sampled for manual review. They only support quality
+// control, so failures are logged rather than failing the task.
+func (h *TaskHandler) storeReviewSamples(ctx context.Context, result *models.TaskResult, collection *review.Collection) {
+	samples := collection.Samples()
+	if len(samples) == 0 {
+		return
+	}
+	policy := h.redaction.Lookup(result.TenantID)
+	for i := range samples {
+		samples[i].HTTPExchange = policy.Exchange(samples[i].HTTPExchange)
+	}
+
+	blobPath, err := h.blobClient.StoreReviewSamples(ctx, &models.ReviewSamples{
+		Task:          result.Task,
+		ScanID:        result.ScanID,
+		Domain:        result.Domain,
+		TenantID:      result.TenantID,
+		CorrelationID: result.CorrelationID,
+		Candidates:    collection.Candidates(),
+		Samples:       samples,
+	}, result.Attempt)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to store review samples for domain %s: %v", result.Domain, err)
+		return
+	}
+	gologger.Info().Msgf("Stored %d of %d interesting responses for review at %s", len(samples), collection.Candidates(), blobPath)
+}
+
+// storeScopeSuggestions adds the suggestions of a scope expansion result to the domain's
+// suggestions for review, keeping the decisions already made
+func (h *TaskHandler) storeScopeSuggestions(ctx context.Context, result *models.TaskResult, expansion models.ScopeExpansionResult) error {
+	suggestedAt, err := time.Parse(time.RFC3339, result.Timestamp)
+	if err != nil {
+		suggestedAt = time.Now()
+	}
+	return h.blobClient.UpdateScopeSuggestions(ctx, result.TenantID, result.Domain, func(suggestions *models.ScopeSuggestions) error {
+		if added := suggestions.Merge(expansion.Suggestions, suggestedAt.UTC()); added > 0 {
+			gologger.Info().Msgf("%d new scope suggestions for domain %s wait for review", added, result.Domain)
+		}
+		return nil
+	})
+}
+
+// storeSubfinderSources stores the source attribution of a subfinder result next to its text
+// result. Downstream tasks only need the text, so failures are logged rather than failing the task.
+func (h *TaskHandler) storeSubfinderSources(ctx context.Context, result *models.TaskResult, subfinderResult models.SubfinderResult) {
+	if err := h.blobClient.StoreSubfinderSourcesResult(ctx, &subfinderResult, result.TenantID, result.ScanID, string(result.Task), result.Attempt); err != nil {
+		gologger.Warning().Msgf("Failed to store subfinder sources for domain %s: %v", result.Domain, err)
+		return
+	}
+	gologger.Info().Msgf("Stored subfinder sources for domain %s", result.Domain)
+}
+
+// publishStep publishes a task step on the event bus, which sends it on to Discord and the
+// lifecycle recorders
+func (h *TaskHandler) publishStep(taskMsg *models.TaskMessage, result *models.TaskResult, err error, step notification.NotificationStep) {
+	h.events.RecordStep(taskMsg, string(step), result, err)
 }
 
 // createFailureResult creates a failure result with the given error and retryable flag
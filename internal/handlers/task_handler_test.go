@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/capacity"
+	"github.com/allsafeASM/api/internal/events"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestErrorArtifactStoredOnEveryFailure(t *testing.T) {
+	artifactPath := "acme/example.com-5/subfinder/errors/attempt-1.json"
+	tests := []struct {
+		name          string
+		taskMsg       *models.TaskMessage
+		failStorage   bool
+		wantMessage   string
+		wantRetryable bool
+	}{
+		{
+			name:        "validation failure",
+			taskMsg:     &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 5, Domain: "example.com", TenantID: "acme", FilePath: "acme/other.com-1/input.txt"},
+			wantMessage: "outside of the scan prefix",
+		},
+		{
+			name:          "result storage failure",
+			taskMsg:       &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 5, Domain: "example.com", TenantID: "acme"},
+			failStorage:   true,
+			wantMessage:   "failed to upload subfinder text result",
+			wantRetryable: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, server := newTestHandler(t, nil)
+			if tt.failStorage {
+				server.FailWrites(func(container, name string) bool { return !strings.Contains(name, "/errors/") })
+			}
+
+			result := h.HandleTask(context.Background(), tt.taskMsg)
+			if result.Success || result.Retryable != tt.wantRetryable {
+				t.Fatalf("HandleTask() = %+v, want a failure with retryable %t", result, tt.wantRetryable)
+			}
+
+			content, ok := server.Blob("scans", artifactPath)
+			if !ok {
+				t.Fatalf("No error artifact at %s, stored %v", artifactPath, server.Names("scans", ""))
+			}
+			var stored models.TaskResult
+			if err := json.Unmarshal(content, &stored); err != nil {
+				t.Fatalf("Error artifact is not a task result: %v", err)
+			}
+			if stored.Status != models.TaskStatusFailed || stored.ErrorDetails == nil {
+				t.Fatalf("Error artifact = %s, want a failed result with error details", content)
+			}
+			if !strings.Contains(stored.Error, tt.wantMessage) || stored.ErrorDetails.Retryable != tt.wantRetryable {
+				t.Errorf("Error artifact = %s, want an error about %q and retryable %t", content, tt.wantMessage, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestErrorArtifactTextIsBounded(t *testing.T) {
+	h, _ := newTestHandler(t, nil)
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 5, Domain: "example.com"}
+	output := strings.Repeat("x", 4*maxErrorTextSize)
+	processingResult := &models.MessageProcessingResult{Error: fmt.Errorf("tool failed: %s", output)}
+
+	taskError := h.buildTaskError(taskMsg, &models.TaskResult{}, processingResult)
+	if !taskError.Truncated {
+		t.Error("Truncated = false, want true")
+	}
+	for name, text := range map[string]string{"message": taskError.Message, "cause": taskError.Cause, "stack": taskError.Stack} {
+		if len(text) > maxErrorTextSize {
+			t.Errorf("%s has %d bytes, want at most %d", name, len(text), maxErrorTextSize)
+		}
+	}
+}
+
+func TestErrorArtifactNeedsASafeTenant(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 5, Domain: "example.com", TenantID: "../acme"}
+
+	if result := h.HandleTask(context.Background(), taskMsg); result.Success {
+		t.Fatal("Expected a task with an invalid tenant ID to fail")
+	}
+	if names := server.Names("scans", ""); len(names) != 0 {
+		t.Errorf("Stored %v for a task with an invalid tenant ID", names)
+	}
+}
+
+func TestPartialResultIsKept(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	h.SetSimulation("fixtures")
+	server.PutBlob("scans", "fixtures/httpx.json", []byte(`{"domain": "{{domain}}", "output": [{"host": "www.{{domain}}", "status_code": 200}], "probed": 3, "partial": true}`))
+	server.PutBlob("scans", "example.com-6/subdomains.txt", []byte("www.example.com\napi.example.com\nmail.example.com\n"))
+
+	taskMsg := &models.TaskMessage{Task: models.TaskHttpx, ScanID: 6, Domain: "example.com", FilePath: "example.com-6/subdomains.txt"}
+	if result := h.HandleTask(context.Background(), taskMsg); !result.Success {
+		t.Fatalf("HandleTask() = %+v, want the partial result stored", result)
+	}
+
+	content, ok := server.Blob("scans", "example.com-6/httpx/out/attempt-1.json")
+	if !ok {
+		t.Fatalf("No result stored, stored %v", server.Names("scans", ""))
+	}
+	var stored struct {
+		Status models.TaskStatus  `json:"status"`
+		Error  string             `json:"error"`
+		Data   models.HttpxResult `json:"data"`
+	}
+	if err := json.Unmarshal(content, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.TaskStatusPartial || stored.Error == "" || !stored.Data.Partial || len(stored.Data.Results) != 1 {
+		t.Errorf("Stored result = %s, want the partial hosts with the timeout", content)
+	}
+	if names := server.Names("scans", "example.com-6/httpx/errors/"); len(names) != 0 {
+		t.Errorf("A partial result stored error artifacts %v", names)
+	}
+}
+
+func TestResultHandlersSeeEveryStoredResult(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	h.SetSimulation("fixtures")
+	server.PutBlob("scans", "fixtures/httpx.json", []byte(`{"domain": "{{domain}}", "output": [{"host": "www.{{domain}}", "status_code": 200}], "probed": 1}`))
+
+	var handled []string
+	h.handleResults("panicking", func(ctx context.Context, event events.Event) error { panic("broken handler") })
+	h.handleResults("recording", func(ctx context.Context, event events.Event) error {
+		handled = append(handled, event.BlobPath)
+		return nil
+	})
+
+	// More results than the bus would queue for a subscriber that does not keep up
+	for scanID := 1; scanID <= 40; scanID++ {
+		input := fmt.Sprintf("example.com-%d/subdomains.txt", scanID)
+		server.PutBlob("scans", input, []byte("www.example.com\n"))
+		taskMsg := &models.TaskMessage{Task: models.TaskHttpx, ScanID: scanID, Domain: "example.com", FilePath: input}
+		if result := h.HandleTask(context.Background(), taskMsg); !result.Success {
+			t.Fatalf("HandleTask() = %+v, want the result stored", result)
+		}
+		if len(handled) != scanID {
+			t.Fatalf("Result handler saw %d results after %d tasks completed", len(handled), scanID)
+		}
+	}
+}
+
+func TestMultiDomainTaskSkipsFinishedDomains(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	h.SetSimulation("fixtures")
+	server.PutBlob("scans", "fixtures/subfinder.json", []byte(`{"domain": "{{domain}}", "subdomains": ["www.{{domain}}"]}`))
+
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 7, InstanceID: "abc", Domains: []string{"example.com", "example.org"}}
+	// An earlier delivery finished example.org before the message was retried
+	err := h.blobClient.UpdateMultiDomainProgress(context.Background(), taskMsg, func(progress *models.MultiDomainProgress) error {
+		progress.Domains["example.org"] = models.DomainOutcome{Status: models.DomainCompleted}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := h.HandleTask(context.Background(), taskMsg); !result.Success {
+		t.Fatalf("HandleTask() = %+v, want the message completed", result)
+	}
+	if names := server.Names("scans", "example.com-7/subfinder/"); len(names) == 0 {
+		t.Errorf("No result stored for the unfinished domain, stored %v", server.Names("scans", ""))
+	}
+	if names := server.Names("scans", "example.org-7/"); len(names) != 0 {
+		t.Errorf("The finished domain ran again and stored %v", names)
+	}
+	if _, ok := server.Blob("scans", models.MultiDomainProgressBlobPath(taskMsg)); ok {
+		t.Error("Expected the progress to be removed once the message completed")
+	}
+}
+
+func TestStaleWorkerDefersTask(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	h.SetVersionCheck(func() error { return fmt.Errorf("worker dev has no release version") })
+
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 5, Domain: "example.com", TenantID: "acme"}
+	result := h.HandleTask(context.Background(), taskMsg)
+	if !result.Success || result.Abandon || result.DeferUntil.IsZero() {
+		t.Fatalf("HandleTask() = %+v, want the task deferred instead of abandoned", result)
+	}
+	if names := server.Names("scans", "acme/"); len(names) != 0 {
+		t.Errorf("Stale worker stored %v", names)
+	}
+}
+
+func TestTaskWithoutCapacityIsDeferred(t *testing.T) {
+	h, server := newTestHandler(t, nil)
+	budget := capacity.NewBudget(capacity.DefaultCapacity, nil)
+	h.SetCapacityBudget(budget)
+	releaseRunning := budget.TryAcquire(models.TaskNuclei)
+
+	// A second nuclei task is turned away before anything is done for it
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, ScanID: 6, Domain: "example.com", TenantID: "acme"}
+	result := h.HandleTask(context.Background(), taskMsg)
+	if !result.Success || result.DeferUntil.IsZero() {
+		t.Fatalf("HandleTask() = %+v, want the task deferred", result)
+	}
+	if names := server.Names("scans", "acme/"); len(names) != 0 {
+		t.Errorf("Deferred task stored %v", names)
+	}
+
+	// A light scanner still fits next to the running one, and gives its units back once
+	release, deferral := h.admitCapacity(&models.TaskMessage{Task: models.TaskDNSResolve})
+	if deferral != nil {
+		t.Fatalf("admitCapacity() = %+v, want DNSX admitted next to nuclei", deferral)
+	}
+	release()
+	release()
+	releaseRunning()
+	if release, deferral = h.admitCapacity(taskMsg); deferral != nil {
+		t.Fatalf("admitCapacity() = %+v, want nuclei admitted once the units are free", deferral)
+	}
+	release()
+}
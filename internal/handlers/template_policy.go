@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// SetTemplatePolicy sets the nuclei template policy of tenants without a stored one
+func (h *TaskHandler) SetTemplatePolicy(policy *models.TemplatePolicy) {
+	h.templatePolicy = policy
+}
+
+// loadTemplatePolicy returns the tenant's stored template policy, or else the global stored one,
+// or else the configured one
+func (h *TaskHandler) loadTemplatePolicy(ctx context.Context, tenantID string) (*models.TemplatePolicy, error) {
+	if h.blobClient == nil {
+		return h.templatePolicy, nil
+	}
+
+	var paths []string
+	if tenantID != "" {
+		paths = append(paths, models.TemplatePolicyBlobPath(tenantID))
+	}
+	paths = append(paths, models.TemplatePolicyBlobPath(""))
+	for _, path := range paths {
+		policy, err := h.blobClient.LoadTemplatePolicy(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load template policy %s: %w", path, err)
+		}
+		if policy != nil {
+			return policy, nil
+		}
+	}
+	return h.templatePolicy, nil
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/notification"
+	"github.com/projectdiscovery/gologger"
+)
+
+// SetVerifyTimeout sets the timeout of verify tasks, shorter than the scanner timeout so change
+// alerts are confirmed within minutes; 0 uses the scanner timeout
+func (h *TaskHandler) SetVerifyTimeout(timeout time.Duration) {
+	h.verifyTimeout = timeout
+}
+
+// taskTimeout returns how long the task's scanner may run
+func (h *TaskHandler) taskTimeout(taskMsg *models.TaskMessage) time.Duration {
+	if taskMsg.Mode == models.TaskModeVerify && h.verifyTimeout > 0 {
+		return h.verifyTimeout
+	}
+	return h.scannerTimeout
+}
+
+// verifyTargets returns the targets of a verify task's input result missing from its baseline
+// result, and records the counts on the result
+func (h *TaskHandler) verifyTargets(ctx context.Context, taskMsg *models.TaskMessage, targets []string, result *models.TaskResult) ([]string, error) {
+	baseline, err := h.resultTargets(ctx, taskMsg.Task, taskMsg.BaselineResultPath, "baseline_result_path")
+	if err != nil {
+		return nil, err
+	}
+
+	delta := models.DeltaTargets(targets, baseline)
+	result.Verify = &models.VerifyDelta{
+		BaselineResultPath: taskMsg.BaselineResultPath,
+		Targets:            len(targets),
+		Baseline:           len(baseline),
+		Scanned:            len(delta),
+		Unchanged:          len(targets) - len(delta),
+	}
+	gologger.Info().Msgf("Verify task %s for domain %s scans %d of %d targets not in the baseline",
+		taskMsg.Task, taskMsg.Domain, len(delta), len(targets))
+	return delta, nil
+}
+
+// completeWithoutTargets completes a verify task whose input result adds nothing to its baseline
+// with an empty result, without running the scanner
+func (h *TaskHandler) completeWithoutTargets(taskMsg *models.TaskMessage, result *models.TaskResult) *models.MessageProcessingResult {
+	result.Status = models.TaskStatusCompleted
+	result.Data = models.EmptyResult(taskMsg.Task, taskMsg.Domain)
+	gologger.Info().Msgf("Verify task %s for domain %s has no new targets, nothing to scan", taskMsg.Task, taskMsg.Domain)
+
+	h.publishStep(taskMsg, result, nil, notification.StepTaskCompleted)
+	return &models.MessageProcessingResult{Success: true}
+}
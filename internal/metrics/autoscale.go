@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// AnyTask is the task type the backlog is put under when no messages could be sampled
+const AnyTask models.Task = "*"
+
+// durationSmoothing is the weight of a new task duration in the moving average of its task type
+const durationSmoothing = 0.2
+
+// TaskMixReader samples the task types of messages waiting in a queue without receiving them
+type TaskMixReader interface {
+	PeekTaskTypes(ctx context.Context, max int) (map[models.Task]int, error)
+}
+
+// TaskDurations keeps a moving average of the duration of each task type
+type TaskDurations struct {
+	mu      sync.RWMutex
+	average map[models.Task]time.Duration
+}
+
+// NewTaskDurations creates an empty set of averages
+func NewTaskDurations() *TaskDurations {
+	return &TaskDurations{average: make(map[models.Task]time.Duration)}
+}
+
+// Observe adds the duration of a finished task to the average of its type
+func (d *TaskDurations) Observe(task models.Task, duration time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if average, ok := d.average[task]; ok {
+		duration = time.Duration(float64(average)*(1-durationSmoothing) + float64(duration)*durationSmoothing)
+	}
+	d.average[task] = duration
+}
+
+// Average returns the average duration of a task type, or false before one finished
+func (d *TaskDurations) Average(task models.Task) (time.Duration, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	average, ok := d.average[task]
+	return average, ok
+}
+
+// ReplicaPolicy turns the backlog of the task queue into the number of workers that would
+// clear it within TargetDrain. Each task type costs its weight in capacity units for its
+// average duration, so a backlog of nuclei scans asks for more workers than one of dnsx tasks.
+type ReplicaPolicy struct {
+	Capacity        int                   // Units of capacity of a worker
+	Weight          func(models.Task) int // Units a task type takes; nil weighs every type 1
+	TargetDrain     time.Duration
+	DefaultDuration time.Duration // Duration of task types no task of which finished yet
+	MinReplicas     int
+	MaxReplicas     int
+	PeekMessages    int // Messages sampled for the task types of the backlog; 0 disables sampling
+}
+
+// ReplicaHint is the number of workers the backlog asks for, with the inputs of each task type
+type ReplicaHint struct {
+	DesiredReplicas int                             `json:"desired_replicas"`
+	Tasks           map[models.Task]TaskReplicaHint `json:"tasks,omitempty"`
+}
+
+// TaskReplicaHint is the share of one task type in a replica hint
+type TaskReplicaHint struct {
+	Messages    float64 `json:"messages"` // Active messages of the type, estimated from the sample
+	AvgDuration float64 `json:"avg_duration_seconds"`
+	Weight      int     `json:"weight"`
+	Replicas    float64 `json:"replicas"`
+}
+
+// Hint computes the replica hint of a queue depth sample. sampled counts the task types of the
+// messages peeked at; the active messages are split between the types in the same proportion.
+func (p ReplicaPolicy) Hint(depth models.QueueDepth, sampled map[models.Task]int, durations *TaskDurations) ReplicaHint {
+	hint := ReplicaHint{Tasks: make(map[models.Task]TaskReplicaHint)}
+
+	total := 0
+	for _, count := range sampled {
+		total += count
+	}
+	if total == 0 && depth.Active > 0 {
+		sampled, total = map[models.Task]int{AnyTask: 1}, 1
+	}
+
+	var replicas float64
+	capacitySeconds := float64(p.Capacity) * p.TargetDrain.Seconds()
+	for task, count := range sampled {
+		if count == 0 {
+			continue
+		}
+		share := TaskReplicaHint{
+			Messages:    float64(depth.Active) * float64(count) / float64(total),
+			AvgDuration: p.duration(task, durations).Seconds(),
+			Weight:      1,
+		}
+		if p.Weight != nil && task != AnyTask {
+			share.Weight = p.Weight(task)
+		}
+		if capacitySeconds > 0 {
+			share.Replicas = share.Messages * float64(share.Weight) * share.AvgDuration / capacitySeconds
+		}
+		replicas += share.Replicas
+		hint.Tasks[task] = share
+	}
+
+	hint.DesiredReplicas = int(math.Ceil(replicas))
+	// Any backlog gets one worker, even when it clears well within the target
+	if depth.Active > 0 {
+		hint.DesiredReplicas = max(hint.DesiredReplicas, 1)
+	}
+	hint.DesiredReplicas = max(hint.DesiredReplicas, p.MinReplicas)
+	if p.MaxReplicas > 0 {
+		hint.DesiredReplicas = min(hint.DesiredReplicas, p.MaxReplicas)
+	}
+	return hint
+}
+
+// duration returns the average duration of a task type. The backlog of unknown types takes the
+// longest average seen, so an unsampled backlog is not underestimated.
+func (p ReplicaPolicy) duration(task models.Task, durations *TaskDurations) time.Duration {
+	if durations == nil {
+		return p.DefaultDuration
+	}
+	if task != AnyTask {
+		if average, ok := durations.Average(task); ok {
+			return average
+		}
+		return p.DefaultDuration
+	}
+
+	durations.mu.RLock()
+	defer durations.mu.RUnlock()
+	longest := time.Duration(0)
+	for _, average := range durations.average {
+		longest = max(longest, average)
+	}
+	if longest == 0 {
+		return p.DefaultDuration
+	}
+	return longest
+}
@@ -0,0 +1,156 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+// QueueDepthReader reads the message counts of a queue
+type QueueDepthReader interface {
+	QueueDepth(ctx context.Context) (models.QueueDepth, error)
+}
+
+// QueueMonitor samples the depth of the task queue at a fixed interval, publishes it as gauges
+// and serves the latest sample as JSON for the KEDA metrics-api scaler
+type QueueMonitor struct {
+	reader   QueueDepthReader
+	interval time.Duration
+	messages *Gauge
+
+	// The replica hint is computed with each sample once a policy is set
+	policy       *ReplicaPolicy
+	durations    *TaskDurations
+	replicas     *Gauge
+	taskReplicas *Gauge
+
+	mu         sync.RWMutex
+	latest     *models.QueueDepth
+	latestHint *ReplicaHint
+}
+
+// NewQueueMonitor creates a monitor that publishes the queue depth in the registry
+func NewQueueMonitor(reader QueueDepthReader, registry *Registry, interval time.Duration) *QueueMonitor {
+	return &QueueMonitor{
+		reader:   reader,
+		interval: interval,
+		messages: registry.Gauge("asm_queue_messages", "Messages in the task queue by state.", "queue", "state"),
+		replicas: registry.Gauge("asm_autoscale_desired_replicas", "Workers the backlog of the task queue asks for."),
+		taskReplicas: registry.Gauge("asm_autoscale_task_replicas",
+			"Workers the backlog of each task type asks for, before rounding.", "task"),
+	}
+}
+
+// SetAutoscale makes each sample compute a replica hint with the policy and the task durations
+func (m *QueueMonitor) SetAutoscale(policy ReplicaPolicy, durations *TaskDurations) {
+	m.policy = &policy
+	m.durations = durations
+}
+
+// Run samples the queue depth until ctx is done
+func (m *QueueMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.sample(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample reads the queue depth once and updates the gauges
+func (m *QueueMonitor) sample(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+
+	depth, err := m.reader.QueueDepth(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			gologger.Warning().Msgf("Failed to sample queue depth: %v", err)
+		}
+		return
+	}
+
+	m.messages.Set(float64(depth.Active), depth.Queue, "active")
+	m.messages.Set(float64(depth.Scheduled), depth.Queue, "scheduled")
+	m.messages.Set(float64(depth.DeadLetter), depth.Queue, "dead_letter")
+
+	var hint *ReplicaHint
+	if m.policy != nil {
+		hint = m.hint(ctx, depth)
+	}
+
+	m.mu.Lock()
+	previous := m.latestHint
+	m.latest = &depth
+	m.latestHint = hint
+	m.mu.Unlock()
+
+	if hint == nil {
+		return
+	}
+	m.replicas.Set(float64(hint.DesiredReplicas))
+	for task, share := range hint.Tasks {
+		m.taskReplicas.Set(share.Replicas, string(task))
+	}
+	if previous != nil {
+		for task := range previous.Tasks {
+			if _, ok := hint.Tasks[task]; !ok {
+				m.taskReplicas.Delete(string(task))
+			}
+		}
+	}
+}
+
+// hint samples the task types of the backlog, when the queue can be peeked, and computes the replica hint
+func (m *QueueMonitor) hint(ctx context.Context, depth models.QueueDepth) *ReplicaHint {
+	var sampled map[models.Task]int
+	if mix, ok := m.reader.(TaskMixReader); ok && m.policy.PeekMessages > 0 && depth.Active > 0 {
+		var err error
+		if sampled, err = mix.PeekTaskTypes(ctx, m.policy.PeekMessages); err != nil && ctx.Err() == nil {
+			gologger.Warning().Msgf("Failed to sample the task types of the queue: %v", err)
+		}
+	}
+	hint := m.policy.Hint(depth, sampled, m.durations)
+	return &hint
+}
+
+// Latest returns the most recent sample, or nil before the first one succeeded
+func (m *QueueMonitor) Latest() *models.QueueDepth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.latest
+}
+
+// autoscaleResponse is the latest sample with its replica hint, when there is one
+type autoscaleResponse struct {
+	models.QueueDepth
+	*ReplicaHint
+}
+
+// ServeHTTP serves the latest sample as JSON. KEDA's metrics-api scaler can scale the workers on
+// it with valueLocation "active", or "desired_replicas" once an autoscale policy is set. Until the
+// first sample succeeds it answers 503, so the scaler keeps its current replica count instead of
+// scaling on a missing value.
+func (m *QueueMonitor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mu.RLock()
+	latest, hint := m.latest, m.latestHint
+	m.mu.RUnlock()
+	if latest == nil {
+		http.Error(w, "queue depth not sampled yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(autoscaleResponse{QueueDepth: *latest, ReplicaHint: hint})
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// fakeQueue returns a fixed depth or error
+type fakeQueue struct {
+	depth models.QueueDepth
+	err   error
+}
+
+func (q *fakeQueue) QueueDepth(ctx context.Context) (models.QueueDepth, error) {
+	return q.depth, q.err
+}
+
+func TestQueueMonitor(t *testing.T) {
+	queue := &fakeQueue{err: errors.New("unauthorized")}
+	registry := NewRegistry()
+	monitor := NewQueueMonitor(queue, registry, time.Minute)
+
+	monitor.sample(context.Background())
+	rec := httptest.NewRecorder()
+	monitor.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/autoscale", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before the first sample, got %d", rec.Code)
+	}
+
+	queue.depth, queue.err = models.QueueDepth{Queue: "tasks", Active: 42, Scheduled: 3, DeadLetter: 1}, nil
+	monitor.sample(context.Background())
+
+	rec = httptest.NewRecorder()
+	monitor.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/autoscale", nil))
+	var depth models.QueueDepth
+	if err := json.Unmarshal(rec.Body.Bytes(), &depth); err != nil || depth.Active != 42 {
+		t.Errorf("Expected the latest sample as JSON, got %q (%v)", rec.Body.String(), err)
+	}
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	for _, line := range []string{
+		`asm_queue_messages{queue="tasks",state="active"} 42`,
+		`asm_queue_messages{queue="tasks",state="dead_letter"} 1`,
+		`asm_queue_messages{queue="tasks",state="scheduled"} 3`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %s in:\n%s", line, out.String())
+		}
+	}
+}
+
+// fakeMixQueue also samples the task types of its messages
+type fakeMixQueue struct {
+	fakeQueue
+	sampled map[models.Task]int
+}
+
+func (q *fakeMixQueue) PeekTaskTypes(ctx context.Context, max int) (map[models.Task]int, error) {
+	return q.sampled, nil
+}
+
+func TestQueueMonitorAutoscale(t *testing.T) {
+	queue := &fakeMixQueue{
+		fakeQueue: fakeQueue{depth: models.QueueDepth{Queue: "tasks", Active: 100}},
+		sampled:   map[models.Task]int{models.TaskNuclei: 1, models.TaskDNSResolve: 3},
+	}
+	registry := NewRegistry()
+	monitor := NewQueueMonitor(queue, registry, time.Minute)
+	durations := NewTaskDurations()
+	durations.Observe(models.TaskNuclei, 20*time.Minute)
+	durations.Observe(models.TaskDNSResolve, time.Minute)
+	monitor.SetAutoscale(ReplicaPolicy{
+		Capacity:        4,
+		Weight:          func(task models.Task) int { return map[models.Task]int{models.TaskNuclei: 3}[task] + 1 },
+		TargetDrain:     10 * time.Minute,
+		DefaultDuration: 5 * time.Minute,
+		MaxReplicas:     50,
+		PeekMessages:    100,
+	}, durations)
+	monitor.sample(context.Background())
+
+	rec := httptest.NewRecorder()
+	monitor.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/autoscale", nil))
+	var body struct {
+		Active          int                             `json:"active"`
+		DesiredReplicas int                             `json:"desired_replicas"`
+		Tasks           map[models.Task]TaskReplicaHint `json:"tasks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode %q: %v", rec.Body.String(), err)
+	}
+	// 25 nuclei scans of 4 units for 20 minutes ask for 50 workers, 75 dnsx tasks of 1 unit
+	// for a minute for less than 2, each worker giving 4 units for 10 minutes
+	if body.Active != 100 || body.Tasks[models.TaskNuclei].Replicas != 50 || body.DesiredReplicas != 50 {
+		t.Errorf("Unexpected autoscale response %s", rec.Body.String())
+	}
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+	for _, line := range []string{
+		"asm_autoscale_desired_replicas 50",
+		`asm_autoscale_task_replicas{task="nuclei"} 50`,
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("Expected %s in:\n%s", line, out.String())
+		}
+	}
+}
+
+func TestReplicaPolicyHint(t *testing.T) {
+	policy := ReplicaPolicy{Capacity: 4, TargetDrain: 10 * time.Minute, DefaultDuration: 5 * time.Minute, MinReplicas: 1, MaxReplicas: 10}
+
+	if hint := policy.Hint(models.QueueDepth{}, nil, nil); hint.DesiredReplicas != 1 {
+		t.Errorf("Expected the minimum for an empty queue, got %d", hint.DesiredReplicas)
+	}
+	policy.MinReplicas = 0
+	if hint := policy.Hint(models.QueueDepth{}, nil, nil); hint.DesiredReplicas != 0 {
+		t.Errorf("Expected no workers for an empty queue, got %d", hint.DesiredReplicas)
+	}
+	if hint := policy.Hint(models.QueueDepth{Active: 1}, nil, nil); hint.DesiredReplicas != 1 {
+		t.Errorf("Expected one worker for a small backlog, got %d", hint.DesiredReplicas)
+	}
+
+	// Without a sample the backlog takes the longest average duration seen
+	durations := NewTaskDurations()
+	durations.Observe(models.TaskHttpx, 2*time.Minute)
+	durations.Observe(models.TaskHttpx, 12*time.Minute)
+	if average, _ := durations.Average(models.TaskHttpx); average != 4*time.Minute {
+		t.Errorf("Expected a moving average of 4m, got %s", average)
+	}
+	hint := policy.Hint(models.QueueDepth{Active: 30}, nil, durations)
+	if hint.DesiredReplicas != 3 || hint.Tasks[AnyTask].AvgDuration != 240 {
+		t.Errorf("Expected 3 workers for 30 unsampled tasks of 4m, got %+v", hint)
+	}
+	if hint := policy.Hint(models.QueueDepth{Active: 1000}, nil, durations); hint.DesiredReplicas != 10 {
+		t.Errorf("Expected the maximum for a large backlog, got %d", hint.DesiredReplicas)
+	}
+}
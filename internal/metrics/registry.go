@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric kinds in the Prometheus text format
+const (
+	kindGauge   = "gauge"
+	kindCounter = "counter"
+)
+
+// Registry holds metrics and renders them in the Prometheus text exposition format.
+// A nil registry hands out nil metrics, whose methods do nothing.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is a named metric with one series per label value combination
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	series map[string]*series
+}
+
+// series is one labelled value of a metric
+type series struct {
+	labelValues []string
+	value       float64
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Gauge registers a gauge, or returns the gauge already registered under the name
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	if r == nil {
+		return nil
+	}
+	return &Gauge{registry: r, family: r.register(name, help, kindGauge, labels)}
+}
+
+// Counter registers a counter, or returns the counter already registered under the name
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	if r == nil {
+		return nil
+	}
+	return &Counter{registry: r, family: r.register(name, help, kindCounter, labels)}
+}
+
+// register returns the family of a metric, creating it on first use
+func (r *Registry) register(name, help, kind string, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.families[name]; ok {
+		return existing
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, series: make(map[string]*series)}
+	r.families[name] = f
+	return f
+}
+
+// update applies a change to the series of the label values, creating it at 0 when missing
+func (r *Registry) update(f *family, labelValues []string, change func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		f.series[key] = s
+	}
+	change(s)
+}
+
+// remove deletes the series of the label values
+func (r *Registry) remove(f *family, labelValues []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(f.series, strings.Join(labelValues, "\xff"))
+}
+
+// WriteTo writes every metric in the Prometheus text format, sorted by name and labels
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			b.WriteString(f.name)
+			if len(f.labels) > 0 {
+				b.WriteByte('{')
+				for i, label := range f.labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", label, escapeLabelValue(s.labelValues[i]))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			b.WriteByte('\n')
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics for Prometheus to scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// escapeHelp escapes a help text for the text format
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// escapeLabelValue escapes a label value for the text format
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// Gauge is a metric that can go up and down
+type Gauge struct {
+	registry *Registry
+	family   *family
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.registry.update(g.family, labelValues, func(s *series) { s.value = value })
+}
+
+// Add adds delta, which may be negative, to the gauge of the label values
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.registry.update(g.family, labelValues, func(s *series) { s.value += delta })
+}
+
+// Delete removes the gauge of the label values, e.g. once the task it describes has ended
+func (g *Gauge) Delete(labelValues ...string) {
+	if g == nil {
+		return
+	}
+	g.registry.remove(g.family, labelValues)
+}
+
+// Counter is a metric that only goes up
+type Counter struct {
+	registry *Registry
+	family   *family
+}
+
+// Inc adds one to the counter of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative delta to the counter of the label values
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if c == nil || delta < 0 {
+		return
+	}
+	c.registry.update(c.family, labelValues, func(s *series) { s.value += delta })
+}
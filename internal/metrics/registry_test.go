@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	registry := NewRegistry()
+	tasks := registry.Counter("asm_tasks_total", "Tasks processed", "task", "status")
+	running := registry.Gauge("asm_tasks_in_progress", "Tasks running", "task")
+
+	tasks.Inc("nuclei", "completed")
+	tasks.Add(2, "dns_resolve", "failed")
+	tasks.Add(-5, "dns_resolve", "failed")
+	running.Set(3, "nuclei")
+	running.Add(-1, "nuclei")
+	running.Set(1, `we"ird`)
+	running.Delete(`we"ird`)
+
+	var out strings.Builder
+	registry.WriteTo(&out)
+
+	expected := `# HELP asm_tasks_in_progress Tasks running
+# TYPE asm_tasks_in_progress gauge
+asm_tasks_in_progress{task="nuclei"} 2
+# HELP asm_tasks_total Tasks processed
+# TYPE asm_tasks_total counter
+asm_tasks_total{task="dns_resolve",status="failed"} 2
+asm_tasks_total{task="nuclei",status="completed"} 1
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", out.String())
+	}
+}
+
+func TestRegistry_NilIsNoop(t *testing.T) {
+	var registry *Registry
+	registry.Gauge("g", "help").Set(1)
+	registry.Counter("c", "help").Inc()
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	if got := escapeLabelValue("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("Unexpected escaping %s", got)
+	}
+}
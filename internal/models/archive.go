@@ -0,0 +1,450 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// archiveMonthLayout is the month an archive covers, in its blob name
+const archiveMonthLayout = "2006-01"
+
+// ResultArchivePrefix returns the blob prefix of a domain's result archives
+func ResultArchivePrefix(tenantID, domain string) string {
+	prefix := "archive/" + DomainBlobSegment(domain) + "/"
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// ResultArchiveBlobPath returns the blob path of a part of the archive of a domain's scans that
+// were last written in a month. Each compaction writes its scans to a new part named after the
+// first of them, e.g. "acme/archive/example.com/2026-03/12.ndjson.gz", so parts are never rewritten.
+func ResultArchiveBlobPath(tenantID, domain, month string, firstScanID int) string {
+	return ResultArchiveMonthPrefix(tenantID, domain, month) + strconv.Itoa(firstScanID) + ".ndjson.gz"
+}
+
+// ResultArchiveMonthPrefix returns the blob prefix of the archive parts of a domain's scans that
+// were last written in a month
+func ResultArchiveMonthPrefix(tenantID, domain, month string) string {
+	return ResultArchivePrefix(tenantID, domain) + month + "/"
+}
+
+// ArchiveMonth returns the month of a time as archives name it, in UTC
+func ArchiveMonth(t time.Time) string {
+	return t.UTC().Format(archiveMonthLayout)
+}
+
+// ResultArchiveMonth parses the month of an archive part's blob path, e.g. "2026-03"
+func ResultArchiveMonth(tenantID, domain, blobPath string) (string, bool) {
+	name, ok := strings.CutPrefix(blobPath, ResultArchivePrefix(tenantID, domain))
+	if !ok {
+		return "", false
+	}
+	month, part, ok := strings.Cut(name, "/")
+	if _, err := time.Parse(archiveMonthLayout, month); !ok || err != nil {
+		return "", false
+	}
+	id, ok := strings.CutSuffix(part, ".ndjson.gz")
+	if scanID, err := strconv.Atoi(id); !ok || err != nil || scanID <= 0 {
+		return "", false
+	}
+	return month, true
+}
+
+// ScanBlob parses a blob path below a domain's scans, e.g. "acme/example.com-12/httpx/latest.json",
+// into its scan ID and its path within the scan
+func ScanBlob(tenantID, domain, blobPath string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(blobPath, DomainScansPrefix(tenantID, domain))
+	if !ok {
+		return 0, "", false
+	}
+	id, name, ok := strings.Cut(rest, "/")
+	scanID, err := strconv.Atoi(id)
+	if !ok || err != nil || scanID <= 0 || strconv.Itoa(scanID) != id {
+		return 0, "", false
+	}
+	return scanID, name, true
+}
+
+// ArchivedScan is one line of a result archive: every blob a scan of a domain stored, and what
+// its results changed since the scan archived before it
+type ArchivedScan struct {
+	TenantID    string         `json:"tenant_id,omitempty"`
+	Domain      string         `json:"domain"`
+	ScanID      int            `json:"scan_id"`
+	CompletedAt time.Time      `json:"completed_at"` // Last write to any of the scan's blobs
+	ArchivedAt  time.Time      `json:"archived_at"`
+	Blobs       []ArchivedBlob `json:"blobs"`
+	// Changes holds, per task, the items of the task's latest result that were added or removed
+	// since the previous archived scan with a result of the task. Tasks without a previous result,
+	// and results stored as a summary or streamed, have no entry.
+	Changes map[Task]ResultChanges `json:"changes,omitempty"`
+}
+
+// ArchivedBlob is a blob of an archived scan. JSON content is kept as is, other content base64 encoded.
+type ArchivedBlob struct {
+	Path       string          `json:"path"`
+	ModifiedAt time.Time       `json:"modified_at"`
+	JSON       json.RawMessage `json:"json,omitempty"`
+	Data       []byte          `json:"data,omitempty"`
+}
+
+// ResultChanges lists the items a result added and removed compared to an earlier result of its
+// task: subdomains, resolved names, "ip:port"s, URLs, finding keys, apex domains or zone names
+type ResultChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// NewArchivedBlob returns the archived form of a blob's content
+func NewArchivedBlob(blobPath string, modifiedAt time.Time, content []byte) ArchivedBlob {
+	blob := ArchivedBlob{Path: blobPath, ModifiedAt: modifiedAt.UTC()}
+	if strings.HasSuffix(blobPath, ".json") && json.Valid(content) {
+		blob.JSON = content
+	} else {
+		blob.Data = content
+	}
+	return blob
+}
+
+// Content returns the content the blob had before it was archived. JSON comes back compacted.
+func (b ArchivedBlob) Content() []byte {
+	if b.JSON != nil {
+		return b.JSON
+	}
+	return b.Data
+}
+
+// Items returns the items of the latest result of each task of the scan, sorted. Tasks whose
+// result is stored as a summary or streams its records are left out, as their items are not
+// all in the result.
+func (s *ArchivedScan) Items() map[Task][]string {
+	items := make(map[Task][]string)
+	s.latestResults(func(task Task, blobPath string, content []byte) {
+		if resultItems, ok := StoredResultItems(task, blobPath, content); ok {
+			items[task] = resultItems
+		}
+	})
+	return items
+}
+
+// ExcludedTemplates returns the templates the scan's latest nuclei result left out
+func (s *ArchivedScan) ExcludedTemplates() []string {
+	var excluded []string
+	s.latestResults(func(task Task, _ string, content []byte) {
+		if task == TaskNuclei {
+			excluded = StoredExcludedTemplates(content)
+		}
+	})
+	return excluded
+}
+
+// latestResults calls fn with the latest result of each task of the scan that is in the scan
+func (s *ArchivedScan) latestResults(fn func(task Task, blobPath string, content []byte)) {
+	contents := make(map[string][]byte, len(s.Blobs))
+	for _, blob := range s.Blobs {
+		contents[blob.Path] = blob.Content()
+	}
+
+	for _, blob := range s.Blobs {
+		_, name, _ := ScanBlob(s.TenantID, s.Domain, blob.Path)
+		task, file, _ := strings.Cut(name, "/")
+		if file != "latest.json" {
+			continue
+		}
+		var latest LatestResult
+		if err := json.Unmarshal(blob.Content(), &latest); err != nil {
+			continue
+		}
+		if content, ok := contents[latest.BlobPath]; ok {
+			fn(Task(task), latest.BlobPath, content)
+		}
+	}
+}
+
+// Compare sets the changes of the scan's results since the items of earlier results, and returns
+// those items updated with the scan's own
+func (s *ArchivedScan) Compare(previous map[Task][]string) map[Task][]string {
+	var updated map[Task][]string
+	s.Changes, updated = CompareItems(previous, s.Items(), s.ExcludedTemplates())
+	return updated
+}
+
+// CompareItems returns the changes of the items of a scan's results since the items of earlier
+// results, and those items updated with the scan's own. Earlier findings of the nuclei templates
+// the scan excluded are neither removed nor dropped from the items, as the scan did not look for
+// them.
+func CompareItems(previous, current map[Task][]string, excludedTemplates []string) (map[Task]ResultChanges, map[Task][]string) {
+	updated := make(map[Task][]string, len(previous)+len(current))
+	for task, items := range previous {
+		updated[task] = items
+	}
+	var changes map[Task]ResultChanges
+	for task, items := range current {
+		if earlier, ok := previous[task]; ok {
+			if task == TaskNuclei && len(excludedTemplates) > 0 {
+				items = slices.Clone(items)
+				for _, item := range earlier {
+					if templateID, _, _ := strings.Cut(item, "|"); slices.Contains(excludedTemplates, templateID) {
+						items = append(items, item)
+					}
+				}
+				items = sortedItems(items)
+			}
+			if changes == nil {
+				changes = make(map[Task]ResultChanges)
+			}
+			changes[task] = ResultChanges{Added: DeltaTargets(items, earlier), Removed: DeltaTargets(earlier, items)}
+		}
+		updated[task] = items
+	}
+	return changes, updated
+}
+
+// StoredResultItems returns the sorted items of a stored task result: a subfinder text result or
+// a JSON task result. It reports false when the items are not all in the stored result.
+func StoredResultItems(task Task, blobPath string, content []byte) ([]string, bool) {
+	if strings.HasSuffix(blobPath, ".txt") {
+		var items []string
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return sortedItems(items), true
+	}
+
+	var stored storedResult
+	if err := json.Unmarshal(content, &stored); err != nil || stored.Summary != nil || len(stored.Data) == 0 {
+		return nil, false
+	}
+	var items []string
+	switch task {
+	case TaskSubfinder:
+		var data SubfinderResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		items = data.Subdomains
+	case TaskDNSResolve:
+		var data DNSXResult
+		if json.Unmarshal(stored.Data, &data) != nil || data.RecordsBlob != "" {
+			return nil, false
+		}
+		for name, info := range data.Records {
+			if info.Status == DNSStatusResolved {
+				items = append(items, name)
+			}
+		}
+	case TaskNaabu:
+		var data NaabuResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		for host, ports := range data.Ports {
+			for _, port := range ports {
+				items = append(items, net.JoinHostPort(host, strconv.Itoa(port.Port)))
+			}
+		}
+	case TaskHttpx:
+		var data HttpxResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		for _, host := range data.Results {
+			items = append(items, host.URL)
+		}
+	case TaskNuclei:
+		var data NucleiResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		for _, finding := range data.Vulnerabilities {
+			items = append(items, ReplayKey(finding))
+		}
+	case TaskScopeExpansion:
+		var data ScopeExpansionResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		for _, suggestion := range data.Suggestions {
+			items = append(items, suggestion.Apex)
+		}
+	case TaskCloudDNS:
+		var data CloudDNSResult
+		if json.Unmarshal(stored.Data, &data) != nil {
+			return nil, false
+		}
+		items = data.Names()
+	default:
+		return nil, false
+	}
+	return sortedItems(items), true
+}
+
+// StoredExcludedTemplates returns the templates a stored nuclei task result left out
+func StoredExcludedTemplates(content []byte) []string {
+	var stored storedResult
+	var data NucleiResult
+	if json.Unmarshal(content, &stored) != nil || len(stored.Data) == 0 || json.Unmarshal(stored.Data, &data) != nil {
+		return nil
+	}
+	return data.ExcludedTemplates()
+}
+
+// sortedItems sorts items and drops duplicates, returning an empty list rather than nil
+func sortedItems(items []string) []string {
+	items = append([]string{}, items...)
+	slices.Sort(items)
+	return slices.Compact(items)
+}
+
+// WriteResultArchive writes scans as one gzip member of NDJSON lines
+func WriteResultArchive(w io.Writer, scans []ArchivedScan) error {
+	archive := NewResultArchiveWriter(w)
+	for i := range scans {
+		if err := archive.StartScan(&scans[i]); err != nil {
+			return err
+		}
+		for _, blob := range scans[i].Blobs {
+			if err := archive.WriteBlob(blob); err != nil {
+				return err
+			}
+		}
+		if err := archive.EndScan(scans[i].Changes); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// ResultArchiveWriter writes scans to a result archive as one gzip member of NDJSON lines, one
+// blob at a time, so the blobs of a scan are never all held in memory
+type ResultArchiveWriter struct {
+	gz     *gzip.Writer
+	scanID int // Scan whose line is being written
+	blobs  int // Blobs written to the line so far
+}
+
+// archivedScanHeader holds the fields of an archived scan that precede its blobs on its line
+type archivedScanHeader struct {
+	TenantID    string    `json:"tenant_id,omitempty"`
+	Domain      string    `json:"domain"`
+	ScanID      int       `json:"scan_id"`
+	CompletedAt time.Time `json:"completed_at"`
+	ArchivedAt  time.Time `json:"archived_at"`
+}
+
+// NewResultArchiveWriter returns a writer of a gzip member of archived scans to w
+func NewResultArchiveWriter(w io.Writer) *ResultArchiveWriter {
+	return &ResultArchiveWriter{gz: gzip.NewWriter(w)}
+}
+
+// StartScan starts the line of a scan with its fields other than its blobs and changes, which
+// WriteBlob and EndScan add
+func (a *ResultArchiveWriter) StartScan(scan *ArchivedScan) error {
+	header, err := marshalArchived(archivedScanHeader{
+		TenantID:    scan.TenantID,
+		Domain:      scan.Domain,
+		ScanID:      scan.ScanID,
+		CompletedAt: scan.CompletedAt,
+		ArchivedAt:  scan.ArchivedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write archived scan %d: %w", scan.ScanID, err)
+	}
+	a.scanID, a.blobs = scan.ScanID, 0
+	// The header's closing brace makes way for the blobs
+	return a.write(header[:len(header)-1], []byte(`,"blobs":[`))
+}
+
+// WriteBlob adds a blob to the line of the scan being written
+func (a *ResultArchiveWriter) WriteBlob(blob ArchivedBlob) error {
+	content, err := marshalArchived(blob)
+	if err != nil {
+		return fmt.Errorf("failed to write blob %s of archived scan %d: %w", blob.Path, a.scanID, err)
+	}
+	if a.blobs++; a.blobs > 1 {
+		return a.write([]byte(","), content)
+	}
+	return a.write(content)
+}
+
+// EndScan ends the line of the scan being written with its changes
+func (a *ResultArchiveWriter) EndScan(changes map[Task]ResultChanges) error {
+	if len(changes) == 0 {
+		return a.write([]byte("]}\n"))
+	}
+	content, err := marshalArchived(changes)
+	if err != nil {
+		return fmt.Errorf("failed to write the changes of archived scan %d: %w", a.scanID, err)
+	}
+	return a.write([]byte(`],"changes":`), content, []byte("}\n"))
+}
+
+// Close ends the gzip member; it does not close the underlying writer
+func (a *ResultArchiveWriter) Close() error {
+	return a.gz.Close()
+}
+
+func (a *ResultArchiveWriter) write(parts ...[]byte) error {
+	for _, part := range parts {
+		if _, err := a.gz.Write(part); err != nil {
+			return fmt.Errorf("failed to write archived scan %d: %w", a.scanID, err)
+		}
+	}
+	return nil
+}
+
+// marshalArchived encodes a value of an archive line without escaping HTML
+func marshalArchived(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ReadResultArchive reads the scans of a result archive, in the order they were archived
+func ReadResultArchive(content []byte) ([]ArchivedScan, error) {
+	if len(content) == 0 {
+		return nil, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open result archive: %w", err)
+	}
+	defer gz.Close()
+
+	var scans []ArchivedScan
+	reader := bufio.NewReader(gz)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var scan ArchivedScan
+			if err := json.Unmarshal(line, &scan); err != nil {
+				return nil, fmt.Errorf("failed to parse line %d of result archive: %w", len(scans)+1, err)
+			}
+			scans = append(scans, scan)
+		}
+		if errors.Is(err, io.EOF) {
+			return scans, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read result archive: %w", err)
+		}
+	}
+}
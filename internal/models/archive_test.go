@@ -0,0 +1,140 @@
+package models
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScanBlob(t *testing.T) {
+	tests := []struct {
+		tenantID, blobPath string
+		scanID             int
+		name               string
+		ok                 bool
+	}{
+		{"acme", "acme/example.com-12/httpx/latest.json", 12, "httpx/latest.json", true},
+		{"", "example.com-3/nuclei/out/attempt-1.json", 3, "nuclei/out/attempt-1.json", true},
+		{"acme", "acme/example.com-staging.io-4/httpx/latest.json", 0, "", false},
+		{"acme", "acme/example.com-012/httpx/latest.json", 0, "", false},
+		{"acme", "acme/archive/example.com/2026-03.ndjson.gz", 0, "", false},
+		{"", "acme/example.com-12/httpx/latest.json", 0, "", false},
+	}
+	for _, tt := range tests {
+		scanID, name, ok := ScanBlob(tt.tenantID, "example.com", tt.blobPath)
+		if scanID != tt.scanID || name != tt.name || ok != tt.ok {
+			t.Errorf("ScanBlob(%q, %q) = %d, %q, %t, want %d, %q, %t", tt.tenantID, tt.blobPath, scanID, name, ok, tt.scanID, tt.name, tt.ok)
+		}
+	}
+
+	part := ResultArchiveBlobPath("acme", "example.com", "2026-03", 12)
+	if part != "acme/archive/example.com/2026-03/12.ndjson.gz" {
+		t.Errorf("ResultArchiveBlobPath() = %q", part)
+	}
+	if month, ok := ResultArchiveMonth("acme", "example.com", part); !ok || month != "2026-03" {
+		t.Errorf("ResultArchiveMonth() = %q, %t, want 2026-03", month, ok)
+	}
+	for _, name := range []string{"acme/archive/example.com/latest/12.ndjson.gz", "acme/archive/example.com/2026-03/part.ndjson.gz", "acme/archive/example.com/2026-03.ndjson.gz"} {
+		if _, ok := ResultArchiveMonth("acme", "example.com", name); ok {
+			t.Errorf("ResultArchiveMonth() accepted %q", name)
+		}
+	}
+}
+
+// archivedScan returns an archived scan with a subfinder text result and a nuclei JSON result
+func archivedScan(scanID int, subdomains string, nuclei string) ArchivedScan {
+	prefix := ScanBlobPrefix("acme", "example.com", scanID)
+	at := time.Date(2026, 3, scanID, 8, 0, 0, 0, time.UTC)
+	return ArchivedScan{
+		TenantID: "acme", Domain: "example.com", ScanID: scanID, CompletedAt: at,
+		Blobs: []ArchivedBlob{
+			NewArchivedBlob(prefix+"subfinder/latest.json", at, []byte(`{"blob_path":"`+prefix+`subfinder/out/attempt-1.txt","attempt":1}`)),
+			NewArchivedBlob(prefix+"subfinder/out/attempt-1.txt", at, []byte(subdomains)),
+			NewArchivedBlob(prefix+"nuclei/latest.json", at, []byte(`{"blob_path":"`+prefix+`nuclei/out/attempt-2.json","attempt":2}`)),
+			NewArchivedBlob(prefix+"nuclei/out/attempt-1.json", at, []byte(`{"task":"nuclei","status":"failed"}`)),
+			NewArchivedBlob(prefix+"nuclei/out/attempt-2.json", at, []byte(nuclei)),
+		},
+	}
+}
+
+func TestArchivedScanCompare(t *testing.T) {
+	first := archivedScan(1, "www.example.com\napi.example.com\n",
+		`{"task":"nuclei","status":"completed","data":{"domain":"example.com","output":[{"template_id":"git-config","host":"https://www.example.com"}]}}`)
+	second := archivedScan(2, "www.example.com\nmail.example.com",
+		`{"task":"nuclei","status":"completed","data":{"domain":"example.com"},"summary":{"count":3}}`)
+	third := archivedScan(3, "www.example.com\nmail.example.com",
+		`{"task":"nuclei","status":"completed","data":{"domain":"example.com","output":[]}}`)
+
+	items := first.Compare(nil)
+	if first.Changes != nil {
+		t.Errorf("Changes of the first scan = %v, want none", first.Changes)
+	}
+	if want := []string{"git-config|https://www.example.com|"}; !reflect.DeepEqual(items[TaskNuclei], want) {
+		t.Errorf("Nuclei items = %v, want %v", items[TaskNuclei], want)
+	}
+
+	// Summarized results are not compared, so the third scan is compared with the first's findings
+	items = second.Compare(items)
+	want := map[Task]ResultChanges{TaskSubfinder: {Added: []string{"mail.example.com"}, Removed: []string{"api.example.com"}}}
+	if !reflect.DeepEqual(second.Changes, want) {
+		t.Errorf("Changes of the second scan = %+v, want %+v", second.Changes, want)
+	}
+	third.Compare(items)
+	want = map[Task]ResultChanges{
+		TaskSubfinder: {Added: []string{}, Removed: []string{}},
+		TaskNuclei:    {Added: []string{}, Removed: []string{"git-config|https://www.example.com|"}},
+	}
+	if !reflect.DeepEqual(third.Changes, want) {
+		t.Errorf("Changes of the third scan = %+v, want %+v", third.Changes, want)
+	}
+
+	// A scan whose template policy excluded the template keeps its earlier findings
+	excluding := archivedScan(4, "www.example.com\nmail.example.com",
+		`{"task":"nuclei","status":"completed","data":{"domain":"example.com","output":[],"template_policy":{"policy":{"deny_ids":["git-config"]},"templates":1,"violations":[{"template_id":"git-config","rule":"deny_ids: git-config"}]}}}`)
+	kept := excluding.Compare(items)
+	if changes := excluding.Changes[TaskNuclei]; len(changes.Added) != 0 || len(changes.Removed) != 0 {
+		t.Errorf("Nuclei changes of a scan excluding the template = %+v, want none", changes)
+	}
+	if want := []string{"git-config|https://www.example.com|"}; !reflect.DeepEqual(kept[TaskNuclei], want) {
+		t.Errorf("Nuclei items after a scan excluding the template = %v, want %v", kept[TaskNuclei], want)
+	}
+}
+
+func TestResultArchiveRoundTrip(t *testing.T) {
+	first := archivedScan(1, "www.example.com", `{"task":"nuclei","status":"completed","data":{"domain":"example.com"}}`)
+	second := archivedScan(2, "www.example.com\nmail.example.com", `{"task":"nuclei","status":"completed","data":{"domain":"<example>"}}`)
+	second.Changes = map[Task]ResultChanges{TaskSubfinder: {Added: []string{"mail.example.com"}}}
+
+	// A later compaction appends a member to the archive
+	var archive bytes.Buffer
+	if err := WriteResultArchive(&archive, []ArchivedScan{first}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteResultArchive(&archive, []ArchivedScan{second}); err != nil {
+		t.Fatal(err)
+	}
+
+	scans, err := ReadResultArchive(archive.Bytes())
+	if err != nil {
+		t.Fatalf("ReadResultArchive() error = %v", err)
+	}
+	if len(scans) != 2 || scans[0].ScanID != 1 || scans[1].ScanID != 2 {
+		t.Fatalf("ReadResultArchive() = %+v, want both scans in order", scans)
+	}
+	if !reflect.DeepEqual(scans[1].Changes, second.Changes) {
+		t.Errorf("Changes = %+v, want %+v", scans[1].Changes, second.Changes)
+	}
+	for i, blob := range scans[1].Blobs {
+		if !bytes.Equal(blob.Content(), second.Blobs[i].Content()) || blob.Path != second.Blobs[i].Path {
+			t.Errorf("Blob %s = %s, want %s", blob.Path, blob.Content(), second.Blobs[i].Content())
+		}
+	}
+	if scans[1].Blobs[1].JSON != nil || scans[1].Blobs[4].Data != nil {
+		t.Error("Text blobs must be kept as data and JSON blobs as JSON")
+	}
+
+	if scans, err := ReadResultArchive(nil); err != nil || scans != nil {
+		t.Errorf("ReadResultArchive(nil) = %v, %v, want no scans", scans, err)
+	}
+}
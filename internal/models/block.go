@@ -0,0 +1,229 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Signals of a WAF or rate-limit ban: every response of a host group suddenly looks the same
+const (
+	BlockSignalForbidden   = "forbidden"        // HTTP 403
+	BlockSignalRateLimited = "rate_limited"     // HTTP 429
+	BlockSignalReset       = "connection_reset" // Connections reset by the peer
+)
+
+// Actions taken on a host group once it is blocked
+const (
+	BlockActionBackoff = "backoff" // Wait before every further request to the group
+	BlockActionAbort   = "abort"   // Skip the rest of the group
+)
+
+// BlockPolicy configures how scanners react when their responses shift to a ban mid-scan
+type BlockPolicy struct {
+	Window     int           // Consecutive blocked responses of a group that trip detection; 0 disables it
+	Action     string        // BlockActionBackoff or BlockActionAbort
+	Backoff    time.Duration // Wait before each request to a backed-off group
+	RetryAfter time.Duration // Wait before retrying a task that ran into a ban; 0 completes it instead
+}
+
+// Enabled reports whether scanners watch for bans
+func (p BlockPolicy) Enabled() bool {
+	return p.Window > 0
+}
+
+// BlockEvent records a host group whose responses shifted to a ban, stored in the stage's result
+type BlockEvent struct {
+	Group      string    `json:"group"`     // Registrable domain, or /24 (IPv4) or /64 (IPv6) network, of the blocked hosts
+	Signal     string    `json:"signal"`    // Kind of blocked responses
+	Responses  int       `json:"responses"` // Consecutive blocked responses that tripped detection
+	Answered   int       `json:"answered"`  // Regular responses the group gave before the shift
+	Action     string    `json:"action"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// String describes the event, e.g. "example.com switched to forbidden after 40 regular responses (25 in a row), backoff"
+func (e BlockEvent) String() string {
+	return fmt.Sprintf("%s switched to %s after %d regular responses (%d in a row), %s", e.Group, e.Signal, e.Answered, e.Responses, e.Action)
+}
+
+// BlockGroup returns the host group of a target: the registrable domain of a name, or the /24
+// (IPv4) or /64 (IPv6) network of an IP address. WAFs and rate limiters usually ban whole zones
+// or networks, so the hosts of a group are judged together.
+func BlockGroup(target string) string {
+	host := targetHost(target)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return ip4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+		}
+		return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	if apex, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return apex
+	}
+	return host
+}
+
+// BlockSignal classifies the outcome of a request: the ban signal it shows, "" for a regular
+// response, and ok false for failures that tell nothing about a ban, such as timeouts
+func BlockSignal(status int, err error) (signal string, ok bool) {
+	if err != nil {
+		if errors.Is(err, syscall.ECONNRESET) || strings.Contains(strings.ToLower(err.Error()), "connection reset") {
+			return BlockSignalReset, true
+		}
+		return "", false
+	}
+	switch status {
+	case 0:
+		return "", false
+	case 403:
+		return BlockSignalForbidden, true
+	case 429:
+		return BlockSignalRateLimited, true
+	}
+	return "", true
+}
+
+// BlockDetector watches the responses of a scan per host group. A group that answered regularly
+// and then gives Window blocked responses of one kind in a row is taken as banned; groups that
+// were blocked from the start are left alone, as that is how they always answer. A nil detector
+// watches nothing.
+type BlockDetector struct {
+	policy  BlockPolicy
+	onBlock func(BlockEvent)
+
+	mu     sync.Mutex
+	groups map[string]*blockGroup
+	events []BlockEvent
+}
+
+// blockGroup is the response history of a host group
+type blockGroup struct {
+	answered int    // Regular responses
+	signal   string // Signal of the current run of blocked responses
+	streak   int    // Length of the current run
+	action   string // Action taken once the group is blocked
+}
+
+// NewBlockDetector creates a detector for a scan, or returns nil when the policy is disabled.
+// onBlock, if set, is called once for every group found blocked.
+func NewBlockDetector(policy BlockPolicy, onBlock func(BlockEvent)) *BlockDetector {
+	if !policy.Enabled() {
+		return nil
+	}
+	if policy.Action == "" {
+		policy.Action = BlockActionBackoff
+	}
+	return &BlockDetector{policy: policy, onBlock: onBlock, groups: make(map[string]*blockGroup)}
+}
+
+// Observe records the outcome of a request to target, its HTTP status or the error it failed
+// with, and returns the action now taken on the target's group, "" while it is not blocked
+func (d *BlockDetector) Observe(target string, status int, err error) string {
+	if d == nil {
+		return ""
+	}
+	signal, ok := BlockSignal(status, err)
+	if !ok {
+		return d.Action(target)
+	}
+
+	name := BlockGroup(target)
+	d.mu.Lock()
+	group := d.groups[name]
+	if group == nil {
+		group = &blockGroup{}
+		d.groups[name] = group
+	}
+	if group.action != "" {
+		d.mu.Unlock()
+		return group.action
+	}
+
+	switch {
+	case signal == "":
+		group.answered++
+		group.signal, group.streak = "", 0
+	case signal != group.signal:
+		group.signal, group.streak = signal, 1
+	default:
+		group.streak++
+	}
+	if group.answered == 0 || group.streak < d.policy.Window {
+		d.mu.Unlock()
+		return ""
+	}
+
+	group.action = d.policy.Action
+	event := BlockEvent{
+		Group:      name,
+		Signal:     signal,
+		Responses:  group.streak,
+		Answered:   group.answered,
+		Action:     group.action,
+		DetectedAt: time.Now().UTC(),
+	}
+	d.events = append(d.events, event)
+	d.mu.Unlock()
+
+	if d.onBlock != nil {
+		d.onBlock(event)
+	}
+	return event.Action
+}
+
+// Action returns the action taken on the target's group, "" while it is not blocked
+func (d *BlockDetector) Action(target string) string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if group := d.groups[BlockGroup(target)]; group != nil {
+		return group.action
+	}
+	return ""
+}
+
+// Wait holds a request to target back while its group is backed off, and reports whether the
+// request should be skipped because its group was aborted
+func (d *BlockDetector) Wait(ctx context.Context, target string) (skip bool) {
+	switch d.Action(target) {
+	case BlockActionAbort:
+		return true
+	case BlockActionBackoff:
+		select {
+		case <-ctx.Done():
+		case <-time.After(d.policy.Backoff):
+		}
+	}
+	return false
+}
+
+// Aborted reports whether any of the events skipped the rest of its group, leaving the scan
+// short of targets it was asked to cover
+func Aborted(events []BlockEvent) bool {
+	for _, event := range events {
+		if event.Action == BlockActionAbort {
+			return true
+		}
+	}
+	return false
+}
+
+// Events returns the groups found blocked so far, in the order they were found
+func (d *BlockDetector) Events() []BlockEvent {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]BlockEvent(nil), d.events...)
+}
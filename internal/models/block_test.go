@@ -0,0 +1,122 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBlockGroup(t *testing.T) {
+	tests := map[string]string{
+		"https://api.example.com/login": "example.com",
+		"www.example.co.uk:8443":        "example.co.uk",
+		"203.0.113.7:443":               "203.0.113.0/24",
+		"[2001:db8::1]:443":             "2001:db8::/64",
+	}
+	for target, want := range tests {
+		if got := BlockGroup(target); got != want {
+			t.Errorf("BlockGroup(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestBlockSignal(t *testing.T) {
+	tests := []struct {
+		status int
+		err    error
+		signal string
+		ok     bool
+	}{
+		{200, nil, "", true},
+		{404, nil, "", true},
+		{403, nil, BlockSignalForbidden, true},
+		{429, nil, BlockSignalRateLimited, true},
+		{0, fmt.Errorf("read: %w", syscall.ECONNRESET), BlockSignalReset, true},
+		{0, errors.New("read tcp: connection reset by peer"), BlockSignalReset, true},
+		{0, errors.New("i/o timeout"), "", false},
+		{0, nil, "", false},
+	}
+	for _, tt := range tests {
+		if signal, ok := BlockSignal(tt.status, tt.err); signal != tt.signal || ok != tt.ok {
+			t.Errorf("BlockSignal(%d, %v) = %q, %v, want %q, %v", tt.status, tt.err, signal, ok, tt.signal, tt.ok)
+		}
+	}
+}
+
+func TestBlockDetector_Observe(t *testing.T) {
+	var alerts []BlockEvent
+	detector := NewBlockDetector(BlockPolicy{Window: 3, Action: BlockActionAbort}, func(event BlockEvent) {
+		alerts = append(alerts, event)
+	})
+
+	// A group blocked from the start is how it always answers, not a ban
+	for i := 0; i < 5; i++ {
+		if action := detector.Observe("admin.example.org", 403, nil); action != "" {
+			t.Fatalf("Observe() of a group blocked from the start = %q, want none", action)
+		}
+	}
+
+	detector.Observe("a.example.com", 200, nil)
+	detector.Observe("b.example.com", 403, nil)
+	detector.Observe("c.example.com", 429, nil) // A different signal starts a new run
+	detector.Observe("c.example.com", 0, errors.New("i/o timeout"))
+	detector.Observe("d.example.com", 429, nil)
+	if action := detector.Observe("e.example.com", 429, nil); action != BlockActionAbort {
+		t.Fatalf("Observe() after 3 rate-limited responses = %q, want abort", action)
+	}
+	if action := detector.Action("z.example.com"); action != BlockActionAbort {
+		t.Errorf("Action() of another host of the group = %q, want abort", action)
+	}
+	if action := detector.Observe("f.example.com", 200, nil); action != BlockActionAbort {
+		t.Errorf("Observe() after the ban = %q, want the group to stay aborted", action)
+	}
+
+	if len(alerts) != 1 {
+		t.Fatalf("onBlock called %d times, want once", len(alerts))
+	}
+	event := alerts[0]
+	if event.Group != "example.com" || event.Signal != BlockSignalRateLimited || event.Responses != 3 || event.Answered != 1 {
+		t.Errorf("event = %+v, want example.com rate limited after 1 regular response", event)
+	}
+	if events := detector.Events(); len(events) != 1 || events[0] != event {
+		t.Errorf("Events() = %+v, want the one event", events)
+	}
+}
+
+func TestBlockDetector_Wait(t *testing.T) {
+	detector := NewBlockDetector(BlockPolicy{Window: 1, Backoff: 20 * time.Millisecond}, nil)
+	detector.Observe("a.example.com", 200, nil)
+	if action := detector.Observe("a.example.com", 403, nil); action != BlockActionBackoff {
+		t.Fatalf("Observe() = %q, want the default backoff", action)
+	}
+
+	start := time.Now()
+	if detector.Wait(context.Background(), "b.example.com") {
+		t.Error("Wait() skipped a backed-off group")
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Wait() returned after %s, want the backoff", elapsed)
+	}
+	if detector.Wait(context.Background(), "example.net") {
+		t.Error("Wait() skipped a group that is not blocked")
+	}
+
+	disabled := NewBlockDetector(BlockPolicy{}, nil)
+	if disabled != nil || disabled.Observe("a.example.com", 403, nil) != "" || disabled.Events() != nil {
+		t.Error("a disabled detector watched responses")
+	}
+}
+
+func TestAborted(t *testing.T) {
+	backoff := BlockEvent{Group: "example.com", Action: BlockActionBackoff}
+	abort := BlockEvent{Group: "example.net", Action: BlockActionAbort}
+	if Aborted(nil) || Aborted([]BlockEvent{backoff}) {
+		t.Error("Aborted() = true without an aborted group")
+	}
+	if !Aborted([]BlockEvent{backoff, abort}) {
+		t.Error("Aborted() = false with an aborted group")
+	}
+}
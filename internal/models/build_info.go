@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+// BuildInfo identifies the worker build that produced a result, so results can be traced to the
+// binary and scanner library versions behind them
+type BuildInfo struct {
+	Version   string            `json:"version"`
+	Commit    string            `json:"commit,omitempty"`
+	BuildDate string            `json:"build_date,omitempty"`
+	GoVersion string            `json:"go_version,omitempty"`
+	Scanners  map[string]string `json:"scanners,omitempty"` // Library version per scanner, e.g. "nuclei": "v3.4.7"
+}
+
+// String describes the build, e.g. "v1.4.0 (commit 1a2b3c4, built 2025-01-01T10:00:00Z)"
+func (b BuildInfo) String() string {
+	commit, date := b.Commit, b.BuildDate
+	if commit == "" {
+		commit = "unknown"
+	}
+	if len(commit) > 7 {
+		commit = commit[:7]
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("%s (commit %s, built %s)", b.Version, commit, date)
+}
+
+// VersionManifest names the worker, scanner and template versions a deployment requires, so
+// stale workers can be told apart from current ones
+type VersionManifest struct {
+	MinimumVersion   string            `json:"minimum_version"`             // Oldest version allowed to produce results, e.g. "v1.4.0"
+	LatestVersion    string            `json:"latest_version,omitempty"`    // Newest released version
+	MinimumScanners  map[string]string `json:"minimum_scanners,omitempty"`  // Oldest library version per scanner, e.g. "nuclei": "v3.4.7"
+	MinimumTemplates string            `json:"minimum_templates,omitempty"` // Oldest nuclei templates release, e.g. "v10.1.0"
+	AllowUnversioned bool              `json:"allow_unversioned,omitempty"` // Lets versions that are not semantic versions, such as "dev", pass
+	Message          string            `json:"message,omitempty"`           // Sent with drift alerts, e.g. why the minimum was raised
+}
+
+// RunningVersions are the versions of the running worker a manifest is compared with
+type RunningVersions struct {
+	Worker    string
+	Scanners  map[string]string // Library version per scanner, as in BuildInfo
+	Templates string            // Nuclei templates release; empty when unknown
+}
+
+// VersionDrift compares the running worker version with a version manifest
+type VersionDrift struct {
+	Running   string    `json:"running"`
+	Minimum   string    `json:"minimum"`
+	Latest    string    `json:"latest,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Stale     bool      `json:"stale"`             // Older than a required minimum
+	Reasons   []string  `json:"reasons,omitempty"` // Every minimum the worker does not meet
+	Behind    bool      `json:"behind"`            // Older than the latest version
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Compare checks the running versions against the manifest. A version that is not a semantic
+// version, such as a "dev" build or templates of unknown release, cannot be shown to meet a
+// minimum, so it is stale unless the manifest allows unversioned builds.
+func (m VersionManifest) Compare(running RunningVersions, now time.Time) VersionDrift {
+	drift := VersionDrift{Running: running.Worker, Minimum: m.MinimumVersion, Latest: m.LatestVersion, Message: m.Message, CheckedAt: now}
+	drift.addReason(m.olderThan("worker", running.Worker, m.MinimumVersion))
+	for _, scanner := range slices.Sorted(maps.Keys(m.MinimumScanners)) {
+		drift.addReason(m.olderThan(scanner, running.Scanners[scanner], m.MinimumScanners[scanner]))
+	}
+	drift.addReason(m.olderThan("nuclei templates", running.Templates, m.MinimumTemplates))
+	drift.Stale = len(drift.Reasons) > 0
+	drift.Behind = semver.IsValid(running.Worker) && semver.IsValid(m.LatestVersion) && semver.Compare(running.Worker, m.LatestVersion) < 0
+	return drift
+}
+
+// olderThan describes how a version fails a minimum, or returns "" when it meets it
+func (m VersionManifest) olderThan(name, version, minimum string) string {
+	switch {
+	case minimum == "":
+		return ""
+	case !semver.IsValid(version):
+		if m.AllowUnversioned {
+			return ""
+		}
+		if version == "" {
+			version = "of unknown version"
+		}
+		return fmt.Sprintf("%s %s has no release version to compare with the required minimum %s", name, version, minimum)
+	case semver.IsValid(minimum) && semver.Compare(version, minimum) < 0:
+		return fmt.Sprintf("%s %s is older than the required minimum %s", name, version, minimum)
+	}
+	return ""
+}
+
+// addReason records a reason the worker is stale, skipping empty ones
+func (d *VersionDrift) addReason(reason string) {
+	if reason != "" {
+		d.Reasons = append(d.Reasons, reason)
+	}
+}
+
+// String describes the drift, e.g. "worker v1.3.0 is older than the required minimum v1.4.0"
+func (d VersionDrift) String() string {
+	switch {
+	case d.Stale:
+		return strings.Join(d.Reasons, "; ")
+	case d.Behind:
+		return fmt.Sprintf("worker %s is older than the latest version %s", d.Running, d.Latest)
+	}
+	return fmt.Sprintf("worker %s meets the required minimum %s", d.Running, d.Minimum)
+}
@@ -0,0 +1,80 @@
+package models
+
+import "sort"
+
+// Capabilities a worker can advertise; other labels, such as "gpu", are free-form
+const (
+	CapabilityNetRaw = "net_raw" // Raw sockets, needed for SYN scans
+)
+
+// synOnlyNaabuOptions are the port scan config keys that only apply to SYN scans. A port scan
+// setting one needs raw sockets; the others fall back to connect scans without them.
+var synOnlyNaabuOptions = []string{"source_port", "source_ip", "interface"}
+
+// taskCapabilities returns the capabilities a worker needs to run the task because of its type
+// and config
+func (t *TaskMessage) taskCapabilities() []string {
+	if t.Task != TaskNaabu {
+		return nil
+	}
+	for _, key := range synOnlyNaabuOptions {
+		if value, ok := t.Config[key]; ok && value != nil && value != "" && value != float64(0) {
+			return []string{CapabilityNetRaw}
+		}
+	}
+	return nil
+}
+
+// Application properties set on task messages for routing, e.g. by subscription filters
+const (
+	TaskProperty             = "task"
+	CapabilityPropertyPrefix = "requires_" // Followed by the capability, set to true
+	ModeProperty             = "mode"      // The task mode, e.g. "verify"; missing for regular tasks
+)
+
+// RequiredCapabilities returns the capabilities a worker needs for the task: those its type and
+// config need and those the message asks for, sorted and without duplicates
+func (t *TaskMessage) RequiredCapabilities() []string {
+	seen := make(map[string]bool)
+	var capabilities []string
+	for _, capability := range append(t.taskCapabilities(), t.Requires...) {
+		if !seen[capability] {
+			seen[capability] = true
+			capabilities = append(capabilities, capability)
+		}
+	}
+	sort.Strings(capabilities)
+	return capabilities
+}
+
+// RoutingProperties returns the application properties that route the task to workers able to
+// run it: its task type, a requires_<capability> property per required capability, and its mode,
+// so a subscription filter can send verify tasks to workers of their own
+func (t *TaskMessage) RoutingProperties() map[string]any {
+	properties := map[string]any{TaskProperty: string(t.Task)}
+	for _, capability := range t.RequiredCapabilities() {
+		properties[CapabilityPropertyPrefix+capability] = true
+	}
+	if t.Mode != "" {
+		properties[ModeProperty] = string(t.Mode)
+	}
+	return properties
+}
+
+// MissingCapabilities returns the required capabilities that are not among those advertised
+func MissingCapabilities(required, advertised []string) []string {
+	var missing []string
+	for _, capability := range required {
+		found := false
+		for _, a := range advertised {
+			if a == capability {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
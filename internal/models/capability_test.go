@@ -0,0 +1,39 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaskMessage_RoutingProperties(t *testing.T) {
+	taskMsg := &TaskMessage{Task: TaskNaabu, Requires: []string{"gpu", CapabilityNetRaw}}
+	if got := taskMsg.RequiredCapabilities(); !reflect.DeepEqual(got, []string{"gpu", CapabilityNetRaw}) {
+		t.Errorf("Expected gpu and net_raw once each, got %v", got)
+	}
+
+	expected := map[string]any{"task": "port_scan", "requires_gpu": true, "requires_net_raw": true}
+	if got := taskMsg.RoutingProperties(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// Tasks without requirements only carry their type; port scans fall back to connect scans
+	// unless they set an option only SYN scans have
+	for _, taskMsg := range []*TaskMessage{{Task: TaskSubfinder}, {Task: TaskNaabu, Config: map[string]interface{}{"source_port": float64(0)}}} {
+		if got := taskMsg.RoutingProperties(); len(got) != 1 || got["task"] != string(taskMsg.Task) {
+			t.Errorf("Expected only the task property, got %v", got)
+		}
+	}
+	synOnly := &TaskMessage{Task: TaskNaabu, Config: map[string]interface{}{"interface": "eth1"}}
+	if got := synOnly.RequiredCapabilities(); !reflect.DeepEqual(got, []string{CapabilityNetRaw}) {
+		t.Errorf("Expected a port scan on an interface to need net_raw, got %v", got)
+	}
+}
+
+func TestMissingCapabilities(t *testing.T) {
+	if missing := MissingCapabilities([]string{"gpu", CapabilityNetRaw}, []string{CapabilityNetRaw}); !reflect.DeepEqual(missing, []string{"gpu"}) {
+		t.Errorf("Expected gpu to be missing, got %v", missing)
+	}
+	if missing := MissingCapabilities(nil, nil); len(missing) != 0 {
+		t.Errorf("Expected nothing missing, got %v", missing)
+	}
+}
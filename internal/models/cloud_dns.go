@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Cloud DNS providers a cloud_dns task reads zones from
+const (
+	CloudDNSProviderRoute53 = "route53"
+	CloudDNSProviderAzure   = "azure"
+	CloudDNSProviderGoogle  = "google"
+)
+
+// CloudDNSSource tells a cloud_dns task where the zones of the tenant live. The worker reads them
+// with its own read-only credentials, from the accounts the tenant is bound to.
+type CloudDNSSource struct {
+	Provider       string `json:"provider"`
+	SubscriptionID string `json:"subscription_id,omitempty"` // Azure subscription holding the zones
+	ResourceGroup  string `json:"resource_group,omitempty"`  // Azure resource group holding the zones
+	Project        string `json:"project,omitempty"`         // Google Cloud project holding the zones
+}
+
+// Account returns the account a source reads zones of: "route53" for the worker's AWS account,
+// "azure:<subscription_id>" or "google:<project>"
+func (s CloudDNSSource) Account() string {
+	switch s.Provider {
+	case CloudDNSProviderAzure:
+		return s.Provider + ":" + strings.ToLower(s.SubscriptionID)
+	case CloudDNSProviderGoogle:
+		return s.Provider + ":" + s.Project
+	}
+	return s.Provider
+}
+
+// CloudDNSAccounts maps tenants to the accounts their cloud_dns tasks may read with the worker's
+// credentials, named as CloudDNSSource.Account names them
+type CloudDNSAccounts map[string][]string
+
+// ParseCloudDNSAccounts parses accounts in the form "tenant=account|account,tenant=account". The
+// tenant "*" sets the accounts of all other tenants and of tasks without one, and the account "*"
+// stands for every account.
+func ParseCloudDNSAccounts(spec string) (CloudDNSAccounts, error) {
+	accounts := make(CloudDNSAccounts)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, list, ok := strings.Cut(entry, "=")
+		tenant = strings.TrimSpace(tenant)
+		if !ok || tenant == "" {
+			return nil, fmt.Errorf("invalid cloud DNS accounts %q: expected tenant=account|account", entry)
+		}
+		for _, account := range strings.Split(list, "|") {
+			account = strings.TrimSpace(account)
+			provider, id, hasID := strings.Cut(account, ":")
+			switch {
+			case account == "*" || account == CloudDNSProviderRoute53:
+			case (provider == CloudDNSProviderAzure || provider == CloudDNSProviderGoogle) && hasID && id != "":
+				if provider == CloudDNSProviderAzure {
+					account = strings.ToLower(account)
+				}
+			default:
+				return nil, fmt.Errorf("invalid cloud DNS account %q of tenant %s: must be *, %s, %s:<subscription_id> or %s:<project>",
+					account, tenant, CloudDNSProviderRoute53, CloudDNSProviderAzure, CloudDNSProviderGoogle)
+			}
+			accounts[tenant] = append(accounts[tenant], account)
+		}
+	}
+	return accounts, nil
+}
+
+// Allows reports whether the cloud_dns tasks of a tenant may read zones from a source
+func (a CloudDNSAccounts) Allows(tenantID string, source CloudDNSSource) bool {
+	accounts, ok := a[tenantID]
+	if !ok || tenantID == "" {
+		accounts = a["*"]
+	}
+	return slices.Contains(accounts, "*") || slices.Contains(accounts, source.Account())
+}
+
+// CloudDNSInput represents input for the cloud DNS zone ingestion
+type CloudDNSInput struct {
+	Domain  string           `json:"domain"`
+	Sources []CloudDNSSource `json:"sources"`
+	Exclude TargetExclusions `json:"exclude,omitzero"` // Record names left out of the result
+}
+
+func (c CloudDNSInput) GetDomain() string {
+	return c.Domain
+}
+
+func (c CloudDNSInput) GetScannerName() string {
+	return "cloud_dns"
+}
+
+// CloudDNSRecord is a record set read from a cloud DNS zone
+type CloudDNSRecord struct {
+	Name     string   `json:"name"` // Lower-case FQDN without the trailing dot
+	Type     string   `json:"type"`
+	TTL      int      `json:"ttl,omitempty"`
+	Values   []string `json:"values"`
+	Provider string   `json:"provider"`
+	Zone     string   `json:"zone"`
+}
+
+// CloudDNSResult represents the records of the domain read from the tenant's cloud DNS zones
+type CloudDNSResult struct {
+	Domain  string           `json:"domain"`
+	Records []CloudDNSRecord `json:"output"`
+	Zones   []string         `json:"zones"` // Zones read, as "<provider>:<zone>"
+	Partial bool             `json:"partial,omitempty"`
+}
+
+func (r CloudDNSResult) GetCount() int {
+	return len(r.Records)
+}
+
+func (r CloudDNSResult) GetDomain() string {
+	return r.Domain
+}
+
+func (r CloudDNSResult) IsPartial() bool {
+	return r.Partial
+}
+
+// Names returns the sorted host names of the records. Wildcard records are left out, as no
+// enumeration can find the names they stand for.
+func (r CloudDNSResult) Names() []string {
+	seen := make(map[string]bool, len(r.Records))
+	var names []string
+	for _, record := range r.Records {
+		if record.Name == "" || strings.HasPrefix(record.Name, "*.") || seen[record.Name] {
+			continue
+		}
+		seen[record.Name] = true
+		names = append(names, record.Name)
+	}
+	sort.Strings(names)
+	return names
+}
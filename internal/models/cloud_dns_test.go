@@ -0,0 +1,43 @@
+package models
+
+import "testing"
+
+func TestParseCloudDNSAccounts(t *testing.T) {
+	accounts, err := ParseCloudDNSAccounts("acme=route53|azure:00000000-0000-0000-0000-00000000000A, *=google:shared-dns")
+	if err != nil {
+		t.Fatalf("ParseCloudDNSAccounts failed: %v", err)
+	}
+
+	route53 := CloudDNSSource{Provider: CloudDNSProviderRoute53}
+	azure := CloudDNSSource{Provider: CloudDNSProviderAzure, SubscriptionID: "00000000-0000-0000-0000-00000000000a", ResourceGroup: "dns"}
+	shared := CloudDNSSource{Provider: CloudDNSProviderGoogle, Project: "shared-dns"}
+	tests := []struct {
+		tenant string
+		source CloudDNSSource
+		want   bool
+	}{
+		{"acme", route53, true},
+		{"acme", azure, true},
+		{"acme", shared, false},
+		{"globex", route53, false},
+		{"globex", shared, true},
+		{"", shared, true},
+	}
+	for _, tt := range tests {
+		if got := accounts.Allows(tt.tenant, tt.source); got != tt.want {
+			t.Errorf("Allows(%q, %s) = %t, want %t", tt.tenant, tt.source.Account(), got, tt.want)
+		}
+	}
+	if (CloudDNSAccounts{}).Allows("acme", route53) {
+		t.Error("Allows() without accounts = true")
+	}
+	if all, _ := ParseCloudDNSAccounts("*=*"); !all.Allows("acme", shared) {
+		t.Error("Allows() with every account = false")
+	}
+
+	for _, spec := range []string{"route53", "acme=aws", "acme=azure:", "=route53"} {
+		if _, err := ParseCloudDNSAccounts(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
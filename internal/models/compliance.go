@@ -0,0 +1,208 @@
+package models
+
+import (
+	"bufio"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultComplianceUserAgent is the product token scans send in compliance mode and match robots.txt groups by
+const DefaultComplianceUserAgent = "allsafe-asm"
+
+// CompliancePolicy records the robots.txt and security.txt policies a scan honored in compliance mode
+type CompliancePolicy struct {
+	UserAgent string       `json:"user_agent"`
+	Hosts     []HostPolicy `json:"hosts"`
+	// Skipped lists the targets not scanned because robots.txt disallows them
+	Skipped []string `json:"skipped,omitempty"`
+	// ExcludedTemplates lists the templates not run because robots.txt of a target disallows a
+	// path they request
+	ExcludedTemplates []string `json:"excluded_templates,omitempty"`
+}
+
+// HostPolicy is what a host publishes about being scanned
+type HostPolicy struct {
+	Origin      string       `json:"origin"` // scheme://host[:port]
+	Robots      bool         `json:"robots"` // Whether the host serves a robots.txt
+	Allow       []string     `json:"allow,omitempty"`
+	Disallow    []string     `json:"disallow,omitempty"`
+	SecurityTxt *SecurityTxt `json:"security_txt,omitempty"`
+}
+
+// SecurityTxt holds the fields of a host's security.txt (RFC 9116) that matter when reporting findings
+type SecurityTxt struct {
+	Contact   []string `json:"contact,omitempty"`
+	Policy    []string `json:"policy,omitempty"`
+	Expires   string   `json:"expires,omitempty"`
+	Canonical []string `json:"canonical,omitempty"`
+}
+
+// ParseRobots returns the allow and disallow rules robots.txt (RFC 9309) gives a user agent: those
+// of the groups naming its product token, or of the "*" groups when none does
+func ParseRobots(content, userAgent string) (allow, disallow []string) {
+	token := strings.ToLower(userAgent)
+	if name, _, ok := strings.Cut(token, "/"); ok {
+		token = name
+	}
+
+	var ownAllow, ownDisallow, starAllow, starDisallow []string
+	var own, star, inRules, ownGroup bool
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules {
+				own, star, inRules = false, false, false
+			}
+			agent := strings.ToLower(value)
+			own = own || agent == token
+			star = star || agent == "*"
+			ownGroup = ownGroup || own
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			if own {
+				if key == "allow" {
+					ownAllow = append(ownAllow, value)
+				} else {
+					ownDisallow = append(ownDisallow, value)
+				}
+			}
+			if star {
+				if key == "allow" {
+					starAllow = append(starAllow, value)
+				} else {
+					starDisallow = append(starDisallow, value)
+				}
+			}
+		}
+	}
+	if ownGroup {
+		return ownAllow, ownDisallow
+	}
+	return starAllow, starDisallow
+}
+
+// Allowed reports whether robots.txt lets the user agent fetch the path of a URL. The longest
+// matching rule wins and allow wins a tie; paths no rule matches are allowed.
+func (p HostPolicy) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allowed, longest := true, -1
+	for _, rule := range p.Allow {
+		if len(rule) >= longest && robotsMatch(rule, path) {
+			allowed, longest = true, len(rule)
+		}
+	}
+	for _, rule := range p.Disallow {
+		if len(rule) > longest && robotsMatch(rule, path) {
+			allowed, longest = false, len(rule)
+		}
+	}
+	return allowed
+}
+
+// robotsMatch reports whether a robots.txt rule matches a path; "*" matches any characters and a
+// trailing "$" anchors the rule at the end of the path
+func robotsMatch(rule, path string) bool {
+	anchored := strings.HasSuffix(rule, "$")
+	parts := strings.Split(strings.TrimSuffix(rule, "$"), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	pattern := "^" + strings.Join(parts, ".*")
+	if anchored {
+		pattern += "$"
+	}
+	matched, err := regexp.MatchString(pattern, path)
+	return err == nil && matched
+}
+
+// ParseSecurityTxt reads the fields of a security.txt file; unknown fields and comments are skipped
+func ParseSecurityTxt(content string) SecurityTxt {
+	var txt SecurityTxt
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "contact":
+			txt.Contact = append(txt.Contact, value)
+		case "policy":
+			txt.Policy = append(txt.Policy, value)
+		case "expires":
+			txt.Expires = value
+		case "canonical":
+			txt.Canonical = append(txt.Canonical, value)
+		}
+	}
+	return txt
+}
+
+// IsZero reports whether the file had none of the fields
+func (t SecurityTxt) IsZero() bool {
+	return len(t.Contact) == 0 && len(t.Policy) == 0 && t.Expires == "" && len(t.Canonical) == 0
+}
+
+// Policy returns the policy of the URL's origin, or nil when none was fetched
+func (c *CompliancePolicy) Policy(rawURL string) *HostPolicy {
+	if c == nil {
+		return nil
+	}
+	origin, _ := URLOrigin(rawURL)
+	for i := range c.Hosts {
+		if c.Hosts[i].Origin == origin {
+			return &c.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// Review returns why a finding at a URL needs legal review: robots.txt of its origin disallows
+// its path. It returns "" for allowed paths and origins without a policy.
+func (c *CompliancePolicy) Review(rawURL string) string {
+	policy := c.Policy(rawURL)
+	if policy == nil {
+		return ""
+	}
+	_, path := URLOrigin(rawURL)
+	if policy.Allowed(path) {
+		return ""
+	}
+	return "path " + path + " is disallowed by " + policy.Origin + "/robots.txt"
+}
+
+// URLOrigin splits a URL into its origin, scheme://host[:port], and its path with query
+func URLOrigin(rawURL string) (origin, path string) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", ""
+	}
+	path = parsed.EscapedPath()
+	if parsed.RawQuery != "" {
+		path += "?" + parsed.RawQuery
+	}
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), path
+}
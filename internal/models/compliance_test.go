@@ -0,0 +1,92 @@
+package models
+
+import "testing"
+
+func TestParseRobots(t *testing.T) {
+	robots := `# robots.txt
+User-agent: *
+Disallow: /admin
+Allow: /admin/public
+
+User-agent: googlebot
+User-agent: allsafe-asm
+Disallow: /private # internal
+Disallow:
+
+User-agent: badbot
+Disallow: /
+`
+	allow, disallow := ParseRobots(robots, "allsafe-asm/1.0")
+	if len(allow) != 0 || len(disallow) != 1 || disallow[0] != "/private" {
+		t.Errorf("ParseRobots() for the own group = %v, %v, want only /private disallowed", allow, disallow)
+	}
+
+	allow, disallow = ParseRobots(robots, "other-scanner")
+	if len(allow) != 1 || allow[0] != "/admin/public" || len(disallow) != 1 || disallow[0] != "/admin" {
+		t.Errorf("ParseRobots() for the * group = %v, %v", allow, disallow)
+	}
+
+	// An own group that allows everything overrides the * group
+	allow, disallow = ParseRobots("User-agent: *\nDisallow: /\n\nUser-agent: allsafe-asm\nDisallow:\n", "allsafe-asm")
+	if len(allow) != 0 || len(disallow) != 0 {
+		t.Errorf("ParseRobots() with an empty own group = %v, %v, want no rules", allow, disallow)
+	}
+}
+
+func TestHostPolicy_Allowed(t *testing.T) {
+	policy := HostPolicy{
+		Allow:    []string{"/admin/public", "/*.css$"},
+		Disallow: []string{"/admin", "/*.php", "/search?"},
+	}
+	tests := map[string]bool{
+		"/":                   true,
+		"/admin":              false,
+		"/admin/users":        false,
+		"/admin/public/index": true,
+		"/wp-login.php":       false,
+		"/index.php?page=1":   false,
+		"/style.css":          true,
+		"/style.css?v=2":      true,
+		"/search?q=x":         false,
+		"/search":             true,
+	}
+	for path, want := range tests {
+		if got := policy.Allowed(path); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestParseSecurityTxt(t *testing.T) {
+	txt := ParseSecurityTxt(`# Our security policy
+Contact: mailto:security@example.com
+Contact: https://example.com/report
+Expires: 2027-01-01T00:00:00.000Z
+Policy: https://example.com/disclosure
+Hiring: https://example.com/jobs
+`)
+	if len(txt.Contact) != 2 || txt.Contact[0] != "mailto:security@example.com" || txt.Expires != "2027-01-01T00:00:00.000Z" || len(txt.Policy) != 1 {
+		t.Errorf("ParseSecurityTxt() = %+v", txt)
+	}
+	if !ParseSecurityTxt("<html>not found</html>").IsZero() {
+		t.Error("ParseSecurityTxt() of an HTML page found fields")
+	}
+}
+
+func TestCompliancePolicy_Review(t *testing.T) {
+	compliance := &CompliancePolicy{Hosts: []HostPolicy{{Origin: "https://example.com", Robots: true, Disallow: []string{"/admin"}}}}
+
+	if review := compliance.Review("https://EXAMPLE.com/admin/login?next=/"); review != "path /admin/login?next=/ is disallowed by https://example.com/robots.txt" {
+		t.Errorf("Review() of a disallowed path = %q", review)
+	}
+	if review := compliance.Review("https://example.com/login"); review != "" {
+		t.Errorf("Review() of an allowed path = %q, want none", review)
+	}
+	if review := compliance.Review("http://example.com/admin"); review != "" {
+		t.Errorf("Review() of an origin without a policy = %q, want none", review)
+	}
+	var off *CompliancePolicy
+	if review := off.Review("https://example.com/admin"); review != "" {
+		t.Errorf("Review() outside compliance mode = %q, want none", review)
+	}
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// correlationIDKey is the context key of the correlation ID
+type correlationIDKey struct{}
+
+// NewCorrelationID returns a random correlation ID of 32 hex characters, so it doubles as a W3C trace ID
+func NewCorrelationID() string {
+	return randomHex(16)
+}
+
+// WithCorrelationID returns a context carrying the correlation ID
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, correlationID)
+}
+
+// CorrelationIDFromContext returns the correlation ID of the context, or ""
+func CorrelationIDFromContext(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey{}).(string)
+	return correlationID
+}
+
+// Traceparent returns a W3C traceparent header for a request made on behalf of the correlation ID.
+// A correlation ID that is a valid trace ID is used as is; any other ID is hashed into one.
+// Every call returns a new parent span ID.
+func Traceparent(correlationID string) string {
+	traceID := strings.ToLower(correlationID)
+	if !isTraceID(traceID) {
+		sum := sha256.Sum256([]byte(correlationID))
+		traceID = hex.EncodeToString(sum[:16])
+	}
+	return fmt.Sprintf("00-%s-%s-01", traceID, randomHex(8))
+}
+
+// TraceIDFromTraceparent returns the trace ID of a W3C traceparent header, or "" when it is malformed
+func TraceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || !isTraceID(parts[1]) {
+		return ""
+	}
+	return parts[1]
+}
+
+// isTraceID reports whether id is 32 lowercase hex characters that are not all zero
+func isTraceID(id string) bool {
+	if len(id) != 32 || strings.Trim(id, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil && id == strings.ToLower(id)
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
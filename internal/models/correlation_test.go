@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTraceparent(t *testing.T) {
+	id := NewCorrelationID()
+	if !isTraceID(id) {
+		t.Fatalf("Expected a generated correlation ID to be a trace ID, got %s", id)
+	}
+
+	traceparent := Traceparent(id)
+	if TraceIDFromTraceparent(traceparent) != id || !strings.HasSuffix(traceparent, "-01") {
+		t.Errorf("Expected the correlation ID as trace ID, got %s", traceparent)
+	}
+	if Traceparent(id) == traceparent {
+		t.Error("Expected a new span ID per traceparent")
+	}
+
+	hashed := TraceIDFromTraceparent(Traceparent("orchestrator-run-42"))
+	if !isTraceID(hashed) || hashed != TraceIDFromTraceparent(Traceparent("orchestrator-run-42")) {
+		t.Errorf("Expected other IDs hashed into a stable trace ID, got %s", hashed)
+	}
+
+	for _, header := range []string{"", "00-abc-def-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"} {
+		if TraceIDFromTraceparent(header) != "" {
+			t.Errorf("Expected %q to be rejected", header)
+		}
+	}
+
+	ctx := WithCorrelationID(context.Background(), id)
+	if CorrelationIDFromContext(ctx) != id || CorrelationIDFromContext(context.Background()) != "" {
+		t.Error("Expected the correlation ID to be carried by the context")
+	}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DigestTasks are the scan stages summarized in a scan digest, in scan order
+var DigestTasks = []Task{TaskSubfinder, TaskDNSResolve, TaskNaabu, TaskHttpx, TaskNuclei}
+
+// ScanDigest summarizes the stored results of a whole scan
+type ScanDigest struct {
+	TenantID string
+	Domain   string
+	ScanID   int
+
+	Subdomains      int
+	Resolved        int
+	OpenPorts       int
+	HTTPServices    int
+	Vulnerabilities map[string]int // Findings per lower-case severity
+	// Duration is the wall-clock time from the start of the earliest summarized stage to the end
+	// of the latest; stages that ran concurrently are not counted twice
+	Duration time.Duration
+
+	Tasks   []Task // Stages whose results were summarized
+	Missing []Task // Stages without a stored result
+	Partial bool   // A summarized result was cut short
+
+	start, end time.Time // Span of the summarized stages
+}
+
+// NewScanDigest creates an empty digest of a scan
+func NewScanDigest(tenantID, domain string, scanID int) *ScanDigest {
+	return &ScanDigest{TenantID: tenantID, Domain: domain, ScanID: scanID, Vulnerabilities: make(map[string]int)}
+}
+
+// storedResult is a stored task result with its data left undecoded until the task is known
+type storedResult struct {
+	Task    Task            `json:"task"`
+	Status  TaskStatus      `json:"status"`
+	Data    json.RawMessage `json:"data"`
+	Summary *ResultSummary  `json:"summary"`
+}
+
+// AddSubdomains counts the subdomains of a stored subfinder text result, one per line
+func (d *ScanDigest) AddSubdomains(content []byte) {
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			d.Subdomains++
+		}
+	}
+	d.Tasks = append(d.Tasks, TaskSubfinder)
+}
+
+// AddResult counts the entries of a stored JSON task result. Results stored as a summary are
+// counted from the summary, and streamed DNSX results from their metadata.
+func (d *ScanDigest) AddResult(content []byte) error {
+	var stored storedResult
+	if err := json.Unmarshal(content, &stored); err != nil {
+		return fmt.Errorf("failed to parse stored result: %w", err)
+	}
+	if stored.Status == TaskStatusPartial {
+		d.Partial = true
+	}
+
+	switch stored.Task {
+	case TaskDNSResolve:
+		var data DNSXResult
+		if err := json.Unmarshal(stored.Data, &data); err != nil {
+			return fmt.Errorf("failed to parse stored dnsx result: %w", err)
+		}
+		if data.Metadata != nil {
+			d.Resolved += data.Metadata.StatusCounts[DNSStatusResolved]
+		} else {
+			for _, info := range data.Records {
+				if info.Status == DNSStatusResolved {
+					d.Resolved++
+				}
+			}
+		}
+	case TaskNaabu:
+		if stored.Summary != nil {
+			d.OpenPorts += stored.Summary.Count
+			break
+		}
+		var data NaabuResult
+		if err := json.Unmarshal(stored.Data, &data); err != nil {
+			return fmt.Errorf("failed to parse stored naabu result: %w", err)
+		}
+		d.OpenPorts += data.GetCount()
+	case TaskHttpx:
+		if stored.Summary != nil {
+			d.HTTPServices += stored.Summary.Count
+			break
+		}
+		var data HttpxResult
+		if err := json.Unmarshal(stored.Data, &data); err != nil {
+			return fmt.Errorf("failed to parse stored httpx result: %w", err)
+		}
+		d.HTTPServices += data.GetCount()
+	case TaskNuclei:
+		if stored.Summary != nil {
+			for severity, count := range stored.Summary.Counts {
+				d.Vulnerabilities[severity] += count
+			}
+			break
+		}
+		var data NucleiResult
+		if err := json.Unmarshal(stored.Data, &data); err != nil {
+			return fmt.Errorf("failed to parse stored nuclei result: %w", err)
+		}
+		_, counts := ResultCounts(data)
+		for severity, count := range counts {
+			d.Vulnerabilities[severity] += count
+		}
+	default:
+		return fmt.Errorf("task %s is not summarized in scan digests", stored.Task)
+	}
+
+	d.Tasks = append(d.Tasks, stored.Task)
+	return nil
+}
+
+// AddSpan extends the digest's duration by a stage that finished at finishedAt, an RFC3339 time,
+// after running for duration. Stages without a valid time or duration are left out.
+func (d *ScanDigest) AddSpan(finishedAt, duration string) {
+	end, err := time.Parse(time.RFC3339, finishedAt)
+	if err != nil {
+		return
+	}
+	elapsed, err := time.ParseDuration(duration)
+	if err != nil {
+		return
+	}
+	start := end.Add(-elapsed)
+	if d.start.IsZero() || start.Before(d.start) {
+		d.start = start
+	}
+	if end.After(d.end) {
+		d.end = end
+	}
+	d.Duration = d.end.Sub(d.start)
+}
+
+// VulnerabilityCount returns the number of findings of all severities
+func (d *ScanDigest) VulnerabilityCount() int {
+	total := 0
+	for _, count := range d.Vulnerabilities {
+		total += count
+	}
+	return total
+}
+
+// VulnerabilitySummary lists the findings per severity from most to least severe, e.g.
+// "critical: 1, high: 3"
+func (d *ScanDigest) VulnerabilitySummary() string {
+	severities := sortedMapKeys(d.Vulnerabilities)
+	ordered := make([]string, 0, len(severities))
+	for rank := 5; rank >= 0; rank-- {
+		for _, severity := range severities {
+			if summarySeverityOrder[severity] == rank && d.Vulnerabilities[severity] > 0 {
+				ordered = append(ordered, fmt.Sprintf("%s: %d", severity, d.Vulnerabilities[severity]))
+			}
+		}
+	}
+	if len(ordered) == 0 {
+		return "none"
+	}
+	return strings.Join(ordered, ", ")
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestScanDigest(t *testing.T) {
+	digest := NewScanDigest("", "example.com", 7)
+	digest.AddSubdomains([]byte("a.example.com\nb.example.com\n\n"))
+
+	results := []*TaskResult{
+		{Task: TaskDNSResolve, Status: TaskStatusCompleted, Duration: "30s", Data: DNSXResult{Records: map[string]ResolutionInfo{
+			"a.example.com": {Status: DNSStatusResolved},
+			"b.example.com": {Status: DNSStatusNXDomain},
+		}}},
+		{Task: TaskNaabu, Status: TaskStatusPartial, Duration: "2m", Data: NaabuResult{Ports: map[string][]PortInfo{
+			"192.0.2.1": {{Port: 80}, {Port: 443}},
+		}}},
+		// Summarized results are counted from their summary
+		SummarizeResult(&TaskResult{Task: TaskNuclei, Status: TaskStatusCompleted, Duration: "10m", Data: NucleiResult{Vulnerabilities: []NucleiVulnerability{
+			{TemplateID: "a", Severity: "low"},
+			{TemplateID: "b", Severity: "critical"},
+			{TemplateID: "c", Severity: "low"},
+		}}}, 4096, 1),
+	}
+	for _, result := range results {
+		content, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := digest.AddResult(content); err != nil {
+			t.Fatalf("AddResult failed for %s: %v", result.Task, err)
+		}
+	}
+
+	if digest.Subdomains != 2 || digest.Resolved != 1 || digest.OpenPorts != 2 || digest.HTTPServices != 0 {
+		t.Errorf("Unexpected counts %+v", digest)
+	}
+	if got := digest.VulnerabilitySummary(); got != "critical: 1, low: 2" {
+		t.Errorf("Unexpected vulnerability summary %q", got)
+	}
+	if len(digest.Tasks) != 4 || !digest.Partial {
+		t.Errorf("Unexpected tasks %v or partial %t", digest.Tasks, digest.Partial)
+	}
+
+	// Stages running at the same time count once: subfinder and DNSX overlap here
+	digest.AddSpan("2026-01-01T10:01:00Z", "1m")
+	digest.AddSpan("2026-01-01T10:01:30Z", "1m")
+	digest.AddSpan("2026-01-01T10:12:00Z", "10m")
+	digest.AddSpan("not a time", "1h")
+	if digest.Duration != 12*time.Minute {
+		t.Errorf("Expected 12m of wall-clock time, got %s", digest.Duration)
+	}
+
+	if err := digest.AddResult([]byte(`{"task":"scope_expansion","data":{}}`)); err == nil {
+		t.Error("Expected tasks outside the digest to be rejected")
+	}
+	if got := NewScanDigest("", "example.com", 1).VulnerabilitySummary(); got != "none" {
+		t.Errorf("Expected no findings to read none, got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// EventResultAvailable is the type of the event announcing a stored task result
+const EventResultAvailable = "result_available"
+
+// ResultEvent announces a stored task result so consumers can fetch it without polling blob storage
+type ResultEvent struct {
+	EventType string         `json:"event_type"`
+	ScanID    int            `json:"scan_id"`
+	Task      Task           `json:"task"`
+	Domain    string         `json:"domain"`
+	TenantID  string         `json:"tenant_id,omitempty"`
+	Status    TaskStatus     `json:"status"`
+	BlobPath  string         `json:"blob_path"`
+	Count     int            `json:"count"`
+	Counts    map[string]int `json:"counts,omitempty"` // Breakdown of the count, e.g. findings per severity
+	Duration  string         `json:"duration,omitempty"`
+	Timestamp string         `json:"timestamp"`
+	// CorrelationID is the correlation ID of the task that produced the result
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// NewResultEvent creates the event announcing a result stored at blobPath
+func NewResultEvent(result *TaskResult, blobPath string) ResultEvent {
+	event := ResultEvent{
+		EventType: EventResultAvailable,
+		ScanID:    result.ScanID,
+		Task:      result.Task,
+		Domain:    result.Domain,
+		TenantID:  result.TenantID,
+		Status:    result.Status,
+		BlobPath:  blobPath,
+		Duration:  result.Duration,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+
+		CorrelationID: result.CorrelationID,
+	}
+
+	event.Count, event.Counts = ResultCounts(result.Data)
+
+	return event
+}
+
+// Subject returns the event subject used for routing, e.g. "scans/42/nuclei"
+func (e ResultEvent) Subject() string {
+	return fmt.Sprintf("scans/%d/%s", e.ScanID, e.Task)
+}
+
+// ID returns the deterministic ID of the event: the hex SHA-256 of its type, tenant, domain,
+// scan, task and status. It leaves out the blob path, which names the attempt, so every
+// attempt of a task announcing the same outcome gets the same 64-character ID.
+func (e ResultEvent) ID() string {
+	sum := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%s\x00%d\x00%s\x00%s",
+		e.EventType, e.TenantID, e.Domain, e.ScanID, e.Task, e.Status))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestNewResultEvent(t *testing.T) {
+	result := &TaskResult{
+		Task:     TaskNuclei,
+		ScanID:   12,
+		Domain:   "example.com",
+		TenantID: "acme",
+		Status:   TaskStatusCompleted,
+		Duration: "3m0s",
+		Data: NucleiResult{Vulnerabilities: []NucleiVulnerability{
+			{TemplateID: "a", Severity: "High"},
+			{TemplateID: "b", Severity: "high"},
+			{TemplateID: "c"},
+		}},
+	}
+
+	event := NewResultEvent(result, "acme/example.com-12/nuclei/out/x.json")
+
+	if event.EventType != EventResultAvailable || event.BlobPath != "acme/example.com-12/nuclei/out/x.json" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Count != 3 || event.Counts["high"] != 2 || event.Counts["unknown"] != 1 {
+		t.Errorf("Expected findings counted per severity, got count %d and %v", event.Count, event.Counts)
+	}
+	if event.Subject() != "scans/12/nuclei" {
+		t.Errorf("Unexpected subject %s", event.Subject())
+	}
+
+	retried := NewResultEvent(result, "acme/example.com-12/nuclei/out/attempt-2.json")
+	if event.ID() != retried.ID() || len(event.ID()) != 64 {
+		t.Errorf("Expected a 64-character ID shared by the attempts, got %s and %s", event.ID(), retried.ID())
+	}
+	retried.Status = TaskStatusFailed
+	if event.ID() == retried.ID() {
+		t.Error("Expected the ID to change with the status")
+	}
+
+	naabu := NewResultEvent(&TaskResult{Task: TaskNaabu, Data: NaabuResult{Ports: map[string][]PortInfo{
+		"192.0.2.1": {{Port: 22}, {Port: 443}},
+		"192.0.2.2": {{Port: 80}},
+	}}}, "path")
+	if naabu.Count != 3 || naabu.Counts["hosts"] != 2 || naabu.Counts["ports"] != 3 {
+		t.Errorf("Expected host and port counts, got %d and %v", naabu.Count, naabu.Counts)
+	}
+}
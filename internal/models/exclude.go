@@ -0,0 +1,330 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ExcludeRegexPrefix marks an exclusion entry as a regular expression on host names
+const ExcludeRegexPrefix = "re:"
+
+// TargetExclusions lists targets a scanner skips while collecting its input, so known-fragile
+// systems can be left out without editing the hosts files of earlier stages. An entry is a host
+// name, "*.example.com" for the subdomains of a domain, an IP address, a CIDR, or a regular
+// expression on host names prefixed with "re:". Scanners that contact targets match IP and CIDR
+// entries against the addresses of host names too, and host name entries against addresses.
+type TargetExclusions struct {
+	Targets  []string `json:"targets,omitempty"`   // Inline entries, merged with the blob's when both are set
+	BlobPath string   `json:"blob_path,omitempty"` // Blob with more entries, one per line or a JSON array
+}
+
+// IsZero reports whether nothing is excluded
+func (e TargetExclusions) IsZero() bool {
+	return len(e.Targets) == 0 && e.BlobPath == ""
+}
+
+// Excluder compiles the inline entries; the entries of the blob are merged into them when the
+// task is received, before the scanner runs
+func (e TargetExclusions) Excluder() (*Excluder, error) {
+	return NewExcluder(e.Targets)
+}
+
+// ParseExclusionList parses the entries of an exclusion blob: a JSON array of strings, or one
+// entry per line with blank lines and '#' comments skipped
+func ParseExclusionList(content []byte) ([]string, error) {
+	trimmed := strings.TrimSpace(string(content))
+	if strings.HasPrefix(trimmed, "[") {
+		var entries []string
+		if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+			return nil, fmt.Errorf("invalid exclusion list: %w", err)
+		}
+		return entries, nil
+	}
+
+	var entries []string
+	for _, line := range strings.Split(trimmed, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, nil
+}
+
+// Excluder matches targets against compiled exclusion entries. A nil Excluder excludes nothing.
+type Excluder struct {
+	hosts    map[string]struct{}
+	suffixes []string // ".example.com" for "*.example.com"
+	prefixes []netip.Prefix
+	patterns []*regexp.Regexp
+}
+
+// NewExcluder compiles exclusion entries, rejecting those that are none of the supported forms.
+// It returns nil when there are no entries.
+func NewExcluder(entries []string) (*Excluder, error) {
+	excluder := &Excluder{hosts: make(map[string]struct{})}
+	count := 0
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		count++
+
+		if pattern, ok := strings.CutPrefix(entry, ExcludeRegexPrefix); ok {
+			compiled, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclusion %q: %w", entry, err)
+			}
+			excluder.patterns = append(excluder.patterns, compiled)
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid exclusion %q: not a CIDR", entry)
+			}
+			excluder.prefixes = append(excluder.prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(entry, "[]")); err == nil {
+			excluder.prefixes = append(excluder.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		name := strings.TrimSuffix(strings.ToLower(entry), ".")
+		if domain, ok := strings.CutPrefix(name, "*."); ok {
+			name = domain
+			if !isExclusionName(name) {
+				return nil, fmt.Errorf("invalid exclusion %q: not a host name", entry)
+			}
+			excluder.suffixes = append(excluder.suffixes, "."+name)
+			continue
+		}
+		if !isExclusionName(name) {
+			return nil, fmt.Errorf("invalid exclusion %q: not a host name, IP, CIDR or %s pattern", entry, ExcludeRegexPrefix)
+		}
+		excluder.hosts[name] = struct{}{}
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	return excluder, nil
+}
+
+// Excludes reports whether a target is excluded. The target is a host name, an IP address, a
+// host:port or a URL; IP entries and CIDRs match addresses, the other entries match names.
+func (e *Excluder) Excludes(target string) bool {
+	if e == nil {
+		return false
+	}
+
+	host := targetHost(target)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range e.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if _, ok := e.hosts[host]; ok {
+		return true
+	}
+	for _, suffix := range e.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	for _, pattern := range e.patterns {
+		if pattern.MatchString(host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the targets that are not excluded, keeping their order
+func (e *Excluder) Filter(targets []string) (kept []string, excluded int) {
+	if e == nil {
+		return targets, 0
+	}
+	kept = make([]string, 0, len(targets))
+	for _, target := range targets {
+		if e.Excludes(target) {
+			excluded++
+		} else {
+			kept = append(kept, target)
+		}
+	}
+	return kept, excluded
+}
+
+// AddrLookup returns the addresses of a host name. It returns an error wrapping ErrHostNotFound
+// when the name does not resolve.
+type AddrLookup func(ctx context.Context, host string) ([]netip.Addr, error)
+
+// ErrHostNotFound is returned by an AddrLookup for a name that does not resolve
+var ErrHostNotFound = errors.New("host not found")
+
+// ErrExclusionLookup is returned by FilterResolved when a host name entry could not be looked up
+var ErrExclusionLookup = errors.New("cannot enforce host name exclusions on IP targets")
+
+// exclusionLookups bounds the names FilterResolved looks up at once
+const exclusionLookups = 32
+
+// FilterResolved is Filter for scanners that contact their targets, which may be host names or
+// addresses whatever the entries are. Host names are also excluded when one of their addresses is
+// in an IP or CIDR entry, and addresses when a host name entry resolves to them. A host name whose
+// lookup fails for another reason than not resolving is excluded, as it may still reach an
+// excluded address. Wildcard and pattern entries cannot be matched against addresses, so they are
+// rejected when any target is an address.
+func (e *Excluder) FilterResolved(ctx context.Context, targets []string, lookup AddrLookup) (kept []string, excluded int, err error) {
+	if e == nil {
+		return targets, 0, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool, len(targets))
+	hasAddrs := false
+	for _, target := range targets {
+		host := targetHost(target)
+		if _, err := netip.ParseAddr(host); err == nil {
+			hasAddrs = true
+		} else if !seen[host] {
+			seen[host] = true
+			names = append(names, host)
+		}
+	}
+
+	resolved := *e
+	if hasAddrs {
+		if len(e.suffixes) > 0 || len(e.patterns) > 0 {
+			return nil, 0, fmt.Errorf("wildcard and %s exclusions match host names only and cannot be enforced on IP targets", ExcludeRegexPrefix)
+		}
+		hosts := make([]string, 0, len(e.hosts))
+		for host := range e.hosts {
+			hosts = append(hosts, host)
+		}
+		addrs, err := lookupAll(ctx, hosts, lookup)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrExclusionLookup, err)
+		}
+		resolved.prefixes = append([]netip.Prefix(nil), e.prefixes...)
+		for _, hostAddrs := range addrs {
+			for _, addr := range hostAddrs {
+				resolved.prefixes = append(resolved.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			}
+		}
+	}
+
+	var nameAddrs map[string][]netip.Addr
+	failed := make(map[string]bool)
+	if len(resolved.prefixes) > 0 && len(names) > 0 {
+		nameAddrs = make(map[string][]netip.Addr, len(names))
+		var mu sync.Mutex
+		forEachLookup(ctx, names, lookup, func(name string, addrs []netip.Addr, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && !errors.Is(err, ErrHostNotFound) {
+				failed[name] = true
+			}
+			nameAddrs[name] = addrs
+		})
+	}
+
+	kept = make([]string, 0, len(targets))
+	for _, target := range targets {
+		host := targetHost(target)
+		if resolved.Excludes(target) || failed[host] || resolved.excludesAny(nameAddrs[host]) {
+			excluded++
+		} else {
+			kept = append(kept, target)
+		}
+	}
+	return kept, excluded, nil
+}
+
+// excludesAny reports whether any of the addresses is in an IP or CIDR entry
+func (e *Excluder) excludesAny(addrs []netip.Addr) bool {
+	for _, addr := range addrs {
+		for _, prefix := range e.prefixes {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupAll looks up the addresses of every host, failing on the first host that cannot be
+// looked up; hosts that do not resolve have no addresses
+func lookupAll(ctx context.Context, hosts []string, lookup AddrLookup) (map[string][]netip.Addr, error) {
+	var (
+		mu       sync.Mutex
+		firstErr error
+		addrs    = make(map[string][]netip.Addr, len(hosts))
+	)
+	forEachLookup(ctx, hosts, lookup, func(host string, hostAddrs []netip.Addr, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil && !errors.Is(err, ErrHostNotFound) && firstErr == nil {
+			firstErr = fmt.Errorf("failed to look up %s: %w", host, err)
+		}
+		addrs[host] = hostAddrs
+	})
+	return addrs, firstErr
+}
+
+// forEachLookup looks up the hosts exclusionLookups at a time, calling fn with each outcome
+func forEachLookup(ctx context.Context, hosts []string, lookup AddrLookup, fn func(host string, addrs []netip.Addr, err error)) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, exclusionLookups)
+	for _, host := range hosts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			addrs, err := lookup(ctx, host)
+			fn(host, addrs, err)
+		}(host)
+	}
+	wg.Wait()
+}
+
+// targetHost returns the lower-case host of a host name, host:port or URL
+func targetHost(target string) string {
+	host := strings.ToLower(strings.TrimSpace(target))
+	if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if end := strings.IndexAny(host, "/?#"); end >= 0 {
+		host = host[:end]
+	}
+	if splitHost, _, err := net.SplitHostPort(host); err == nil {
+		host = splitHost
+	}
+	return strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+}
+
+// isExclusionName reports whether a name is made of host name characters only
+func isExclusionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
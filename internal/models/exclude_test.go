@@ -0,0 +1,135 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestExcluder(t *testing.T) {
+	excluder, err := NewExcluder([]string{
+		"db.example.com",
+		"*.legacy.example.com",
+		"192.0.2.10",
+		"198.51.100.0/24",
+		"2001:db8::/32",
+		"re:^printer-\\d+\\.",
+	})
+	if err != nil {
+		t.Fatalf("NewExcluder() error = %v", err)
+	}
+
+	for target, want := range map[string]bool{
+		"db.example.com":                    true,
+		"DB.Example.com.":                   true,
+		"https://db.example.com:8443/login": true,
+		"db.example.com:5432":               true,
+		"app.legacy.example.com":            true,
+		"legacy.example.com":                false,
+		"192.0.2.10":                        true,
+		"192.0.2.11":                        false,
+		"198.51.100.77:443":                 true,
+		"[2001:db8::1]:443":                 true,
+		"::ffff:198.51.100.1":               true,
+		"printer-12.example.com":            true,
+		"printer-x.example.com":             false,
+		"www.example.com":                   false,
+	} {
+		if got := excluder.Excludes(target); got != want {
+			t.Errorf("Excludes(%q) = %t, want %t", target, got, want)
+		}
+	}
+
+	kept, excluded := excluder.Filter([]string{"www.example.com", "db.example.com", "192.0.2.10", "api.example.com"})
+	if excluded != 2 || !reflect.DeepEqual(kept, []string{"www.example.com", "api.example.com"}) {
+		t.Errorf("Filter() = %v, %d", kept, excluded)
+	}
+}
+
+func TestExcluder_Empty(t *testing.T) {
+	excluder, err := NewExcluder([]string{"", "  "})
+	if err != nil || excluder != nil {
+		t.Fatalf("Expected no excluder without entries, got %v, %v", excluder, err)
+	}
+	targets := []string{"db.example.com"}
+	if kept, excluded := excluder.Filter(targets); excluded != 0 || len(kept) != 1 || excluder.Excludes("db.example.com") {
+		t.Error("Expected a nil excluder to keep every target")
+	}
+}
+
+func TestExcluder_FilterResolved(t *testing.T) {
+	addrs := map[string][]netip.Addr{
+		"db.example.com":  {netip.MustParseAddr("192.0.2.10")},
+		"www.example.com": {netip.MustParseAddr("203.0.113.5")},
+		"api.example.com": {netip.MustParseAddr("198.51.100.7")},
+	}
+	lookup := func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if host == "flaky.example.com" {
+			return nil, errors.New("i/o timeout")
+		}
+		if found, ok := addrs[host]; ok {
+			return found, nil
+		}
+		return nil, ErrHostNotFound
+	}
+
+	// IP entries apply to the addresses of names, and failed lookups exclude the name
+	excluder, err := NewExcluder([]string{"198.51.100.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, excluded, err := excluder.FilterResolved(context.Background(), []string{"https://api.example.com", "www.example.com", "flaky.example.com", "gone.example.com"}, lookup)
+	if err != nil || excluded != 2 || !reflect.DeepEqual(kept, []string{"www.example.com", "gone.example.com"}) {
+		t.Errorf("FilterResolved() = %v, %d, %v", kept, excluded, err)
+	}
+
+	// Name entries apply to the addresses they resolve to
+	excluder, err = NewExcluder([]string{"db.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, excluded, err = excluder.FilterResolved(context.Background(), []string{"192.0.2.10", "192.0.2.10:5432", "203.0.113.5"}, lookup)
+	if err != nil || excluded != 2 || !reflect.DeepEqual(kept, []string{"203.0.113.5"}) {
+		t.Errorf("FilterResolved() = %v, %d, %v", kept, excluded, err)
+	}
+
+	// Wildcards and patterns cannot be matched against addresses
+	for _, entry := range []string{"*.legacy.example.com", "re:^printer-"} {
+		excluder, err = NewExcluder([]string{entry})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := excluder.FilterResolved(context.Background(), []string{"192.0.2.10"}, lookup); err == nil {
+			t.Errorf("Expected %q to be rejected for IP targets", entry)
+		}
+		if _, _, err := excluder.FilterResolved(context.Background(), []string{"www.example.com"}, lookup); err != nil {
+			t.Errorf("Expected %q to apply to host names, got %v", entry, err)
+		}
+	}
+}
+
+func TestNewExcluder_Invalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0.0/33", "re:(unclosed", "db example.com", "*.", "https://example.com/"} {
+		if _, err := NewExcluder([]string{entry}); err == nil {
+			t.Errorf("Expected %q to be rejected", entry)
+		}
+	}
+}
+
+func TestParseExclusionList(t *testing.T) {
+	entries, err := ParseExclusionList([]byte("# fragile systems\ndb.example.com\n\n  10.0.0.0/8 \nre:^printer-\n"))
+	if err != nil || !reflect.DeepEqual(entries, []string{"db.example.com", "10.0.0.0/8", "re:^printer-"}) {
+		t.Errorf("Unexpected text entries %v, %v", entries, err)
+	}
+
+	entries, err = ParseExclusionList([]byte(`["db.example.com", "192.0.2.1"]`))
+	if err != nil || !reflect.DeepEqual(entries, []string{"db.example.com", "192.0.2.1"}) {
+		t.Errorf("Unexpected JSON entries %v, %v", entries, err)
+	}
+
+	if _, err := ParseExclusionList([]byte(`["unterminated`)); err == nil {
+		t.Error("Expected an invalid JSON list to be rejected")
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Bundle formats of export jobs
+const (
+	ExportFormatNDJSON = "ndjson" // Gzipped NDJSON, one stored result per line
+	ExportFormatZip    = "zip"    // One file per stored result, named by its blob path
+)
+
+// Export job states
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+)
+
+// ExportFilter selects the stored results an export job bundles. Only the result of the latest
+// attempt of each task is exported.
+type ExportFilter struct {
+	Domain  string `json:"domain"`
+	ScanIDs []int  `json:"scan_ids,omitempty"` // All scans of the domain when empty
+	Tasks   []Task `json:"tasks,omitempty"`    // All tasks when empty
+}
+
+// ExportJob is a bulk export assembled in the background into a bundle blob, so clients can pull
+// large amounts of results without a synchronous download timing out
+type ExportJob struct {
+	ID          string       `json:"id"`
+	TenantID    string       `json:"tenant_id,omitempty"`
+	Format      string       `json:"format"`
+	Filter      ExportFilter `json:"filter"`
+	Status      string       `json:"status"`
+	RequestedBy string       `json:"requested_by,omitempty"`
+	CreatedAt   string       `json:"created_at"`
+	UpdatedAt   string       `json:"updated_at"`
+	Results     int          `json:"results"`             // Results bundled so far
+	BlobPath    string       `json:"blob_path,omitempty"` // Bundle blob, once completed
+	Size        int64        `json:"size,omitempty"`      // Bundle size in bytes, once completed
+	Error       string       `json:"error,omitempty"`
+}
+
+// ExportJobBlobPath returns the blob path of an export job's state
+func ExportJobBlobPath(tenantID, id string) string {
+	return exportPrefix(tenantID, id) + "job.json"
+}
+
+// ExportBundleBlobPath returns the blob path of an export job's bundle
+func ExportBundleBlobPath(tenantID, id, format string) string {
+	if format == ExportFormatZip {
+		return exportPrefix(tenantID, id) + "export.zip"
+	}
+	return exportPrefix(tenantID, id) + "export.ndjson.gz"
+}
+
+func exportPrefix(tenantID, id string) string {
+	prefix := "exports/" + id + "/"
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// LatestResultPointer parses the blob path of a latest result pointer below a domain's scans,
+// e.g. "acme/example.com-12/httpx/latest.json", into its scan ID and task
+func LatestResultPointer(tenantID, domain, blobPath string) (int, Task, bool) {
+	rest, ok := strings.CutPrefix(blobPath, DomainScansPrefix(tenantID, domain))
+	if !ok {
+		return 0, "", false
+	}
+	parts := strings.Split(rest, "/")
+	if len(parts) != 3 || parts[2] != "latest.json" {
+		return 0, "", false
+	}
+	scanID, err := strconv.Atoi(parts[0])
+	if err != nil || scanID <= 0 {
+		return 0, "", false
+	}
+	return scanID, Task(parts[1]), true
+}
+
+// Matches reports whether the filter selects the result of a task of a scan
+func (f ExportFilter) Matches(scanID int, task Task) bool {
+	return (len(f.ScanIDs) == 0 || slices.Contains(f.ScanIDs, scanID)) &&
+		(len(f.Tasks) == 0 || slices.Contains(f.Tasks, task))
+}
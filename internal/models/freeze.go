@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// FreezeListBlobPath returns the blob path of the freeze list.
+// The global list lives at "control/freeze.json"; tenants get their own under "<tenant_id>/".
+func FreezeListBlobPath(tenantID string) string {
+	path := "control/freeze.json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// FreezeList holds scopes that must not be scanned, e.g. during a customer incident
+type FreezeList struct {
+	Entries []FreezeEntry `json:"entries"`
+	// Version counts the changes of the list; a change names the version it was made on, so two
+	// operators editing the list at once cannot overwrite each other's entries
+	Version int `json:"version,omitempty"`
+}
+
+// FreezeEntry freezes a domain and all of its subdomains
+type FreezeEntry struct {
+	Scope  string `json:"scope"`
+	Reason string `json:"reason,omitempty"`
+	Until  string `json:"until,omitempty"` // Optional RFC3339 expiry; the entry is ignored afterwards
+}
+
+// Match returns the first active entry whose scope covers the domain, or nil
+func (l *FreezeList) Match(domain string, now time.Time) *FreezeEntry {
+	if l == nil {
+		return nil
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for i := range l.Entries {
+		entry := &l.Entries[i]
+		if !entry.Active(now) {
+			continue
+		}
+
+		scope := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(entry.Scope), "*."))
+		if scope == "*" || domain == scope || strings.HasSuffix(domain, "."+scope) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Active reports whether the entry has not expired yet. Entries with an unparsable expiry stay active.
+func (e *FreezeEntry) Active(now time.Time) bool {
+	if e.Until == "" {
+		return true
+	}
+
+	until, err := time.Parse(time.RFC3339, e.Until)
+	if err != nil {
+		return true
+	}
+	return now.Before(until)
+}
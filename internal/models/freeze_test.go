@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreezeListMatch(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	list := &FreezeList{Entries: []FreezeEntry{
+		{Scope: "example.com", Reason: "incident"},
+		{Scope: "*.shop.org", Reason: "maintenance"},
+		{Scope: "expired.net", Until: "2024-04-30T00:00:00Z"},
+		{Scope: "later.net", Until: "2024-05-02T00:00:00Z"},
+	}}
+
+	tests := []struct {
+		domain string
+		frozen bool
+	}{
+		{"example.com", true},
+		{"api.Example.com.", true},
+		{"notexample.com", false},
+		{"cart.shop.org", true},
+		{"shop.org", true},
+		{"expired.net", false},
+		{"www.later.net", true},
+		{"other.io", false},
+	}
+
+	for _, tt := range tests {
+		if got := list.Match(tt.domain, now) != nil; got != tt.frozen {
+			t.Errorf("Match(%q) frozen = %v, want %v", tt.domain, got, tt.frozen)
+		}
+	}
+}
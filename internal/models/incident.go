@@ -0,0 +1,72 @@
+package models
+
+// IncidentStateBlobPath returns the blob path of a domain's incident state
+func IncidentStateBlobPath(tenantID, domain string) string {
+	path := "incidents/" + DomainBlobSegment(domain) + ".json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// IncidentState tracks the incidents opened for a domain so that later scans can resolve them
+type IncidentState struct {
+	TenantID    string                  `json:"tenant_id,omitempty"`
+	Domain      string                  `json:"domain"`
+	Open        map[string]OpenIncident `json:"open"`                   // Keyed by finding fingerprint
+	KnownAssets []string                `json:"known_assets,omitempty"` // IPs seen by earlier port scans
+}
+
+// OpenIncident is an incident that has been triggered and not yet resolved
+type OpenIncident struct {
+	Scope    string `json:"scope"` // Scanner run that can confirm the finding is gone, e.g. "nuclei:http"
+	Summary  string `json:"summary"`
+	OpenedAt string `json:"opened_at"`
+	// TemplateID is the nuclei template of the finding; runs that exclude it leave the incident open
+	TemplateID string `json:"template_id,omitempty"`
+}
+
+// TicketingConfigBlobPath returns the blob path of a tenant's ticketing configuration
+func TicketingConfigBlobPath(tenantID string) string {
+	path := "control/ticketing.json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// TicketingConfig controls how a tenant's findings are turned into issue tracker tickets
+type TicketingConfig struct {
+	Enabled         bool   `json:"enabled"`
+	Provider        string `json:"provider"`                   // "jira" or "github"
+	Project         string `json:"project"`                    // Jira project key or GitHub "owner/repo"
+	MinSeverity     string `json:"min_severity,omitempty"`     // Lowest severity that gets a ticket, "medium" by default
+	IssueType       string `json:"issue_type,omitempty"`       // Jira issue type, "Bug" by default
+	CloseTransition string `json:"close_transition,omitempty"` // Jira transition that closes a ticket, "Done" by default
+}
+
+// TicketStateBlobPath returns the blob path of a domain's ticket state
+func TicketStateBlobPath(tenantID, domain string) string {
+	path := "tickets/" + DomainBlobSegment(domain) + ".json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// TicketState tracks the tickets opened for a domain's findings
+type TicketState struct {
+	TenantID string                `json:"tenant_id,omitempty"`
+	Domain   string                `json:"domain"`
+	Tickets  map[string]OpenTicket `json:"tickets"` // Keyed by finding fingerprint
+}
+
+// OpenTicket is a ticket that was created for a finding and not yet closed
+type OpenTicket struct {
+	Key      string `json:"key"` // Jira issue key or GitHub issue number
+	Scope    string `json:"scope"`
+	Severity string `json:"severity"`
+	OpenedAt string `json:"opened_at"`
+	// TemplateID is the template of the finding; runs that exclude it leave the ticket open
+	TemplateID string `json:"template_id,omitempty"`
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"net"
+	"time"
+)
+
+// InventoryBlobPath returns the blob path of a domain's asset inventory
+func InventoryBlobPath(tenantID, domain string) string {
+	path := "inventory/" + DomainBlobSegment(domain) + ".json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// Confidence levels of an inventory asset, from least to most certain
+const (
+	ConfidencePassiveOnly        = "passive_only"        // Only reported by passive sources
+	ConfidenceResolved           = "resolved"            // The name resolved in DNS
+	ConfidenceAlive              = "alive"               // An open port or HTTP service answered
+	ConfidenceScreenshotVerified = "screenshot_verified" // A screenshot of the service was taken
+)
+
+// confidenceRanks orders the confidence levels
+var confidenceRanks = map[string]int{
+	ConfidencePassiveOnly:        1,
+	ConfidenceResolved:           2,
+	ConfidenceAlive:              3,
+	ConfidenceScreenshotVerified: 4,
+}
+
+// ConfidenceRank returns the rank of a confidence level; unknown levels rank lowest at 0
+func ConfidenceRank(confidence string) int {
+	return confidenceRanks[confidence]
+}
+
+// Inventory is every asset ever found for a domain, across scans
+type Inventory struct {
+	TenantID  string                     `json:"tenant_id,omitempty"`
+	Domain    string                     `json:"domain"`
+	Assets    map[string]*InventoryAsset `json:"assets"` // Keyed by host name or IP
+	UpdatedAt time.Time                  `json:"updated_at"`
+}
+
+// InventoryAsset is a host name or IP of a domain's inventory. Confidence is the strongest
+// evidence any scan found; LastSeen tells whether that evidence is still current.
+type InventoryAsset struct {
+	Type       string    `json:"type"` // "host" or "ip"
+	Confidence string    `json:"confidence"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	LastScanID int       `json:"last_scan_id,omitempty"`
+	// InZone is set while the host is a record of the tenant's cloud DNS zones, the ground truth
+	InZone bool `json:"in_zone,omitempty"`
+	// MissedByEnumeration flags a zone record no subfinder, DNSX, naabu or httpx result listed
+	MissedByEnumeration bool `json:"missed_by_enumeration,omitempty"`
+}
+
+// Index records the assets of a task result as seen at seenAt and returns how many were new.
+// Confidence only increases, so a passive listing does not downgrade a resolved host. Streamed
+// DNSX results are indexed with their records read back inline. Cloud DNS results mark the zone's hosts
+// and flag those enumeration has not found; any other result listing a host clears its flag.
+func (inv *Inventory) Index(result *TaskResult, seenAt time.Time) int {
+	if inv.Assets == nil {
+		inv.Assets = make(map[string]*InventoryAsset)
+	}
+
+	_, fromZone := result.Data.(CloudDNSResult)
+	added := 0
+	see := func(name, confidence string) {
+		if name == "" {
+			return
+		}
+		asset, ok := inv.Assets[name]
+		if !ok {
+			assetType := "host"
+			if net.ParseIP(name) != nil {
+				assetType = "ip"
+			}
+			asset = &InventoryAsset{Type: assetType, Confidence: confidence, FirstSeen: seenAt}
+			inv.Assets[name] = asset
+			added++
+		}
+		if ConfidenceRank(confidence) > ConfidenceRank(asset.Confidence) {
+			asset.Confidence = confidence
+		}
+		if seenAt.After(asset.LastSeen) {
+			asset.LastSeen, asset.LastScanID = seenAt, result.ScanID
+		}
+		if !fromZone {
+			asset.MissedByEnumeration = false
+		}
+	}
+
+	switch data := result.Data.(type) {
+	case SubfinderResult:
+		for _, subdomain := range data.Subdomains {
+			see(subdomain, ConfidencePassiveOnly)
+		}
+	case DNSXResult:
+		for host, info := range data.Records {
+			if info.Status == DNSStatusResolved {
+				see(host, ConfidenceResolved)
+			}
+		}
+	case NaabuResult:
+		for ip, ports := range data.Ports {
+			if len(ports) > 0 {
+				see(ip, ConfidenceAlive)
+			}
+		}
+	case HttpxResult:
+		for _, service := range data.Results {
+			see(service.Host, ConfidenceAlive)
+		}
+	case CloudDNSResult:
+		inv.indexZone(data, see)
+	}
+
+	inv.UpdatedAt = seenAt
+	return added
+}
+
+// indexZone marks the hosts of a cloud DNS result as zone records, flagging those no earlier
+// result listed. Hosts that left the zone lose the mark once a complete result is indexed.
+func (inv *Inventory) indexZone(data CloudDNSResult, see func(name, confidence string)) {
+	inZone := make(map[string]bool)
+	for _, name := range data.Names() {
+		inZone[name] = true
+		asset, known := inv.Assets[name]
+		see(name, ConfidenceResolved)
+		if !known {
+			asset = inv.Assets[name]
+			asset.MissedByEnumeration = true
+		}
+		asset.InZone = true
+	}
+
+	if data.Partial {
+		return
+	}
+	for name, asset := range inv.Assets {
+		if asset.InZone && !inZone[name] {
+			asset.InZone, asset.MissedByEnumeration = false, false
+		}
+	}
+}
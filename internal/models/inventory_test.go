@@ -0,0 +1,80 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInventory_Index(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	friday := monday.AddDate(0, 0, 4)
+	inventory := &Inventory{Domain: "example.com"}
+
+	added := inventory.Index(&TaskResult{ScanID: 1, Data: SubfinderResult{Subdomains: []string{"www.example.com", "old.example.com"}}}, monday)
+	if added != 2 || inventory.Assets["www.example.com"].Confidence != ConfidencePassiveOnly {
+		t.Fatalf("Expected 2 passive assets, got %d: %+v", added, inventory.Assets)
+	}
+
+	added = inventory.Index(&TaskResult{ScanID: 2, Data: DNSXResult{Records: map[string]ResolutionInfo{
+		"www.example.com": {Status: DNSStatusResolved},
+		"old.example.com": {Status: DNSStatusNXDomain},
+	}}}, friday)
+	added += inventory.Index(&TaskResult{ScanID: 2, Data: NaabuResult{Ports: map[string][]PortInfo{"192.0.2.1": {{Port: 443}}}}}, friday)
+	if added != 1 {
+		t.Errorf("Expected only the IP to be new, got %d", added)
+	}
+
+	www := inventory.Assets["www.example.com"]
+	if www.Confidence != ConfidenceResolved || !www.FirstSeen.Equal(monday) || !www.LastSeen.Equal(friday) || www.LastScanID != 2 {
+		t.Errorf("Expected www to be resolved, first seen monday and last seen friday, got %+v", www)
+	}
+	if old := inventory.Assets["old.example.com"]; old.Confidence != ConfidencePassiveOnly || !old.LastSeen.Equal(monday) {
+		t.Errorf("Expected the NXDOMAIN host to keep its passive confidence and last sighting, got %+v", old)
+	}
+	if ip := inventory.Assets["192.0.2.1"]; ip.Type != "ip" || ip.Confidence != ConfidenceAlive {
+		t.Errorf("Expected an alive IP asset, got %+v", ip)
+	}
+
+	// A later passive listing refreshes last_seen but never lowers the confidence
+	inventory.Index(&TaskResult{ScanID: 3, Data: SubfinderResult{Subdomains: []string{"www.example.com"}}}, friday.Add(time.Hour))
+	if www.Confidence != ConfidenceResolved || www.LastScanID != 3 {
+		t.Errorf("Expected www to stay resolved with its last sighting in scan 3, got %+v", www)
+	}
+}
+
+func TestInventory_IndexCloudDNS(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	inventory := &Inventory{Domain: "example.com"}
+	inventory.Index(&TaskResult{ScanID: 1, Data: SubfinderResult{Subdomains: []string{"www.example.com"}}}, monday)
+
+	zone := CloudDNSResult{Domain: "example.com", Records: []CloudDNSRecord{
+		{Name: "www.example.com", Type: "A"},
+		{Name: "vpn.example.com", Type: "A"},
+		{Name: "vpn.example.com", Type: "AAAA"},
+		{Name: "*.dev.example.com", Type: "CNAME"},
+	}}
+	if added := inventory.Index(&TaskResult{ScanID: 2, Data: zone}, monday.Add(time.Hour)); added != 1 {
+		t.Fatalf("Expected only vpn to be new, got %d", added)
+	}
+	if _, ok := inventory.Assets["*.dev.example.com"]; ok {
+		t.Error("Expected wildcard records to be left out")
+	}
+
+	www, vpn := inventory.Assets["www.example.com"], inventory.Assets["vpn.example.com"]
+	if !www.InZone || www.MissedByEnumeration || www.Confidence != ConfidenceResolved {
+		t.Errorf("Expected www to be a zone record found by enumeration, got %+v", www)
+	}
+	if !vpn.InZone || !vpn.MissedByEnumeration {
+		t.Errorf("Expected vpn to be flagged as missed by enumeration, got %+v", vpn)
+	}
+
+	// Enumeration catching up clears the flag; a complete zone read without vpn clears the mark
+	inventory.Index(&TaskResult{ScanID: 3, Data: DNSXResult{Records: map[string]ResolutionInfo{"vpn.example.com": {Status: DNSStatusResolved}}}}, monday.Add(2*time.Hour))
+	if vpn.MissedByEnumeration {
+		t.Error("Expected a DNSX sighting to clear the missed flag")
+	}
+	inventory.Index(&TaskResult{ScanID: 4, Data: CloudDNSResult{Records: []CloudDNSRecord{{Name: "www.example.com", Type: "A"}}}}, monday.Add(3*time.Hour))
+	if vpn.InZone || !www.InZone {
+		t.Errorf("Expected only vpn to leave the zone, got vpn %+v, www %+v", vpn, www)
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MessageLock tracks the lock the worker holds on the queue message of the task it processes.
+// The lock is renewed until renewUntil; once a renewal fails the lock is lost and expires at
+// its current locked-until time, after which another worker may receive the message.
+// A nil MessageLock never expires and is never lost.
+type MessageLock struct {
+	mu          sync.Mutex
+	lockedUntil time.Time
+	renewUntil  time.Time
+	lost        chan struct{}
+	lostOnce    sync.Once
+}
+
+// messageLockKey is the context key of the message lock
+type messageLockKey struct{}
+
+// NewMessageLock creates the lock of a message locked until lockedUntil that is renewed until renewUntil
+func NewMessageLock(lockedUntil, renewUntil time.Time) *MessageLock {
+	return &MessageLock{
+		lockedUntil: lockedUntil,
+		renewUntil:  renewUntil,
+		lost:        make(chan struct{}),
+	}
+}
+
+// WithMessageLock returns a context carrying the message lock
+func WithMessageLock(ctx context.Context, lock *MessageLock) context.Context {
+	return context.WithValue(ctx, messageLockKey{}, lock)
+}
+
+// MessageLockFromContext returns the message lock of the context, or nil
+func MessageLockFromContext(ctx context.Context) *MessageLock {
+	lock, _ := ctx.Value(messageLockKey{}).(*MessageLock)
+	return lock
+}
+
+// Renewed records that the lock was renewed until lockedUntil
+func (l *MessageLock) Renewed(lockedUntil time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lockedUntil.After(l.lockedUntil) {
+		l.lockedUntil = lockedUntil
+	}
+}
+
+// MarkLost records that the lock can no longer be renewed
+func (l *MessageLock) MarkLost() {
+	l.lostOnce.Do(func() { close(l.lost) })
+}
+
+// Lost returns a channel that is closed once the lock can no longer be renewed
+func (l *MessageLock) Lost() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.lost
+}
+
+// IsLost reports whether the lock can no longer be renewed
+func (l *MessageLock) IsLost() bool {
+	select {
+	case <-l.Lost():
+		return true
+	default:
+		return false
+	}
+}
+
+// LockedUntil returns when the lock expires unless it is renewed
+func (l *MessageLock) LockedUntil() time.Time {
+	if l == nil {
+		return time.Time{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lockedUntil
+}
+
+// Deadline returns when the worker stops holding the message: the end of the renewals, or the
+// locked-until time once the lock is lost. It is zero for a nil lock.
+func (l *MessageLock) Deadline() time.Time {
+	if l == nil {
+		return time.Time{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.IsLost() || l.renewUntil.Before(l.lockedUntil) {
+		return l.lockedUntil
+	}
+	return l.renewUntil
+}
+
+// Remaining returns the time left until the deadline, or 0 for a nil lock
+func (l *MessageLock) Remaining() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return time.Until(l.Deadline())
+}
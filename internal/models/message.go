@@ -0,0 +1,126 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxMessageSize is the largest task message accepted by default, the Service Bus
+// standard tier limit; real tasks are a few KB and keep large inputs in blobs
+const DefaultMaxMessageSize = 256 << 10
+
+// Dead-letter reasons of messages rejected before they reach the handler
+const (
+	RejectTooLarge      = "MessageTooLarge"
+	RejectMalformed     = "MalformedMessage"
+	RejectUnknownField  = "UnknownField"
+	RejectMissingField  = "MissingRequiredField"
+	RejectUnknownTask   = "UnknownTaskType"
+	RejectUnknownAction = "UnknownAction"
+)
+
+// KnownTasks lists the task types a worker can run
+var KnownTasks = []Task{
+	TaskSubfinder,
+	TaskHttpx,
+	TaskDNSResolve,
+	TaskNaabu,
+	TaskNuclei,
+	TaskScopeExpansion,
+	TaskCloudDNS,
+}
+
+// MessageRejection is why a queue message was rejected before processing. Retrying cannot fix
+// it, so the message is dead-lettered right away with Reason.
+type MessageRejection struct {
+	Reason string // One of the Reject* reasons
+	Field  string // JSON field at fault, if any
+	Err    error
+}
+
+func (r *MessageRejection) Error() string {
+	if r.Field != "" {
+		return fmt.Sprintf("%s: %s: %v", r.Reason, r.Field, r.Err)
+	}
+	return fmt.Sprintf("%s: %v", r.Reason, r.Err)
+}
+
+func (r *MessageRejection) Unwrap() error {
+	return r.Err
+}
+
+// DecodeTaskMessage decodes a queue message body into a task message, rejecting bodies larger
+// than maxSize bytes (0 for no limit), bodies that are not a single JSON object of the task
+// message's fields and field types, and messages missing the fields every task needs or naming a
+// task type or control action no worker runs. Its errors are *MessageRejection; the handler still
+// validates the contents of the fields.
+func DecodeTaskMessage(body []byte, maxSize int) (*TaskMessage, error) {
+	if maxSize > 0 && len(body) > maxSize {
+		return nil, &MessageRejection{Reason: RejectTooLarge, Err: fmt.Errorf("message is %d bytes, the limit is %d", len(body), maxSize)}
+	}
+
+	var taskMsg TaskMessage
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&taskMsg); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return nil, &MessageRejection{Reason: RejectMalformed, Field: typeErr.Field, Err: fmt.Errorf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+		}
+		// encoding/json has no error type for unknown fields, only this message
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field, _ = strconv.Unquote(field)
+			return nil, &MessageRejection{Reason: RejectUnknownField, Field: field, Err: errors.New("unknown field")}
+		}
+		return nil, &MessageRejection{Reason: RejectMalformed, Err: err}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, &MessageRejection{Reason: RejectMalformed, Err: errors.New("trailing data after the JSON object")}
+	}
+
+	if err := checkRequiredFields(&taskMsg); err != nil {
+		return nil, err
+	}
+	return &taskMsg, nil
+}
+
+// checkRequiredFields checks the fields a control message or task cannot be routed without
+func checkRequiredFields(taskMsg *TaskMessage) error {
+	if taskMsg.Action != "" {
+		if taskMsg.Action != TaskActionPause && taskMsg.Action != TaskActionResume && taskMsg.Action != TaskActionCompact {
+			return &MessageRejection{Reason: RejectUnknownAction, Field: "action", Err: fmt.Errorf("unknown control action %q", taskMsg.Action)}
+		}
+		// Compactions act on all scans of a domain rather than on one scan
+		if taskMsg.Action == TaskActionCompact {
+			if taskMsg.Domain == "" {
+				return &MessageRejection{Reason: RejectMissingField, Field: "domain", Err: errors.New("domain is required")}
+			}
+			return nil
+		}
+	} else {
+		if taskMsg.Task == "" {
+			return &MessageRejection{Reason: RejectMissingField, Field: "task", Err: errors.New("task type is required")}
+		}
+		if !slices.Contains(KnownTasks, taskMsg.Task) {
+			return &MessageRejection{Reason: RejectUnknownTask, Field: "task", Err: fmt.Errorf("unknown task type %q", taskMsg.Task)}
+		}
+		if taskMsg.Domain == "" && len(taskMsg.Domains) == 0 && taskMsg.DomainsBlobPath == "" {
+			return &MessageRejection{Reason: RejectMissingField, Field: "domain", Err: errors.New("domain, domains or domains_blob_path is required")}
+		}
+	}
+	if taskMsg.ScanID == 0 {
+		return &MessageRejection{Reason: RejectMissingField, Field: "scan_id", Err: errors.New("scan_id is required")}
+	}
+	return nil
+}
+
+// Result returns the processing result of the rejected message, which dead-letters it with the reason
+func (r *MessageRejection) Result() *MessageProcessingResult {
+	return &MessageProcessingResult{Error: r, DeadLetterReason: r.Reason}
+}
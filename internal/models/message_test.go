@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecodeTaskMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		reason string
+		field  string
+	}{
+		{"task", `{"task":"httpx","scan_id":7,"domain":"example.com","config":{"threads":10}}`, "", ""},
+		{"multi-domain task", `{"task":"subfinder","scan_id":7,"domains":["a.com","b.com"]}`, "", ""},
+		{"control message", `{"action":"pause","scan_id":7}`, "", ""},
+		{"too large", `{"task":"httpx","scan_id":7,"domain":"` + strings.Repeat("a", 200) + `.com"}`, RejectTooLarge, ""},
+		{"not JSON", `task=httpx`, RejectMalformed, ""},
+		{"array", `[{"task":"httpx"}]`, RejectMalformed, ""},
+		{"trailing data", `{"task":"httpx","scan_id":7,"domain":"example.com"} {}`, RejectMalformed, ""},
+		{"wrong field type", `{"task":"httpx","scan_id":"7","domain":"example.com"}`, RejectMalformed, "scan_id"},
+		{"unknown field", `{"task":"httpx","scan_id":7,"domain":"example.com","sacn_id":8}`, RejectUnknownField, "sacn_id"},
+		{"missing task", `{"scan_id":7,"domain":"example.com"}`, RejectMissingField, "task"},
+		{"unknown task", `{"task":"masscan","scan_id":7,"domain":"example.com"}`, RejectUnknownTask, "task"},
+		{"missing domain", `{"task":"httpx","scan_id":7}`, RejectMissingField, "domain"},
+		{"missing scan", `{"task":"httpx","domain":"example.com"}`, RejectMissingField, "scan_id"},
+		{"unknown action", `{"action":"stop","scan_id":7}`, RejectUnknownAction, "action"},
+		{"compaction", `{"action":"compact","domain":"example.com","older_than_days":30}`, "", ""},
+		{"compaction without domain", `{"action":"compact","scan_id":7}`, RejectMissingField, "domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			taskMsg, err := DecodeTaskMessage([]byte(tt.body), 200)
+			if tt.reason == "" {
+				if err != nil || taskMsg == nil || (taskMsg.ScanID != 7 && taskMsg.Action != TaskActionCompact) {
+					t.Fatalf("DecodeTaskMessage() = %+v, %v, want the message", taskMsg, err)
+				}
+				return
+			}
+
+			var rejection *MessageRejection
+			if !errors.As(err, &rejection) {
+				t.Fatalf("DecodeTaskMessage() error = %v, want a rejection", err)
+			}
+			if rejection.Reason != tt.reason || rejection.Field != tt.field {
+				t.Errorf("rejection = %s of %q, want %s of %q", rejection.Reason, rejection.Field, tt.reason, tt.field)
+			}
+			if result := rejection.Result(); result.Retryable || result.DeadLetterReason != tt.reason {
+				t.Errorf("Result() = %+v, want a dead-letter with the reason", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxMessageDomains bounds the domains a multi-domain task may carry
+const MaxMessageDomains = 10000
+
+// MultiDomainTasks are the task types light enough to run for many domains from one message.
+// They need nothing but the domain, so every domain runs as its own task.
+var MultiDomainTasks = []Task{TaskSubfinder, TaskDNSResolve}
+
+// IsMultiDomain reports whether the task lists its domains instead of naming a single one
+func (t *TaskMessage) IsMultiDomain() bool {
+	return len(t.Domains) > 0 || t.DomainsBlobPath != ""
+}
+
+// ForDomain returns the single-domain task a multi-domain task runs for one of its domains.
+// Everything but the domain list is shared, so the result lands under the same scan_id.
+func (t *TaskMessage) ForDomain(domain string) *TaskMessage {
+	task := *t
+	task.Domain = domain
+	task.Domains = nil
+	task.DomainsBlobPath = ""
+	return &task
+}
+
+// ScanInputPrefix returns the blob prefix for inputs of a scan that are not tied to one domain,
+// such as the domain list of a multi-domain task
+func ScanInputPrefix(tenantID string, scanID int) string {
+	prefix := fmt.Sprintf("inputs/scan-%d/", scanID)
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// UniqueDomains returns the domains lower-cased, without blanks and duplicates, in their original order
+func UniqueDomains(domains []string) []string {
+	seen := make(map[string]bool, len(domains))
+	unique := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && !seen[domain] {
+			seen[domain] = true
+			unique = append(unique, domain)
+		}
+	}
+	return unique
+}
+
+// Outcomes of a domain of a multi-domain task that later deliveries of its message skip
+const (
+	DomainCompleted = "completed" // The domain stored its result, or had nothing to do
+	DomainFailed    = "failed"    // The domain failed for good and stored its error artifact
+)
+
+// MultiDomainProgress records the domains of a multi-domain message that finished, so that a
+// retried or deferred delivery of the message only runs the domains that did not
+type MultiDomainProgress struct {
+	ID        string                   `json:"id"`
+	TenantID  string                   `json:"tenant_id,omitempty"`
+	ScanID    int                      `json:"scan_id"`
+	Task      Task                     `json:"task"`
+	Domains   map[string]DomainOutcome `json:"domains"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// DomainOutcome is how a domain of a multi-domain message finished
+type DomainOutcome struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MultiDomainProgressID identifies a multi-domain message. Like OutboxEntryID it is built from the
+// fields every delivery of the message shares, and it covers the domain list, so two messages of a
+// scan listing different domains do not share their progress.
+func MultiDomainProgressID(taskMsg *TaskMessage) string {
+	key := fmt.Sprintf("%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s", taskMsg.TenantID, taskMsg.ScanID, taskMsg.Task,
+		taskMsg.Type, taskMsg.Mode, taskMsg.InstanceID, taskMsg.DomainsBlobPath, strings.Join(taskMsg.Domains, "\n"))
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// MultiDomainProgressBlobPath returns the blob path of a multi-domain message's progress
+func MultiDomainProgressBlobPath(taskMsg *TaskMessage) string {
+	path := "control/multi-domain/" + MultiDomainProgressID(taskMsg) + ".json"
+	if taskMsg.TenantID != "" {
+		path = taskMsg.TenantID + "/" + path
+	}
+	return path
+}
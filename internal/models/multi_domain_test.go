@@ -0,0 +1,66 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForDomain(t *testing.T) {
+	taskMsg := &TaskMessage{
+		Task:            TaskSubfinder,
+		ScanID:          42,
+		TenantID:        "acme",
+		Domains:         []string{"example.com", "example.org"},
+		DomainsBlobPath: "acme/inputs/scan-42/domains.txt",
+	}
+	if !taskMsg.IsMultiDomain() {
+		t.Fatal("Expected a task listing domains to be multi-domain")
+	}
+
+	single := taskMsg.ForDomain("example.org")
+	if single.Domain != "example.org" || single.IsMultiDomain() {
+		t.Errorf("Expected a single-domain task for example.org, got %+v", single)
+	}
+	if single.ScanID != 42 || single.TenantID != "acme" {
+		t.Errorf("Expected the scan and tenant to be kept, got %+v", single)
+	}
+	if len(taskMsg.Domains) != 2 {
+		t.Errorf("Expected the original task to keep its domains, got %v", taskMsg.Domains)
+	}
+}
+
+func TestUniqueDomains(t *testing.T) {
+	got := UniqueDomains([]string{"Example.com", " example.org ", "", "example.com"})
+	if expected := []string{"example.com", "example.org"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestScanInputPrefix(t *testing.T) {
+	if got := ScanInputPrefix("acme", 42); got != "acme/inputs/scan-42/" {
+		t.Errorf("Unexpected prefix %q", got)
+	}
+	if got := ScanInputPrefix("", 42); got != "inputs/scan-42/" {
+		t.Errorf("Unexpected prefix without a tenant %q", got)
+	}
+}
+
+func TestMultiDomainProgressID(t *testing.T) {
+	taskMsg := &TaskMessage{Task: TaskSubfinder, ScanID: 42, TenantID: "acme", InstanceID: "abc", Domains: []string{"example.com", "example.org"}}
+	redelivered := *taskMsg
+	redelivered.CorrelationID = "other"
+	redelivered.Attempt = 2
+	if MultiDomainProgressID(taskMsg) != MultiDomainProgressID(&redelivered) {
+		t.Error("Expected every delivery of a message to share its progress")
+	}
+
+	other := *taskMsg
+	other.Domains = []string{"example.com"}
+	if MultiDomainProgressID(taskMsg) == MultiDomainProgressID(&other) {
+		t.Error("Expected messages listing different domains to have their own progress")
+	}
+	if path := MultiDomainProgressBlobPath(taskMsg); !strings.HasPrefix(path, "acme/control/multi-domain/") {
+		t.Errorf("Expected the progress under the tenant's control prefix, got %s", path)
+	}
+}
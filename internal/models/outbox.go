@@ -0,0 +1,68 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// outboxFolder is where completion notifications wait for delivery to the orchestrator, under the
+// folder of their tenant
+const outboxFolder = "control/outbox/"
+
+// OutboxEntry is a completion notification for the orchestrator. It is stored before the task's
+// message is completed and delivered afterwards, so a worker that stops in between leaves it for
+// the next sweep. Delivered entries are kept for a while as a record that the task run finished.
+type OutboxEntry struct {
+	ID          string          `json:"id"`
+	InstanceID  string          `json:"instance_id"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	TaskMessage TaskMessage     `json:"task_message"` // The task the notification completes
+	CreatedAt   time.Time       `json:"created_at"`
+	Attempts    int             `json:"attempts"` // Failed delivery attempts
+	LastError   string          `json:"last_error,omitempty"`
+	DeliveredAt *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// OutboxEntryID identifies the completion notification of a task run. It is built from the fields
+// every delivery of a message shares, not from the correlation ID, which is generated anew for
+// each delivery of a message without one, so a redelivered message finds and replaces its own entry.
+func OutboxEntryID(taskMsg *TaskMessage) string {
+	key := fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%s", taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID, taskMsg.Task,
+		taskMsg.Type, taskMsg.Mode, taskMsg.InstanceID)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// OutboxBlobPrefix returns the folder of a tenant's outbox entries. They are kept with the
+// tenant's other blobs, so they stay in its data region.
+func OutboxBlobPrefix(tenantID string) string {
+	if tenantID == "" {
+		return outboxFolder
+	}
+	return tenantID + "/" + outboxFolder
+}
+
+// OutboxBlobPath returns the blob path of an outbox entry of a tenant
+func OutboxBlobPath(tenantID, id string) string {
+	return OutboxBlobPrefix(tenantID) + id + ".json"
+}
+
+// BlobPath returns the blob path the entry is stored at
+func (e *OutboxEntry) BlobPath() string {
+	return OutboxBlobPath(e.TaskMessage.TenantID, e.ID)
+}
+
+// IsOutboxBlobPath reports whether a blob path is an outbox entry of any tenant
+func IsOutboxBlobPath(blobPath string) bool {
+	folder, name, ok := strings.Cut(blobPath, outboxFolder)
+	if !ok || (folder != "" && (strings.Count(folder, "/") != 1 || !strings.HasSuffix(folder, "/"))) {
+		return false
+	}
+	id, ok := strings.CutSuffix(name, ".json")
+	return ok && id != "" && !strings.Contains(id, "/")
+}
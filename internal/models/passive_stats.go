@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// PassiveSourceTotalsBlobPath returns the blob path of the running totals of a passive source
+func PassiveSourceTotalsBlobPath(source string) string {
+	return "control/passive-sources/" + source + ".json"
+}
+
+// PassiveSourceTotals adds up the statistics of a passive subdomain source over every scan of
+// every worker, so how a source performs survives worker restarts
+type PassiveSourceTotals struct {
+	Source          string    `json:"source"`
+	Scans           int       `json:"scans"`
+	Results         int       `json:"results"`
+	Requests        int       `json:"requests"`
+	Retries         int       `json:"retries"`
+	Failures        int       `json:"failures"`         // Scans in which the source returned an error
+	BudgetExhausted int       `json:"budget_exhausted"` // Scans in which the source used its request budget
+	QuotaLimited    int       `json:"quota_limited"`    // Scans in which the API key's quota cut the budget
+	Skipped         int       `json:"skipped"`          // Scans that skipped the source as its quota ran out
+	LastError       string    `json:"last_error,omitempty"`
+	LastErrorAt     time.Time `json:"last_error_at,omitempty"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Add counts the statistics of one scan into the totals
+func (t *PassiveSourceTotals) Add(stats PassiveSourceStats, now time.Time) {
+	t.Scans++
+	t.Results += stats.Results
+	t.Requests += stats.Requests
+	t.Retries += stats.Retries
+	if stats.Error != "" {
+		t.Failures++
+		t.LastError, t.LastErrorAt = stats.Error, now
+	}
+	if stats.BudgetExhausted {
+		t.BudgetExhausted++
+	}
+	if stats.QuotaLimited {
+		t.QuotaLimited++
+	}
+	if stats.Skipped {
+		t.Skipped++
+	}
+	t.UpdatedAt = now
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// scanProfileName matches the names of scan profiles
+var scanProfileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ScanProfilesBlobPath returns the blob path of the scan profiles kept in storage.
+// The global profiles live at "control/profiles.json"; tenants override them under "<tenant_id>/".
+func ScanProfilesBlobPath(tenantID string) string {
+	path := "control/profiles.json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// ScanProfile bundles the task config of every task type it sets, e.g.
+// {"port_scan": {"top_ports": "1000"}, "dns_resolve": {"retries": 2}}
+type ScanProfile map[Task]map[string]any
+
+// ScanProfiles holds named scan profiles, which tasks reference instead of repeating their config
+type ScanProfiles map[string]ScanProfile
+
+// DefaultScanProfiles are the profiles every worker knows; SCAN_PROFILES and the profile blobs
+// override their keys or add profiles
+var DefaultScanProfiles = ScanProfiles{
+	"quick": {
+		TaskDNSResolve: {"retries": 1, "timeout_ms": 1000},
+		TaskNaabu:      {"top_ports": "100", "timeout": 1},
+		TaskNuclei:     {"disable_interactsh": true},
+	},
+	"standard": {
+		TaskDNSResolve: {"retries": 2},
+		TaskNaabu:      {"top_ports": "1000"},
+	},
+	"deep": {
+		TaskDNSResolve: {"retries": 3, "retry_pass": true, "question_types": []any{"A", "AAAA", "CNAME", "MX", "NS", "TXT"}},
+		TaskNaabu:      {"top_ports": "full", "pre_filter": true, "scan_cdn": true},
+	},
+}
+
+// ParseScanProfiles parses profiles given as a JSON object of profile names, checking every
+// task config they hold the way the config of a task is checked
+func ParseScanProfiles(data []byte) (ScanProfiles, error) {
+	var profiles ScanProfiles
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid scan profiles: %w", err)
+	}
+	for _, name := range profiles.Names() {
+		if !scanProfileName.MatchString(name) {
+			return nil, fmt.Errorf("invalid scan profile name %q: use 1 to 32 lowercase letters, digits, '-' and '_'", name)
+		}
+		for task, config := range profiles[name] {
+			if _, err := ParseTaskConfig(task, config); err != nil {
+				return nil, fmt.Errorf("scan profile %s, %s: %w", name, task, err)
+			}
+		}
+	}
+	return profiles, nil
+}
+
+// ConfiguredScanProfiles returns the default profiles with those of a SCAN_PROFILES setting, a
+// JSON object like the profile blobs, layered on top
+func ConfiguredScanProfiles(spec string) (ScanProfiles, error) {
+	if strings.TrimSpace(spec) == "" {
+		return DefaultScanProfiles, nil
+	}
+	profiles, err := ParseScanProfiles([]byte(spec))
+	if err != nil {
+		return nil, err
+	}
+	return DefaultScanProfiles.Merge(profiles), nil
+}
+
+// ValidScanProfileName reports whether a task may reference a profile by the name
+func ValidScanProfileName(name string) bool {
+	return scanProfileName.MatchString(name)
+}
+
+// Names returns the profile names in order
+func (p ScanProfiles) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merge returns the profiles with those of override layered on top: keys set by an override
+// profile replace the same keys of the profile of that name, and new names are added
+func (p ScanProfiles) Merge(override ScanProfiles) ScanProfiles {
+	merged := make(ScanProfiles, len(p)+len(override))
+	for _, layer := range []ScanProfiles{p, override} {
+		for name, profile := range layer {
+			if merged[name] == nil {
+				merged[name] = make(ScanProfile, len(profile))
+			}
+			for task, config := range profile {
+				taskConfig := maps.Clone(merged[name][task])
+				if taskConfig == nil {
+					taskConfig = make(map[string]any, len(config))
+				}
+				maps.Copy(taskConfig, config)
+				merged[name][task] = taskConfig
+			}
+		}
+	}
+	return merged
+}
+
+// Apply returns the config of a task of the given type with the keys of the named profile it
+// does not set itself filled in. Task types the profile does not mention keep their config.
+func (p ScanProfiles) Apply(name string, task Task, config map[string]any) (map[string]any, error) {
+	profile, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scan profile %q", name)
+	}
+	if len(profile[task]) == 0 {
+		return config, nil
+	}
+	applied := maps.Clone(profile[task])
+	maps.Copy(applied, config)
+	return applied, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultScanProfilesParse(t *testing.T) {
+	for _, name := range DefaultScanProfiles.Names() {
+		for task, config := range DefaultScanProfiles[name] {
+			if _, err := ParseTaskConfig(task, config); err != nil {
+				t.Errorf("profile %s, %s: %v", name, task, err)
+			}
+		}
+	}
+}
+
+func TestScanProfilesApply(t *testing.T) {
+	profiles := DefaultScanProfiles.Merge(ScanProfiles{
+		"deep":   {TaskNaabu: {"rate_limit": 500}},
+		"custom": {TaskNuclei: {"custom_templates": "web"}},
+	})
+
+	config, err := profiles.Apply("deep", TaskNaabu, map[string]any{"top_ports": "1000"})
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if config["top_ports"] != "1000" || config["rate_limit"] != 500 || config["pre_filter"] != true {
+		t.Errorf("Apply() = %v, want the task's top_ports over the merged profile", config)
+	}
+	if DefaultScanProfiles["deep"][TaskNaabu]["rate_limit"] != nil {
+		t.Error("Merge() changed the default profiles")
+	}
+
+	// Task types the profile does not mention keep their config
+	config, err = profiles.Apply("custom", TaskHttpx, map[string]any{"tls_fingerprint": "go"})
+	if err != nil || len(config) != 1 {
+		t.Errorf("Apply() of httpx = %v, %v, want the task's config", config, err)
+	}
+
+	if _, err := profiles.Apply("missing", TaskNaabu, nil); err == nil {
+		t.Error("Apply() of an unknown profile succeeded")
+	}
+}
+
+func TestParseScanProfiles(t *testing.T) {
+	profiles, err := ParseScanProfiles([]byte(`{"quick": {"port_scan": {"top_ports": "1000"}}}`))
+	if err != nil || profiles["quick"][TaskNaabu]["top_ports"] != "1000" {
+		t.Errorf("ParseScanProfiles() = %v, %v", profiles, err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{"bad name", `{"Deep Scan": {}}`, "invalid scan profile name"},
+		{"unknown task", `{"deep": {"whois": {}}}`, "unknown task type"},
+		{"bad key", `{"deep": {"port_scan": {"top_port": "100"}}}`, "unrecognized config keys: top_port"},
+		{"bad value", `{"deep": {"port_scan": {"top_ports": "50"}}}`, "top_ports must be one of"},
+		{"not json", `quick`, "invalid scan profiles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseScanProfiles([]byte(tt.spec)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseScanProfiles() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfiguredScanProfiles(t *testing.T) {
+	profiles, err := ConfiguredScanProfiles(`{"standard": {"port_scan": {"rate_limit": 100}}}`)
+	if err != nil {
+		t.Fatalf("ConfiguredScanProfiles() error = %v", err)
+	}
+	if profiles["standard"][TaskNaabu]["top_ports"] != "1000" || profiles["standard"][TaskNaabu]["rate_limit"] != 100.0 {
+		t.Errorf("standard port_scan = %v, want the default top_ports and the configured rate_limit", profiles["standard"][TaskNaabu])
+	}
+	if len(profiles["quick"]) == 0 {
+		t.Error("ConfiguredScanProfiles() dropped the default quick profile")
+	}
+}
@@ -0,0 +1,472 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	// The generated package registers the result messages ResultToProto creates
+	_ "github.com/allsafeASM/api/internal/models/resultpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+//go:generate go test -run TestResultProto -update-proto .
+
+const (
+	// ResultProtoPackage is the protobuf package of the result messages
+	ResultProtoPackage = "allsafe.results.v1"
+	// ResultProtoFile is the name of the protobuf file of the result messages
+	ResultProtoFile = "results.proto"
+	// resultProtoGoPackage is the Go package the result messages are generated in
+	resultProtoGoPackage = "github.com/allsafeASM/api/internal/models/resultpb"
+
+	timestampProto = "google/protobuf/timestamp.proto"
+	structProto    = "google/protobuf/struct.proto"
+)
+
+// protoFieldName matches the JSON names that are valid protobuf field names as they are, e.g. "cve_ids" or "CNAME"
+var protoFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ResultToProto converts result data to its protobuf message. The message has the fields of the
+// result's JSON under the same names, but protojson does not write the same JSON as encoding/json:
+// it quotes 64-bit integers and leaves out fields with zero values.
+func ResultToProto(result ScannerResult) (proto.Message, error) {
+	messageType, err := resultMessageType(reflect.TypeOf(result))
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %T: %w", result, err)
+	}
+	message := messageType.New().Interface()
+	if err := protojson.Unmarshal(data, message); err != nil {
+		return nil, fmt.Errorf("failed to convert %T to protobuf: %w", result, err)
+	}
+	return message, nil
+}
+
+// ResultFromProto converts the protobuf message of a task's result data back to the result
+func ResultFromProto(task Task, message proto.Message) (ScannerResult, error) {
+	result, ok := resultTypes[task]
+	if !ok {
+		return nil, fmt.Errorf("no result message for task %s", task)
+	}
+	t := reflect.TypeOf(result)
+	if name := message.ProtoReflect().Descriptor().Name(); string(name) != t.Name() {
+		return nil, fmt.Errorf("%s is not the result message of task %s", name, task)
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", t.Name(), err)
+	}
+	// protojson writes 64-bit integers as strings, which encoding/json only reads as numbers
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", t.Name(), err)
+	}
+	if data, err = json.Marshal(jsonIntegers(value, t)); err != nil {
+		return nil, err
+	}
+
+	converted := reflect.New(t)
+	if err := json.Unmarshal(data, converted.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to convert %s from protobuf: %w", t.Name(), err)
+	}
+	return converted.Elem().Interface().(ScannerResult), nil
+}
+
+// resultMessageType returns the generated message type of a result type
+func resultMessageType(t reflect.Type) (protoreflect.MessageType, error) {
+	name := protoreflect.FullName(ResultProtoPackage + "." + t.Name())
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("no result message for %s: %w", t, err)
+	}
+	return messageType, nil
+}
+
+// jsonIntegers turns the strings of a decoded JSON value that t holds integers in into numbers
+func jsonIntegers(value any, t reflect.Type) any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonIntegers(value, t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s, ok := value.(string); ok {
+			return json.Number(s)
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := value.([]any); ok {
+			for i := range items {
+				items[i] = jsonIntegers(items[i], t.Elem())
+			}
+		}
+	case reflect.Map:
+		if entries, ok := value.(map[string]any); ok {
+			for key := range entries {
+				entries[key] = jsonIntegers(entries[key], t.Elem())
+			}
+		}
+	case reflect.Struct:
+		if entries, ok := value.(map[string]any); ok && t != timeType {
+			for _, field := range jsonFields(t) {
+				if entry, ok := entries[field.name]; ok {
+					entries[field.name] = jsonIntegers(entry, field.Type)
+				}
+			}
+		}
+	}
+	return value
+}
+
+// jsonField is a struct field encoding/json writes, under its JSON name
+type jsonField struct {
+	reflect.StructField
+	name string
+}
+
+// jsonFields returns the fields of a struct encoding/json writes, in declaration order
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{StructField: field, name: name})
+	}
+	return fields
+}
+
+// protoBuilder builds the protobuf messages of the result models. Fields and messages keep the
+// numbers of the previous version of the file, new fields get the next free numbers, and the
+// numbers and names of removed fields are reserved, so encoded results stay readable.
+type protoBuilder struct {
+	previous protoreflect.FileDescriptor // nil before the first version
+	messages []*descriptorpb.DescriptorProto
+	built    map[reflect.Type]bool
+	imports  map[string]bool
+}
+
+// buildResultProto returns the protobuf file of the result messages, numbered after the previous version
+func buildResultProto(previous protoreflect.FileDescriptor) (*descriptorpb.FileDescriptorProto, error) {
+	b := &protoBuilder{previous: previous, built: make(map[reflect.Type]bool), imports: make(map[string]bool)}
+
+	tasks := make([]Task, 0, len(resultTypes))
+	for task := range resultTypes {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i] < tasks[j] })
+	for _, task := range tasks {
+		if err := b.message(reflect.TypeOf(resultTypes[task])); err != nil {
+			return nil, err
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String(ResultProtoFile),
+		Package:     proto.String(ResultProtoPackage),
+		Syntax:      proto.String("proto3"),
+		MessageType: b.messages,
+		Options:     &descriptorpb.FileOptions{GoPackage: proto.String(resultProtoGoPackage)},
+	}
+	for _, dependency := range []string{structProto, timestampProto} {
+		if b.imports[dependency] {
+			file.Dependency = append(file.Dependency, dependency)
+		}
+	}
+	return file, nil
+}
+
+// message adds the message of a struct type and of the structs its fields hold
+func (b *protoBuilder) message(t reflect.Type) error {
+	if b.built[t] {
+		return nil
+	}
+	b.built[t] = true
+
+	message := &descriptorpb.DescriptorProto{Name: proto.String(t.Name())}
+	b.messages = append(b.messages, message)
+
+	var previous protoreflect.MessageDescriptor
+	if b.previous != nil {
+		previous = b.previous.Messages().ByName(protoreflect.Name(t.Name()))
+	}
+	next := int32(1)
+	if previous != nil {
+		for i := 0; i < previous.Fields().Len(); i++ {
+			next = max(next, int32(previous.Fields().Get(i).Number())+1)
+		}
+		for i := 0; i < previous.ReservedRanges().Len(); i++ {
+			next = max(next, int32(previous.ReservedRanges().Get(i)[1]))
+		}
+	}
+
+	current := make(map[string]bool)
+	for _, field := range jsonFields(t) {
+		if !protoFieldName.MatchString(field.name) {
+			return fmt.Errorf("%s.%s: JSON name %q is not a valid protobuf field name", t.Name(), field.Name, field.name)
+		}
+		current[field.name] = true
+
+		descriptor := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(field.name),
+			JsonName: proto.String(field.name),
+		}
+		if previousField := previousFieldByName(previous, field.name); previousField != nil {
+			descriptor.Number = proto.Int32(int32(previousField.Number()))
+		} else {
+			descriptor.Number = proto.Int32(next)
+			next++
+		}
+		if err := b.fieldType(message, descriptor, field.Type); err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		message.Field = append(message.Field, descriptor)
+	}
+
+	// Removed fields keep their numbers and names reserved
+	if previous != nil {
+		for i := 0; i < previous.ReservedRanges().Len(); i++ {
+			r := previous.ReservedRanges().Get(i)
+			message.ReservedRange = append(message.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+				Start: proto.Int32(int32(r[0])), End: proto.Int32(int32(r[1])),
+			})
+		}
+		for i := 0; i < previous.ReservedNames().Len(); i++ {
+			message.ReservedName = append(message.ReservedName, string(previous.ReservedNames().Get(i)))
+		}
+		for i := 0; i < previous.Fields().Len(); i++ {
+			field := previous.Fields().Get(i)
+			if !current[string(field.Name())] {
+				message.ReservedRange = append(message.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+					Start: proto.Int32(int32(field.Number())), End: proto.Int32(int32(field.Number()) + 1),
+				})
+				message.ReservedName = append(message.ReservedName, string(field.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// previousFieldByName returns a field of the previous version of a message, or nil
+func previousFieldByName(previous protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	if previous == nil {
+		return nil
+	}
+	return previous.Fields().ByName(protoreflect.Name(name))
+}
+
+// fieldType sets the type of a field from the Go type it holds
+func (b *protoBuilder) fieldType(message *descriptorpb.DescriptorProto, field *descriptorpb.FieldDescriptorProto, t reflect.Type) error {
+	switch {
+	case t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8:
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return b.valueType(field, t.Elem())
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("map keys of %s are not strings", t)
+		}
+		entry := &descriptorpb.DescriptorProto{
+			Name:    proto.String(mapEntryName(field.GetName())),
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("key"), JsonName: proto.String("key"), Number: proto.Int32(1),
+					Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("value"), JsonName: proto.String("value"), Number: proto.Int32(2)},
+			},
+		}
+		for _, f := range entry.Field {
+			f.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		}
+		if err := b.valueType(entry.Field[1], t.Elem()); err != nil {
+			return err
+		}
+		message.NestedType = append(message.NestedType, entry)
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String("." + ResultProtoPackage + "." + message.GetName() + "." + entry.GetName())
+		return nil
+	case t.Kind() == reflect.Pointer && t.Elem().Kind() != reflect.Struct:
+		// Optional scalars tell an unset value from the zero value, as encoding/json does with nil
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		field.Proto3Optional = proto.Bool(true)
+		field.OneofIndex = proto.Int32(int32(len(message.OneofDecl)))
+		message.OneofDecl = append(message.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + field.GetName())})
+		return b.valueType(field, t.Elem())
+	default:
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		return b.valueType(field, t)
+	}
+}
+
+// valueType sets the type of a singular value: a scalar, a message, or a well-known type for
+// values protobuf cannot nest, such as lists of lists
+func (b *protoBuilder) valueType(field *descriptorpb.FieldDescriptorProto, t reflect.Type) error {
+	if t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct {
+		t = t.Elem()
+	}
+
+	scalars := map[reflect.Kind]descriptorpb.FieldDescriptorProto_Type{
+		reflect.Bool:    descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+		reflect.String:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		reflect.Int:     descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		reflect.Int8:    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		reflect.Int16:   descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		reflect.Int32:   descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		reflect.Int64:   descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		reflect.Uint:    descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		reflect.Uint8:   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		reflect.Uint16:  descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		reflect.Uint32:  descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		reflect.Uint64:  descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		reflect.Float32: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+		reflect.Float64: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	}
+	if scalar, ok := scalars[t.Kind()]; ok {
+		field.Type = scalar.Enum()
+		return nil
+	}
+
+	var typeName string
+	switch {
+	case t.Kind() == reflect.Interface || t == rawMessageType:
+		typeName = ".google.protobuf.Value"
+		b.imports[structProto] = true
+	case t == timeType:
+		typeName = ".google.protobuf.Timestamp"
+		b.imports[timestampProto] = true
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_BYTES.Enum()
+		return nil
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		// A list inside a list or map is written as a plain JSON array
+		typeName = ".google.protobuf.ListValue"
+		b.imports[structProto] = true
+	case t.Kind() == reflect.Map:
+		typeName = ".google.protobuf.Struct"
+		b.imports[structProto] = true
+	case t.Kind() == reflect.Struct:
+		if err := b.message(t); err != nil {
+			return err
+		}
+		typeName = "." + ResultProtoPackage + "." + t.Name()
+	default:
+		return fmt.Errorf("no protobuf type for %s", t)
+	}
+	field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	field.TypeName = proto.String(typeName)
+	return nil
+}
+
+// mapEntryName returns the name protoc gives the entry message of a map field, e.g. SubdomainSourcesEntry
+func mapEntryName(fieldName string) string {
+	var name strings.Builder
+	for _, part := range strings.Split(fieldName, "_") {
+		if part != "" {
+			name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return name.String() + "Entry"
+}
+
+// resultProtoDependencies returns the files the result messages import, to resolve them with
+func resultProtoDependencies() []protoreflect.FileDescriptor {
+	return []protoreflect.FileDescriptor{structpb.File_google_protobuf_struct_proto, timestamppb.File_google_protobuf_timestamp_proto}
+}
+
+// newResultProtoFile checks the protobuf file of the result messages and resolves its types
+func newResultProtoFile(file *descriptorpb.FileDescriptorProto) (protoreflect.FileDescriptor, error) {
+	files := new(protoregistry.Files)
+	for _, dependency := range resultProtoDependencies() {
+		if err := files.RegisterFile(dependency); err != nil {
+			return nil, err
+		}
+	}
+	return protodesc.NewFile(file, files)
+}
+
+// formatResultProto writes the protobuf file of the result messages in the protobuf language
+func formatResultProto(file *descriptorpb.FileDescriptorProto) string {
+	var out strings.Builder
+	out.WriteString("// Code generated by go generate ./internal/models. DO NOT EDIT.\n")
+	out.WriteString("//\n// The result data of every task type, generated from the Go result structs. Field names match the\n")
+	out.WriteString("// result JSON, but protojson quotes 64-bit integers and leaves out zero values, so its JSON is not\n")
+	out.WriteString("// the JSON the worker stores. Removed fields are reserved, never reused.\n\n")
+	fmt.Fprintf(&out, "syntax = %q;\n\npackage %s;\n\n", file.GetSyntax(), file.GetPackage())
+	for _, dependency := range file.Dependency {
+		fmt.Fprintf(&out, "import %q;\n", dependency)
+	}
+	if len(file.Dependency) > 0 {
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(&out, "option go_package = %q;\n", file.GetOptions().GetGoPackage())
+
+	for _, message := range file.MessageType {
+		entries := make(map[string]*descriptorpb.DescriptorProto)
+		for _, entry := range message.NestedType {
+			entries[entry.GetName()] = entry
+		}
+
+		fmt.Fprintf(&out, "\nmessage %s {\n", message.GetName())
+		for _, field := range message.Field {
+			var fieldType string
+			if entry, ok := entries[lastName(field.GetTypeName())]; ok && entry.GetOptions().GetMapEntry() {
+				fieldType = fmt.Sprintf("map<%s, %s>", protoTypeName(entry.Field[0]), protoTypeName(entry.Field[1]))
+			} else {
+				fieldType = protoTypeName(field)
+				if field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+					fieldType = "repeated " + fieldType
+				} else if field.GetProto3Optional() {
+					fieldType = "optional " + fieldType
+				}
+			}
+			fmt.Fprintf(&out, "  %s %s = %d [json_name = %q];\n", fieldType, field.GetName(), field.GetNumber(), field.GetJsonName())
+		}
+		for _, r := range message.ReservedRange {
+			if r.GetEnd() == r.GetStart()+1 {
+				fmt.Fprintf(&out, "  reserved %d;\n", r.GetStart())
+			} else {
+				fmt.Fprintf(&out, "  reserved %d to %d;\n", r.GetStart(), r.GetEnd()-1)
+			}
+		}
+		for _, name := range message.ReservedName {
+			fmt.Fprintf(&out, "  reserved %q;\n", name)
+		}
+		out.WriteString("}\n")
+	}
+	return out.String()
+}
+
+// protoTypeName returns the name of the type of a field in the protobuf language
+func protoTypeName(field *descriptorpb.FieldDescriptorProto) string {
+	if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+		return strings.TrimPrefix(strings.TrimPrefix(field.GetTypeName(), "."+ResultProtoPackage+"."), ".")
+	}
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+// lastName returns the last element of a dotted type name
+func lastName(typeName string) string {
+	return typeName[strings.LastIndex(typeName, ".")+1:]
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models/resultpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var updateProto = flag.Bool("update-proto", false, "rewrite schemas/results.proto and the generated resultpb package")
+
+// resultProtoGoDir holds the Go code generated from the result messages
+const resultProtoGoDir = "resultpb"
+
+func TestResultProto(t *testing.T) {
+	// Fields keep the numbers of the messages the Go code was last generated from
+	file, err := buildResultProto(resultpb.File_results_proto)
+	if err != nil {
+		t.Fatalf("buildResultProto() error = %v", err)
+	}
+	if _, err := newResultProtoFile(file); err != nil {
+		t.Fatalf("Invalid result messages: %v", err)
+	}
+	generated := []byte(formatResultProto(file))
+	path := filepath.Join(schemaDir, ResultProtoFile)
+
+	if *updateProto {
+		if err := os.WriteFile(path, generated, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		generateResultProtoGo(t, file)
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Missing %s, run go generate ./internal/models: %v", path, err)
+	}
+	if !bytes.Equal(golden, generated) {
+		t.Errorf("The result models no longer match %s. If the change is intended and the orchestrator and UI "+
+			"can handle it, run go generate ./internal/models and commit the result.\n\ngot:\n%s", path, generated)
+	}
+	if compiled := protodesc.ToFileDescriptorProto(resultpb.File_results_proto); !proto.Equal(compiled, file) {
+		t.Errorf("The resultpb package no longer matches the result models, run go generate ./internal/models")
+	}
+}
+
+// generateResultProtoGo runs protoc-gen-go on the result messages, in place of protoc
+func generateResultProtoGo(t *testing.T, file *descriptorpb.FileDescriptorProto) {
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{file.GetName()},
+		Parameter:      proto.String("paths=source_relative"),
+	}
+	for _, dependency := range resultProtoDependencies() {
+		request.ProtoFile = append(request.ProtoFile, protodesc.ToFileDescriptorProto(dependency))
+	}
+	request.ProtoFile = append(request.ProtoFile, file)
+	input, err := proto.Marshal(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "google.golang.org/protobuf/cmd/protoc-gen-go")
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("protoc-gen-go failed: %v", err)
+	}
+	var response pluginpb.CodeGeneratorResponse
+	if err := proto.Unmarshal(output, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != nil {
+		t.Fatalf("protoc-gen-go failed: %s", response.GetError())
+	}
+	for _, generated := range response.File {
+		if err := os.MkdirAll(resultProtoGoDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(resultProtoGoDir, generated.GetName()), []byte(generated.GetContent()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResultProtoNumbering(t *testing.T) {
+	// A previous version where CloudDNSRecord had a field that was since removed and no zone
+	previous := protodesc.ToFileDescriptorProto(resultpb.File_results_proto)
+	for _, message := range previous.MessageType {
+		if message.GetName() != "CloudDNSRecord" {
+			continue
+		}
+		var fields []*descriptorpb.FieldDescriptorProto
+		for _, field := range message.Field {
+			if field.GetName() != "zone" {
+				fields = append(fields, field)
+			}
+		}
+		message.Field = append(fields, &descriptorpb.FieldDescriptorProto{
+			Name: proto.String("weight"), JsonName: proto.String("weight"), Number: proto.Int32(20),
+			Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum(),
+		})
+	}
+	previousFile, err := newResultProtoFile(previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := buildResultProto(previousFile)
+	if err != nil {
+		t.Fatalf("buildResultProto() error = %v", err)
+	}
+	formatted := formatResultProto(file)
+	for _, want := range []string{
+		`string name = 1 [json_name = "name"];`,
+		`string zone = 21 [json_name = "zone"];`,
+		"  reserved 20;\n  reserved \"weight\";\n}",
+	} {
+		if !bytes.Contains([]byte(formatted), []byte(want)) {
+			t.Errorf("formatResultProto() is missing %s:\n%s", want, formatted)
+		}
+	}
+	if _, err := newResultProtoFile(file); err != nil {
+		t.Errorf("Renumbered result messages are invalid: %v", err)
+	}
+}
+
+func TestResultProtoRoundTrip(t *testing.T) {
+	hostsfile := false
+	results := map[Task]ScannerResult{
+		TaskSubfinder: SubfinderResult{
+			Domain:           "example.com",
+			Subdomains:       []string{"api.example.com", "www.example.com"},
+			Sources:          map[string]PassiveSourceStats{"crtsh": {Results: 2}},
+			SubdomainSources: map[string][]string{"api.example.com": {"crtsh", "input"}},
+		},
+		TaskDNSResolve: DNSXResult{
+			Domain:   "example.com",
+			Records:  map[string]ResolutionInfo{"www.example.com": {Status: "NOERROR", A: []string{"93.184.216.34"}, RTTMs: 12}},
+			Metadata: &DNSXMetadata{Queries: 3, StatusCounts: map[DNSStatus]int{"NOERROR": 3}, Settings: &DNSSettings{Retries: 2, Hostsfile: &hostsfile}},
+		},
+		TaskNaabu: NaabuResult{
+			Domain: "example.com",
+			Ports:  map[string][]PortInfo{"93.184.216.34": {{Port: 443, Protocol: "tcp", Service: "https"}}},
+		},
+		TaskNuclei: NucleiResult{
+			Domain: "example.com",
+			Vulnerabilities: []NucleiVulnerability{{TemplateID: "git-config", Host: "https://example.com",
+				MatchedAt: "https://example.com/.git/config", Severity: "medium", EPSSScore: 0.25, Verification: FindingVerified}},
+			Partial:        true,
+			TemplatePolicy: &TemplatePolicyReport{Policy: TemplatePolicy{DenyTags: []string{"dos"}}, Templates: 9000},
+		},
+		TaskScopeExpansion: ScopeExpansionResult{
+			Domain: "example.com",
+			Suggestions: []ScopeSuggestion{{Apex: "example.net", Evidence: []ScopeEvidence{{Kind: "certificate", Detail: "www.example.com"}},
+				FirstSuggested: time.Date(2025, 3, 1, 12, 0, 0, 500, time.UTC)}},
+			HostsProbed: 4,
+		},
+	}
+
+	for task, result := range results {
+		t.Run(string(task), func(t *testing.T) {
+			message, err := ResultToProto(result)
+			if err != nil {
+				t.Fatalf("ResultToProto() error = %v", err)
+			}
+			encoded, err := proto.Marshal(message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded := message.ProtoReflect().New().Interface()
+			if err := proto.Unmarshal(encoded, decoded); err != nil {
+				t.Fatal(err)
+			}
+
+			converted, err := ResultFromProto(task, decoded)
+			if err != nil {
+				t.Fatalf("ResultFromProto() error = %v", err)
+			}
+			if !reflect.DeepEqual(converted, result) {
+				t.Errorf("ResultFromProto() = %+v, want %+v", converted, result)
+			}
+		})
+	}
+
+	if _, err := ResultFromProto(TaskNaabu, &resultpb.NucleiResult{}); err == nil {
+		t.Error("ResultFromProto() accepted the message of another task")
+	}
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ScanQualityBlobPath returns the blob path of the quality gate record of a scan. Like the retry
+// count, it lives outside the per-domain prefix so every task of the scan shares it.
+func ScanQualityBlobPath(tenantID string, scanID int) string {
+	path := fmt.Sprintf("control/quality/scan-%d.json", scanID)
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// Quality gates a stage's result is checked against
+const (
+	QualityGateAliveRatio   = "alive_ratio"    // Share of the hosts httpx probed that answered
+	QualityGateDNSErrorRate = "dns_error_rate" // Share of the names DNSX queried that failed transiently
+)
+
+// QualityGates are the thresholds that mark a scan degraded, e.g. when a blocked egress IP makes
+// most hosts look dead. Zero thresholds turn their gate off.
+type QualityGates struct {
+	MinAliveRatio   float64       // Fraction of probed hosts that must answer httpx
+	MaxDNSErrorRate float64       // Fraction of DNS queries that may fail with SERVFAIL, REFUSED or a timeout
+	MinSample       int           // Hosts or names a result needs before it is judged
+	HoldTasks       []Task        // Tasks not started while the scan is degraded and not reviewed
+	MaxHold         time.Duration // How long a held task waits for a review before it fails
+}
+
+// Enabled reports whether any gate is on
+func (g QualityGates) Enabled() bool {
+	return g.MinAliveRatio > 0 || g.MaxDNSErrorRate > 0
+}
+
+// Holds reports whether a task waits for review while its scan is degraded
+func (g QualityGates) Holds(task Task) bool {
+	return slices.Contains(g.HoldTasks, task)
+}
+
+// QualityCheck is the outcome of one gate on a stage's result
+type QualityCheck struct {
+	Gate      string    `json:"gate"`
+	Task      Task      `json:"task"`
+	Domain    string    `json:"domain"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Sample    int       `json:"sample"` // Hosts probed or names queried
+	Passed    bool      `json:"passed"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// String describes the check, e.g. "alive_ratio of httpx for example.com was 0.12, below 0.50 (400 hosts)"
+func (c QualityCheck) String() string {
+	comparison, unit := "below", "hosts"
+	if c.Gate == QualityGateDNSErrorRate {
+		comparison, unit = "above", "names"
+	}
+	if c.Passed {
+		comparison = "within"
+	}
+	return fmt.Sprintf("%s of %s for %s was %.2f, %s %.2f (%d %s)", c.Gate, c.Task, c.Domain, c.Value, comparison, c.Threshold, c.Sample, unit)
+}
+
+// Check judges a stage's result, returning nil when no gate applies to it or the result is too
+// small to judge
+func (g QualityGates) Check(task Task, result ScannerResult, now time.Time) *QualityCheck {
+	switch r := result.(type) {
+	case HttpxResult:
+		probed := r.Probed
+		if g.MinAliveRatio <= 0 || probed == 0 || probed < g.MinSample {
+			return nil
+		}
+		ratio := float64(min(r.Answered, probed)) / float64(probed)
+		return &QualityCheck{Gate: QualityGateAliveRatio, Task: task, Domain: r.Domain, Value: ratio, Threshold: g.MinAliveRatio,
+			Sample: probed, Passed: ratio >= g.MinAliveRatio, CheckedAt: now}
+	case DNSXResult:
+		if g.MaxDNSErrorRate <= 0 || r.Metadata == nil || r.Metadata.Queries == 0 || r.Metadata.Queries < g.MinSample {
+			return nil
+		}
+		failed := 0
+		for _, status := range []DNSStatus{DNSStatusServFail, DNSStatusRefused, DNSStatusTimeout, DNSStatusError} {
+			failed += r.Metadata.StatusCounts[status]
+		}
+		rate := float64(failed) / float64(r.Metadata.Queries)
+		return &QualityCheck{Gate: QualityGateDNSErrorRate, Task: task, Domain: r.Domain, Value: rate, Threshold: g.MaxDNSErrorRate,
+			Sample: r.Metadata.Queries, Passed: rate <= g.MaxDNSErrorRate, CheckedAt: now}
+	}
+	return nil
+}
+
+// ScanQuality holds the latest quality checks of a scan and whether it is degraded
+type ScanQuality struct {
+	ScanID     int            `json:"scan_id"`
+	TenantID   string         `json:"tenant_id,omitempty"`
+	Checks     []QualityCheck `json:"checks"`                // Latest check per gate, task and domain
+	DegradedAt time.Time      `json:"degraded_at,omitzero"`  // When a check last failed after the scan was healthy or reviewed
+	ReviewedAt time.Time      `json:"reviewed_at,omitzero"`  // When an operator last released the scan
+	ReviewedBy string         `json:"reviewed_by,omitempty"` // Who released the scan
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// Degraded reports whether a check failed since the scan was last reviewed
+func (q *ScanQuality) Degraded() bool {
+	return q != nil && !q.DegradedAt.IsZero() && q.ReviewedAt.Before(q.DegradedAt)
+}
+
+// Record keeps a check, replacing the previous check of the same gate, task and domain, and
+// reports whether it turned the scan degraded. Only the check that does so reports it, so
+// operators are notified once per degradation.
+func (q *ScanQuality) Record(check QualityCheck) bool {
+	q.Checks = slices.DeleteFunc(q.Checks, func(c QualityCheck) bool {
+		return c.Gate == check.Gate && c.Task == check.Task && c.Domain == check.Domain
+	})
+	q.Checks = append(q.Checks, check)
+	q.UpdatedAt = check.CheckedAt
+	if check.Passed || q.Degraded() {
+		return false
+	}
+	q.DegradedAt = check.CheckedAt
+	return true
+}
+
+// HoldExpired reports whether a task held for review waited longer than the gates allow
+func (g QualityGates) HoldExpired(quality *ScanQuality, now time.Time) bool {
+	return g.MaxHold > 0 && quality.Degraded() && now.Sub(quality.DegradedAt) > g.MaxHold
+}
+
+// Review releases a degraded scan, so the tasks held for review start
+func (q *ScanQuality) Review(reviewer string, now time.Time) {
+	q.ReviewedAt = now
+	q.ReviewedBy = reviewer
+	q.UpdatedAt = now
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQualityGates_Check(t *testing.T) {
+	gates := QualityGates{MinAliveRatio: 0.5, MaxDNSErrorRate: 0.2, MinSample: 4}
+	now := time.Now().UTC()
+
+	httpx := HttpxResult{Domain: "example.com", Probed: 10, Answered: 2}
+	check := gates.Check(TaskHttpx, httpx, now)
+	if check == nil || check.Gate != QualityGateAliveRatio || check.Value != 0.2 || check.Passed {
+		t.Fatalf("Check(httpx) = %+v, want a failed alive ratio of 0.2", check)
+	}
+	httpx.Probed = 3
+	if check := gates.Check(TaskHttpx, httpx, now); check != nil {
+		t.Errorf("Check(httpx) below the minimum sample = %+v, want nil", check)
+	}
+
+	dnsx := DNSXResult{Domain: "example.com", Metadata: &DNSXMetadata{Queries: 10, StatusCounts: map[DNSStatus]int{
+		DNSStatusResolved: 8, DNSStatusServFail: 1, DNSStatusTimeout: 1,
+	}}}
+	check = gates.Check(TaskDNSResolve, dnsx, now)
+	if check == nil || check.Gate != QualityGateDNSErrorRate || check.Value != 0.2 || !check.Passed {
+		t.Fatalf("Check(dnsx) = %+v, want a passed DNS error rate of 0.2", check)
+	}
+	if check := gates.Check(TaskDNSResolve, DNSXResult{Domain: "example.com"}, now); check != nil {
+		t.Errorf("Check(dnsx) without metadata = %+v, want nil", check)
+	}
+	if check := (QualityGates{MaxDNSErrorRate: 0.2}).Check(TaskHttpx, httpx, now); check != nil {
+		t.Errorf("Check(httpx) with the alive gate off = %+v, want nil", check)
+	}
+}
+
+func TestScanQuality_Record(t *testing.T) {
+	var quality ScanQuality
+	start := time.Now().UTC()
+	failed := QualityCheck{Gate: QualityGateAliveRatio, Task: TaskHttpx, Domain: "example.com", CheckedAt: start}
+
+	if quality.Record(QualityCheck{Gate: QualityGateAliveRatio, Task: TaskHttpx, Domain: "example.com", Passed: true, CheckedAt: start}) {
+		t.Error("Record() of a passed check reported a degradation")
+	}
+	if !quality.Record(failed) || !quality.Degraded() {
+		t.Fatal("Record() of a failed check did not degrade the scan")
+	}
+	if len(quality.Checks) != 1 {
+		t.Errorf("Record() kept %d checks, want the latest only", len(quality.Checks))
+	}
+	failed.CheckedAt = start.Add(time.Minute)
+	if quality.Record(failed) {
+		t.Error("Record() reported an already degraded scan again")
+	}
+
+	quality.Review("ops", start.Add(2*time.Minute))
+	if quality.Degraded() {
+		t.Fatal("Review() did not release the scan")
+	}
+	failed.CheckedAt = start.Add(3 * time.Minute)
+	if !quality.Record(failed) || !quality.Degraded() {
+		t.Error("Record() after a review did not degrade the scan again")
+	}
+	if (*ScanQuality)(nil).Degraded() {
+		t.Error("Degraded() of a missing record = true")
+	}
+
+	gates := QualityGates{MaxHold: time.Hour}
+	if gates.HoldExpired(&quality, failed.CheckedAt.Add(time.Hour)) {
+		t.Error("HoldExpired() within the maximum hold = true")
+	}
+	if !gates.HoldExpired(&quality, failed.CheckedAt.Add(2*time.Hour)) {
+		t.Error("HoldExpired() after the maximum hold = false")
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// QueueDepth is a snapshot of the message counts of the task queue
+type QueueDepth struct {
+	Queue      string    `json:"queue"`
+	Active     int       `json:"active"`      // Messages waiting to be received
+	Scheduled  int       `json:"scheduled"`   // Messages scheduled for a later enqueue time
+	DeadLetter int       `json:"dead_letter"` // Messages moved to the dead-letter subqueue
+	SampledAt  time.Time `json:"sampled_at"`
+}
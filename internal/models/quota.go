@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// QuotaStateBlobPath returns the blob path of an API key's quota state
+func QuotaStateBlobPath(key string) string {
+	return "control/quotas/" + key + ".json"
+}
+
+// QuotaState is the token bucket of an API key shared by all workers. Tokens refill continuously
+// so that a full bucket is regained over one quota period.
+type QuotaState struct {
+	Key       string    `json:"key"`
+	Limit     int       `json:"limit"`  // Requests allowed per period
+	Tokens    float64   `json:"tokens"` // Requests currently available
+	Used      int       `json:"used"`   // Requests consumed since the state was created
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "sort"
+
+// RecordsIndex locates the lines of a gzipped NDJSON records blob. The blob is written as a series
+// of gzip members of ChunkLines lines each, so a page can be read by decompressing from the start
+// of the member that holds its first line instead of from the start of the blob.
+type RecordsIndex struct {
+	Lines      int            `json:"lines"`
+	ChunkLines int            `json:"chunk_lines"`
+	Chunks     []RecordsChunk `json:"chunks"`
+}
+
+// RecordsChunk is one gzip member of a records blob
+type RecordsChunk struct {
+	Line   int   `json:"line"`   // Line number of the member's first line, from 0
+	Offset int64 `json:"offset"` // Byte offset of the member in the blob
+}
+
+// Locate returns the chunk holding a line, or false when the line is past the end
+func (i *RecordsIndex) Locate(line int) (RecordsChunk, bool) {
+	if line < 0 || line >= i.Lines || len(i.Chunks) == 0 {
+		return RecordsChunk{}, false
+	}
+	n := sort.Search(len(i.Chunks), func(n int) bool { return i.Chunks[n].Line > line })
+	if n == 0 {
+		return RecordsChunk{}, false
+	}
+	return i.Chunks[n-1], true
+}
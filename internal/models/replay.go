@@ -0,0 +1,63 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Outcomes of replaying a finding, recorded on it as its verification
+const (
+	FindingVerified    = "verified"    // The replay matched the finding again
+	FindingUnconfirmed = "unconfirmed" // The replay did not match it, so it may be a false positive
+	FindingUnreplayed  = "unreplayed"  // The replay could not run, e.g. as the scan was interrupted
+)
+
+// FindingReplay selects the nuclei findings that are replayed before they are reported
+type FindingReplay struct {
+	MinSeverity string        // Lowest severity replayed; empty replays nothing
+	Delay       time.Duration // Least time between finding and replaying, so a brief glitch has passed
+}
+
+// Selects reports whether a finding is replayed
+func (r FindingReplay) Selects(finding NucleiVulnerability) bool {
+	minRank := summarySeverityOrder[strings.ToLower(r.MinSeverity)]
+	return minRank > 0 && summarySeverityOrder[strings.ToLower(finding.Severity)] >= minRank
+}
+
+// SplitUnconfirmed separates the findings the replay did not confirm from the others, keeping
+// their order
+func SplitUnconfirmed(findings []NucleiVulnerability) (reported, unconfirmed []NucleiVulnerability) {
+	reported = make([]NucleiVulnerability, 0, len(findings))
+	for _, finding := range findings {
+		if finding.Verification == FindingUnconfirmed {
+			unconfirmed = append(unconfirmed, finding)
+		} else {
+			reported = append(reported, finding)
+		}
+	}
+	return reported, unconfirmed
+}
+
+// ReplayKey identifies a finding within the replay of its template against its host
+func ReplayKey(finding NucleiVulnerability) string {
+	return finding.TemplateID + "|" + finding.Host + "|" + finding.MatcherName
+}
+
+// ReplayTargets returns the template IDs and hosts to replay the findings with, sorted
+func ReplayTargets(findings []NucleiVulnerability) (templateIDs, hosts []string) {
+	seenIDs, seenHosts := make(map[string]bool), make(map[string]bool)
+	for _, finding := range findings {
+		if !seenIDs[finding.TemplateID] {
+			seenIDs[finding.TemplateID] = true
+			templateIDs = append(templateIDs, finding.TemplateID)
+		}
+		if !seenHosts[finding.Host] {
+			seenHosts[finding.Host] = true
+			hosts = append(hosts, finding.Host)
+		}
+	}
+	sort.Strings(templateIDs)
+	sort.Strings(hosts)
+	return templateIDs, hosts
+}
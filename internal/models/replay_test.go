@@ -0,0 +1,58 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindingReplaySelects(t *testing.T) {
+	tests := []struct {
+		minSeverity string
+		severity    string
+		want        bool
+	}{
+		{"high", "critical", true},
+		{"high", "High", true},
+		{"high", "medium", false},
+		{"high", "", false},
+		{"info", "info", true},
+		{"", "critical", false},
+	}
+	for _, tt := range tests {
+		replay := FindingReplay{MinSeverity: tt.minSeverity}
+		if got := replay.Selects(NucleiVulnerability{Severity: tt.severity}); got != tt.want {
+			t.Errorf("FindingReplay{%q}.Selects(%q) = %v, want %v", tt.minSeverity, tt.severity, got, tt.want)
+		}
+	}
+}
+
+func TestReplayTargets(t *testing.T) {
+	findings := []NucleiVulnerability{
+		{TemplateID: "git-config", Host: "https://b.example.com"},
+		{TemplateID: "CVE-2021-44228", Host: "https://a.example.com"},
+		{TemplateID: "git-config", Host: "https://a.example.com"},
+	}
+	ids, hosts := ReplayTargets(findings)
+	if !reflect.DeepEqual(ids, []string{"CVE-2021-44228", "git-config"}) {
+		t.Errorf("ReplayTargets() template IDs = %v", ids)
+	}
+	if !reflect.DeepEqual(hosts, []string{"https://a.example.com", "https://b.example.com"}) {
+		t.Errorf("ReplayTargets() hosts = %v", hosts)
+	}
+}
+
+func TestSplitUnconfirmed(t *testing.T) {
+	findings := []NucleiVulnerability{
+		{TemplateID: "git-config", Verification: FindingVerified},
+		{TemplateID: "CVE-2021-44228", Verification: FindingUnconfirmed},
+		{TemplateID: "tech-detect"},
+		{TemplateID: "exposed-panel", Verification: FindingUnreplayed},
+	}
+	reported, unconfirmed := SplitUnconfirmed(findings)
+	if len(reported) != 3 || reported[1].TemplateID != "tech-detect" {
+		t.Errorf("SplitUnconfirmed() reported %v", reported)
+	}
+	if len(unconfirmed) != 1 || unconfirmed[0].TemplateID != "CVE-2021-44228" {
+		t.Errorf("SplitUnconfirmed() unconfirmed %v", unconfirmed)
+	}
+}
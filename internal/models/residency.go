@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// sharedBlobFolders are the top-level folders of blobs stored without a tenant
+var sharedBlobFolders = []string{"control", "review", "incidents", "tickets", "inventory", "scope", "nuclei-templates", "inputs", "exports"}
+
+// TenantRegions maps tenants to the data region whose storage account keeps their blobs
+type TenantRegions map[string]string
+
+// ParseTenantRegions parses routing rules in the form "tenant=region,tenant=region". Tenants
+// without a rule are kept in the default region.
+func ParseTenantRegions(spec string) (TenantRegions, error) {
+	regions := make(TenantRegions)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, region, ok := strings.Cut(entry, "=")
+		tenant, region = strings.TrimSpace(tenant), strings.ToLower(strings.TrimSpace(region))
+		if !ok || tenant == "" || region == "" {
+			return nil, fmt.Errorf("invalid tenant region %q: expected tenant=region", entry)
+		}
+		// The first segment of a blob path names its tenant, so a tenant named like a shared
+		// folder would route that folder with it
+		if slices.Contains(sharedBlobFolders, tenant) || strings.Contains(tenant, "/") {
+			return nil, fmt.Errorf("invalid tenant region %q: %s cannot be routed", entry, tenant)
+		}
+		if previous, dup := regions[tenant]; dup && previous != region {
+			return nil, fmt.Errorf("tenant %s is routed to both %s and %s", tenant, previous, region)
+		}
+		regions[tenant] = region
+	}
+	return regions, nil
+}
+
+// Lookup returns the region of a tenant, or defaultRegion when it has no rule
+func (r TenantRegions) Lookup(tenantID, defaultRegion string) string {
+	if region, ok := r[tenantID]; ok && tenantID != "" {
+		return region
+	}
+	return defaultRegion
+}
+
+// IsTenantFolder reports whether a top-level folder may hold the blobs of a tenant. Shared folders
+// do not, nor do the scan folders of scans without a tenant, whose names hold a domain: tenant IDs
+// have no dots.
+func IsTenantFolder(name string) bool {
+	return name != "" && !slices.Contains(sharedBlobFolders, name) && !strings.Contains(name, ".")
+}
+
+// BlobPathTenant returns the tenant a blob path belongs to by routing, which is its first segment
+func BlobPathTenant(blobPath string) string {
+	tenant, _, _ := strings.Cut(strings.TrimPrefix(blobPath, "/"), "/")
+	return tenant
+}
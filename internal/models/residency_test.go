@@ -0,0 +1,63 @@
+package models
+
+import "testing"
+
+// TestParseTenantRegions tests parsing and tenant lookup of data regions
+func TestParseTenantRegions(t *testing.T) {
+	regions, err := ParseTenantRegions("acme=EU, globex=us,acme=eu")
+	if err != nil {
+		t.Fatalf("ParseTenantRegions failed: %v", err)
+	}
+	tests := map[string]string{
+		"acme":    "eu",
+		"globex":  "us",
+		"initech": "default",
+		"":        "default",
+	}
+	for tenant, expected := range tests {
+		if got := regions.Lookup(tenant, "default"); got != expected {
+			t.Errorf("Lookup(%q) = %q, expected %q", tenant, got, expected)
+		}
+	}
+
+	for _, spec := range []string{"acme=eu,acme=us", "=eu", "acme", "scope=eu", "acme/scans=eu"} {
+		if _, err := ParseTenantRegions(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+// TestBlobPathTenant tests that the tenant is taken from the first path segment
+func TestBlobPathTenant(t *testing.T) {
+	tests := map[string]string{
+		"acme/scans/1/subfinder/example.com/out.json": "acme",
+		"/acme/scans/1":       "acme",
+		"control/quotas.json": "control",
+		"":                    "",
+	}
+	for blobPath, expected := range tests {
+		if got := BlobPathTenant(blobPath); got != expected {
+			t.Errorf("BlobPathTenant(%q) = %q, expected %q", blobPath, got, expected)
+		}
+	}
+}
+
+// TestIsOutboxBlobPath tests that outbox entries are recognized under every tenant's folder
+func TestIsOutboxBlobPath(t *testing.T) {
+	tests := map[string]bool{
+		OutboxBlobPath("", "abc"):              true,
+		OutboxBlobPath("acme", "abc"):          true,
+		"acme/control/outbox/abc.txt":          false,
+		"acme/control/outbox/old/abc.json":     false,
+		"a/b/control/outbox/abc.json":          false,
+		"example.com-7/control/quality/x.json": false,
+	}
+	for blobPath, expected := range tests {
+		if got := IsOutboxBlobPath(blobPath); got != expected {
+			t.Errorf("IsOutboxBlobPath(%q) = %t, expected %t", blobPath, got, expected)
+		}
+	}
+	if IsTenantFolder("control") || IsTenantFolder("example.com-7") || !IsTenantFolder("acme") {
+		t.Error("IsTenantFolder() accepted a shared or scan folder, or rejected a tenant")
+	}
+}
@@ -0,0 +1,2532 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: results.proto
+
+package resultpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CloudDNSResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output        []*CloudDNSRecord      `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty"`
+	Zones         []string               `protobuf:"bytes,3,rep,name=zones,proto3" json:"zones,omitempty"`
+	Partial       bool                   `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloudDNSResult) Reset() {
+	*x = CloudDNSResult{}
+	mi := &file_results_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloudDNSResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloudDNSResult) ProtoMessage() {}
+
+func (x *CloudDNSResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloudDNSResult.ProtoReflect.Descriptor instead.
+func (*CloudDNSResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CloudDNSResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *CloudDNSResult) GetOutput() []*CloudDNSRecord {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *CloudDNSResult) GetZones() []string {
+	if x != nil {
+		return x.Zones
+	}
+	return nil
+}
+
+func (x *CloudDNSResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+type CloudDNSRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Ttl           int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	Values        []string               `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"`
+	Provider      string                 `protobuf:"bytes,5,opt,name=provider,proto3" json:"provider,omitempty"`
+	Zone          string                 `protobuf:"bytes,6,opt,name=zone,proto3" json:"zone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CloudDNSRecord) Reset() {
+	*x = CloudDNSRecord{}
+	mi := &file_results_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CloudDNSRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloudDNSRecord) ProtoMessage() {}
+
+func (x *CloudDNSRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloudDNSRecord.ProtoReflect.Descriptor instead.
+func (*CloudDNSRecord) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CloudDNSRecord) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CloudDNSRecord) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CloudDNSRecord) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *CloudDNSRecord) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *CloudDNSRecord) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *CloudDNSRecord) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+type DNSXResult struct {
+	state         protoimpl.MessageState     `protogen:"open.v1"`
+	Domain        string                     `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output        map[string]*ResolutionInfo `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Partial       bool                       `protobuf:"varint,3,opt,name=partial,proto3" json:"partial,omitempty"`
+	Metadata      *DNSXMetadata              `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	RecordsBlob   string                     `protobuf:"bytes,5,opt,name=records_blob,proto3" json:"records_blob,omitempty"`
+	RecordsCount  int64                      `protobuf:"varint,6,opt,name=records_count,proto3" json:"records_count,omitempty"`
+	RecordsIndex  string                     `protobuf:"bytes,7,opt,name=records_index,proto3" json:"records_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSXResult) Reset() {
+	*x = DNSXResult{}
+	mi := &file_results_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSXResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSXResult) ProtoMessage() {}
+
+func (x *DNSXResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSXResult.ProtoReflect.Descriptor instead.
+func (*DNSXResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DNSXResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *DNSXResult) GetOutput() map[string]*ResolutionInfo {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *DNSXResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *DNSXResult) GetMetadata() *DNSXMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *DNSXResult) GetRecordsBlob() string {
+	if x != nil {
+		return x.RecordsBlob
+	}
+	return ""
+}
+
+func (x *DNSXResult) GetRecordsCount() int64 {
+	if x != nil {
+		return x.RecordsCount
+	}
+	return 0
+}
+
+func (x *DNSXResult) GetRecordsIndex() string {
+	if x != nil {
+		return x.RecordsIndex
+	}
+	return ""
+}
+
+type ResolutionInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	A             []string               `protobuf:"bytes,2,rep,name=A,proto3" json:"A,omitempty"`
+	AAAA          []string               `protobuf:"bytes,3,rep,name=AAAA,proto3" json:"AAAA,omitempty"`
+	CNAME         []string               `protobuf:"bytes,4,rep,name=CNAME,proto3" json:"CNAME,omitempty"`
+	MX            []string               `protobuf:"bytes,5,rep,name=MX,proto3" json:"MX,omitempty"`
+	NS            []string               `protobuf:"bytes,6,rep,name=NS,proto3" json:"NS,omitempty"`
+	TXT           []string               `protobuf:"bytes,7,rep,name=TXT,proto3" json:"TXT,omitempty"`
+	SRV           []string               `protobuf:"bytes,8,rep,name=SRV,proto3" json:"SRV,omitempty"`
+	CAA           []string               `protobuf:"bytes,9,rep,name=CAA,proto3" json:"CAA,omitempty"`
+	PTR           []string               `protobuf:"bytes,10,rep,name=PTR,proto3" json:"PTR,omitempty"`
+	CnameChain    []string               `protobuf:"bytes,11,rep,name=cname_chain,proto3" json:"cname_chain,omitempty"`
+	Dangling      bool                   `protobuf:"varint,12,opt,name=dangling,proto3" json:"dangling,omitempty"`
+	Resolver      string                 `protobuf:"bytes,13,opt,name=resolver,proto3" json:"resolver,omitempty"`
+	RttMs         int64                  `protobuf:"varint,14,opt,name=rtt_ms,proto3" json:"rtt_ms,omitempty"`
+	Retried       bool                   `protobuf:"varint,15,opt,name=retried,proto3" json:"retried,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolutionInfo) Reset() {
+	*x = ResolutionInfo{}
+	mi := &file_results_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolutionInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolutionInfo) ProtoMessage() {}
+
+func (x *ResolutionInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolutionInfo.ProtoReflect.Descriptor instead.
+func (*ResolutionInfo) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ResolutionInfo) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ResolutionInfo) GetA() []string {
+	if x != nil {
+		return x.A
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetAAAA() []string {
+	if x != nil {
+		return x.AAAA
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetCNAME() []string {
+	if x != nil {
+		return x.CNAME
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetMX() []string {
+	if x != nil {
+		return x.MX
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetNS() []string {
+	if x != nil {
+		return x.NS
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetTXT() []string {
+	if x != nil {
+		return x.TXT
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetSRV() []string {
+	if x != nil {
+		return x.SRV
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetCAA() []string {
+	if x != nil {
+		return x.CAA
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetPTR() []string {
+	if x != nil {
+		return x.PTR
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetCnameChain() []string {
+	if x != nil {
+		return x.CnameChain
+	}
+	return nil
+}
+
+func (x *ResolutionInfo) GetDangling() bool {
+	if x != nil {
+		return x.Dangling
+	}
+	return false
+}
+
+func (x *ResolutionInfo) GetResolver() string {
+	if x != nil {
+		return x.Resolver
+	}
+	return ""
+}
+
+func (x *ResolutionInfo) GetRttMs() int64 {
+	if x != nil {
+		return x.RttMs
+	}
+	return 0
+}
+
+func (x *ResolutionInfo) GetRetried() bool {
+	if x != nil {
+		return x.Retried
+	}
+	return false
+}
+
+type DNSXMetadata struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Queries        int64                  `protobuf:"varint,1,opt,name=queries,proto3" json:"queries,omitempty"`
+	StatusCounts   map[string]int64       `protobuf:"bytes,2,rep,name=status_counts,proto3" json:"status_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	ResolverHits   map[string]int64       `protobuf:"bytes,3,rep,name=resolver_hits,proto3" json:"resolver_hits,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Retried        int64                  `protobuf:"varint,4,opt,name=retried,proto3" json:"retried,omitempty"`
+	Recovered      int64                  `protobuf:"varint,5,opt,name=recovered,proto3" json:"recovered,omitempty"`
+	AvgRttMs       int64                  `protobuf:"varint,6,opt,name=avg_rtt_ms,proto3" json:"avg_rtt_ms,omitempty"`
+	MaxRttMs       int64                  `protobuf:"varint,7,opt,name=max_rtt_ms,proto3" json:"max_rtt_ms,omitempty"`
+	Workers        int64                  `protobuf:"varint,8,opt,name=workers,proto3" json:"workers,omitempty"`
+	RateLimit      int64                  `protobuf:"varint,9,opt,name=rate_limit,proto3" json:"rate_limit,omitempty"`
+	Settings       *DNSSettings           `protobuf:"bytes,10,opt,name=settings,proto3" json:"settings,omitempty"`
+	ResolverHealth []*ResolverHealth      `protobuf:"bytes,11,rep,name=resolver_health,proto3" json:"resolver_health,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DNSXMetadata) Reset() {
+	*x = DNSXMetadata{}
+	mi := &file_results_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSXMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSXMetadata) ProtoMessage() {}
+
+func (x *DNSXMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSXMetadata.ProtoReflect.Descriptor instead.
+func (*DNSXMetadata) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DNSXMetadata) GetQueries() int64 {
+	if x != nil {
+		return x.Queries
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetStatusCounts() map[string]int64 {
+	if x != nil {
+		return x.StatusCounts
+	}
+	return nil
+}
+
+func (x *DNSXMetadata) GetResolverHits() map[string]int64 {
+	if x != nil {
+		return x.ResolverHits
+	}
+	return nil
+}
+
+func (x *DNSXMetadata) GetRetried() int64 {
+	if x != nil {
+		return x.Retried
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetRecovered() int64 {
+	if x != nil {
+		return x.Recovered
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetAvgRttMs() int64 {
+	if x != nil {
+		return x.AvgRttMs
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetMaxRttMs() int64 {
+	if x != nil {
+		return x.MaxRttMs
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetWorkers() int64 {
+	if x != nil {
+		return x.Workers
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetRateLimit() int64 {
+	if x != nil {
+		return x.RateLimit
+	}
+	return 0
+}
+
+func (x *DNSXMetadata) GetSettings() *DNSSettings {
+	if x != nil {
+		return x.Settings
+	}
+	return nil
+}
+
+func (x *DNSXMetadata) GetResolverHealth() []*ResolverHealth {
+	if x != nil {
+		return x.ResolverHealth
+	}
+	return nil
+}
+
+type DNSSettings struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Retries       int64                  `protobuf:"varint,1,opt,name=retries,proto3" json:"retries,omitempty"`
+	TimeoutMs     int64                  `protobuf:"varint,2,opt,name=timeout_ms,proto3" json:"timeout_ms,omitempty"`
+	QuestionTypes []string               `protobuf:"bytes,3,rep,name=question_types,proto3" json:"question_types,omitempty"`
+	Hostsfile     *bool                  `protobuf:"varint,4,opt,name=hostsfile,proto3,oneof" json:"hostsfile,omitempty"`
+	RetryPass     *bool                  `protobuf:"varint,5,opt,name=retry_pass,proto3,oneof" json:"retry_pass,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DNSSettings) Reset() {
+	*x = DNSSettings{}
+	mi := &file_results_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DNSSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DNSSettings) ProtoMessage() {}
+
+func (x *DNSSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DNSSettings.ProtoReflect.Descriptor instead.
+func (*DNSSettings) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DNSSettings) GetRetries() int64 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *DNSSettings) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+func (x *DNSSettings) GetQuestionTypes() []string {
+	if x != nil {
+		return x.QuestionTypes
+	}
+	return nil
+}
+
+func (x *DNSSettings) GetHostsfile() bool {
+	if x != nil && x.Hostsfile != nil {
+		return *x.Hostsfile
+	}
+	return false
+}
+
+func (x *DNSSettings) GetRetryPass() bool {
+	if x != nil && x.RetryPass != nil {
+		return *x.RetryPass
+	}
+	return false
+}
+
+type ResolverHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resolver      string                 `protobuf:"bytes,1,opt,name=resolver,proto3" json:"resolver,omitempty"`
+	Queries       int64                  `protobuf:"varint,2,opt,name=queries,proto3" json:"queries,omitempty"`
+	Errors        int64                  `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	Timeouts      int64                  `protobuf:"varint,4,opt,name=timeouts,proto3" json:"timeouts,omitempty"`
+	ErrorRate     float64                `protobuf:"fixed64,5,opt,name=error_rate,proto3" json:"error_rate,omitempty"`
+	Evictions     int64                  `protobuf:"varint,6,opt,name=evictions,proto3" json:"evictions,omitempty"`
+	Evicted       bool                   `protobuf:"varint,7,opt,name=evicted,proto3" json:"evicted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResolverHealth) Reset() {
+	*x = ResolverHealth{}
+	mi := &file_results_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResolverHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResolverHealth) ProtoMessage() {}
+
+func (x *ResolverHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResolverHealth.ProtoReflect.Descriptor instead.
+func (*ResolverHealth) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ResolverHealth) GetResolver() string {
+	if x != nil {
+		return x.Resolver
+	}
+	return ""
+}
+
+func (x *ResolverHealth) GetQueries() int64 {
+	if x != nil {
+		return x.Queries
+	}
+	return 0
+}
+
+func (x *ResolverHealth) GetErrors() int64 {
+	if x != nil {
+		return x.Errors
+	}
+	return 0
+}
+
+func (x *ResolverHealth) GetTimeouts() int64 {
+	if x != nil {
+		return x.Timeouts
+	}
+	return 0
+}
+
+func (x *ResolverHealth) GetErrorRate() float64 {
+	if x != nil {
+		return x.ErrorRate
+	}
+	return 0
+}
+
+func (x *ResolverHealth) GetEvictions() int64 {
+	if x != nil {
+		return x.Evictions
+	}
+	return 0
+}
+
+func (x *ResolverHealth) GetEvicted() bool {
+	if x != nil {
+		return x.Evicted
+	}
+	return false
+}
+
+type HttpxResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Domain         string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output         []*HttpxHostResult     `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty"`
+	Partial        bool                   `protobuf:"varint,3,opt,name=partial,proto3" json:"partial,omitempty"`
+	Probed         int64                  `protobuf:"varint,4,opt,name=probed,proto3" json:"probed,omitempty"`
+	Answered       int64                  `protobuf:"varint,7,opt,name=answered,proto3" json:"answered,omitempty"`
+	TlsFingerprint string                 `protobuf:"bytes,5,opt,name=tls_fingerprint,proto3" json:"tls_fingerprint,omitempty"`
+	Blocks         []*BlockEvent          `protobuf:"bytes,6,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HttpxResult) Reset() {
+	*x = HttpxResult{}
+	mi := &file_results_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HttpxResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HttpxResult) ProtoMessage() {}
+
+func (x *HttpxResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HttpxResult.ProtoReflect.Descriptor instead.
+func (*HttpxResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *HttpxResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *HttpxResult) GetOutput() []*HttpxHostResult {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *HttpxResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *HttpxResult) GetProbed() int64 {
+	if x != nil {
+		return x.Probed
+	}
+	return 0
+}
+
+func (x *HttpxResult) GetAnswered() int64 {
+	if x != nil {
+		return x.Answered
+	}
+	return 0
+}
+
+func (x *HttpxResult) GetTlsFingerprint() string {
+	if x != nil {
+		return x.TlsFingerprint
+	}
+	return ""
+}
+
+func (x *HttpxResult) GetBlocks() []*BlockEvent {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+type HttpxHostResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Host          string                 `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	StatusCode    int64                  `protobuf:"varint,3,opt,name=status_code,proto3" json:"status_code,omitempty"`
+	Technologies  []string               `protobuf:"bytes,4,rep,name=technologies,proto3" json:"technologies,omitempty"`
+	ContentLength int64                  `protobuf:"varint,5,opt,name=content_length,proto3" json:"content_length,omitempty"`
+	ContentType   string                 `protobuf:"bytes,6,opt,name=content_type,proto3" json:"content_type,omitempty"`
+	WebServer     string                 `protobuf:"bytes,7,opt,name=web_server,proto3" json:"web_server,omitempty"`
+	Title         string                 `protobuf:"bytes,8,opt,name=title,proto3" json:"title,omitempty"`
+	Asn           string                 `protobuf:"bytes,9,opt,name=asn,proto3" json:"asn,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HttpxHostResult) Reset() {
+	*x = HttpxHostResult{}
+	mi := &file_results_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HttpxHostResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HttpxHostResult) ProtoMessage() {}
+
+func (x *HttpxHostResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HttpxHostResult.ProtoReflect.Descriptor instead.
+func (*HttpxHostResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *HttpxHostResult) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *HttpxHostResult) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *HttpxHostResult) GetStatusCode() int64 {
+	if x != nil {
+		return x.StatusCode
+	}
+	return 0
+}
+
+func (x *HttpxHostResult) GetTechnologies() []string {
+	if x != nil {
+		return x.Technologies
+	}
+	return nil
+}
+
+func (x *HttpxHostResult) GetContentLength() int64 {
+	if x != nil {
+		return x.ContentLength
+	}
+	return 0
+}
+
+func (x *HttpxHostResult) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *HttpxHostResult) GetWebServer() string {
+	if x != nil {
+		return x.WebServer
+	}
+	return ""
+}
+
+func (x *HttpxHostResult) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *HttpxHostResult) GetAsn() string {
+	if x != nil {
+		return x.Asn
+	}
+	return ""
+}
+
+type BlockEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Signal        string                 `protobuf:"bytes,2,opt,name=signal,proto3" json:"signal,omitempty"`
+	Responses     int64                  `protobuf:"varint,3,opt,name=responses,proto3" json:"responses,omitempty"`
+	Answered      int64                  `protobuf:"varint,4,opt,name=answered,proto3" json:"answered,omitempty"`
+	Action        string                 `protobuf:"bytes,5,opt,name=action,proto3" json:"action,omitempty"`
+	DetectedAt    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=detected_at,proto3" json:"detected_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlockEvent) Reset() {
+	*x = BlockEvent{}
+	mi := &file_results_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlockEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockEvent) ProtoMessage() {}
+
+func (x *BlockEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockEvent.ProtoReflect.Descriptor instead.
+func (*BlockEvent) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *BlockEvent) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *BlockEvent) GetSignal() string {
+	if x != nil {
+		return x.Signal
+	}
+	return ""
+}
+
+func (x *BlockEvent) GetResponses() int64 {
+	if x != nil {
+		return x.Responses
+	}
+	return 0
+}
+
+func (x *BlockEvent) GetAnswered() int64 {
+	if x != nil {
+		return x.Answered
+	}
+	return 0
+}
+
+func (x *BlockEvent) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *BlockEvent) GetDetectedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DetectedAt
+	}
+	return nil
+}
+
+type NucleiResult struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Domain         string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output         []*NucleiVulnerability `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty"`
+	Partial        bool                   `protobuf:"varint,3,opt,name=partial,proto3" json:"partial,omitempty"`
+	Blocks         []*BlockEvent          `protobuf:"bytes,4,rep,name=blocks,proto3" json:"blocks,omitempty"`
+	Compliance     *CompliancePolicy      `protobuf:"bytes,5,opt,name=compliance,proto3" json:"compliance,omitempty"`
+	TimeBoxed      []string               `protobuf:"bytes,6,rep,name=time_boxed,proto3" json:"time_boxed,omitempty"`
+	Unscanned      []string               `protobuf:"bytes,7,rep,name=unscanned,proto3" json:"unscanned,omitempty"`
+	TemplatePolicy *TemplatePolicyReport  `protobuf:"bytes,8,opt,name=template_policy,proto3" json:"template_policy,omitempty"`
+	Unconfirmed    []*NucleiVulnerability `protobuf:"bytes,9,rep,name=unconfirmed,proto3" json:"unconfirmed,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NucleiResult) Reset() {
+	*x = NucleiResult{}
+	mi := &file_results_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NucleiResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NucleiResult) ProtoMessage() {}
+
+func (x *NucleiResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NucleiResult.ProtoReflect.Descriptor instead.
+func (*NucleiResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *NucleiResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *NucleiResult) GetOutput() []*NucleiVulnerability {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *NucleiResult) GetBlocks() []*BlockEvent {
+	if x != nil {
+		return x.Blocks
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetCompliance() *CompliancePolicy {
+	if x != nil {
+		return x.Compliance
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetTimeBoxed() []string {
+	if x != nil {
+		return x.TimeBoxed
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetUnscanned() []string {
+	if x != nil {
+		return x.Unscanned
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetTemplatePolicy() *TemplatePolicyReport {
+	if x != nil {
+		return x.TemplatePolicy
+	}
+	return nil
+}
+
+func (x *NucleiResult) GetUnconfirmed() []*NucleiVulnerability {
+	if x != nil {
+		return x.Unconfirmed
+	}
+	return nil
+}
+
+type NucleiVulnerability struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TemplateId       string                 `protobuf:"bytes,1,opt,name=template_id,proto3" json:"template_id,omitempty"`
+	Type             string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Host             string                 `protobuf:"bytes,3,opt,name=host,proto3" json:"host,omitempty"`
+	MatchedAt        string                 `protobuf:"bytes,4,opt,name=matched_at,proto3" json:"matched_at,omitempty"`
+	ExtractedResults []string               `protobuf:"bytes,5,rep,name=extracted_results,proto3" json:"extracted_results,omitempty"`
+	Request          string                 `protobuf:"bytes,6,opt,name=request,proto3" json:"request,omitempty"`
+	Response         string                 `protobuf:"bytes,7,opt,name=response,proto3" json:"response,omitempty"`
+	Name             string                 `protobuf:"bytes,8,opt,name=name,proto3" json:"name,omitempty"`
+	Description      string                 `protobuf:"bytes,9,opt,name=description,proto3" json:"description,omitempty"`
+	Reference        []string               `protobuf:"bytes,10,rep,name=reference,proto3" json:"reference,omitempty"`
+	Severity         string                 `protobuf:"bytes,11,opt,name=severity,proto3" json:"severity,omitempty"`
+	MatcherName      string                 `protobuf:"bytes,12,opt,name=matcher_name,proto3" json:"matcher_name,omitempty"`
+	Tags             []string               `protobuf:"bytes,13,rep,name=tags,proto3" json:"tags,omitempty"`
+	CveIds           []string               `protobuf:"bytes,14,rep,name=cve_ids,proto3" json:"cve_ids,omitempty"`
+	CweIds           []string               `protobuf:"bytes,15,rep,name=cwe_ids,proto3" json:"cwe_ids,omitempty"`
+	CvssScore        float64                `protobuf:"fixed64,16,opt,name=cvss_score,proto3" json:"cvss_score,omitempty"`
+	CvssMetrics      string                 `protobuf:"bytes,17,opt,name=cvss_metrics,proto3" json:"cvss_metrics,omitempty"`
+	EpssScore        float64                `protobuf:"fixed64,18,opt,name=epss_score,proto3" json:"epss_score,omitempty"`
+	CurlCommand      string                 `protobuf:"bytes,19,opt,name=curl_command,proto3" json:"curl_command,omitempty"`
+	ComplianceReview string                 `protobuf:"bytes,20,opt,name=compliance_review,proto3" json:"compliance_review,omitempty"`
+	Verification     string                 `protobuf:"bytes,21,opt,name=verification,proto3" json:"verification,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *NucleiVulnerability) Reset() {
+	*x = NucleiVulnerability{}
+	mi := &file_results_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NucleiVulnerability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NucleiVulnerability) ProtoMessage() {}
+
+func (x *NucleiVulnerability) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NucleiVulnerability.ProtoReflect.Descriptor instead.
+func (*NucleiVulnerability) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *NucleiVulnerability) GetTemplateId() string {
+	if x != nil {
+		return x.TemplateId
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetMatchedAt() string {
+	if x != nil {
+		return x.MatchedAt
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetExtractedResults() []string {
+	if x != nil {
+		return x.ExtractedResults
+	}
+	return nil
+}
+
+func (x *NucleiVulnerability) GetRequest() string {
+	if x != nil {
+		return x.Request
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetReference() []string {
+	if x != nil {
+		return x.Reference
+	}
+	return nil
+}
+
+func (x *NucleiVulnerability) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetMatcherName() string {
+	if x != nil {
+		return x.MatcherName
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *NucleiVulnerability) GetCveIds() []string {
+	if x != nil {
+		return x.CveIds
+	}
+	return nil
+}
+
+func (x *NucleiVulnerability) GetCweIds() []string {
+	if x != nil {
+		return x.CweIds
+	}
+	return nil
+}
+
+func (x *NucleiVulnerability) GetCvssScore() float64 {
+	if x != nil {
+		return x.CvssScore
+	}
+	return 0
+}
+
+func (x *NucleiVulnerability) GetCvssMetrics() string {
+	if x != nil {
+		return x.CvssMetrics
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetEpssScore() float64 {
+	if x != nil {
+		return x.EpssScore
+	}
+	return 0
+}
+
+func (x *NucleiVulnerability) GetCurlCommand() string {
+	if x != nil {
+		return x.CurlCommand
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetComplianceReview() string {
+	if x != nil {
+		return x.ComplianceReview
+	}
+	return ""
+}
+
+func (x *NucleiVulnerability) GetVerification() string {
+	if x != nil {
+		return x.Verification
+	}
+	return ""
+}
+
+type CompliancePolicy struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	UserAgent         string                 `protobuf:"bytes,1,opt,name=user_agent,proto3" json:"user_agent,omitempty"`
+	Hosts             []*HostPolicy          `protobuf:"bytes,2,rep,name=hosts,proto3" json:"hosts,omitempty"`
+	Skipped           []string               `protobuf:"bytes,3,rep,name=skipped,proto3" json:"skipped,omitempty"`
+	ExcludedTemplates []string               `protobuf:"bytes,4,rep,name=excluded_templates,proto3" json:"excluded_templates,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *CompliancePolicy) Reset() {
+	*x = CompliancePolicy{}
+	mi := &file_results_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompliancePolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompliancePolicy) ProtoMessage() {}
+
+func (x *CompliancePolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompliancePolicy.ProtoReflect.Descriptor instead.
+func (*CompliancePolicy) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CompliancePolicy) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+func (x *CompliancePolicy) GetHosts() []*HostPolicy {
+	if x != nil {
+		return x.Hosts
+	}
+	return nil
+}
+
+func (x *CompliancePolicy) GetSkipped() []string {
+	if x != nil {
+		return x.Skipped
+	}
+	return nil
+}
+
+func (x *CompliancePolicy) GetExcludedTemplates() []string {
+	if x != nil {
+		return x.ExcludedTemplates
+	}
+	return nil
+}
+
+type HostPolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Origin        string                 `protobuf:"bytes,1,opt,name=origin,proto3" json:"origin,omitempty"`
+	Robots        bool                   `protobuf:"varint,2,opt,name=robots,proto3" json:"robots,omitempty"`
+	Allow         []string               `protobuf:"bytes,3,rep,name=allow,proto3" json:"allow,omitempty"`
+	Disallow      []string               `protobuf:"bytes,4,rep,name=disallow,proto3" json:"disallow,omitempty"`
+	SecurityTxt   *SecurityTxt           `protobuf:"bytes,5,opt,name=security_txt,proto3" json:"security_txt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HostPolicy) Reset() {
+	*x = HostPolicy{}
+	mi := &file_results_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostPolicy) ProtoMessage() {}
+
+func (x *HostPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostPolicy.ProtoReflect.Descriptor instead.
+func (*HostPolicy) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *HostPolicy) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *HostPolicy) GetRobots() bool {
+	if x != nil {
+		return x.Robots
+	}
+	return false
+}
+
+func (x *HostPolicy) GetAllow() []string {
+	if x != nil {
+		return x.Allow
+	}
+	return nil
+}
+
+func (x *HostPolicy) GetDisallow() []string {
+	if x != nil {
+		return x.Disallow
+	}
+	return nil
+}
+
+func (x *HostPolicy) GetSecurityTxt() *SecurityTxt {
+	if x != nil {
+		return x.SecurityTxt
+	}
+	return nil
+}
+
+type SecurityTxt struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Contact       []string               `protobuf:"bytes,1,rep,name=contact,proto3" json:"contact,omitempty"`
+	Policy        []string               `protobuf:"bytes,2,rep,name=policy,proto3" json:"policy,omitempty"`
+	Expires       string                 `protobuf:"bytes,3,opt,name=expires,proto3" json:"expires,omitempty"`
+	Canonical     []string               `protobuf:"bytes,4,rep,name=canonical,proto3" json:"canonical,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SecurityTxt) Reset() {
+	*x = SecurityTxt{}
+	mi := &file_results_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SecurityTxt) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SecurityTxt) ProtoMessage() {}
+
+func (x *SecurityTxt) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SecurityTxt.ProtoReflect.Descriptor instead.
+func (*SecurityTxt) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SecurityTxt) GetContact() []string {
+	if x != nil {
+		return x.Contact
+	}
+	return nil
+}
+
+func (x *SecurityTxt) GetPolicy() []string {
+	if x != nil {
+		return x.Policy
+	}
+	return nil
+}
+
+func (x *SecurityTxt) GetExpires() string {
+	if x != nil {
+		return x.Expires
+	}
+	return ""
+}
+
+func (x *SecurityTxt) GetCanonical() []string {
+	if x != nil {
+		return x.Canonical
+	}
+	return nil
+}
+
+type TemplatePolicyReport struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Policy        *TemplatePolicy        `protobuf:"bytes,1,opt,name=policy,proto3" json:"policy,omitempty"`
+	Templates     int64                  `protobuf:"varint,2,opt,name=templates,proto3" json:"templates,omitempty"`
+	Violations    []*TemplateViolation   `protobuf:"bytes,3,rep,name=violations,proto3" json:"violations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplatePolicyReport) Reset() {
+	*x = TemplatePolicyReport{}
+	mi := &file_results_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplatePolicyReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplatePolicyReport) ProtoMessage() {}
+
+func (x *TemplatePolicyReport) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplatePolicyReport.ProtoReflect.Descriptor instead.
+func (*TemplatePolicyReport) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *TemplatePolicyReport) GetPolicy() *TemplatePolicy {
+	if x != nil {
+		return x.Policy
+	}
+	return nil
+}
+
+func (x *TemplatePolicyReport) GetTemplates() int64 {
+	if x != nil {
+		return x.Templates
+	}
+	return 0
+}
+
+func (x *TemplatePolicyReport) GetViolations() []*TemplateViolation {
+	if x != nil {
+		return x.Violations
+	}
+	return nil
+}
+
+type TemplatePolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AllowTags     []string               `protobuf:"bytes,1,rep,name=allow_tags,proto3" json:"allow_tags,omitempty"`
+	DenyTags      []string               `protobuf:"bytes,2,rep,name=deny_tags,proto3" json:"deny_tags,omitempty"`
+	DenyIds       []string               `protobuf:"bytes,3,rep,name=deny_ids,proto3" json:"deny_ids,omitempty"`
+	DenyWordlists bool                   `protobuf:"varint,4,opt,name=deny_wordlists,proto3" json:"deny_wordlists,omitempty"`
+	AllowIds      []string               `protobuf:"bytes,5,rep,name=allow_ids,proto3" json:"allow_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplatePolicy) Reset() {
+	*x = TemplatePolicy{}
+	mi := &file_results_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplatePolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplatePolicy) ProtoMessage() {}
+
+func (x *TemplatePolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplatePolicy.ProtoReflect.Descriptor instead.
+func (*TemplatePolicy) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *TemplatePolicy) GetAllowTags() []string {
+	if x != nil {
+		return x.AllowTags
+	}
+	return nil
+}
+
+func (x *TemplatePolicy) GetDenyTags() []string {
+	if x != nil {
+		return x.DenyTags
+	}
+	return nil
+}
+
+func (x *TemplatePolicy) GetDenyIds() []string {
+	if x != nil {
+		return x.DenyIds
+	}
+	return nil
+}
+
+func (x *TemplatePolicy) GetDenyWordlists() bool {
+	if x != nil {
+		return x.DenyWordlists
+	}
+	return false
+}
+
+func (x *TemplatePolicy) GetAllowIds() []string {
+	if x != nil {
+		return x.AllowIds
+	}
+	return nil
+}
+
+type TemplateViolation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TemplateId    string                 `protobuf:"bytes,1,opt,name=template_id,proto3" json:"template_id,omitempty"`
+	Rule          string                 `protobuf:"bytes,2,opt,name=rule,proto3" json:"rule,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TemplateViolation) Reset() {
+	*x = TemplateViolation{}
+	mi := &file_results_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TemplateViolation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TemplateViolation) ProtoMessage() {}
+
+func (x *TemplateViolation) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TemplateViolation.ProtoReflect.Descriptor instead.
+func (*TemplateViolation) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *TemplateViolation) GetTemplateId() string {
+	if x != nil {
+		return x.TemplateId
+	}
+	return ""
+}
+
+func (x *TemplateViolation) GetRule() string {
+	if x != nil {
+		return x.Rule
+	}
+	return ""
+}
+
+type NaabuResult struct {
+	state             protoimpl.MessageState         `protogen:"open.v1"`
+	Domain            string                         `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output            map[string]*structpb.ListValue `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Partial           bool                           `protobuf:"varint,3,opt,name=partial,proto3" json:"partial,omitempty"`
+	UnresponsiveHosts int64                          `protobuf:"varint,4,opt,name=unresponsive_hosts,proto3" json:"unresponsive_hosts,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *NaabuResult) Reset() {
+	*x = NaabuResult{}
+	mi := &file_results_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NaabuResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NaabuResult) ProtoMessage() {}
+
+func (x *NaabuResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NaabuResult.ProtoReflect.Descriptor instead.
+func (*NaabuResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *NaabuResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *NaabuResult) GetOutput() map[string]*structpb.ListValue {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *NaabuResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *NaabuResult) GetUnresponsiveHosts() int64 {
+	if x != nil {
+		return x.UnresponsiveHosts
+	}
+	return 0
+}
+
+type ScopeExpansionResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Domain        string                 `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Output        []*ScopeSuggestion     `protobuf:"bytes,2,rep,name=output,proto3" json:"output,omitempty"`
+	HostsProbed   int64                  `protobuf:"varint,3,opt,name=hosts_probed,proto3" json:"hosts_probed,omitempty"`
+	Partial       bool                   `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScopeExpansionResult) Reset() {
+	*x = ScopeExpansionResult{}
+	mi := &file_results_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScopeExpansionResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScopeExpansionResult) ProtoMessage() {}
+
+func (x *ScopeExpansionResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScopeExpansionResult.ProtoReflect.Descriptor instead.
+func (*ScopeExpansionResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ScopeExpansionResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *ScopeExpansionResult) GetOutput() []*ScopeSuggestion {
+	if x != nil {
+		return x.Output
+	}
+	return nil
+}
+
+func (x *ScopeExpansionResult) GetHostsProbed() int64 {
+	if x != nil {
+		return x.HostsProbed
+	}
+	return 0
+}
+
+func (x *ScopeExpansionResult) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+type ScopeSuggestion struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Apex           string                 `protobuf:"bytes,1,opt,name=apex,proto3" json:"apex,omitempty"`
+	Evidence       []*ScopeEvidence       `protobuf:"bytes,2,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	Status         string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	FirstSuggested *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=first_suggested,proto3" json:"first_suggested,omitempty"`
+	LastSuggested  *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=last_suggested,proto3" json:"last_suggested,omitempty"`
+	DecidedBy      string                 `protobuf:"bytes,6,opt,name=decided_by,proto3" json:"decided_by,omitempty"`
+	DecidedAt      *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=decided_at,proto3" json:"decided_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ScopeSuggestion) Reset() {
+	*x = ScopeSuggestion{}
+	mi := &file_results_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScopeSuggestion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScopeSuggestion) ProtoMessage() {}
+
+func (x *ScopeSuggestion) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScopeSuggestion.ProtoReflect.Descriptor instead.
+func (*ScopeSuggestion) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ScopeSuggestion) GetApex() string {
+	if x != nil {
+		return x.Apex
+	}
+	return ""
+}
+
+func (x *ScopeSuggestion) GetEvidence() []*ScopeEvidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *ScopeSuggestion) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ScopeSuggestion) GetFirstSuggested() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstSuggested
+	}
+	return nil
+}
+
+func (x *ScopeSuggestion) GetLastSuggested() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSuggested
+	}
+	return nil
+}
+
+func (x *ScopeSuggestion) GetDecidedBy() string {
+	if x != nil {
+		return x.DecidedBy
+	}
+	return ""
+}
+
+func (x *ScopeSuggestion) GetDecidedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DecidedAt
+	}
+	return nil
+}
+
+type ScopeEvidence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Detail        string                 `protobuf:"bytes,2,opt,name=detail,proto3" json:"detail,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScopeEvidence) Reset() {
+	*x = ScopeEvidence{}
+	mi := &file_results_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScopeEvidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScopeEvidence) ProtoMessage() {}
+
+func (x *ScopeEvidence) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScopeEvidence.ProtoReflect.Descriptor instead.
+func (*ScopeEvidence) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ScopeEvidence) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ScopeEvidence) GetDetail() string {
+	if x != nil {
+		return x.Detail
+	}
+	return ""
+}
+
+type SubfinderResult struct {
+	state            protoimpl.MessageState         `protogen:"open.v1"`
+	Domain           string                         `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	Subdomains       []string                       `protobuf:"bytes,2,rep,name=subdomains,proto3" json:"subdomains,omitempty"`
+	Sources          map[string]*PassiveSourceStats `protobuf:"bytes,3,rep,name=sources,proto3" json:"sources,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	SubdomainSources map[string]*structpb.ListValue `protobuf:"bytes,4,rep,name=subdomain_sources,proto3" json:"subdomain_sources,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Filtered         int64                          `protobuf:"varint,5,opt,name=filtered,proto3" json:"filtered,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SubfinderResult) Reset() {
+	*x = SubfinderResult{}
+	mi := &file_results_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubfinderResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubfinderResult) ProtoMessage() {}
+
+func (x *SubfinderResult) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubfinderResult.ProtoReflect.Descriptor instead.
+func (*SubfinderResult) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *SubfinderResult) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *SubfinderResult) GetSubdomains() []string {
+	if x != nil {
+		return x.Subdomains
+	}
+	return nil
+}
+
+func (x *SubfinderResult) GetSources() map[string]*PassiveSourceStats {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *SubfinderResult) GetSubdomainSources() map[string]*structpb.ListValue {
+	if x != nil {
+		return x.SubdomainSources
+	}
+	return nil
+}
+
+func (x *SubfinderResult) GetFiltered() int64 {
+	if x != nil {
+		return x.Filtered
+	}
+	return 0
+}
+
+type PassiveSourceStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Results         int64                  `protobuf:"varint,1,opt,name=results,proto3" json:"results,omitempty"`
+	Requests        int64                  `protobuf:"varint,2,opt,name=requests,proto3" json:"requests,omitempty"`
+	Retries         int64                  `protobuf:"varint,3,opt,name=retries,proto3" json:"retries,omitempty"`
+	Duration        string                 `protobuf:"bytes,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	Error           string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	BudgetExhausted bool                   `protobuf:"varint,6,opt,name=budget_exhausted,proto3" json:"budget_exhausted,omitempty"`
+	QuotaLimited    bool                   `protobuf:"varint,7,opt,name=quota_limited,proto3" json:"quota_limited,omitempty"`
+	Skipped         bool                   `protobuf:"varint,8,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PassiveSourceStats) Reset() {
+	*x = PassiveSourceStats{}
+	mi := &file_results_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PassiveSourceStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PassiveSourceStats) ProtoMessage() {}
+
+func (x *PassiveSourceStats) ProtoReflect() protoreflect.Message {
+	mi := &file_results_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PassiveSourceStats.ProtoReflect.Descriptor instead.
+func (*PassiveSourceStats) Descriptor() ([]byte, []int) {
+	return file_results_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *PassiveSourceStats) GetResults() int64 {
+	if x != nil {
+		return x.Results
+	}
+	return 0
+}
+
+func (x *PassiveSourceStats) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *PassiveSourceStats) GetRetries() int64 {
+	if x != nil {
+		return x.Retries
+	}
+	return 0
+}
+
+func (x *PassiveSourceStats) GetDuration() string {
+	if x != nil {
+		return x.Duration
+	}
+	return ""
+}
+
+func (x *PassiveSourceStats) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *PassiveSourceStats) GetBudgetExhausted() bool {
+	if x != nil {
+		return x.BudgetExhausted
+	}
+	return false
+}
+
+func (x *PassiveSourceStats) GetQuotaLimited() bool {
+	if x != nil {
+		return x.QuotaLimited
+	}
+	return false
+}
+
+func (x *PassiveSourceStats) GetSkipped() bool {
+	if x != nil {
+		return x.Skipped
+	}
+	return false
+}
+
+var File_results_proto protoreflect.FileDescriptor
+
+const file_results_proto_rawDesc = "" +
+	"\n" +
+	"\rresults.proto\x12\x12allsafe.results.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x94\x01\n" +
+	"\x0eCloudDNSResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12:\n" +
+	"\x06output\x18\x02 \x03(\v2\".allsafe.results.v1.CloudDNSRecordR\x06output\x12\x14\n" +
+	"\x05zones\x18\x03 \x03(\tR\x05zones\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\"\x92\x01\n" +
+	"\x0eCloudDNSRecord\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x10\n" +
+	"\x03ttl\x18\x03 \x01(\x03R\x03ttl\x12\x16\n" +
+	"\x06values\x18\x04 \x03(\tR\x06values\x12\x1a\n" +
+	"\bprovider\x18\x05 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04zone\x18\x06 \x01(\tR\x04zone\"\x8f\x03\n" +
+	"\n" +
+	"DNSXResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12B\n" +
+	"\x06output\x18\x02 \x03(\v2*.allsafe.results.v1.DNSXResult.OutputEntryR\x06output\x12\x18\n" +
+	"\apartial\x18\x03 \x01(\bR\apartial\x12<\n" +
+	"\bmetadata\x18\x04 \x01(\v2 .allsafe.results.v1.DNSXMetadataR\bmetadata\x12\"\n" +
+	"\frecords_blob\x18\x05 \x01(\tR\frecords_blob\x12$\n" +
+	"\rrecords_count\x18\x06 \x01(\x03R\rrecords_count\x12$\n" +
+	"\rrecords_index\x18\a \x01(\tR\rrecords_index\x1a]\n" +
+	"\vOutputEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x128\n" +
+	"\x05value\x18\x02 \x01(\v2\".allsafe.results.v1.ResolutionInfoR\x05value:\x028\x01\"\xd4\x02\n" +
+	"\x0eResolutionInfo\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\f\n" +
+	"\x01A\x18\x02 \x03(\tR\x01A\x12\x12\n" +
+	"\x04AAAA\x18\x03 \x03(\tR\x04AAAA\x12\x14\n" +
+	"\x05CNAME\x18\x04 \x03(\tR\x05CNAME\x12\x0e\n" +
+	"\x02MX\x18\x05 \x03(\tR\x02MX\x12\x0e\n" +
+	"\x02NS\x18\x06 \x03(\tR\x02NS\x12\x10\n" +
+	"\x03TXT\x18\a \x03(\tR\x03TXT\x12\x10\n" +
+	"\x03SRV\x18\b \x03(\tR\x03SRV\x12\x10\n" +
+	"\x03CAA\x18\t \x03(\tR\x03CAA\x12\x10\n" +
+	"\x03PTR\x18\n" +
+	" \x03(\tR\x03PTR\x12 \n" +
+	"\vcname_chain\x18\v \x03(\tR\vcname_chain\x12\x1a\n" +
+	"\bdangling\x18\f \x01(\bR\bdangling\x12\x1a\n" +
+	"\bresolver\x18\r \x01(\tR\bresolver\x12\x16\n" +
+	"\x06rtt_ms\x18\x0e \x01(\x03R\x06rtt_ms\x12\x18\n" +
+	"\aretried\x18\x0f \x01(\bR\aretried\"\x9b\x05\n" +
+	"\fDNSXMetadata\x12\x18\n" +
+	"\aqueries\x18\x01 \x01(\x03R\aqueries\x12X\n" +
+	"\rstatus_counts\x18\x02 \x03(\v22.allsafe.results.v1.DNSXMetadata.StatusCountsEntryR\rstatus_counts\x12X\n" +
+	"\rresolver_hits\x18\x03 \x03(\v22.allsafe.results.v1.DNSXMetadata.ResolverHitsEntryR\rresolver_hits\x12\x18\n" +
+	"\aretried\x18\x04 \x01(\x03R\aretried\x12\x1c\n" +
+	"\trecovered\x18\x05 \x01(\x03R\trecovered\x12\x1e\n" +
+	"\n" +
+	"avg_rtt_ms\x18\x06 \x01(\x03R\n" +
+	"avg_rtt_ms\x12\x1e\n" +
+	"\n" +
+	"max_rtt_ms\x18\a \x01(\x03R\n" +
+	"max_rtt_ms\x12\x18\n" +
+	"\aworkers\x18\b \x01(\x03R\aworkers\x12\x1e\n" +
+	"\n" +
+	"rate_limit\x18\t \x01(\x03R\n" +
+	"rate_limit\x12;\n" +
+	"\bsettings\x18\n" +
+	" \x01(\v2\x1f.allsafe.results.v1.DNSSettingsR\bsettings\x12L\n" +
+	"\x0fresolver_health\x18\v \x03(\v2\".allsafe.results.v1.ResolverHealthR\x0fresolver_health\x1a?\n" +
+	"\x11StatusCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1a?\n" +
+	"\x11ResolverHitsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\xd4\x01\n" +
+	"\vDNSSettings\x12\x18\n" +
+	"\aretries\x18\x01 \x01(\x03R\aretries\x12\x1e\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x03R\n" +
+	"timeout_ms\x12&\n" +
+	"\x0equestion_types\x18\x03 \x03(\tR\x0equestion_types\x12!\n" +
+	"\thostsfile\x18\x04 \x01(\bH\x00R\thostsfile\x88\x01\x01\x12#\n" +
+	"\n" +
+	"retry_pass\x18\x05 \x01(\bH\x01R\n" +
+	"retry_pass\x88\x01\x01B\f\n" +
+	"\n" +
+	"_hostsfileB\r\n" +
+	"\v_retry_pass\"\xd2\x01\n" +
+	"\x0eResolverHealth\x12\x1a\n" +
+	"\bresolver\x18\x01 \x01(\tR\bresolver\x12\x18\n" +
+	"\aqueries\x18\x02 \x01(\x03R\aqueries\x12\x16\n" +
+	"\x06errors\x18\x03 \x01(\x03R\x06errors\x12\x1a\n" +
+	"\btimeouts\x18\x04 \x01(\x03R\btimeouts\x12\x1e\n" +
+	"\n" +
+	"error_rate\x18\x05 \x01(\x01R\n" +
+	"error_rate\x12\x1c\n" +
+	"\tevictions\x18\x06 \x01(\x03R\tevictions\x12\x18\n" +
+	"\aevicted\x18\a \x01(\bR\aevicted\"\x92\x02\n" +
+	"\vHttpxResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12;\n" +
+	"\x06output\x18\x02 \x03(\v2#.allsafe.results.v1.HttpxHostResultR\x06output\x12\x18\n" +
+	"\apartial\x18\x03 \x01(\bR\apartial\x12\x16\n" +
+	"\x06probed\x18\x04 \x01(\x03R\x06probed\x12\x1a\n" +
+	"\banswered\x18\a \x01(\x03R\banswered\x12(\n" +
+	"\x0ftls_fingerprint\x18\x05 \x01(\tR\x0ftls_fingerprint\x126\n" +
+	"\x06blocks\x18\x06 \x03(\v2\x1e.allsafe.results.v1.BlockEventR\x06blocks\"\x91\x02\n" +
+	"\x0fHttpxHostResult\x12\x12\n" +
+	"\x04host\x18\x01 \x01(\tR\x04host\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\x12 \n" +
+	"\vstatus_code\x18\x03 \x01(\x03R\vstatus_code\x12\"\n" +
+	"\ftechnologies\x18\x04 \x03(\tR\ftechnologies\x12&\n" +
+	"\x0econtent_length\x18\x05 \x01(\x03R\x0econtent_length\x12\"\n" +
+	"\fcontent_type\x18\x06 \x01(\tR\fcontent_type\x12\x1e\n" +
+	"\n" +
+	"web_server\x18\a \x01(\tR\n" +
+	"web_server\x12\x14\n" +
+	"\x05title\x18\b \x01(\tR\x05title\x12\x10\n" +
+	"\x03asn\x18\t \x01(\tR\x03asn\"\xca\x01\n" +
+	"\n" +
+	"BlockEvent\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x16\n" +
+	"\x06signal\x18\x02 \x01(\tR\x06signal\x12\x1c\n" +
+	"\tresponses\x18\x03 \x01(\x03R\tresponses\x12\x1a\n" +
+	"\banswered\x18\x04 \x01(\x03R\banswered\x12\x16\n" +
+	"\x06action\x18\x05 \x01(\tR\x06action\x12<\n" +
+	"\vdetected_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\vdetected_at\"\xdc\x03\n" +
+	"\fNucleiResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12?\n" +
+	"\x06output\x18\x02 \x03(\v2'.allsafe.results.v1.NucleiVulnerabilityR\x06output\x12\x18\n" +
+	"\apartial\x18\x03 \x01(\bR\apartial\x126\n" +
+	"\x06blocks\x18\x04 \x03(\v2\x1e.allsafe.results.v1.BlockEventR\x06blocks\x12D\n" +
+	"\n" +
+	"compliance\x18\x05 \x01(\v2$.allsafe.results.v1.CompliancePolicyR\n" +
+	"compliance\x12\x1e\n" +
+	"\n" +
+	"time_boxed\x18\x06 \x03(\tR\n" +
+	"time_boxed\x12\x1c\n" +
+	"\tunscanned\x18\a \x03(\tR\tunscanned\x12R\n" +
+	"\x0ftemplate_policy\x18\b \x01(\v2(.allsafe.results.v1.TemplatePolicyReportR\x0ftemplate_policy\x12I\n" +
+	"\vunconfirmed\x18\t \x03(\v2'.allsafe.results.v1.NucleiVulnerabilityR\vunconfirmed\"\x99\x05\n" +
+	"\x13NucleiVulnerability\x12 \n" +
+	"\vtemplate_id\x18\x01 \x01(\tR\vtemplate_id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04host\x18\x03 \x01(\tR\x04host\x12\x1e\n" +
+	"\n" +
+	"matched_at\x18\x04 \x01(\tR\n" +
+	"matched_at\x12,\n" +
+	"\x11extracted_results\x18\x05 \x03(\tR\x11extracted_results\x12\x18\n" +
+	"\arequest\x18\x06 \x01(\tR\arequest\x12\x1a\n" +
+	"\bresponse\x18\a \x01(\tR\bresponse\x12\x12\n" +
+	"\x04name\x18\b \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\t \x01(\tR\vdescription\x12\x1c\n" +
+	"\treference\x18\n" +
+	" \x03(\tR\treference\x12\x1a\n" +
+	"\bseverity\x18\v \x01(\tR\bseverity\x12\"\n" +
+	"\fmatcher_name\x18\f \x01(\tR\fmatcher_name\x12\x12\n" +
+	"\x04tags\x18\r \x03(\tR\x04tags\x12\x18\n" +
+	"\acve_ids\x18\x0e \x03(\tR\acve_ids\x12\x18\n" +
+	"\acwe_ids\x18\x0f \x03(\tR\acwe_ids\x12\x1e\n" +
+	"\n" +
+	"cvss_score\x18\x10 \x01(\x01R\n" +
+	"cvss_score\x12\"\n" +
+	"\fcvss_metrics\x18\x11 \x01(\tR\fcvss_metrics\x12\x1e\n" +
+	"\n" +
+	"epss_score\x18\x12 \x01(\x01R\n" +
+	"epss_score\x12\"\n" +
+	"\fcurl_command\x18\x13 \x01(\tR\fcurl_command\x12,\n" +
+	"\x11compliance_review\x18\x14 \x01(\tR\x11compliance_review\x12\"\n" +
+	"\fverification\x18\x15 \x01(\tR\fverification\"\xb2\x01\n" +
+	"\x10CompliancePolicy\x12\x1e\n" +
+	"\n" +
+	"user_agent\x18\x01 \x01(\tR\n" +
+	"user_agent\x124\n" +
+	"\x05hosts\x18\x02 \x03(\v2\x1e.allsafe.results.v1.HostPolicyR\x05hosts\x12\x18\n" +
+	"\askipped\x18\x03 \x03(\tR\askipped\x12.\n" +
+	"\x12excluded_templates\x18\x04 \x03(\tR\x12excluded_templates\"\xb3\x01\n" +
+	"\n" +
+	"HostPolicy\x12\x16\n" +
+	"\x06origin\x18\x01 \x01(\tR\x06origin\x12\x16\n" +
+	"\x06robots\x18\x02 \x01(\bR\x06robots\x12\x14\n" +
+	"\x05allow\x18\x03 \x03(\tR\x05allow\x12\x1a\n" +
+	"\bdisallow\x18\x04 \x03(\tR\bdisallow\x12C\n" +
+	"\fsecurity_txt\x18\x05 \x01(\v2\x1f.allsafe.results.v1.SecurityTxtR\fsecurity_txt\"w\n" +
+	"\vSecurityTxt\x12\x18\n" +
+	"\acontact\x18\x01 \x03(\tR\acontact\x12\x16\n" +
+	"\x06policy\x18\x02 \x03(\tR\x06policy\x12\x18\n" +
+	"\aexpires\x18\x03 \x01(\tR\aexpires\x12\x1c\n" +
+	"\tcanonical\x18\x04 \x03(\tR\tcanonical\"\xb7\x01\n" +
+	"\x14TemplatePolicyReport\x12:\n" +
+	"\x06policy\x18\x01 \x01(\v2\".allsafe.results.v1.TemplatePolicyR\x06policy\x12\x1c\n" +
+	"\ttemplates\x18\x02 \x01(\x03R\ttemplates\x12E\n" +
+	"\n" +
+	"violations\x18\x03 \x03(\v2%.allsafe.results.v1.TemplateViolationR\n" +
+	"violations\"\xb0\x01\n" +
+	"\x0eTemplatePolicy\x12\x1e\n" +
+	"\n" +
+	"allow_tags\x18\x01 \x03(\tR\n" +
+	"allow_tags\x12\x1c\n" +
+	"\tdeny_tags\x18\x02 \x03(\tR\tdeny_tags\x12\x1a\n" +
+	"\bdeny_ids\x18\x03 \x03(\tR\bdeny_ids\x12&\n" +
+	"\x0edeny_wordlists\x18\x04 \x01(\bR\x0edeny_wordlists\x12\x1c\n" +
+	"\tallow_ids\x18\x05 \x03(\tR\tallow_ids\"I\n" +
+	"\x11TemplateViolation\x12 \n" +
+	"\vtemplate_id\x18\x01 \x01(\tR\vtemplate_id\x12\x12\n" +
+	"\x04rule\x18\x02 \x01(\tR\x04rule\"\x8b\x02\n" +
+	"\vNaabuResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12C\n" +
+	"\x06output\x18\x02 \x03(\v2+.allsafe.results.v1.NaabuResult.OutputEntryR\x06output\x12\x18\n" +
+	"\apartial\x18\x03 \x01(\bR\apartial\x12.\n" +
+	"\x12unresponsive_hosts\x18\x04 \x01(\x03R\x12unresponsive_hosts\x1aU\n" +
+	"\vOutputEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.google.protobuf.ListValueR\x05value:\x028\x01\"\xa9\x01\n" +
+	"\x14ScopeExpansionResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12;\n" +
+	"\x06output\x18\x02 \x03(\v2#.allsafe.results.v1.ScopeSuggestionR\x06output\x12\"\n" +
+	"\fhosts_probed\x18\x03 \x01(\x03R\fhosts_probed\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\"\xe2\x02\n" +
+	"\x0fScopeSuggestion\x12\x12\n" +
+	"\x04apex\x18\x01 \x01(\tR\x04apex\x12=\n" +
+	"\bevidence\x18\x02 \x03(\v2!.allsafe.results.v1.ScopeEvidenceR\bevidence\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12D\n" +
+	"\x0ffirst_suggested\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x0ffirst_suggested\x12B\n" +
+	"\x0elast_suggested\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x0elast_suggested\x12\x1e\n" +
+	"\n" +
+	"decided_by\x18\x06 \x01(\tR\n" +
+	"decided_by\x12:\n" +
+	"\n" +
+	"decided_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"decided_at\";\n" +
+	"\rScopeEvidence\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x16\n" +
+	"\x06detail\x18\x02 \x01(\tR\x06detail\"\xdf\x03\n" +
+	"\x0fSubfinderResult\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x1e\n" +
+	"\n" +
+	"subdomains\x18\x02 \x03(\tR\n" +
+	"subdomains\x12J\n" +
+	"\asources\x18\x03 \x03(\v20.allsafe.results.v1.SubfinderResult.SourcesEntryR\asources\x12g\n" +
+	"\x11subdomain_sources\x18\x04 \x03(\v29.allsafe.results.v1.SubfinderResult.SubdomainSourcesEntryR\x11subdomain_sources\x12\x1a\n" +
+	"\bfiltered\x18\x05 \x01(\x03R\bfiltered\x1ab\n" +
+	"\fSourcesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12<\n" +
+	"\x05value\x18\x02 \x01(\v2&.allsafe.results.v1.PassiveSourceStatsR\x05value:\x028\x01\x1a_\n" +
+	"\x15SubdomainSourcesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x120\n" +
+	"\x05value\x18\x02 \x01(\v2\x1a.google.protobuf.ListValueR\x05value:\x028\x01\"\x82\x02\n" +
+	"\x12PassiveSourceStats\x12\x18\n" +
+	"\aresults\x18\x01 \x01(\x03R\aresults\x12\x1a\n" +
+	"\brequests\x18\x02 \x01(\x03R\brequests\x12\x18\n" +
+	"\aretries\x18\x03 \x01(\x03R\aretries\x12\x1a\n" +
+	"\bduration\x18\x04 \x01(\tR\bduration\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12*\n" +
+	"\x10budget_exhausted\x18\x06 \x01(\bR\x10budget_exhausted\x12$\n" +
+	"\rquota_limited\x18\a \x01(\bR\rquota_limited\x12\x18\n" +
+	"\askipped\x18\b \x01(\bR\askippedB4Z2github.com/allsafeASM/api/internal/models/resultpbb\x06proto3"
+
+var (
+	file_results_proto_rawDescOnce sync.Once
+	file_results_proto_rawDescData []byte
+)
+
+func file_results_proto_rawDescGZIP() []byte {
+	file_results_proto_rawDescOnce.Do(func() {
+		file_results_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_results_proto_rawDesc), len(file_results_proto_rawDesc)))
+	})
+	return file_results_proto_rawDescData
+}
+
+var file_results_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
+var file_results_proto_goTypes = []any{
+	(*CloudDNSResult)(nil),        // 0: allsafe.results.v1.CloudDNSResult
+	(*CloudDNSRecord)(nil),        // 1: allsafe.results.v1.CloudDNSRecord
+	(*DNSXResult)(nil),            // 2: allsafe.results.v1.DNSXResult
+	(*ResolutionInfo)(nil),        // 3: allsafe.results.v1.ResolutionInfo
+	(*DNSXMetadata)(nil),          // 4: allsafe.results.v1.DNSXMetadata
+	(*DNSSettings)(nil),           // 5: allsafe.results.v1.DNSSettings
+	(*ResolverHealth)(nil),        // 6: allsafe.results.v1.ResolverHealth
+	(*HttpxResult)(nil),           // 7: allsafe.results.v1.HttpxResult
+	(*HttpxHostResult)(nil),       // 8: allsafe.results.v1.HttpxHostResult
+	(*BlockEvent)(nil),            // 9: allsafe.results.v1.BlockEvent
+	(*NucleiResult)(nil),          // 10: allsafe.results.v1.NucleiResult
+	(*NucleiVulnerability)(nil),   // 11: allsafe.results.v1.NucleiVulnerability
+	(*CompliancePolicy)(nil),      // 12: allsafe.results.v1.CompliancePolicy
+	(*HostPolicy)(nil),            // 13: allsafe.results.v1.HostPolicy
+	(*SecurityTxt)(nil),           // 14: allsafe.results.v1.SecurityTxt
+	(*TemplatePolicyReport)(nil),  // 15: allsafe.results.v1.TemplatePolicyReport
+	(*TemplatePolicy)(nil),        // 16: allsafe.results.v1.TemplatePolicy
+	(*TemplateViolation)(nil),     // 17: allsafe.results.v1.TemplateViolation
+	(*NaabuResult)(nil),           // 18: allsafe.results.v1.NaabuResult
+	(*ScopeExpansionResult)(nil),  // 19: allsafe.results.v1.ScopeExpansionResult
+	(*ScopeSuggestion)(nil),       // 20: allsafe.results.v1.ScopeSuggestion
+	(*ScopeEvidence)(nil),         // 21: allsafe.results.v1.ScopeEvidence
+	(*SubfinderResult)(nil),       // 22: allsafe.results.v1.SubfinderResult
+	(*PassiveSourceStats)(nil),    // 23: allsafe.results.v1.PassiveSourceStats
+	nil,                           // 24: allsafe.results.v1.DNSXResult.OutputEntry
+	nil,                           // 25: allsafe.results.v1.DNSXMetadata.StatusCountsEntry
+	nil,                           // 26: allsafe.results.v1.DNSXMetadata.ResolverHitsEntry
+	nil,                           // 27: allsafe.results.v1.NaabuResult.OutputEntry
+	nil,                           // 28: allsafe.results.v1.SubfinderResult.SourcesEntry
+	nil,                           // 29: allsafe.results.v1.SubfinderResult.SubdomainSourcesEntry
+	(*timestamppb.Timestamp)(nil), // 30: google.protobuf.Timestamp
+	(*structpb.ListValue)(nil),    // 31: google.protobuf.ListValue
+}
+var file_results_proto_depIdxs = []int32{
+	1,  // 0: allsafe.results.v1.CloudDNSResult.output:type_name -> allsafe.results.v1.CloudDNSRecord
+	24, // 1: allsafe.results.v1.DNSXResult.output:type_name -> allsafe.results.v1.DNSXResult.OutputEntry
+	4,  // 2: allsafe.results.v1.DNSXResult.metadata:type_name -> allsafe.results.v1.DNSXMetadata
+	25, // 3: allsafe.results.v1.DNSXMetadata.status_counts:type_name -> allsafe.results.v1.DNSXMetadata.StatusCountsEntry
+	26, // 4: allsafe.results.v1.DNSXMetadata.resolver_hits:type_name -> allsafe.results.v1.DNSXMetadata.ResolverHitsEntry
+	5,  // 5: allsafe.results.v1.DNSXMetadata.settings:type_name -> allsafe.results.v1.DNSSettings
+	6,  // 6: allsafe.results.v1.DNSXMetadata.resolver_health:type_name -> allsafe.results.v1.ResolverHealth
+	8,  // 7: allsafe.results.v1.HttpxResult.output:type_name -> allsafe.results.v1.HttpxHostResult
+	9,  // 8: allsafe.results.v1.HttpxResult.blocks:type_name -> allsafe.results.v1.BlockEvent
+	30, // 9: allsafe.results.v1.BlockEvent.detected_at:type_name -> google.protobuf.Timestamp
+	11, // 10: allsafe.results.v1.NucleiResult.output:type_name -> allsafe.results.v1.NucleiVulnerability
+	9,  // 11: allsafe.results.v1.NucleiResult.blocks:type_name -> allsafe.results.v1.BlockEvent
+	12, // 12: allsafe.results.v1.NucleiResult.compliance:type_name -> allsafe.results.v1.CompliancePolicy
+	15, // 13: allsafe.results.v1.NucleiResult.template_policy:type_name -> allsafe.results.v1.TemplatePolicyReport
+	11, // 14: allsafe.results.v1.NucleiResult.unconfirmed:type_name -> allsafe.results.v1.NucleiVulnerability
+	13, // 15: allsafe.results.v1.CompliancePolicy.hosts:type_name -> allsafe.results.v1.HostPolicy
+	14, // 16: allsafe.results.v1.HostPolicy.security_txt:type_name -> allsafe.results.v1.SecurityTxt
+	16, // 17: allsafe.results.v1.TemplatePolicyReport.policy:type_name -> allsafe.results.v1.TemplatePolicy
+	17, // 18: allsafe.results.v1.TemplatePolicyReport.violations:type_name -> allsafe.results.v1.TemplateViolation
+	27, // 19: allsafe.results.v1.NaabuResult.output:type_name -> allsafe.results.v1.NaabuResult.OutputEntry
+	20, // 20: allsafe.results.v1.ScopeExpansionResult.output:type_name -> allsafe.results.v1.ScopeSuggestion
+	21, // 21: allsafe.results.v1.ScopeSuggestion.evidence:type_name -> allsafe.results.v1.ScopeEvidence
+	30, // 22: allsafe.results.v1.ScopeSuggestion.first_suggested:type_name -> google.protobuf.Timestamp
+	30, // 23: allsafe.results.v1.ScopeSuggestion.last_suggested:type_name -> google.protobuf.Timestamp
+	30, // 24: allsafe.results.v1.ScopeSuggestion.decided_at:type_name -> google.protobuf.Timestamp
+	28, // 25: allsafe.results.v1.SubfinderResult.sources:type_name -> allsafe.results.v1.SubfinderResult.SourcesEntry
+	29, // 26: allsafe.results.v1.SubfinderResult.subdomain_sources:type_name -> allsafe.results.v1.SubfinderResult.SubdomainSourcesEntry
+	3,  // 27: allsafe.results.v1.DNSXResult.OutputEntry.value:type_name -> allsafe.results.v1.ResolutionInfo
+	31, // 28: allsafe.results.v1.NaabuResult.OutputEntry.value:type_name -> google.protobuf.ListValue
+	23, // 29: allsafe.results.v1.SubfinderResult.SourcesEntry.value:type_name -> allsafe.results.v1.PassiveSourceStats
+	31, // 30: allsafe.results.v1.SubfinderResult.SubdomainSourcesEntry.value:type_name -> google.protobuf.ListValue
+	31, // [31:31] is the sub-list for method output_type
+	31, // [31:31] is the sub-list for method input_type
+	31, // [31:31] is the sub-list for extension type_name
+	31, // [31:31] is the sub-list for extension extendee
+	0,  // [0:31] is the sub-list for field type_name
+}
+
+func init() { file_results_proto_init() }
+func file_results_proto_init() {
+	if File_results_proto != nil {
+		return
+	}
+	file_results_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_results_proto_rawDesc), len(file_results_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   30,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_results_proto_goTypes,
+		DependencyIndexes: file_results_proto_depIdxs,
+		MessageInfos:      file_results_proto_msgTypes,
+	}.Build()
+	File_results_proto = out.File
+	file_results_proto_goTypes = nil
+	file_results_proto_depIdxs = nil
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScanRetriesBlobPath returns the blob path of the retries charged to a scan. Like pause
+// markers, it lives outside the per-domain prefix so every task of the scan shares it.
+func ScanRetriesBlobPath(tenantID string, scanID int) string {
+	path := fmt.Sprintf("control/retries/scan-%d.json", scanID)
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// ScanRetries counts the retries of all tasks of a scan against its retry budget
+type ScanRetries struct {
+	ScanID    int          `json:"scan_id"`
+	TenantID  string       `json:"tenant_id,omitempty"`
+	Retries   int          `json:"retries"`
+	ByTask    map[Task]int `json:"by_task,omitempty"`
+	LastError string       `json:"last_error,omitempty"`
+	HaltedAt  time.Time    `json:"halted_at,omitzero"` // When the scan used up its budget and was paused
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Charge records a retry of a task and reports whether it used up the budget. Only the retry
+// that crosses the budget reports it, so the scan is halted and operators are notified once.
+func (r *ScanRetries) Charge(task Task, lastError string, budget int, now time.Time) bool {
+	if r.ByTask == nil {
+		r.ByTask = make(map[Task]int)
+	}
+	r.Retries++
+	r.ByTask[task]++
+	r.LastError = lastError
+	r.UpdatedAt = now
+	if r.Retries <= budget || !r.HaltedAt.IsZero() {
+		return false
+	}
+	r.HaltedAt = now
+	return true
+}
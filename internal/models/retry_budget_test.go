@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestScanRetries_Charge tests that a scan is halted once, by the retry that crosses its budget
+func TestScanRetries_Charge(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	retries := ScanRetries{ScanID: 7}
+
+	for i := 0; i < 3; i++ {
+		if retries.Charge(TaskNuclei, "connection reset", 3, now) {
+			t.Fatalf("Expected retry %d to stay within the budget", i+1)
+		}
+	}
+	if !retries.Charge(TaskHttpx, "timeout", 3, now) {
+		t.Fatal("Expected the fourth retry to use up a budget of 3")
+	}
+	if retries.Charge(TaskHttpx, "timeout", 3, now) {
+		t.Error("Expected a scan to be halted only once")
+	}
+
+	if retries.Retries != 5 || retries.ByTask[TaskNuclei] != 3 || retries.ByTask[TaskHttpx] != 2 {
+		t.Errorf("Unexpected retry counts: %+v", retries)
+	}
+	if retries.LastError != "timeout" || !retries.HaltedAt.Equal(now) {
+		t.Errorf("Expected the last error and halt time to be recorded, got %+v", retries)
+	}
+}
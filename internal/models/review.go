@@ -0,0 +1,43 @@
+package models
+
+import "fmt"
+
+// HTTPExchange is a request a scanner sent and the response it got, as raw HTTP
+type HTTPExchange struct {
+	URL              string              `json:"url"`
+	StatusCode       int                 `json:"status_code"`
+	ChainStatusCodes []int               `json:"chain_status_codes,omitempty"` // Status codes of the redirects followed
+	Request          string              `json:"request"`
+	Response         string              `json:"response"`
+	Headers          map[string][]string `json:"-"` // Response headers, for classifying the exchange
+	BodyLength       int                 `json:"-"` // Length of the final response body
+}
+
+// ReviewSample is an exchange kept for manual review, with why it was considered interesting
+type ReviewSample struct {
+	HTTPExchange
+	Reasons   []string `json:"reasons"`
+	Truncated bool     `json:"truncated,omitempty"` // True when the request or response was cut to the size limit
+	SampledAt string   `json:"sampled_at"`
+}
+
+// ReviewSamples are the exchanges of one task attempt kept for manual review
+type ReviewSamples struct {
+	Task          Task           `json:"task"`
+	ScanID        int            `json:"scan_id"`
+	Domain        string         `json:"domain"`
+	TenantID      string         `json:"tenant_id,omitempty"`
+	CorrelationID string         `json:"correlation_id,omitempty"`
+	Candidates    int            `json:"candidates"` // Interesting exchanges seen, sampled or not
+	Samples       []ReviewSample `json:"samples"`
+}
+
+// ReviewSamplesBlobPath returns the blob path of the review samples of a task attempt. Samples
+// are kept under one review folder, apart from the results, so reviewers can list them across scans.
+func ReviewSamplesBlobPath(tenantID, domain string, scanID int, task string, attempt int) string {
+	path := fmt.Sprintf("review/%s-%d/%s/%s", DomainBlobSegment(domain), scanID, task, AttemptBlobName(attempt, ".json"))
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// ScanScopeBlobPath returns the blob path of the scope imported from asset sources
+func ScanScopeBlobPath(tenantID string) string {
+	path := "control/scope.json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// Types of scope assets
+const (
+	ScopeAssetDomain  = "domain"
+	ScopeAssetIPRange = "ip_range"
+)
+
+// ScopeEntry is an asset reported by an asset source, normalized to a lower-case domain or a CIDR
+type ScopeEntry struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// ScanScope holds the domains and IP ranges imported from asset sources such as a CMDB
+type ScanScope struct {
+	TenantID  string                 `json:"tenant_id,omitempty"`
+	Assets    map[string]*ScopeAsset `json:"assets"` // Keyed by domain or CIDR
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// ScopeAsset is an imported asset and the sources that currently report it
+type ScopeAsset struct {
+	Type          string    `json:"type"`
+	Sources       []string  `json:"sources"`
+	FirstImported time.Time `json:"first_imported"`
+	LastImported  time.Time `json:"last_imported"`
+	RemovedAt     time.Time `json:"removed_at,omitzero"`      // Set when no source reports the asset anymore
+	ScanPending   bool      `json:"scan_pending,omitempty"`   // The scan of a newly imported domain is not queued yet
+	ScanClaimedAt time.Time `json:"scan_claimed_at,omitzero"` // When a sync last took on queuing the scan
+}
+
+// Reconcile replaces what source reports with entries as of now and returns the entries that
+// were not in scope before, or were removed and are reported again. Assets the source no longer
+// reports lose it, and are marked removed once no source reports them; they are kept for history.
+func (s *ScanScope) Reconcile(source string, entries []ScopeEntry, now time.Time) []ScopeEntry {
+	if s.Assets == nil {
+		s.Assets = make(map[string]*ScopeAsset)
+	}
+
+	reported := make(map[string]struct{}, len(entries))
+	var added []ScopeEntry
+	for _, entry := range entries {
+		if _, ok := reported[entry.Value]; ok {
+			continue
+		}
+		reported[entry.Value] = struct{}{}
+
+		asset, ok := s.Assets[entry.Value]
+		if !ok {
+			asset = &ScopeAsset{Type: entry.Type, FirstImported: now}
+			s.Assets[entry.Value] = asset
+		}
+		if !ok || !asset.RemovedAt.IsZero() {
+			added = append(added, entry)
+			asset.ScanPending = entry.Type == ScopeAssetDomain
+			asset.ScanClaimedAt = time.Time{}
+		}
+		asset.RemovedAt = time.Time{}
+		asset.LastImported = now
+		if !slices.Contains(asset.Sources, source) {
+			asset.Sources = append(asset.Sources, source)
+			sort.Strings(asset.Sources)
+		}
+	}
+
+	for value, asset := range s.Assets {
+		if _, ok := reported[value]; ok || !slices.Contains(asset.Sources, source) {
+			continue
+		}
+		asset.Sources = slices.DeleteFunc(asset.Sources, func(name string) bool { return name == source })
+		if len(asset.Sources) == 0 && asset.RemovedAt.IsZero() {
+			asset.RemovedAt = now
+			asset.ScanPending = false
+		}
+	}
+
+	s.UpdatedAt = now
+	sort.Slice(added, func(i, j int) bool { return added[i].Value < added[j].Value })
+	return added
+}
+
+// ClaimScans returns the domains whose scan is still to be queued, skipping those another sync
+// claimed less than lease ago, and claims them as of now. A claim whose scan was not queued in
+// time lapses, so the next sync queues it.
+func (s *ScanScope) ClaimScans(now time.Time, lease time.Duration) []ScopeEntry {
+	var claimed []ScopeEntry
+	for value, asset := range s.Assets {
+		if !asset.ScanPending || now.Sub(asset.ScanClaimedAt) < lease {
+			continue
+		}
+		asset.ScanClaimedAt = now
+		claimed = append(claimed, ScopeEntry{Value: value, Type: asset.Type})
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].Value < claimed[j].Value })
+	return claimed
+}
+
+// ScansQueued records that the scans of the given domains were queued
+func (s *ScanScope) ScansQueued(values []string) {
+	for _, value := range values {
+		if asset, ok := s.Assets[value]; ok {
+			asset.ScanPending = false
+			asset.ScanClaimedAt = time.Time{}
+		}
+	}
+}
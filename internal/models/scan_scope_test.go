@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanScope_Reconcile(t *testing.T) {
+	monday := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	scope := &ScanScope{}
+
+	added := scope.Reconcile("csv", []ScopeEntry{
+		{Value: "example.com", Type: ScopeAssetDomain},
+		{Value: "192.0.2.0/24", Type: ScopeAssetIPRange},
+		{Value: "example.com", Type: ScopeAssetDomain},
+	}, monday)
+	if len(added) != 2 || added[0].Value != "192.0.2.0/24" || added[1].Value != "example.com" {
+		t.Fatalf("Expected both assets to be new and sorted, got %+v", added)
+	}
+
+	added = scope.Reconcile("cloudflare", []ScopeEntry{{Value: "example.com", Type: ScopeAssetDomain}}, monday)
+	if len(added) != 0 {
+		t.Errorf("Expected an asset reported by a second source not to be new, got %+v", added)
+	}
+	if sources := scope.Assets["example.com"].Sources; len(sources) != 2 || sources[0] != "cloudflare" {
+		t.Errorf("Expected both sources sorted, got %v", sources)
+	}
+
+	// The CSV drops both assets: the domain is still reported by Cloudflare, the range by nobody
+	scope.Reconcile("csv", nil, tuesday)
+	if domain := scope.Assets["example.com"]; !domain.RemovedAt.IsZero() || len(domain.Sources) != 1 {
+		t.Errorf("Expected the domain to stay in scope through Cloudflare, got %+v", domain)
+	}
+	if network := scope.Assets["192.0.2.0/24"]; !network.RemovedAt.Equal(tuesday) || len(network.Sources) != 0 {
+		t.Errorf("Expected the range to be marked removed, got %+v", network)
+	}
+
+	// A removed asset that is reported again counts as new
+	added = scope.Reconcile("csv", []ScopeEntry{{Value: "192.0.2.0/24", Type: ScopeAssetIPRange}}, tuesday.Add(time.Hour))
+	if len(added) != 1 || !scope.Assets["192.0.2.0/24"].RemovedAt.IsZero() {
+		t.Errorf("Expected the range to be re-added, got %+v", added)
+	}
+	if !scope.Assets["192.0.2.0/24"].FirstImported.Equal(monday) {
+		t.Error("Expected the first import time to be kept")
+	}
+}
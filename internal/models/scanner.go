@@ -2,11 +2,14 @@ package models
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
+	"time"
 )
 
-// Scanner defines the interface for all security scanners
+// Scanner defines the interface for all security scanners. The task context may be nil.
 type Scanner interface {
-	Execute(ctx context.Context, input interface{}) (ScannerResult, error)
+	Execute(ctx context.Context, taskCtx *TaskContext, input interface{}) (ScannerResult, error)
 	GetName() string
 	GetBaseScanner() interface{} // Return interface{} to avoid import cycle
 }
@@ -17,6 +20,20 @@ type ScannerResult interface {
 	GetDomain() string
 }
 
+// PartialResult is implemented by scanner results that can be returned incomplete
+// when the scan is cancelled or times out before finishing
+type PartialResult interface {
+	ScannerResult
+	IsPartial() bool
+}
+
+// IsPartialResult reports whether the given result was cut short by a timeout or cancellation, or
+// left targets out that it was asked to cover
+func IsPartialResult(result ScannerResult) bool {
+	partial, ok := result.(PartialResult)
+	return ok && partial.IsPartial()
+}
+
 // ScannerInput represents the base interface for all scanner inputs
 type ScannerInput interface {
 	GetDomain() string
@@ -25,7 +42,9 @@ type ScannerInput interface {
 
 // SubfinderInput represents input for the subfinder scanner
 type SubfinderInput struct {
-	Domain string `json:"domain"`
+	Domain  string           `json:"domain"`
+	Exclude TargetExclusions `json:"exclude,omitzero"` // Subdomains left out of the result
+	Filter  SubdomainFilter  `json:"filter,omitzero"`  // Patterns the discovered subdomains must pass
 }
 
 func (s SubfinderInput) GetDomain() string {
@@ -38,8 +57,31 @@ func (s SubfinderInput) GetScannerName() string {
 
 // SubfinderResult represents the result of a subfinder scan
 type SubfinderResult struct {
-	Domain     string   `json:"domain"`
-	Subdomains []string `json:"subdomains"`
+	Domain     string                        `json:"domain"`
+	Subdomains []string                      `json:"subdomains"`
+	Sources    map[string]PassiveSourceStats `json:"sources,omitempty"` // Statistics of the passive sources queried
+	// Names of the sources that found each subdomain: passive source names, subfinder sources
+	// prefixed with SubfinderSourcePrefix, and SubdomainSourceInput for the scanned domain itself
+	SubdomainSources map[string][]string `json:"subdomain_sources,omitempty"`
+	// Filtered counts the discovered subdomains dropped by the task's include and exclude patterns
+	Filtered int `json:"filtered,omitempty"`
+}
+
+const (
+	SubfinderSourcePrefix = "subfinder:" // Prefix of the sources queried through subfinder
+	SubdomainSourceInput  = "input"      // Source of the scanned domain, which is always included
+)
+
+// PassiveSourceStats reports how a passive subdomain source performed during a scan
+type PassiveSourceStats struct {
+	Results         int    `json:"results"`
+	Requests        int    `json:"requests"`
+	Retries         int    `json:"retries,omitempty"`
+	Duration        string `json:"duration"`
+	Error           string `json:"error,omitempty"`
+	BudgetExhausted bool   `json:"budget_exhausted,omitempty"` // The source stopped because it used its request budget
+	QuotaLimited    bool   `json:"quota_limited,omitempty"`    // The API key's quota allowed fewer requests than the budget
+	Skipped         bool   `json:"skipped,omitempty"`          // The API key's quota is exhausted, so the source was not queried
 }
 
 func (r SubfinderResult) GetCount() int {
@@ -52,8 +94,15 @@ func (r SubfinderResult) GetDomain() string {
 
 // HttpxInput represents input for the httpx scanner
 type HttpxInput struct {
-	Domain    string `json:"domain"`
-	InputPath string `json:"input_path,omitempty"` // Local path to the input file for httpx
+	Domain         string `json:"domain"`
+	InputPath      string `json:"input_path,omitempty"`      // Local path to the input file for httpx
+	TLSFingerprint string `json:"tls_fingerprint,omitempty"` // TLS ClientHello fingerprint to probe with; Go's own when empty
+
+	// Exclude lists targets of the input file that are not probed
+	Exclude TargetExclusions `json:"exclude,omitzero"`
+
+	// OnResponse, when set, receives the raw request and response of every probe
+	OnResponse func(exchange HTTPExchange) `json:"-"`
 }
 
 func (h HttpxInput) GetDomain() string {
@@ -79,8 +128,15 @@ type HttpxHostResult struct {
 
 // HttpxResult represents the result of an httpx scan
 type HttpxResult struct {
-	Domain  string            `json:"domain"`
-	Results []HttpxHostResult `json:"output"`
+	Domain   string            `json:"domain"`
+	Results  []HttpxHostResult `json:"output"`
+	Partial  bool              `json:"partial,omitempty"`  // True when the scan was cut short by a timeout or cancellation, or skipped aborted host groups
+	Probed   int               `json:"probed,omitempty"`   // Distinct input targets probed, answering or not
+	Answered int               `json:"answered,omitempty"` // Distinct input targets that answered on any URL
+	// TLSFingerprint is set when the hosts were probed with a TLS fingerprint other than Go's own
+	TLSFingerprint string `json:"tls_fingerprint,omitempty"`
+	// Blocks lists the host groups whose responses shifted to a WAF or rate-limit ban mid-scan
+	Blocks []BlockEvent `json:"blocks,omitempty"`
 }
 
 func (r HttpxResult) GetCount() int {
@@ -91,16 +147,94 @@ func (r HttpxResult) GetDomain() string {
 	return r.Domain
 }
 
+func (r HttpxResult) IsPartial() bool {
+	return r.Partial
+}
+
 // DNSXInput represents input for the dnsx scanner
 type DNSXInput struct {
-	Domain            string   `json:"domain"`
-	Subdomains        []string `json:"subdomains,omitempty"`      // List of subdomains to resolve
-	HostsFileLocation string   `json:"input_blob_path,omitempty"` // The location of where the hosts file is located from blob storage
+	Domain            string      `json:"domain"`
+	Subdomains        []string    `json:"subdomains,omitempty"`      // List of subdomains to resolve
+	HostsFileLocation string      `json:"input_blob_path,omitempty"` // The location of where the hosts file is located from blob storage
+	SkipTargets       []string    `json:"skip_targets,omitempty"`    // Subdomains already resolved by a checkpointed run
+	Settings          DNSSettings `json:"settings,omitempty"`        // Per-task overrides of the default DNS settings
+
+	// Exclude lists names that are not resolved
+	Exclude TargetExclusions `json:"exclude,omitzero"`
 	// Future fields could include:
-	// RecordTypes []string `json:"record_types,omitempty"`
 	// Resolvers []string `json:"resolvers,omitempty"`
 }
 
+// DNSSettings controls how the DNSX client queries resolvers. Zero values inherit the defaults.
+type DNSSettings struct {
+	Retries       int      `json:"retries,omitempty"`        // Attempts per DNS question
+	TimeoutMs     int      `json:"timeout_ms,omitempty"`     // Per-attempt timeout in milliseconds
+	QuestionTypes []string `json:"question_types,omitempty"` // Record types to query, e.g. ["A", "CNAME"]
+	Hostsfile     *bool    `json:"hostsfile,omitempty"`      // Answer from the local hosts file before querying resolvers
+	RetryPass     *bool    `json:"retry_pass,omitempty"`     // Re-query failed names with alternate resolvers and a higher timeout
+}
+
+// Merge returns the settings with every field set in override replacing its default
+func (d DNSSettings) Merge(override DNSSettings) DNSSettings {
+	merged := d
+	if override.Retries > 0 {
+		merged.Retries = override.Retries
+	}
+	if override.TimeoutMs > 0 {
+		merged.TimeoutMs = override.TimeoutMs
+	}
+	if len(override.QuestionTypes) > 0 {
+		merged.QuestionTypes = override.QuestionTypes
+	}
+	if override.Hostsfile != nil {
+		merged.Hostsfile = override.Hostsfile
+	} else if len(override.QuestionTypes) > 0 && !merged.QueriesAddresses() {
+		// The hosts file only provides addresses, so an inherited hosts file is turned off for
+		// question types without A or AAAA instead of failing validation
+		disabled := false
+		merged.Hostsfile = &disabled
+	}
+	if override.RetryPass != nil {
+		merged.RetryPass = override.RetryPass
+	}
+	return merged
+}
+
+// QueriesAddresses reports whether A or AAAA records are among the question types
+func (d DNSSettings) QueriesAddresses() bool {
+	for _, questionType := range d.QuestionTypes {
+		switch strings.ToUpper(strings.TrimSpace(questionType)) {
+		case "A", "AAAA":
+			return true
+		}
+	}
+	return false
+}
+
+// UsesHostsfile reports whether the hosts file is enabled
+func (d DNSSettings) UsesHostsfile() bool {
+	return d.Hostsfile != nil && *d.Hostsfile
+}
+
+// UsesRetryPass reports whether failed names get a second resolution pass
+func (d DNSSettings) UsesRetryPass() bool {
+	return d.RetryPass != nil && *d.RetryPass
+}
+
+// DNSQuestionTypes maps the supported DNS record type names to their wire values
+var DNSQuestionTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"SOA":   6,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+	"SRV":   33,
+	"CAA":   257,
+}
+
 func (d DNSXInput) GetDomain() string {
 	return d.Domain
 }
@@ -111,18 +245,146 @@ func (d DNSXInput) GetScannerName() string {
 
 // DNSXResult represents the result of a dnsx scan
 type DNSXResult struct {
-	Domain  string                    `json:"domain"`
-	Records map[string]ResolutionInfo `json:"output"`
-}
+	Domain   string                    `json:"domain"`
+	Records  map[string]ResolutionInfo `json:"output"`
+	Partial  bool                      `json:"partial,omitempty"`  // True when the scan was cut short by a timeout or cancellation
+	Metadata *DNSXMetadata             `json:"metadata,omitempty"` // Aggregate query statistics for quality monitoring
+	// Large runs stream their records to a gzipped NDJSON blob of DNSRecordLine instead of
+	// holding them in Records
+	RecordsBlob  string `json:"records_blob,omitempty"`
+	RecordsCount int    `json:"records_count,omitempty"` // Lines in RecordsBlob
+	RecordsIndex string `json:"records_index,omitempty"` // Blob of the RecordsIndex for reading pages of RecordsBlob
+}
+
+// DNSRecordLine is one line of a streamed DNSX records blob
+type DNSRecordLine struct {
+	Host string `json:"host"`
+	ResolutionInfo
+}
+
+// DNSStatus is the resolution status reported per queried name
+type DNSStatus string
+
+const (
+	DNSStatusResolved    DNSStatus = "resolved"     // At least one record was returned
+	DNSStatusNotResolved DNSStatus = "not_resolved" // The name exists but has no records of the queried types
+	DNSStatusNXDomain    DNSStatus = "nxdomain"     // The name does not exist
+	DNSStatusServFail    DNSStatus = "servfail"     // The authoritative servers failed to answer
+	DNSStatusRefused     DNSStatus = "refused"      // The resolver refused the query
+	DNSStatusTimeout     DNSStatus = "timeout"      // No resolver answered within the retries
+	DNSStatusError       DNSStatus = "error"        // Any other failure
+)
 
 // ResolutionInfo represents DNS resolution information for a record type
 type ResolutionInfo struct {
-	Status string   `json:"status"`
-	A      []string `json:"A,omitempty"`
-	CNAME  []string `json:"CNAME,omitempty"`
+	Status     DNSStatus `json:"status"`
+	A          []string  `json:"A,omitempty"`
+	AAAA       []string  `json:"AAAA,omitempty"`
+	CNAME      []string  `json:"CNAME,omitempty"`
+	MX         []string  `json:"MX,omitempty"`
+	NS         []string  `json:"NS,omitempty"`
+	TXT        []string  `json:"TXT,omitempty"`
+	SRV        []string  `json:"SRV,omitempty"`
+	CAA        []string  `json:"CAA,omitempty"`
+	PTR        []string  `json:"PTR,omitempty"`
+	CNAMEChain []string  `json:"cname_chain,omitempty"` // Ordered CNAME hops from the queried name to the final target
+	Dangling   bool      `json:"dangling,omitempty"`    // True when the CNAME chain terminates in NXDOMAIN
+	Resolver   string    `json:"resolver,omitempty"`    // Resolver that gave the final answer
+	RTTMs      int64     `json:"rtt_ms,omitempty"`      // Lookup time in milliseconds, including retries
+	Retried    bool      `json:"retried,omitempty"`     // True when the answer comes from the retry pass
+}
+
+// IsTransientFailure reports whether the lookup failed in a way that may succeed when retried.
+// NXDOMAIN and empty answers are definitive and are not retried.
+func (r ResolutionInfo) IsTransientFailure() bool {
+	switch r.Status {
+	case DNSStatusServFail, DNSStatusRefused, DNSStatusTimeout, DNSStatusError:
+		return true
+	}
+	return false
+}
+
+// DNSXMetadata holds aggregate query statistics for a DNSX scan
+type DNSXMetadata struct {
+	Queries        int               `json:"queries"`                   // Number of names queried
+	StatusCounts   map[DNSStatus]int `json:"status_counts"`             // Number of names per resolution status
+	ResolverHits   map[string]int    `json:"resolver_hits"`             // Number of final answers per resolver
+	Retried        int               `json:"retried"`                   // Names re-queried by the retry pass
+	Recovered      int               `json:"recovered"`                 // Retried names that got a definitive answer
+	AvgRTTMs       int64             `json:"avg_rtt_ms"`                // Mean lookup time in milliseconds
+	MaxRTTMs       int64             `json:"max_rtt_ms"`                // Slowest lookup time in milliseconds
+	Workers        int               `json:"workers,omitempty"`         // Worker count chosen for the run
+	RateLimit      int               `json:"rate_limit,omitempty"`      // Queries per second allowed for the run
+	Settings       *DNSSettings      `json:"settings,omitempty"`        // Effective DNS settings of the run
+	ResolverHealth []ResolverHealth  `json:"resolver_health,omitempty"` // Final health of every upstream resolver
+}
+
+// ResolverHealth holds the query outcomes of one upstream resolver during a DNSX scan
+type ResolverHealth struct {
+	Resolver  string  `json:"resolver"`
+	Queries   int     `json:"queries"`
+	Errors    int     `json:"errors"`     // Refused and failed queries
+	Timeouts  int     `json:"timeouts"`   // Queries without an answer
+	ErrorRate float64 `json:"error_rate"` // (errors + timeouts) / queries
+	Evictions int     `json:"evictions"`  // Times the resolver was left out for a high error rate
+	Evicted   bool    `json:"evicted"`    // True when the resolver was still evicted at the end of the run
+}
+
+// SummarizeDNSRecords aggregates per-name resolution info into scan-level statistics
+func SummarizeDNSRecords(records map[string]ResolutionInfo) *DNSXMetadata {
+	summary := NewDNSSummary()
+	for _, info := range records {
+		summary.Add(info)
+	}
+	return summary.Metadata()
+}
+
+// DNSSummary aggregates resolution info one name at a time, so streamed runs keep only counters
+type DNSSummary struct {
+	metadata *DNSXMetadata
+	totalRTT int64
+}
+
+// NewDNSSummary creates an empty summary
+func NewDNSSummary() *DNSSummary {
+	return &DNSSummary{metadata: &DNSXMetadata{
+		StatusCounts: make(map[DNSStatus]int),
+		ResolverHits: make(map[string]int),
+	}}
+}
+
+// Add counts the resolution info of one name
+func (s *DNSSummary) Add(info ResolutionInfo) {
+	metadata := s.metadata
+	metadata.Queries++
+	metadata.StatusCounts[info.Status]++
+	if info.Resolver != "" {
+		metadata.ResolverHits[info.Resolver]++
+	}
+	if info.Retried {
+		metadata.Retried++
+		if !info.IsTransientFailure() {
+			metadata.Recovered++
+		}
+	}
+	s.totalRTT += info.RTTMs
+	if info.RTTMs > metadata.MaxRTTMs {
+		metadata.MaxRTTMs = info.RTTMs
+	}
+}
+
+// Metadata returns the statistics of the names added so far
+func (s *DNSSummary) Metadata() *DNSXMetadata {
+	if s.metadata.Queries > 0 {
+		s.metadata.AvgRTTMs = s.totalRTT / int64(s.metadata.Queries)
+	}
+	return s.metadata
 }
 
 func (r DNSXResult) GetCount() int {
+	if r.RecordsBlob != "" {
+		return r.RecordsCount
+	}
 	return len(r.Records)
 }
 
@@ -130,17 +392,32 @@ func (r DNSXResult) GetDomain() string {
 	return r.Domain
 }
 
+func (r DNSXResult) IsPartial() bool {
+	return r.Partial
+}
+
 // NaabuInput represents input for the naabu scanner
 type NaabuInput struct {
 	Domain            string   `json:"domain"`
-	IPs               []string `json:"ips,omitempty"`             // List of IPs to scan
-	HostsFileLocation string   `json:"input_blob_path,omitempty"` // The location of where the hosts file is located from blob storage
-	Ports             []int    `json:"ports,omitempty"`           // Specific ports to scan
-	PortRange         string   `json:"port_range,omitempty"`      // Port range (e.g., "1-1000")
-	TopPorts          string   `json:"top_ports,omitempty"`       // Number of top ports to scan (valid values: "full", "100", "1000")
-	RateLimit         int      `json:"rate_limit,omitempty"`      // Rate limit for scanning
-	Concurrency       int      `json:"concurrency,omitempty"`     // Number of concurrent scans
-	Timeout           int      `json:"timeout,omitempty"`         // Timeout in seconds
+	IPs               []string `json:"ips,omitempty"`              // List of IPs to scan
+	HostsFileLocation string   `json:"input_blob_path,omitempty"`  // The location of where the hosts file is located from blob storage
+	Ports             []int    `json:"ports,omitempty"`            // Specific ports to scan
+	PortRange         string   `json:"port_range,omitempty"`       // Port range (e.g., "1-1000")
+	TopPorts          string   `json:"top_ports,omitempty"`        // Number of top ports to scan (valid values: "full", "100", "1000")
+	RateLimit         int      `json:"rate_limit,omitempty"`       // Rate limit for scanning
+	Concurrency       int      `json:"concurrency,omitempty"`      // Number of concurrent scans
+	Timeout           int      `json:"timeout,omitempty"`          // Timeout in seconds
+	HostDiscovery     bool     `json:"host_discovery,omitempty"`   // Let naabu skip hosts that do not answer discovery probes
+	PreFilter         bool     `json:"pre_filter,omitempty"`       // Run a discovery-only pass and port scan only the live hosts
+	DiscoveryProbes   []string `json:"discovery_probes,omitempty"` // Probes for host discovery, e.g. "icmp-echo", "arp", "tcp-syn:80,443"
+	SourcePort        int      `json:"source_port,omitempty"`      // Source port of the probes, e.g. one that NAT or egress rules let through
+	SourceIP          string   `json:"source_ip,omitempty"`        // Source IP of the probes on multi-homed workers
+	Interface         string   `json:"interface,omitempty"`        // Network interface to scan from, e.g. "eth1"
+	CDNPorts          []int    `json:"cdn_ports,omitempty"`        // Ports scanned on CDN and WAF IPs instead of naabu's 80 and 443
+	ScanCDN           bool     `json:"scan_cdn,omitempty"`         // Scan CDN and WAF IPs on every requested port
+
+	// Exclude lists IPs and CIDRs that are not scanned
+	Exclude TargetExclusions `json:"exclude,omitzero"`
 }
 
 func (n NaabuInput) GetDomain() string {
@@ -153,8 +430,10 @@ func (n NaabuInput) GetScannerName() string {
 
 // NaabuResult represents the result of a naabu scan
 type NaabuResult struct {
-	Domain string                `json:"domain"`
-	Ports  map[string][]PortInfo `json:"output"` // IP -> []PortInfo
+	Domain            string                `json:"domain"`
+	Ports             map[string][]PortInfo `json:"output"`                       // IP -> []PortInfo
+	Partial           bool                  `json:"partial,omitempty"`            // True when the scan was cut short by a timeout or cancellation
+	UnresponsiveHosts int                   `json:"unresponsive_hosts,omitempty"` // Hosts dropped by the pre-filter for not answering discovery probes
 }
 
 // PortInfo represents information about an open port
@@ -176,11 +455,52 @@ func (r NaabuResult) GetDomain() string {
 	return r.Domain
 }
 
+func (r NaabuResult) IsPartial() bool {
+	return r.Partial
+}
+
 // NucleiInput represents input for the nuclei scanner
 type NucleiInput struct {
-	Domain            string `json:"domain"`
-	HostsFileLocation string `json:"input_blob_path,omitempty"` // The location of where the hosts file is located from blob storage
-	Type              string `json:"type,omitempty"`            // Type of nuclei scan (e.g., "http")
+	Domain            string   `json:"domain"`
+	HostsFileLocation string   `json:"input_blob_path,omitempty"`  // The location of where the hosts file is located from blob storage
+	Hosts             []string `json:"hosts,omitempty"`            // Targets taken from an earlier stage's result
+	Type              string   `json:"type,omitempty"`             // Type of nuclei scan (e.g., "http")
+	CustomTemplates   string   `json:"custom_templates,omitempty"` // Name of the tenant's custom templates directory in blob storage
+
+	// Exclude lists targets that are not scanned
+	Exclude TargetExclusions `json:"exclude,omitzero"`
+
+	// InteractshServer overrides the worker's interactsh server for OOB templates
+	InteractshServer string `json:"interactsh_server,omitempty"`
+	// DisableInteractsh turns off OOB interactions, e.g. when tenant policy forbids callbacks
+	DisableInteractsh bool `json:"disable_interactsh,omitempty"`
+
+	// TemplatesPrefix is the blob prefix the custom templates are synced from
+	TemplatesPrefix string `json:"-"`
+	// ResumeState is nuclei's resume config from an interrupted run of the same task
+	ResumeState json.RawMessage `json:"-"`
+	// OnCheckpoint, when set, makes the scan resumable and periodically receives its progress
+	OnCheckpoint func(progress NucleiResult) `json:"-"`
+	// OnResult, when set, receives every finding as soon as nuclei reports it
+	OnResult func(finding NucleiVulnerability) `json:"-"`
+
+	// Compliance fetches robots.txt and security.txt of the targets first, skips targets robots.txt
+	// disallows and flags findings on disallowed paths for legal review
+	Compliance bool `json:"compliance,omitempty"`
+	// ComplianceUserAgent is sent with every request in compliance mode and picks the robots.txt rules
+	ComplianceUserAgent string `json:"-"`
+
+	// HostBudget, when set, skips the remaining requests to a target once this long has passed
+	// since its first request, so one slow target cannot hold up the rest
+	HostBudget time.Duration `json:"-"`
+	// ScanBudget, when set, ends the scan with the findings so far once it has run this long
+	ScanBudget time.Duration `json:"-"`
+
+	// Replay runs the templates of the selected findings again once the scan ends. Selected
+	// findings reach OnResult only when the replay confirmed them.
+	Replay FindingReplay `json:"-"`
+	// TemplatePolicy excludes the templates of the tenant's policy from the scan
+	TemplatePolicy *TemplatePolicy `json:"-"`
 }
 
 func (n NucleiInput) GetDomain() string {
@@ -204,12 +524,40 @@ type NucleiVulnerability struct {
 	Description      string   `json:"description,omitempty"`
 	Reference        []string `json:"reference,omitempty"`
 	Severity         string   `json:"severity,omitempty"`
+	MatcherName      string   `json:"matcher_name,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	CVEIDs           []string `json:"cve_ids,omitempty"`
+	CWEIDs           []string `json:"cwe_ids,omitempty"`
+	CVSSScore        float64  `json:"cvss_score,omitempty"`
+	CVSSMetrics      string   `json:"cvss_metrics,omitempty"`
+	EPSSScore        float64  `json:"epss_score,omitempty"`
+	CurlCommand      string   `json:"curl_command,omitempty"` // Command to reproduce the request, for HTTP findings
+	// ComplianceReview tells why the finding needs legal review in compliance mode, e.g. a path robots.txt disallows
+	ComplianceReview string `json:"compliance_review,omitempty"`
+	// Verification is FindingVerified for a finding the replay confirmed, or FindingUnreplayed for
+	// one selected for replay that was reported without it
+	Verification string `json:"verification,omitempty"`
 }
 
 // NucleiResult represents the result of a nuclei scan
 type NucleiResult struct {
 	Domain          string                `json:"domain"`
 	Vulnerabilities []NucleiVulnerability `json:"output"`
+	Partial         bool                  `json:"partial,omitempty"` // True when the scan was cut short, skipped aborted host groups or time-boxed targets
+	ResumeState     json.RawMessage       `json:"-"`                 // Nuclei resume config to continue an interrupted scan
+	// Blocks lists the host groups whose responses shifted to a WAF or rate-limit ban mid-scan
+	Blocks []BlockEvent `json:"blocks,omitempty"`
+	// Compliance records the robots.txt and security.txt policies honored in compliance mode
+	Compliance *CompliancePolicy `json:"compliance,omitempty"`
+	// TimeBoxed lists the targets whose remaining requests were skipped when their time budget ran out
+	TimeBoxed []string `json:"time_boxed,omitempty"`
+	// Unscanned lists the targets of a scan with a host budget that were not reached before it was interrupted
+	Unscanned []string `json:"unscanned,omitempty"`
+	// TemplatePolicy records the templates the tenant's template policy excluded
+	TemplatePolicy *TemplatePolicyReport `json:"template_policy,omitempty"`
+	// Unconfirmed lists the findings the replay did not match again. They are kept out of the
+	// output, so nothing reports them, and findings reported earlier are not resolved by them.
+	Unconfirmed []NucleiVulnerability `json:"unconfirmed,omitempty"`
 }
 
 func (r NucleiResult) GetCount() int {
@@ -219,3 +567,23 @@ func (r NucleiResult) GetCount() int {
 func (r NucleiResult) GetDomain() string {
 	return r.Domain
 }
+
+func (r NucleiResult) IsPartial() bool {
+	return r.Partial
+}
+
+// ExcludedTemplates returns the IDs of the templates the scan left out, by the tenant's template
+// policy or because robots.txt disallows a path they request. The scan cannot tell whether their
+// earlier findings are gone.
+func (r NucleiResult) ExcludedTemplates() []string {
+	var ids []string
+	if r.TemplatePolicy != nil {
+		for _, violation := range r.TemplatePolicy.Violations {
+			ids = append(ids, violation.TemplateID)
+		}
+	}
+	if r.Compliance != nil {
+		ids = append(ids, r.Compliance.ExcludedTemplates...)
+	}
+	return ids
+}
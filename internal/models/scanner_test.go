@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestIsPartialResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ScannerResult
+		want   bool
+	}{
+		{"partial dnsx", DNSXResult{Partial: true}, true},
+		{"partial naabu", NaabuResult{Partial: true}, true},
+		{"partial httpx", HttpxResult{Partial: true}, true},
+		{"partial nuclei", NucleiResult{Partial: true}, true},
+		{"partial cloud dns", CloudDNSResult{Partial: true}, true},
+		{"complete httpx", HttpxResult{Results: []HttpxHostResult{{Host: "example.com"}}}, false},
+		{"subfinder is never partial", SubfinderResult{Subdomains: []string{"www.example.com"}}, false},
+		{"no result", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPartialResult(tt.result); got != tt.want {
+				t.Errorf("IsPartialResult() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
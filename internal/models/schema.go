@@ -0,0 +1,113 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//go:generate go test -run TestResultSchemas -update-schemas .
+
+// jsonSchemaDraft is the JSON Schema version the result schemas are written in
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// resultTypes holds the result data of every task the result contracts are generated from
+var resultTypes = map[Task]ScannerResult{
+	TaskSubfinder:      SubfinderResult{},
+	TaskDNSResolve:     DNSXResult{},
+	TaskNaabu:          NaabuResult{},
+	TaskHttpx:          HttpxResult{},
+	TaskNuclei:         NucleiResult{},
+	TaskScopeExpansion: ScopeExpansionResult{},
+	TaskCloudDNS:       CloudDNSResult{},
+}
+
+// ResultSchemas returns the JSON Schema of the result data of every task, keyed by task. The
+// orchestrator and UI read these fields, so a change to a schema is a change to the contract.
+func ResultSchemas() map[Task]map[string]any {
+	schemas := make(map[Task]map[string]any, len(resultTypes))
+	for task, result := range resultTypes {
+		schema := JSONSchema(reflect.TypeOf(result))
+		schema["$schema"] = jsonSchemaDraft
+		schema["title"] = reflect.TypeOf(result).Name()
+		schemas[task] = schema
+	}
+	return schemas
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// JSONSchema describes how encoding/json marshals values of type t. Fields without omitempty are
+// required; pointers, slices and maps may also be null.
+func JSONSchema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(JSONSchema(t.Elem()))
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return nullable(map[string]any{"type": "array", "items": JSONSchema(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": JSONSchema(t.Elem())})
+	case reflect.Struct:
+		return structSchema(t)
+	default:
+		// Interfaces hold any JSON value
+		return map[string]any{}
+	}
+}
+
+// structSchema describes the JSON object of a struct by its json tags
+func structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = JSONSchema(field.Type)
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// nullable allows null in place of the value a schema describes, as encoding/json writes nil
+// pointers, slices and maps
+func nullable(schema map[string]any) map[string]any {
+	if kind, ok := schema["type"].(string); ok {
+		schema["type"] = []string{kind, "null"}
+	}
+	return schema
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var updateSchemas = flag.Bool("update-schemas", false, "rewrite the result schemas in schemas/")
+
+// schemaDir holds the result schemas, shipped with the worker image for the orchestrator and UI
+const schemaDir = "../../schemas"
+
+func TestResultSchemas(t *testing.T) {
+	for task, schema := range ResultSchemas() {
+		generated, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			t.Fatalf("Failed to marshal the %s schema: %v", task, err)
+		}
+		generated = append(generated, '\n')
+		path := filepath.Join(schemaDir, string(task)+".schema.json")
+
+		if *updateSchemas {
+			if err := os.WriteFile(path, generated, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		golden, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Missing schema for %s, run go generate ./internal/models: %v", task, err)
+		}
+		if !bytes.Equal(golden, generated) {
+			t.Errorf("The %s result no longer matches %s. If the change is intended and the orchestrator "+
+				"and UI can handle it, run go generate ./internal/models and commit the schema.\n\ngot:\n%s", task, path, generated)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	type example struct {
+		Name     string            `json:"name"`
+		Tags     []string          `json:"tags,omitempty"`
+		Counts   map[string]int    `json:"counts"`
+		Next     *example          `json:"-"`
+		Raw      json.RawMessage   `json:"raw,omitempty"`
+		Internal string            `json:"-"`
+		Extra    map[string]string `json:"extra,omitzero"`
+	}
+
+	schema := JSONSchema(reflect.TypeOf(example{}))
+	encoded, _ := json.Marshal(schema)
+	expected := `{"properties":{"counts":{"additionalProperties":{"type":"integer"},"type":["object","null"]},` +
+		`"extra":{"additionalProperties":{"type":"string"},"type":["object","null"]},"name":{"type":"string"},"raw":{},` +
+		`"tags":{"items":{"type":"string"},"type":["array","null"]}},"required":["name","counts"],"type":"object"}`
+	if string(encoded) != expected {
+		t.Errorf("Unexpected schema:\n%s", encoded)
+	}
+}
@@ -0,0 +1,110 @@
+package models
+
+import "time"
+
+// ScopeExpansionInput represents input for the scope expansion scanner
+type ScopeExpansionInput struct {
+	Domain  string           `json:"domain"`
+	Hosts   []string         `json:"hosts,omitempty"`  // Hosts whose certificates are read; the domain and www when empty
+	Exclude TargetExclusions `json:"exclude,omitzero"` // Hosts whose certificates are not read
+}
+
+func (s ScopeExpansionInput) GetDomain() string {
+	return s.Domain
+}
+
+func (s ScopeExpansionInput) GetScannerName() string {
+	return "scope_expansion"
+}
+
+// Kinds of evidence relating a suggested apex domain to the scanned domain
+const (
+	ScopeEvidenceCertificateSAN  = "certificate_san"  // A certificate of a scanned host also names the apex
+	ScopeEvidenceWhoisRegistrant = "whois_registrant" // The apex has the same registrant in RDAP
+	ScopeEvidenceASNColocation   = "asn_colocation"   // The apex is hosted in an ASN the scanned hosts are in
+)
+
+// ScopeEvidence is one reason an apex domain is suggested
+type ScopeEvidence struct {
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// Review states of a scope suggestion
+const (
+	ScopeSuggestionPending  = "pending"
+	ScopeSuggestionApproved = "approved"
+	ScopeSuggestionRejected = "rejected"
+)
+
+// ScopeSuggestion is an apex domain that may belong to the same organization as the scanned
+// domain. Suggestions are never scanned by the worker; a person approves or rejects them.
+type ScopeSuggestion struct {
+	Apex     string          `json:"apex"`
+	Evidence []ScopeEvidence `json:"evidence"`
+
+	// Review state, kept in the domain's suggestions blob
+	Status         string    `json:"status,omitempty"`
+	FirstSuggested time.Time `json:"first_suggested,omitzero"`
+	LastSuggested  time.Time `json:"last_suggested,omitzero"`
+	DecidedBy      string    `json:"decided_by,omitempty"`
+	DecidedAt      time.Time `json:"decided_at,omitzero"`
+}
+
+// ScopeExpansionResult represents the result of a scope expansion scan
+type ScopeExpansionResult struct {
+	Domain      string            `json:"domain"`
+	Suggestions []ScopeSuggestion `json:"output"`
+	HostsProbed int               `json:"hosts_probed"` // Hosts whose certificates were read
+	Partial     bool              `json:"partial,omitempty"`
+}
+
+func (r ScopeExpansionResult) GetCount() int {
+	return len(r.Suggestions)
+}
+
+func (r ScopeExpansionResult) GetDomain() string {
+	return r.Domain
+}
+
+func (r ScopeExpansionResult) IsPartial() bool {
+	return r.Partial
+}
+
+// ScopeSuggestionsBlobPath returns the blob path of a domain's scope suggestions
+func ScopeSuggestionsBlobPath(tenantID, domain string) string {
+	path := "scope/" + DomainBlobSegment(domain) + ".json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// ScopeSuggestions tracks the apex domains suggested for a domain and their review state
+type ScopeSuggestions struct {
+	TenantID    string                      `json:"tenant_id,omitempty"`
+	Domain      string                      `json:"domain"`
+	Suggestions map[string]*ScopeSuggestion `json:"suggestions"` // Keyed by apex domain
+}
+
+// Merge adds the suggestions of a scan at suggestedAt and returns how many apexes were new.
+// Known apexes get the latest evidence but keep their review state, so a rejected apex is not
+// put up for review again.
+func (s *ScopeSuggestions) Merge(suggestions []ScopeSuggestion, suggestedAt time.Time) int {
+	if s.Suggestions == nil {
+		s.Suggestions = make(map[string]*ScopeSuggestion)
+	}
+
+	added := 0
+	for _, suggestion := range suggestions {
+		current, ok := s.Suggestions[suggestion.Apex]
+		if !ok {
+			current = &ScopeSuggestion{Apex: suggestion.Apex, Status: ScopeSuggestionPending, FirstSuggested: suggestedAt}
+			s.Suggestions[suggestion.Apex] = current
+			added++
+		}
+		current.Evidence = suggestion.Evidence
+		current.LastSuggested = suggestedAt
+	}
+	return added
+}
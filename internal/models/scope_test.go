@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScopeSuggestionsMerge(t *testing.T) {
+	first := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	suggestions := ScopeSuggestions{Domain: "example.com"}
+	added := suggestions.Merge([]ScopeSuggestion{
+		{Apex: "example.org", Evidence: []ScopeEvidence{{Kind: ScopeEvidenceCertificateSAN}}},
+		{Apex: "example.net", Evidence: []ScopeEvidence{{Kind: ScopeEvidenceCertificateSAN}}},
+	}, first)
+	if added != 2 || suggestions.Suggestions["example.org"].Status != ScopeSuggestionPending {
+		t.Fatalf("Expected 2 pending suggestions, got %d: %+v", added, suggestions.Suggestions)
+	}
+
+	suggestions.Suggestions["example.net"].Status = ScopeSuggestionRejected
+	added = suggestions.Merge([]ScopeSuggestion{
+		{Apex: "example.net", Evidence: []ScopeEvidence{{Kind: ScopeEvidenceCertificateSAN}, {Kind: ScopeEvidenceASNColocation}}},
+	}, second)
+	if added != 0 {
+		t.Errorf("Expected no new suggestions, got %d", added)
+	}
+
+	rejected := suggestions.Suggestions["example.net"]
+	if rejected.Status != ScopeSuggestionRejected {
+		t.Errorf("Expected a rejected apex to stay rejected, got %s", rejected.Status)
+	}
+	if len(rejected.Evidence) != 2 || !rejected.FirstSuggested.Equal(first) || !rejected.LastSuggested.Equal(second) {
+		t.Errorf("Expected the latest evidence and first/last suggested times, got %+v", rejected)
+	}
+	if org := suggestions.Suggestions["example.org"]; !org.LastSuggested.Equal(first) {
+		t.Errorf("Expected example.org to keep its last suggested time, got %v", org.LastSuggested)
+	}
+}
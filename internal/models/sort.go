@@ -0,0 +1,70 @@
+package models
+
+import (
+	"sort"
+)
+
+// SortResult puts the lists of a scanner result in a deterministic order, so identical scans
+// store identical results and diffs only show real changes. Maps already serialize with sorted
+// keys; this orders subdomains alphabetically, ports ascending per host, the records of every
+// host, httpx hosts by URL and nuclei findings by host. Lists whose order carries meaning, such
+// as CNAME chains, are left alone. The result's slices are sorted in place.
+func SortResult(result ScannerResult) ScannerResult {
+	switch r := result.(type) {
+	case SubfinderResult:
+		sort.Strings(r.Subdomains)
+		for _, sources := range r.SubdomainSources {
+			sort.Strings(sources)
+		}
+		return r
+	case DNSXResult:
+		for _, info := range r.Records {
+			info.SortRecords()
+		}
+		return r
+	case NaabuResult:
+		for _, ports := range r.Ports {
+			sort.SliceStable(ports, func(i, j int) bool {
+				if ports[i].Port != ports[j].Port {
+					return ports[i].Port < ports[j].Port
+				}
+				return ports[i].Protocol < ports[j].Protocol
+			})
+		}
+		return r
+	case HttpxResult:
+		for _, host := range r.Results {
+			sort.Strings(host.Technologies)
+		}
+		sort.SliceStable(r.Results, func(i, j int) bool {
+			if r.Results[i].Host != r.Results[j].Host {
+				return r.Results[i].Host < r.Results[j].Host
+			}
+			return r.Results[i].URL < r.Results[j].URL
+		})
+		return r
+	case NucleiResult:
+		for _, findings := range [][]NucleiVulnerability{r.Vulnerabilities, r.Unconfirmed} {
+			sort.SliceStable(findings, func(i, j int) bool {
+				a, b := findings[i], findings[j]
+				if a.Host != b.Host {
+					return a.Host < b.Host
+				}
+				if a.MatchedAt != b.MatchedAt {
+					return a.MatchedAt < b.MatchedAt
+				}
+				return a.TemplateID < b.TemplateID
+			})
+		}
+		return r
+	}
+	return result
+}
+
+// SortRecords sorts the answers of each record type. CNAME and the CNAME chain keep the order
+// of the hops.
+func (r ResolutionInfo) SortRecords() {
+	for _, records := range [][]string{r.A, r.AAAA, r.MX, r.NS, r.TXT, r.SRV, r.CAA, r.PTR} {
+		sort.Strings(records)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestSortResult(t *testing.T) {
+	subfinder := SortResult(SubfinderResult{Subdomains: []string{"www.example.com", "api.example.com"}}).(SubfinderResult)
+	if fmt.Sprint(subfinder.Subdomains) != "[api.example.com www.example.com]" {
+		t.Errorf("Expected sorted subdomains, got %v", subfinder.Subdomains)
+	}
+
+	dnsx := SortResult(DNSXResult{Records: map[string]ResolutionInfo{
+		"www.example.com": {A: []string{"192.0.2.2", "192.0.2.1"}, CNAMEChain: []string{"z.example.net", "a.example.net"}},
+	}}).(DNSXResult)
+	if info := dnsx.Records["www.example.com"]; fmt.Sprint(info.A) != "[192.0.2.1 192.0.2.2]" || info.CNAMEChain[0] != "z.example.net" {
+		t.Errorf("Expected sorted A records and an untouched CNAME chain, got %v and %v", info.A, info.CNAMEChain)
+	}
+
+	naabu := SortResult(NaabuResult{Ports: map[string][]PortInfo{
+		"192.0.2.1": {{Port: 443, Protocol: "tcp"}, {Port: 22, Protocol: "tcp"}, {Port: 53, Protocol: "udp"}, {Port: 53, Protocol: "tcp"}},
+	}}).(NaabuResult)
+	if got, _ := json.Marshal(naabu.Ports["192.0.2.1"]); string(got) != `[{"port":22,"protocol":"tcp"},{"port":53,"protocol":"tcp"},{"port":53,"protocol":"udp"},{"port":443,"protocol":"tcp"}]` {
+		t.Errorf("Expected ports in ascending order, got %s", got)
+	}
+
+	httpx := SortResult(HttpxResult{Results: []HttpxHostResult{
+		{Host: "b.example.com", URL: "https://b.example.com"},
+		{Host: "a.example.com", URL: "https://a.example.com:8443", Technologies: []string{"Nginx", "HSTS"}},
+		{Host: "a.example.com", URL: "https://a.example.com"},
+	}}).(HttpxResult)
+	if httpx.Results[0].URL != "https://a.example.com" || httpx.Results[2].Host != "b.example.com" || httpx.Results[1].Technologies[0] != "HSTS" {
+		t.Errorf("Expected hosts sorted by host and URL, got %+v", httpx.Results)
+	}
+
+	nuclei := SortResult(NucleiResult{Vulnerabilities: []NucleiVulnerability{
+		{Host: "b.example.com", TemplateID: "a"},
+		{Host: "a.example.com", MatchedAt: "https://a.example.com/x", TemplateID: "b"},
+		{Host: "a.example.com", MatchedAt: "https://a.example.com/x", TemplateID: "a"},
+	}}).(NucleiResult)
+	if nuclei.Vulnerabilities[0].TemplateID != "a" || nuclei.Vulnerabilities[0].Host != "a.example.com" || nuclei.Vulnerabilities[2].Host != "b.example.com" {
+		t.Errorf("Expected findings sorted by host, location and template, got %+v", nuclei.Vulnerabilities)
+	}
+
+	if SortResult(nil) != nil {
+		t.Error("Expected a nil result to stay nil")
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScanState is the coarse state of a scan shown on its status endpoint
+type ScanState string
+
+const (
+	ScanStateQueued    ScanState = "queued"    // No stage has started yet
+	ScanStateRunning   ScanState = "running"   // A stage is running, or the scan waits for its next stage
+	ScanStateCompleted ScanState = "completed" // The final stage finished or was skipped
+	ScanStateFailed    ScanState = "failed"    // A stage failed and was not run again
+	ScanStateSkipped   ScanState = "skipped"   // Of stages only: the stage was skipped, e.g. for a frozen scope
+	// The scan used up its retry budget and was paused until an operator resumes it
+	ScanStateNeedsIntervention ScanState = "needs_intervention"
+	// A stage's result failed a quality gate; held stages wait until an operator reviews the scan
+	ScanStateDegraded ScanState = "degraded"
+)
+
+// ScanStatusBlobPath returns the blob path of the status of a scan.
+// Like pause markers, it lives outside the per-domain prefix so it can be found by scan ID alone.
+func ScanStatusBlobPath(tenantID string, scanID int) string {
+	path := fmt.Sprintf("control/status/scan-%d.json", scanID)
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// ScanStatus is the coarse progress of a scan, kept without any of its results. The state of the
+// scan is derived from the status of each of its stages.
+type ScanStatus struct {
+	ScanID    int                     `json:"scan_id"`
+	TenantID  string                  `json:"tenant_id,omitempty"`
+	State     ScanState               `json:"state"`
+	Stage     string                  `json:"stage,omitempty"`       // Task of the stage the state is about
+	Percent   int                     `json:"percent"`               // Completed percentage of the stage's current phase
+	ETA       int                     `json:"eta_seconds,omitempty"` // Estimated seconds until the stage finishes, 0 when unknown
+	StartedAt time.Time               `json:"started_at,omitzero"`   // When the stage started
+	UpdatedAt time.Time               `json:"updated_at"`
+	Stages    map[string]*StageStatus `json:"stages,omitempty"` // Latest status of every stage, by task
+}
+
+// StageStatus is the status of one stage of a scan
+type StageStatus struct {
+	State     ScanState `json:"state"`
+	Percent   int       `json:"percent"`
+	ETA       int       `json:"eta_seconds,omitempty"`
+	Final     bool      `json:"final,omitempty"` // The stage ends the scan
+	StartedAt time.Time `json:"started_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Merge takes the stages of update that are more recent than the ones already known and derives
+// the state of the scan again. It reports whether anything changed.
+func (s *ScanStatus) Merge(update *ScanStatus) bool {
+	changed := false
+	for name, stage := range update.Stages {
+		if current, ok := s.Stages[name]; ok && !stage.UpdatedAt.After(current.UpdatedAt) {
+			continue
+		}
+		if s.Stages == nil {
+			s.Stages = make(map[string]*StageStatus)
+		}
+		copied := *stage
+		s.Stages[name] = &copied
+		changed = true
+	}
+	if !changed {
+		return false
+	}
+	s.ScanID, s.TenantID = update.ScanID, update.TenantID
+	s.derive()
+	return true
+}
+
+// derive sets the state of the scan from its stages. A scan-wide state of the latest update comes
+// first, then a running stage and then a failed one. The scan is completed once its final stage
+// completed or was skipped, and running while it waits for the next stage otherwise.
+func (s *ScanStatus) derive() {
+	var latest, running, failed, final string
+	newer := func(name, than string) bool {
+		return than == "" || s.Stages[name].UpdatedAt.After(s.Stages[than].UpdatedAt)
+	}
+	for name, stage := range s.Stages {
+		if newer(name, latest) {
+			latest = name
+		}
+		switch {
+		case stage.State == ScanStateRunning && newer(name, running):
+			running = name
+		case stage.State == ScanStateFailed && newer(name, failed):
+			failed = name
+		case stage.Final && (stage.State == ScanStateCompleted || stage.State == ScanStateSkipped):
+			final = name
+		}
+	}
+
+	show := func(name string, state ScanState) {
+		stage := s.Stages[name]
+		s.State, s.Stage = state, name
+		s.Percent, s.ETA, s.StartedAt = stage.Percent, stage.ETA, stage.StartedAt
+	}
+	switch {
+	case latest == "":
+		s.State = ScanStateQueued
+		return
+	case s.Stages[latest].State == ScanStateNeedsIntervention || s.Stages[latest].State == ScanStateDegraded:
+		show(latest, s.Stages[latest].State)
+	case running != "":
+		show(running, ScanStateRunning)
+	case failed != "":
+		show(failed, ScanStateFailed)
+	case final != "":
+		show(final, ScanStateCompleted)
+		s.Percent = 100
+	default:
+		show(latest, ScanStateRunning)
+		s.Percent, s.ETA = 0, 0
+	}
+	s.UpdatedAt = s.Stages[latest].UpdatedAt
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SubdomainRegexPrefix marks a subdomain pattern as a regular expression rather than a glob
+const SubdomainRegexPrefix = "re:"
+
+// SubdomainFilter narrows the subdomains a subfinder task discovers before they are stored and
+// handed to later stages. A pattern is a glob matching whole names, where '*' matches any
+// characters, dots included, e.g. "*.sandbox.*", or a regular expression prefixed with "re:"
+// that matches anywhere in a name unless anchored. Both ignore case.
+type SubdomainFilter struct {
+	Include []string `json:"include,omitempty"` // Keep only names matching one of these; all names when empty
+	Exclude []string `json:"exclude,omitempty"` // Drop names matching one of these, even when included
+}
+
+// IsZero reports whether the filter keeps every name
+func (f SubdomainFilter) IsZero() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// NameFilter is a compiled SubdomainFilter. A nil NameFilter keeps every name.
+type NameFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// Compile compiles the patterns of the filter, returning nil when it has none
+func (f SubdomainFilter) Compile() (*NameFilter, error) {
+	if f.IsZero() {
+		return nil, nil
+	}
+	include, err := compileNamePatterns(f.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileNamePatterns(f.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &NameFilter{include: include, exclude: exclude}, nil
+}
+
+// Keep reports whether a name passes the filter
+func (f *NameFilter) Keep(name string) bool {
+	if f == nil {
+		return true
+	}
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+	for _, pattern := range f.exclude {
+		if pattern.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if pattern.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileNamePatterns compiles globs and "re:" regular expressions into case-insensitive regular
+// expressions
+func compileNamePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("empty subdomain pattern")
+		}
+
+		expression, isRegex := strings.CutPrefix(pattern, SubdomainRegexPrefix)
+		if !isRegex {
+			expression = "^" + strings.ReplaceAll(regexp.QuoteMeta(strings.ToLower(pattern)), `\*`, ".*") + "$"
+		}
+		re, err := regexp.Compile("(?i)" + expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subdomain pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,50 @@
+package models
+
+import "testing"
+
+func TestSubdomainFilter(t *testing.T) {
+	filter, err := SubdomainFilter{
+		Include: []string{"*.prod.*", `re:^api\d+\.example\.com$`},
+		Exclude: []string{"*.sandbox.*"},
+	}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	for name, want := range map[string]bool{
+		"www.prod.example.com":         true,
+		"WWW.Prod.Example.com.":        true,
+		"api7.example.com":             true,
+		"api7.example.com.evil.org":    false,
+		"www.staging.example.com":      false,
+		"app.sandbox.prod.example.com": false,
+		"prod.example.com":             false,
+	} {
+		if got := filter.Keep(name); got != want {
+			t.Errorf("Keep(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestSubdomainFilter_ExcludeOnly(t *testing.T) {
+	filter, err := SubdomainFilter{Exclude: []string{"*.sandbox.*", "re:^dev-"}}.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if !filter.Keep("www.example.com") || filter.Keep("a.sandbox.example.com") || filter.Keep("dev-1.example.com") {
+		t.Error("Expected only the excluded names to be dropped")
+	}
+}
+
+func TestSubdomainFilter_Empty(t *testing.T) {
+	filter, err := SubdomainFilter{}.Compile()
+	if err != nil || filter != nil || !filter.Keep("anything.example.com") {
+		t.Errorf("Expected an empty filter to keep every name, got %v, %v", filter, err)
+	}
+	if _, err := (SubdomainFilter{Include: []string{"re:(unclosed"}}).Compile(); err == nil {
+		t.Error("Expected an invalid regular expression to be rejected")
+	}
+	if _, err := (SubdomainFilter{Exclude: []string{" "}}).Compile(); err == nil {
+		t.Error("Expected an empty pattern to be rejected")
+	}
+}
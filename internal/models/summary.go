@@ -0,0 +1,125 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// ResultSummary describes a result that was too large to store whole. The stored result keeps
+// the original data type with only a sample of the entries, and the full result is stored
+// gzip-compressed in one or more parts which, concatenated, form a single gzip stream.
+type ResultSummary struct {
+	OriginalSize int            `json:"original_size"` // Size of the full result JSON in bytes
+	Count        int            `json:"count"`
+	Counts       map[string]int `json:"counts,omitempty"` // Breakdown of the count, e.g. findings per severity
+	Samples      int            `json:"samples"`          // Entries kept in the stored result's data
+	Compression  string         `json:"compression"`
+	FullData     []string       `json:"full_data"` // Blob paths of the parts of the full result, in order
+}
+
+// summarySeverityOrder ranks nuclei severities so the most severe findings are sampled first
+var summarySeverityOrder = map[string]int{"critical": 5, "high": 4, "medium": 3, "low": 2, "info": 1}
+
+// SummarizeResult returns a copy of the result whose data keeps at most samples entries, with a
+// summary of the full data. The full data parts are left for the caller to fill in.
+func SummarizeResult(result *TaskResult, originalSize, samples int) *TaskResult {
+	summarized := *result
+	count, counts := ResultCounts(result.Data)
+	summary := &ResultSummary{OriginalSize: originalSize, Count: count, Counts: counts, Compression: "gzip"}
+
+	switch data := result.Data.(type) {
+	case SubfinderResult:
+		if len(data.Subdomains) > samples {
+			data.Subdomains = data.Subdomains[:samples]
+		}
+		summary.Samples = len(data.Subdomains)
+		summarized.Data = data
+	case DNSXResult:
+		names := sortedMapKeys(data.Records)
+		if len(names) > samples {
+			names = names[:samples]
+		}
+		records := make(map[string]ResolutionInfo, len(names))
+		for _, name := range names {
+			records[name] = data.Records[name]
+		}
+		data.Records = records
+		summary.Samples = len(records)
+		summarized.Data = data
+	case NaabuResult:
+		// Hosts are kept whole, so the sample may hold slightly more ports than requested
+		ports := make(map[string][]PortInfo)
+		kept := 0
+		for _, host := range sortedMapKeys(data.Ports) {
+			if kept >= samples {
+				break
+			}
+			ports[host] = data.Ports[host]
+			kept += len(data.Ports[host])
+		}
+		data.Ports = ports
+		summary.Samples = kept
+		summarized.Data = data
+	case HttpxResult:
+		if len(data.Results) > samples {
+			data.Results = data.Results[:samples]
+		}
+		summary.Samples = len(data.Results)
+		summarized.Data = data
+	case NucleiResult:
+		vulnerabilities := append([]NucleiVulnerability(nil), data.Vulnerabilities...)
+		sort.SliceStable(vulnerabilities, func(i, j int) bool {
+			return summarySeverityOrder[strings.ToLower(vulnerabilities[i].Severity)] > summarySeverityOrder[strings.ToLower(vulnerabilities[j].Severity)]
+		})
+		if len(vulnerabilities) > samples {
+			vulnerabilities = vulnerabilities[:samples]
+		}
+		data.Vulnerabilities = vulnerabilities
+		summary.Samples = len(vulnerabilities)
+		summarized.Data = data
+	default:
+		summarized.Data = nil
+	}
+
+	summarized.Summary = summary
+	return &summarized
+}
+
+// ResultCounts returns the number of entries in result data with a breakdown where one applies
+func ResultCounts(data any) (int, map[string]int) {
+	switch data := data.(type) {
+	case SubfinderResult:
+		return data.GetCount(), nil
+	case DNSXResult:
+		counts := make(map[string]int)
+		for _, record := range data.Records {
+			counts[string(record.Status)]++
+		}
+		return data.GetCount(), counts
+	case NaabuResult:
+		return data.GetCount(), map[string]int{"hosts": len(data.Ports), "ports": data.GetCount()}
+	case HttpxResult:
+		return data.GetCount(), nil
+	case NucleiResult:
+		counts := make(map[string]int)
+		for _, vuln := range data.Vulnerabilities {
+			severity := strings.ToLower(vuln.Severity)
+			if severity == "" {
+				severity = "unknown"
+			}
+			counts[severity]++
+		}
+		return data.GetCount(), counts
+	}
+	return 0, nil
+}
+
+// sortedMapKeys returns the keys of a map in sorted order
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestSummarizeResult(t *testing.T) {
+	result := &TaskResult{
+		Task:   TaskNuclei,
+		Domain: "example.com",
+		Data: NucleiResult{Domain: "example.com", Vulnerabilities: []NucleiVulnerability{
+			{TemplateID: "a", Severity: "info"},
+			{TemplateID: "b", Severity: "critical"},
+			{TemplateID: "c", Severity: "low"},
+			{TemplateID: "d", Severity: "high"},
+		}},
+	}
+
+	summarized := SummarizeResult(result, 4096, 2)
+
+	data, ok := summarized.Data.(NucleiResult)
+	if !ok || len(data.Vulnerabilities) != 2 || data.Vulnerabilities[0].TemplateID != "b" || data.Vulnerabilities[1].TemplateID != "d" {
+		t.Fatalf("Expected the two most severe findings sampled, got %+v", summarized.Data)
+	}
+	if summary := summarized.Summary; summary.Count != 4 || summary.Samples != 2 || summary.OriginalSize != 4096 || summary.Counts["critical"] != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if len(result.Data.(NucleiResult).Vulnerabilities) != 4 || result.Summary != nil {
+		t.Error("Expected the original result to be left unchanged")
+	}
+
+	naabu := SummarizeResult(&TaskResult{Task: TaskNaabu, Data: NaabuResult{Ports: map[string][]PortInfo{
+		"192.0.2.3": {{Port: 80}},
+		"192.0.2.1": {{Port: 22}, {Port: 443}},
+		"192.0.2.2": {{Port: 25}},
+	}}}, 100, 2)
+	ports := naabu.Data.(NaabuResult).Ports
+	if len(ports) != 1 || len(ports["192.0.2.1"]) != 2 || naabu.Summary.Counts["hosts"] != 3 {
+		t.Errorf("Expected whole hosts sampled in order, got %v and %+v", ports, naabu.Summary)
+	}
+}
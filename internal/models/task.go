@@ -1,26 +1,187 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // TaskMessage represents the structure of messages in the queue
 type TaskMessage struct {
 	Task       Task                   `json:"task"`
 	ScanID     int                    `json:"scan_id"`
 	Domain     string                 `json:"domain"`
+	TenantID   string                 `json:"tenant_id,omitempty"` // Optional tenant, used as the top-level blob prefix
 	InstanceID string                 `json:"instance_id"`
 	FilePath   string                 `json:"input_blob_path,omitempty"` // Optional file path for tools that need file input
 	Type       string                 `json:"type,omitempty"`            // Type of nuclei scan (e.g., "http")
 	Config     map[string]interface{} `json:"config,omitempty"`          // Tool-specific configuration
+	Action     TaskAction             `json:"action,omitempty"`          // Control action; empty for regular scan tasks
+	// Profile names a scan profile whose config for the task type fills the keys Config leaves unset
+	Profile string `json:"profile,omitempty"`
+	// InputResultPath points at the stored JSON result of an earlier stage to take targets from
+	InputResultPath string `json:"input_result_path,omitempty"`
+	// Mode "verify" scans only the targets of InputResultPath missing from BaselineResultPath, the
+	// same stage's result of an earlier scan of the domain
+	Mode               TaskMode `json:"mode,omitempty"`
+	BaselineResultPath string   `json:"baseline_result_path,omitempty"`
+	// Domains, or the blob at DomainsBlobPath, lists the domains of a multi-domain task in place of Domain
+	Domains         []string `json:"domains,omitempty"`
+	DomainsBlobPath string   `json:"domains_blob_path,omitempty"`
+	// NotBefore delays the task: a message received earlier is re-scheduled for that time
+	NotBefore *time.Time `json:"not_before,omitempty"`
+	// CorrelationID ties the task's logs, blobs, notifications and requests together; generated when missing
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Requires lists worker capabilities the task needs beyond those of its task type, e.g. "gpu"
+	Requires []string `json:"requires,omitempty"`
+	// FinalStage marks the last task of a scan, after which a digest of the whole scan is sent
+	FinalStage bool `json:"final_stage,omitempty"`
+	// DataRegion is the region the orchestrator expects the tenant's results in; the task is
+	// rejected when the worker would store them elsewhere
+	DataRegion string `json:"data_region,omitempty"`
+	// OlderThanDays is the age, counted from their last write, past which a compact action
+	// archives a domain's scans; the worker's default when 0
+	OlderThanDays int `json:"older_than_days,omitempty"`
+	// Attempt counts the deliveries of the task, starting at 1; set by the worker from the queue message
+	Attempt int `json:"-"`
 }
 
 // TaskResult represents the result of a completed task
 type TaskResult struct {
-	Task      Task       `json:"task"`
-	ScanID    int        `json:"scan_id"`
-	Domain    string     `json:"domain"`
-	Status    TaskStatus `json:"status"`
-	Data      any        `json:"data,omitempty"`
-	Error     string     `json:"error,omitempty"`
-	Timestamp string     `json:"timestamp"`
-	Duration  string     `json:"duration,omitempty"` // Duration of the task execution
+	Task     Task       `json:"task"`
+	ScanID   int        `json:"scan_id"`
+	Domain   string     `json:"domain"`
+	TenantID string     `json:"tenant_id,omitempty"`
+	Status   TaskStatus `json:"status"`
+	Data     any        `json:"data,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	// ErrorDetails holds the structured failure description for failed tasks
+	ErrorDetails *TaskError `json:"error_details,omitempty"`
+	Timestamp    string     `json:"timestamp"`
+	Duration     string     `json:"duration,omitempty"` // Duration of the task execution
+	// Summary is set when the data was too large to store whole and holds only a sample
+	Summary *ResultSummary `json:"summary,omitempty"`
+	// CorrelationID is the correlation ID of the task that produced the result
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Attempt is the delivery of the task that produced the result; it names the result's blobs
+	Attempt int `json:"attempt,omitempty"`
+	// Worker is the build of the worker that produced the result
+	Worker *BuildInfo `json:"worker,omitempty"`
+	// Mode is the mode of the task that produced the result; empty for regular tasks
+	Mode TaskMode `json:"mode,omitempty"`
+	// Verify is set for verify tasks and tells which targets were scanned
+	Verify *VerifyDelta `json:"verify,omitempty"`
+}
+
+// CoversScope reports whether the result covers every target of its stage, so that findings and
+// assets missing from it are gone rather than unscanned. Partial results do not, nor do verify
+// results, which scan only the targets new since a baseline.
+func (r *TaskResult) CoversScope() bool {
+	return r.Status != TaskStatusPartial && r.Mode != TaskModeVerify
+}
+
+// TaskError is a structured description of a task failure, stored as an error artifact
+// so failures can be triaged without the worker logs
+type TaskError struct {
+	Type           string            `json:"type"`
+	Message        string            `json:"message"`
+	Field          string            `json:"field,omitempty"`
+	Cause          string            `json:"cause,omitempty"`
+	Retryable      bool              `json:"retryable"`
+	Scanner        string            `json:"scanner,omitempty"`
+	Context        map[string]string `json:"context,omitempty"`
+	Stack          string            `json:"stack,omitempty"`
+	Truncated      bool              `json:"truncated,omitempty"` // True when the message, cause or stack was cut to the size limit
+	PartialResults any               `json:"partial_results,omitempty"`
+}
+
+// DomainBlobSegment returns the form of a task's domain used in blob paths. Host names are kept as
+// they are, while the other characters of URL and host:port targets, e.g. "https://example.com:8443",
+// are replaced with underscores so the target stays a single path segment.
+func DomainBlobSegment(domain string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, domain)
+}
+
+// ScanBlobPrefix returns the blob prefix that holds all artifacts of a scan.
+// Layout is "<domain>-<scan_id>/", nested under "<tenant_id>/" when a tenant is set.
+func ScanBlobPrefix(tenantID, domain string, scanID int) string {
+	prefix := fmt.Sprintf("%s-%d/", DomainBlobSegment(domain), scanID)
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// DomainScansPrefix returns the blob prefix shared by all scans of a domain. Other domains may
+// share it too, e.g. "example.com-staging.io", so listings check the scan ID that follows.
+func DomainScansPrefix(tenantID, domain string) string {
+	prefix := DomainBlobSegment(domain) + "-"
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// TaskBlobPrefix returns the blob prefix that holds the artifacts of one task of a scan
+func TaskBlobPrefix(tenantID, domain string, scanID int, task string) string {
+	return ScanBlobPrefix(tenantID, domain, scanID) + task + "/"
+}
+
+// AttemptBlobName returns the deterministic name of a blob written by an attempt of a task, e.g.
+// "attempt-2.json". A retried attempt overwrites its own blobs instead of adding new ones.
+func AttemptBlobName(attempt int, extension string) string {
+	return fmt.Sprintf("attempt-%d%s", max(attempt, 1), extension)
+}
+
+// LatestResultBlobPath returns the blob path of the pointer to a task's authoritative result
+func LatestResultBlobPath(tenantID, domain string, scanID int, task string) string {
+	return TaskBlobPrefix(tenantID, domain, scanID, task) + "latest.json"
+}
+
+// LatestResult points at the result of the latest attempt of a task that stored one
+type LatestResult struct {
+	BlobPath      string `json:"blob_path"`
+	Attempt       int    `json:"attempt"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Duration      string `json:"duration,omitempty"` // Duration of the attempt, for scan digests
+	UpdatedAt     string `json:"updated_at"`
+}
+
+// ScanControlBlobPath returns the blob path of the pause marker for a scan.
+// Control messages only carry the scan ID, so the marker lives outside the per-domain prefix.
+func ScanControlBlobPath(tenantID string, scanID int) string {
+	path := fmt.Sprintf("control/scan-%d.paused", scanID)
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// TenantTemplatesPrefix returns the blob prefix of a tenant's custom nuclei templates directory
+func TenantTemplatesPrefix(tenantID, name string) string {
+	prefix := fmt.Sprintf("nuclei-templates/%s/", name)
+	if tenantID != "" {
+		prefix = tenantID + "/" + prefix
+	}
+	return prefix
+}
+
+// ScanCheckpoint records how far a paused scan got so a later task can continue from it
+type ScanCheckpoint struct {
+	Task             Task            `json:"task"`
+	ScanID           int             `json:"scan_id"`
+	Domain           string          `json:"domain"`
+	TenantID         string          `json:"tenant_id,omitempty"`
+	ProcessedTargets []string        `json:"processed_targets"`
+	Result           json.RawMessage `json:"result,omitempty"`       // Partial scanner result collected before pausing
+	ResumeState      json.RawMessage `json:"resume_state,omitempty"` // Scanner-specific resume state, e.g. nuclei's resume config
+	PausedAt         string          `json:"paused_at"`
 }
 
 // Task types
@@ -32,15 +193,32 @@ const (
 	TaskDNSResolve Task = "dns_resolve"
 	TaskNaabu      Task = "port_scan"
 	TaskNuclei     Task = "nuclei"
+	// Suggests related apex domains for review; its suggestions are never scanned automatically
+	TaskScopeExpansion Task = "scope_expansion"
+	// Reads the records of the tenant's cloud DNS zones into the inventory as ground truth
+	TaskCloudDNS Task = "cloud_dns"
 )
 
 // Task status
 type TaskStatus string
 
 const (
-	TaskStatusCompleted TaskStatus = "completed"
-	TaskStatusFailed    TaskStatus = "failed"
-	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusCompleted     TaskStatus = "completed"
+	TaskStatusFailed        TaskStatus = "failed"
+	TaskStatusRunning       TaskStatus = "running"
+	TaskStatusPartial       TaskStatus = "partial"        // Scan timed out, was cancelled or skipped targets; results cover only what finished
+	TaskStatusPaused        TaskStatus = "paused"         // Scan was paused by a control message and checkpointed
+	TaskStatusSkippedFrozen TaskStatus = "skipped_frozen" // Domain matched the freeze list and was not scanned
+)
+
+// Control actions carried by control messages
+type TaskAction string
+
+const (
+	TaskActionPause  TaskAction = "pause"
+	TaskActionResume TaskAction = "resume"
+	// Archives the results of a domain's old scans into monthly archives
+	TaskActionCompact TaskAction = "compact"
 )
 
 // MessageProcessingResult represents the result of processing a message
@@ -51,4 +229,12 @@ type MessageProcessingResult struct {
 	Retryable bool
 	// RetryCount is the number of times this message has been retried
 	RetryCount int
+	// DeferUntil, when set, asks for the message to be re-delivered at that time instead of processed now.
+	// On a failed result it schedules the retry, e.g. after a rate-limit ban.
+	DeferUntil time.Time
+	// Abandon hands a failed message straight back to the queue for another worker, without
+	// retrying it on this one, e.g. when this worker does not run its task type
+	Abandon bool
+	// DeadLetterReason, when set on a failed result, is recorded as the dead-letter reason
+	DeadLetterReason string
 }
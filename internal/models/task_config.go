@@ -0,0 +1,305 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// TaskConfig holds the config keys every task type accepts
+type TaskConfig struct {
+	// Exclude is a list of entries, or a string of them one per line
+	Exclude         []string `json:"exclude,omitempty" validate:"dive,required"`
+	ExcludeBlobPath string   `json:"exclude_blob_path,omitempty"`
+	ScanWindow      string   `json:"scan_window,omitempty"`
+}
+
+// Common returns the keys every task type accepts
+func (c *TaskConfig) Common() *TaskConfig {
+	return c
+}
+
+// Exclusions returns the inline exclusion entries and the blob path of more entries
+func (c *TaskConfig) Exclusions() TargetExclusions {
+	return TargetExclusions{Targets: c.Exclude, BlobPath: c.ExcludeBlobPath}
+}
+
+// ScannerConfig is the decoded config of a task type. Every one embeds TaskConfig.
+type ScannerConfig interface {
+	Common() *TaskConfig
+}
+
+// SubfinderConfig is the config of subfinder tasks
+type SubfinderConfig struct {
+	TaskConfig
+	IncludePatterns []string `json:"include_patterns,omitempty"`
+	ExcludePatterns []string `json:"exclude_patterns,omitempty"`
+}
+
+// HttpxConfig is the config of httpx tasks
+type HttpxConfig struct {
+	TaskConfig
+	TLSFingerprint string `json:"tls_fingerprint,omitempty"` // Overrides the default fingerprint, not a tenant's own
+}
+
+// DNSXConfig is the config of dns_resolve tasks; unset keys keep the worker's DNS settings
+type DNSXConfig struct {
+	TaskConfig
+	Retries       int      `json:"retries,omitempty" validate:"min=0"`
+	TimeoutMs     int      `json:"timeout_ms,omitempty" validate:"min=0"`
+	QuestionTypes []string `json:"question_types,omitempty" validate:"dive,required"`
+	Hostsfile     *bool    `json:"hostsfile,omitempty"`
+	RetryPass     *bool    `json:"retry_pass,omitempty"`
+}
+
+// Settings returns the DNS setting overrides of the task
+func (c *DNSXConfig) Settings() DNSSettings {
+	return DNSSettings{
+		Retries:       c.Retries,
+		TimeoutMs:     c.TimeoutMs,
+		QuestionTypes: c.QuestionTypes,
+		Hostsfile:     c.Hostsfile,
+		RetryPass:     c.RetryPass,
+	}
+}
+
+// NaabuConfig is the config of port_scan tasks; see NaabuInput for the meaning of the keys
+type NaabuConfig struct {
+	TaskConfig
+	TopPorts        string   `json:"top_ports,omitempty" default:"100" validate:"omitempty,oneof=full 100 1000"`
+	Ports           []int    `json:"ports,omitempty" validate:"dive,min=1,max=65535"`
+	PortRange       string   `json:"port_range,omitempty"`
+	RateLimit       int      `json:"rate_limit,omitempty" validate:"min=0"`
+	Concurrency     int      `json:"concurrency,omitempty" validate:"min=0"`
+	Timeout         int      `json:"timeout,omitempty" validate:"min=0"`
+	HostDiscovery   bool     `json:"host_discovery,omitempty"`
+	PreFilter       bool     `json:"pre_filter,omitempty"`
+	DiscoveryProbes []string `json:"discovery_probes,omitempty" validate:"dive,required"`
+	SourcePort      int      `json:"source_port,omitempty" validate:"min=0,max=65535"`
+	SourceIP        string   `json:"source_ip,omitempty" validate:"omitempty,ip"`
+	Interface       string   `json:"interface,omitempty"`
+	CDNPorts        []int    `json:"cdn_ports,omitempty" validate:"dive,min=1,max=65535"`
+	ScanCDN         bool     `json:"scan_cdn,omitempty"`
+}
+
+// NucleiConfig is the config of nuclei tasks
+type NucleiConfig struct {
+	TaskConfig
+	CustomTemplates   string `json:"custom_templates,omitempty"`
+	InteractshServer  string `json:"interactsh_server,omitempty"`
+	DisableInteractsh bool   `json:"disable_interactsh,omitempty"`
+	Compliance        bool   `json:"compliance,omitempty"`                   // Turns compliance mode on; it cannot turn off the worker's
+	HostBudget        int    `json:"host_budget,omitempty" validate:"min=0"` // Seconds per target; overrides the worker's
+	ScanBudget        int    `json:"scan_budget,omitempty" validate:"min=0"` // Seconds per scan; overrides the worker's
+	// Lowest severity of findings replayed before they are reported, or "none"; overrides the worker's
+	ReplayMinSeverity string `json:"replay_min_severity,omitempty" validate:"omitempty,oneof=none info low medium high critical"`
+	// Checkpoints the scan's resume state while it runs, so a redelivered task resumes; turns it on
+	// where the worker leaves it off, as resumable scans use template-spray instead of host-spray
+	Checkpoints bool `json:"checkpoints,omitempty"`
+}
+
+// ScopeExpansionConfig is the config of scope_expansion tasks
+type ScopeExpansionConfig struct {
+	TaskConfig
+}
+
+// CloudDNSConfig is the config of cloud_dns tasks
+type CloudDNSConfig struct {
+	TaskConfig
+	Sources []CloudDNSSource `json:"sources,omitempty"`
+}
+
+// NewTaskConfig returns the config of a task type with its defaults, or nil for unknown types
+func NewTaskConfig(task Task) ScannerConfig {
+	var config ScannerConfig
+	switch task {
+	case TaskSubfinder:
+		config = &SubfinderConfig{}
+	case TaskHttpx:
+		config = &HttpxConfig{}
+	case TaskDNSResolve:
+		config = &DNSXConfig{}
+	case TaskNaabu:
+		config = &NaabuConfig{}
+	case TaskNuclei:
+		config = &NucleiConfig{}
+	case TaskScopeExpansion:
+		config = &ScopeExpansionConfig{}
+	case TaskCloudDNS:
+		config = &CloudDNSConfig{}
+	default:
+		return nil
+	}
+	setDefaults(reflect.ValueOf(config).Elem())
+	return config
+}
+
+// ParseTaskConfig decodes the config of a task message into the config of its task type
+func ParseTaskConfig(task Task, config map[string]any) (ScannerConfig, error) {
+	out := NewTaskConfig(task)
+	if out == nil {
+		return nil, fmt.Errorf("unknown task type %q", task)
+	}
+	if err := DecodeTaskConfig(config, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DecodeTaskConfig decodes a task config into out, a pointer to a config struct, and checks the
+// struct's validate tags. Keys missing from the config keep the values out already holds. A list
+// key also takes a string with one entry per line, and whole numbers are accepted for text keys.
+// Keys out has no field for are reported together.
+func DecodeTaskConfig(config map[string]any, out any) error {
+	var metadata mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(wholeNumberHook, numberToStringHook, stringToListHook),
+		Metadata:   &metadata,
+		Result:     out,
+		TagName:    "json",
+		Squash:     true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("invalid config: %s", strings.Join(decodeErrorMessages(err), "; "))
+	}
+	if len(metadata.Unused) > 0 {
+		sort.Strings(metadata.Unused)
+		return fmt.Errorf("unrecognized config keys: %s", strings.Join(metadata.Unused, ", "))
+	}
+
+	if err := configValidator.Struct(out); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(err, &fieldErrs) {
+			return err
+		}
+		messages := make([]string, len(fieldErrs))
+		for i, fieldErr := range fieldErrs {
+			messages[i] = describeFieldError(fieldErr)
+		}
+		return fmt.Errorf("invalid config: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// configValidator checks the validate tags of config structs, naming fields by their config key
+var configValidator = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
+	})
+	return v
+}()
+
+// describeFieldError turns a failed validate tag into a message naming the config key, e.g.
+// "ports[1] must be at most 65535"
+func describeFieldError(fieldErr validator.FieldError) string {
+	// The namespace starts with the struct name, and embedded TaskConfig keys with "TaskConfig."
+	key := fieldErr.Namespace()
+	if _, rest, ok := strings.Cut(key, "."); ok {
+		key = strings.TrimPrefix(rest, "TaskConfig.")
+	}
+
+	switch fieldErr.Tag() {
+	case "min":
+		return fmt.Sprintf("%s must be at least %s", key, fieldErr.Param())
+	case "max":
+		return fmt.Sprintf("%s must be at most %s", key, fieldErr.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of %s, got %v", key, strings.ReplaceAll(fieldErr.Param(), " ", ", "), fieldErr.Value())
+	case "required":
+		return fmt.Sprintf("%s must not be empty", key)
+	case "ip":
+		return fmt.Sprintf("%s must be an IP address, got %v", key, fieldErr.Value())
+	}
+	return fmt.Sprintf("%s failed the %s check", key, fieldErr.Tag())
+}
+
+// decodeErrorMessages flattens the errors of a decode into one message per key
+func decodeErrorMessages(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var messages []string
+		for _, err := range joined.Unwrap() {
+			messages = append(messages, decodeErrorMessages(err)...)
+		}
+		return messages
+	}
+	var decodeErr *mapstructure.DecodeError
+	if errors.As(err, &decodeErr) {
+		return []string{decodeErr.Name() + ": " + decodeErr.Unwrap().Error()}
+	}
+	return []string{err.Error()}
+}
+
+// wholeNumberHook rejects fractions for integer keys, which the decoder would truncate
+func wholeNumberHook(from, to reflect.Type, data any) (any, error) {
+	if value, ok := data.(float64); ok && isIntKind(to.Kind()) && value != math.Trunc(value) {
+		return nil, fmt.Errorf("expected a whole number, got %v", value)
+	}
+	return data, nil
+}
+
+// numberToStringHook takes whole numbers for text keys, e.g. "top_ports": 1000
+func numberToStringHook(from, to reflect.Type, data any) (any, error) {
+	if value, ok := data.(float64); ok && to.Kind() == reflect.String && value == math.Trunc(value) {
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	}
+	return data, nil
+}
+
+// stringToListHook takes a string for a list of strings, read like an exclusion list: one entry
+// per line with blank lines and "#" comments skipped, or a JSON array
+func stringToListHook(from, to reflect.Type, data any) (any, error) {
+	text, ok := data.(string)
+	if !ok || to.Kind() != reflect.Slice || to.Elem().Kind() != reflect.String {
+		return data, nil
+	}
+	entries, err := ParseExclusionList([]byte(text))
+	if entries == nil && err == nil {
+		entries = []string{}
+	}
+	return entries, err
+}
+
+// setDefaults sets the fields of a config struct to the values of their default tags
+func setDefaults(value reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field, fieldType := value.Field(i), value.Type().Field(i)
+		if fieldType.Anonymous && field.Kind() == reflect.Struct {
+			setDefaults(field)
+			continue
+		}
+		def, ok := fieldType.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(def)
+		case reflect.Bool:
+			b, _ := strconv.ParseBool(def)
+			field.SetBool(b)
+		default:
+			if isIntKind(field.Kind()) {
+				n, _ := strconv.ParseInt(def, 10, 64)
+				field.SetInt(n)
+			}
+		}
+	}
+}
+
+// isIntKind reports whether a kind is a signed integer
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Int64
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTaskConfig(t *testing.T) {
+	config, err := ParseTaskConfig(TaskNaabu, map[string]any{
+		"ports":   []any{80.0, 443.0},
+		"exclude": "10.0.0.0/8\n# staging\n192.168.1.1",
+		"timeout": 30.0,
+	})
+	if err != nil {
+		t.Fatalf("ParseTaskConfig() error = %v", err)
+	}
+	naabu := config.(*NaabuConfig)
+	if len(naabu.Ports) != 2 || naabu.Ports[1] != 443 || naabu.Timeout != 30 {
+		t.Errorf("ParseTaskConfig() = %+v, want ports 80 and 443 and a 30s timeout", naabu)
+	}
+	if naabu.TopPorts != "100" {
+		t.Errorf("TopPorts = %q, want the default 100", naabu.TopPorts)
+	}
+	if exclusions := naabu.Common().Exclusions(); len(exclusions.Targets) != 2 {
+		t.Errorf("Exclusions() = %v, want the two entries of the string", exclusions.Targets)
+	}
+
+	// Nested structs and whole numbers for text keys
+	config, err = ParseTaskConfig(TaskCloudDNS, map[string]any{
+		"sources": []any{map[string]any{"provider": "azure", "subscription_id": "sub", "resource_group": "dns"}},
+	})
+	if err != nil || config.(*CloudDNSConfig).Sources[0].ResourceGroup != "dns" {
+		t.Errorf("ParseTaskConfig() of cloud_dns = %+v, %v, want the nested source", config, err)
+	}
+	config, err = ParseTaskConfig(TaskNaabu, map[string]any{"top_ports": 1000.0})
+	if err != nil || config.(*NaabuConfig).TopPorts != "1000" {
+		t.Errorf("ParseTaskConfig() with numeric top_ports = %+v, %v, want \"1000\"", config, err)
+	}
+}
+
+func TestParseTaskConfig_Errors(t *testing.T) {
+	tests := []struct {
+		task   Task
+		config map[string]any
+		want   string
+	}{
+		{TaskHttpx, map[string]any{"top_ports": "100", "rate": 10.0}, "unrecognized config keys: rate, top_ports"},
+		{TaskCloudDNS, map[string]any{"sources": []any{map[string]any{"provider": "aws", "region": "x"}}}, "unrecognized config keys: sources[0].region"},
+		{TaskCloudDNS, map[string]any{"sources": map[string]any{"provider": "route53"}}, "sources"},
+		{TaskNaabu, map[string]any{"ports": []any{80.0, 70000.0}}, "ports[1] must be at most 65535"},
+		{TaskNaabu, map[string]any{"top_ports": "500"}, "top_ports must be one of full, 100, 1000, got 500"},
+		{TaskNaabu, map[string]any{"rate_limit": 1.5}, "rate_limit: expected a whole number"},
+		{TaskNaabu, map[string]any{"host_discovery": "yes"}, "host_discovery:"},
+		{TaskDNSResolve, map[string]any{"retries": -1.0}, "retries must be at least 0"},
+		{TaskSubfinder, map[string]any{"exclude": []any{"a.example.com", ""}}, "exclude[1] must not be empty"},
+		{TaskSubfinder, map[string]any{"include_patterns": 5.0}, "include_patterns"},
+		{TaskSubfinder, map[string]any{"exclude_patterns": map[string]any{"re": "^dev"}}, "exclude_patterns"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTaskConfig(tt.task, tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseTaskConfig(%s, %v) error = %v, want %q", tt.task, tt.config, err, tt.want)
+		}
+	}
+}
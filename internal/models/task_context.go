@@ -0,0 +1,247 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/projectdiscovery/gologger"
+)
+
+// ArtifactStore stores scanner artifacts in blob storage
+type ArtifactStore interface {
+	StoreArtifact(ctx context.Context, blobPath string, data []byte) error
+}
+
+// ArtifactStreamer is an ArtifactStore that can also upload an artifact while it is written
+type ArtifactStreamer interface {
+	ArtifactStore
+	StreamArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error)
+}
+
+// TaskContext carries the metadata of the task a scanner runs for, so scanners can log with the
+// task's identity, honor its scope, report progress and write artifacts without global state.
+// A nil TaskContext is valid: everything is in scope and progress and artifacts are dropped.
+type TaskContext struct {
+	ScanID        int
+	TenantID      string
+	CorrelationID string
+	Domain        string
+	Task          Task
+	Attempt       int          // Delivery of the task, which names the blobs it writes
+	Scope         []string     // Domains the task may touch, each covering its subdomains
+	OutputPrefix  string       // Blob prefix of the task's outputs, e.g. "acme/example.com-12/nuclei/"
+	Deadline      time.Time    // When the scanner time budget runs out; zero when unbounded
+	Lock          *MessageLock // Lock on the task's queue message; nil when not received from a queue
+
+	Artifacts  ArtifactStore
+	OnProgress func(progress ScanProgress)
+
+	BlockPolicy BlockPolicy      // How scanners react to a WAF or rate-limit ban mid-scan
+	OnBlock     func(BlockEvent) // Called once for every host group found blocked
+}
+
+// ScanProgress is a scanner's progress through the current phase of a task
+type ScanProgress struct {
+	Phase     string `json:"phase"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total,omitempty"` // 0 when the amount of work is not known up front
+}
+
+// Ratio returns the completed fraction of the phase, or -1 when the total is unknown
+func (p ScanProgress) Ratio() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Processed) / float64(p.Total)
+}
+
+// String formats the progress, e.g. "resolve 500/2000 (25%)"
+func (p ScanProgress) String() string {
+	if p.Total <= 0 {
+		return fmt.Sprintf("%s %d", p.Phase, p.Processed)
+	}
+	return fmt.Sprintf("%s %d/%d (%.0f%%)", p.Phase, p.Processed, p.Total, 100*p.Ratio())
+}
+
+// NewTaskContext creates the context of a task whose scanner must finish by deadline. The task's
+// domain is its scope; a domain field listing several names scopes the task to each of them. A
+// URL or host:port domain scopes the task to its host name.
+func NewTaskContext(taskMsg *TaskMessage, deadline time.Time) *TaskContext {
+	var scope []string
+	for _, line := range strings.Split(taskMsg.Domain, "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if host := targetHost(line); host != "" {
+			scope = append(scope, host)
+		}
+	}
+
+	return &TaskContext{
+		ScanID:        taskMsg.ScanID,
+		TenantID:      taskMsg.TenantID,
+		CorrelationID: taskMsg.CorrelationID,
+		Domain:        taskMsg.Domain,
+		Task:          taskMsg.Task,
+		Attempt:       taskMsg.Attempt,
+		Scope:         scope,
+		OutputPrefix:  ScanBlobPrefix(taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID) + string(taskMsg.Task) + "/",
+		Deadline:      deadline,
+	}
+}
+
+// InScope reports whether a target may be scanned. IP addresses and URLs whose host is an IP
+// cannot be judged by domain and are allowed; names must be a scope domain or a subdomain of one.
+func (t *TaskContext) InScope(target string) bool {
+	if t == nil || len(t.Scope) == 0 {
+		return true
+	}
+
+	host := targetHost(target)
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	for _, domain := range t.Scope {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterScope returns the targets that are in scope, keeping their order
+func (t *TaskContext) FilterScope(targets []string) (inScope []string, dropped int) {
+	inScope = make([]string, 0, len(targets))
+	for _, target := range targets {
+		if t.InScope(target) {
+			inScope = append(inScope, target)
+		} else {
+			dropped++
+		}
+	}
+	return inScope, dropped
+}
+
+// Tenant returns the tenant the task runs for, or "" without a task context
+func (t *TaskContext) Tenant() string {
+	if t == nil {
+		return ""
+	}
+	return t.TenantID
+}
+
+// Remaining returns the time left until the deadline or the end of the message lock, whichever
+// comes first, or 0 when there is neither
+func (t *TaskContext) Remaining() time.Duration {
+	if t == nil {
+		return 0
+	}
+	deadline := t.Deadline
+	if lockDeadline := t.Lock.Deadline(); !lockDeadline.IsZero() && (deadline.IsZero() || lockDeadline.Before(deadline)) {
+		deadline = lockDeadline
+	}
+	if deadline.IsZero() {
+		return 0
+	}
+	return time.Until(deadline)
+}
+
+// LockLost returns a channel that is closed once the message lock can no longer be renewed.
+// A scanner should then checkpoint and stop before Remaining runs out and another worker
+// receives the message.
+func (t *TaskContext) LockLost() <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	return t.Lock.Lost()
+}
+
+// ReportProgress reports that processed of total units of work of a phase are done; a total of
+// 0 means the amount of work is unknown
+func (t *TaskContext) ReportProgress(phase string, processed, total int) {
+	if t != nil && t.OnProgress != nil {
+		t.OnProgress(ScanProgress{Phase: phase, Processed: processed, Total: total})
+	}
+}
+
+// NewBlockDetector creates the detector a scanner watches its responses for bans with, or returns
+// nil when ban detection is off
+func (t *TaskContext) NewBlockDetector() *BlockDetector {
+	if t == nil {
+		return nil
+	}
+	return NewBlockDetector(t.BlockPolicy, t.OnBlock)
+}
+
+// WriteArtifact stores data under the task's artifacts/ prefix and returns its blob path
+func (t *TaskContext) WriteArtifact(ctx context.Context, name string, data []byte) (string, error) {
+	if t == nil || t.Artifacts == nil {
+		return "", fmt.Errorf("no artifact store for artifact %s", name)
+	}
+	if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid artifact name %q", name)
+	}
+
+	blobPath := t.OutputPrefix + "artifacts/" + name
+	if err := t.Artifacts.StoreArtifact(ctx, blobPath, data); err != nil {
+		return "", err
+	}
+	return blobPath, nil
+}
+
+// CreateArtifact opens an artifact under the task's artifacts/ prefix that is uploaded while it is
+// written, returning it with its blob path. The upload completes when the writer is closed.
+func (t *TaskContext) CreateArtifact(ctx context.Context, name string) (io.WriteCloser, string, error) {
+	if t == nil || t.Artifacts == nil {
+		return nil, "", fmt.Errorf("no artifact store for artifact %s", name)
+	}
+	streamer, ok := t.Artifacts.(ArtifactStreamer)
+	if !ok {
+		return nil, "", fmt.Errorf("artifact store cannot stream artifact %s", name)
+	}
+	if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		return nil, "", fmt.Errorf("invalid artifact name %q", name)
+	}
+
+	blobPath := t.OutputPrefix + "artifacts/" + name
+	writer, err := streamer.StreamArtifact(ctx, blobPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return writer, blobPath, nil
+}
+
+// Info starts an info log event tagged with the task's identity
+func (t *TaskContext) Info() *gologger.Event {
+	return t.tag(gologger.Info())
+}
+
+// Warning starts a warning log event tagged with the task's identity
+func (t *TaskContext) Warning() *gologger.Event {
+	return t.tag(gologger.Warning())
+}
+
+// Debug starts a debug log event tagged with the task's identity
+func (t *TaskContext) Debug() *gologger.Event {
+	return t.tag(gologger.Debug())
+}
+
+// tag adds the scan, task, tenant and correlation ID to a log event
+func (t *TaskContext) tag(event *gologger.Event) *gologger.Event {
+	if t == nil {
+		return event
+	}
+	event = event.Str("scan_id", strconv.Itoa(t.ScanID)).Str("task", string(t.Task))
+	if t.TenantID != "" {
+		event = event.Str("tenant", t.TenantID)
+	}
+	if t.CorrelationID != "" {
+		event = event.Str("correlation_id", t.CorrelationID)
+	}
+	return event
+}
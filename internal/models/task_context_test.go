@@ -0,0 +1,107 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type memoryArtifacts map[string][]byte
+
+func (m memoryArtifacts) StoreArtifact(ctx context.Context, blobPath string, data []byte) error {
+	m[blobPath] = data
+	return nil
+}
+
+func TestTaskContext(t *testing.T) {
+	taskCtx := NewTaskContext(&TaskMessage{Task: TaskNuclei, ScanID: 7, TenantID: "acme", Domain: "Example.com"}, time.Now().Add(time.Hour))
+
+	if taskCtx.OutputPrefix != "acme/Example.com-7/nuclei/" {
+		t.Errorf("Unexpected output prefix %s", taskCtx.OutputPrefix)
+	}
+
+	for target, want := range map[string]bool{
+		"example.com":                    true,
+		"api.example.com.":               true,
+		"https://www.example.com:8443/x": true,
+		"192.0.2.1:443":                  true,
+		"[2001:db8::1]:443":              true,
+		"badexample.com":                 false,
+		"https://example.com.evil.org/":  false,
+	} {
+		if got := taskCtx.InScope(target); got != want {
+			t.Errorf("InScope(%q) = %t, want %t", target, got, want)
+		}
+	}
+
+	// A URL or host:port target is scoped to its host name
+	for _, domain := range []string{"https://app.example.com:8443/login", "app.example.com:8443"} {
+		urlCtx := NewTaskContext(&TaskMessage{Task: TaskNuclei, ScanID: 7, Domain: domain}, time.Time{})
+		if !urlCtx.InScope("https://app.example.com:8443/login") || !urlCtx.InScope("app.example.com") || urlCtx.InScope("example.com") {
+			t.Errorf("NewTaskContext(%q) scope = %v, want app.example.com", domain, urlCtx.Scope)
+		}
+	}
+
+	artifacts := memoryArtifacts{}
+	taskCtx.Artifacts = artifacts
+	path, err := taskCtx.WriteArtifact(context.Background(), "partial.json", []byte("{}"))
+	if err != nil || path != "acme/Example.com-7/nuclei/artifacts/partial.json" || artifacts[path] == nil {
+		t.Errorf("Unexpected artifact %s, %v", path, err)
+	}
+	if _, err := taskCtx.WriteArtifact(context.Background(), "../escape.json", nil); err == nil {
+		t.Error("Expected a name leaving the artifacts prefix to be rejected")
+	}
+
+	var progress []ScanProgress
+	taskCtx.OnProgress = func(p ScanProgress) { progress = append(progress, p) }
+	taskCtx.ReportProgress("resolve", 5, 20)
+	if len(progress) != 1 || progress[0].String() != "resolve 5/20 (25%)" {
+		t.Errorf("Expected the progress callback to be called, got %v", progress)
+	}
+	if (ScanProgress{Phase: "probe", Processed: 3}).String() != "probe 3" {
+		t.Error("Expected progress without a total to show only the processed count")
+	}
+}
+
+func TestTaskContext_Nil(t *testing.T) {
+	var taskCtx *TaskContext
+
+	if !taskCtx.InScope("anything.org") || taskCtx.Remaining() != 0 {
+		t.Error("Expected a nil task context to allow every target without a deadline")
+	}
+	taskCtx.ReportProgress("phase", 1, 2)
+	taskCtx.Info().Msg("logging through a nil task context")
+	if _, err := taskCtx.WriteArtifact(context.Background(), "x.json", nil); err == nil {
+		t.Error("Expected writing an artifact without a store to fail")
+	}
+}
+
+func TestTaskContext_MessageLock(t *testing.T) {
+	now := time.Now()
+	lock := NewMessageLock(now.Add(time.Minute), now.Add(time.Hour))
+	taskCtx := &TaskContext{Deadline: now.Add(2 * time.Hour), Lock: lock}
+
+	if remaining := taskCtx.Remaining(); remaining < 59*time.Minute || remaining > time.Hour {
+		t.Errorf("Expected the renewals to bound the remaining time, got %s", remaining)
+	}
+
+	lock.Renewed(now.Add(5 * time.Minute))
+	lock.MarkLost()
+	select {
+	case <-taskCtx.LockLost():
+	default:
+		t.Fatal("Expected the lost channel to be closed")
+	}
+	if remaining := taskCtx.Remaining(); remaining < 4*time.Minute || remaining > 5*time.Minute {
+		t.Errorf("Expected a lost lock to leave the time until it expires, got %s", remaining)
+	}
+
+	ctx := WithMessageLock(context.Background(), lock)
+	if MessageLockFromContext(ctx) != lock || MessageLockFromContext(context.Background()) != nil {
+		t.Error("Expected the lock to be carried by the context")
+	}
+	var nilLock *MessageLock
+	if nilLock.IsLost() || !nilLock.Deadline().IsZero() {
+		t.Error("Expected a nil lock to be held without a deadline")
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestResultBlobNames(t *testing.T) {
+	if got := AttemptBlobName(3, ".json"); got != "attempt-3.json" {
+		t.Errorf("Expected attempt-3.json, got %s", got)
+	}
+	if got := AttemptBlobName(0, ""); got != "attempt-1" {
+		t.Errorf("Expected an unknown attempt named as the first, got %s", got)
+	}
+	if got := LatestResultBlobPath("acme", "example.com", 12, "dnsx"); got != "acme/example.com-12/dnsx/latest.json" {
+		t.Errorf("Unexpected latest result path %s", got)
+	}
+	if got := LatestResultBlobPath("", "example.com", 12, "dnsx"); got != "example.com-12/dnsx/latest.json" {
+		t.Errorf("Unexpected latest result path without tenant %s", got)
+	}
+}
+
+func TestDomainBlobSegment(t *testing.T) {
+	tests := map[string]string{
+		"example.com":             "example.com",
+		"_dmarc.example.com":      "_dmarc.example.com",
+		"https://example.com":     "https___example.com",
+		"example.com:8443":        "example.com_8443",
+		"http://example.com/../x": "http___example.com_.._x",
+		"[2001:db8::1]:8080":      "_2001_db8__1__8080",
+	}
+	for domain, want := range tests {
+		if got := DomainBlobSegment(domain); got != want {
+			t.Errorf("DomainBlobSegment(%q) = %q, want %q", domain, got, want)
+		}
+	}
+	if got := ScanBlobPrefix("acme", "https://example.com:8443", 7); got != "acme/https___example.com_8443-7/" {
+		t.Errorf("Unexpected scan prefix for a URL target %s", got)
+	}
+}
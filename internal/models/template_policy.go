@@ -0,0 +1,131 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TemplatePolicyBlobPath returns the blob path of the template policy of a tenant, or of the
+// global policy for an empty tenant
+func TemplatePolicyBlobPath(tenantID string) string {
+	path := "control/template_policy.json"
+	if tenantID != "" {
+		path = tenantID + "/" + path
+	}
+	return path
+}
+
+// TemplatePolicy limits the nuclei templates the scans of a tenant run, e.g.
+//
+//	{"deny_tags": ["dos", "intrusive"], "deny_wordlists": true, "allow_ids": ["http-missing-security-headers"]}
+type TemplatePolicy struct {
+	AllowTags []string `json:"allow_tags,omitempty"` // When set, only templates with one of these tags run
+	DenyTags  []string `json:"deny_tags,omitempty"`
+	DenyIDs   []string `json:"deny_ids,omitempty"`
+	// DenyWordlists excludes templates that read their payloads from wordlist files, such as
+	// bruteforce and fuzzing templates
+	DenyWordlists bool `json:"deny_wordlists,omitempty"`
+	// AllowIDs run even when another rule excludes them
+	AllowIDs []string `json:"allow_ids,omitempty"`
+}
+
+// TemplateInfo is the metadata of a template a policy is evaluated against
+type TemplateInfo struct {
+	ID        string
+	Tags      []string
+	Wordlists []string // Payload files the template reads
+	Paths     []string // Paths its HTTP requests fetch, e.g. "/admin/login"
+}
+
+// TemplateViolation is a template a policy excluded, with the rule that excluded it
+type TemplateViolation struct {
+	TemplateID string `json:"template_id"`
+	Rule       string `json:"rule"` // e.g. "deny_tags: dos"
+}
+
+// TemplatePolicyReport records how a template policy was applied to a scan
+type TemplatePolicyReport struct {
+	Policy     TemplatePolicy      `json:"policy"`
+	Templates  int                 `json:"templates"` // Templates evaluated
+	Violations []TemplateViolation `json:"violations,omitempty"`
+}
+
+// RuleCounts summarizes the violations by rule, e.g. "deny_tags: dos (12), deny_wordlists (3)"
+func (r *TemplatePolicyReport) RuleCounts() string {
+	counts := make(map[string]int)
+	var rules []string
+	for _, violation := range r.Violations {
+		rule := violation.Rule
+		if key, _, ok := strings.Cut(rule, ": "); ok && key != "deny_tags" {
+			rule = key
+		}
+		if counts[rule] == 0 {
+			rules = append(rules, rule)
+		}
+		counts[rule]++
+	}
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = fmt.Sprintf("%s (%d)", rule, counts[rule])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ParseTemplatePolicy parses and validates a template policy
+func ParseTemplatePolicy(data []byte) (*TemplatePolicy, error) {
+	var policy TemplatePolicy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return nil, fmt.Errorf("invalid template policy: %w", err)
+	}
+	lists := map[string][]string{
+		"allow_tags": policy.AllowTags,
+		"deny_tags":  policy.DenyTags,
+		"deny_ids":   policy.DenyIDs,
+		"allow_ids":  policy.AllowIDs,
+	}
+	for key, values := range lists {
+		for _, value := range values {
+			if strings.TrimSpace(value) == "" || strings.Contains(value, ",") {
+				return nil, fmt.Errorf("invalid template policy: %s entry %q must be a single non-empty value", key, value)
+			}
+		}
+	}
+	return &policy, nil
+}
+
+// IsZero reports whether the policy excludes nothing
+func (p *TemplatePolicy) IsZero() bool {
+	return p == nil || (len(p.AllowTags) == 0 && len(p.DenyTags) == 0 && len(p.DenyIDs) == 0 && !p.DenyWordlists)
+}
+
+// Violation returns the rule that excludes a template, or false when the template may run
+func (p *TemplatePolicy) Violation(template TemplateInfo) (string, bool) {
+	if p.IsZero() || slices.Contains(p.AllowIDs, template.ID) {
+		return "", false
+	}
+	if slices.Contains(p.DenyIDs, template.ID) {
+		return "deny_ids: " + template.ID, true
+	}
+	for _, tag := range template.Tags {
+		if containsFold(p.DenyTags, tag) {
+			return "deny_tags: " + strings.ToLower(tag), true
+		}
+	}
+	if p.DenyWordlists && len(template.Wordlists) > 0 {
+		return "deny_wordlists: " + template.Wordlists[0], true
+	}
+	if len(p.AllowTags) > 0 && !slices.ContainsFunc(template.Tags, func(tag string) bool { return containsFold(p.AllowTags, tag) }) {
+		return "allow_tags: none of " + strings.Join(p.AllowTags, ", "), true
+	}
+	return "", false
+}
+
+// containsFold reports whether values contain value, ignoring case
+func containsFold(values []string, value string) bool {
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, value) })
+}
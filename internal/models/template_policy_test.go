@@ -0,0 +1,73 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplatePolicyViolation(t *testing.T) {
+	policy, err := ParseTemplatePolicy([]byte(`{"deny_tags": ["dos", "intrusive"], "deny_ids": ["git-config"],
+		"deny_wordlists": true, "allow_ids": ["ssh-default-logins"]}`))
+	if err != nil {
+		t.Fatalf("ParseTemplatePolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		template TemplateInfo
+		want     string
+	}{
+		{"allowed", TemplateInfo{ID: "tech-detect", Tags: []string{"tech"}}, ""},
+		{"denied tag", TemplateInfo{ID: "slowloris", Tags: []string{"DoS", "network"}}, "deny_tags: dos"},
+		{"denied id", TemplateInfo{ID: "git-config", Tags: []string{"git"}}, "deny_ids: git-config"},
+		{"wordlist", TemplateInfo{ID: "ftp-weak", Wordlists: []string{"helpers/wordlists/ftp.txt"}}, "deny_wordlists: helpers/wordlists/ftp.txt"},
+		{"allowed id", TemplateInfo{ID: "ssh-default-logins", Tags: []string{"intrusive"}, Wordlists: []string{"users.txt"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, violated := policy.Violation(tt.template)
+			if rule != tt.want || violated != (tt.want != "") {
+				t.Errorf("Violation() = %q, %v, want %q", rule, violated, tt.want)
+			}
+		})
+	}
+
+	allow := &TemplatePolicy{AllowTags: []string{"cve"}}
+	if _, violated := allow.Violation(TemplateInfo{ID: "CVE-2021-44228", Tags: []string{"CVE", "rce"}}); violated {
+		t.Error("Violation() excluded a template with an allowed tag")
+	}
+	if rule, _ := allow.Violation(TemplateInfo{ID: "tech-detect", Tags: []string{"tech"}}); rule != "allow_tags: none of cve" {
+		t.Errorf("Violation() = %q, want the allow_tags rule", rule)
+	}
+
+	report := TemplatePolicyReport{Violations: []TemplateViolation{
+		{TemplateID: "slowloris", Rule: "deny_tags: dos"},
+		{TemplateID: "ftp-weak", Rule: "deny_wordlists: ftp.txt"},
+		{TemplateID: "http-flood", Rule: "deny_tags: dos"},
+	}}
+	if got := report.RuleCounts(); got != "deny_tags: dos (2), deny_wordlists (1)" {
+		t.Errorf("RuleCounts() = %q", got)
+	}
+
+	var none *TemplatePolicy
+	if _, violated := none.Violation(TemplateInfo{ID: "slowloris", Tags: []string{"dos"}}); violated {
+		t.Error("a nil policy excluded a template")
+	}
+}
+
+func TestParseTemplatePolicy(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{`{"deny_tag": ["dos"]}`, "unknown field"},
+		{`{"deny_tags": ["dos,intrusive"]}`, "deny_tags entry"},
+		{`{"allow_ids": [" "]}`, "allow_ids entry"},
+		{`deny`, "invalid template policy"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseTemplatePolicy([]byte(tt.spec)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseTemplatePolicy(%s) error = %v, want %q", tt.spec, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TLS ClientHello fingerprints httpx can probe with. Some WAFs block the fingerprint of Go's
+// TLS stack, which hides the services behind them from the scan.
+const (
+	TLSFingerprintGo     = "go"     // Go's standard TLS stack
+	TLSFingerprintRandom = "random" // A randomized ClientHello sent through uTLS
+	TLSFingerprintZTLS   = "ztls"   // The zcrypto TLS stack, whose ClientHello differs from Go's
+)
+
+// IsValidTLSFingerprint reports whether the fingerprint is one httpx can probe with
+func IsValidTLSFingerprint(fingerprint string) bool {
+	switch fingerprint {
+	case TLSFingerprintGo, TLSFingerprintRandom, TLSFingerprintZTLS:
+		return true
+	}
+	return false
+}
+
+// TLSFingerprints maps tenants to the TLS fingerprint their httpx probes use
+type TLSFingerprints map[string]string
+
+// ParseTLSFingerprints parses fingerprints in the form "tenant=fingerprint,tenant=fingerprint".
+// The tenant "*", or an entry without a tenant, sets the fingerprint of all other tenants.
+func ParseTLSFingerprints(spec string) (TLSFingerprints, error) {
+	fingerprints := make(TLSFingerprints)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, fingerprint, ok := strings.Cut(entry, "=")
+		if !ok {
+			tenant, fingerprint = "*", entry
+		}
+		tenant = strings.TrimSpace(tenant)
+		fingerprint = strings.ToLower(strings.TrimSpace(fingerprint))
+		if tenant == "" {
+			return nil, fmt.Errorf("invalid TLS fingerprint %q: expected tenant=fingerprint", entry)
+		}
+		if !IsValidTLSFingerprint(fingerprint) {
+			return nil, fmt.Errorf("invalid TLS fingerprint %q: must be %s, %s or %s", entry, TLSFingerprintGo, TLSFingerprintRandom, TLSFingerprintZTLS)
+		}
+		fingerprints[tenant] = fingerprint
+	}
+	return fingerprints, nil
+}
+
+// Lookup returns the TLS fingerprint of a tenant, falling back to "*" and then to Go's own
+func (f TLSFingerprints) Lookup(tenantID string) string {
+	if fingerprint, ok := f[tenantID]; ok && tenantID != "" {
+		return fingerprint
+	}
+	if fingerprint, ok := f["*"]; ok {
+		return fingerprint
+	}
+	return TLSFingerprintGo
+}
+
+// Resolve returns the TLS fingerprint of a task of the tenant. The task's override applies only
+// to tenants without a fingerprint of their own: a tenant's entry is policy and always wins.
+func (f TLSFingerprints) Resolve(tenantID, override string) string {
+	if _, pinned := f[tenantID]; pinned && tenantID != "" {
+		return f[tenantID]
+	}
+	if override != "" {
+		return override
+	}
+	return f.Lookup(tenantID)
+}
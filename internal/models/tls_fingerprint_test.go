@@ -0,0 +1,59 @@
+package models
+
+import "testing"
+
+// TestParseTLSFingerprints tests parsing and tenant lookup of TLS fingerprints
+func TestParseTLSFingerprints(t *testing.T) {
+	fingerprints, err := ParseTLSFingerprints("random, acme=go,globex=ZTLS")
+	if err != nil {
+		t.Fatalf("ParseTLSFingerprints failed: %v", err)
+	}
+	tests := map[string]string{
+		"acme":    TLSFingerprintGo,
+		"globex":  TLSFingerprintZTLS,
+		"initech": TLSFingerprintRandom,
+		"":        TLSFingerprintRandom,
+	}
+	for tenant, expected := range tests {
+		if got := fingerprints.Lookup(tenant); got != expected {
+			t.Errorf("Lookup(%q) = %q, expected %q", tenant, got, expected)
+		}
+	}
+
+	empty, err := ParseTLSFingerprints("")
+	if err != nil || empty.Lookup("acme") != TLSFingerprintGo {
+		t.Errorf("Expected Go's fingerprint without configuration, got %q (%v)", empty.Lookup("acme"), err)
+	}
+	var unset TLSFingerprints
+	if unset.Lookup("acme") != TLSFingerprintGo {
+		t.Error("Expected a nil map to fall back to Go's fingerprint")
+	}
+
+	for _, spec := range []string{"acme=chrome", "=random", "firefox"} {
+		if _, err := ParseTLSFingerprints(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+// TestTLSFingerprintsResolve tests that a tenant's own fingerprint wins over a task's override
+func TestTLSFingerprintsResolve(t *testing.T) {
+	fingerprints, err := ParseTLSFingerprints("*=go,acme=ztls")
+	if err != nil {
+		t.Fatalf("ParseTLSFingerprints failed: %v", err)
+	}
+	tests := []struct {
+		tenant, override, expected string
+	}{
+		{"acme", "random", TLSFingerprintZTLS},
+		{"acme", "", TLSFingerprintZTLS},
+		{"globex", "random", TLSFingerprintRandom},
+		{"globex", "", TLSFingerprintGo},
+		{"", "random", TLSFingerprintRandom},
+	}
+	for _, tt := range tests {
+		if got := fingerprints.Resolve(tt.tenant, tt.override); got != tt.expected {
+			t.Errorf("Resolve(%q, %q) = %q, expected %q", tt.tenant, tt.override, got, tt.expected)
+		}
+	}
+}
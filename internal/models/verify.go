@@ -0,0 +1,54 @@
+package models
+
+// TaskMode changes how a task picks its targets; the empty mode scans every target
+type TaskMode string
+
+// TaskModeVerify re-scans only the targets an earlier stage's latest result added since a baseline
+// result, e.g. new subdomains or newly open ports, so change alerts can be confirmed quickly
+const TaskModeVerify TaskMode = "verify"
+
+// VerifyTasks are the task types that can run in verify mode: those that take their targets from
+// an earlier stage's result
+var VerifyTasks = []Task{TaskDNSResolve, TaskNaabu, TaskHttpx, TaskNuclei, TaskScopeExpansion}
+
+// VerifyDelta records which targets a verify task scanned
+type VerifyDelta struct {
+	BaselineResultPath string `json:"baseline_result_path"`
+	Targets            int    `json:"targets"`   // Targets of the input result
+	Baseline           int    `json:"baseline"`  // Targets of the baseline result
+	Scanned            int    `json:"scanned"`   // Targets of the input result missing from the baseline
+	Unchanged          int    `json:"unchanged"` // Targets of the input result also in the baseline
+}
+
+// DeltaTargets returns the targets missing from the baseline, keeping their order
+func DeltaTargets(targets, baseline []string) []string {
+	known := make(map[string]struct{}, len(baseline))
+	for _, target := range baseline {
+		known[target] = struct{}{}
+	}
+	delta := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if _, ok := known[target]; !ok {
+			delta = append(delta, target)
+		}
+	}
+	return delta
+}
+
+// EmptyResult returns the result of a task type that found nothing, for verify tasks without new
+// targets. It returns nil for task types that do not run in verify mode.
+func EmptyResult(task Task, domain string) ScannerResult {
+	switch task {
+	case TaskDNSResolve:
+		return DNSXResult{Domain: domain, Records: map[string]ResolutionInfo{}}
+	case TaskNaabu:
+		return NaabuResult{Domain: domain, Ports: map[string][]PortInfo{}}
+	case TaskHttpx:
+		return HttpxResult{Domain: domain, Results: []HttpxHostResult{}}
+	case TaskNuclei:
+		return NucleiResult{Domain: domain, Vulnerabilities: []NucleiVulnerability{}}
+	case TaskScopeExpansion:
+		return ScopeExpansionResult{Domain: domain, Suggestions: []ScopeSuggestion{}}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeltaTargets(t *testing.T) {
+	targets := []string{"192.0.2.1:443", "192.0.2.1:8443", "192.0.2.2:80", "192.0.2.3:22"}
+	baseline := []string{"192.0.2.1:443", "192.0.2.2:80", "192.0.2.9:80"}
+	if got := DeltaTargets(targets, baseline); !reflect.DeepEqual(got, []string{"192.0.2.1:8443", "192.0.2.3:22"}) {
+		t.Errorf("DeltaTargets() = %v, want the newly open ports in input order", got)
+	}
+	if got := DeltaTargets(baseline[:2], baseline); len(got) != 0 {
+		t.Errorf("DeltaTargets() of unchanged targets = %v", got)
+	}
+}
+
+func TestEmptyResult(t *testing.T) {
+	for _, task := range VerifyTasks {
+		result := EmptyResult(task, "example.com")
+		if result == nil || result.GetDomain() != "example.com" || result.GetCount() != 0 {
+			t.Errorf("EmptyResult(%s) = %+v", task, result)
+		}
+	}
+	if result := EmptyResult(TaskSubfinder, "example.com"); result != nil {
+		t.Errorf("EmptyResult(subfinder) = %+v, want nil", result)
+	}
+}
+
+func TestTaskMessage_RoutingPropertiesMode(t *testing.T) {
+	taskMsg := &TaskMessage{Task: TaskHttpx, Mode: TaskModeVerify}
+	if got := taskMsg.RoutingProperties(); got[ModeProperty] != "verify" {
+		t.Errorf("Expected the mode property, got %v", got)
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// WebhookCallBlobPath returns the blob path of the record of a signed webhook call, named by the
+// hash of its signature
+func WebhookCallBlobPath(signature string) string {
+	sum := sha256.Sum256([]byte(signature))
+	return "control/webhooks/" + hex.EncodeToString(sum[:]) + ".json"
+}
+
+// WebhookCall records a signed webhook call in blob storage, so that every API replica rejects
+// its replays, also after a restart. The tasks of the call are counted as they are queued: a
+// call retried after a partial enqueue failure queues only the tasks that are still missing.
+type WebhookCall struct {
+	ReceivedAt     time.Time `json:"received_at"`
+	ScanID         int       `json:"scan_id"`
+	CorrelationIDs []string  `json:"correlation_ids"` // One per task, in the order of the call
+	Queued         int       `json:"queued"`          // Tasks queued so far
+	// ClaimedAt is when a replica started queuing the tasks; another replica receiving the
+	// same call waits for the claim to expire before taking over
+	ClaimedAt time.Time `json:"claimed_at,omitempty"`
+}
+
+// Complete reports whether every task of the call was queued
+func (c *WebhookCall) Complete() bool {
+	return len(c.CorrelationIDs) > 0 && c.Queued >= len(c.CorrelationIDs)
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/allsafeASM/api/internal/faults"
 	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
 	"github.com/projectdiscovery/gologger"
 )
 
@@ -18,6 +21,7 @@ type DiscordNotifier struct {
 	webhookURL string
 	httpClient *http.Client
 	enabled    bool
+	locale     Locale
 }
 
 // DiscordEmbed represents a Discord embed object
@@ -66,6 +70,12 @@ const (
 	StepTaskFailed       NotificationStep = "task_failed"
 	StepResultStored     NotificationStep = "result_stored"
 	StepNotificationSent NotificationStep = "notification_sent"
+	StepTaskSkipped      NotificationStep = "task_skipped"
+	StepTaskPaused       NotificationStep = "task_paused"
+	StepTaskProgress     NotificationStep = "task_progress"
+	StepScanHalted       NotificationStep = "scan_halted"
+	StepScanDegraded     NotificationStep = "scan_degraded"
+	StepTargetsBlocked   NotificationStep = "targets_blocked"
 )
 
 // Color constants for Discord embeds
@@ -77,52 +87,34 @@ const (
 	ColorPurple  = 0x9b59b6 // Purple
 )
 
-// formatDuration formats a duration string to a more readable format
-func formatDuration(durationStr string) string {
-	// Parse the duration string
-	duration, err := time.ParseDuration(durationStr)
-	if err != nil {
-		return durationStr // Return original if parsing fails
-	}
-
-	// Format based on duration length
-	if duration < time.Second {
-		return fmt.Sprintf("%.0fms", float64(duration.Microseconds())/1000)
-	} else if duration < time.Minute {
-		return fmt.Sprintf("%.1fs", duration.Seconds())
-	} else if duration < time.Hour {
-		minutes := int(duration.Minutes())
-		seconds := int(duration.Seconds()) % 60
-		return fmt.Sprintf("%dm %ds", minutes, seconds)
-	} else {
-		hours := int(duration.Hours())
-		minutes := int(duration.Minutes()) % 60
-		return fmt.Sprintf("%dh %dm", hours, minutes)
-	}
-}
-
 // NewDiscordNotifier creates a new Discord notifier
 func NewDiscordNotifier() (*DiscordNotifier, error) {
 	webhookURL := os.Getenv("DISCORD_WEBHOOK_URL")
 	if webhookURL == "" {
 		return &DiscordNotifier{
 			webhookURL: "",
-			httpClient: &http.Client{
-				Timeout: 30 * time.Second,
-			},
-			enabled: false,
+			httpClient: utils.NewTracingClient(30 * time.Second),
+			enabled:    false,
 		}, nil
 	}
 
 	return &DiscordNotifier{
 		webhookURL: webhookURL,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		enabled: true,
+		httpClient: utils.NewTracingClient(30 * time.Second),
+		enabled:    true,
 	}, nil
 }
 
+// SetFaultInjector drops webhook calls as the injector decides, for resilience testing
+func (d *DiscordNotifier) SetFaultInjector(injector *faults.Injector) {
+	d.httpClient.Transport = injector.Transport(d.httpClient.Transport)
+}
+
+// SetLocale renders the times and durations of notifications in a language and timezone
+func (d *DiscordNotifier) SetLocale(locale Locale) {
+	d.locale = locale
+}
+
 // NewConfiguredDiscordNotifier creates a Discord notifier based on configuration
 func NewConfiguredDiscordNotifier(enableDiscordNotifications bool) (*DiscordNotifier, error) {
 	if !enableDiscordNotifications {
@@ -156,10 +148,110 @@ func (d *DiscordNotifier) NotifyStep(ctx context.Context, step NotificationStep,
 	return d.sendWebhook(ctx, payload)
 }
 
+// NotifyProgress sends a periodic progress update for a long-running scanner
+func (d *DiscordNotifier) NotifyProgress(ctx context.Context, taskMsg *models.TaskMessage, progress models.ScanProgress, elapsed time.Duration) error {
+	if !d.enabled {
+		return nil
+	}
+
+	payload := d.createPayload(StepTaskProgress, taskMsg, nil, nil)
+	payload.Embeds[0].Fields = append(payload.Embeds[0].Fields,
+		DiscordEmbedField{Name: "Progress", Value: progress.String(), Inline: true},
+		DiscordEmbedField{Name: "Elapsed", Value: d.locale.FormatDuration(elapsed.Round(time.Second).String()), Inline: true},
+	)
+	return d.sendWebhook(ctx, payload)
+}
+
+// NotifyDigest sends the digest of a whole scan after its final task completed
+func (d *DiscordNotifier) NotifyDigest(ctx context.Context, taskMsg *models.TaskMessage, digest *models.ScanDigest) error {
+	if !d.enabled {
+		return nil
+	}
+
+	return d.sendWebhook(ctx, d.createDigestPayload(taskMsg, digest))
+}
+
+// NotifyVersionDrift alerts operators that the worker is older than the required minimum version
+func (d *DiscordNotifier) NotifyVersionDrift(ctx context.Context, drift models.VersionDrift) error {
+	if !d.enabled {
+		return nil
+	}
+
+	now := d.locale.In(time.Now())
+	embed := DiscordEmbed{
+		Title:       "⏳ Worker Out of Date",
+		Description: drift.String(),
+		Color:       ColorWarning,
+		Timestamp:   now.Format(time.RFC3339),
+		Fields: []DiscordEmbedField{
+			{Name: "Running", Value: drift.Running, Inline: true},
+			{Name: "Required", Value: drift.Minimum, Inline: true},
+		},
+		Footer: &DiscordEmbedFooter{Text: "AllSafe ASM Worker • " + d.locale.FormatTime(now)},
+	}
+	if drift.Latest != "" {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Latest", Value: drift.Latest, Inline: true})
+	}
+	if drift.Message != "" {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Reason", Value: drift.Message, Inline: false})
+	}
+	return d.sendWebhook(ctx, DiscordWebhookPayload{Embeds: []DiscordEmbed{embed}})
+}
+
+// createDigestPayload creates the Discord webhook payload of a scan digest
+func (d *DiscordNotifier) createDigestPayload(taskMsg *models.TaskMessage, digest *models.ScanDigest) DiscordWebhookPayload {
+	now := d.locale.In(time.Now())
+	embed := DiscordEmbed{
+		Title:       "📊 Scan Digest",
+		Description: fmt.Sprintf("Scan of %s finished", digest.Domain),
+		Color:       ColorSuccess,
+		Timestamp:   now.Format(time.RFC3339),
+		Fields: []DiscordEmbedField{
+			{Name: "Domain", Value: digest.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", digest.ScanID), Inline: true},
+			{Name: "Elapsed", Value: d.locale.FormatDuration(digest.Duration.Round(time.Second).String()), Inline: true},
+			{Name: "Subdomains", Value: fmt.Sprintf("%d", digest.Subdomains), Inline: true},
+			{Name: "Resolved", Value: fmt.Sprintf("%d", digest.Resolved), Inline: true},
+			{Name: "Open Ports", Value: fmt.Sprintf("%d", digest.OpenPorts), Inline: true},
+			{Name: "HTTP Services", Value: fmt.Sprintf("%d", digest.HTTPServices), Inline: true},
+			{Name: "Vulnerabilities", Value: digest.VulnerabilitySummary(), Inline: false},
+		},
+	}
+
+	switch {
+	case digest.Vulnerabilities["critical"] > 0 || digest.Vulnerabilities["high"] > 0:
+		embed.Color = ColorError
+	case digest.Partial || len(digest.Missing) > 0:
+		embed.Color = ColorWarning
+	}
+
+	if len(digest.Missing) > 0 {
+		missing := make([]string, len(digest.Missing))
+		for i, task := range digest.Missing {
+			missing[i] = string(task)
+		}
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "Stages Without Results", Value: strings.Join(missing, ", "), Inline: false})
+	}
+	if digest.Partial {
+		embed.Description += "; some stages were cut short and their results are partial"
+	}
+
+	embed.Footer = &DiscordEmbedFooter{Text: "AllSafe ASM Worker"}
+	if taskMsg.CorrelationID != "" {
+		embed.Footer.Text += " • " + taskMsg.CorrelationID
+	}
+	embed.Footer.Text += " • " + d.locale.FormatTime(now)
+
+	return DiscordWebhookPayload{Embeds: []DiscordEmbed{embed}}
+}
+
 // createPayload creates a Discord webhook payload based on the step and data
 func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.TaskMessage, result *models.TaskResult, err error) DiscordWebhookPayload {
+	// Discord shows the embed timestamp in each reader's own timezone; the footer carries the
+	// time in the team's timezone
+	now := d.locale.In(time.Now())
 	embed := DiscordEmbed{
-		Timestamp: time.Now().Format(time.RFC3339),
+		Timestamp: now.Format(time.RFC3339),
 	}
 
 	switch step {
@@ -196,7 +288,7 @@ func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.T
 		// Add duration if available
 		if result != nil && result.Duration != "" {
 			embed.Fields = append(embed.Fields, DiscordEmbedField{
-				Name: "Duration", Value: formatDuration(result.Duration), Inline: true,
+				Name: "Duration", Value: d.locale.FormatDuration(result.Duration), Inline: true,
 			})
 		}
 
@@ -221,7 +313,7 @@ func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.T
 		// Add duration if available
 		if result != nil && result.Duration != "" {
 			embed.Fields = append(embed.Fields, DiscordEmbedField{
-				Name: "Duration", Value: formatDuration(result.Duration), Inline: true,
+				Name: "Duration", Value: d.locale.FormatDuration(result.Duration), Inline: true,
 			})
 		}
 
@@ -244,7 +336,91 @@ func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.T
 		// Add duration if available
 		if result != nil && result.Duration != "" {
 			embed.Fields = append(embed.Fields, DiscordEmbedField{
-				Name: "Duration", Value: formatDuration(result.Duration), Inline: true,
+				Name: "Duration", Value: d.locale.FormatDuration(result.Duration), Inline: true,
+			})
+		}
+
+	case StepTaskSkipped:
+		embed.Title = "🧊 Task Skipped"
+		embed.Description = "Domain is frozen, task was not scanned"
+		embed.Color = ColorWarning
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+		if result != nil && result.Error != "" {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{
+				Name: "Reason", Value: result.Error, Inline: false,
+			})
+		}
+
+	case StepTaskPaused:
+		embed.Title = "⏸️ Task Paused"
+		embed.Description = "Scan is paused, task waits on the queue until it is resumed"
+		embed.Color = ColorWarning
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+	case StepTaskProgress:
+		embed.Title = "⏳ Task Progress"
+		embed.Description = "Scanner is still running"
+		embed.Color = ColorPurple
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+	case StepScanHalted:
+		embed.Title = "🛑 Scan Halted"
+		embed.Description = "Scan used up its retry budget and was paused; it needs manual intervention"
+		embed.Color = ColorError
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+		if err != nil {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{
+				Name: "Reason", Value: err.Error(), Inline: false,
+			})
+		}
+
+	case StepScanDegraded:
+		embed.Title = "📉 Scan Degraded"
+		embed.Description = "A stage's result failed a quality gate; held stages wait for a review"
+		embed.Color = ColorWarning
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+		if err != nil {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{
+				Name: "Reason", Value: err.Error(), Inline: false,
+			})
+		}
+
+	case StepTargetsBlocked:
+		embed.Title = "🧱 Targets Blocked"
+		embed.Description = "Responses of a host group shifted to a WAF or rate-limit ban mid-scan"
+		embed.Color = ColorWarning
+		embed.Fields = []DiscordEmbedField{
+			{Name: "Task", Value: string(taskMsg.Task), Inline: true},
+			{Name: "Domain", Value: taskMsg.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", taskMsg.ScanID), Inline: true},
+		}
+
+		if err != nil {
+			embed.Fields = append(embed.Fields, DiscordEmbedField{
+				Name: "Reason", Value: err.Error(), Inline: false,
 			})
 		}
 
@@ -261,7 +437,7 @@ func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.T
 		// Add duration if available
 		if result != nil && result.Duration != "" {
 			embed.Fields = append(embed.Fields, DiscordEmbedField{
-				Name: "Duration", Value: formatDuration(result.Duration), Inline: true,
+				Name: "Duration", Value: d.locale.FormatDuration(result.Duration), Inline: true,
 			})
 		}
 	}
@@ -269,6 +445,10 @@ func (d *DiscordNotifier) createPayload(step NotificationStep, taskMsg *models.T
 	embed.Footer = &DiscordEmbedFooter{
 		Text: "AllSafe ASM Worker",
 	}
+	if taskMsg.CorrelationID != "" {
+		embed.Footer.Text += " • " + taskMsg.CorrelationID
+	}
+	embed.Footer.Text += " • " + d.locale.FormatTime(now)
 
 	return DiscordWebhookPayload{
 		Embeds: []DiscordEmbed{embed},
@@ -117,3 +117,26 @@ func TestDiscordNotifier_NotifyMethods(t *testing.T) {
 		})
 	}
 }
+
+func TestDiscordDigestPayload(t *testing.T) {
+	notifier := &DiscordNotifier{enabled: true}
+	digest := models.NewScanDigest("", "example.com", 7)
+	digest.Subdomains, digest.OpenPorts = 12, 3
+	digest.Vulnerabilities["high"] = 2
+	digest.Duration = 90 * time.Minute
+	digest.Missing = []models.Task{models.TaskHttpx}
+
+	embed := notifier.createDigestPayload(&models.TaskMessage{ScanID: 7, Domain: "example.com"}, digest).Embeds[0]
+	if embed.Color != ColorError {
+		t.Errorf("expected high findings to color the digest red, got %x", embed.Color)
+	}
+
+	fields := make(map[string]string)
+	for _, field := range embed.Fields {
+		fields[field.Name] = field.Value
+	}
+	if fields["Subdomains"] != "12" || fields["Open Ports"] != "3" || fields["Vulnerabilities"] != "high: 2" ||
+		fields["Elapsed"] != "1h 30m" || fields["Stages Without Results"] != "httpx" {
+		t.Errorf("unexpected digest fields: %v", fields)
+	}
+}
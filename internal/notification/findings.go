@@ -0,0 +1,316 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	findingQueueSize     = 1000  // Alerts waiting for delivery before new ones are dropped
+	findingDedupeLimit   = 10000 // Remembered findings before the dedupe set is reset
+	findingSendTimeout   = 10 * time.Second
+	defaultAlertSeverity = "high"
+)
+
+// severityRanks orders nuclei severities from least to most severe
+var severityRanks = map[string]int{
+	"info":     1,
+	"low":      2,
+	"medium":   3,
+	"high":     4,
+	"critical": 5,
+}
+
+// SeverityRank returns the rank of a severity, or 0 when it is unknown
+func SeverityRank(severity string) int {
+	return severityRanks[strings.ToLower(severity)]
+}
+
+// FindingAlert is a finding pushed to alert channels while its scan is still running
+type FindingAlert struct {
+	TenantID   string                     `json:"tenant_id,omitempty"`
+	ScanID     int                        `json:"scan_id"`
+	Task       string                     `json:"task"`
+	Domain     string                     `json:"domain"`
+	Finding    models.NucleiVulnerability `json:"finding"`
+	DetectedAt string                     `json:"detected_at"`
+}
+
+// key identifies the finding of an alert within its scan
+func (a FindingAlert) key() string {
+	return fmt.Sprintf("%s|%d|%s|%s|%s", a.TenantID, a.ScanID, a.Finding.TemplateID, a.Finding.Host, a.Finding.MatchedAt)
+}
+
+// FindingChannel delivers finding alerts to one destination
+type FindingChannel interface {
+	Name() string
+	SendFinding(ctx context.Context, alert FindingAlert) error
+}
+
+// FindingRouter pushes findings at or above a minimum severity to dedicated alert channels,
+// separately from the step notifications. Alerts are delivered in the background so that
+// routing never slows down the scan that reports them.
+type FindingRouter struct {
+	channels    []FindingChannel
+	minSeverity int
+
+	queue     chan FindingAlert
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+// NewFindingRouter creates a router delivering findings of at least minSeverity to the given channels
+func NewFindingRouter(minSeverity string, channels ...FindingChannel) *FindingRouter {
+	rank := SeverityRank(minSeverity)
+	if rank == 0 {
+		rank = SeverityRank(defaultAlertSeverity)
+	}
+
+	router := &FindingRouter{
+		channels:    channels,
+		minSeverity: rank,
+		queue:       make(chan FindingAlert, findingQueueSize),
+		done:        make(chan struct{}),
+		seen:        make(map[string]struct{}),
+	}
+	go router.deliver()
+	return router
+}
+
+// NewConfiguredFindingRouter creates a finding router from the FINDING_ALERT_* webhook URLs,
+// returning nil when no channel is configured
+func NewConfiguredFindingRouter(minSeverity string, timeout time.Duration) *FindingRouter {
+	httpClient := utils.NewTracingClient(timeout)
+
+	var channels []FindingChannel
+	if url := os.Getenv("FINDING_ALERT_DISCORD_WEBHOOK_URL"); url != "" {
+		channels = append(channels, &DiscordNotifier{webhookURL: url, httpClient: httpClient, enabled: true})
+	}
+	if url := os.Getenv("FINDING_ALERT_SLACK_WEBHOOK_URL"); url != "" {
+		channels = append(channels, &SlackFindingChannel{webhookURL: url, httpClient: httpClient})
+	}
+	if url := os.Getenv("FINDING_ALERT_WEBHOOK_URL"); url != "" {
+		channels = append(channels, &WebhookFindingChannel{url: url, httpClient: httpClient})
+	}
+
+	if len(channels) == 0 {
+		return nil
+	}
+	return NewFindingRouter(minSeverity, channels...)
+}
+
+// Route queues a finding for delivery if it is severe enough and was not routed before
+func (r *FindingRouter) Route(taskMsg *models.TaskMessage, finding models.NucleiVulnerability) {
+	if r == nil || SeverityRank(finding.Severity) < r.minSeverity {
+		return
+	}
+
+	alert := FindingAlert{
+		TenantID:   taskMsg.TenantID,
+		ScanID:     taskMsg.ScanID,
+		Task:       string(taskMsg.Task),
+		Domain:     taskMsg.Domain,
+		Finding:    finding,
+		DetectedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Resumed scans repeat the templates that were running at the checkpoint
+	r.mu.Lock()
+	if _, ok := r.seen[alert.key()]; ok {
+		r.mu.Unlock()
+		return
+	}
+	if len(r.seen) >= findingDedupeLimit {
+		r.seen = make(map[string]struct{})
+	}
+	r.seen[alert.key()] = struct{}{}
+	r.mu.Unlock()
+
+	select {
+	case r.queue <- alert:
+	default:
+		gologger.Warning().Msgf("Finding alert queue is full, dropping %s alert for %s", finding.Severity, finding.MatchedAt)
+	}
+}
+
+// Close stops accepting alerts and waits until the queued ones are delivered or ctx expires
+func (r *FindingRouter) Close(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.closeOnce.Do(func() { close(r.queue) })
+
+	select {
+	case <-r.done:
+	case <-ctx.Done():
+		gologger.Warning().Msg("Gave up waiting for queued finding alerts")
+	}
+}
+
+// deliver sends queued alerts to every channel
+func (r *FindingRouter) deliver() {
+	defer close(r.done)
+
+	for alert := range r.queue {
+		for _, channel := range r.channels {
+			ctx, cancel := context.WithTimeout(context.Background(), findingSendTimeout)
+			if err := channel.SendFinding(ctx, alert); err != nil {
+				gologger.Warning().Msgf("Failed to send finding alert to %s: %v", channel.Name(), err)
+			}
+			cancel()
+		}
+		gologger.Info().Msgf("Routed %s finding %s on %s", alert.Finding.Severity, alert.Finding.TemplateID, alert.Finding.MatchedAt)
+	}
+}
+
+// severityColor returns the Discord embed color of a severity
+func severityColor(severity string) int {
+	switch SeverityRank(severity) {
+	case 5:
+		return ColorError
+	case 4:
+		return ColorWarning
+	default:
+		return ColorInfo
+	}
+}
+
+// Name returns the channel name of the Discord notifier
+func (d *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// SendFinding sends a finding alert as a Discord embed
+func (d *DiscordNotifier) SendFinding(ctx context.Context, alert FindingAlert) error {
+	finding := alert.Finding
+	embed := DiscordEmbed{
+		Title:       fmt.Sprintf("🚨 %s finding: %s", strings.ToUpper(finding.Severity), findingTitle(finding)),
+		Description: finding.Description,
+		Color:       severityColor(finding.Severity),
+		Timestamp:   alert.DetectedAt,
+		Fields: []DiscordEmbedField{
+			{Name: "Matched At", Value: finding.MatchedAt, Inline: false},
+			{Name: "Template", Value: finding.TemplateID, Inline: true},
+			{Name: "Domain", Value: alert.Domain, Inline: true},
+			{Name: "Scan ID", Value: fmt.Sprintf("%d", alert.ScanID), Inline: true},
+		},
+		Footer: &DiscordEmbedFooter{Text: "AllSafe ASM Worker"},
+	}
+	if len(finding.CVEIDs) > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "CVE", Value: strings.Join(finding.CVEIDs, ", "), Inline: true})
+	}
+	if finding.CVSSScore > 0 {
+		embed.Fields = append(embed.Fields, DiscordEmbedField{Name: "CVSS", Value: fmt.Sprintf("%.1f", finding.CVSSScore), Inline: true})
+	}
+
+	return d.sendWebhook(ctx, DiscordWebhookPayload{Embeds: []DiscordEmbed{embed}})
+}
+
+// SlackFindingChannel sends finding alerts to a Slack incoming webhook
+type SlackFindingChannel struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// Name returns the channel name
+func (s *SlackFindingChannel) Name() string {
+	return "slack"
+}
+
+// SendFinding sends a finding alert as a Slack message
+func (s *SlackFindingChannel) SendFinding(ctx context.Context, alert FindingAlert) error {
+	finding := alert.Finding
+	text := fmt.Sprintf(":rotating_light: *%s* finding *%s* on `%s`\nTemplate: `%s` | Domain: %s | Scan: %d",
+		strings.ToUpper(finding.Severity), findingTitle(finding), finding.MatchedAt, finding.TemplateID, alert.Domain, alert.ScanID)
+	if len(finding.CVEIDs) > 0 {
+		text += " | CVE: " + strings.Join(finding.CVEIDs, ", ")
+	}
+
+	return postJSON(ctx, s.httpClient, s.webhookURL, map[string]string{"text": text})
+}
+
+// WebhookFindingChannel posts finding alerts as JSON to a generic webhook
+type WebhookFindingChannel struct {
+	url        string
+	httpClient *http.Client
+}
+
+// Name returns the channel name
+func (w *WebhookFindingChannel) Name() string {
+	return "webhook"
+}
+
+// SendFinding posts the alert as JSON
+func (w *WebhookFindingChannel) SendFinding(ctx context.Context, alert FindingAlert) error {
+	return postJSON(ctx, w.httpClient, w.url, alert)
+}
+
+// findingTitle returns the template name of a finding, falling back to its template ID
+func findingTitle(finding models.NucleiVulnerability) string {
+	if finding.Name != "" {
+		return finding.Name
+	}
+	return finding.TemplateID
+}
+
+// postJSON posts a JSON payload and fails on non-2xx responses
+func postJSON(ctx context.Context, httpClient *http.Client, url string, payload interface{}) error {
+	return postJSONWithHeaders(ctx, httpClient, url, nil, payload)
+}
+
+// postJSONWithHeaders posts a JSON payload with extra request headers and fails on non-2xx responses
+func postJSONWithHeaders(ctx context.Context, httpClient *http.Client, url string, headers map[string]string, payload interface{}) error {
+	return doJSON(ctx, httpClient, "POST", url, headers, payload, nil)
+}
+
+// doJSON sends a JSON request, decoding the response into out when it is not nil, and fails on non-2xx responses
+func doJSON(ctx context.Context, httpClient *http.Client, method, url string, headers map[string]string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// recordingChannel collects the alerts it receives
+type recordingChannel struct {
+	mu     sync.Mutex
+	alerts []FindingAlert
+}
+
+func (c *recordingChannel) Name() string { return "recording" }
+
+func (c *recordingChannel) SendFinding(ctx context.Context, alert FindingAlert) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.alerts = append(c.alerts, alert)
+	return nil
+}
+
+func TestFindingRouterFiltersAndDedupes(t *testing.T) {
+	channel := &recordingChannel{}
+	router := NewFindingRouter("high", channel)
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, ScanID: 7, Domain: "example.com", TenantID: "tenant-a"}
+
+	critical := models.NucleiVulnerability{TemplateID: "CVE-2021-44228", MatchedAt: "https://example.com/", Severity: "critical"}
+	router.Route(taskMsg, critical)
+	router.Route(taskMsg, critical)
+	router.Route(taskMsg, models.NucleiVulnerability{TemplateID: "tech-detect", MatchedAt: "https://example.com/", Severity: "info"})
+	router.Route(taskMsg, models.NucleiVulnerability{TemplateID: "exposed-panel", MatchedAt: "https://example.com/admin", Severity: "HIGH"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	router.Close(ctx)
+
+	if len(channel.alerts) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d: %+v", len(channel.alerts), channel.alerts)
+	}
+	if channel.alerts[0].Finding.TemplateID != "CVE-2021-44228" || channel.alerts[0].TenantID != "tenant-a" || channel.alerts[0].ScanID != 7 {
+		t.Errorf("Unexpected first alert: %+v", channel.alerts[0])
+	}
+	if channel.alerts[1].Finding.TemplateID != "exposed-panel" {
+		t.Errorf("Unexpected second alert: %+v", channel.alerts[1])
+	}
+}
+
+func TestWebhookFindingChannel(t *testing.T) {
+	var received FindingAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	channel := &WebhookFindingChannel{url: server.URL, httpClient: server.Client()}
+	alert := FindingAlert{ScanID: 1, Domain: "example.com", Finding: models.NucleiVulnerability{TemplateID: "CVE-2021-44228", Severity: "critical"}}
+	if err := channel.SendFinding(context.Background(), alert); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Finding.TemplateID != "CVE-2021-44228" || received.Domain != "example.com" {
+		t.Errorf("Unexpected alert received: %+v", received)
+	}
+}
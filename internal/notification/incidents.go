@@ -0,0 +1,397 @@
+package notification
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	defaultPagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	defaultOpsgenieAPIURL     = "https://api.opsgenie.com"
+	incidentSource            = "AllSafe ASM Worker"
+)
+
+// databasePorts lists ports whose exposure on a new asset opens an incident
+var databasePorts = map[int]string{
+	1433:  "mssql",
+	1521:  "oracle",
+	3306:  "mysql",
+	5432:  "postgresql",
+	5984:  "couchdb",
+	6379:  "redis",
+	9042:  "cassandra",
+	9200:  "elasticsearch",
+	11211: "memcached",
+	27017: "mongodb",
+}
+
+// Incident is a critical finding raised with incident providers
+type Incident struct {
+	DedupKey  string            // Stable fingerprint of the finding
+	Summary   string            // One line description
+	Severity  string            // critical, error, warning or info
+	Source    string            // Affected asset
+	Component string            // Scanner that found it
+	Details   map[string]string // Extra context shown in the incident
+
+	asset   string // Asset the finding is on
+	newOnly bool   // Only raise the incident when the asset was not seen before
+}
+
+// IncidentProvider opens and resolves incidents in an on-call system
+type IncidentProvider interface {
+	Name() string
+	Trigger(ctx context.Context, incident Incident) error
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// IncidentStateStore persists the incidents opened for each domain. Updates are applied to the
+// latest stored state, so tasks of the same domain finishing at once do not lose each other's changes.
+type IncidentStateStore interface {
+	LoadIncidentState(ctx context.Context, tenantID, domain string) (*models.IncidentState, error)
+	UpdateIncidentState(ctx context.Context, tenantID, domain string, update func(*models.IncidentState) error) error
+}
+
+// IncidentNotifier opens incidents for critical findings and resolves them once a later scan
+// no longer reports them. Findings are deduplicated by fingerprint, so an incident is opened once.
+type IncidentNotifier struct {
+	providers []IncidentProvider
+	store     IncidentStateStore
+	now       func() time.Time
+}
+
+// NewIncidentNotifier creates an incident notifier for the given providers
+func NewIncidentNotifier(store IncidentStateStore, providers ...IncidentProvider) *IncidentNotifier {
+	return &IncidentNotifier{providers: providers, store: store, now: time.Now}
+}
+
+// NewConfiguredIncidentNotifier creates an incident notifier from the PagerDuty and Opsgenie
+// environment variables, returning nil when neither is configured
+func NewConfiguredIncidentNotifier(store IncidentStateStore, timeout time.Duration) *IncidentNotifier {
+	httpClient := utils.NewTracingClient(timeout)
+
+	var providers []IncidentProvider
+	if routingKey := os.Getenv("PAGERDUTY_ROUTING_KEY"); routingKey != "" {
+		providers = append(providers, &PagerDutyProvider{
+			routingKey: routingKey,
+			eventsURL:  defaultPagerDutyEventsURL,
+			httpClient: httpClient,
+		})
+	}
+	if apiKey := os.Getenv("OPSGENIE_API_KEY"); apiKey != "" {
+		apiURL := os.Getenv("OPSGENIE_API_URL")
+		if apiURL == "" {
+			apiURL = defaultOpsgenieAPIURL
+		}
+		providers = append(providers, &OpsgenieProvider{
+			apiKey:     apiKey,
+			apiURL:     strings.TrimSuffix(apiURL, "/"),
+			httpClient: httpClient,
+		})
+	}
+
+	if len(providers) == 0 || store == nil {
+		return nil
+	}
+	return NewIncidentNotifier(store, providers...)
+}
+
+// Process opens incidents for new critical findings of a stored task result and resolves the
+// open incidents of the same scope that the result no longer reports. Results that do not cover
+// their whole scope, partial and verify results, never resolve incidents because the scan may
+// simply not have reached the finding. Neither do runs that excluded the finding's template, nor
+// findings the replay did not confirm, which are neither opened nor resolved.
+func (n *IncidentNotifier) Process(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) error {
+	if n == nil {
+		return nil
+	}
+
+	scannerResult, ok := result.Data.(models.ScannerResult)
+	if !ok {
+		return nil
+	}
+	scope, present, assets := criticalIncidents(taskMsg, scannerResult)
+	if scope == "" {
+		return nil
+	}
+	var excluded []string
+	unconfirmed := make(map[string]bool)
+	if nucleiResult, ok := scannerResult.(models.NucleiResult); ok {
+		excluded = nucleiResult.ExcludedTemplates()
+		for _, vuln := range nucleiResult.Unconfirmed {
+			unconfirmed[fingerprint(taskMsg.TenantID, taskMsg.Domain, "nuclei", vuln.TemplateID, vuln.MatchedAt)] = true
+		}
+	}
+
+	state, err := n.store.LoadIncidentState(ctx, taskMsg.TenantID, taskMsg.Domain)
+	if err != nil {
+		return fmt.Errorf("failed to load incident state: %w", err)
+	}
+	known := make(map[string]bool, len(state.KnownAssets))
+	for _, asset := range state.KnownAssets {
+		known[asset] = true
+	}
+
+	// The providers are called on the state as it was loaded, and only the incidents this result
+	// opened or resolved are applied to the latest state, as another task may have changed it since
+	opened := make(map[string]models.OpenIncident)
+	for key, incident := range present {
+		if _, open := state.Open[key]; open || (incident.newOnly && known[incident.asset]) {
+			continue
+		}
+		if n.send(ctx, "open", key, func(p IncidentProvider) error { return p.Trigger(ctx, incident) }) {
+			opened[key] = models.OpenIncident{Scope: scope, Summary: incident.Summary, OpenedAt: n.now().UTC().Format(time.RFC3339), TemplateID: incident.Details["template_id"]}
+		}
+	}
+
+	var resolved []string
+	if result.CoversScope() {
+		for key, open := range state.Open {
+			if _, stillPresent := present[key]; open.Scope != scope || stillPresent || unconfirmed[key] || slices.Contains(excluded, open.TemplateID) {
+				continue
+			}
+			if n.send(ctx, "resolve", key, func(p IncidentProvider) error { return p.Resolve(ctx, key) }) {
+				resolved = append(resolved, key)
+			}
+		}
+	}
+
+	err = n.store.UpdateIncidentState(ctx, taskMsg.TenantID, taskMsg.Domain, func(state *models.IncidentState) error {
+		for key, incident := range opened {
+			if _, open := state.Open[key]; !open {
+				state.Open[key] = incident
+			}
+		}
+		for _, key := range resolved {
+			delete(state.Open, key)
+		}
+		state.KnownAssets = mergeAssets(state.KnownAssets, assets)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store incident state: %w", err)
+	}
+	return nil
+}
+
+// mergeAssets returns the sorted union of the known assets and the assets a result saw
+func mergeAssets(known, assets []string) []string {
+	merged := make(map[string]bool, len(known)+len(assets))
+	for _, asset := range known {
+		merged[asset] = true
+	}
+	for _, asset := range assets {
+		merged[asset] = true
+	}
+	union := make([]string, 0, len(merged))
+	for asset := range merged {
+		union = append(union, asset)
+	}
+	sort.Strings(union)
+	return union
+}
+
+// send runs an action against every provider and reports whether at least one succeeded
+func (n *IncidentNotifier) send(ctx context.Context, action, key string, call func(IncidentProvider) error) bool {
+	succeeded := false
+	for _, provider := range n.providers {
+		if err := call(provider); err != nil {
+			gologger.Warning().Msgf("Failed to %s %s incident %s: %v", action, provider.Name(), key, err)
+			continue
+		}
+		succeeded = true
+	}
+	if succeeded {
+		gologger.Info().Msgf("Incident %s: %s", action, key)
+	}
+	return succeeded
+}
+
+// criticalIncidents returns the scope a result covers, the critical findings it reports keyed
+// by fingerprint, and the assets it saw
+func criticalIncidents(taskMsg *models.TaskMessage, result models.ScannerResult) (string, map[string]Incident, []string) {
+	present := make(map[string]Incident)
+
+	switch r := result.(type) {
+	case models.NucleiResult:
+		scope := "nuclei:" + taskMsg.Type
+		for _, vuln := range r.Vulnerabilities {
+			var summary string
+			switch {
+			case containsTag(vuln.Tags, "takeover"):
+				summary = fmt.Sprintf("Subdomain takeover confirmed on %s", vuln.Host)
+			case SeverityRank(vuln.Severity) == SeverityRank("critical"):
+				summary = fmt.Sprintf("Critical vulnerability %s on %s", findingTitle(vuln), vuln.MatchedAt)
+			default:
+				continue
+			}
+
+			key := fingerprint(taskMsg.TenantID, taskMsg.Domain, "nuclei", vuln.TemplateID, vuln.MatchedAt)
+			details := map[string]string{"template_id": vuln.TemplateID, "matched_at": vuln.MatchedAt, "severity": vuln.Severity}
+			if len(vuln.CVEIDs) > 0 {
+				details["cve"] = strings.Join(vuln.CVEIDs, ", ")
+			}
+			present[key] = Incident{
+				DedupKey:  key,
+				Summary:   summary,
+				Severity:  "critical",
+				Source:    vuln.Host,
+				Component: "nuclei",
+				Details:   details,
+				asset:     vuln.Host,
+			}
+		}
+		return scope, present, nil
+
+	case models.NaabuResult:
+		assets := make([]string, 0, len(r.Ports))
+		for ip, ports := range r.Ports {
+			assets = append(assets, ip)
+			for _, port := range ports {
+				service, ok := databasePorts[port.Port]
+				if !ok {
+					continue
+				}
+				key := fingerprint(taskMsg.TenantID, taskMsg.Domain, "port", ip, fmt.Sprintf("%d", port.Port))
+				present[key] = Incident{
+					DedupKey:  key,
+					Summary:   fmt.Sprintf("Exposed %s port %d on new asset %s", service, port.Port, ip),
+					Severity:  "critical",
+					Source:    ip,
+					Component: "naabu",
+					Details:   map[string]string{"ip": ip, "port": fmt.Sprintf("%d", port.Port), "service": service},
+					asset:     ip,
+					newOnly:   true,
+				}
+			}
+		}
+		return string(taskMsg.Task), present, assets
+	}
+
+	return "", nil, nil
+}
+
+// fingerprint derives a stable dedup key for a finding
+func fingerprint(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	return "asm-" + hex.EncodeToString(sum[:16])
+}
+
+// containsTag reports whether a tag list contains the tag, ignoring case
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// PagerDutyProvider raises incidents through the PagerDuty Events API v2
+type PagerDutyProvider struct {
+	routingKey string
+	eventsURL  string
+	httpClient *http.Client
+}
+
+// Name returns the provider name
+func (p *PagerDutyProvider) Name() string {
+	return "pagerduty"
+}
+
+// Trigger opens or updates the PagerDuty incident with the incident's dedup key
+func (p *PagerDutyProvider) Trigger(ctx context.Context, incident Incident) error {
+	return postJSON(ctx, p.httpClient, p.eventsURL, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "trigger",
+		"dedup_key":    incident.DedupKey,
+		"payload": map[string]interface{}{
+			"summary":        incident.Summary,
+			"source":         incident.Source,
+			"severity":       incident.Severity,
+			"component":      incident.Component,
+			"custom_details": incident.Details,
+		},
+	})
+}
+
+// Resolve resolves the PagerDuty incident with the given dedup key
+func (p *PagerDutyProvider) Resolve(ctx context.Context, dedupKey string) error {
+	return postJSON(ctx, p.httpClient, p.eventsURL, map[string]interface{}{
+		"routing_key":  p.routingKey,
+		"event_action": "resolve",
+		"dedup_key":    dedupKey,
+	})
+}
+
+// OpsgenieProvider raises incidents as Opsgenie alerts, using the dedup key as the alert alias
+type OpsgenieProvider struct {
+	apiKey     string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// Name returns the provider name
+func (o *OpsgenieProvider) Name() string {
+	return "opsgenie"
+}
+
+// Trigger creates an Opsgenie alert; Opsgenie deduplicates open alerts with the same alias
+func (o *OpsgenieProvider) Trigger(ctx context.Context, incident Incident) error {
+	message := incident.Summary
+	if len(message) > 130 {
+		message = message[:130]
+	}
+
+	return postJSONWithHeaders(ctx, o.httpClient, o.apiURL+"/v2/alerts", o.headers(), map[string]interface{}{
+		"message":     message,
+		"alias":       incident.DedupKey,
+		"description": incident.Summary,
+		"priority":    opsgeniePriority(incident.Severity),
+		"entity":      incident.Source,
+		"source":      incidentSource,
+		"tags":        []string{"asm", incident.Component},
+		"details":     incident.Details,
+	})
+}
+
+// Resolve closes the Opsgenie alert with the given alias
+func (o *OpsgenieProvider) Resolve(ctx context.Context, dedupKey string) error {
+	closeURL := fmt.Sprintf("%s/v2/alerts/%s/close?identifierType=alias", o.apiURL, url.PathEscape(dedupKey))
+	return postJSONWithHeaders(ctx, o.httpClient, closeURL, o.headers(), map[string]string{
+		"source": incidentSource,
+		"note":   "Finding no longer detected",
+	})
+}
+
+// headers returns the authorization headers of the Opsgenie API
+func (o *OpsgenieProvider) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.apiKey}
+}
+
+// opsgeniePriority maps an incident severity to an Opsgenie priority
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case "critical":
+		return "P1"
+	case "error":
+		return "P2"
+	case "warning":
+		return "P3"
+	default:
+		return "P5"
+	}
+}
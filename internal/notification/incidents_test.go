@@ -0,0 +1,212 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// memoryIncidentStore keeps incident state in memory
+type memoryIncidentStore struct {
+	state *models.IncidentState
+	// concurrent, when set, changes the state between a load and the next update, like another worker
+	concurrent func(*models.IncidentState)
+}
+
+func (s *memoryIncidentStore) LoadIncidentState(ctx context.Context, tenantID, domain string) (*models.IncidentState, error) {
+	if s.state == nil {
+		return &models.IncidentState{TenantID: tenantID, Domain: domain, Open: map[string]models.OpenIncident{}}, nil
+	}
+	return s.state, nil
+}
+
+func (s *memoryIncidentStore) UpdateIncidentState(ctx context.Context, tenantID, domain string, update func(*models.IncidentState) error) error {
+	state, _ := s.LoadIncidentState(ctx, tenantID, domain)
+	s.state = state
+	if s.concurrent != nil {
+		s.concurrent(state)
+		s.concurrent = nil
+	}
+	return update(state)
+}
+
+// recordingProvider records triggered and resolved incidents
+type recordingProvider struct {
+	triggered []Incident
+	resolved  []string
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Trigger(ctx context.Context, incident Incident) error {
+	p.triggered = append(p.triggered, incident)
+	return nil
+}
+
+func (p *recordingProvider) Resolve(ctx context.Context, dedupKey string) error {
+	p.resolved = append(p.resolved, dedupKey)
+	return nil
+}
+
+func TestIncidentNotifierNucleiLifecycle(t *testing.T) {
+	store := &memoryIncidentStore{}
+	provider := &recordingProvider{}
+	notifier := NewIncidentNotifier(store, provider)
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, Domain: "example.com", TenantID: "tenant-a", Type: "http"}
+
+	critical := models.NucleiVulnerability{TemplateID: "CVE-2021-44228", Host: "https://example.com", MatchedAt: "https://example.com/", Severity: "critical"}
+	takeover := models.NucleiVulnerability{TemplateID: "github-takeover", Host: "docs.example.com", MatchedAt: "docs.example.com", Severity: "high", Tags: []string{"takeover", "github"}}
+	medium := models.NucleiVulnerability{TemplateID: "cors-misconfig", MatchedAt: "https://example.com/api", Severity: "medium"}
+
+	run := func(status models.TaskStatus, vulns ...models.NucleiVulnerability) {
+		result := &models.TaskResult{Status: status, Data: models.NucleiResult{Domain: "example.com", Vulnerabilities: vulns}}
+		if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	run(models.TaskStatusCompleted, critical, takeover, medium)
+	if len(provider.triggered) != 2 || len(store.state.Open) != 2 {
+		t.Fatalf("Expected 2 incidents to be opened, got %d triggered and %d open", len(provider.triggered), len(store.state.Open))
+	}
+
+	// Findings that are still open are not raised again
+	run(models.TaskStatusCompleted, critical, takeover)
+	if len(provider.triggered) != 2 {
+		t.Errorf("Expected no new incidents, got %d triggered", len(provider.triggered))
+	}
+
+	// A partial scan never resolves incidents
+	run(models.TaskStatusPartial, critical)
+	if len(provider.resolved) != 0 {
+		t.Errorf("Expected a partial scan to resolve nothing, got %v", provider.resolved)
+	}
+
+	// Nor does a verify scan, which only covers the new targets
+	verify := &models.TaskResult{Status: models.TaskStatusCompleted, Mode: models.TaskModeVerify, Data: models.NucleiResult{Domain: "example.com"}}
+	if err := notifier.Process(context.Background(), taskMsg, verify); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(provider.resolved) != 0 {
+		t.Errorf("Expected a verify scan to resolve nothing, got %v", provider.resolved)
+	}
+
+	// Nor does a scan that excluded the templates of the findings
+	excluding := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{
+		Domain:     "example.com",
+		Compliance: &models.CompliancePolicy{ExcludedTemplates: []string{"CVE-2021-44228", "github-takeover"}},
+	}}
+	if err := notifier.Process(context.Background(), taskMsg, excluding); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(provider.resolved) != 0 {
+		t.Errorf("Expected a scan excluding the templates to resolve nothing, got %v", provider.resolved)
+	}
+
+	// Nor does a finding the replay did not confirm
+	unconfirmed := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{
+		Domain:          "example.com",
+		Vulnerabilities: []models.NucleiVulnerability{critical},
+		Unconfirmed:     []models.NucleiVulnerability{takeover},
+	}}
+	if err := notifier.Process(context.Background(), taskMsg, unconfirmed); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(provider.resolved) != 0 {
+		t.Errorf("Expected an unconfirmed finding to resolve nothing, got %v", provider.resolved)
+	}
+
+	// Another nuclei scope cannot resolve the incidents of this one
+	taskMsg.Type = "network"
+	run(models.TaskStatusCompleted)
+	taskMsg.Type = "http"
+	if len(provider.resolved) != 0 {
+		t.Errorf("Expected another scope to resolve nothing, got %v", provider.resolved)
+	}
+
+	run(models.TaskStatusCompleted, critical)
+	if len(provider.resolved) != 1 || len(store.state.Open) != 1 {
+		t.Errorf("Expected the takeover incident to be resolved, got %v resolved and %d open", provider.resolved, len(store.state.Open))
+	}
+}
+
+func TestIncidentNotifierKeepsConcurrentChanges(t *testing.T) {
+	store := &memoryIncidentStore{}
+	provider := &recordingProvider{}
+	notifier := NewIncidentNotifier(store, provider)
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, Domain: "example.com", TenantID: "tenant-a", Type: "http"}
+	critical := models.NucleiVulnerability{TemplateID: "CVE-2021-44228", Host: "https://example.com", MatchedAt: "https://example.com/", Severity: "critical"}
+
+	// A network scan of the same domain opens an incident while this result is processed
+	store.concurrent = func(state *models.IncidentState) {
+		state.Open["other"] = models.OpenIncident{Scope: "nuclei:network", Summary: "Critical vulnerability on 192.0.2.1"}
+		state.KnownAssets = append(state.KnownAssets, "192.0.2.1")
+	}
+	result := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{Domain: "example.com", Vulnerabilities: []models.NucleiVulnerability{critical}}}
+	if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(store.state.Open) != 2 || len(store.state.KnownAssets) != 1 {
+		t.Errorf("Expected both incidents and the known asset to be kept, got %v and %v", store.state.Open, store.state.KnownAssets)
+	}
+}
+
+func TestIncidentNotifierDatabasePortsOnNewAssets(t *testing.T) {
+	store := &memoryIncidentStore{state: &models.IncidentState{Domain: "example.com", Open: map[string]models.OpenIncident{}, KnownAssets: []string{"192.0.2.1"}}}
+	provider := &recordingProvider{}
+	notifier := NewIncidentNotifier(store, provider)
+	taskMsg := &models.TaskMessage{Task: models.TaskNaabu, Domain: "example.com"}
+
+	result := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NaabuResult{Ports: map[string][]models.PortInfo{
+		"192.0.2.1": {{Port: 3306, Protocol: "tcp"}},
+		"192.0.2.2": {{Port: 443, Protocol: "tcp"}, {Port: 6379, Protocol: "tcp"}},
+	}}}
+	if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if len(provider.triggered) != 1 || provider.triggered[0].Source != "192.0.2.2" {
+		t.Fatalf("Expected only the database port on the new asset to open an incident, got %+v", provider.triggered)
+	}
+	if len(store.state.KnownAssets) != 2 {
+		t.Errorf("Expected the new asset to be remembered, got %v", store.state.KnownAssets)
+	}
+
+	// The incident stays open while the port is exposed, even though the asset is now known
+	if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(provider.resolved) != 0 || len(store.state.Open) != 1 {
+		t.Errorf("Expected the incident to stay open, got %v resolved and %d open", provider.resolved, len(store.state.Open))
+	}
+}
+
+func TestPagerDutyProvider(t *testing.T) {
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	provider := &PagerDutyProvider{routingKey: "routing-key", eventsURL: server.URL, httpClient: server.Client()}
+	if err := provider.Trigger(context.Background(), Incident{DedupKey: "asm-1", Summary: "Critical", Severity: "critical", Source: "example.com"}); err != nil {
+		t.Fatalf("Trigger failed: %v", err)
+	}
+	if err := provider.Resolve(context.Background(), "asm-1"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if len(events) != 2 || events[0]["event_action"] != "trigger" || events[1]["event_action"] != "resolve" {
+		t.Fatalf("Unexpected events: %v", events)
+	}
+	if events[0]["dedup_key"] != "asm-1" || events[1]["dedup_key"] != "asm-1" || events[0]["routing_key"] != "routing-key" {
+		t.Errorf("Unexpected event keys: %v", events)
+	}
+}
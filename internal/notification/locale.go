@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+)
+
+// localeFormat holds the layouts and units notifications are rendered with in a language
+type localeFormat struct {
+	timeLayout       string
+	decimalSeparator string
+	millisecond      string
+	second           string
+	minute           string
+	hour             string
+}
+
+// localeFormats are the supported notification languages. Go does not translate month names,
+// so languages other than English use numeric dates.
+var localeFormats = map[string]localeFormat{
+	"en": {timeLayout: "Jan 2, 2006 3:04 PM MST", decimalSeparator: ".", millisecond: "ms", second: "s", minute: "m", hour: "h"},
+	"de": {timeLayout: "02.01.2006 15:04 MST", decimalSeparator: ",", millisecond: " ms", second: " s", minute: " Min.", hour: " Std."},
+	"fr": {timeLayout: "02/01/2006 15:04 MST", decimalSeparator: ",", millisecond: " ms", second: " s", minute: " min", hour: " h"},
+	"es": {timeLayout: "02/01/2006 15:04 MST", decimalSeparator: ",", millisecond: " ms", second: " s", minute: " min", hour: " h"},
+}
+
+// Locale is the language and timezone notification times and durations are rendered in. The
+// zero value renders English in UTC.
+type Locale struct {
+	Language string
+	Location *time.Location
+}
+
+// ParseLocale returns the locale of a language code such as "de" and an IANA timezone name such
+// as "Europe/Berlin". Empty values fall back to English and UTC.
+func ParseLocale(language, timezone string) (Locale, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		language = "en"
+	}
+	if _, ok := localeFormats[language]; !ok {
+		languages := maps.Keys(localeFormats)
+		sort.Strings(languages)
+		return Locale{}, fmt.Errorf("unsupported language %q, supported languages are: %s", language, strings.Join(languages, ", "))
+	}
+
+	location := time.UTC
+	if timezone = strings.TrimSpace(timezone); timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil {
+			return Locale{}, fmt.Errorf("unknown timezone %q: %w", timezone, err)
+		}
+	}
+
+	return Locale{Language: language, Location: location}, nil
+}
+
+// format returns the layouts of the locale's language
+func (l Locale) format() localeFormat {
+	if format, ok := localeFormats[l.Language]; ok {
+		return format
+	}
+	return localeFormats["en"]
+}
+
+// In returns t in the locale's timezone
+func (l Locale) In(t time.Time) time.Time {
+	if l.Location == nil {
+		return t.UTC()
+	}
+	return t.In(l.Location)
+}
+
+// FormatTime renders t for people reading notifications, in the locale's timezone and language
+func (l Locale) FormatTime(t time.Time) string {
+	return l.In(t).Format(l.format().timeLayout)
+}
+
+// FormatDuration formats a duration string to a more readable format with localized units
+func (l Locale) FormatDuration(durationStr string) string {
+	// Parse the duration string
+	duration, err := time.ParseDuration(durationStr)
+	if err != nil {
+		return durationStr // Return original if parsing fails
+	}
+
+	format := l.format()
+
+	// Format based on duration length
+	if duration < time.Second {
+		return fmt.Sprintf("%.0f%s", float64(duration.Microseconds())/1000, format.millisecond)
+	} else if duration < time.Minute {
+		seconds := strings.Replace(fmt.Sprintf("%.1f", duration.Seconds()), ".", format.decimalSeparator, 1)
+		return seconds + format.second
+	} else if duration < time.Hour {
+		minutes := int(duration.Minutes())
+		seconds := int(duration.Seconds()) % 60
+		return fmt.Sprintf("%d%s %d%s", minutes, format.minute, seconds, format.second)
+	} else {
+		hours := int(duration.Hours())
+		minutes := int(duration.Minutes()) % 60
+		return fmt.Sprintf("%d%s %d%s", hours, format.hour, minutes, format.minute)
+	}
+}
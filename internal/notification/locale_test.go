@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestParseLocale(t *testing.T) {
+	locale, err := ParseLocale("DE", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("ParseLocale failed: %v", err)
+	}
+	if locale.Language != "de" || locale.Location.String() != "Europe/Berlin" {
+		t.Errorf("unexpected locale: %+v", locale)
+	}
+
+	locale, err = ParseLocale("", "")
+	if err != nil || locale.Language != "en" || locale.Location != time.UTC {
+		t.Errorf("expected English in UTC by default, got %+v (%v)", locale, err)
+	}
+
+	if _, err := ParseLocale("xx", "UTC"); err == nil {
+		t.Error("expected an unsupported language to fail")
+	}
+	if _, err := ParseLocale("en", "Mars/Olympus"); err == nil {
+		t.Error("expected an unknown timezone to fail")
+	}
+}
+
+func TestLocaleFormatDuration(t *testing.T) {
+	tests := []struct {
+		language string
+		duration string
+		want     string
+	}{
+		{"en", "250ms", "250ms"},
+		{"en", "1.5s", "1.5s"},
+		{"en", "2m3s", "2m 3s"},
+		{"en", "1h2m", "1h 2m"},
+		{"de", "1.5s", "1,5 s"},
+		{"de", "2m3s", "2 Min. 3 s"},
+		{"de", "1h2m", "1 Std. 2 Min."},
+		{"fr", "1h2m", "1 h 2 min"},
+		{"en", "not a duration", "not a duration"},
+	}
+
+	for _, tt := range tests {
+		got := Locale{Language: tt.language}.FormatDuration(tt.duration)
+		if got != tt.want {
+			t.Errorf("%s FormatDuration(%q) = %q, want %q", tt.language, tt.duration, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormatTime(t *testing.T) {
+	at := time.Date(2025, 3, 14, 13, 5, 0, 0, time.UTC)
+
+	if got := (Locale{}).FormatTime(at); got != "Mar 14, 2025 1:05 PM UTC" {
+		t.Errorf("unexpected default time: %q", got)
+	}
+
+	locale, err := ParseLocale("de", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("ParseLocale failed: %v", err)
+	}
+	if got := locale.FormatTime(at); got != "14.03.2025 14:05 CET" {
+		t.Errorf("unexpected German time: %q", got)
+	}
+}
+
+func TestDiscordPayloadUsesLocale(t *testing.T) {
+	locale, err := ParseLocale("de", "Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("ParseLocale failed: %v", err)
+	}
+	notifier := &DiscordNotifier{enabled: true}
+	notifier.SetLocale(locale)
+
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, ScanID: 1, Domain: "example.com"}
+	result := &models.TaskResult{Duration: "1h2m"}
+	embed := notifier.createPayload(StepTaskCompleted, taskMsg, result, nil).Embeds[0]
+
+	if !strings.HasSuffix(embed.Timestamp, "+09:00") {
+		t.Errorf("expected the timestamp in the configured zone, got %q", embed.Timestamp)
+	}
+	if !strings.HasSuffix(embed.Footer.Text, "JST") {
+		t.Errorf("expected the localized time in the footer, got %q", embed.Footer.Text)
+	}
+
+	var duration string
+	for _, field := range embed.Fields {
+		if field.Name == "Duration" {
+			duration = field.Value
+		}
+	}
+	if duration != "1 Std. 2 Min." {
+		t.Errorf("expected a localized duration, got %q", duration)
+	}
+}
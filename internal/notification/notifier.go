@@ -1,34 +1,113 @@
 package notification
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-	"strings"
+	"reflect"
 	"time"
 
+	"github.com/allsafeASM/api/internal/faults"
 	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
 	"github.com/projectdiscovery/gologger"
 )
 
+//go:generate go test -run TestNotificationPayloadSchema -update-schemas .
+
+// Detail levels of the completion payload sent to the orchestrator
+const (
+	PayloadDetailSummary = "summary" // Task, status, timing and error only
+	PayloadDetailCounts  = "counts"  // Adds the result count and its breakdown
+	PayloadDetailFull    = "full"    // Adds the scanner result itself, unless it is too large
+)
+
+// PayloadDetails lists the valid detail levels
+var PayloadDetails = []string{PayloadDetailSummary, PayloadDetailCounts, PayloadDetailFull}
+
+// maxFullPayloadData bounds the size of the scanner result sent at the full detail level. The
+// orchestrator keeps event data in its history, so larger results are left to blob storage.
+const maxFullPayloadData = 256 << 10
+
 // Notifier handles Azure Function notifications
 type Notifier struct {
 	durableBaseURL string
 	durableKey     string
 	httpClient     *http.Client
+	detail         string
 }
 
 // NotificationPayload represents the payload sent to the Azure Function
 type NotificationPayload struct {
-	ScanID    int                    `json:"scan_id"`
-	Task      string                 `json:"task"`
-	Domain    string                 `json:"domain"`
-	Status    string                 `json:"status"`
-	Data      map[string]interface{} `json:"data,omitempty"`
-	Error     string                 `json:"error,omitempty"`
-	Timestamp string                 `json:"timestamp"`
-	Duration  string                 `json:"duration,omitempty"`
+	ScanID        int                    `json:"scan_id"`
+	Task          string                 `json:"task"`
+	Domain        string                 `json:"domain"`
+	TenantID      string                 `json:"tenant_id,omitempty"`
+	Status        string                 `json:"status"`
+	Detail        string                 `json:"detail"` // Detail level the data was built at
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Error         string                 `json:"error,omitempty"`
+	Timestamp     string                 `json:"timestamp"`
+	Duration      string                 `json:"duration,omitempty"`
+	CorrelationID string                 `json:"correlation_id,omitempty"`
+}
+
+// payloadData describes the keys of NotificationPayload.Data for the payload schema
+type payloadData struct {
+	Count     int            `json:"count"`
+	Counts    map[string]int `json:"counts,omitempty"`    // Breakdown of the count, e.g. findings per severity
+	Result    any            `json:"result,omitempty"`    // Scanner result, at the full detail level
+	Truncated bool           `json:"truncated,omitempty"` // The result was too large to send and is only in blob storage
+}
+
+// PayloadSchema returns the JSON Schema of the completion payload. The orchestrator reads
+// these fields, so a change to the schema is a change to the contract.
+func PayloadSchema() map[string]any {
+	schema := models.JSONSchema(reflect.TypeOf(NotificationPayload{}))
+	schema["properties"].(map[string]any)["data"] = models.JSONSchema(reflect.TypeOf(payloadData{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "NotificationPayload"
+	return schema
+}
+
+// NewNotificationPayload builds the completion payload of a result at a detail level
+func NewNotificationPayload(result *models.TaskResult, detail string) NotificationPayload {
+	payload := NotificationPayload{
+		ScanID:        result.ScanID,
+		Task:          string(result.Task),
+		Domain:        result.Domain,
+		TenantID:      result.TenantID,
+		Status:        string(result.Status),
+		Detail:        detail,
+		Error:         result.Error,
+		Timestamp:     result.Timestamp,
+		Duration:      result.Duration,
+		CorrelationID: result.CorrelationID,
+	}
+	if detail == PayloadDetailSummary || result.Data == nil {
+		return payload
+	}
+
+	count, counts := models.ResultCounts(result.Data)
+	if scannerResult, ok := result.Data.(models.ScannerResult); ok {
+		count = scannerResult.GetCount()
+	}
+	payload.Data = map[string]interface{}{"count": count}
+	if len(counts) > 0 {
+		payload.Data["counts"] = counts
+	}
+
+	if detail == PayloadDetailFull {
+		if data, err := json.Marshal(result.Data); err == nil && len(data) <= maxFullPayloadData {
+			payload.Data["result"] = json.RawMessage(data)
+		} else {
+			payload.Data["truncated"] = true
+		}
+	}
+	return payload
 }
 
 // NewNotifier creates a new notifier instance
@@ -46,9 +125,8 @@ func NewNotifier() (*Notifier, error) {
 	return &Notifier{
 		durableBaseURL: durableBaseURL,
 		durableKey:     durableKey,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		httpClient:     utils.NewTracingClient(30 * time.Second),
+		detail:         PayloadDetailCounts,
 	}, nil
 }
 
@@ -66,22 +144,44 @@ func NewConfiguredNotifier(enableNotifications bool) (*Notifier, error) {
 	return notifier, nil
 }
 
+// SetFaultInjector drops orchestrator calls as the injector decides, for resilience testing
+func (n *Notifier) SetFaultInjector(injector *faults.Injector) {
+	n.httpClient.Transport = injector.Transport(n.httpClient.Transport)
+}
+
+// SetPayloadDetail sets how much of the result the completion payload carries
+func (n *Notifier) SetPayloadDetail(detail string) {
+	n.detail = detail
+}
+
 // NotifyCompletion sends a completion notification to the Azure Function orchestrator
 func (n *Notifier) NotifyCompletion(ctx context.Context, instanceID string, toolName string, result *models.TaskResult) error {
 	if n == nil {
 		return nil // Notifications disabled
 	}
 
-	eventName := fmt.Sprintf("%s_completed", toolName)
+	body, err := json.Marshal(NewNotificationPayload(result, n.detail))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	return n.raiseEvent(ctx, instanceID, completionEvent(toolName), body)
+}
+
+// completionEvent returns the name of the orchestrator event that completes a task
+func completionEvent(toolName string) string {
+	return fmt.Sprintf("%s_completed", toolName)
+}
 
+// raiseEvent sends an event with its JSON payload to an orchestrator instance
+func (n *Notifier) raiseEvent(ctx context.Context, instanceID, eventName string, body []byte) error {
 	// Construct the notification URL
 	notificationURL := fmt.Sprintf("%s/instances/%s/raiseEvent/%s?code=%s",
 		n.durableBaseURL, instanceID, eventName, n.durableKey)
 
 	gologger.Info().Msgf("Notifying orchestrator at: %s", notificationURL)
 
-	// Create HTTP request with empty JSON body
-	req, err := http.NewRequestWithContext(ctx, "POST", notificationURL, strings.NewReader("{}"))
+	req, err := http.NewRequestWithContext(ctx, "POST", notificationURL, bytes.NewReader(body))
 	if err != nil {
 		return fmt.Errorf("failed to create HTTP request: %w", err)
 	}
@@ -104,17 +204,46 @@ func (n *Notifier) NotifyCompletion(ctx context.Context, instanceID string, tool
 	return nil
 }
 
+// NotifyPausedWithRetry tells the orchestrator that a task of a paused scan is waiting for the
+// scan to resume, so the missing completion event is not taken for a hung task. The completion
+// event follows once the resumed task finishes.
+func (n *Notifier) NotifyPausedWithRetry(ctx context.Context, instanceID string, toolName string, result *models.TaskResult) error {
+	if n == nil {
+		return nil // Notifications disabled
+	}
+
+	body, err := json.Marshal(NewNotificationPayload(result, PayloadDetailCounts))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return n.withRetry(ctx, func() error {
+		return n.raiseEvent(ctx, instanceID, pausedEvent(toolName), body)
+	})
+}
+
+// pausedEvent returns the name of the orchestrator event that reports a paused task
+func pausedEvent(toolName string) string {
+	return fmt.Sprintf("%s_paused", toolName)
+}
+
 // NotifyCompletionWithRetry sends a completion notification with retry logic
 func (n *Notifier) NotifyCompletionWithRetry(ctx context.Context, instanceID string, toolName string, result *models.TaskResult) error {
 	if n == nil {
 		return nil // Notifications disabled
 	}
 
+	return n.withRetry(ctx, func() error {
+		return n.NotifyCompletion(ctx, instanceID, toolName, result)
+	})
+}
+
+// withRetry calls send until it succeeds, retrying with exponential backoff
+func (n *Notifier) withRetry(ctx context.Context, send func() error) error {
 	maxRetries := 3
 	baseDelay := 1 * time.Second
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
-		err := n.NotifyCompletion(ctx, instanceID, toolName, result)
+		err := send()
 		if err == nil {
 			return nil
 		}
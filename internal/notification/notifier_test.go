@@ -1,12 +1,19 @@
 package notification
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/allsafeASM/api/internal/models"
+	"golang.org/x/exp/maps"
 )
 
 func TestNewNotifier(t *testing.T) {
@@ -122,3 +129,121 @@ func TestNotifyCompletionWithRetry(t *testing.T) {
 		t.Error("Expected error when calling non-existent endpoint")
 	}
 }
+
+var updateSchemas = flag.Bool("update-schemas", false, "rewrite the notification payload schema in schemas/")
+
+func TestNewNotificationPayload(t *testing.T) {
+	result := &models.TaskResult{
+		ScanID:   7,
+		Task:     models.TaskNuclei,
+		Domain:   "example.com",
+		Status:   models.TaskStatusCompleted,
+		Duration: "2m0s",
+		Data: models.NucleiResult{Domain: "example.com", Vulnerabilities: []models.NucleiVulnerability{
+			{TemplateID: "a", Severity: "high"},
+			{TemplateID: "b", Severity: "low"},
+		}},
+	}
+
+	summary := NewNotificationPayload(result, PayloadDetailSummary)
+	if summary.Data != nil || summary.Detail != PayloadDetailSummary || summary.Duration != "2m0s" {
+		t.Errorf("Expected a summary payload without data, got %+v", summary)
+	}
+
+	counts := NewNotificationPayload(result, PayloadDetailCounts)
+	if counts.Data["count"] != 2 || counts.Data["counts"].(map[string]int)["high"] != 1 || counts.Data["result"] != nil {
+		t.Errorf("Expected counts without the result, got %v", counts.Data)
+	}
+
+	full := NewNotificationPayload(result, PayloadDetailFull)
+	if _, ok := full.Data["result"].(json.RawMessage); !ok {
+		t.Errorf("Expected the full result, got %v", full.Data)
+	}
+
+	// Results too large for the orchestrator's history are left to blob storage
+	large := &models.TaskResult{Task: models.TaskSubfinder, Data: models.SubfinderResult{Subdomains: make([]string, maxFullPayloadData/2)}}
+	if truncated := NewNotificationPayload(large, PayloadDetailFull); truncated.Data["truncated"] != true || truncated.Data["result"] != nil {
+		t.Errorf("Expected a large result to be truncated, got keys %v", maps.Keys(truncated.Data))
+	}
+}
+
+func TestNotifyCompletionSendsPayload(t *testing.T) {
+	var received NotificationPayload
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{durableBaseURL: server.URL, durableKey: "key", httpClient: server.Client(), detail: PayloadDetailCounts}
+	result := &models.TaskResult{
+		ScanID: 3,
+		Task:   models.TaskSubfinder,
+		Domain: "example.com",
+		Status: models.TaskStatusCompleted,
+		Data:   models.SubfinderResult{Subdomains: []string{"a.example.com"}},
+	}
+	if err := notifier.NotifyCompletion(context.Background(), "instance", "subfinder", result); err != nil {
+		t.Fatalf("NotifyCompletion failed: %v", err)
+	}
+
+	if path != "/instances/instance/raiseEvent/subfinder_completed" {
+		t.Errorf("Unexpected path %s", path)
+	}
+	if received.ScanID != 3 || received.Detail != PayloadDetailCounts || received.Data["count"] != float64(1) {
+		t.Errorf("Unexpected payload %+v", received)
+	}
+}
+
+func TestNotifyPausedSendsPausedEvent(t *testing.T) {
+	var received NotificationPayload
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{durableBaseURL: server.URL, durableKey: "key", httpClient: server.Client(), detail: PayloadDetailFull}
+	result := &models.TaskResult{ScanID: 4, Task: models.TaskHttpx, Domain: "example.com", Status: models.TaskStatusPaused}
+	if err := notifier.NotifyPausedWithRetry(context.Background(), "instance", "httpx", result); err != nil {
+		t.Fatalf("NotifyPausedWithRetry failed: %v", err)
+	}
+
+	if path != "/instances/instance/raiseEvent/httpx_paused" {
+		t.Errorf("Unexpected path %s", path)
+	}
+	if received.Status != string(models.TaskStatusPaused) || received.Detail != PayloadDetailCounts {
+		t.Errorf("Unexpected payload %+v", received)
+	}
+}
+
+func TestNotificationPayloadSchema(t *testing.T) {
+	generated, err := json.MarshalIndent(PayloadSchema(), "", "  ")
+	if err != nil {
+		t.Fatalf("Failed to marshal the payload schema: %v", err)
+	}
+	generated = append(generated, '\n')
+	path := filepath.Join("..", "..", "schemas", "notification.schema.json")
+
+	if *updateSchemas {
+		if err := os.WriteFile(path, generated, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Missing payload schema, run go generate ./internal/notification: %v", err)
+	}
+	if !bytes.Equal(golden, generated) {
+		t.Errorf("The notification payload no longer matches %s. If the change is intended and the orchestrator "+
+			"can handle it, run go generate ./internal/notification and commit the schema.\n\ngot:\n%s", path, generated)
+	}
+}
@@ -0,0 +1,218 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	// outboxQueueSize bounds the entries queued for delivery right away; the sweep picks up the rest
+	outboxQueueSize = 256
+
+	// outboxSweepAge is how old an undelivered entry must be before a sweep delivers it. Younger
+	// entries are still being delivered by the worker that stored them.
+	outboxSweepAge = 5 * time.Minute
+
+	// outboxRetention is how long delivered entries are kept to recognize redelivered messages,
+	// and how long undelivered ones are retried before they are given up
+	outboxRetention = 24 * time.Hour
+)
+
+// OutboxStore persists completion notifications until they are delivered. Entries are kept with
+// their tenant's blobs and addressed by blob path.
+type OutboxStore interface {
+	StoreOutboxEntry(ctx context.Context, entry *models.OutboxEntry) error
+	// UpdateOutboxEntry changes an entry in place unless another worker changed it since it was
+	// read. update reports whether it changed the entry; entries that are gone are left alone.
+	UpdateOutboxEntry(ctx context.Context, blobPath string, update func(entry *models.OutboxEntry) bool) error
+	LoadOutboxEntry(ctx context.Context, blobPath string) (*models.OutboxEntry, error)
+	ListOutboxEntries(ctx context.Context) ([]string, error)
+	DeleteOutboxEntry(ctx context.Context, blobPath string) error
+}
+
+// Outbox delivers completion notifications to the orchestrator after the task's message has been
+// completed. Entries are stored before the message is completed, so a notification is sent at
+// least once even when the worker stops before delivering it: every worker sweeps the outbox for
+// entries left behind and delivers them.
+type Outbox struct {
+	store         OutboxStore
+	notifier      *Notifier
+	sweepInterval time.Duration
+	pending       chan string // Blob paths of entries to deliver right away
+	onDelivered   func(entry *models.OutboxEntry)
+	now           func() time.Time
+}
+
+// NewOutbox creates an outbox that delivers through the notifier and sweeps the store every sweepInterval
+func NewOutbox(store OutboxStore, notifier *Notifier, sweepInterval time.Duration) *Outbox {
+	return &Outbox{
+		store:         store,
+		notifier:      notifier,
+		sweepInterval: sweepInterval,
+		pending:       make(chan string, outboxQueueSize),
+		now:           time.Now,
+	}
+}
+
+// OnDelivered sets a function called with every entry once it has been delivered
+func (o *Outbox) OnDelivered(fn func(entry *models.OutboxEntry)) {
+	o.onDelivered = fn
+}
+
+// Add stores the completion notification of a result in the outbox and queues it for delivery.
+// It returns once the entry is stored; an error means the notification would be lost.
+func (o *Outbox) Add(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) error {
+	if taskMsg.InstanceID == "" {
+		return fmt.Errorf("instance_id is required for notification")
+	}
+
+	payload, err := json.Marshal(NewNotificationPayload(result, o.notifier.detail))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	entry := &models.OutboxEntry{
+		ID:          models.OutboxEntryID(taskMsg),
+		InstanceID:  taskMsg.InstanceID,
+		Event:       completionEvent(string(taskMsg.Task)),
+		Payload:     payload,
+		TaskMessage: *taskMsg,
+		CreatedAt:   o.now().UTC(),
+	}
+	if err := o.store.StoreOutboxEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	select {
+	case o.pending <- entry.BlobPath():
+	default:
+		gologger.Warning().Msgf("Outbox queue is full, %s notification for domain %s waits for the next sweep", entry.Event, taskMsg.Domain)
+	}
+	return nil
+}
+
+// Contains reports whether the outbox holds the completion notification of a task run, delivered or not
+func (o *Outbox) Contains(ctx context.Context, taskMsg *models.TaskMessage) (bool, error) {
+	entry, err := o.store.LoadOutboxEntry(ctx, models.OutboxBlobPath(taskMsg.TenantID, models.OutboxEntryID(taskMsg)))
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// Run delivers queued entries and sweeps the outbox for older ones until ctx is done. Entries
+// still queued then stay in the outbox for the next sweep.
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.sweepInterval)
+	defer ticker.Stop()
+
+	o.sweep(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case blobPath := <-o.pending:
+			o.deliver(ctx, blobPath, false)
+		case <-ticker.C:
+			o.sweep(ctx)
+		}
+	}
+}
+
+// sweep delivers the entries left in the outbox by failed deliveries and stopped workers, and
+// removes the expired ones
+func (o *Outbox) sweep(ctx context.Context) {
+	paths, err := o.store.ListOutboxEntries(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			gologger.Warning().Msgf("Failed to sweep the notification outbox: %v", err)
+		}
+		return
+	}
+
+	for _, blobPath := range paths {
+		if ctx.Err() != nil {
+			return
+		}
+		o.deliver(ctx, blobPath, true)
+	}
+}
+
+// deliver sends an entry to the orchestrator and records the outcome. Swept entries are only
+// delivered once they are older than outboxSweepAge.
+func (o *Outbox) deliver(ctx context.Context, blobPath string, swept bool) {
+	entry, err := o.store.LoadOutboxEntry(ctx, blobPath)
+	if err != nil {
+		gologger.Warning().Msgf("Failed to load outbox entry %s: %v", blobPath, err)
+		return
+	}
+	if entry == nil {
+		return
+	}
+
+	now := o.now()
+	age := now.Sub(entry.CreatedAt)
+	switch {
+	case entry.DeliveredAt != nil:
+		if now.Sub(*entry.DeliveredAt) > outboxRetention {
+			o.remove(ctx, blobPath)
+		}
+		return
+	case age > outboxRetention:
+		gologger.Error().Msgf("Giving up %s notification for instance %s after %d failed attempts: %s",
+			entry.Event, entry.InstanceID, entry.Attempts, entry.LastError)
+		o.remove(ctx, blobPath)
+		return
+	case swept && age < outboxSweepAge:
+		return
+	}
+
+	err = o.notifier.withRetry(ctx, func() error {
+		return o.notifier.raiseEvent(ctx, entry.InstanceID, entry.Event, entry.Payload)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		gologger.Warning().Msgf("Failed to deliver %s notification for domain %s, retrying on a later sweep: %v", entry.Event, entry.TaskMessage.Domain, err)
+	}
+	deliveredAt := o.now().UTC()
+
+	// The entry is updated on a fresh context, so a delivery during shutdown is still recorded.
+	// Another worker may have delivered it meanwhile, or a redelivered message replaced it with a
+	// newer entry; their records are kept.
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	storeErr := o.store.UpdateOutboxEntry(storeCtx, blobPath, func(current *models.OutboxEntry) bool {
+		if current.DeliveredAt != nil || !current.CreatedAt.Equal(entry.CreatedAt) {
+			return false
+		}
+		if err != nil {
+			current.Attempts++
+			current.LastError = err.Error()
+		} else {
+			current.DeliveredAt = &deliveredAt
+		}
+		*entry = *current
+		return true
+	})
+	if storeErr != nil {
+		gologger.Warning().Msgf("Failed to update outbox entry %s: %v", entry.ID, storeErr)
+	}
+
+	if err == nil && o.onDelivered != nil {
+		o.onDelivered(entry)
+	}
+}
+
+// remove deletes an expired entry from the outbox
+func (o *Outbox) remove(ctx context.Context, blobPath string) {
+	if err := o.store.DeleteOutboxEntry(ctx, blobPath); err != nil {
+		gologger.Warning().Msgf("Failed to remove outbox entry %s: %v", blobPath, err)
+	}
+}
@@ -0,0 +1,204 @@
+package notification
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// memoryOutboxStore keeps outbox entries in memory
+type memoryOutboxStore struct {
+	mu      sync.Mutex
+	entries map[string]models.OutboxEntry
+}
+
+func (s *memoryOutboxStore) StoreOutboxEntry(ctx context.Context, entry *models.OutboxEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.entries == nil {
+		s.entries = map[string]models.OutboxEntry{}
+	}
+	s.entries[entry.BlobPath()] = *entry
+	return nil
+}
+
+func (s *memoryOutboxStore) UpdateOutboxEntry(ctx context.Context, blobPath string, update func(entry *models.OutboxEntry) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[blobPath]
+	if ok && update(&entry) {
+		s.entries[blobPath] = entry
+	}
+	return nil
+}
+
+func (s *memoryOutboxStore) LoadOutboxEntry(ctx context.Context, blobPath string) (*models.OutboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[blobPath]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *memoryOutboxStore) ListOutboxEntries(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.entries))
+	for blobPath := range s.entries {
+		paths = append(paths, blobPath)
+	}
+	return paths, nil
+}
+
+func (s *memoryOutboxStore) DeleteOutboxEntry(ctx context.Context, blobPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, blobPath)
+	return nil
+}
+
+// newTestOutbox returns an outbox delivering to a server that records the raised event paths
+func newTestOutbox(t *testing.T, store OutboxStore) (*Outbox, *[]string) {
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+
+	notifier := &Notifier{durableBaseURL: server.URL, durableKey: "key", httpClient: server.Client(), detail: PayloadDetailCounts}
+	return NewOutbox(store, notifier, time.Hour), &paths
+}
+
+func TestOutboxDeliversAddedEntry(t *testing.T) {
+	store := &memoryOutboxStore{}
+	outbox, paths := newTestOutbox(t, store)
+	taskMsg := &models.TaskMessage{TenantID: "acme", Task: models.TaskSubfinder, Domain: "example.com", ScanID: 7, InstanceID: "instance", CorrelationID: "corr"}
+	result := &models.TaskResult{ScanID: 7, Task: models.TaskSubfinder, Domain: "example.com", Status: models.TaskStatusCompleted}
+
+	delivered := make(chan *models.OutboxEntry, 1)
+	outbox.OnDelivered(func(entry *models.OutboxEntry) { delivered <- entry })
+
+	if err := outbox.Add(context.Background(), taskMsg, result); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if ok, err := outbox.Contains(context.Background(), taskMsg); err != nil || !ok {
+		t.Fatalf("Expected the entry to be stored before delivery, got %v, %v", ok, err)
+	}
+	// A redelivery generates another correlation ID when the message has none
+	redelivered := *taskMsg
+	redelivered.CorrelationID, redelivered.Attempt = "other", 2
+	if ok, err := outbox.Contains(context.Background(), &redelivered); err != nil || !ok {
+		t.Fatalf("Expected a redelivered message to find the entry, got %v, %v", ok, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go outbox.Run(ctx)
+
+	select {
+	case entry := <-delivered:
+		if entry.TaskMessage.Domain != "example.com" {
+			t.Errorf("Unexpected delivered entry %+v", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for delivery")
+	}
+	cancel()
+
+	if len(*paths) != 1 || (*paths)[0] != "/instances/instance/raiseEvent/subfinder_completed" {
+		t.Errorf("Expected one subfinder_completed event, got %v", *paths)
+	}
+
+	// The delivered entry stays behind so a redelivered message is recognized
+	entry, _ := store.LoadOutboxEntry(context.Background(), models.OutboxBlobPath("acme", models.OutboxEntryID(taskMsg)))
+	if entry == nil || entry.DeliveredAt == nil {
+		t.Errorf("Expected the entry to be kept as delivered, got %+v", entry)
+	}
+}
+
+func TestOutboxAddRequiresInstanceID(t *testing.T) {
+	outbox, _ := newTestOutbox(t, &memoryOutboxStore{})
+	taskMsg := &models.TaskMessage{Task: models.TaskSubfinder, Domain: "example.com"}
+	if err := outbox.Add(context.Background(), taskMsg, &models.TaskResult{}); err == nil {
+		t.Error("Expected an error without an instance ID")
+	}
+}
+
+func TestOutboxSweep(t *testing.T) {
+	store := &memoryOutboxStore{}
+	outbox, paths := newTestOutbox(t, store)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+
+	delivered := now.Add(-2 * outboxRetention)
+	entries := []models.OutboxEntry{
+		// Left behind by a stopped worker
+		{ID: "stale", InstanceID: "a", Event: "httpx_completed", Payload: []byte(`{}`), CreatedAt: now.Add(-time.Hour)},
+		// Still being delivered by the worker that stored it
+		{ID: "fresh", InstanceID: "b", Event: "httpx_completed", Payload: []byte(`{}`), CreatedAt: now.Add(-time.Minute)},
+		// Delivered long ago
+		{ID: "done", InstanceID: "c", Event: "httpx_completed", Payload: []byte(`{}`), CreatedAt: delivered, DeliveredAt: &delivered},
+		// Never delivered within the retention
+		{ID: "expired", InstanceID: "d", Event: "httpx_completed", Payload: []byte(`{}`), CreatedAt: now.Add(-2 * outboxRetention), Attempts: 40},
+	}
+	for i := range entries {
+		store.StoreOutboxEntry(context.Background(), &entries[i])
+	}
+
+	outbox.sweep(context.Background())
+
+	if len(*paths) != 1 || (*paths)[0] != "/instances/a/raiseEvent/httpx_completed" {
+		t.Errorf("Expected only the stale entry to be delivered, got %v", *paths)
+	}
+	if entry, _ := store.LoadOutboxEntry(context.Background(), models.OutboxBlobPath("", "stale")); entry == nil || entry.DeliveredAt == nil {
+		t.Errorf("Expected the stale entry to be marked delivered, got %+v", entry)
+	}
+	if entry, _ := store.LoadOutboxEntry(context.Background(), models.OutboxBlobPath("", "fresh")); entry == nil || entry.DeliveredAt != nil {
+		t.Errorf("Expected the fresh entry to be left alone, got %+v", entry)
+	}
+	for _, id := range []string{"done", "expired"} {
+		if entry, _ := store.LoadOutboxEntry(context.Background(), models.OutboxBlobPath("", id)); entry != nil {
+			t.Errorf("Expected entry %s to be removed", id)
+		}
+	}
+}
+
+func TestOutboxKeepsConcurrentDelivery(t *testing.T) {
+	store := &memoryOutboxStore{}
+	outbox, _ := newTestOutbox(t, store)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	outbox.now = func() time.Time { return now }
+
+	// Another worker delivers the entry while this one is sending it
+	createdAt := now.Add(-time.Hour)
+	store.StoreOutboxEntry(context.Background(), &models.OutboxEntry{ID: "stale", InstanceID: "a", Event: "httpx_completed", Payload: []byte(`{}`), CreatedAt: createdAt})
+	deliveredAt := now.Add(-time.Second)
+	outbox.notifier.httpClient.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		store.UpdateOutboxEntry(context.Background(), models.OutboxBlobPath("", "stale"), func(entry *models.OutboxEntry) bool {
+			entry.DeliveredAt = &deliveredAt
+			return true
+		})
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	outbox.sweep(context.Background())
+
+	if entry, _ := store.LoadOutboxEntry(context.Background(), models.OutboxBlobPath("", "stale")); entry == nil || entry.DeliveredAt == nil || !entry.DeliveredAt.Equal(deliveredAt) {
+		t.Errorf("Expected the other worker's delivery to be kept, got %+v", entry)
+	}
+}
+
+// roundTripFunc answers HTTP requests with a function
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,439 @@
+package notification
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	defaultTicketSeverity  = "medium"
+	defaultJiraIssueType   = "Bug"
+	defaultCloseTransition = "Done"
+	defaultGitHubAPIURL    = "https://api.github.com"
+)
+
+// Ticket is the issue tracker representation of a finding
+type Ticket struct {
+	Title    string
+	Body     string
+	Severity string
+	Labels   []string
+}
+
+// TicketProvider creates, updates and closes tickets in an issue tracker
+type TicketProvider interface {
+	Name() string
+	Create(ctx context.Context, config *models.TicketingConfig, ticket Ticket) (string, error)
+	Update(ctx context.Context, config *models.TicketingConfig, key string, ticket Ticket) error
+	Close(ctx context.Context, config *models.TicketingConfig, key string) error
+}
+
+// TicketStore holds the per-tenant ticketing configuration and the tickets opened per domain.
+// Updates are applied to the latest stored state, so tasks of the same domain finishing at once
+// do not lose each other's tickets.
+type TicketStore interface {
+	LoadTicketingConfig(ctx context.Context, tenantID string) (*models.TicketingConfig, error)
+	LoadTicketState(ctx context.Context, tenantID, domain string) (*models.TicketState, error)
+	UpdateTicketState(ctx context.Context, tenantID, domain string, update func(*models.TicketState) error) error
+}
+
+// TicketNotifier keeps one issue tracker ticket per unique nuclei finding: tickets are created
+// for new findings, updated when their severity changes and closed once a later scan no longer
+// reports them. Tenants opt in with their ticketing configuration.
+type TicketNotifier struct {
+	providers map[string]TicketProvider
+	store     TicketStore
+	now       func() time.Time
+}
+
+// NewTicketNotifier creates a ticket notifier for the given providers
+func NewTicketNotifier(store TicketStore, providers ...TicketProvider) *TicketNotifier {
+	notifier := &TicketNotifier{providers: make(map[string]TicketProvider), store: store, now: time.Now}
+	for _, provider := range providers {
+		notifier.providers[provider.Name()] = provider
+	}
+	return notifier
+}
+
+// NewConfiguredTicketNotifier creates a ticket notifier from the Jira and GitHub credentials in
+// the environment, returning nil when neither is configured
+func NewConfiguredTicketNotifier(store TicketStore, timeout time.Duration) *TicketNotifier {
+	httpClient := utils.NewTracingClient(timeout)
+
+	var providers []TicketProvider
+	if baseURL := os.Getenv("JIRA_BASE_URL"); baseURL != "" {
+		providers = append(providers, &JiraProvider{
+			baseURL:    strings.TrimSuffix(baseURL, "/"),
+			email:      os.Getenv("JIRA_EMAIL"),
+			apiToken:   os.Getenv("JIRA_API_TOKEN"),
+			httpClient: httpClient,
+		})
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		apiURL := os.Getenv("GITHUB_API_URL")
+		if apiURL == "" {
+			apiURL = defaultGitHubAPIURL
+		}
+		providers = append(providers, &GitHubIssuesProvider{
+			apiURL:     strings.TrimSuffix(apiURL, "/"),
+			token:      token,
+			httpClient: httpClient,
+		})
+	}
+
+	if len(providers) == 0 || store == nil {
+		return nil
+	}
+	return NewTicketNotifier(store, providers...)
+}
+
+// Process syncs the tickets of a domain with the nuclei findings of a stored task result.
+// Partial and verify results never close tickets because the scan may not have reached the finding,
+// and no run closes the tickets of templates it excluded.
+func (n *TicketNotifier) Process(ctx context.Context, taskMsg *models.TaskMessage, result *models.TaskResult) error {
+	if n == nil {
+		return nil
+	}
+
+	nucleiResult, ok := result.Data.(models.NucleiResult)
+	if !ok {
+		return nil
+	}
+
+	config, err := n.store.LoadTicketingConfig(ctx, taskMsg.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load ticketing config: %w", err)
+	}
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	if err := validateTicketingConfig(config); err != nil {
+		return err
+	}
+	provider, ok := n.providers[config.Provider]
+	if !ok {
+		return fmt.Errorf("ticketing provider %q is not configured on this worker", config.Provider)
+	}
+
+	minSeverity := SeverityRank(config.MinSeverity)
+	if minSeverity == 0 {
+		minSeverity = SeverityRank(defaultTicketSeverity)
+	}
+
+	state, err := n.store.LoadTicketState(ctx, taskMsg.TenantID, taskMsg.Domain)
+	if err != nil {
+		return fmt.Errorf("failed to load ticket state: %w", err)
+	}
+
+	// The provider is called on the state as it was loaded, and only the tickets this result
+	// changed are applied to the latest state, as another task may have changed it since
+	scope := "nuclei:" + taskMsg.Type
+	changed := make(map[string]models.OpenTicket)
+	var closed []models.OpenTicket
+	present := make(map[string]bool)
+	for _, vuln := range nucleiResult.Vulnerabilities {
+		if SeverityRank(vuln.Severity) < minSeverity {
+			continue
+		}
+		key := fingerprint(taskMsg.TenantID, taskMsg.Domain, "nuclei", vuln.TemplateID, vuln.MatchedAt)
+		if present[key] {
+			continue
+		}
+		present[key] = true
+
+		ticket := findingTicket(taskMsg, vuln, key)
+		open, exists := state.Tickets[key]
+		switch {
+		case !exists:
+			ticketKey, err := provider.Create(ctx, config, ticket)
+			if err != nil {
+				gologger.Warning().Msgf("Failed to create %s ticket for %s: %v", provider.Name(), vuln.MatchedAt, err)
+				continue
+			}
+			changed[key] = models.OpenTicket{Key: ticketKey, Scope: scope, Severity: vuln.Severity, OpenedAt: n.now().UTC().Format(time.RFC3339), TemplateID: vuln.TemplateID}
+			gologger.Info().Msgf("Created %s ticket %s for %s", provider.Name(), ticketKey, vuln.MatchedAt)
+		case !strings.EqualFold(open.Severity, vuln.Severity):
+			if err := provider.Update(ctx, config, open.Key, ticket); err != nil {
+				gologger.Warning().Msgf("Failed to update %s ticket %s: %v", provider.Name(), open.Key, err)
+				continue
+			}
+			open.Severity = vuln.Severity
+			changed[key] = open
+		}
+	}
+
+	if result.CoversScope() {
+		// A finding the replay did not confirm may still be there, so its ticket stays open
+		for _, vuln := range nucleiResult.Unconfirmed {
+			present[fingerprint(taskMsg.TenantID, taskMsg.Domain, "nuclei", vuln.TemplateID, vuln.MatchedAt)] = true
+		}
+		excluded := nucleiResult.ExcludedTemplates()
+		for key, open := range state.Tickets {
+			if open.Scope != scope || present[key] || slices.Contains(excluded, open.TemplateID) {
+				continue
+			}
+			if err := provider.Close(ctx, config, open.Key); err != nil {
+				gologger.Warning().Msgf("Failed to close %s ticket %s: %v", provider.Name(), open.Key, err)
+				continue
+			}
+			closed = append(closed, open)
+			gologger.Info().Msgf("Closed %s ticket %s, finding is no longer present", provider.Name(), open.Key)
+		}
+	}
+	if len(changed) == 0 && len(closed) == 0 {
+		return nil
+	}
+
+	err = n.store.UpdateTicketState(ctx, taskMsg.TenantID, taskMsg.Domain, func(state *models.TicketState) error {
+		for key, ticket := range changed {
+			// A ticket another task created for the finding in the meantime is the one kept
+			if stored, exists := state.Tickets[key]; exists && stored.Key != ticket.Key {
+				gologger.Warning().Msgf("Finding %s got %s ticket %s concurrently, %s is a duplicate", key, provider.Name(), stored.Key, ticket.Key)
+				continue
+			}
+			state.Tickets[key] = ticket
+		}
+		for _, ticket := range closed {
+			for key, stored := range state.Tickets {
+				if stored.Key == ticket.Key {
+					delete(state.Tickets, key)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store ticket state: %w", err)
+	}
+	return nil
+}
+
+// validateTicketingConfig checks that a tenant's ticketing configuration names a project the provider can address
+func validateTicketingConfig(config *models.TicketingConfig) error {
+	switch config.Provider {
+	case "jira":
+		if config.Project == "" || strings.ContainsAny(config.Project, "/ ") {
+			return fmt.Errorf("invalid Jira project key: %q", config.Project)
+		}
+	case "github":
+		owner, repo, ok := strings.Cut(config.Project, "/")
+		if !ok || owner == "" || repo == "" || strings.ContainsAny(repo, "/ ") || owner == ".." || repo == ".." {
+			return fmt.Errorf("invalid GitHub repository, expected owner/repo: %q", config.Project)
+		}
+	default:
+		return fmt.Errorf("unsupported ticketing provider: %q", config.Provider)
+	}
+	return nil
+}
+
+// findingTicket renders a finding as a ticket labelled by severity, domain and tenant
+func findingTicket(taskMsg *models.TaskMessage, vuln models.NucleiVulnerability, key string) Ticket {
+	var body strings.Builder
+	if vuln.Description != "" {
+		body.WriteString(vuln.Description + "\n\n")
+	}
+	fmt.Fprintf(&body, "Template: %s\nMatched at: %s\nSeverity: %s\n", vuln.TemplateID, vuln.MatchedAt, vuln.Severity)
+	if len(vuln.CVEIDs) > 0 {
+		fmt.Fprintf(&body, "CVE: %s\n", strings.Join(vuln.CVEIDs, ", "))
+	}
+	if vuln.CVSSScore > 0 {
+		fmt.Fprintf(&body, "CVSS: %.1f %s\n", vuln.CVSSScore, vuln.CVSSMetrics)
+	}
+	for _, reference := range vuln.Reference {
+		fmt.Fprintf(&body, "Reference: %s\n", reference)
+	}
+	if vuln.CurlCommand != "" {
+		fmt.Fprintf(&body, "\nReproduce:\n%s\n", vuln.CurlCommand)
+	}
+	fmt.Fprintf(&body, "\nFingerprint: %s", key)
+
+	labels := []string{"asm", "severity:" + strings.ToLower(vuln.Severity), "domain:" + taskMsg.Domain}
+	if taskMsg.TenantID != "" {
+		labels = append(labels, "tenant:"+taskMsg.TenantID)
+	}
+
+	return Ticket{
+		Title:    fmt.Sprintf("[%s] %s on %s", strings.ToUpper(vuln.Severity), findingTitle(vuln), vuln.MatchedAt),
+		Body:     body.String(),
+		Severity: vuln.Severity,
+		Labels:   labels,
+	}
+}
+
+// JiraProvider manages tickets as Jira issues through the Jira REST API
+type JiraProvider struct {
+	baseURL    string
+	email      string
+	apiToken   string
+	httpClient *http.Client
+}
+
+// Name returns the provider name
+func (j *JiraProvider) Name() string {
+	return "jira"
+}
+
+// Create creates a Jira issue and returns its key
+func (j *JiraProvider) Create(ctx context.Context, config *models.TicketingConfig, ticket Ticket) (string, error) {
+	issueType := config.IssueType
+	if issueType == "" {
+		issueType = defaultJiraIssueType
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	err := doJSON(ctx, j.httpClient, "POST", j.baseURL+"/rest/api/2/issue", j.headers(), map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": config.Project},
+			"issuetype":   map[string]string{"name": issueType},
+			"summary":     ticket.Title,
+			"description": ticket.Body,
+			"labels":      jiraLabels(ticket.Labels),
+			"priority":    map[string]string{"name": jiraPriority(ticket.Severity)},
+		},
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	return created.Key, nil
+}
+
+// Update updates the priority and labels of a Jira issue
+func (j *JiraProvider) Update(ctx context.Context, config *models.TicketingConfig, key string, ticket Ticket) error {
+	return doJSON(ctx, j.httpClient, "PUT", j.baseURL+"/rest/api/2/issue/"+url.PathEscape(key), j.headers(), map[string]interface{}{
+		"fields": map[string]interface{}{
+			"summary":  ticket.Title,
+			"labels":   jiraLabels(ticket.Labels),
+			"priority": map[string]string{"name": jiraPriority(ticket.Severity)},
+		},
+	}, nil)
+}
+
+// Close moves a Jira issue through the configured close transition
+func (j *JiraProvider) Close(ctx context.Context, config *models.TicketingConfig, key string) error {
+	transitionName := config.CloseTransition
+	if transitionName == "" {
+		transitionName = defaultCloseTransition
+	}
+	transitionsURL := j.baseURL + "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+
+	var available struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := doJSON(ctx, j.httpClient, "GET", transitionsURL, j.headers(), nil, &available); err != nil {
+		return err
+	}
+
+	for _, transition := range available.Transitions {
+		if strings.EqualFold(transition.Name, transitionName) {
+			return doJSON(ctx, j.httpClient, "POST", transitionsURL, j.headers(), map[string]interface{}{
+				"transition": map[string]string{"id": transition.ID},
+			}, nil)
+		}
+	}
+	return fmt.Errorf("issue %s has no %q transition", key, transitionName)
+}
+
+// headers returns the basic authentication headers of the Jira API
+func (j *JiraProvider) headers() map[string]string {
+	credentials := base64.StdEncoding.EncodeToString([]byte(j.email + ":" + j.apiToken))
+	return map[string]string{"Authorization": "Basic " + credentials}
+}
+
+// jiraLabels makes labels valid for Jira, which does not allow spaces
+func jiraLabels(labels []string) []string {
+	converted := make([]string, len(labels))
+	for i, label := range labels {
+		converted[i] = strings.ReplaceAll(label, " ", "_")
+	}
+	return converted
+}
+
+// jiraPriority maps a finding severity to a default Jira priority
+func jiraPriority(severity string) string {
+	switch SeverityRank(severity) {
+	case 5:
+		return "Highest"
+	case 4:
+		return "High"
+	case 3:
+		return "Medium"
+	case 2:
+		return "Low"
+	default:
+		return "Lowest"
+	}
+}
+
+// GitHubIssuesProvider manages tickets as GitHub issues
+type GitHubIssuesProvider struct {
+	apiURL     string
+	token      string
+	httpClient *http.Client
+}
+
+// Name returns the provider name
+func (g *GitHubIssuesProvider) Name() string {
+	return "github"
+}
+
+// Create opens a GitHub issue and returns its number
+func (g *GitHubIssuesProvider) Create(ctx context.Context, config *models.TicketingConfig, ticket Ticket) (string, error) {
+	var created struct {
+		Number int `json:"number"`
+	}
+	err := doJSON(ctx, g.httpClient, "POST", g.issuesURL(config), g.headers(), map[string]interface{}{
+		"title":  ticket.Title,
+		"body":   ticket.Body,
+		"labels": ticket.Labels,
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(created.Number), nil
+}
+
+// Update updates the title and labels of a GitHub issue
+func (g *GitHubIssuesProvider) Update(ctx context.Context, config *models.TicketingConfig, key string, ticket Ticket) error {
+	return doJSON(ctx, g.httpClient, "PATCH", g.issuesURL(config)+"/"+key, g.headers(), map[string]interface{}{
+		"title":  ticket.Title,
+		"labels": ticket.Labels,
+	}, nil)
+}
+
+// Close closes a GitHub issue as completed
+func (g *GitHubIssuesProvider) Close(ctx context.Context, config *models.TicketingConfig, key string) error {
+	return doJSON(ctx, g.httpClient, "PATCH", g.issuesURL(config)+"/"+key, g.headers(), map[string]string{
+		"state":        "closed",
+		"state_reason": "completed",
+	}, nil)
+}
+
+// issuesURL returns the issues endpoint of the configured repository
+func (g *GitHubIssuesProvider) issuesURL(config *models.TicketingConfig) string {
+	return fmt.Sprintf("%s/repos/%s/issues", g.apiURL, config.Project)
+}
+
+// headers returns the authentication headers of the GitHub API
+func (g *GitHubIssuesProvider) headers() map[string]string {
+	return map[string]string{
+		"Authorization": "Bearer " + g.token,
+		"Accept":        "application/vnd.github+json",
+	}
+}
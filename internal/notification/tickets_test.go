@@ -0,0 +1,182 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// memoryTicketStore keeps ticketing configuration and state in memory
+type memoryTicketStore struct {
+	config *models.TicketingConfig
+	state  *models.TicketState
+	// concurrent, when set, changes the state between a load and the next update, like another worker
+	concurrent func(*models.TicketState)
+}
+
+func (s *memoryTicketStore) LoadTicketingConfig(ctx context.Context, tenantID string) (*models.TicketingConfig, error) {
+	return s.config, nil
+}
+
+func (s *memoryTicketStore) LoadTicketState(ctx context.Context, tenantID, domain string) (*models.TicketState, error) {
+	if s.state == nil {
+		return &models.TicketState{TenantID: tenantID, Domain: domain, Tickets: map[string]models.OpenTicket{}}, nil
+	}
+	return s.state, nil
+}
+
+func (s *memoryTicketStore) UpdateTicketState(ctx context.Context, tenantID, domain string, update func(*models.TicketState) error) error {
+	state, _ := s.LoadTicketState(ctx, tenantID, domain)
+	s.state = state
+	if s.concurrent != nil {
+		s.concurrent(state)
+		s.concurrent = nil
+	}
+	return update(state)
+}
+
+// recordingTicketProvider records ticket operations
+type recordingTicketProvider struct {
+	created []Ticket
+	updated []string
+	closed  []string
+}
+
+func (p *recordingTicketProvider) Name() string { return "github" }
+
+func (p *recordingTicketProvider) Create(ctx context.Context, config *models.TicketingConfig, ticket Ticket) (string, error) {
+	p.created = append(p.created, ticket)
+	return "42", nil
+}
+
+func (p *recordingTicketProvider) Update(ctx context.Context, config *models.TicketingConfig, key string, ticket Ticket) error {
+	p.updated = append(p.updated, key)
+	return nil
+}
+
+func (p *recordingTicketProvider) Close(ctx context.Context, config *models.TicketingConfig, key string) error {
+	p.closed = append(p.closed, key)
+	return nil
+}
+
+func TestTicketNotifierLifecycle(t *testing.T) {
+	store := &memoryTicketStore{config: &models.TicketingConfig{Enabled: true, Provider: "github", Project: "acme/security"}}
+	provider := &recordingTicketProvider{}
+	notifier := NewTicketNotifier(store, provider)
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, Domain: "example.com", TenantID: "tenant-a"}
+
+	run := func(vulns ...models.NucleiVulnerability) {
+		result := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{Vulnerabilities: vulns}}
+		if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+
+	cors := models.NucleiVulnerability{TemplateID: "cors-misconfig", MatchedAt: "https://example.com/api", Severity: "medium"}
+	run(cors, models.NucleiVulnerability{TemplateID: "tech-detect", MatchedAt: "https://example.com/", Severity: "info"})
+	if len(provider.created) != 1 {
+		t.Fatalf("Expected one ticket for the medium finding, got %d", len(provider.created))
+	}
+	labels := provider.created[0].Labels
+	if len(labels) != 4 || labels[1] != "severity:medium" || labels[2] != "domain:example.com" || labels[3] != "tenant:tenant-a" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+
+	// Unchanged findings leave the ticket alone, a severity change updates it
+	run(cors)
+	cors.Severity = "high"
+	run(cors)
+	if len(provider.created) != 1 || len(provider.updated) != 1 {
+		t.Errorf("Expected one update and no new tickets, got %d created and %d updated", len(provider.created), len(provider.updated))
+	}
+
+	run()
+	if len(provider.closed) != 1 || provider.closed[0] != "42" || len(store.state.Tickets) != 0 {
+		t.Errorf("Expected the ticket to be closed, got %v closed and %d open", provider.closed, len(store.state.Tickets))
+	}
+}
+
+func TestTicketNotifierKeepsConcurrentTickets(t *testing.T) {
+	store := &memoryTicketStore{config: &models.TicketingConfig{Enabled: true, Provider: "github", Project: "acme/security"}}
+	notifier := NewTicketNotifier(store, &recordingTicketProvider{})
+	taskMsg := &models.TaskMessage{Task: models.TaskNuclei, Domain: "example.com", TenantID: "tenant-a", Type: "http"}
+
+	// A network scan of the same domain creates a ticket while this result is processed
+	store.concurrent = func(state *models.TicketState) {
+		state.Tickets["other"] = models.OpenTicket{Key: "7", Scope: "nuclei:network", Severity: "high"}
+	}
+	cors := models.NucleiVulnerability{TemplateID: "cors-misconfig", MatchedAt: "https://example.com/api", Severity: "medium"}
+	result := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{cors}}}
+	if err := notifier.Process(context.Background(), taskMsg, result); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(store.state.Tickets) != 2 {
+		t.Errorf("Expected both tickets to be kept, got %v", store.state.Tickets)
+	}
+}
+
+func TestTicketNotifierDisabledTenant(t *testing.T) {
+	provider := &recordingTicketProvider{}
+	notifier := NewTicketNotifier(&memoryTicketStore{}, provider)
+	result := &models.TaskResult{Status: models.TaskStatusCompleted, Data: models.NucleiResult{Vulnerabilities: []models.NucleiVulnerability{{Severity: "critical"}}}}
+
+	if err := notifier.Process(context.Background(), &models.TaskMessage{Domain: "example.com"}, result); err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if len(provider.created) != 0 {
+		t.Error("Expected no tickets for a tenant without ticketing configuration")
+	}
+}
+
+func TestValidateTicketingConfig(t *testing.T) {
+	tests := []struct {
+		config  models.TicketingConfig
+		wantErr bool
+	}{
+		{models.TicketingConfig{Provider: "jira", Project: "ASM"}, false},
+		{models.TicketingConfig{Provider: "github", Project: "acme/security"}, false},
+		{models.TicketingConfig{Provider: "github", Project: "acme/security/../../orgs"}, true},
+		{models.TicketingConfig{Provider: "jira", Project: ""}, true},
+		{models.TicketingConfig{Provider: "trello", Project: "board"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateTicketingConfig(&tt.config); (err != nil) != tt.wantErr {
+			t.Errorf("validateTicketingConfig(%+v) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJiraProviderClose(t *testing.T) {
+	var transitioned string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/2/issue/ASM-7/transitions" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		if r.Method == http.MethodGet {
+			w.Write([]byte(`{"transitions":[{"id":"11","name":"In Progress"},{"id":"31","name":"Done"}]}`))
+			return
+		}
+		var body struct {
+			Transition struct {
+				ID string `json:"id"`
+			} `json:"transition"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		transitioned = body.Transition.ID
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	provider := &JiraProvider{baseURL: server.URL, email: "bot@example.com", apiToken: "token", httpClient: server.Client()}
+	if err := provider.Close(context.Background(), &models.TicketingConfig{Project: "ASM"}, "ASM-7"); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if transitioned != "31" {
+		t.Errorf("Expected the Done transition, got %q", transitioned)
+	}
+}
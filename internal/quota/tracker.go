@@ -0,0 +1,126 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// DefaultPeriod is the period a quota limit applies to; providers reset API quotas monthly
+const DefaultPeriod = 30 * 24 * time.Hour
+
+// Store persists quota states shared by all workers. Update must apply the function to the
+// latest state and only write it if no other worker changed the state in the meantime.
+type Store interface {
+	UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error
+}
+
+// Tracker keeps a token bucket per API key so that sources are throttled, and then skipped,
+// as their monthly quota runs out instead of burning the key
+type Tracker struct {
+	store  Store
+	limits map[string]int // Requests per period by source name
+	period time.Duration
+	now    func() time.Time
+}
+
+// NewTracker creates a tracker enforcing the per-source limits with states kept in store
+func NewTracker(store Store, limits map[string]int) *Tracker {
+	return &Tracker{store: store, limits: limits, period: DefaultPeriod, now: time.Now}
+}
+
+// ParseLimits parses limits in the form "source=requests,source=requests"
+func ParseLimits(spec string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		source, value, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(source) == "" {
+			return nil, fmt.Errorf("invalid quota %q: expected source=requests", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid quota %q: requests must be a positive integer", entry)
+		}
+		limits[strings.ToLower(strings.TrimSpace(source))] = limit
+	}
+	return limits, nil
+}
+
+// Reserve takes up to max requests from the key's bucket and returns how many were granted.
+// Sources without a limit, and a nil tracker, are always granted max.
+func (t *Tracker) Reserve(ctx context.Context, source, key string, max int) (int, error) {
+	limit, ok := t.limit(source)
+	if !ok || max <= 0 {
+		return max, nil
+	}
+
+	granted := 0
+	err := t.store.UpdateQuotaState(ctx, key, func(state *models.QuotaState) error {
+		t.refill(state, limit)
+		granted = max
+		if available := int(math.Floor(state.Tokens)); available < granted {
+			granted = available
+		}
+		state.Tokens -= float64(granted)
+		state.Used += granted
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve quota for %s: %w", source, err)
+	}
+	return granted, nil
+}
+
+// Release returns n reserved requests that were not used to the key's bucket
+func (t *Tracker) Release(ctx context.Context, source, key string, n int) error {
+	limit, ok := t.limit(source)
+	if !ok || n <= 0 {
+		return nil
+	}
+
+	err := t.store.UpdateQuotaState(ctx, key, func(state *models.QuotaState) error {
+		t.refill(state, limit)
+		state.Tokens = math.Min(state.Tokens+float64(n), float64(limit))
+		state.Used -= n
+		if state.Used < 0 {
+			state.Used = 0
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release quota for %s: %w", source, err)
+	}
+	return nil
+}
+
+// limit returns the limit of a source, reporting false when the source is not tracked
+func (t *Tracker) limit(source string) (int, bool) {
+	if t == nil {
+		return 0, false
+	}
+	limit, ok := t.limits[strings.ToLower(source)]
+	return limit, ok
+}
+
+// refill adds the tokens regained since the state was last updated. A new state starts full,
+// and a changed limit takes effect immediately.
+func (t *Tracker) refill(state *models.QuotaState, limit int) {
+	now := t.now().UTC()
+	if state.UpdatedAt.IsZero() {
+		state.Tokens = float64(limit)
+	} else if elapsed := now.Sub(state.UpdatedAt); elapsed > 0 {
+		state.Tokens += float64(limit) * elapsed.Seconds() / t.period.Seconds()
+	}
+	state.Tokens = math.Min(state.Tokens, float64(limit))
+	state.Limit = limit
+	state.UpdatedAt = now
+}
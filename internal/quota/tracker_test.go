@@ -0,0 +1,94 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// memoryStore keeps quota states in memory
+type memoryStore struct {
+	states map[string]models.QuotaState
+	err    error
+}
+
+func (s *memoryStore) UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error {
+	if s.err != nil {
+		return s.err
+	}
+	state := s.states[key]
+	state.Key = key
+	if err := update(&state); err != nil {
+		return err
+	}
+	s.states[key] = state
+	return nil
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := ParseLimits(" VirusTotal=15000, securitytrails=50,")
+	if err != nil {
+		t.Fatalf("ParseLimits() error = %v", err)
+	}
+	if len(limits) != 2 || limits["virustotal"] != 15000 || limits["securitytrails"] != 50 {
+		t.Errorf("Unexpected limits %v", limits)
+	}
+
+	for _, spec := range []string{"virustotal", "virustotal=0", "=5", "chaos=many"} {
+		if _, err := ParseLimits(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestTracker_ReserveThrottlesAndRefills(t *testing.T) {
+	store := &memoryStore{states: make(map[string]models.QuotaState)}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewTracker(store, map[string]int{"securitytrails": 30})
+	tracker.period = 30 * time.Hour
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if granted, _ := tracker.Reserve(ctx, "securitytrails", "key", 20); granted != 20 {
+		t.Errorf("Expected the full request from a new bucket, got %d", granted)
+	}
+	if granted, _ := tracker.Reserve(ctx, "securitytrails", "key", 20); granted != 10 {
+		t.Errorf("Expected the request throttled to the 10 tokens left, got %d", granted)
+	}
+	if granted, _ := tracker.Reserve(ctx, "securitytrails", "key", 20); granted != 0 {
+		t.Errorf("Expected an exhausted bucket to grant nothing, got %d", granted)
+	}
+
+	// One token is regained per hour
+	now = now.Add(3 * time.Hour)
+	if granted, _ := tracker.Reserve(ctx, "securitytrails", "key", 20); granted != 3 {
+		t.Errorf("Expected 3 refilled tokens, got %d", granted)
+	}
+
+	if err := tracker.Release(ctx, "securitytrails", "key", 2); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if state := store.states["key"]; state.Tokens != 2 || state.Used != 31 || state.Limit != 30 {
+		t.Errorf("Unexpected state after release: %+v", state)
+	}
+}
+
+func TestTracker_UntrackedSources(t *testing.T) {
+	store := &memoryStore{err: errors.New("store unavailable")}
+	tracker := NewTracker(store, map[string]int{"virustotal": 100})
+
+	if granted, err := tracker.Reserve(context.Background(), "chaos", "key", 20); err != nil || granted != 20 {
+		t.Errorf("Expected untracked sources to be granted everything, got %d, %v", granted, err)
+	}
+	if _, err := tracker.Reserve(context.Background(), "virustotal", "key", 20); err == nil {
+		t.Error("Expected the store error to be returned")
+	}
+
+	var nilTracker *Tracker
+	if granted, err := nilTracker.Reserve(context.Background(), "virustotal", "key", 5); err != nil || granted != 5 {
+		t.Errorf("Expected a nil tracker to grant everything, got %d, %v", granted, err)
+	}
+}
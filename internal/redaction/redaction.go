@@ -0,0 +1,222 @@
+// Package redaction scrubs sensitive values such as session cookies, authorization headers and
+// credentials from results before they are stored or sent anywhere.
+package redaction
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Rules a policy can turn on
+const (
+	RuleCookies       = "cookies"       // Cookie and Set-Cookie header values
+	RuleAuthorization = "authorization" // Authorization and Proxy-Authorization header values
+	RuleCredentials   = "credentials"   // Keys, tokens, private keys and passwords found in bodies and extracted values
+)
+
+// Policy names that stand for all rules and for none
+const (
+	PolicyAll  = "all"
+	PolicyNone = "none"
+)
+
+// Mask replaces every redacted value
+const Mask = "[REDACTED]"
+
+// Rules lists the valid rules
+var Rules = []string{RuleCookies, RuleAuthorization, RuleCredentials}
+
+// pattern replaces the sensitive part of its matches; the replacement may refer to groups
+type pattern struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+var rulePatterns = map[string][]pattern{
+	RuleCookies: {
+		// Keep the cookie names, which help triage, and drop their values
+		{regexp.MustCompile(`(?im)^(Set-Cookie:[ \t]*[^=;\r\n]+=)[^;\r\n]*`), "${1}" + Mask},
+		{regexp.MustCompile(`(?im)^(Cookie:[ \t]*)[^\r\n]*`), "${1}" + Mask},
+		// Curl commands pass cookies as -H 'Cookie: ...' or -b '...'
+		{regexp.MustCompile(`(?i)('Cookie:\s*)[^']*`), "${1}" + Mask},
+		{regexp.MustCompile(`(?i)((?:^|\s)(?:-b|--cookie)\s+')[^']*`), "${1}" + Mask},
+	},
+	RuleAuthorization: {
+		// Keep the scheme, e.g. Basic or Bearer, and drop the credentials
+		{regexp.MustCompile(`(?im)^((?:Proxy-)?Authorization:[ \t]*(?:[A-Za-z-]+[ \t]+)?)\S[^\r\n]*`), "${1}" + Mask},
+		{regexp.MustCompile(`(?i)('(?:Proxy-)?Authorization:\s*(?:[A-Za-z-]+\s+)?)[^'\s][^']*`), "${1}" + Mask},
+	},
+	RuleCredentials: {
+		{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), Mask},
+		{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), Mask},
+		{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), Mask},
+		{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`), Mask},
+		{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`), Mask},
+		{regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret|api[_-]?key|access[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?)[^"'\s&,;<]{4,}`), "${1}" + Mask},
+	},
+}
+
+// Policy is the set of rules applied to one tenant's results. A nil policy redacts nothing.
+type Policy struct {
+	rules    []string
+	patterns []pattern
+}
+
+// NewPolicy returns the policy applying the given rules, or nil for none
+func NewPolicy(rules ...string) (*Policy, error) {
+	policy := &Policy{}
+	for _, rule := range rules {
+		patterns, ok := rulePatterns[rule]
+		if !ok {
+			return nil, fmt.Errorf("unknown redaction rule %q: valid rules are %s", rule, strings.Join(Rules, ", "))
+		}
+		if slices.Contains(policy.rules, rule) {
+			continue
+		}
+		policy.rules = append(policy.rules, rule)
+		policy.patterns = append(policy.patterns, patterns...)
+	}
+	if len(policy.rules) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// Rules returns the rules the policy applies
+func (p *Policy) Rules() []string {
+	if p == nil {
+		return nil
+	}
+	return p.rules
+}
+
+// String redacts a single value
+func (p *Policy) String(value string) string {
+	if p == nil || value == "" {
+		return value
+	}
+	for _, pattern := range p.patterns {
+		value = pattern.re.ReplaceAllString(value, pattern.replacement)
+	}
+	return value
+}
+
+// Strings redacts a list of values into a new list
+func (p *Policy) Strings(values []string) []string {
+	if p == nil || values == nil {
+		return values
+	}
+	redacted := make([]string, len(values))
+	for i, value := range values {
+		redacted[i] = p.String(value)
+	}
+	return redacted
+}
+
+// Finding redacts the raw exchange and extracted values of a nuclei finding
+func (p *Policy) Finding(finding models.NucleiVulnerability) models.NucleiVulnerability {
+	if p == nil {
+		return finding
+	}
+	finding.Request = p.String(finding.Request)
+	finding.Response = p.String(finding.Response)
+	finding.CurlCommand = p.String(finding.CurlCommand)
+	finding.ExtractedResults = p.Strings(finding.ExtractedResults)
+	return finding
+}
+
+// Exchange redacts the raw request and response of an HTTP exchange
+func (p *Policy) Exchange(exchange models.HTTPExchange) models.HTTPExchange {
+	if p == nil {
+		return exchange
+	}
+	exchange.Request = p.String(exchange.Request)
+	exchange.Response = p.String(exchange.Response)
+	return exchange
+}
+
+// Result redacts the scanner results that carry raw responses. Other results are returned as they are.
+func (p *Policy) Result(data any) any {
+	if p == nil {
+		return data
+	}
+	switch result := data.(type) {
+	case models.NucleiResult:
+		findings := make([]models.NucleiVulnerability, len(result.Vulnerabilities))
+		for i, finding := range result.Vulnerabilities {
+			findings[i] = p.Finding(finding)
+		}
+		result.Vulnerabilities = findings
+		return result
+	case *models.NucleiResult:
+		redacted := p.Result(*result).(models.NucleiResult)
+		return &redacted
+	}
+	return data
+}
+
+// TaskResult redacts a task result's data and error in place
+func (p *Policy) TaskResult(result *models.TaskResult) {
+	if p == nil || result == nil {
+		return
+	}
+	result.Data = p.Result(result.Data)
+	result.Error = p.String(result.Error)
+}
+
+// Policies maps tenants to their redaction policy
+type Policies map[string]*Policy
+
+// ParsePolicies parses policies in the form "tenant=rule|rule,tenant=rule". The tenant "*", or
+// an entry without a tenant, sets the policy of all other tenants, which is "all" unless set.
+// Besides rules, an entry may name the policies "all" and "none".
+func ParsePolicies(spec string) (Policies, error) {
+	policies := make(Policies)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		tenant, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			tenant, value = "*", entry
+		}
+		tenant = strings.TrimSpace(tenant)
+		if tenant == "" {
+			return nil, fmt.Errorf("invalid redaction policy %q: expected tenant=rules", entry)
+		}
+
+		var rules []string
+		for _, rule := range strings.Split(value, "|") {
+			switch rule = strings.ToLower(strings.TrimSpace(rule)); rule {
+			case PolicyNone, "":
+			case PolicyAll:
+				rules = append(rules, Rules...)
+			default:
+				rules = append(rules, rule)
+			}
+		}
+		policy, err := NewPolicy(rules...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction policy %q: %w", entry, err)
+		}
+		policies[tenant] = policy
+	}
+
+	if _, ok := policies["*"]; !ok {
+		policies["*"], _ = NewPolicy(Rules...)
+	}
+	return policies, nil
+}
+
+// Lookup returns the policy of a tenant, falling back to "*"
+func (p Policies) Lookup(tenantID string) *Policy {
+	if policy, ok := p[tenantID]; ok && tenantID != "" {
+		return policy
+	}
+	return p["*"]
+}
@@ -0,0 +1,135 @@
+package review
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Reasons an exchange is considered interesting enough to review
+const (
+	ReasonUnusualStatus = "unusual_status" // A status code automated triage rarely sees
+	ReasonAuthChallenge = "auth_challenge" // The server asked for credentials
+	ReasonLongRedirect  = "long_redirect"  // The request went through a long redirect chain
+	ReasonRedirectBody  = "redirect_body"  // A redirect came with a large body, which often leaks the page it guards
+)
+
+const (
+	// longRedirectHops is how many redirects make a chain long
+	longRedirectHops = 3
+	// largeRedirectBody is the body length in bytes from which a redirect body is large
+	largeRedirectBody = 1024
+	// maxExchangeSize bounds the stored request and response, each, in bytes
+	maxExchangeSize = 256 * 1024
+)
+
+// commonStatusCodes are the status codes scanned hosts usually answer with
+var commonStatusCodes = map[int]bool{
+	http.StatusOK:                true,
+	http.StatusNoContent:         true,
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusNotModified:       true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+	http.StatusBadRequest:        true,
+	http.StatusForbidden:         true,
+	http.StatusNotFound:          true,
+}
+
+// Sampler keeps a share of the interesting exchanges of scans for manual review, so the
+// quality of automated results can be checked against what the hosts actually answered
+type Sampler struct {
+	rate       float64 // Share of interesting exchanges kept, between 0 and 1
+	maxSamples int     // Samples kept per task at most
+	random     func() float64
+	now        func() time.Time
+}
+
+// NewSampler creates a sampler keeping percent of the interesting exchanges, at most maxSamples per task
+func NewSampler(percent float64, maxSamples int) *Sampler {
+	return &Sampler{rate: percent / 100, maxSamples: maxSamples, random: rand.Float64, now: time.Now}
+}
+
+// Reasons returns why an exchange is interesting, or nothing when it is not
+func Reasons(exchange models.HTTPExchange) []string {
+	var reasons []string
+	headers := http.Header(exchange.Headers)
+	if exchange.StatusCode != 0 && !commonStatusCodes[exchange.StatusCode] &&
+		exchange.StatusCode != http.StatusUnauthorized && exchange.StatusCode != http.StatusProxyAuthRequired {
+		reasons = append(reasons, ReasonUnusualStatus)
+	}
+	if exchange.StatusCode == http.StatusUnauthorized || exchange.StatusCode == http.StatusProxyAuthRequired ||
+		headers.Get("WWW-Authenticate") != "" || headers.Get("Proxy-Authenticate") != "" {
+		reasons = append(reasons, ReasonAuthChallenge)
+	}
+	if len(exchange.ChainStatusCodes) > longRedirectHops {
+		reasons = append(reasons, ReasonLongRedirect)
+	}
+	if exchange.StatusCode >= 300 && exchange.StatusCode < 400 && exchange.BodyLength >= largeRedirectBody {
+		reasons = append(reasons, ReasonRedirectBody)
+	}
+	return reasons
+}
+
+// Collection gathers the samples of one task. It is safe for concurrent use.
+type Collection struct {
+	sampler    *Sampler
+	mu         sync.Mutex
+	candidates int
+	samples    []models.ReviewSample
+}
+
+// NewCollection starts gathering the samples of a task
+func (s *Sampler) NewCollection() *Collection {
+	return &Collection{sampler: s}
+}
+
+// Offer keeps the exchange when it is interesting, is picked by the sampling rate and the
+// task has not reached its sample limit
+func (c *Collection) Offer(exchange models.HTTPExchange) {
+	reasons := Reasons(exchange)
+	if len(reasons) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.candidates++
+	if len(c.samples) >= c.sampler.maxSamples || c.sampler.random() >= c.sampler.rate {
+		return
+	}
+
+	sample := models.ReviewSample{
+		HTTPExchange: exchange,
+		Reasons:      reasons,
+		SampledAt:    c.sampler.now().UTC().Format(time.RFC3339),
+	}
+	if len(sample.Request) > maxExchangeSize {
+		sample.Request = sample.Request[:maxExchangeSize]
+		sample.Truncated = true
+	}
+	if len(sample.Response) > maxExchangeSize {
+		sample.Response = sample.Response[:maxExchangeSize]
+		sample.Truncated = true
+	}
+	c.samples = append(c.samples, sample)
+}
+
+// Candidates returns how many interesting exchanges were offered
+func (c *Collection) Candidates() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.candidates
+}
+
+// Samples returns the samples kept so far
+func (c *Collection) Samples() []models.ReviewSample {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]models.ReviewSample(nil), c.samples...)
+}
@@ -0,0 +1,70 @@
+package review
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// TestReasons tests which exchanges are considered interesting
+func TestReasons(t *testing.T) {
+	tests := []struct {
+		name     string
+		exchange models.HTTPExchange
+		expected []string
+	}{
+		{"plain page", models.HTTPExchange{StatusCode: 200}, nil},
+		{"not found", models.HTTPExchange{StatusCode: 404}, nil},
+		{"teapot", models.HTTPExchange{StatusCode: 418}, []string{ReasonUnusualStatus}},
+		{"server error", models.HTTPExchange{StatusCode: 502}, []string{ReasonUnusualStatus}},
+		{"unauthorized", models.HTTPExchange{StatusCode: 401}, []string{ReasonAuthChallenge}},
+		{"basic auth on a page", models.HTTPExchange{StatusCode: 200, Headers: map[string][]string{"Www-Authenticate": {"Basic"}}}, []string{ReasonAuthChallenge}},
+		{"short chain", models.HTTPExchange{StatusCode: 200, ChainStatusCodes: []int{301, 302}}, nil},
+		{"long chain", models.HTTPExchange{StatusCode: 200, ChainStatusCodes: []int{301, 302, 302, 307}}, []string{ReasonLongRedirect}},
+		{"small redirect", models.HTTPExchange{StatusCode: 302, BodyLength: 120}, nil},
+		{"redirect with a page", models.HTTPExchange{StatusCode: 302, BodyLength: 8000}, []string{ReasonRedirectBody}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reasons(tt.exchange); !slices.Equal(got, tt.expected) {
+				t.Errorf("Reasons() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestCollection tests that interesting exchanges are sampled at the rate and up to the limit
+func TestCollection(t *testing.T) {
+	sampler := NewSampler(50, 2)
+	draws := []float64{0.9, 0.1, 0.2, 0.3}
+	sampler.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+
+	collection := sampler.NewCollection()
+	collection.Offer(models.HTTPExchange{URL: "https://example.com", StatusCode: 200})
+	for _, url := range []string{"https://a.example.com", "https://b.example.com", "https://c.example.com", "https://d.example.com"} {
+		collection.Offer(models.HTTPExchange{URL: url, StatusCode: 500, Response: strings.Repeat("x", maxExchangeSize+1)})
+	}
+
+	if collection.Candidates() != 4 {
+		t.Errorf("Expected 4 candidates, got %d", collection.Candidates())
+	}
+	samples := collection.Samples()
+	if len(samples) != 2 || samples[0].URL != "https://b.example.com" || samples[1].URL != "https://c.example.com" {
+		t.Fatalf("Expected the second and third candidates to be sampled, got %+v", samples)
+	}
+	if !samples[0].Truncated || len(samples[0].Response) != maxExchangeSize {
+		t.Errorf("Expected the response to be truncated to %d bytes", maxExchangeSize)
+	}
+
+	disabled := NewSampler(0, 10).NewCollection()
+	disabled.Offer(models.HTTPExchange{StatusCode: 500})
+	if len(disabled.Samples()) > 0 {
+		t.Error("Expected a rate of 0 to keep nothing")
+	}
+}
@@ -1,6 +1,13 @@
 package scanners
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
 	"github.com/allsafeASM/api/internal/common"
 	"github.com/allsafeASM/api/internal/models"
 	"github.com/allsafeASM/api/internal/validation"
@@ -57,3 +64,46 @@ func (b *BaseScanner) ClassifyError(err error) *common.AppError {
 func (b *BaseScanner) IsRetryableError(err error) bool {
 	return b.errorClassifier.IsRetryableError(err)
 }
+
+// banError returns the rate-limit error a scan that ran into a ban fails with, so the task is
+// retried once the ban is expected to be lifted, or nil when nothing was banned or the policy
+// does not retry
+func banError(taskCtx *models.TaskContext, tool string, blocks []models.BlockEvent) error {
+	if len(blocks) == 0 || taskCtx == nil || taskCtx.BlockPolicy.RetryAfter <= 0 {
+		return nil
+	}
+	message := fmt.Sprintf("%s was banned by %d host groups, first %s", tool, len(blocks), blocks[0])
+	return common.NewRateLimitError(message, taskCtx.BlockPolicy.RetryAfter, nil)
+}
+
+// exclusionLookupTimeout bounds the lookup of one name while enforcing exclusions
+const exclusionLookupTimeout = 5 * time.Second
+
+// filterExcluded drops the excluded targets of a scanner that contacts them, looking up names so
+// that IP and CIDR entries apply to host names and host name entries to IPs
+func filterExcluded(ctx context.Context, exclusions models.TargetExclusions, targets []string) ([]string, int, error) {
+	excluder, err := exclusions.Excluder()
+	if err != nil {
+		return nil, 0, common.NewValidationError("exclude", err.Error())
+	}
+	kept, excluded, err := excluder.FilterResolved(ctx, targets, lookupExclusionAddrs)
+	if errors.Is(err, models.ErrExclusionLookup) {
+		return nil, 0, common.NewNetworkError("failed to look up excluded host names", err)
+	}
+	if err != nil {
+		return nil, 0, common.NewValidationError("exclude", err.Error())
+	}
+	return kept, excluded, nil
+}
+
+// lookupExclusionAddrs looks up the addresses of a host name with the worker's resolver
+func lookupExclusionAddrs(ctx context.Context, host string) ([]netip.Addr, error) {
+	ctx, cancel := context.WithTimeout(ctx, exclusionLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, fmt.Errorf("%w: %v", models.ErrHostNotFound, err)
+	}
+	return addrs, err
+}
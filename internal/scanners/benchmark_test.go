@@ -0,0 +1,96 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// benchmarkNames is the number of synthetic names resolved per DNSX benchmark run
+const benchmarkNames = 2000
+
+// BenchmarkDNSXResolution measures DNSX throughput against a local synthetic zone. The rate limits
+// are high enough that the worker pool, not the limiter, bounds the runs, apart from the last,
+// which measures how closely the limiter is met.
+func BenchmarkDNSXResolution(b *testing.B) {
+	resolver := startSyntheticDNSServer(b, 60)
+	names := syntheticSubdomains(benchmarkNames)
+
+	hostsfile := false
+	settings := models.DNSSettings{Retries: 1, TimeoutMs: 1000, QuestionTypes: []string{"A", "CNAME"}, Hostsfile: &hostsfile}
+	pool := dnsResolverPool{name: "synthetic", resolvers: []string{resolver}}
+
+	for _, scaling := range []dnsScaling{
+		{Workers: 10, RateLimit: 100000},
+		{Workers: 50, RateLimit: 100000},
+		{Workers: 200, RateLimit: 100000},
+		{Workers: 200, RateLimit: 4000},
+	} {
+		b.Run(fmt.Sprintf("workers=%d/rate=%d", scaling.Workers, scaling.RateLimit), func(b *testing.B) {
+			scanner := NewDNSXScanner()
+			query, err := scanner.initializeComponents(settings, pool)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				records := scanner.processDNSResolutionOptimized(context.Background(), nil, query, scaling, names)
+				if len(records) != len(names) {
+					b.Fatalf("Expected %d records, got %d", len(names), len(records))
+				}
+			}
+			b.ReportMetric(float64(b.N*len(names))/b.Elapsed().Seconds(), "names/s")
+		})
+	}
+}
+
+// BenchmarkShardedResultMap measures concurrent result stores from DNSX workers
+func BenchmarkShardedResultMap(b *testing.B) {
+	names := syntheticSubdomains(10000)
+	info := models.ResolutionInfo{Status: models.DNSStatusResolved, A: []string{"10.0.0.1"}}
+
+	for _, shards := range []int{1, 16, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			results := NewShardedResultMap(shards)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					results.Set(names[i%len(names)], info)
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkNaabuResultCollector measures the OnResult callback overhead with as many concurrent
+// callers as naabu threads
+func BenchmarkNaabuResultCollector(b *testing.B) {
+	hosts := syntheticHostResults(1000, 10)
+
+	for _, threads := range []int{1, 5, 25, 50} {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				collector := newNaabuResultCollector()
+				var wg sync.WaitGroup
+				for t := 0; t < threads; t++ {
+					wg.Add(1)
+					go func(t int) {
+						defer wg.Done()
+						for h := t; h < len(hosts); h += threads {
+							collector.onResult(hosts[h])
+						}
+					}(t)
+				}
+				wg.Wait()
+				if len(collector.ports) != len(hosts) {
+					b.Fatalf("Expected %d hosts, got %d", len(hosts), len(collector.ports))
+				}
+			}
+			b.ReportMetric(float64(b.N*len(hosts))/b.Elapsed().Seconds(), "hosts/s")
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// cloudDNSTimeout bounds a single request to a cloud DNS API
+const cloudDNSTimeout = 30 * time.Second
+
+// CloudDNSCredentials are the read-only credentials cloud_dns tasks read zones with. Azure DNS
+// uses the default Azure credential chain and needs nothing here. The credentials may reach the
+// zones of several tenants, so each tenant only reads from the accounts it is bound to.
+type CloudDNSCredentials struct {
+	AWS                  utils.AWSCredentials
+	GoogleServiceAccount []byte // JSON key of a Google Cloud service account
+	Accounts             models.CloudDNSAccounts
+}
+
+// cloudDNSZone is a public zone a provider serves
+type cloudDNSZone struct {
+	ID   string // Identifier the provider's record API takes
+	Name string // Lower-case zone name without the trailing dot
+}
+
+// cloudDNSProvider reads the public zones and their records from a cloud DNS service
+type cloudDNSProvider interface {
+	zones(ctx context.Context, source models.CloudDNSSource) ([]cloudDNSZone, error)
+	records(ctx context.Context, source models.CloudDNSSource, zone cloudDNSZone) ([]models.CloudDNSRecord, error)
+}
+
+// CloudDNSScanner reads the records of a domain straight from the tenant's Route 53, Azure DNS
+// and Google Cloud DNS zones. Zones of the domain, of its parents and of delegated subdomains are
+// read, and records outside the domain are dropped. Private zones are skipped.
+type CloudDNSScanner struct {
+	*BaseScanner
+	providers map[string]cloudDNSProvider
+	accounts  models.CloudDNSAccounts
+}
+
+// NewCloudDNSScanner creates a cloud DNS scanner without AWS or Google credentials
+func NewCloudDNSScanner() *CloudDNSScanner {
+	scanner := &CloudDNSScanner{BaseScanner: NewBaseScanner()}
+	scanner.SetCredentials(CloudDNSCredentials{})
+	return scanner
+}
+
+// SetCredentials sets the credentials the zones are read with
+func (s *CloudDNSScanner) SetCredentials(credentials CloudDNSCredentials) {
+	s.providers = map[string]cloudDNSProvider{
+		models.CloudDNSProviderRoute53: newRoute53Zones(credentials.AWS),
+		models.CloudDNSProviderAzure:   newAzureZones(),
+		models.CloudDNSProviderGoogle:  newGoogleZones(credentials.GoogleServiceAccount),
+	}
+	s.accounts = credentials.Accounts
+}
+
+func (s *CloudDNSScanner) GetName() string {
+	return "cloud_dns"
+}
+
+func (s *CloudDNSScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
+	cloudInput, ok := input.(models.CloudDNSInput)
+	if !ok {
+		return nil, common.NewValidationError("input", "invalid input type, expected CloudDNSInput")
+	}
+	if err := s.ValidateInput(cloudInput); err != nil {
+		return nil, err
+	}
+	excluder, err := cloudInput.Exclude.Excluder()
+	if err != nil {
+		return nil, common.NewValidationError("exclude", err.Error())
+	}
+	for i, source := range cloudInput.Sources {
+		if !s.accounts.Allows(taskCtx.Tenant(), source) {
+			return nil, common.NewValidationError("sources", fmt.Sprintf("source %d reads account %s, which tenant %q is not bound to", i, source.Account(), taskCtx.Tenant()))
+		}
+	}
+
+	domain := strings.ToLower(cloudInput.Domain)
+	excluded := 0
+	result := models.CloudDNSResult{Domain: cloudInput.Domain, Records: []models.CloudDNSRecord{}, Zones: []string{}}
+	for i, source := range cloudInput.Sources {
+		provider := s.providers[source.Provider]
+		zones, err := provider.zones(ctx, source)
+		if err != nil {
+			result.Partial = len(result.Zones) > 0
+			return result, classifyCloudDNSError(ctx, fmt.Sprintf("failed to list %s zones", source.Provider), err)
+		}
+
+		for _, zone := range zones {
+			if !zoneCoversDomain(zone.Name, domain) {
+				continue
+			}
+			records, err := provider.records(ctx, source, zone)
+			if err != nil {
+				result.Partial = len(result.Zones) > 0
+				return result, classifyCloudDNSError(ctx, fmt.Sprintf("failed to read %s zone %s", source.Provider, zone.Name), err)
+			}
+			for _, record := range records {
+				if record.Name != domain && !strings.HasSuffix(record.Name, "."+domain) {
+					continue
+				}
+				if excluder.Excludes(record.Name) {
+					excluded++
+					continue
+				}
+				result.Records = append(result.Records, record)
+			}
+			result.Zones = append(result.Zones, source.Provider+":"+zone.Name)
+		}
+		taskCtx.ReportProgress("sources", i+1, len(cloudInput.Sources))
+	}
+
+	if len(result.Zones) == 0 {
+		return result, common.NewNotFoundError(fmt.Sprintf("no public zone of %s found in the configured sources", cloudInput.Domain), nil)
+	}
+
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d records of %s", excluded, cloudInput.Domain)
+	}
+	sort.SliceStable(result.Records, func(i, j int) bool {
+		if result.Records[i].Name != result.Records[j].Name {
+			return result.Records[i].Name < result.Records[j].Name
+		}
+		return result.Records[i].Type < result.Records[j].Type
+	})
+	taskCtx.Info().Msgf("Read %d records of %s from %d zones", len(result.Records), cloudInput.Domain, len(result.Zones))
+	return result, nil
+}
+
+// zoneCoversDomain reports whether a zone can hold records of the domain: the zone of the domain
+// itself, of a parent, or of a subdomain delegated to its own zone
+func zoneCoversDomain(zone, domain string) bool {
+	return zone == domain || strings.HasSuffix(domain, "."+zone) || strings.HasSuffix(zone, "."+domain)
+}
+
+// classifyCloudDNSError turns a failed provider call into a timeout when the task ran out of time,
+// into a rate-limit error, retried later, when the provider throttled it, and into a network
+// error, which is retried, otherwise
+func classifyCloudDNSError(ctx context.Context, message string, err error) error {
+	if ctx.Err() != nil {
+		return common.NewTimeoutError(message, err)
+	}
+	if retryAfter := common.RetryAfter(err); retryAfter > 0 {
+		return common.NewRateLimitError(message, retryAfter, err)
+	}
+	return common.NewNetworkError(message, err)
+}
+
+// normalizeRecordName lower-cases a record name and drops the trailing dot
+func normalizeRecordName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// normalizeRecordValues drops the trailing dot of values that are host names
+func normalizeRecordValues(recordType string, values []string) []string {
+	switch recordType {
+	case "CNAME", "NS", "MX", "PTR", "SRV", "ALIAS":
+		for i, value := range values {
+			values[i] = strings.TrimSuffix(value, ".")
+		}
+	}
+	return values
+}
@@ -0,0 +1,478 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+)
+
+const (
+	defaultRoute53BaseURL    = "https://route53.amazonaws.com/2013-04-01"
+	defaultAzureDNSBaseURL   = "https://management.azure.com"
+	defaultGoogleDNSBaseURL  = "https://dns.googleapis.com/dns/v1"
+	azureDNSAPIVersion       = "2018-05-01"
+	azureManagementScope     = "https://management.azure.com/.default"
+	googleDNSReadOnlyScope   = "https://www.googleapis.com/auth/ndev.clouddns.readonly"
+	defaultGoogleOAuthTokens = "https://oauth2.googleapis.com/token"
+
+	// cloudDNSThrottleDelay is how long a task throttled by a provider waits when the provider
+	// does not say with Retry-After
+	cloudDNSThrottleDelay = time.Minute
+)
+
+// getCloudJSON sends a GET request with a bearer token and decodes the JSON response into v
+func getCloudJSON(ctx context.Context, client *http.Client, endpoint, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cloudStatusError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// cloudStatusError returns the error of a provider response other than 200. A throttled request
+// is a rate-limit error, so the task is retried once the provider allows requests again.
+func cloudStatusError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	delay := retryAfter(resp.Header.Get("Retry-After"))
+	if delay <= 0 {
+		delay = cloudDNSThrottleDelay
+	}
+	return common.NewRateLimitError(fmt.Sprintf("throttled (status %d)", resp.StatusCode), delay, nil)
+}
+
+// route53Zones reads hosted zones with requests signed with AWS Signature Version 4
+type route53Zones struct {
+	baseURL     string
+	credentials utils.AWSCredentials
+	httpClient  *http.Client
+}
+
+func newRoute53Zones(credentials utils.AWSCredentials) *route53Zones {
+	return &route53Zones{baseURL: defaultRoute53BaseURL, credentials: credentials, httpClient: utils.NewTracingClient(cloudDNSTimeout)}
+}
+
+// getXML sends a signed GET request and decodes the XML response into v
+func (r *route53Zones) getXML(ctx context.Context, endpoint string, v interface{}) error {
+	if r.credentials.AccessKeyID == "" {
+		return errors.New("no AWS credentials configured")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	utils.SignAWSRequest(req, nil, r.credentials, "us-east-1", "route53", time.Now())
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return cloudStatusError(resp)
+	}
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+func (r *route53Zones) zones(ctx context.Context, source models.CloudDNSSource) ([]cloudDNSZone, error) {
+	var zones []cloudDNSZone
+	marker := ""
+	for {
+		endpoint := r.baseURL + "/hostedzone"
+		if marker != "" {
+			endpoint += "?marker=" + url.QueryEscape(marker)
+		}
+		var page struct {
+			HostedZones []struct {
+				ID          string `xml:"Id"`
+				Name        string `xml:"Name"`
+				PrivateZone bool   `xml:"Config>PrivateZone"`
+			} `xml:"HostedZones>HostedZone"`
+			IsTruncated bool   `xml:"IsTruncated"`
+			NextMarker  string `xml:"NextMarker"`
+		}
+		if err := r.getXML(ctx, endpoint, &page); err != nil {
+			return nil, err
+		}
+		for _, zone := range page.HostedZones {
+			if !zone.PrivateZone {
+				zones = append(zones, cloudDNSZone{ID: strings.TrimPrefix(zone.ID, "/hostedzone/"), Name: decodeRoute53Name(zone.Name)})
+			}
+		}
+		if !page.IsTruncated || page.NextMarker == "" {
+			return zones, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+func (r *route53Zones) records(ctx context.Context, source models.CloudDNSSource, zone cloudDNSZone) ([]models.CloudDNSRecord, error) {
+	var records []models.CloudDNSRecord
+	query := url.Values{}
+	for {
+		endpoint := r.baseURL + "/hostedzone/" + url.PathEscape(zone.ID) + "/rrset"
+		if len(query) > 0 {
+			endpoint += "?" + query.Encode()
+		}
+		var page struct {
+			RecordSets []struct {
+				Name   string   `xml:"Name"`
+				Type   string   `xml:"Type"`
+				TTL    int      `xml:"TTL"`
+				Values []string `xml:"ResourceRecords>ResourceRecord>Value"`
+				Alias  string   `xml:"AliasTarget>DNSName"`
+			} `xml:"ResourceRecordSets>ResourceRecordSet"`
+			IsTruncated          bool   `xml:"IsTruncated"`
+			NextRecordName       string `xml:"NextRecordName"`
+			NextRecordType       string `xml:"NextRecordType"`
+			NextRecordIdentifier string `xml:"NextRecordIdentifier"`
+		}
+		if err := r.getXML(ctx, endpoint, &page); err != nil {
+			return nil, err
+		}
+		for _, set := range page.RecordSets {
+			values := set.Values
+			if set.Alias != "" {
+				values = append(values, set.Alias)
+			}
+			records = append(records, models.CloudDNSRecord{
+				Name:     decodeRoute53Name(set.Name),
+				Type:     set.Type,
+				TTL:      set.TTL,
+				Values:   normalizeRecordValues(set.Type, values),
+				Provider: models.CloudDNSProviderRoute53,
+				Zone:     zone.Name,
+			})
+		}
+		if !page.IsTruncated {
+			return records, nil
+		}
+		query = url.Values{"name": {page.NextRecordName}, "type": {page.NextRecordType}}
+		if page.NextRecordIdentifier != "" {
+			query.Set("identifier", page.NextRecordIdentifier)
+		}
+	}
+}
+
+// decodeRoute53Name normalizes a Route 53 name, in which characters such as '*' are escaped as
+// backslash and three octal digits
+func decodeRoute53Name(name string) string {
+	var decoded strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if code, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				decoded.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		decoded.WriteByte(name[i])
+	}
+	return normalizeRecordName(decoded.String())
+}
+
+// azureZones reads DNS zones through the Azure Resource Manager API
+type azureZones struct {
+	baseURL    string
+	httpClient *http.Client
+	token      func(ctx context.Context) (string, error)
+}
+
+func newAzureZones() *azureZones {
+	zones := &azureZones{baseURL: defaultAzureDNSBaseURL, httpClient: utils.NewTracingClient(cloudDNSTimeout)}
+	zones.token = zones.defaultToken()
+	return zones
+}
+
+// defaultToken gets management tokens from the default Azure credential chain, created on first
+// use so workers without Azure credentials start as usual
+func (a *azureZones) defaultToken() func(ctx context.Context) (string, error) {
+	var (
+		once       sync.Once
+		credential *azidentity.DefaultAzureCredential
+		credErr    error
+	)
+	return func(ctx context.Context) (string, error) {
+		once.Do(func() { credential, credErr = azidentity.NewDefaultAzureCredential(nil) })
+		if credErr != nil {
+			return "", fmt.Errorf("failed to create Azure credential: %w", credErr)
+		}
+		token, err := credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureManagementScope}})
+		if err != nil {
+			return "", fmt.Errorf("failed to get Azure management token: %w", err)
+		}
+		return token.Token, nil
+	}
+}
+
+// list pages through an Azure list operation, decoding each page's value into add
+func (a *azureZones) list(ctx context.Context, endpoint string, add func(value json.RawMessage) error) error {
+	token, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+	for endpoint != "" {
+		var page struct {
+			Value    json.RawMessage `json:"value"`
+			NextLink string          `json:"nextLink"`
+		}
+		if err := getCloudJSON(ctx, a.httpClient, endpoint, token, &page); err != nil {
+			return err
+		}
+		if err := add(page.Value); err != nil {
+			return err
+		}
+		endpoint = page.NextLink
+	}
+	return nil
+}
+
+func (a *azureZones) zonesURL(source models.CloudDNSSource) string {
+	return a.baseURL + "/subscriptions/" + url.PathEscape(source.SubscriptionID) +
+		"/resourceGroups/" + url.PathEscape(source.ResourceGroup) + "/providers/Microsoft.Network/dnsZones"
+}
+
+func (a *azureZones) zones(ctx context.Context, source models.CloudDNSSource) ([]cloudDNSZone, error) {
+	var zones []cloudDNSZone
+	err := a.list(ctx, a.zonesURL(source)+"?api-version="+azureDNSAPIVersion, func(value json.RawMessage) error {
+		var page []struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(value, &page); err != nil {
+			return err
+		}
+		for _, zone := range page {
+			zones = append(zones, cloudDNSZone{ID: zone.Name, Name: normalizeRecordName(zone.Name)})
+		}
+		return nil
+	})
+	return zones, err
+}
+
+// azureRecordSet is a record set of the Azure DNS API; each record type has its own property
+type azureRecordSet struct {
+	Type       string `json:"type"` // e.g. "Microsoft.Network/dnszones/A"
+	Properties struct {
+		TTL         int                            `json:"TTL"`
+		FQDN        string                         `json:"fqdn"`
+		ARecords    []struct{ IPv4Address string } `json:"ARecords"`
+		AAAARecords []struct{ IPv6Address string } `json:"AAAARecords"`
+		CNAMERecord *struct{ CNAME string }        `json:"CNAMERecord"`
+		MXRecords   []struct{ Exchange string }    `json:"MXRecords"`
+		NSRecords   []struct{ NSDName string }     `json:"NSRecords"`
+		PTRRecords  []struct{ PTRDName string }    `json:"PTRRecords"`
+		SRVRecords  []struct{ Target string }      `json:"SRVRecords"`
+		TXTRecords  []struct{ Value []string }     `json:"TXTRecords"`
+	} `json:"properties"`
+}
+
+// values returns the values of the record set's type
+func (set *azureRecordSet) values() []string {
+	p := set.Properties
+	var values []string
+	for _, r := range p.ARecords {
+		values = append(values, r.IPv4Address)
+	}
+	for _, r := range p.AAAARecords {
+		values = append(values, r.IPv6Address)
+	}
+	if p.CNAMERecord != nil {
+		values = append(values, p.CNAMERecord.CNAME)
+	}
+	for _, r := range p.MXRecords {
+		values = append(values, r.Exchange)
+	}
+	for _, r := range p.NSRecords {
+		values = append(values, r.NSDName)
+	}
+	for _, r := range p.PTRRecords {
+		values = append(values, r.PTRDName)
+	}
+	for _, r := range p.SRVRecords {
+		values = append(values, r.Target)
+	}
+	for _, r := range p.TXTRecords {
+		values = append(values, strings.Join(r.Value, ""))
+	}
+	return values
+}
+
+func (a *azureZones) records(ctx context.Context, source models.CloudDNSSource, zone cloudDNSZone) ([]models.CloudDNSRecord, error) {
+	var records []models.CloudDNSRecord
+	endpoint := a.zonesURL(source) + "/" + url.PathEscape(zone.ID) + "/recordsets?api-version=" + azureDNSAPIVersion
+	err := a.list(ctx, endpoint, func(value json.RawMessage) error {
+		var page []azureRecordSet
+		if err := json.Unmarshal(value, &page); err != nil {
+			return err
+		}
+		for _, set := range page {
+			recordType := set.Type[strings.LastIndex(set.Type, "/")+1:]
+			records = append(records, models.CloudDNSRecord{
+				Name:     normalizeRecordName(set.Properties.FQDN),
+				Type:     recordType,
+				TTL:      set.Properties.TTL,
+				Values:   normalizeRecordValues(recordType, set.values()),
+				Provider: models.CloudDNSProviderAzure,
+				Zone:     zone.Name,
+			})
+		}
+		return nil
+	})
+	return records, err
+}
+
+// googleZones reads managed zones through the Cloud DNS API, signing in as a service account
+type googleZones struct {
+	baseURL    string
+	httpClient *http.Client
+	token      func(ctx context.Context) (string, error)
+}
+
+func newGoogleZones(serviceAccount []byte) *googleZones {
+	zones := &googleZones{baseURL: defaultGoogleDNSBaseURL, httpClient: utils.NewTracingClient(cloudDNSTimeout)}
+	zones.token = googleServiceAccountToken(serviceAccount)
+	return zones
+}
+
+// googleServiceAccountToken returns a source of read-only Cloud DNS tokens for a service account
+// JSON key, exchanging a signed JWT for each token
+func googleServiceAccountToken(serviceAccount []byte) func(ctx context.Context) (string, error) {
+	if len(serviceAccount) == 0 {
+		return func(ctx context.Context) (string, error) {
+			return "", errors.New("no Google Cloud service account configured")
+		}
+	}
+
+	var key struct {
+		ClientEmail  string `json:"client_email"`
+		PrivateKey   string `json:"private_key"`
+		PrivateKeyID string `json:"private_key_id"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(serviceAccount, &key); err != nil || key.ClientEmail == "" || key.PrivateKey == "" {
+		return func(ctx context.Context) (string, error) {
+			return "", errors.New("invalid Google Cloud service account key")
+		}
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = defaultGoogleOAuthTokens
+	}
+	config := &jwt.Config{
+		Email:        key.ClientEmail,
+		PrivateKey:   []byte(key.PrivateKey),
+		PrivateKeyID: key.PrivateKeyID,
+		Scopes:       []string{googleDNSReadOnlyScope},
+		TokenURL:     key.TokenURI,
+	}
+	source := config.TokenSource(context.WithValue(context.Background(), oauth2.HTTPClient, utils.NewTracingClient(cloudDNSTimeout)))
+	return func(ctx context.Context) (string, error) {
+		token, err := source.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to get Google Cloud token: %w", err)
+		}
+		return token.AccessToken, nil
+	}
+}
+
+func (g *googleZones) projectURL(source models.CloudDNSSource) string {
+	return g.baseURL + "/projects/" + url.PathEscape(source.Project) + "/managedZones"
+}
+
+func (g *googleZones) zones(ctx context.Context, source models.CloudDNSSource) ([]cloudDNSZone, error) {
+	token, err := g.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []cloudDNSZone
+	pageToken := ""
+	for {
+		var page struct {
+			ManagedZones []struct {
+				Name       string `json:"name"`
+				DNSName    string `json:"dnsName"`
+				Visibility string `json:"visibility"`
+			} `json:"managedZones"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := getCloudJSON(ctx, g.httpClient, g.projectURL(source)+"?pageToken="+url.QueryEscape(pageToken), token, &page); err != nil {
+			return nil, err
+		}
+		for _, zone := range page.ManagedZones {
+			if zone.Visibility != "private" {
+				zones = append(zones, cloudDNSZone{ID: zone.Name, Name: normalizeRecordName(zone.DNSName)})
+			}
+		}
+		if page.NextPageToken == "" {
+			return zones, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+func (g *googleZones) records(ctx context.Context, source models.CloudDNSSource, zone cloudDNSZone) ([]models.CloudDNSRecord, error) {
+	token, err := g.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.CloudDNSRecord
+	pageToken := ""
+	for {
+		var page struct {
+			RRSets []struct {
+				Name    string   `json:"name"`
+				Type    string   `json:"type"`
+				TTL     int      `json:"ttl"`
+				RRDatas []string `json:"rrdatas"`
+			} `json:"rrsets"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		endpoint := g.projectURL(source) + "/" + url.PathEscape(zone.ID) + "/rrsets?pageToken=" + url.QueryEscape(pageToken)
+		if err := getCloudJSON(ctx, g.httpClient, endpoint, token, &page); err != nil {
+			return nil, err
+		}
+		for _, set := range page.RRSets {
+			records = append(records, models.CloudDNSRecord{
+				Name:     normalizeRecordName(set.Name),
+				Type:     set.Type,
+				TTL:      set.TTL,
+				Values:   normalizeRecordValues(set.Type, set.RRDatas),
+				Provider: models.CloudDNSProviderGoogle,
+				Zone:     zone.Name,
+			})
+		}
+		if page.NextPageToken == "" {
+			return records, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
@@ -0,0 +1,235 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// fakeZones serves fixed zones and records
+type fakeZones struct {
+	zoneList []cloudDNSZone
+	byZone   map[string][]models.CloudDNSRecord
+	err      error
+}
+
+func (f *fakeZones) zones(ctx context.Context, source models.CloudDNSSource) ([]cloudDNSZone, error) {
+	return f.zoneList, f.err
+}
+
+func (f *fakeZones) records(ctx context.Context, source models.CloudDNSSource, zone cloudDNSZone) ([]models.CloudDNSRecord, error) {
+	return f.byZone[zone.ID], nil
+}
+
+// TestCloudDNSScanner tests that the zones covering the domain are read and foreign records dropped
+func TestCloudDNSScanner(t *testing.T) {
+	scanner := NewCloudDNSScanner()
+	scanner.accounts = models.CloudDNSAccounts{"*": {models.CloudDNSProviderRoute53}}
+	scanner.providers[models.CloudDNSProviderRoute53] = &fakeZones{
+		zoneList: []cloudDNSZone{{ID: "Z1", Name: "example.com"}, {ID: "Z2", Name: "example.org"}, {ID: "Z3", Name: "dev.shop.example.com"}},
+		byZone: map[string][]models.CloudDNSRecord{
+			"Z1": {{Name: "www.example.com", Type: "A"}, {Name: "shop.example.com", Type: "CNAME"}, {Name: "example.com", Type: "NS"}},
+			"Z2": {{Name: "shop.example.org", Type: "A"}},
+			"Z3": {{Name: "api.dev.shop.example.com", Type: "A"}},
+		},
+	}
+
+	input := models.CloudDNSInput{Domain: "shop.example.com", Sources: []models.CloudDNSSource{{Provider: models.CloudDNSProviderRoute53}}}
+	result, err := scanner.Execute(context.Background(), nil, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	zone := result.(models.CloudDNSResult)
+	if len(zone.Zones) != 2 || zone.Zones[0] != "route53:example.com" || zone.Zones[1] != "route53:dev.shop.example.com" {
+		t.Errorf("Expected the parent and the delegated zone to be read, got %v", zone.Zones)
+	}
+	if len(zone.Records) != 2 || zone.Records[0].Name != "api.dev.shop.example.com" || zone.Records[1].Name != "shop.example.com" {
+		t.Errorf("Expected only the records of the domain, got %+v", zone.Records)
+	}
+
+	if _, err := scanner.Execute(context.Background(), nil, models.CloudDNSInput{Domain: "example.net", Sources: input.Sources}); err == nil {
+		t.Error("Expected a domain without a zone to fail")
+	}
+
+	scanner.providers[models.CloudDNSProviderRoute53] = &fakeZones{err: errors.New("access denied")}
+	_, err = scanner.Execute(context.Background(), nil, input)
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || !appErr.IsRetryable() {
+		t.Errorf("Expected a failing provider to be retried, got %v", err)
+	}
+
+	if _, err := scanner.Execute(context.Background(), nil, models.CloudDNSInput{Domain: "example.com"}); err == nil {
+		t.Error("Expected an input without sources to fail validation")
+	}
+
+	// Tenants only read the accounts they are bound to
+	scanner.accounts = models.CloudDNSAccounts{"acme": {"google:acme-dns"}}
+	_, err = scanner.Execute(context.Background(), &models.TaskContext{TenantID: "acme"}, input)
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeValidation {
+		t.Errorf("Expected a source outside the tenant's accounts to fail validation, got %v", err)
+	}
+}
+
+// TestRoute53Zones tests the signed, paginated hosted zone and record set listing
+func TestRoute53Zones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch {
+		case r.URL.Path == "/hostedzone":
+			w.Write([]byte(`<ListHostedZonesResponse><HostedZones>
+				<HostedZone><Id>/hostedzone/Z1</Id><Name>example.com.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>
+				<HostedZone><Id>/hostedzone/Z2</Id><Name>corp.example.com.</Name><Config><PrivateZone>true</PrivateZone></Config></HostedZone>
+				</HostedZones><IsTruncated>false</IsTruncated></ListHostedZonesResponse>`))
+		case r.URL.Path == "/hostedzone/Z1/rrset" && r.URL.Query().Get("name") == "":
+			w.Write([]byte(`<ListResourceRecordSetsResponse><ResourceRecordSets>
+				<ResourceRecordSet><Name>\052.example.com.</Name><Type>CNAME</Type><TTL>300</TTL><ResourceRecords><ResourceRecord><Value>lb.example.net.</Value></ResourceRecord></ResourceRecords></ResourceRecordSet>
+				</ResourceRecordSets><IsTruncated>true</IsTruncated><NextRecordName>www.example.com.</NextRecordName><NextRecordType>A</NextRecordType></ListResourceRecordSetsResponse>`))
+		case r.URL.Path == "/hostedzone/Z1/rrset":
+			w.Write([]byte(`<ListResourceRecordSetsResponse><ResourceRecordSets>
+				<ResourceRecordSet><Name>WWW.example.com.</Name><Type>A</Type><AliasTarget><DNSName>d1.cloudfront.net.</DNSName></AliasTarget></ResourceRecordSet>
+				</ResourceRecordSets><IsTruncated>false</IsTruncated></ListResourceRecordSetsResponse>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	zones := newRoute53Zones(utils.AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	zones.baseURL = server.URL
+	list, err := zones.zones(context.Background(), models.CloudDNSSource{})
+	if err != nil || len(list) != 1 || list[0].ID != "Z1" || list[0].Name != "example.com" {
+		t.Fatalf("Expected the public zone only, got %+v (%v)", list, err)
+	}
+
+	records, err := zones.records(context.Background(), models.CloudDNSSource{}, list[0])
+	if err != nil {
+		t.Fatalf("records failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Name != "*.example.com" || records[0].Values[0] != "lb.example.net" {
+		t.Fatalf("Expected the decoded wildcard record first, got %+v", records)
+	}
+	if records[1].Name != "www.example.com" || records[1].Values[0] != "d1.cloudfront.net." {
+		t.Errorf("Expected the alias target as the value, got %+v", records[1])
+	}
+
+	if _, err := newRoute53Zones(utils.AWSCredentials{}).zones(context.Background(), models.CloudDNSSource{}); err == nil {
+		t.Error("Expected listing without credentials to fail")
+	}
+}
+
+// TestAzureZones tests the paginated zone and record set listing of Azure DNS
+func TestAzureZones(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer azure-token" || r.URL.Query().Get("api-version") != azureDNSAPIVersion {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		base := "/subscriptions/00000000-0000-0000-0000-000000000001/resourceGroups/dns/providers/Microsoft.Network/dnsZones"
+		switch r.URL.Path {
+		case base:
+			w.Write([]byte(`{"value":[{"name":"example.com"}]}`))
+		case base + "/example.com/recordsets":
+			if r.URL.Query().Get("page") == "" {
+				w.Write([]byte(`{"value":[{"type":"Microsoft.Network/dnszones/A","properties":{"TTL":60,"fqdn":"www.example.com.","ARecords":[{"ipv4Address":"192.0.2.1"}]}}],
+					"nextLink":"` + server.URL + base + `/example.com/recordsets?api-version=` + azureDNSAPIVersion + `&page=2"}`))
+				return
+			}
+			w.Write([]byte(`{"value":[{"type":"Microsoft.Network/dnszones/CNAME","properties":{"TTL":60,"fqdn":"shop.example.com.","CNAMERecord":{"cname":"shops.example.net."}}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	zones := newAzureZones()
+	zones.baseURL = server.URL
+	zones.token = func(ctx context.Context) (string, error) { return "azure-token", nil }
+	source := models.CloudDNSSource{Provider: models.CloudDNSProviderAzure, SubscriptionID: "00000000-0000-0000-0000-000000000001", ResourceGroup: "dns"}
+
+	list, err := zones.zones(context.Background(), source)
+	if err != nil || len(list) != 1 || list[0].Name != "example.com" {
+		t.Fatalf("Expected one zone, got %+v (%v)", list, err)
+	}
+	records, err := zones.records(context.Background(), source, list[0])
+	if err != nil {
+		t.Fatalf("records failed: %v", err)
+	}
+	if len(records) != 2 || records[0].Type != "A" || records[0].Values[0] != "192.0.2.1" {
+		t.Fatalf("Expected the records of both pages, got %+v", records)
+	}
+	if records[1].Name != "shop.example.com" || records[1].Values[0] != "shops.example.net" {
+		t.Errorf("Expected a normalized CNAME record, got %+v", records[1])
+	}
+}
+
+// TestGoogleZones tests the paginated managed zone and record set listing of Cloud DNS
+func TestGoogleZones(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer google-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/projects/acme-dns/managedZones":
+			w.Write([]byte(`{"managedZones":[{"name":"public","dnsName":"example.com.","visibility":"public"},{"name":"internal","dnsName":"example.com.","visibility":"private"}]}`))
+		case "/projects/acme-dns/managedZones/public/rrsets":
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"rrsets":[{"name":"www.example.com.","type":"A","ttl":300,"rrdatas":["192.0.2.1"]}],"nextPageToken":"next"}`))
+				return
+			}
+			w.Write([]byte(`{"rrsets":[{"name":"mail.example.com.","type":"MX","ttl":300,"rrdatas":["10 mx.example.com."]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	zones := newGoogleZones(nil)
+	zones.baseURL = server.URL
+	source := models.CloudDNSSource{Provider: models.CloudDNSProviderGoogle, Project: "acme-dns"}
+	if _, err := zones.zones(context.Background(), source); err == nil {
+		t.Error("Expected listing without a service account to fail")
+	}
+
+	zones.token = func(ctx context.Context) (string, error) { return "google-token", nil }
+	list, err := zones.zones(context.Background(), source)
+	if err != nil || len(list) != 1 || list[0].ID != "public" {
+		t.Fatalf("Expected the public zone only, got %+v (%v)", list, err)
+	}
+	records, err := zones.records(context.Background(), source, list[0])
+	if err != nil || len(records) != 2 || records[1].Name != "mail.example.com" {
+		t.Errorf("Expected the records of both pages, got %+v (%v)", records, err)
+	}
+}
+
+// TestCloudDNSThrottling tests that a throttled provider call is retried after its Retry-After
+func TestCloudDNSThrottling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	zones := newGoogleZones(nil)
+	zones.baseURL = server.URL
+	zones.token = func(ctx context.Context) (string, error) { return "google-token", nil }
+	_, err := zones.zones(context.Background(), models.CloudDNSSource{Provider: models.CloudDNSProviderGoogle, Project: "acme-dns"})
+
+	err = classifyCloudDNSError(context.Background(), "failed to list google zones", err)
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeRateLimited || appErr.RetryAfter != 2*time.Minute {
+		t.Errorf("Expected a rate-limit error retried in 2m, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package scanners
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+const (
+	complianceFetchTimeout = 10 * time.Second // Per robots.txt or security.txt request
+	complianceFetchLimit   = 512 << 10        // Bytes read of a robots.txt or security.txt
+	complianceConcurrency  = 20               // Hosts whose policies are fetched at once
+)
+
+// complianceClient fetches robots.txt and security.txt files
+var complianceClient = utils.NewTracingClient(complianceFetchTimeout)
+
+// fetchCompliancePolicy fetches robots.txt and security.txt of the origins of the targets. Bare
+// hosts are tried over https and http; origins that do not answer are left out.
+func fetchCompliancePolicy(ctx context.Context, targets []string, userAgent string) *models.CompliancePolicy {
+	origins := make([]string, 0, len(targets))
+	seen := make(map[string]struct{})
+	for _, target := range targets {
+		for _, origin := range targetOrigins(target) {
+			if _, ok := seen[origin]; !ok {
+				seen[origin] = struct{}{}
+				origins = append(origins, origin)
+			}
+		}
+	}
+
+	policies := make([]*models.HostPolicy, len(origins))
+	semaphore := make(chan struct{}, complianceConcurrency)
+	var wg sync.WaitGroup
+	for i, origin := range origins {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			policies[i] = fetchHostPolicy(ctx, origin, userAgent)
+		}()
+	}
+	wg.Wait()
+
+	compliance := &models.CompliancePolicy{UserAgent: userAgent, Hosts: make([]models.HostPolicy, 0, len(origins))}
+	for _, policy := range policies {
+		if policy != nil {
+			compliance.Hosts = append(compliance.Hosts, *policy)
+		}
+	}
+	return compliance
+}
+
+// fetchHostPolicy fetches the policies of one origin, or returns nil when it does not answer
+func fetchHostPolicy(ctx context.Context, origin, userAgent string) *models.HostPolicy {
+	status, robots, err := fetchPolicyFile(ctx, origin+"/robots.txt", userAgent)
+	if err != nil {
+		return nil
+	}
+
+	policy := &models.HostPolicy{Origin: origin}
+	switch {
+	case status >= 200 && status < 300:
+		policy.Robots = true
+		policy.Allow, policy.Disallow = models.ParseRobots(robots, userAgent)
+	case status >= 500:
+		// RFC 9309: an unreachable robots.txt means nothing may be fetched
+		policy.Disallow = []string{"/"}
+	}
+
+	for _, path := range []string{"/.well-known/security.txt", "/security.txt"} {
+		status, content, err := fetchPolicyFile(ctx, origin+path, userAgent)
+		if err != nil || status < 200 || status >= 300 {
+			continue
+		}
+		if txt := models.ParseSecurityTxt(content); !txt.IsZero() {
+			policy.SecurityTxt = &txt
+			break
+		}
+	}
+	return policy
+}
+
+// fetchPolicyFile fetches a plain text policy file, returning its status and at most complianceFetchLimit bytes
+func fetchPolicyFile(ctx context.Context, rawURL, userAgent string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := complianceClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, complianceFetchLimit))
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// targetOrigins returns the origins a target may be fetched from: its own for a URL, https and
+// http for a bare host[:port]
+func targetOrigins(target string) []string {
+	if origin, _ := models.URLOrigin(target); origin != "" {
+		return []string{origin}
+	}
+	host := strings.ToLower(strings.TrimSpace(target))
+	if host == "" || strings.ContainsAny(host, "/?#") {
+		return nil
+	}
+	return []string{"https://" + host, "http://" + host}
+}
+
+// filterCompliantTargets drops the targets robots.txt disallows. A URL is judged by its path, a
+// bare host by "/" on the first of its origins that answered.
+func filterCompliantTargets(compliance *models.CompliancePolicy, targets []string) []string {
+	allowed := make([]string, 0, len(targets))
+	for _, target := range targets {
+		path := "/"
+		if _, urlPath := models.URLOrigin(target); urlPath != "" {
+			path = urlPath
+		}
+		if policy := targetPolicy(compliance, target); policy != nil && !policy.Allowed(path) {
+			compliance.Skipped = append(compliance.Skipped, target)
+			continue
+		}
+		allowed = append(allowed, target)
+	}
+	return allowed
+}
+
+// targetPolicy returns the policy of the first origin of a target that answered, or nil
+func targetPolicy(compliance *models.CompliancePolicy, target string) *models.HostPolicy {
+	for _, origin := range targetOrigins(target) {
+		if policy := compliance.Policy(origin); policy != nil {
+			return policy
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package scanners
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchCompliancePolicy(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			userAgent = r.UserAgent()
+			w.Write([]byte("User-agent: *\nDisallow: /admin\n"))
+		case "/.well-known/security.txt":
+			w.Write([]byte("Contact: mailto:security@example.com\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	targets := []string{server.URL, server.URL + "/admin/login", server.URL + "/app"}
+	compliance := fetchCompliancePolicy(context.Background(), targets, "allsafe-asm")
+	if userAgent != "allsafe-asm" {
+		t.Errorf("robots.txt fetched as %q, want the compliance user agent", userAgent)
+	}
+	if len(compliance.Hosts) != 1 {
+		t.Fatalf("fetched %d host policies, want 1: %+v", len(compliance.Hosts), compliance.Hosts)
+	}
+	policy := compliance.Hosts[0]
+	if !policy.Robots || len(policy.Disallow) != 1 || policy.SecurityTxt == nil || policy.SecurityTxt.Contact[0] != "mailto:security@example.com" {
+		t.Errorf("host policy = %+v", policy)
+	}
+
+	allowed := filterCompliantTargets(compliance, targets)
+	if len(allowed) != 2 || len(compliance.Skipped) != 1 || compliance.Skipped[0] != server.URL+"/admin/login" {
+		t.Errorf("filterCompliantTargets() = %v, skipped %v", allowed, compliance.Skipped)
+	}
+}
+
+func TestFetchCompliancePolicy_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	compliance := fetchCompliancePolicy(context.Background(), []string{server.URL}, "allsafe-asm")
+	if allowed := filterCompliantTargets(compliance, []string{server.URL}); len(allowed) != 0 {
+		t.Errorf("a host whose robots.txt fails with 503 was allowed: %v", allowed)
+	}
+}
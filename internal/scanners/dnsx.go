@@ -2,7 +2,11 @@ package scanners
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"hash/fnv"
+	"net"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -10,10 +14,9 @@ import (
 	"github.com/allsafeASM/api/internal/azure"
 	"github.com/allsafeASM/api/internal/common"
 	"github.com/allsafeASM/api/internal/models"
-	"github.com/allsafeASM/api/internal/utils"
-	"github.com/projectdiscovery/dnsx/libs/dnsx"
+	"github.com/allsafeASM/api/internal/validation"
+	"github.com/miekg/dns"
 	"github.com/projectdiscovery/gologger"
-	"github.com/projectdiscovery/ratelimit"
 	"github.com/projectdiscovery/retryabledns"
 )
 
@@ -77,36 +80,160 @@ type DNSXScanner struct {
 	blobClient *azure.BlobStorageClient
 
 	// Optimized components
-	dnsClient   *dnsx.DNSX
-	clientOnce  sync.Once
-	clientMutex sync.RWMutex
+	clients         map[string]*retryabledns.Client // DNS clients keyed by settings, reused across tasks
+	clientMutex     sync.Mutex
+	defaultSettings models.DNSSettings
 
-	// Worker management
-	workerChan chan string
-	resultChan chan struct {
-		domain string
-		result models.ResolutionInfo
-	}
-	wgWorkers *sync.WaitGroup
-	wgResults *sync.WaitGroup
-	limiter   *ratelimit.Limiter
+	// Lookup workers shared by all runs, started on the first run with maxWorkers workers
+	pool *dnsWorkerPool
 
 	// Configuration
-	workerCount int
-	rateLimit   int
-	shardCount  int
+	maxWorkers   int // Size of the shared worker pool and ceiling for the per-run worker count
+	maxRateLimit int // Ceiling for the per-run queries per second
+	// Runs with more names than this stream their records to blob storage; 0 never streams
+	streamThreshold int
+	shardCount      int
+	cnameMaxDepth   int
+}
+
+const (
+	dnsWorkersPerCPU     = 50 // DNS lookups are I/O bound, so each CPU can drive many workers
+	dnsQueriesPerWorker  = 20 // Queries per second each worker is allowed to issue
+	dnsTargetsPerWorker  = 4  // Small runs get one worker per few targets
+	dnsMinWorkers        = 1
+	defaultDNSMaxWorkers = 200
+	defaultDNSMaxRate    = 2000
+)
+
+// dnsResolverPool is a named set of upstream resolvers
+type dnsResolverPool struct {
+	name      string
+	resolvers []string
+}
+
+// primaryResolvers answer the first resolution pass
+var primaryResolvers = dnsResolverPool{
+	name: "primary",
+	resolvers: []string{
+		"udp:1.1.1.1:53",         // Cloudflare
+		"udp:1.0.0.1:53",         // Cloudflare
+		"udp:8.8.8.8:53",         // Google
+		"udp:8.8.4.4:53",         // Google
+		"udp:9.9.9.9:53",         // Quad9
+		"udp:149.112.112.112:53", // Quad9
+		"udp:208.67.222.222:53",  // OpenDNS
+		"udp:208.67.220.220:53",  // OpenDNS
+		"udp:94.140.14.14:53",    // AdGuard
+		"udp:94.140.15.15:53",    // AdGuard
+	},
+}
+
+// retryResolvers answer the retry pass over TCP, which is not affected by UDP packet loss
+var retryResolvers = dnsResolverPool{
+	name: "retry",
+	resolvers: []string{
+		"tcp:1.1.1.1:53",        // Cloudflare
+		"tcp:8.8.8.8:53",        // Google
+		"tcp:9.9.9.10:53",       // Quad9 (unfiltered)
+		"tcp:149.112.112.10:53", // Quad9 (unfiltered)
+		"tcp:208.67.222.220:53", // OpenDNS
+		"tcp:208.67.220.222:53", // OpenDNS
+	},
+}
+
+// dnsxProgressInterval is how many resolved names pass between progress reports
+const dnsxProgressInterval = 500
+
+// dnsScaling holds the worker count and rate limit chosen for one run
+type dnsScaling struct {
+	Workers   int
+	RateLimit int
+}
+
+// scaleDNSWorkers sizes the worker pool and rate limit from the target count and available CPUs,
+// capped at the configured ceilings
+func scaleDNSWorkers(targets, cpus, maxWorkers, maxRateLimit int) dnsScaling {
+	workers := (targets + dnsTargetsPerWorker - 1) / dnsTargetsPerWorker
+	workers = min(workers, cpus*dnsWorkersPerCPU)
+	workers = min(workers, maxWorkers)
+	if workers < dnsMinWorkers {
+		workers = dnsMinWorkers
+	}
+
+	rateLimit := min(workers*dnsQueriesPerWorker, maxRateLimit)
+	if rateLimit < 1 {
+		rateLimit = 1
+	}
+
+	return dnsScaling{Workers: workers, RateLimit: rateLimit}
 }
 
 // NewDNSXScanner creates a new dnsx scanner with optimized defaults
 func NewDNSXScanner() *DNSXScanner {
+	hostsfile := true
 	return &DNSXScanner{
-		BaseScanner: NewBaseScanner(),
-		wgWorkers:   &sync.WaitGroup{},
-		wgResults:   &sync.WaitGroup{},
-		workerCount: 50,   // Default worker count
-		rateLimit:   1000, // Default rate limit per second
-		shardCount:  16,   // Number of shards for result map
+		BaseScanner:   NewBaseScanner(),
+		clients:       make(map[string]*retryabledns.Client),
+		pool:          newDNSWorkerPool(defaultDNSMaxWorkers),
+		maxWorkers:    defaultDNSMaxWorkers, // Worker ceiling, actual count scales with the targets
+		maxRateLimit:  defaultDNSMaxRate,    // Rate limit ceiling per second
+		shardCount:    16,                   // Number of shards for result map
+		cnameMaxDepth: 10,                   // Maximum number of CNAME hops to follow
+		defaultSettings: models.DNSSettings{
+			Retries:       1,                      // Reduced for speed
+			TimeoutMs:     3000,                   // Per-attempt timeout
+			QuestionTypes: []string{"A", "CNAME"}, // A, CNAME only
+			Hostsfile:     &hostsfile,
+		},
+	}
+}
+
+// SetDefaultSettings sets the DNS settings used when a task does not override them
+func (s *DNSXScanner) SetDefaultSettings(settings models.DNSSettings) {
+	s.defaultSettings = settings
+}
+
+// SetScalingLimits sets the ceilings for the adaptive worker count and rate limit. The shared
+// worker pool is resized to the new worker ceiling, even after it has started.
+func (s *DNSXScanner) SetScalingLimits(maxWorkers, maxRateLimit int) {
+	if maxWorkers > 0 {
+		s.maxWorkers = maxWorkers
+		s.pool.resize(maxWorkers)
 	}
+	if maxRateLimit > 0 {
+		s.maxRateLimit = maxRateLimit
+	}
+}
+
+// SetStreamThreshold sets the name count above which a run streams its records to blob storage
+func (s *DNSXScanner) SetStreamThreshold(threshold int) {
+	s.streamThreshold = threshold
+}
+
+// dnsQuery holds the client, question types and resolver health used by one DNSX run
+type dnsQuery struct {
+	client        *retryabledns.Client
+	questionTypes []uint16
+	health        *resolverHealth
+}
+
+// query resolves a name with the run's question types on a healthy resolver
+func (q dnsQuery) query(host string) (*retryabledns.DNSData, error) {
+	if q.health == nil || len(q.health.resolvers) == 0 {
+		return q.client.QueryMultiple(host, q.questionTypes)
+	}
+
+	state := q.health.pick()
+	dnsData, err := q.client.QueryMultipleWithResolver(host, q.questionTypes, state.resolver)
+
+	// Only failures the resolver is responsible for count against its health
+	status := models.DNSStatusResolved
+	if err != nil || dnsData == nil || dnsData.StatusCode == dns.RcodeToString[dns.RcodeRefused] {
+		status = classifyDNSFailure(dnsData, err)
+	}
+	q.health.record(state, status)
+
+	return dnsData, err
 }
 
 // SetBlobClient sets the blob client for the DNSX scanner
@@ -126,7 +253,7 @@ func (s *DNSXScanner) ValidateInput(input models.ScannerInput) error {
 	return s.BaseScanner.ValidateInput(input)
 }
 
-func (s *DNSXScanner) Execute(ctx context.Context, input interface{}) (models.ScannerResult, error) {
+func (s *DNSXScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
 	// Type assert to the specific input type we expect
 	dnsxInput, ok := input.(models.DNSXInput)
 	if !ok {
@@ -138,7 +265,7 @@ func (s *DNSXScanner) Execute(ctx context.Context, input interface{}) (models.Sc
 		return nil, err
 	}
 
-	gologger.Info().Msgf("Starting DNS resolution for domain: %s", dnsxInput.Domain)
+	taskCtx.Info().Msgf("Starting DNS resolution for domain: %s", dnsxInput.Domain)
 
 	// Check if context is cancelled
 	select {
@@ -147,8 +274,15 @@ func (s *DNSXScanner) Execute(ctx context.Context, input interface{}) (models.Sc
 	default:
 	}
 
+	// Resolve the effective DNS settings for this task
+	settings := s.defaultSettings.Merge(dnsxInput.Settings)
+	if err := s.validator.ValidateDNSSettings(settings); err != nil {
+		return nil, err
+	}
+
 	// Initialize components if needed
-	if err := s.initializeComponents(); err != nil {
+	query, err := s.initializeComponents(settings, primaryResolvers)
+	if err != nil {
 		return nil, err
 	}
 
@@ -157,119 +291,192 @@ func (s *DNSXScanner) Execute(ctx context.Context, input interface{}) (models.Sc
 	if err != nil {
 		return nil, err
 	}
+	subdomainsToProcess, outOfScope := taskCtx.FilterScope(subdomainsToProcess)
+	if outOfScope > 0 {
+		taskCtx.Warning().Msgf("Dropped %d names outside the scope of %s", outOfScope, dnsxInput.Domain)
+	}
+	excluder, err := dnsxInput.Exclude.Excluder()
+	if err != nil {
+		return nil, common.NewValidationError("exclude", err.Error())
+	}
+	subdomainsToProcess, excluded := excluder.Filter(subdomainsToProcess)
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d names of %s from resolution", excluded, dnsxInput.Domain)
+	}
 
 	if len(subdomainsToProcess) == 0 {
+		// A resumed scan may have nothing left to resolve
+		if len(dnsxInput.SkipTargets) > 0 {
+			gologger.Info().Msgf("All subdomains for %s were already resolved by a previous run", dnsxInput.Domain)
+			return models.DNSXResult{Domain: dnsxInput.Domain, Records: map[string]models.ResolutionInfo{}}, nil
+		}
 		return nil, common.NewValidationError("subdomains", "no subdomains provided for DNS resolution")
 	}
 
-	gologger.Debug().Msgf("Processing %d subdomains for DNS resolution", len(subdomainsToProcess))
+	// Size the worker pool for this run
+	scaling := scaleDNSWorkers(len(subdomainsToProcess), runtime.NumCPU(), s.maxWorkers, s.maxRateLimit)
+	gologger.Debug().Msgf("Processing %d subdomains for DNS resolution with %d workers at %d queries/s",
+		len(subdomainsToProcess), scaling.Workers, scaling.RateLimit)
+
+	// Large runs stream their records to blob storage instead of holding them all in memory
+	var stream *dnsRecordStream
+	if s.streamThreshold > 0 && len(subdomainsToProcess) > s.streamThreshold {
+		if stream, err = openDNSRecordStream(ctx, taskCtx, settings.UsesRetryPass()); err != nil {
+			taskCtx.Warning().Msgf("Cannot stream DNS records to blob storage, holding them in memory: %v", err)
+		}
+	}
+
+	// Execute DNS resolution. A streamed run only keeps the names the retry pass may recover.
+	var records map[string]models.ResolutionInfo
+	if stream != nil {
+		s.resolveOnPool(ctx, taskCtx, query, scaling, subdomainsToProcess, stream.record)
+		records = stream.failed
+	} else {
+		records = s.processDNSResolutionOptimized(ctx, taskCtx, query, scaling, subdomainsToProcess)
+	}
 
-	// Execute DNS resolution
-	records := s.processDNSResolutionOptimized(ctx, subdomainsToProcess)
+	resolverStats := query.health.Stats()
+
+	// Give names that failed transiently a second chance
+	if settings.UsesRetryPass() && ctx.Err() == nil {
+		retryStats, err := s.retryFailedLookups(ctx, settings, records)
+		if err != nil {
+			gologger.Warning().Msgf("DNS retry pass for %s failed, keeping first pass results: %v", dnsxInput.Domain, err)
+		}
+		resolverStats = append(resolverStats, retryStats...)
+	}
 
 	// Determine result domain
 	resultDomain := s.determineResultDomain(dnsxInput, subdomainsToProcess)
 
-	// Count subdomains with records
-	subdomainsWithRecords := 0
-	for _, record := range records {
-		if !s.hasNoRecords(record) {
-			subdomainsWithRecords++
+	// Create and return the result
+	result := models.DNSXResult{Domain: resultDomain}
+	if stream != nil {
+		stream.flush()
+		if err := stream.close(ctx); err != nil {
+			return nil, common.NewNetworkError("failed to store DNSX records", err)
 		}
+		result.RecordsBlob, result.RecordsCount, result.Metadata = stream.blobPath, stream.count, stream.summary.Metadata()
+		result.RecordsIndex = stream.indexPath
+		taskCtx.Info().Msgf("Streamed %d DNS records to %s", stream.count, stream.blobPath)
+	} else {
+		result.Records, result.Metadata = records, models.SummarizeDNSRecords(records)
 	}
+	result.Metadata.Workers = scaling.Workers
+	result.Metadata.RateLimit = scaling.RateLimit
+	result.Metadata.Settings = &settings
+	result.Metadata.ResolverHealth = resolverStats
 
-	gologger.Info().Msgf("DNS resolution completed for %s: %d records found across %d subdomains",
-		resultDomain, subdomainsWithRecords, len(records))
+	gologger.Info().Msgf("DNS resolution completed for %s: %d records found across %d subdomains (status counts: %v, avg RTT %dms)",
+		resultDomain, result.Metadata.StatusCounts[models.DNSStatusResolved], result.GetCount(), result.Metadata.StatusCounts, result.Metadata.AvgRTTMs)
 
-	// Create and return the result
-	result := models.DNSXResult{
-		Domain:  resultDomain,
-		Records: records,
+	// Workers stop on cancellation, so whatever was resolved so far is returned as a partial result
+	if ctx.Err() != nil {
+		result.Partial = true
+		gologger.Warning().Msgf("DNS resolution for %s was interrupted: returning partial results for %d/%d subdomains",
+			resultDomain, result.GetCount(), len(subdomainsToProcess))
+		return result, common.NewTimeoutError("DNSX execution cancelled", ctx.Err())
 	}
 
 	return result, nil
 }
 
-// initializeComponents initializes all optimized components
-func (s *DNSXScanner) initializeComponents() error {
-	// Get or create DNS client (connection pooling)
-	if _, err := s.getDNSClient(); err != nil {
-		return err
+// retryFailedLookups re-queries names that failed transiently using the retry resolvers and a
+// higher timeout, replacing their first pass results in place. It returns the health of the retry resolvers.
+func (s *DNSXScanner) retryFailedLookups(ctx context.Context, settings models.DNSSettings, records map[string]models.ResolutionInfo) ([]models.ResolverHealth, error) {
+	var failed []string
+	for subdomain, info := range records {
+		if info.IsTransientFailure() {
+			failed = append(failed, subdomain)
+		}
+	}
+	if len(failed) == 0 {
+		return nil, nil
 	}
 
-	// Initialize rate limiter
-	s.limiter = ratelimit.New(context.Background(), uint(s.rateLimit), time.Second)
+	retrySettings := retryPassSettings(settings)
+	query, err := s.initializeComponents(retrySettings, retryResolvers)
+	if err != nil {
+		return nil, err
+	}
+
+	scaling := scaleDNSWorkers(len(failed), runtime.NumCPU(), s.maxWorkers, s.maxRateLimit)
+	gologger.Info().Msgf("Retrying %d failed DNS lookups with alternate resolvers and a %dms timeout",
+		len(failed), retrySettings.TimeoutMs)
 
-	// Initialize channels with dynamic sizing (will be set in processDNSResolutionOptimized)
-	s.workerChan = nil
-	s.resultChan = nil
+	recovered := 0
+	for subdomain, info := range s.processDNSResolutionOptimized(ctx, nil, query, scaling, failed) {
+		info.Retried = true
+		if !info.IsTransientFailure() {
+			recovered++
+		}
+		records[subdomain] = info
+	}
 
-	return nil
+	gologger.Info().Msgf("DNS retry pass recovered %d/%d failed lookups", recovered, len(failed))
+	return query.health.Stats(), nil
 }
 
-// getDNSClient implements connection pooling for DNS client
-func (s *DNSXScanner) getDNSClient() (*dnsx.DNSX, error) {
-	s.clientMutex.RLock()
-	if s.dnsClient != nil {
-		defer s.clientMutex.RUnlock()
-		return s.dnsClient, nil
+// retryPassSettings returns the settings of the retry pass: a higher timeout and no hosts file,
+// which the first pass already consulted, so every retried name is answered by the retry resolvers
+func retryPassSettings(settings models.DNSSettings) models.DNSSettings {
+	hostsfile := false
+	settings.TimeoutMs = validation.DNSRetryTimeoutMs(settings.TimeoutMs)
+	settings.Hostsfile = &hostsfile
+	return settings
+}
+
+// initializeComponents initializes all optimized components for a run with the given settings and resolvers
+func (s *DNSXScanner) initializeComponents(settings models.DNSSettings, pool dnsResolverPool) (dnsQuery, error) {
+	// Get or create DNS client (connection pooling)
+	client, err := s.getDNSClient(settings, pool)
+	if err != nil {
+		return dnsQuery{}, err
 	}
-	s.clientMutex.RUnlock()
+
+	questionTypes := make([]uint16, 0, len(settings.QuestionTypes))
+	for _, questionType := range settings.QuestionTypes {
+		questionTypes = append(questionTypes, models.DNSQuestionTypes[strings.ToUpper(strings.TrimSpace(questionType))])
+	}
+
+	return dnsQuery{client: client, questionTypes: questionTypes, health: newResolverHealth(pool)}, nil
+}
+
+// getDNSClient implements connection pooling for DNS clients, keeping one client per resolver pool and distinct settings
+func (s *DNSXScanner) getDNSClient(settings models.DNSSettings, pool dnsResolverPool) (*retryabledns.Client, error) {
+	key := fmt.Sprintf("%s/%d/%d/%t", pool.name, settings.Retries, settings.TimeoutMs, settings.UsesHostsfile())
 
 	s.clientMutex.Lock()
 	defer s.clientMutex.Unlock()
 
-	// Double-check after acquiring write lock
-	if s.dnsClient != nil {
-		return s.dnsClient, nil
+	if client, ok := s.clients[key]; ok {
+		return client, nil
 	}
 
 	// Create new DNS client
-	dnsClient, err := s.createOptimizedDNSXClient()
+	client, err := s.createOptimizedDNSXClient(settings, pool)
 	if err != nil {
 		return nil, err
 	}
-	s.dnsClient = dnsClient
-	return s.dnsClient, nil
+	s.clients[key] = client
+	return client, nil
 }
 
-// createOptimizedDNSXClient creates a new DNSX client with enhanced optimizations
-func (s *DNSXScanner) createOptimizedDNSXClient() (*dnsx.DNSX, error) {
-	// Use ProjectDiscovery's default options as base
-	dnsxOptions := dnsx.DefaultOptions
-
-	// Enhanced resolver configuration for better performance
-	dnsxOptions.BaseResolvers = []string{
-		"udp:1.1.1.1:53",         // Cloudflare
-		"udp:1.0.0.1:53",         // Cloudflare
-		"udp:8.8.8.8:53",         // Google
-		"udp:8.8.4.4:53",         // Google
-		"udp:9.9.9.9:53",         // Quad9
-		"udp:149.112.112.112:53", // Quad9
-		"udp:208.67.222.222:53",  // OpenDNS
-		"udp:208.67.220.220:53",  // OpenDNS
-		"udp:94.140.14.14:53",    // AdGuard
-		"udp:94.140.15.15:53",    // AdGuard
+// createOptimizedDNSXClient creates a new DNS client with enhanced optimizations
+func (s *DNSXScanner) createOptimizedDNSXClient(settings models.DNSSettings, pool dnsResolverPool) (*retryabledns.Client, error) {
+	options := retryabledns.Options{
+		BaseResolvers: pool.resolvers,
+		MaxRetries:    settings.Retries,
+		Timeout:       time.Duration(settings.TimeoutMs) * time.Millisecond,
+		Hostsfile:     settings.UsesHostsfile(),
 	}
 
-	// Optimized settings for bulk processing
-	dnsxOptions.MaxRetries = 1                 // Reduced for speed
-	dnsxOptions.QuestionTypes = []uint16{1, 5} // A, CNAME only
-	dnsxOptions.Hostsfile = true
-	dnsxOptions.QueryAll = false // Disable for speed
-
-	dnsClient, err := dnsx.New(dnsxOptions)
+	client, err := retryabledns.NewWithOptions(options)
 	if err != nil {
 		return nil, common.NewScannerError("failed to create DNSX client", err)
 	}
-	return dnsClient, nil
-}
-
-// calculateBufferSizes calculates optimal buffer sizes based on workload
-func (s *DNSXScanner) calculateBufferSizes(subdomainCount int) (int, int) {
-	workerBuffer := min(subdomainCount, s.workerCount*4)
-	resultBuffer := min(subdomainCount, s.workerCount*2)
-	return workerBuffer, resultBuffer
+	client.TCPFallback = true // Retry truncated UDP answers over TCP
+	return client, nil
 }
 
 // min returns the minimum of two integers
@@ -312,6 +519,22 @@ func (s *DNSXScanner) collectSubdomains(ctx context.Context, dnsxInput models.DN
 		gologger.Debug().Msgf("Processing %d subdomains from combined sources", len(allSubdomains))
 	}
 
+	// 4. Drop subdomains a checkpointed run already resolved
+	if len(dnsxInput.SkipTargets) > 0 {
+		skip := make(map[string]struct{}, len(dnsxInput.SkipTargets))
+		for _, target := range dnsxInput.SkipTargets {
+			skip[target] = struct{}{}
+		}
+		remaining := allSubdomains[:0]
+		for _, subdomain := range allSubdomains {
+			if _, done := skip[subdomain]; !done {
+				remaining = append(remaining, subdomain)
+			}
+		}
+		gologger.Debug().Msgf("Skipping %d already resolved subdomains, %d remaining", len(allSubdomains)-len(remaining), len(remaining))
+		allSubdomains = remaining
+	}
+
 	return allSubdomains, nil
 }
 
@@ -319,137 +542,92 @@ func (s *DNSXScanner) collectSubdomains(ctx context.Context, dnsxInput models.DN
 func (s *DNSXScanner) readSubdomainsFromBlob(ctx context.Context, hostsFileLocation string) ([]string, error) {
 	gologger.Debug().Msgf("Reading hosts file from blob storage: %s", hostsFileLocation)
 
-	hostsFileContent, err := s.blobClient.ReadHostsFileFromBlob(ctx, hostsFileLocation)
+	hostsFile, err := s.blobClient.ReadHostsFileFromBlob(ctx, hostsFileLocation)
 	if err != nil {
 		return nil, common.NewScannerError("failed to read hosts file from blob storage", err)
 	}
 
-	return utils.ReadSubdomainsFromString(hostsFileContent), nil
+	return hostsFile.Hosts, nil
 }
 
-// processDNSResolutionOptimized processes DNS resolution using enhanced optimizations
-func (s *DNSXScanner) processDNSResolutionOptimized(ctx context.Context, subdomains []string) map[string]models.ResolutionInfo {
-	// Calculate optimal buffer sizes
-	workerBuffer, resultBuffer := s.calculateBufferSizes(len(subdomains))
-
-	// Initialize channels with optimal buffer sizes
-	s.workerChan = make(chan string, workerBuffer)
-	s.resultChan = make(chan struct {
-		domain string
-		result models.ResolutionInfo
-	}, resultBuffer)
-
-	// Use sharded result map for better concurrency
-	shardedResults := NewShardedResultMap(s.shardCount)
-
-	// Start result collector
-	s.wgResults.Add(1)
-	go func() {
-		defer s.wgResults.Done()
-		for result := range s.resultChan {
-			shardedResults.Set(result.domain, result.result)
-		}
-	}()
-
-	// Start workers
-	for i := 0; i < s.workerCount; i++ {
-		s.wgWorkers.Add(1)
-		go s.worker(ctx)
-	}
-
-	// Send work to workers
-	go func() {
-		defer close(s.workerChan)
-		for _, subdomain := range subdomains {
-			select {
-			case s.workerChan <- subdomain:
-			case <-ctx.Done():
-				return
-			}
-		}
-	}()
-
-	// Wait for all workers to complete
-	s.wgWorkers.Wait()
-	close(s.resultChan)
-	s.wgResults.Wait()
-
-	return shardedResults.GetAll()
+// processDNSResolutionOptimized resolves subdomains and returns their results by name
+func (s *DNSXScanner) processDNSResolutionOptimized(ctx context.Context, taskCtx *models.TaskContext, query dnsQuery, scaling dnsScaling, subdomains []string) map[string]models.ResolutionInfo {
+	results := NewShardedResultMap(s.shardCount)
+	s.resolveOnPool(ctx, taskCtx, query, scaling, subdomains, results.Set)
+	return results.GetAll()
 }
 
-// worker is the optimized worker function
-func (s *DNSXScanner) worker(ctx context.Context) {
-	defer s.wgWorkers.Done()
-
-	for subdomain := range s.workerChan {
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-
-		// Clean the subdomain
-		cleanSubdomain := strings.TrimSpace(subdomain)
-		if cleanSubdomain == "" {
-			continue
-		}
-
-		// Apply rate limiting
-		s.limiter.Take()
-
-		// Perform DNS lookup using optimized pattern
-		resolutionInfo := s.performOptimizedDNSLookup(cleanSubdomain)
-
-		// Send result
-		select {
-		case s.resultChan <- struct {
-			domain string
-			result models.ResolutionInfo
-		}{cleanSubdomain, resolutionInfo}:
-		case <-ctx.Done():
-			return
-		}
-	}
+// resolveOnPool resolves subdomains on the shared worker pool, starting it on the first run, and
+// hands each result to record
+func (s *DNSXScanner) resolveOnPool(ctx context.Context, taskCtx *models.TaskContext, query dnsQuery, scaling dnsScaling, subdomains []string, record func(string, models.ResolutionInfo)) {
+	s.pool.resolve(ctx, taskCtx, scaling, subdomains, func(subdomain string) models.ResolutionInfo {
+		return s.performOptimizedDNSLookup(query, subdomain)
+	}, record)
 }
 
 // performOptimizedDNSLookup performs DNS lookup using optimized pattern
-func (s *DNSXScanner) performOptimizedDNSLookup(subdomain string) models.ResolutionInfo {
+func (s *DNSXScanner) performOptimizedDNSLookup(query dnsQuery, subdomain string) models.ResolutionInfo {
 	resolutionInfo := models.ResolutionInfo{
-		Status: "resolved",
-	}
-
-	// Get DNS client from pool
-	dnsClient, err := s.getDNSClient()
-	if err != nil {
-		resolutionInfo.Status = "error"
-		return resolutionInfo
+		Status: models.DNSStatusResolved,
 	}
 
 	// Use QueryMultiple like ProjectDiscovery does
-	dnsData, err := dnsClient.QueryMultiple(subdomain)
-	if err != nil {
-		resolutionInfo.Status = "error"
-		return resolutionInfo
+	start := time.Now()
+	dnsData, err := query.query(subdomain)
+	resolutionInfo.RTTMs = time.Since(start).Milliseconds()
+
+	// Record the resolver that gave the last answer
+	if dnsData != nil && len(dnsData.Resolver) > 0 {
+		resolutionInfo.Resolver = dnsData.Resolver[len(dnsData.Resolver)-1]
 	}
 
-	// Skip nil responses (ProjectDiscovery pattern)
-	if dnsData == nil {
-		resolutionInfo.Status = "error"
+	if err != nil || dnsData == nil {
+		resolutionInfo.Status = classifyDNSFailure(dnsData, err)
 		return resolutionInfo
 	}
 
 	// Extract DNS records
 	s.extractDNSRecords(&resolutionInfo, dnsData)
 
-	// If no records found, mark as not resolved
+	// Follow CNAME chains to their terminal target for takeover analysis
+	if len(resolutionInfo.CNAME) > 0 {
+		s.followCNAMEChain(subdomain, &resolutionInfo, dnsData, query.query)
+	}
+
+	// If no records found, report why the name did not resolve
 	if s.hasNoRecords(resolutionInfo) {
-		resolutionInfo.Status = "not_resolved"
+		resolutionInfo.Status = classifyDNSFailure(dnsData, nil)
 	}
 
 	return resolutionInfo
 }
 
+// classifyDNSFailure maps a lookup without records to its resolution status
+func classifyDNSFailure(dnsData *retryabledns.DNSData, err error) models.DNSStatus {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return models.DNSStatusTimeout
+		}
+	}
+
+	if dnsData != nil {
+		switch dnsData.StatusCode {
+		case dns.RcodeToString[dns.RcodeNameError]:
+			return models.DNSStatusNXDomain
+		case dns.RcodeToString[dns.RcodeServerFailure]:
+			return models.DNSStatusServFail
+		case dns.RcodeToString[dns.RcodeRefused]:
+			return models.DNSStatusRefused
+		}
+	}
+
+	if err != nil {
+		return models.DNSStatusError
+	}
+	return models.DNSStatusNotResolved
+}
+
 // extractDNSRecords extracts DNS records from DNSX data
 func (s *DNSXScanner) extractDNSRecords(resolutionInfo *models.ResolutionInfo, dnsData *retryabledns.DNSData) {
 	if len(dnsData.A) > 0 {
@@ -459,11 +637,77 @@ func (s *DNSXScanner) extractDNSRecords(resolutionInfo *models.ResolutionInfo, d
 	if len(dnsData.CNAME) > 0 {
 		resolutionInfo.CNAME = dnsData.CNAME
 	}
+
+	resolutionInfo.AAAA = dnsData.AAAA
+	resolutionInfo.MX = dnsData.MX
+	resolutionInfo.NS = dnsData.NS
+	resolutionInfo.TXT = dnsData.TXT
+	resolutionInfo.SRV = dnsData.SRV
+	resolutionInfo.CAA = dnsData.CAA
+	resolutionInfo.PTR = dnsData.PTR
+}
+
+// followCNAMEChain walks the CNAME chain of a resolved name, recording every hop and flagging
+// chains that terminate in NXDOMAIN as dangling. Loops and chains deeper than cnameMaxDepth stop the walk.
+func (s *DNSXScanner) followCNAMEChain(subdomain string, resolutionInfo *models.ResolutionInfo, dnsData *retryabledns.DNSData, query func(string) (*retryabledns.DNSData, error)) {
+	seen := map[string]bool{normalizeDNSName(subdomain): true}
+	var chain []string
+
+	current := dnsData
+	for {
+		// Record the hops returned in this response, stopping on loops or excessive depth
+		stop := false
+		for _, target := range current.CNAME {
+			name := normalizeDNSName(target)
+			if seen[name] {
+				gologger.Debug().Msgf("CNAME loop detected for %s at %s", subdomain, name)
+				stop = true
+				break
+			}
+			if len(chain) >= s.cnameMaxDepth {
+				gologger.Debug().Msgf("CNAME chain for %s exceeds max depth %d", subdomain, s.cnameMaxDepth)
+				stop = true
+				break
+			}
+			seen[name] = true
+			chain = append(chain, name)
+		}
+
+		// A chain ending in a name that does not exist is a takeover candidate
+		if current.StatusCode == dns.RcodeToString[dns.RcodeNameError] {
+			resolutionInfo.Dangling = len(chain) > 0
+			break
+		}
+
+		// The chain is complete once addresses are returned or no new hop was added
+		if stop || len(current.A) > 0 || len(current.CNAME) == 0 || len(chain) == 0 {
+			break
+		}
+
+		next, err := query(chain[len(chain)-1])
+		if err != nil || next == nil {
+			gologger.Debug().Msgf("Failed to follow CNAME chain for %s at %s: %v", subdomain, chain[len(chain)-1], err)
+			break
+		}
+		if len(resolutionInfo.A) == 0 && len(next.A) > 0 {
+			resolutionInfo.A = next.A
+		}
+		current = next
+	}
+
+	resolutionInfo.CNAMEChain = chain
+}
+
+// normalizeDNSName lowercases a DNS name and strips the trailing root dot
+func normalizeDNSName(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
 }
 
 // hasNoRecords checks if no DNS records were found
 func (s *DNSXScanner) hasNoRecords(resolutionInfo models.ResolutionInfo) bool {
-	return len(resolutionInfo.A) == 0 && len(resolutionInfo.CNAME) == 0
+	return len(resolutionInfo.A) == 0 && len(resolutionInfo.AAAA) == 0 && len(resolutionInfo.CNAME) == 0 &&
+		len(resolutionInfo.MX) == 0 && len(resolutionInfo.NS) == 0 && len(resolutionInfo.TXT) == 0 &&
+		len(resolutionInfo.SRV) == 0 && len(resolutionInfo.CAA) == 0 && len(resolutionInfo.PTR) == 0
 }
 
 // determineResultDomain determines the domain to use for the result
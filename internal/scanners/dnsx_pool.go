@@ -0,0 +1,174 @@
+package scanners
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/allsafeASM/api/internal/models"
+	"golang.org/x/time/rate"
+)
+
+// dnsWorkerPool is a set of DNS lookup workers shared by every DNSX run of the worker process.
+// The workers start with the first run and stay up, so a run only pays for its own accounting
+// instead of starting and stopping a pool of goroutines and channels per task. The pool can be
+// resized at any time; workers beyond the new size stop once they finish their current lookup.
+type dnsWorkerPool struct {
+	lookups chan dnsLookup
+	quit    chan struct{} // Each value stops one worker
+
+	mu      sync.Mutex
+	size    int
+	started bool
+}
+
+// dnsLookup is one name a run queued on the pool
+type dnsLookup struct {
+	run  *dnsRun
+	name string
+}
+
+// dnsRun is the accounting of one resolution pass on the shared pool: its own rate limit, a cap
+// on its lookups in flight, where its results go and its progress
+type dnsRun struct {
+	ctx     context.Context
+	taskCtx *models.TaskContext
+	resolve func(name string) models.ResolutionInfo
+	record  func(name string, info models.ResolutionInfo) // Called from the workers as names resolve
+	limiter *rate.Limiter
+	slots   chan struct{} // Holds a token per lookup in flight, up to the run's worker count
+	pending sync.WaitGroup
+	total   int
+
+	mu   sync.Mutex // Orders progress reports
+	done int
+}
+
+// newDNSWorkerPool creates a pool of size workers; they start with the first run
+func newDNSWorkerPool(size int) *dnsWorkerPool {
+	return &dnsWorkerPool{
+		size:    size,
+		lookups: make(chan dnsLookup, size),
+		quit:    make(chan struct{}),
+	}
+}
+
+// resize changes the number of workers. Added workers start right away once the pool runs;
+// removed ones stop as they become idle, so lookups in flight are not interrupted.
+func (p *dnsWorkerPool) resize(size int) {
+	if size <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		for i := p.size; i < size; i++ {
+			go p.work()
+		}
+		if stop := p.size - size; stop > 0 {
+			go func() {
+				for i := 0; i < stop; i++ {
+					p.quit <- struct{}{}
+				}
+			}()
+		}
+	}
+	p.size = size
+}
+
+// workers starts the workers on the first run and returns the current pool size
+func (p *dnsWorkerPool) workers() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		p.started = true
+		for i := 0; i < p.size; i++ {
+			go p.work()
+		}
+	}
+	return p.size
+}
+
+// resolve looks up names on the pool with at most scaling.Workers lookups in flight and
+// scaling.RateLimit lookups per second, handing each result to record as it arrives. When ctx
+// ends no new lookups start, and resolve returns once the lookups in flight are recorded.
+func (p *dnsWorkerPool) resolve(ctx context.Context, taskCtx *models.TaskContext, scaling dnsScaling, names []string, resolve func(name string) models.ResolutionInfo, record func(name string, info models.ResolutionInfo)) {
+	size := p.workers()
+
+	run := &dnsRun{
+		ctx:     ctx,
+		taskCtx: taskCtx,
+		resolve: resolve,
+		record:  record,
+		limiter: newDNSRunLimiter(scaling.RateLimit),
+		slots:   make(chan struct{}, max(min(scaling.Workers, size), 1)),
+		total:   len(names),
+	}
+
+queue:
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		select {
+		case run.slots <- struct{}{}:
+		case <-ctx.Done():
+			break queue
+		}
+		run.pending.Add(1)
+		select {
+		case p.lookups <- dnsLookup{run: run, name: name}:
+		case <-ctx.Done():
+			run.finish()
+			break queue
+		}
+	}
+
+	run.pending.Wait()
+	run.taskCtx.ReportProgress("resolve", run.done, run.total)
+}
+
+// work resolves queued names until the pool is shrunk by this worker
+func (p *dnsWorkerPool) work() {
+	for {
+		select {
+		case lookup := <-p.lookups:
+			lookup.run.lookup(lookup.name)
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// newDNSRunLimiter allows rateLimit lookups per second, in bursts of up to a second's worth;
+// a limit of 0 or less does not limit lookups
+func newDNSRunLimiter(rateLimit int) *rate.Limiter {
+	if rateLimit <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+	return rate.NewLimiter(rate.Limit(rateLimit), rateLimit)
+}
+
+// lookup resolves a name within the run's rate limit and records the result. A lookup waiting
+// for the limit gives up when the run's context ends.
+func (r *dnsRun) lookup(name string) {
+	defer r.finish()
+	if err := r.limiter.Wait(r.ctx); err != nil {
+		return
+	}
+	r.record(name, r.resolve(name))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.done++; r.done%dnsxProgressInterval == 0 {
+		r.taskCtx.ReportProgress("resolve", r.done, r.total)
+	}
+}
+
+// finish releases the slot of a queued lookup
+func (r *dnsRun) finish() {
+	<-r.slots
+	r.pending.Done()
+}
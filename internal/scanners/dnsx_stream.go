@@ -0,0 +1,288 @@
+package scanners
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// dnsRecordRunLines bounds the records a stream holds in memory. A full run is sorted by host and
+// spilled to a temporary file, and the runs are merged into the records blob when it closes.
+const dnsRecordRunLines = 100000
+
+// dnsRecordUploadTimeout bounds writing the records blob. It is written once resolution ended,
+// which may be because the scanner context is done, so it does not use that context.
+const dnsRecordUploadTimeout = 10 * time.Minute
+
+// dnsRecordSpillPattern names the temporary files of spilled runs
+const dnsRecordSpillPattern = "dnsx-records-*.ndjson"
+
+// dnsRecordStream writes the records of a DNSX run to a gzipped NDJSON blob sorted by host,
+// keeping only counters, the current run and the names waiting for the retry pass in memory. The
+// blob is indexed so the API can read pages of it without downloading all of it.
+type dnsRecordStream struct {
+	mu        sync.Mutex
+	taskCtx   *models.TaskContext
+	name      string
+	blobPath  string
+	indexPath string
+	summary   *models.DNSSummary
+	count     int
+	run       []models.DNSRecordLine
+	runLines  int      // Records of a run before it is spilled
+	spills    []string // Temporary files of the spilled runs, each sorted by host
+	err       error
+
+	// Transient failures are held back for the retry pass instead of being written
+	holdFailures bool
+	failed       map[string]models.ResolutionInfo
+}
+
+// openDNSRecordStream starts the records of the task's current attempt. The blob is only created
+// when the stream closes, but the task must be able to stream artifacts from the start.
+func openDNSRecordStream(ctx context.Context, taskCtx *models.TaskContext, holdFailures bool) (*dnsRecordStream, error) {
+	if taskCtx == nil {
+		return nil, fmt.Errorf("no artifact store for the DNS records")
+	}
+	if _, ok := taskCtx.Artifacts.(models.ArtifactStreamer); !ok {
+		return nil, fmt.Errorf("artifact store cannot stream the DNS records")
+	}
+
+	return &dnsRecordStream{
+		taskCtx:      taskCtx,
+		name:         "records-" + models.AttemptBlobName(taskCtx.Attempt, ".ndjson.gz"),
+		summary:      models.NewDNSSummary(),
+		runLines:     dnsRecordRunLines,
+		holdFailures: holdFailures,
+		failed:       make(map[string]models.ResolutionInfo),
+	}, nil
+}
+
+// record writes the result of a name, or holds it back when it may succeed on retry
+func (s *dnsRecordStream) record(name string, info models.ResolutionInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.holdFailures && info.IsTransientFailure() {
+		s.failed[name] = info
+		return
+	}
+	s.write(name, info)
+}
+
+// flush writes the held back results, once the retry pass has replaced them
+func (s *dnsRecordStream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, info := range s.failed {
+		s.write(name, info)
+	}
+	s.failed = nil
+}
+
+// write adds a record to the current run, spilling the run once it is full; the first error
+// stops all further writes
+func (s *dnsRecordStream) write(name string, info models.ResolutionInfo) {
+	if s.err != nil {
+		return
+	}
+	s.summary.Add(info)
+	info.SortRecords()
+	s.run = append(s.run, models.DNSRecordLine{Host: name, ResolutionInfo: info})
+	s.count++
+	if len(s.run) >= s.runLines {
+		s.err = s.spill()
+	}
+}
+
+// spill sorts the current run and writes it to a temporary file
+func (s *dnsRecordStream) spill() error {
+	sortRecordLines(s.run)
+	file, err := os.CreateTemp("", dnsRecordSpillPattern)
+	if err != nil {
+		return fmt.Errorf("failed to spill DNS records: %w", err)
+	}
+	s.spills = append(s.spills, file.Name())
+
+	buffered := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffered)
+	for _, line := range s.run {
+		if err := encoder.Encode(line); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to spill DNS records: %w", err)
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to spill DNS records: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to spill DNS records: %w", err)
+	}
+	s.run = s.run[:0]
+	return nil
+}
+
+// close merges the runs into the records blob and stores its index, returning the first error
+// of the stream. The index only speeds up reading pages, so failing to store it is logged.
+func (s *dnsRecordStream) close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.removeSpills()
+
+	if s.err != nil {
+		return fmt.Errorf("failed to stream DNSX records: %w", s.err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), dnsRecordUploadTimeout)
+	defer cancel()
+	blob, blobPath, err := s.taskCtx.CreateArtifact(ctx, s.name)
+	if err != nil {
+		return fmt.Errorf("failed to stream DNSX records: %w", err)
+	}
+	s.blobPath = blobPath
+
+	writer := utils.NewIndexedNDJSONWriter(blob, utils.DefaultRecordsChunkLines)
+	err = s.merge(writer)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := blob.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stream DNSX records to %s: %w", s.blobPath, err)
+	}
+
+	index, err := json.Marshal(writer.Index())
+	if err == nil {
+		name := strings.TrimSuffix(s.name, ".ndjson.gz") + ".index.json"
+		s.indexPath, err = s.taskCtx.WriteArtifact(ctx, name, index)
+	}
+	if err != nil {
+		s.taskCtx.Warning().Msgf("Failed to store the index of %s, pages are read from its start: %v", s.blobPath, err)
+	}
+	return nil
+}
+
+// merge writes the records of the spilled runs and the current run in host order
+func (s *dnsRecordStream) merge(writer *utils.IndexedNDJSONWriter) error {
+	sortRecordLines(s.run)
+	sources := &recordSources{}
+	memory := s.run
+	sources.add(func() (models.DNSRecordLine, bool, error) {
+		if len(memory) == 0 {
+			return models.DNSRecordLine{}, false, nil
+		}
+		line := memory[0]
+		memory = memory[1:]
+		return line, true, nil
+	})
+	for _, path := range s.spills {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		decoder := json.NewDecoder(bufio.NewReader(file))
+		sources.add(func() (models.DNSRecordLine, bool, error) {
+			var line models.DNSRecordLine
+			if err := decoder.Decode(&line); err == io.EOF {
+				return line, false, nil
+			} else if err != nil {
+				return line, false, err
+			}
+			return line, true, nil
+		})
+	}
+	if sources.err != nil {
+		return sources.err
+	}
+
+	heap.Init(sources)
+	for sources.Len() > 0 {
+		if err := writer.Encode(sources.heads[0].line); err != nil {
+			return err
+		}
+		if sources.advance(); sources.err != nil {
+			return sources.err
+		}
+	}
+	return nil
+}
+
+// removeSpills deletes the temporary files of the spilled runs
+func (s *dnsRecordStream) removeSpills() {
+	for _, path := range s.spills {
+		os.Remove(path)
+	}
+	s.spills = nil
+	s.run = nil
+}
+
+// sortRecordLines sorts record lines by host
+func sortRecordLines(lines []models.DNSRecordLine) {
+	slices.SortFunc(lines, func(a, b models.DNSRecordLine) int { return strings.Compare(a.Host, b.Host) })
+}
+
+// recordSource returns the next line of a sorted run, or false once the run is exhausted
+type recordSource func() (models.DNSRecordLine, bool, error)
+
+// recordHead is the next line of a run
+type recordHead struct {
+	line models.DNSRecordLine
+	next recordSource
+}
+
+// recordSources is a heap of the next lines of sorted runs, smallest host first
+type recordSources struct {
+	heads []recordHead
+	err   error
+}
+
+// add reads the first line of a run and adds the run unless it is empty
+func (h *recordSources) add(next recordSource) {
+	line, ok, err := next()
+	if err != nil && h.err == nil {
+		h.err = err
+	}
+	if ok {
+		h.heads = append(h.heads, recordHead{line: line, next: next})
+	}
+}
+
+// advance replaces the smallest line with the next line of its run
+func (h *recordSources) advance() {
+	line, ok, err := h.heads[0].next()
+	switch {
+	case err != nil:
+		h.err = err
+	case ok:
+		h.heads[0].line = line
+		heap.Fix(h, 0)
+	default:
+		heap.Pop(h)
+	}
+}
+
+func (h *recordSources) Len() int           { return len(h.heads) }
+func (h *recordSources) Less(i, j int) bool { return h.heads[i].line.Host < h.heads[j].line.Host }
+func (h *recordSources) Swap(i, j int)      { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+func (h *recordSources) Push(x any)         { h.heads = append(h.heads, x.(recordHead)) }
+func (h *recordSources) Pop() any {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
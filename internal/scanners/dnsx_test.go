@@ -0,0 +1,389 @@
+package scanners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/validation"
+	"github.com/projectdiscovery/retryabledns"
+)
+
+// fakeDNSResponses builds a query function backed by a fixed set of responses
+func fakeDNSResponses(responses map[string]*retryabledns.DNSData) func(string) (*retryabledns.DNSData, error) {
+	return func(host string) (*retryabledns.DNSData, error) {
+		if data, ok := responses[host]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("no response for %s", host)
+	}
+}
+
+// TestFollowCNAMEChainResolved tests a multi-hop chain ending in A records
+func TestFollowCNAMEChainResolved(t *testing.T) {
+	scanner := NewDNSXScanner()
+
+	initial := &retryabledns.DNSData{StatusCode: "NOERROR", CNAME: []string{"app.cdn.example.net."}}
+	query := fakeDNSResponses(map[string]*retryabledns.DNSData{
+		"app.cdn.example.net": {StatusCode: "NOERROR", CNAME: []string{"edge.example.net"}, A: []string{"192.0.2.10"}},
+	})
+
+	info := models.ResolutionInfo{CNAME: initial.CNAME}
+	scanner.followCNAMEChain("www.example.com", &info, initial, query)
+
+	expected := []string{"app.cdn.example.net", "edge.example.net"}
+	if len(info.CNAMEChain) != len(expected) {
+		t.Fatalf("Expected chain %v, got %v", expected, info.CNAMEChain)
+	}
+	for i, hop := range expected {
+		if info.CNAMEChain[i] != hop {
+			t.Errorf("Expected hop '%s' at index %d, got '%s'", hop, i, info.CNAMEChain[i])
+		}
+	}
+	if info.Dangling {
+		t.Error("Expected chain ending in A records not to be dangling")
+	}
+	if len(info.A) != 1 || info.A[0] != "192.0.2.10" {
+		t.Errorf("Expected A records from the terminal hop, got %v", info.A)
+	}
+}
+
+// TestFollowCNAMEChainDangling tests that a chain ending in NXDOMAIN is flagged
+func TestFollowCNAMEChainDangling(t *testing.T) {
+	scanner := NewDNSXScanner()
+
+	initial := &retryabledns.DNSData{StatusCode: "NXDOMAIN", CNAME: []string{"old-app.azurewebsites.net"}}
+
+	info := models.ResolutionInfo{CNAME: initial.CNAME}
+	scanner.followCNAMEChain("shop.example.com", &info, initial, fakeDNSResponses(nil))
+
+	if !info.Dangling {
+		t.Error("Expected chain terminating in NXDOMAIN to be dangling")
+	}
+	if len(info.CNAMEChain) != 1 || info.CNAMEChain[0] != "old-app.azurewebsites.net" {
+		t.Errorf("Expected single hop chain, got %v", info.CNAMEChain)
+	}
+}
+
+// TestFollowCNAMEChainLoopAndDepth tests loop protection and the max depth limit
+func TestFollowCNAMEChainLoopAndDepth(t *testing.T) {
+	scanner := NewDNSXScanner()
+
+	// a -> b -> a loop
+	initial := &retryabledns.DNSData{StatusCode: "NOERROR", CNAME: []string{"b.example.com"}}
+	query := fakeDNSResponses(map[string]*retryabledns.DNSData{
+		"b.example.com": {StatusCode: "NOERROR", CNAME: []string{"a.example.com"}},
+	})
+
+	info := models.ResolutionInfo{CNAME: initial.CNAME}
+	scanner.followCNAMEChain("a.example.com", &info, initial, query)
+	if len(info.CNAMEChain) != 1 {
+		t.Errorf("Expected loop to stop after one hop, got %v", info.CNAMEChain)
+	}
+
+	// Endless chain is capped at cnameMaxDepth
+	scanner.cnameMaxDepth = 3
+	endless := func(host string) (*retryabledns.DNSData, error) {
+		return &retryabledns.DNSData{StatusCode: "NOERROR", CNAME: []string{"x" + host}}, nil
+	}
+	info = models.ResolutionInfo{}
+	scanner.followCNAMEChain("start.example.com", &info, &retryabledns.DNSData{CNAME: []string{"hop.example.com"}}, endless)
+	if len(info.CNAMEChain) != 3 {
+		t.Errorf("Expected chain capped at 3 hops, got %d: %v", len(info.CNAMEChain), info.CNAMEChain)
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// TestClassifyDNSFailure tests that failed lookups are mapped to distinct statuses
+func TestClassifyDNSFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		dnsData *retryabledns.DNSData
+		err     error
+		want    models.DNSStatus
+	}{
+		{"nxdomain", &retryabledns.DNSData{StatusCode: "NXDOMAIN"}, nil, models.DNSStatusNXDomain},
+		{"servfail", &retryabledns.DNSData{StatusCode: "SERVFAIL"}, nil, models.DNSStatusServFail},
+		{"refused", &retryabledns.DNSData{StatusCode: "REFUSED"}, nil, models.DNSStatusRefused},
+		{"no data", &retryabledns.DNSData{StatusCode: "NOERROR"}, nil, models.DNSStatusNotResolved},
+		{"timeout", &retryabledns.DNSData{}, errors.Join(retryabledns.ErrRetriesExceeded, timeoutError{}), models.DNSStatusTimeout},
+		{"other error", nil, fmt.Errorf("connection reset"), models.DNSStatusError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyDNSFailure(tt.dnsData, tt.err); got != tt.want {
+				t.Errorf("classifyDNSFailure() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSummarizeDNSRecords tests the per-scan status counters and RTT aggregates
+func TestSummarizeDNSRecords(t *testing.T) {
+	metadata := models.SummarizeDNSRecords(map[string]models.ResolutionInfo{
+		"a.example.com": {Status: models.DNSStatusResolved, Resolver: "udp:1.1.1.1:53", RTTMs: 10},
+		"b.example.com": {Status: models.DNSStatusNXDomain, Resolver: "udp:1.1.1.1:53", RTTMs: 20},
+		"c.example.com": {Status: models.DNSStatusTimeout, RTTMs: 60},
+	})
+
+	if metadata.Queries != 3 {
+		t.Errorf("Expected 3 queries, got %d", metadata.Queries)
+	}
+	if metadata.StatusCounts[models.DNSStatusNXDomain] != 1 || metadata.StatusCounts[models.DNSStatusTimeout] != 1 {
+		t.Errorf("Unexpected status counts: %v", metadata.StatusCounts)
+	}
+	if metadata.ResolverHits["udp:1.1.1.1:53"] != 2 {
+		t.Errorf("Expected 2 answers from 1.1.1.1, got %v", metadata.ResolverHits)
+	}
+	if metadata.AvgRTTMs != 30 || metadata.MaxRTTMs != 60 {
+		t.Errorf("Expected avg 30ms and max 60ms, got avg %dms and max %dms", metadata.AvgRTTMs, metadata.MaxRTTMs)
+	}
+}
+
+// TestScaleDNSWorkers tests that the worker pool scales with targets and CPUs within the ceilings
+func TestScaleDNSWorkers(t *testing.T) {
+	tests := []struct {
+		name                       string
+		targets, cpus, maxW, maxR  int
+		wantWorkers, wantRateLimit int
+	}{
+		{"single target", 1, 8, 200, 2000, 1, 20},
+		{"small run", 10, 8, 200, 2000, 3, 60},
+		{"cpu bound", 100000, 2, 200, 2000, 100, 2000},
+		{"worker ceiling", 100000, 16, 200, 2000, 200, 2000},
+		{"rate ceiling", 400, 8, 200, 500, 100, 500},
+		{"no targets", 0, 8, 200, 2000, 1, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := scaleDNSWorkers(tt.targets, tt.cpus, tt.maxW, tt.maxR)
+			if got.Workers != tt.wantWorkers || got.RateLimit != tt.wantRateLimit {
+				t.Errorf("scaleDNSWorkers() = %+v, want %d workers at %d/s", got, tt.wantWorkers, tt.wantRateLimit)
+			}
+		})
+	}
+}
+
+// TestSummarizeDNSRecordsRetryPass tests that retried and recovered names are counted
+func TestSummarizeDNSRecordsRetryPass(t *testing.T) {
+	metadata := models.SummarizeDNSRecords(map[string]models.ResolutionInfo{
+		"a.example.com": {Status: models.DNSStatusResolved, Retried: true},
+		"b.example.com": {Status: models.DNSStatusNXDomain, Retried: true},
+		"c.example.com": {Status: models.DNSStatusTimeout, Retried: true},
+		"d.example.com": {Status: models.DNSStatusServFail},
+	})
+
+	if metadata.Retried != 3 || metadata.Recovered != 2 {
+		t.Errorf("Expected 3 retried and 2 recovered names, got %d and %d", metadata.Retried, metadata.Recovered)
+	}
+}
+
+func TestRetryPassSettings(t *testing.T) {
+	hostsfile := true
+	settings := models.DNSSettings{Retries: 2, TimeoutMs: 3000, QuestionTypes: []string{"A"}, Hostsfile: &hostsfile}
+
+	retry := retryPassSettings(settings)
+	if retry.TimeoutMs != 6000 || retry.Retries != 2 || retry.UsesHostsfile() {
+		t.Errorf("retryPassSettings() = %+v, want a 6000ms timeout, 2 retries and no hosts file", retry)
+	}
+	if !settings.UsesHostsfile() || settings.TimeoutMs != 3000 {
+		t.Errorf("retryPassSettings() changed the first pass settings to %+v", settings)
+	}
+
+	settings.TimeoutMs = validation.MaxDNSTimeoutMs
+	if retry := retryPassSettings(settings); retry.TimeoutMs != validation.MaxDNSTimeoutMs {
+		t.Errorf("Retry timeout = %d, want it capped at %d", retry.TimeoutMs, validation.MaxDNSTimeoutMs)
+	}
+}
+
+func TestDNSWorkerPoolSharedRuns(t *testing.T) {
+	pool := newDNSWorkerPool(8)
+
+	// Runs sharing the pool keep their own results and stay within their own worker count
+	var wg sync.WaitGroup
+	for run := 0; run < 3; run++ {
+		wg.Add(1)
+		go func(run int) {
+			defer wg.Done()
+			var inFlight, peak atomic.Int32
+			names := syntheticSubdomains(50 + run)
+			results := NewShardedResultMap(4)
+			pool.resolve(context.Background(), nil, dnsScaling{Workers: 2, RateLimit: 100000}, names, func(name string) models.ResolutionInfo {
+				peak.Store(max(peak.Load(), inFlight.Add(1)))
+				time.Sleep(time.Millisecond)
+				inFlight.Add(-1)
+				return models.ResolutionInfo{Status: models.DNSStatusResolved, A: []string{name}}
+			}, results.Set)
+			records := results.GetAll()
+
+			if len(records) != len(names) {
+				t.Errorf("Run %d: expected %d records, got %d", run, len(names), len(records))
+			}
+			for name, info := range records {
+				if info.A[0] != name {
+					t.Errorf("Run %d: %s got the result of %s", run, name, info.A[0])
+				}
+			}
+			if peak.Load() > 2 {
+				t.Errorf("Run %d: expected at most 2 lookups in flight, got %d", run, peak.Load())
+			}
+		}(run)
+	}
+	wg.Wait()
+
+	// A cancelled run stops queueing lookups and returns what it resolved
+	ctx, cancel := context.WithCancel(context.Background())
+	var resolved atomic.Int32
+	results := NewShardedResultMap(4)
+	pool.resolve(ctx, nil, dnsScaling{Workers: 1, RateLimit: 100000}, syntheticSubdomains(100), func(name string) models.ResolutionInfo {
+		if resolved.Add(1) == 10 {
+			cancel()
+		}
+		return models.ResolutionInfo{Status: models.DNSStatusResolved}
+	}, results.Set)
+	records := results.GetAll()
+	if len(records) < 10 || len(records) > 12 {
+		t.Errorf("Expected the run to stop after about 10 lookups, got %d", len(records))
+	}
+}
+
+func TestDNSWorkerPoolResize(t *testing.T) {
+	// peakLookups runs 40 names with up to 8 lookups in flight and returns the most seen at once
+	peakLookups := func(pool *dnsWorkerPool) int32 {
+		var inFlight, peak atomic.Int32
+		var mu sync.Mutex
+		results := NewShardedResultMap(4)
+		pool.resolve(context.Background(), nil, dnsScaling{Workers: 8, RateLimit: 100000}, syntheticSubdomains(40), func(name string) models.ResolutionInfo {
+			current := inFlight.Add(1)
+			mu.Lock()
+			peak.Store(max(peak.Load(), current))
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			inFlight.Add(-1)
+			return models.ResolutionInfo{Status: models.DNSStatusResolved}
+		}, results.Set)
+		if got := len(results.GetAll()); got != 40 {
+			t.Errorf("Expected 40 records, got %d", got)
+		}
+		return peak.Load()
+	}
+
+	scanner := NewDNSXScanner()
+	scanner.SetScalingLimits(2, 0)
+	if peak := peakLookups(scanner.pool); peak > 2 {
+		t.Errorf("Expected at most 2 lookups in flight, got %d", peak)
+	}
+
+	// Limits set after the pool started take effect for the next runs
+	scanner.SetScalingLimits(6, 0)
+	if peak := peakLookups(scanner.pool); peak <= 2 || peak > 6 {
+		t.Errorf("Expected up to 6 lookups in flight after growing the pool, got %d", peak)
+	}
+	scanner.SetScalingLimits(1, 0)
+	if peak := peakLookups(scanner.pool); peak != 1 {
+		t.Errorf("Expected 1 lookup in flight after shrinking the pool, got %d", peak)
+	}
+	if scanner.maxWorkers != 1 || scanner.pool.workers() != 1 {
+		t.Errorf("Scanner ceiling %d and pool size %d, want both 1", scanner.maxWorkers, scanner.pool.workers())
+	}
+}
+
+// memoryArtifacts keeps streamed artifacts in memory
+type memoryArtifacts struct {
+	blobs map[string]*bytes.Buffer
+}
+
+func (m *memoryArtifacts) StoreArtifact(ctx context.Context, blobPath string, data []byte) error {
+	m.blobs[blobPath] = bytes.NewBuffer(data)
+	return nil
+}
+
+func (m *memoryArtifacts) StreamArtifact(ctx context.Context, blobPath string) (io.WriteCloser, error) {
+	m.blobs[blobPath] = &bytes.Buffer{}
+	return nopWriteCloser{m.blobs[blobPath]}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestDNSRecordStream(t *testing.T) {
+	artifacts := &memoryArtifacts{blobs: make(map[string]*bytes.Buffer)}
+	taskCtx := models.NewTaskContext(&models.TaskMessage{TenantID: "t1", Domain: "example.com", ScanID: 7, Task: models.TaskDNSResolve, Attempt: 2}, time.Time{})
+	taskCtx.Artifacts = artifacts
+
+	stream, err := openDNSRecordStream(context.Background(), taskCtx, true)
+	if err != nil {
+		t.Fatalf("openDNSRecordStream() error = %v", err)
+	}
+	stream.runLines = 1 // Every record is spilled, so the blob is merged from sorted runs
+
+	// Transient failures wait for the retry pass, definitive answers are written straight away
+	stream.record("www.example.com", models.ResolutionInfo{Status: models.DNSStatusResolved, A: []string{"192.0.2.1"}})
+	stream.record("old.example.com", models.ResolutionInfo{Status: models.DNSStatusNXDomain})
+	stream.record("api.example.com", models.ResolutionInfo{Status: models.DNSStatusTimeout})
+	if len(stream.failed) != 1 || stream.count != 2 {
+		t.Fatalf("Expected 1 held back and 2 written records, got %d and %d", len(stream.failed), stream.count)
+	}
+
+	stream.failed["api.example.com"] = models.ResolutionInfo{Status: models.DNSStatusResolved, A: []string{"192.0.2.2"}}
+	stream.flush()
+	if err := stream.close(context.Background()); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	if !strings.HasSuffix(stream.indexPath, "/artifacts/records-attempt-2.index.json") || artifacts.blobs[stream.indexPath] == nil {
+		t.Errorf("Expected the index next to the records, got %q", stream.indexPath)
+	}
+
+	blob, ok := artifacts.blobs[stream.blobPath]
+	if !ok || !strings.Contains(stream.blobPath, "/artifacts/records-") {
+		t.Fatalf("Expected the records under the task's artifacts, got %s", stream.blobPath)
+	}
+	gz, err := gzip.NewReader(blob)
+	if err != nil {
+		t.Fatalf("Records blob is not gzipped: %v", err)
+	}
+	hosts := make(map[string]models.ResolutionInfo)
+	var order []string
+	for decoder := json.NewDecoder(gz); ; {
+		var line models.DNSRecordLine
+		if err := decoder.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Failed to decode record line: %v", err)
+		}
+		hosts[line.Host] = line.ResolutionInfo
+		order = append(order, line.Host)
+	}
+	if len(hosts) != 3 || hosts["api.example.com"].Status != models.DNSStatusResolved {
+		t.Errorf("Expected 3 records with the retried result, got %v", hosts)
+	}
+	if !slices.IsSorted(order) {
+		t.Errorf("Expected the records sorted by host, got %v", order)
+	}
+	if stream.spills != nil {
+		t.Errorf("Expected the spilled runs to be removed, got %v", stream.spills)
+	}
+	if metadata := stream.summary.Metadata(); metadata.StatusCounts[models.DNSStatusResolved] != 2 {
+		t.Errorf("Expected 2 resolved names in the summary, got %v", metadata.StatusCounts)
+	}
+}
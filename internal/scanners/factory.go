@@ -2,14 +2,17 @@ package scanners
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/allsafeASM/api/internal/azure"
 	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/quota"
 )
 
 // ScannerFactory creates and manages scanner instances
 type ScannerFactory struct {
 	scanners   map[models.Task]models.Scanner
+	disabled   map[models.Task]error // Task types the self-test found unable to run, with the reason
 	blobClient *azure.BlobStorageClient
 }
 
@@ -17,11 +20,13 @@ type ScannerFactory struct {
 func NewScannerFactory() *ScannerFactory {
 	return &ScannerFactory{
 		scanners: map[models.Task]models.Scanner{
-			models.TaskSubfinder:  NewSubfinderScanner(),
-			models.TaskHttpx:      NewHttpxScanner(),
-			models.TaskDNSResolve: NewDNSXScanner(),
-			models.TaskNaabu:      NewNaabuScanner(nil), // Naabu scanner without blob client
-			models.TaskNuclei:     NewNucleiScanner(),
+			models.TaskSubfinder:      NewSubfinderScanner(),
+			models.TaskHttpx:          NewHttpxScanner(),
+			models.TaskDNSResolve:     NewDNSXScanner(),
+			models.TaskNaabu:          NewNaabuScanner(nil), // Naabu scanner without blob client
+			models.TaskNuclei:         NewNucleiScanner(),
+			models.TaskScopeExpansion: NewScopeExpansionScanner(),
+			models.TaskCloudDNS:       NewCloudDNSScanner(),
 		},
 	}
 }
@@ -45,11 +50,13 @@ func NewScannerFactoryWithBlobClient(blobClient *azure.BlobStorageClient) *Scann
 
 	return &ScannerFactory{
 		scanners: map[models.Task]models.Scanner{
-			models.TaskSubfinder:  NewSubfinderScanner(),
-			models.TaskHttpx:      httpxScanner,
-			models.TaskDNSResolve: dnsxScanner,
-			models.TaskNaabu:      naabuScanner,
-			models.TaskNuclei:     nucleiScanner,
+			models.TaskSubfinder:      NewSubfinderScanner(),
+			models.TaskHttpx:          httpxScanner,
+			models.TaskDNSResolve:     dnsxScanner,
+			models.TaskNaabu:          naabuScanner,
+			models.TaskNuclei:         nucleiScanner,
+			models.TaskScopeExpansion: NewScopeExpansionScanner(),
+			models.TaskCloudDNS:       NewCloudDNSScanner(),
 		},
 		blobClient: blobClient,
 	}
@@ -57,6 +64,9 @@ func NewScannerFactoryWithBlobClient(blobClient *azure.BlobStorageClient) *Scann
 
 // GetScanner returns a scanner for the given task type
 func (factory *ScannerFactory) GetScanner(taskType models.Task) (models.Scanner, error) {
+	if reason := factory.disabled[taskType]; reason != nil {
+		return nil, fmt.Errorf("task type %s is disabled on this worker: %w", taskType, reason)
+	}
 	scanner, exists := factory.scanners[taskType]
 	if !exists {
 		return nil, fmt.Errorf("no scanner found for task type: %s", taskType)
@@ -64,10 +74,77 @@ func (factory *ScannerFactory) GetScanner(taskType models.Task) (models.Scanner,
 	return scanner, nil
 }
 
-// GetAvailableScanners returns a list of available scanner names
+// SetDNSXDefaults sets the default DNS settings of the DNSX scanner
+func (factory *ScannerFactory) SetDNSXDefaults(settings models.DNSSettings) {
+	if dnsxScanner, ok := factory.scanners[models.TaskDNSResolve].(*DNSXScanner); ok {
+		dnsxScanner.SetDefaultSettings(settings)
+	}
+}
+
+// SetDNSXScalingLimits sets the worker and rate limit ceilings of the DNSX scanner
+func (factory *ScannerFactory) SetDNSXScalingLimits(maxWorkers, maxRateLimit int) {
+	if dnsxScanner, ok := factory.scanners[models.TaskDNSResolve].(*DNSXScanner); ok {
+		dnsxScanner.SetScalingLimits(maxWorkers, maxRateLimit)
+	}
+}
+
+// SetDNSXStreamThreshold sets the name count above which DNSX streams its records to blob storage
+func (factory *ScannerFactory) SetDNSXStreamThreshold(threshold int) {
+	if dnsxScanner, ok := factory.scanners[models.TaskDNSResolve].(*DNSXScanner); ok {
+		dnsxScanner.SetStreamThreshold(threshold)
+	}
+}
+
+// SetNucleiTemplateCacheDir sets the directory custom nuclei templates are synced to
+func (factory *ScannerFactory) SetNucleiTemplateCacheDir(dir string) {
+	if nucleiScanner, ok := factory.scanners[models.TaskNuclei].(*NucleiScanner); ok {
+		nucleiScanner.SetTemplateCacheDir(dir)
+	}
+}
+
+// NucleiTemplateCacheLock returns the lock of the custom nuclei template cache, or nil without nuclei
+func (factory *ScannerFactory) NucleiTemplateCacheLock() sync.Locker {
+	if nucleiScanner, ok := factory.scanners[models.TaskNuclei].(*NucleiScanner); ok {
+		return nucleiScanner.TemplateCacheLock()
+	}
+	return nil
+}
+
+// SetNucleiInteractsh sets the interactsh server used by OOB nuclei templates
+func (factory *ScannerFactory) SetNucleiInteractsh(serverURL, token string, disabled bool, allowedServers []string) {
+	if nucleiScanner, ok := factory.scanners[models.TaskNuclei].(*NucleiScanner); ok {
+		nucleiScanner.SetInteractsh(serverURL, token, disabled, allowedServers)
+	}
+}
+
+// SetQuotaTracker sets the tracker that limits the subfinder passive sources to their API key quotas
+func (factory *ScannerFactory) SetQuotaTracker(tracker *quota.Tracker) {
+	if subfinderScanner, ok := factory.scanners[models.TaskSubfinder].(*SubfinderScanner); ok {
+		subfinderScanner.SetQuotaTracker(tracker)
+	}
+}
+
+// SetPassiveStatsStore sets the store the subfinder passive source statistics are added to
+func (factory *ScannerFactory) SetPassiveStatsStore(store PassiveStatsStore) {
+	if subfinderScanner, ok := factory.scanners[models.TaskSubfinder].(*SubfinderScanner); ok {
+		subfinderScanner.SetPassiveStatsStore(store)
+	}
+}
+
+// SetCloudDNSCredentials sets the read-only credentials cloud_dns tasks read zones with
+func (factory *ScannerFactory) SetCloudDNSCredentials(credentials CloudDNSCredentials) {
+	if cloudDNSScanner, ok := factory.scanners[models.TaskCloudDNS].(*CloudDNSScanner); ok {
+		cloudDNSScanner.SetCredentials(credentials)
+	}
+}
+
+// GetAvailableScanners returns a list of available scanner names, leaving out disabled ones
 func (factory *ScannerFactory) GetAvailableScanners() []string {
 	var names []string
 	for taskType := range factory.scanners {
+		if factory.disabled[taskType] != nil {
+			continue
+		}
 		names = append(names, string(taskType))
 	}
 	return names
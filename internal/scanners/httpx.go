@@ -2,6 +2,11 @@ package scanners
 
 import (
 	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/allsafeASM/api/internal/azure"
 	"github.com/allsafeASM/api/internal/common"
@@ -11,6 +16,9 @@ import (
 	"github.com/projectdiscovery/httpx/runner"
 )
 
+// httpxProgressInterval is how many probed hosts pass between progress reports
+const httpxProgressInterval = 100
+
 // HttpxScanner implements the Scanner interface for httpx
 type HttpxScanner struct {
 	*BaseScanner
@@ -29,7 +37,7 @@ func (s *HttpxScanner) SetBlobClient(blobClient *azure.BlobStorageClient) {
 	s.blobClient = blobClient
 }
 
-func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.ScannerResult, error) {
+func (s *HttpxScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
 
 	// Type assert and validate input
 	httpxInput, ok := input.(models.HttpxInput)
@@ -49,16 +57,69 @@ func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.S
 	default:
 	}
 
-	gologger.Info().Msgf("Starting httpx scan for domain: %s", httpxInput.Domain)
+	taskCtx.Info().Msgf("Starting httpx scan for domain: %s", httpxInput.Domain)
 
 	if httpxInput.InputPath == "" {
 		return nil, common.NewValidationError("input_path", "InputPath is required and cannot be empty for httpx scanner")
 	}
 
+	targets, err := readInputTargets(httpxInput.InputPath)
+	if err != nil {
+		return nil, err
+	}
+	targets, excluded, err := filterExcluded(ctx, httpxInput.Exclude, targets)
+	if err != nil {
+		return nil, err
+	}
+	if excluded > 0 {
+		inputPath, err := writeInputTargets(targets)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(inputPath)
+		httpxInput.InputPath = inputPath
+		taskCtx.Info().Msgf("Excluded %d targets of %s from probing", excluded, httpxInput.Domain)
+	}
+
 	results := make([]models.HttpxHostResult, 0)
 	resultCh := make(chan models.HttpxHostResult, 1000)
 	doneCh := make(chan struct{})
 
+	// Host groups whose responses shift to a ban are skipped by the main pass. Aborted groups
+	// stay skipped; the remaining targets of backed-off groups are probed in a slow pass after it,
+	// so one group's backoff does not hold back the probes of the others. The runner is set
+	// before probing starts.
+	blocks := taskCtx.NewBlockDetector()
+	var httpxRunner *runner.Runner
+	var skippedGroups, probed sync.Map
+	observe := func(target string, status int, err error) {
+		probed.Store(target, true)
+		action := blocks.Observe(target, status, err)
+		if action == "" {
+			return
+		}
+		group := models.BlockGroup(target)
+		if _, skipped := skippedGroups.LoadOrStore(group, action); !skipped {
+			skipped := skipBlockedGroup(httpxRunner, targets, group)
+			if action == models.BlockActionBackoff {
+				taskCtx.Warning().Msgf("Host group %s looks banned, probing its %d remaining targets %s apart after the scan", group, skipped, taskCtx.BlockPolicy.Backoff)
+			} else {
+				taskCtx.Warning().Msgf("Host group %s looks banned, skipping its %d remaining targets", group, skipped)
+			}
+		}
+	}
+	// backedOffTargets returns the targets of backed-off groups that were not probed yet
+	backedOffTargets := func() []string {
+		var remaining []string
+		for _, target := range targets {
+			action, _ := skippedGroups.Load(models.BlockGroup(target))
+			if _, done := probed.Load(target); action == models.BlockActionBackoff && !done {
+				remaining = append(remaining, target)
+			}
+		}
+		return remaining
+	}
+
 	options := runner.Options{
 		TechDetect:          true,
 		FollowRedirects:     true,
@@ -69,13 +130,29 @@ func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.S
 		Version:             true,
 		Asn:                 true,
 		InputFile:           httpxInput.InputPath,
+		TlsImpersonate:      httpxInput.TLSFingerprint == models.TLSFingerprintRandom,
+		ZTLS:                httpxInput.TLSFingerprint == models.TLSFingerprintZTLS,
 		OnResult: func(r runner.Result) {
 			if r.Err != nil {
 				gologger.Debug().Msgf("httpx probe failed for %s: %v", r.Input, r.Err)
+				observe(r.Input, 0, r.Err)
 				return
 			}
+			observe(r.Input, r.StatusCode, nil)
+
+			if httpxInput.OnResponse != nil && r.Response != nil {
+				httpxInput.OnResponse(models.HTTPExchange{
+					URL:              r.URL,
+					StatusCode:       r.StatusCode,
+					ChainStatusCodes: r.ChainStatusCodes,
+					Request:          string(r.RequestRaw),
+					Response:         r.Response.Raw,
+					Headers:          r.Response.Headers,
+					BodyLength:       len(r.Response.Data),
+				})
+			}
 
-			resultCh <- models.HttpxHostResult{
+			hostResult := models.HttpxHostResult{
 				Host:          r.Input,
 				URL:           r.URL,
 				StatusCode:    r.StatusCode,
@@ -86,16 +163,27 @@ func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.S
 				Title:         r.Title,
 				ASN:           r.ASN.AsNumber,
 			}
+
+			// Don't block the runner once the collector has stopped reading
+			select {
+			case resultCh <- hostResult:
+			case <-ctx.Done():
+			}
 		},
 	}
 
 	gologger.Info().Msgf("Using input file for httpx: %s", httpxInput.InputPath)
+	fingerprint := ""
+	if httpxInput.TLSFingerprint != "" && httpxInput.TLSFingerprint != models.TLSFingerprintGo {
+		fingerprint = httpxInput.TLSFingerprint
+		taskCtx.Info().Msgf("Probing %s with the %s TLS fingerprint", httpxInput.Domain, fingerprint)
+	}
 
 	if err := options.ValidateOptions(); err != nil {
 		return nil, common.NewScannerError("invalid httpx options", err)
 	}
 
-	httpxRunner, err := runner.New(&options)
+	httpxRunner, err = runner.New(&options)
 	if err != nil {
 		return nil, common.NewScannerError("failed to create httpx runner", err)
 	}
@@ -107,8 +195,11 @@ func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.S
 
 	// Run in a goroutine so we can respect context cancellation
 	go func() {
+		defer close(doneCh)
 		httpxRunner.RunEnumeration()
-		close(doneCh)
+		if remaining := backedOffTargets(); len(remaining) > 0 {
+			s.probeBackedOff(ctx, taskCtx, options, remaining)
+		}
 	}()
 
 	// Collect results or handle context cancellation
@@ -117,17 +208,159 @@ func (s *HttpxScanner) Execute(ctx context.Context, input interface{}) (models.S
 		select {
 		case res := <-resultCh:
 			results = append(results, res)
+			if len(results)%httpxProgressInterval == 0 {
+				taskCtx.ReportProgress("probe", len(results), 0)
+			}
 		case <-doneCh:
 			collecting = false
 		case <-ctx.Done():
-			return nil, common.NewTimeoutError("httpx execution cancelled", ctx.Err())
+			// Keep anything already buffered and return what was probed so far
+			for drained := false; !drained; {
+				select {
+				case res := <-resultCh:
+					results = append(results, res)
+				default:
+					drained = true
+				}
+			}
+			gologger.Warning().Msgf("httpx scan for %s was interrupted: returning partial results for %d hosts", httpxInput.Domain, len(results))
+			probedTargets, answered := countAnswered(targets, results)
+			return models.HttpxResult{
+				Domain:         httpxInput.Domain,
+				Results:        results,
+				Partial:        true,
+				Probed:         probedTargets,
+				Answered:       answered,
+				TLSFingerprint: fingerprint,
+				Blocks:         blocks.Events(),
+			}, common.NewTimeoutError("httpx execution cancelled", ctx.Err())
 		}
 	}
 
-	return models.HttpxResult{
-		Domain:  httpxInput.Domain,
-		Results: results,
-	}, nil
+	probedTargets, answered := countAnswered(targets, results)
+	result := models.HttpxResult{
+		Domain:         httpxInput.Domain,
+		Results:        results,
+		Probed:         probedTargets,
+		Answered:       answered,
+		TLSFingerprint: fingerprint,
+		Blocks:         blocks.Events(),
+	}
+	// A banned scan is retried later; the probes it got through are kept with the failed result
+	if err := banError(taskCtx, "httpx", result.Blocks); err != nil {
+		return result, err
+	}
+	// Otherwise the targets of aborted groups were never probed
+	result.Partial = models.Aborted(result.Blocks)
+	return result, nil
+}
+
+// probeBackedOff probes the remaining targets of backed-off host groups one at a time, waiting the
+// policy's backoff before each request
+func (s *HttpxScanner) probeBackedOff(ctx context.Context, taskCtx *models.TaskContext, options runner.Options, targets []string) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(taskCtx.BlockPolicy.Backoff):
+	}
+
+	inputPath, err := writeInputTargets(targets)
+	if err != nil {
+		taskCtx.Warning().Msgf("Failed to write the %d targets of backed-off host groups: %v", len(targets), err)
+		return
+	}
+	defer os.Remove(inputPath)
+
+	options.InputFile = inputPath
+	options.Threads = 1
+	options.Delay = taskCtx.BlockPolicy.Backoff
+	slowRunner, err := runner.New(&options)
+	if err != nil {
+		taskCtx.Warning().Msgf("Failed to create the httpx runner for backed-off host groups: %v", err)
+		return
+	}
+	defer slowRunner.Close()
+
+	taskCtx.Info().Msgf("Probing %d targets of backed-off host groups %s apart", len(targets), options.Delay)
+	slowRunner.RunEnumeration()
+}
+
+// skipBlockedGroup makes httpx skip the targets of an aborted host group it has not probed yet by
+// marking their host:port pairs unresponsive in its host error cache, and returns how many targets
+// it marked. The cache holds 1000 entries, so part of a larger group may still be probed.
+func skipBlockedGroup(httpxRunner *runner.Runner, targets []string, group string) int {
+	if httpxRunner == nil || httpxRunner.HostErrorsCache == nil {
+		return 0
+	}
+	skipped := 0
+	for _, target := range targets {
+		if models.BlockGroup(target) != group {
+			continue
+		}
+		host := target
+		if _, rest, ok := strings.Cut(host, "://"); ok {
+			host = rest
+		}
+		if end := strings.IndexAny(host, "/?#"); end >= 0 {
+			host = host[:end]
+		}
+		hostPorts := []string{host}
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			hostPorts = []string{net.JoinHostPort(host, "443"), net.JoinHostPort(host, "80")}
+		}
+		for _, hostPort := range hostPorts {
+			// Any error count skips the host while HostMaxErrors is left at 0
+			_ = httpxRunner.HostErrorsCache.Set(hostPort, 1)
+		}
+		skipped++
+	}
+	return skipped
+}
+
+// readInputTargets reads the targets of an httpx input file, one per line
+func readInputTargets(inputPath string) ([]string, error) {
+	content, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, common.NewScannerError("failed to read httpx input file", err)
+	}
+	var targets []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if target := strings.TrimSpace(line); target != "" {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// countAnswered returns how many distinct targets were probed and how many of them answered. A
+// target answering on several URLs, or listed twice in the input, counts once.
+func countAnswered(targets []string, results []models.HttpxHostResult) (int, int) {
+	probed := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		probed[target] = false
+	}
+	answered := 0
+	for _, result := range results {
+		if seen, ok := probed[result.Host]; ok && !seen {
+			probed[result.Host] = true
+			answered++
+		}
+	}
+	return len(probed), answered
+}
+
+// writeInputTargets writes targets to a temp input file and returns its path
+func writeInputTargets(targets []string) (string, error) {
+	file, err := os.CreateTemp("", "httpx-included-*.txt")
+	if err != nil {
+		return "", common.NewScannerError("failed to create httpx input file", err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(strings.Join(targets, "\n") + "\n"); err != nil {
+		os.Remove(file.Name())
+		return "", common.NewScannerError("failed to write httpx input file", err)
+	}
+	return file.Name(), nil
 }
 
 func (s *HttpxScanner) GetName() string {
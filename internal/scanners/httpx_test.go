@@ -0,0 +1,20 @@
+package scanners
+
+import (
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestCountAnswered(t *testing.T) {
+	targets := []string{"a.example.com", "b.example.com", "a.example.com", "c.example.com"}
+	results := []models.HttpxHostResult{
+		{Host: "a.example.com", URL: "http://a.example.com"},
+		{Host: "a.example.com", URL: "https://a.example.com"},
+		{Host: "c.example.com", URL: "https://c.example.com"},
+	}
+	probed, answered := countAnswered(targets, results)
+	if probed != 3 || answered != 2 {
+		t.Errorf("countAnswered() = %d, %d, want 3 probed and 2 answered", probed, answered)
+	}
+}
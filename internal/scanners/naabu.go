@@ -3,6 +3,7 @@ package scanners
 import (
 	"context"
 	"fmt"
+	"maps"
 	"net"
 	"strconv"
 	"strings"
@@ -13,9 +14,10 @@ import (
 	"github.com/allsafeASM/api/internal/azure"
 	"github.com/allsafeASM/api/internal/common"
 	"github.com/allsafeASM/api/internal/models"
-	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/cdncheck"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/gologger/levels"
+	"github.com/projectdiscovery/naabu/v2/pkg/privileges"
 	"github.com/projectdiscovery/naabu/v2/pkg/result"
 	"github.com/projectdiscovery/naabu/v2/pkg/runner"
 )
@@ -23,7 +25,8 @@ import (
 // NaabuScanner implements the Scanner interface for naabu
 type NaabuScanner struct {
 	*BaseScanner
-	blobClient *azure.BlobStorageClient
+	blobClient  *azure.BlobStorageClient
+	connectOnly bool // Set by the self-test when the host cannot open raw sockets
 }
 
 // NewNaabuScanner creates a new naabu scanner
@@ -51,7 +54,7 @@ func (s *NaabuScanner) ValidateInput(input models.ScannerInput) error {
 	return s.BaseScanner.ValidateInput(input)
 }
 
-func (s *NaabuScanner) Execute(ctx context.Context, input interface{}) (models.ScannerResult, error) {
+func (s *NaabuScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
 	// Type assert to the specific input type we expect
 	naabuInput, ok := input.(models.NaabuInput)
 	if !ok {
@@ -63,7 +66,7 @@ func (s *NaabuScanner) Execute(ctx context.Context, input interface{}) (models.S
 		return nil, err
 	}
 
-	gologger.Info().Msgf("Starting naabu scan for domain: %s", naabuInput.Domain)
+	taskCtx.Info().Msgf("Starting naabu scan for domain: %s", naabuInput.Domain)
 
 	// Check if context is cancelled
 	select {
@@ -72,33 +75,87 @@ func (s *NaabuScanner) Execute(ctx context.Context, input interface{}) (models.S
 	default:
 	}
 
+	// A missing interface would only fail inside naabu, where it looks like a scanner fault
+	if naabuInput.Interface != "" {
+		if _, err := net.InterfaceByName(naabuInput.Interface); err != nil {
+			return nil, common.NewValidationError("interface", fmt.Sprintf("network interface %s not found on this worker", naabuInput.Interface))
+		}
+	}
+	if (naabuInput.SourcePort > 0 || naabuInput.SourceIP != "" || naabuInput.Interface != "") && !privileges.IsPrivileged {
+		gologger.Warning().Msgf("Naabu source port, source IP and interface only apply to SYN scans, which need raw socket privileges; falling back to connect scans without them")
+	}
+
 	// Collect and process IPs
 	ipsToProcess, err := s.collectIPs(ctx, naabuInput)
 	if err != nil {
 		return nil, err
 	}
+	ipsToProcess, excluded, err := filterExcluded(ctx, naabuInput.Exclude, ipsToProcess)
+	if err != nil {
+		return nil, err
+	}
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d IPs from port scanning", excluded)
+	}
 
 	if len(ipsToProcess) == 0 {
+		if excluded > 0 {
+			return nil, common.NewValidationError("exclude", "every IP to scan is excluded")
+		}
 		return nil, common.NewValidationError("ips", "no IPs provided for port scanning")
 	}
 
 	gologger.Debug().Msgf("Processing %d IPs for port scanning", len(ipsToProcess))
 	gologger.Debug().Msgf("IPs to be scanned: %v", ipsToProcess)
 
+	// Determine result domain
+	resultDomain := s.determineResultDomain(naabuInput, ipsToProcess)
+
+	// Drop hosts that do not answer discovery probes before the full port scan
+	ipsToScan := ipsToProcess
+	if naabuInput.PreFilter && s.connectOnly {
+		gologger.Warning().Msgf("Naabu host discovery needs raw sockets, scanning all %d IPs without the pre-filter", len(ipsToProcess))
+	} else if naabuInput.PreFilter {
+		liveIPs, err := s.discoverLiveHosts(ctx, naabuInput, ipsToProcess)
+		switch {
+		case ctx.Err() != nil:
+			return nil, common.NewTimeoutError("Naabu execution cancelled", ctx.Err())
+		case err != nil:
+			gologger.Warning().Msgf("Naabu host discovery pre-filter failed, scanning all %d IPs: %v", len(ipsToProcess), err)
+		default:
+			gologger.Info().Msgf("Naabu host discovery found %d/%d live IPs for %s", len(liveIPs), len(ipsToProcess), resultDomain)
+			ipsToScan = liveIPs
+		}
+		taskCtx.ReportProgress("discovery", len(ipsToProcess), len(ipsToProcess))
+	}
+
+	result := models.NaabuResult{
+		Domain:            resultDomain,
+		Ports:             map[string][]models.PortInfo{},
+		UnresponsiveHosts: len(ipsToProcess) - len(ipsToScan),
+	}
+	if len(ipsToScan) == 0 {
+		gologger.Info().Msgf("Naabu scan completed for %s: no live hosts to scan", resultDomain)
+		return result, nil
+	}
+
 	// Execute naabu scan using the library
-	ports, err := s.executeNaabuScan(ctx, naabuInput, ipsToProcess)
-	if err != nil {
+	taskCtx.ReportProgress("port_scan", 0, len(ipsToScan))
+	ports, err := s.scanWithCDNPorts(ctx, naabuInput, ipsToScan)
+	if err != nil && ctx.Err() == nil {
 		gologger.Error().Msgf("Naabu scan failed: %v", err)
 		return nil, err
 	}
+	result.Ports = ports
+	if ctx.Err() == nil {
+		taskCtx.ReportProgress("port_scan", len(ipsToScan), len(ipsToScan))
+	}
 
-	// Determine result domain
-	resultDomain := s.determineResultDomain(naabuInput, ipsToProcess)
-
-	// Create and return the result
-	result := models.NaabuResult{
-		Domain: resultDomain,
-		Ports:  ports,
+	// Naabu flushes the ports found so far when its context is cancelled, so keep them as a partial result
+	if ctx.Err() != nil {
+		result.Partial = true
+		gologger.Warning().Msgf("Naabu scan for %s was interrupted: returning partial results for %d IPs", resultDomain, len(ports))
+		return result, common.NewTimeoutError("Naabu execution cancelled", ctx.Err())
 	}
 
 	// Log summary
@@ -152,12 +209,12 @@ func (s *NaabuScanner) collectIPs(ctx context.Context, naabuInput models.NaabuIn
 func (s *NaabuScanner) readIPsFromBlob(ctx context.Context, hostsFileLocation string) ([]string, error) {
 	gologger.Debug().Msgf("Reading hosts file from blob storage: %s", hostsFileLocation)
 
-	hostsFileContent, err := s.blobClient.ReadHostsFileFromBlob(ctx, hostsFileLocation)
+	hostsFile, err := s.blobClient.ReadHostsFileFromBlob(ctx, hostsFileLocation)
 	if err != nil {
 		return nil, common.NewScannerError("failed to read hosts file from blob storage", err)
 	}
 
-	return utils.ReadIPsFromString(hostsFileContent), nil
+	return hostsFile.IPs(), nil
 }
 
 // deduplicateAndValidateIPs removes duplicates and validates IP addresses
@@ -181,16 +238,62 @@ func (s *NaabuScanner) deduplicateAndValidateIPs(ips []string) []string {
 	return uniqueIPs
 }
 
+// scanWithCDNPorts runs the port scan. With cdn_ports, CDN and WAF IPs are scanned on those ports
+// in a pass of their own instead of on naabu's fixed 80 and 443.
+func (s *NaabuScanner) scanWithCDNPorts(ctx context.Context, naabuInput models.NaabuInput, ips []string) (map[string][]models.PortInfo, error) {
+	if len(naabuInput.CDNPorts) == 0 {
+		return s.executeNaabuScan(ctx, naabuInput, ips)
+	}
+
+	cdnIPs, otherIPs := splitCDNIPs(cdncheck.New(), ips)
+	gologger.Debug().Msgf("Scanning %d CDN and WAF IPs on ports %v", len(cdnIPs), naabuInput.CDNPorts)
+	ports := map[string][]models.PortInfo{}
+	if len(otherIPs) > 0 {
+		found, err := s.executeNaabuScan(ctx, naabuInput, otherIPs)
+		maps.Copy(ports, found)
+		if err != nil {
+			return ports, err
+		}
+	}
+	if len(cdnIPs) > 0 {
+		cdnInput := naabuInput
+		cdnInput.Ports, cdnInput.PortRange, cdnInput.TopPorts = naabuInput.CDNPorts, "", ""
+		found, err := s.executeNaabuScan(ctx, cdnInput, cdnIPs)
+		maps.Copy(ports, found)
+		if err != nil {
+			return ports, err
+		}
+	}
+	return ports, nil
+}
+
+// splitCDNIPs separates the IPs of CDNs and WAFs from the rest the way naabu does, so cloud
+// provider IPs are scanned like any other
+func splitCDNIPs(client *cdncheck.Client, ips []string) (cdnIPs, otherIPs []string) {
+	for _, ip := range ips {
+		matched, _, itemType, err := client.Check(net.ParseIP(ip))
+		if err == nil && matched && itemType != "cloud" {
+			cdnIPs = append(cdnIPs, ip)
+		} else {
+			otherIPs = append(otherIPs, ip)
+		}
+	}
+	return cdnIPs, otherIPs
+}
+
+// applyNetworkOptions sets the source port, source IP and interface the probes leave from
+func applyNetworkOptions(options *runner.Options, naabuInput models.NaabuInput) {
+	if naabuInput.SourcePort > 0 {
+		options.SourcePort = strconv.Itoa(naabuInput.SourcePort)
+	}
+	options.SourceIP = naabuInput.SourceIP
+	options.Interface = naabuInput.Interface
+}
+
 // executeNaabuScan executes the naabu scan using the library following the official documentation pattern
 func (s *NaabuScanner) executeNaabuScan(ctx context.Context, naabuInput models.NaabuInput, ips []string) (map[string][]models.PortInfo, error) {
 	startTime := time.Now()
 
-	// Create result storage
-	ports := make(map[string][]models.PortInfo)
-	var resultMutex sync.Mutex
-	var processedIPs int32
-	var totalPortsFound int32
-
 	// Build naabu options following the official documentation pattern
 	options := runner.Options{
 		Host: ips,
@@ -282,56 +385,29 @@ func (s *NaabuScanner) executeNaabuScan(ctx context.Context, naabuInput models.N
 	}
 
 	// Performance optimizations
-	options.Silent = true             // Suppress banner and progress
-	options.Verbose = false           // Disable verbose output
-	options.Stream = false            // Disable streaming mode to ensure proper result capture
-	options.Passive = false           // Ensure active scanning
-	options.WithHostDiscovery = false // Skip host discovery for faster scanning
-	options.ScanType = "s"            // Use SYN scan for faster scanning (SynScan constant)
-	options.ExcludeCDN = true         // Exclude CDN IPs from the scan
-
-	// Set up the OnResult callback following the official documentation pattern
-	options.OnResult = func(hr *result.HostResult) {
-		gologger.Debug().Msgf("OnResult callback triggered for host: %s (IP: %s)", hr.Host, hr.IP)
-
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			gologger.Debug().Msgf("Context cancelled in OnResult callback")
-			return
-		default:
-		}
-
-		resultMutex.Lock()
-		defer resultMutex.Unlock()
-
-		// Use IP address as the key, fallback to Host if IP is empty
-		ip := hr.IP
-		if ip == "" {
-			ip = hr.Host
-		}
-
-		portsFound := len(hr.Ports)
-
-		atomic.AddInt32(&processedIPs, 1)
-		atomic.AddInt32(&totalPortsFound, int32(portsFound))
-
-		gologger.Debug().Msgf("Found %d open ports on %s", portsFound, ip)
+	options.Silent = true   // Suppress banner and progress
+	options.Verbose = false // Disable verbose output
+	options.Stream = false  // Disable streaming mode to ensure proper result capture
+	options.Passive = false // Ensure active scanning
+	options.ScanType = "s"  // Use SYN scan for faster scanning (SynScan constant)
+	if s.connectOnly {
+		options.ScanType = "c" // Connect scan, which needs no raw sockets (ConnectScan constant)
+	}
 
-		// Process all ports for this host
-		for _, port := range hr.Ports {
-			portInfo := models.PortInfo{
-				Port:     port.Port,
-				Protocol: port.Protocol.String(), // Use actual protocol from result
-			}
+	// CDN and WAF IPs only get ports 80 and 443 unless the input allows more
+	options.ExcludeCDN = !naabuInput.ScanCDN && len(naabuInput.CDNPorts) == 0
+	applyNetworkOptions(&options, naabuInput)
 
-			if ports[ip] == nil {
-				ports[ip] = []models.PortInfo{}
-			}
-			ports[ip] = append(ports[ip], portInfo)
-		}
+	// Host discovery is opt-in because it skips hosts that block the discovery probes
+	options.WithHostDiscovery = naabuInput.HostDiscovery && !s.connectOnly
+	if options.WithHostDiscovery {
+		applyDiscoveryProbes(&options, naabuInput.DiscoveryProbes)
 	}
 
+	// Set up the OnResult callback following the official documentation pattern
+	collector := newNaabuResultCollector()
+	options.OnResult = collector.onResult
+
 	gologger.Debug().Msgf("Starting naabu scan with %d IPs, threads: %d, rate: %d, timeout: %v, retries: %d",
 		len(ips), options.Threads, options.Rate, options.Timeout, options.Retries)
 
@@ -357,13 +433,13 @@ func (s *NaabuScanner) executeNaabuScan(ctx context.Context, naabuInput models.N
 
 	if err != nil {
 		gologger.Error().Msgf("Naabu enumeration failed: %v", err)
-		return nil, common.NewScannerError("naabu scan failed", err)
+		return collector.ports, common.NewScannerError("naabu scan failed", err)
 	}
 	gologger.Debug().Msgf("Naabu enumeration completed successfully")
 
 	duration := time.Since(startTime)
-	processedCount := atomic.LoadInt32(&processedIPs)
-	totalPorts := atomic.LoadInt32(&totalPortsFound)
+	processedCount := atomic.LoadInt32(&collector.hosts)
+	totalPorts := atomic.LoadInt32(&collector.portsFound)
 
 	gologger.Debug().Msgf("Naabu scan completed in %v, processed %d/%d IPs, found %d total open ports",
 		duration, processedCount, len(ips), totalPorts)
@@ -373,7 +449,143 @@ func (s *NaabuScanner) executeNaabuScan(ctx context.Context, naabuInput models.N
 		gologger.Warning().Msgf("No IPs were processed by OnResult callback - this might indicate an issue with result capture")
 	}
 
-	return ports, nil
+	return collector.ports, nil
+}
+
+// naabuResultCollector gathers the open ports naabu reports through its OnResult callback,
+// which naabu calls from its scan goroutines
+type naabuResultCollector struct {
+	mu         sync.Mutex
+	ports      map[string][]models.PortInfo // Keyed by IP
+	hosts      int32
+	portsFound int32
+}
+
+// newNaabuResultCollector creates an empty result collector
+func newNaabuResultCollector() *naabuResultCollector {
+	return &naabuResultCollector{ports: make(map[string][]models.PortInfo)}
+}
+
+// onResult records the open ports of a host
+func (c *naabuResultCollector) onResult(hr *result.HostResult) {
+	gologger.Debug().Msgf("OnResult callback triggered for host: %s (IP: %s)", hr.Host, hr.IP)
+
+	// Results are still recorded after cancellation: naabu reports what it found before
+	// stopping, and those ports are returned as a partial result
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Use IP address as the key, fallback to Host if IP is empty
+	ip := hr.IP
+	if ip == "" {
+		ip = hr.Host
+	}
+
+	portsFound := len(hr.Ports)
+
+	atomic.AddInt32(&c.hosts, 1)
+	atomic.AddInt32(&c.portsFound, int32(portsFound))
+
+	gologger.Debug().Msgf("Found %d open ports on %s", portsFound, ip)
+
+	// Process all ports for this host
+	for _, port := range hr.Ports {
+		portInfo := models.PortInfo{
+			Port:     port.Port,
+			Protocol: port.Protocol.String(), // Use actual protocol from result
+		}
+
+		if c.ports[ip] == nil {
+			c.ports[ip] = []models.PortInfo{}
+		}
+		c.ports[ip] = append(c.ports[ip], portInfo)
+	}
+}
+
+// discoverLiveHosts runs a discovery-only naabu pass and returns the IPs that answered a probe.
+// Discovery sends raw packets, so it fails without the privileges a SYN scan needs.
+func (s *NaabuScanner) discoverLiveHosts(ctx context.Context, naabuInput models.NaabuInput, ips []string) ([]string, error) {
+	var liveIPs []string
+	var resultMutex sync.Mutex
+
+	options := runner.Options{
+		Host:              ips,
+		TopPorts:          "100",
+		OnlyHostDiscovery: true,
+		WithHostDiscovery: true,
+		ScanType:          "s",
+		Silent:            true,
+		Rate:              1000,
+		Threads:           25,
+		Retries:           1,
+		Timeout:           3 * time.Second,
+		OnResult: func(hr *result.HostResult) {
+			ip := hr.IP
+			if ip == "" {
+				ip = hr.Host
+			}
+			resultMutex.Lock()
+			liveIPs = append(liveIPs, ip)
+			resultMutex.Unlock()
+		},
+	}
+	if naabuInput.RateLimit > 0 {
+		options.Rate = naabuInput.RateLimit
+	}
+	if naabuInput.Concurrency > 0 {
+		options.Threads = naabuInput.Concurrency
+	}
+	applyDiscoveryProbes(&options, naabuInput.DiscoveryProbes)
+	applyNetworkOptions(&options, naabuInput)
+
+	gologger.Debug().Msgf("Starting naabu host discovery for %d IPs", len(ips))
+
+	discoveryRunner, err := runner.NewRunner(&options)
+	if err != nil {
+		return nil, common.NewScannerError("failed to create naabu host discovery runner", err)
+	}
+	defer discoveryRunner.Close()
+
+	gologger.DefaultLogger.SetMaxLevel(levels.LevelFatal)
+	err = discoveryRunner.RunEnumeration(ctx)
+	gologger.DefaultLogger.SetMaxLevel(levels.LevelInfo)
+
+	if err != nil {
+		return nil, common.NewScannerError("naabu host discovery failed", err)
+	}
+
+	return s.deduplicateAndValidateIPs(liveIPs), nil
+}
+
+// applyDiscoveryProbes enables the requested host discovery probes; without any, naabu uses
+// ICMP echo and timestamp requests plus TCP SYN probes on ports 80 and 443
+func applyDiscoveryProbes(options *runner.Options, probes []string) {
+	for _, probe := range probes {
+		name, ports, _ := strings.Cut(strings.ToLower(strings.TrimSpace(probe)), ":")
+		switch name {
+		case "icmp-echo":
+			options.IcmpEchoRequestProbe = true
+		case "icmp-timestamp":
+			options.IcmpTimestampRequestProbe = true
+		case "icmp-address-mask":
+			options.IcmpAddressMaskRequestProbe = true
+		case "arp":
+			options.ArpPing = true
+		case "nd":
+			options.IPv6NeighborDiscoveryPing = true
+		case "tcp-syn", "tcp-ack":
+			if ports == "" {
+				ports = "80,443"
+			}
+			for _, port := range strings.Split(ports, ",") {
+				if name == "tcp-syn" {
+					options.TcpSynPingProbes = append(options.TcpSynPingProbes, strings.TrimSpace(port))
+				} else {
+					options.TcpAckPingProbes = append(options.TcpAckPingProbes, strings.TrimSpace(port))
+				}
+			}
+		}
+	}
 }
 
 // determineResultDomain determines the domain for the result
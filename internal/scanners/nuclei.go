@@ -1,8 +1,19 @@
 package scanners
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/allsafeASM/api/internal/azure"
@@ -12,27 +23,107 @@ import (
 	"github.com/projectdiscovery/gologger/levels"
 	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
 	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/contextargs"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/hosterrorscache"
+	"github.com/projectdiscovery/nuclei/v3/pkg/protocols/common/interactsh"
+	"github.com/projectdiscovery/nuclei/v3/pkg/types"
 )
 
+// nucleiCheckpointInterval is how often a resumable nuclei scan reports its progress
+const nucleiCheckpointInterval = time.Minute
+
 // NucleiScanner implements the Scanner interface for nuclei
 type NucleiScanner struct {
 	*BaseScanner
-	blobClient *azure.BlobStorageClient
+	blobClient      *azure.BlobStorageClient
+	templateCache   *templateCache
+	templateCatalog *templateCatalog
+
+	// Worker-wide interactsh settings for OOB templates
+	interactshServer   string
+	interactshToken    string
+	interactshDisabled bool
+	interactshAllowed  map[string]bool // Host names tasks may choose as their interactsh server
 }
 
 // NewNucleiScanner creates a new nuclei scanner
 func NewNucleiScanner() *NucleiScanner {
 	return &NucleiScanner{
-		BaseScanner: NewBaseScanner(),
+		BaseScanner:     NewBaseScanner(),
+		templateCache:   newTemplateCache(defaultTemplateCacheDir),
+		templateCatalog: newTemplateCatalog(),
 	}
 }
 
+// SetTemplateCacheDir sets the directory custom templates are synced to
+func (s *NucleiScanner) SetTemplateCacheDir(dir string) {
+	s.templateCache = newTemplateCache(dir)
+}
+
+// TemplateCacheLock returns the lock the template cache holds while it syncs custom templates
+func (s *NucleiScanner) TemplateCacheLock() sync.Locker {
+	return s.templateCache.lock()
+}
+
 // SetBlobClient sets the blob client for the Nuclei scanner
 func (s *NucleiScanner) SetBlobClient(blobClient *azure.BlobStorageClient) {
 	s.blobClient = blobClient
 }
 
-func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.ScannerResult, error) {
+// SetInteractsh sets the interactsh server used by OOB templates; an empty server keeps nuclei's public servers.
+// Tasks may only choose the worker's server or one of allowedServers.
+func (s *NucleiScanner) SetInteractsh(serverURL, token string, disabled bool, allowedServers []string) {
+	s.interactshServer = serverURL
+	s.interactshToken = token
+	s.interactshDisabled = disabled
+	s.interactshAllowed = make(map[string]bool, len(allowedServers))
+	for _, server := range allowedServers {
+		s.interactshAllowed[interactshHost(server)] = true
+	}
+}
+
+// checkInteractshServer rejects a task's interactsh server outside the allowlist, since OOB
+// interactions send data about the scanned hosts to that server
+func (s *NucleiScanner) checkInteractshServer(server string) error {
+	if server == "" || s.interactshDisabled || server == s.interactshServer || s.interactshAllowed[interactshHost(server)] {
+		return nil
+	}
+	return common.NewValidationError("interactsh_server", fmt.Sprintf("interactsh server %s is not allowed by NUCLEI_INTERACTSH_ALLOWED_SERVERS", server))
+}
+
+// interactshHost returns the lower-cased host name of an interactsh server given as a host name or URL
+func interactshHost(server string) string {
+	value := strings.TrimSpace(server)
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	if parsed, err := url.Parse(value); err == nil {
+		return strings.ToLower(parsed.Hostname())
+	}
+	return strings.ToLower(server)
+}
+
+// interactshOptions returns the interactsh settings of a scan. The worker's token is only sent
+// to the worker's own server, never to a server chosen by the task.
+func (s *NucleiScanner) interactshOptions(input models.NucleiInput) nuclei.InteractshOpts {
+	opts := nuclei.InteractshOpts(*interactsh.DefaultOptions(nil, nil, nil))
+	if s.interactshDisabled || input.DisableInteractsh {
+		opts.NoInteractsh = true
+		return opts
+	}
+
+	if s.interactshServer != "" {
+		opts.ServerURL = s.interactshServer
+		opts.Authorization = s.interactshToken
+	}
+	if input.InteractshServer != "" && input.InteractshServer != s.interactshServer {
+		opts.ServerURL = input.InteractshServer
+		opts.Authorization = ""
+	}
+	return opts
+}
+
+func (s *NucleiScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
 	// Type assert and validate input
 	nucleiInput, ok := input.(models.NucleiInput)
 	if !ok {
@@ -43,6 +134,9 @@ func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.
 	if err := s.ValidateInput(nucleiInput); err != nil {
 		return nil, err
 	}
+	if err := s.checkInteractshServer(nucleiInput.InteractshServer); err != nil {
+		return nil, err
+	}
 
 	// Check if context is cancelled
 	select {
@@ -51,10 +145,7 @@ func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.
 	default:
 	}
 
-	gologger.Info().Msgf("Starting nuclei scan for domain: %s with type: %s", nucleiInput.Domain, nucleiInput.Type)
-
-	// Set log level to fatal to reduce noise during nuclei execution
-	gologger.DefaultLogger.SetMaxLevel(levels.LevelFatal)
+	taskCtx.Info().Msgf("Starting nuclei scan for domain: %s with type: %s", nucleiInput.Domain, nucleiInput.Type)
 
 	var hosts []string
 	if nucleiInput.HostsFileLocation != "" {
@@ -62,33 +153,126 @@ func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.
 			return nil, common.NewValidationError("blob_client", "hosts file location provided but blob client is not initialized")
 		}
 		gologger.Debug().Msgf("Reading hosts file from blob storage: %s", nucleiInput.HostsFileLocation)
-		hostsFileContent, err := s.blobClient.ReadHostsFileFromBlob(ctx, nucleiInput.HostsFileLocation)
+		hostsFile, err := s.blobClient.ReadHostsFileFromBlob(ctx, nucleiInput.HostsFileLocation)
 		if err != nil {
 			return nil, common.NewScannerError("failed to read hosts file from blob storage", err)
 		}
-		for line := range strings.SplitSeq(hostsFileContent, "\n") {
-			clean := strings.TrimSpace(line)
-			if clean != "" {
-				hosts = append(hosts, clean)
-			}
-		}
+		hosts = hostsFile.Hosts
 		gologger.Debug().Msgf("Loaded %d hosts from blob storage", len(hosts))
+	} else if nucleiInput.Hosts != nil {
+		hosts = nucleiInput.Hosts
 	} else {
 		hosts = []string{nucleiInput.Domain}
 	}
 
+	hosts, outOfScope := taskCtx.FilterScope(hosts)
+	if outOfScope > 0 {
+		taskCtx.Warning().Msgf("Dropped %d nuclei targets outside the scope of %s", outOfScope, nucleiInput.Domain)
+	}
+	hosts, excluded, err := filterExcluded(ctx, nucleiInput.Exclude, hosts)
+	if err != nil {
+		return nil, err
+	}
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d nuclei targets of %s", excluded, nucleiInput.Domain)
+	}
+
+	// In compliance mode, targets whose robots.txt disallows them are not scanned
+	var compliance *models.CompliancePolicy
+	userAgent := nucleiInput.ComplianceUserAgent
+	if nucleiInput.Compliance {
+		if userAgent == "" {
+			userAgent = models.DefaultComplianceUserAgent
+		}
+		compliance = fetchCompliancePolicy(ctx, hosts, userAgent)
+		hosts = filterCompliantTargets(compliance, hosts)
+		taskCtx.Info().Msgf("Fetched robots.txt and security.txt of %d origins of %s, skipping %d disallowed targets",
+			len(compliance.Hosts), nucleiInput.Domain, len(compliance.Skipped))
+	}
+
 	if len(hosts) == 0 {
 		return models.NucleiResult{
 			Domain:          nucleiInput.Domain,
 			Vulnerabilities: []models.NucleiVulnerability{},
+			Partial:         compliance != nil && len(compliance.Skipped) > 0,
+			Compliance:      compliance,
 		}, nil
 	}
 
+	templates := []string{nucleiTemplatesDir}
+	if nucleiInput.TemplatesPrefix != "" {
+		if s.blobClient == nil {
+			return nil, common.NewValidationError("blob_client", "custom templates provided but blob client is not initialized")
+		}
+		customDir, err := s.templateCache.sync(ctx, s.blobClient, nucleiInput.TemplatesPrefix)
+		var appErr *common.AppError
+		if errors.As(err, &appErr) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, common.NewScannerError("failed to sync custom nuclei templates", err)
+		}
+		templates = append(templates, customDir)
+	}
+
+	// The tenant's template policy is evaluated before the scan starts, and the templates it
+	// excludes are reported in the result instead of being dropped silently
+	var policyReport *models.TemplatePolicyReport
+	var excludedIDs []string
+	if !nucleiInput.TemplatePolicy.IsZero() {
+		policyReport, excludedIDs, err = s.templateCatalog.evaluate(templates, nucleiInput.TemplatePolicy)
+		if err != nil {
+			return nil, common.NewScannerError("failed to evaluate the nuclei template policy", err)
+		}
+		if len(excludedIDs) > 0 {
+			taskCtx.Warning().Msgf("Nuclei template policy of %s excludes %d of %d templates: %s", nucleiInput.Domain,
+				len(excludedIDs), policyReport.Templates, policyReport.RuleCounts())
+		}
+		for _, violation := range policyReport.Violations {
+			taskCtx.Debug().Msgf("Nuclei template %s excluded by policy rule %s", violation.TemplateID, violation.Rule)
+		}
+	}
+
+	// Templates requesting paths robots.txt disallows are left out as well
+	if compliance != nil {
+		disallowedIDs, err := s.templateCatalog.disallowedTemplates(templates, compliance, hosts)
+		if err != nil {
+			return nil, common.NewScannerError("failed to check nuclei templates against robots.txt", err)
+		}
+		if len(disallowedIDs) > 0 {
+			compliance.ExcludedTemplates = disallowedIDs
+			excludedIDs = append(excludedIDs, disallowedIDs...)
+			taskCtx.Info().Msgf("Excluded %d nuclei templates requesting paths robots.txt of %s targets disallows", len(disallowedIDs), nucleiInput.Domain)
+		}
+	}
+
+	// The scan budget ends the scan with the findings so far, before the scanner timeout would;
+	// the findings it made are still replayed within the task's time
+	replayCtx := ctx
+	if nucleiInput.ScanBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, nucleiInput.ScanBudget, errNucleiScanBudget)
+		defer cancel()
+	}
+
 	// Create nuclei engine with protocol filtering based on input Type
 	var engineOpts []nuclei.NucleiSDKOptions
 
-	// Set scan strategy to host-spray for better reliability and maximum coverage
-	engineOpts = append(engineOpts, nuclei.WithScanStrategy("host-spray"))
+	// Set scan strategy to host-spray for better reliability and maximum coverage.
+	// Nuclei only tracks resume progress with template-spray, so resumable scans use that instead.
+	// A host budget needs host-spray, which scans a batch of targets at a time, while template-spray
+	// starts every target at once; time-boxed scans are not resumable.
+	budget := newHostBudget(nucleiInput.HostBudget)
+	resumable := budget == nil && (nucleiInput.OnCheckpoint != nil || len(nucleiInput.ResumeState) > 0)
+	if budget != nil && (nucleiInput.OnCheckpoint != nil || len(nucleiInput.ResumeState) > 0) {
+		taskCtx.Info().Msgf("Nuclei scan of %s is time-boxed to %s per target, checkpoints disabled", nucleiInput.Domain, nucleiInput.HostBudget)
+		nucleiInput.OnCheckpoint, nucleiInput.ResumeState = nil, nil
+	}
+	if resumable {
+		engineOpts = append(engineOpts, nuclei.WithScanStrategy("template-spray"))
+	} else {
+		engineOpts = append(engineOpts, nuclei.WithScanStrategy("host-spray"))
+	}
 
 	// Set optimized concurrency for maximum results while reducing dropped requests
 	engineOpts = append(engineOpts, nuclei.WithConcurrency(nuclei.Concurrency{
@@ -105,21 +289,41 @@ func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.
 	engineOpts = append(engineOpts, nuclei.WithGlobalRateLimitCtx(ctx, 500, time.Second))
 
 	// Set protocol filters as before
+	filters := nuclei.TemplateFilters{ExcludeProtocolTypes: "http"}
 	if nucleiInput.Type == "http" {
-		engineOpts = append(engineOpts, nuclei.WithTemplateFilters(nuclei.TemplateFilters{ProtocolTypes: "http"}))
-	} else {
-		engineOpts = append(engineOpts, nuclei.WithTemplateFilters(nuclei.TemplateFilters{ExcludeProtocolTypes: "http"}))
+		filters = nuclei.TemplateFilters{ProtocolTypes: "http"}
 	}
+	filters.ExcludeIDs = excludedIDs
+	engineOpts = append(engineOpts, nuclei.WithTemplateFilters(filters))
+
+	// Configure OOB interactions for blind SSRF, log4shell-style and similar templates
+	interactshOpts := s.interactshOptions(nucleiInput)
+	if interactshOpts.NoInteractsh {
+		gologger.Info().Msgf("Interactsh disabled for %s, OOB templates will not match", nucleiInput.Domain)
+	}
+	engineOpts = append(engineOpts, nuclei.WithInteractshOptions(interactshOpts))
 
 	// Disable template update check
 	engineOpts = append(engineOpts, nuclei.DisableUpdateCheck())
 
-	// Set template path to /root/nuclei-templates
+	// Compliance mode identifies the scanner with the user agent its robots.txt rules were picked for
+	if compliance != nil {
+		engineOpts = append(engineOpts, nuclei.WithHeaders([]string{"User-Agent: " + userAgent}))
+	}
+
+	// Ban detection needs the responses of requests that matched nothing as well
+	blocks := taskCtx.NewBlockDetector()
+	if blocks != nil {
+		engineOpts = append(engineOpts, nuclei.EnableMatcherStatus())
+	}
+
+	// Set template path to the bundled templates, plus the tenant's custom templates if any
 	engineOpts = append(engineOpts, nuclei.WithTemplatesOrWorkflows(nuclei.TemplateSources{
-		Templates: []string{"/root/nuclei-templates"},
+		Templates: templates,
 	}))
 
-	// Restore log level to info after nuclei execution
+	// Set log level to fatal to reduce noise during nuclei execution, and restore it afterwards
+	gologger.DefaultLogger.SetMaxLevel(levels.LevelFatal)
 	defer func() {
 		gologger.DefaultLogger.SetMaxLevel(levels.LevelInfo)
 		gologger.Info().Msgf("Nuclei scan completed for domain: %s", nucleiInput.Domain)
@@ -134,52 +338,347 @@ func (s *NucleiScanner) Execute(ctx context.Context, input interface{}) (models.
 	}
 	defer ne.Close()
 
+	// Requests to banned host groups and to targets out of budget are held back or skipped through
+	// the host error cache, which templates take from the executer options once they are loaded
+	if blocks != nil || budget != nil {
+		executerOpts := ne.GetExecuterOptions()
+		executerOpts.HostErrorsCache = &blockingHostErrors{inner: executerOpts.HostErrorsCache, ctx: ctx, blocks: blocks, budget: budget}
+	}
+
 	// Load targets
 	ne.LoadTargets(hosts, false)
 
+	// Continue where an interrupted run stopped
+	resumeCfg := ne.GetExecuterOptions().ResumeCfg
+	if len(nucleiInput.ResumeState) > 0 {
+		if err := json.Unmarshal(nucleiInput.ResumeState, resumeCfg); err != nil {
+			gologger.Warning().Msgf("Ignoring invalid nuclei resume state, starting from scratch: %v", err)
+		} else {
+			resumeCfg.Compile()
+			gologger.Info().Msgf("Resuming nuclei scan for %s with %d templates from a previous run", nucleiInput.Domain, len(resumeCfg.ResumeFrom))
+		}
+	}
+
 	// Collect vulnerabilities
 	vulnerabilities := make([]models.NucleiVulnerability, 0)
+	var vulnMutex sync.Mutex
+	var lastReplayFound time.Time
+
+	// Report progress periodically so that a redelivered task can resume
+	var lastResumeState json.RawMessage
+	var wgCheckpoint sync.WaitGroup
+	checkpointCtx, stopCheckpoints := context.WithCancel(ctx)
+	if nucleiInput.OnCheckpoint != nil {
+		wgCheckpoint.Add(1)
+		go func() {
+			defer wgCheckpoint.Done()
+			ticker := time.NewTicker(nucleiCheckpointInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-checkpointCtx.Done():
+					return
+				case <-ticker.C:
+					state, err := snapshotResumeState(resumeCfg)
+					// Nuclei marks interrupted templates as completed, so snapshots taken after cancellation are discarded
+					if err != nil || ctx.Err() != nil {
+						continue
+					}
+					vulnMutex.Lock()
+					findings := append([]models.NucleiVulnerability(nil), vulnerabilities...)
+					lastResumeState = state
+					vulnMutex.Unlock()
+					nucleiInput.OnCheckpoint(models.NucleiResult{
+						Domain:          nucleiInput.Domain,
+						Vulnerabilities: findings,
+						Partial:         true,
+						ResumeState:     state,
+						Blocks:          blocks.Events(),
+					})
+				}
+			}
+		}()
+	}
 
 	// Execute with callback to collect results
-	err = ne.ExecuteWithCallback(func(event *output.ResultEvent) {
+	err = ne.ExecuteCallbackWithCtx(ctx, func(event *output.ResultEvent) {
 		// Handle the event and convert to our model
 		if event != nil {
-			// Convert severity from severity.Holder to string
-			severityStr := ""
-			if event.Info.SeverityHolder.Severity != 0 {
-				severityStr = event.Info.SeverityHolder.Severity.String()
+			blocks.Observe(event.Host, responseStatus(event.Response), eventError(event))
+			if blocks != nil && !event.MatcherStatus {
+				return
 			}
+			vuln := toNucleiVulnerability(event)
+			vuln.ComplianceReview = compliance.Review(vuln.MatchedAt)
+			replay := nucleiInput.Replay.Selects(vuln)
 
-			// Convert Reference from RawStringSlice to []string
-			var references []string
-			if event.Info.Reference != nil {
-				references = event.Info.Reference.ToSlice()
-			}
-			vuln := models.NucleiVulnerability{
-				TemplateID:  event.TemplateID,
-				Type:        event.Type,
-				Host:        event.Host,
-				MatchedAt:   event.Matched,
-				Request:     event.Request,
-				Response:    event.Response,
-				Name:        event.Info.Name,
-				Description: event.Info.Description,
-				Reference:   references,
-				Severity:    severityStr,
+			vulnMutex.Lock()
+			vulnerabilities = append(vulnerabilities, vuln)
+			if replay {
+				lastReplayFound = time.Now()
 			}
+			vulnMutex.Unlock()
 
-			vulnerabilities = append(vulnerabilities, vuln)
+			// Findings to replay are reported once the replay confirmed them
+			if nucleiInput.OnResult != nil && !replay {
+				nucleiInput.OnResult(vuln)
+			}
 		}
 	})
 
+	stopCheckpoints()
+	wgCheckpoint.Wait()
+
+	result := models.NucleiResult{
+		Domain:          nucleiInput.Domain,
+		Vulnerabilities: vulnerabilities,
+		Blocks:          blocks.Events(),
+		Compliance:      compliance,
+		TimeBoxed:       budget.TimeBoxed(),
+		TemplatePolicy:  policyReport,
+	}
+	if len(result.TimeBoxed) > 0 {
+		gologger.Warning().Msgf("Nuclei skipped the rest of the requests to %d targets of %s after %s each", len(result.TimeBoxed), nucleiInput.Domain, nucleiInput.HostBudget)
+	}
+
+	// Keep the findings of an interrupted scan together with the last resume state taken before the interruption
+	if ctx.Err() != nil {
+		result.Partial = true
+		result.ResumeState = lastResumeState
+		result.Unscanned = budget.Unstarted(hosts)
+		gologger.Warning().Msgf("Nuclei scan for %s was interrupted: returning %d partial findings", nucleiInput.Domain, len(vulnerabilities))
+		if !lastReplayFound.IsZero() {
+			if context.Cause(ctx) == errNucleiScanBudget && replayCtx.Err() == nil {
+				replayOpts := append(slices.Clip(engineOpts), nuclei.WithScanStrategy("host-spray"))
+				s.replayFindings(replayCtx, nucleiInput, replayOpts, filters, blocks != nil, result.Vulnerabilities, lastReplayFound)
+			} else {
+				reportUnreplayed(nucleiInput, result.Vulnerabilities, "as the scan was interrupted")
+			}
+			result.Vulnerabilities, result.Unconfirmed = models.SplitUnconfirmed(result.Vulnerabilities)
+		}
+		if context.Cause(ctx) == errNucleiScanBudget {
+			return result, common.NewTimeoutError(fmt.Sprintf("nuclei scan budget of %s ran out", nucleiInput.ScanBudget), errNucleiScanBudget)
+		}
+		return result, common.NewTimeoutError("nuclei execution cancelled", ctx.Err())
+	}
+
 	if err != nil {
 		return nil, common.NewScannerError("failed to execute nuclei scan", err)
 	}
 
-	return models.NucleiResult{
-		Domain:          nucleiInput.Domain,
-		Vulnerabilities: vulnerabilities,
-	}, nil
+	if !lastReplayFound.IsZero() {
+		replayOpts := append(slices.Clip(engineOpts), nuclei.WithScanStrategy("host-spray"))
+		s.replayFindings(ctx, nucleiInput, replayOpts, filters, blocks != nil, result.Vulnerabilities, lastReplayFound)
+		result.Vulnerabilities, result.Unconfirmed = models.SplitUnconfirmed(result.Vulnerabilities)
+	}
+
+	// A banned scan is retried later; the findings it made are kept with the failed result
+	if err := banError(taskCtx, "nuclei", result.Blocks); err != nil {
+		return result, err
+	}
+	// Otherwise the targets of aborted groups and those robots.txt disallows were never scanned, and
+	// time-boxed targets only in part
+	result.Partial = models.Aborted(result.Blocks) || len(result.TimeBoxed) > 0 || (compliance != nil && len(compliance.Skipped) > 0)
+	return result, nil
+}
+
+// blockingHostErrors wraps nuclei's host error cache so that requests to host groups found banned
+// or to targets out of time budget are held back or skipped, and connection resets count towards
+// ban detection
+type blockingHostErrors struct {
+	inner  hosterrorscache.CacheInterface // nil when nuclei tracks no host errors
+	ctx    context.Context
+	blocks *models.BlockDetector
+	budget *hostBudget
+}
+
+func (c *blockingHostErrors) SetVerbose(verbose bool) {
+	if c.inner != nil {
+		c.inner.SetVerbose(verbose)
+	}
+}
+
+func (c *blockingHostErrors) Close() {
+	if c.inner != nil {
+		c.inner.Close()
+	}
+}
+
+// Check reports whether a request to the input should be skipped, after waiting out a backoff
+func (c *blockingHostErrors) Check(protoType string, input *contextargs.Context) bool {
+	if input != nil && input.MetaInput != nil {
+		if c.budget.exhausted(input.MetaInput.Input) || c.blocks.Wait(c.ctx, input.MetaInput.Input) {
+			return true
+		}
+	}
+	return c.inner != nil && c.inner.Check(protoType, input)
+}
+
+func (c *blockingHostErrors) Remove(input *contextargs.Context) {
+	if c.inner != nil {
+		c.inner.Remove(input)
+	}
+}
+
+func (c *blockingHostErrors) MarkFailed(protoType string, input *contextargs.Context, err error) {
+	c.observe(input, err)
+	if c.inner != nil {
+		c.inner.MarkFailed(protoType, input, err)
+	}
+}
+
+func (c *blockingHostErrors) MarkFailedOrRemove(protoType string, input *contextargs.Context, err error) {
+	c.observe(input, err)
+	if c.inner != nil {
+		c.inner.MarkFailedOrRemove(protoType, input, err)
+	}
+}
+
+// observe passes a failed request on to ban detection
+func (c *blockingHostErrors) observe(input *contextargs.Context, err error) {
+	if err != nil && input != nil && input.MetaInput != nil {
+		c.blocks.Observe(input.MetaInput.Input, 0, err)
+	}
+}
+
+// responseStatus returns the status code of a raw HTTP response, or 0 when there is none
+func responseStatus(rawResponse string) int {
+	statusLine, _, _ := strings.Cut(rawResponse, "\n")
+	proto, rest, ok := strings.Cut(strings.TrimSpace(statusLine), " ")
+	if !ok || !strings.HasPrefix(proto, "HTTP/") {
+		return 0
+	}
+	code, _, _ := strings.Cut(rest, " ")
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return 0
+	}
+	return status
+}
+
+// eventError returns the error a nuclei request failed with, or nil
+func eventError(event *output.ResultEvent) error {
+	if event.Error == "" || event.Error == "none" {
+		return nil
+	}
+	return errors.New(event.Error)
+}
+
+// toNucleiVulnerability converts a nuclei result event into a finding, including its classification
+func toNucleiVulnerability(event *output.ResultEvent) models.NucleiVulnerability {
+	// Convert severity from severity.Holder to string
+	severityStr := ""
+	if event.Info.SeverityHolder.Severity != 0 {
+		severityStr = event.Info.SeverityHolder.Severity.String()
+	}
+
+	// Convert Reference from RawStringSlice to []string
+	var references []string
+	if event.Info.Reference != nil {
+		references = event.Info.Reference.ToSlice()
+	}
+
+	vuln := models.NucleiVulnerability{
+		TemplateID:       event.TemplateID,
+		Type:             event.Type,
+		Host:             event.Host,
+		MatchedAt:        event.Matched,
+		ExtractedResults: event.ExtractedResults,
+		Request:          event.Request,
+		Response:         event.Response,
+		Name:             event.Info.Name,
+		Description:      event.Info.Description,
+		Reference:        references,
+		Severity:         severityStr,
+		MatcherName:      event.MatcherName,
+		Tags:             event.Info.Tags.ToSlice(),
+		CurlCommand:      event.CURLCommand,
+	}
+
+	if classification := event.Info.Classification; classification != nil {
+		vuln.CVEIDs = normalizeClassificationIDs(classification.CVEID.ToSlice(), "CVE-")
+		vuln.CWEIDs = normalizeClassificationIDs(classification.CWEID.ToSlice(), "CWE-")
+		vuln.CVSSScore = classification.CVSSScore
+		vuln.CVSSMetrics = classification.CVSSMetrics
+		vuln.EPSSScore = classification.EPSSScore
+	}
+
+	// Nuclei only renders curl commands for some requests, so HTTP findings fall back to the raw request
+	if vuln.CurlCommand == "" && event.Type == "http" {
+		vuln.CurlCommand = curlFromRawRequest(event.Request, event.Matched)
+	}
+
+	return vuln
+}
+
+// normalizeClassificationIDs upper-cases IDs like "cwe-79" and adds the prefix to bare numbers
+func normalizeClassificationIDs(ids []string, prefix string) []string {
+	var normalized []string
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if !strings.HasPrefix(id, prefix) {
+			id = prefix + id
+		}
+		normalized = append(normalized, id)
+	}
+	return normalized
+}
+
+// curlFromRawRequest builds a curl command from a raw HTTP request, taking scheme and host from the matched URL
+func curlFromRawRequest(rawRequest, matchedAt string) string {
+	if rawRequest == "" {
+		return ""
+	}
+	req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(rawRequest)))
+	if err != nil {
+		return ""
+	}
+	target, err := url.Parse(matchedAt)
+	if err != nil || target.Scheme == "" {
+		return ""
+	}
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	if req.Host != "" {
+		req.URL.Host = req.Host
+	}
+
+	parts := []string{"curl", "-X", req.Method}
+	headers := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		// curl computes the length of the body itself
+		if name != "Content-Length" {
+			headers = append(headers, name)
+		}
+	}
+	sort.Strings(headers)
+	for _, name := range headers {
+		for _, value := range req.Header[name] {
+			parts = append(parts, "-H", shellQuote(name+": "+value))
+		}
+	}
+	if req.Body != nil {
+		if body, err := io.ReadAll(req.Body); err == nil && len(body) > 0 {
+			parts = append(parts, "-d", shellQuote(string(body)))
+		}
+	}
+	parts = append(parts, shellQuote(req.URL.String()))
+	return strings.Join(parts, " ")
+}
+
+// shellQuote wraps a value in single quotes for a POSIX shell
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// snapshotResumeState serializes nuclei's current progress in the format it reads back as a resume config
+func snapshotResumeState(resumeCfg *types.ResumeCfg) (json.RawMessage, error) {
+	snapshot := resumeCfg.Clone()
+	snapshot.ResumeFrom = snapshot.Current
+	return json.Marshal(snapshot)
 }
 
 func (s *NucleiScanner) GetName() string {
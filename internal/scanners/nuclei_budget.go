@@ -0,0 +1,86 @@
+package scanners
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// errNucleiScanBudget is the cause of a nuclei scan ended by its time budget
+var errNucleiScanBudget = errors.New("nuclei scan budget exhausted")
+
+// hostBudget times each target from its first request and reports when its budget has run out,
+// so that its remaining requests are skipped. With host-spray, nuclei scans a batch of targets
+// at a time, and a target out of budget makes room for the next one.
+type hostBudget struct {
+	budget time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	started   map[string]time.Time
+	timeBoxed map[string]bool
+}
+
+// newHostBudget creates a budget of the given time per target, or nil for no budget
+func newHostBudget(budget time.Duration) *hostBudget {
+	if budget <= 0 {
+		return nil
+	}
+	return &hostBudget{budget: budget, now: time.Now, started: make(map[string]time.Time), timeBoxed: make(map[string]bool)}
+}
+
+// exhausted records a request to a target and reports whether the target is out of budget
+func (b *hostBudget) exhausted(target string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	started, ok := b.started[target]
+	if !ok {
+		b.started[target] = now
+		return false
+	}
+	if now.Sub(started) < b.budget {
+		return false
+	}
+	b.timeBoxed[target] = true
+	return true
+}
+
+// TimeBoxed returns the targets whose requests were skipped for running out of budget
+func (b *hostBudget) TimeBoxed() []string {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var targets []string
+	for target := range b.timeBoxed {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Unstarted returns the targets no request was made to
+func (b *hostBudget) Unstarted(targets []string) []string {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var unstarted []string
+	for _, target := range targets {
+		if _, ok := b.started[target]; !ok {
+			unstarted = append(unstarted, target)
+		}
+	}
+	sort.Strings(unstarted)
+	return unstarted
+}
@@ -0,0 +1,46 @@
+package scanners
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestHostBudget tests that a target is skipped once its budget has passed since its first request
+func TestHostBudget(t *testing.T) {
+	if newHostBudget(0) != nil {
+		t.Fatal("newHostBudget(0) created a budget")
+	}
+
+	now := time.Unix(0, 0)
+	budget := newHostBudget(10 * time.Minute)
+	budget.now = func() time.Time { return now }
+
+	if budget.exhausted("slow.example.com") {
+		t.Error("exhausted() on the first request = true")
+	}
+	now = now.Add(5 * time.Minute)
+	if budget.exhausted("slow.example.com") || budget.exhausted("fast.example.com") {
+		t.Error("exhausted() within the budget = true")
+	}
+	now = now.Add(5 * time.Minute)
+	if !budget.exhausted("slow.example.com") {
+		t.Error("exhausted() after the budget = false")
+	}
+	if budget.exhausted("fast.example.com") {
+		t.Error("exhausted() of a target started later = true")
+	}
+
+	if got := budget.TimeBoxed(); !reflect.DeepEqual(got, []string{"slow.example.com"}) {
+		t.Errorf("TimeBoxed() = %v", got)
+	}
+	targets := []string{"slow.example.com", "c.example.com", "fast.example.com", "b.example.com"}
+	if got := budget.Unstarted(targets); !reflect.DeepEqual(got, []string{"b.example.com", "c.example.com"}) {
+		t.Errorf("Unstarted() = %v", got)
+	}
+
+	var none *hostBudget
+	if none.exhausted("slow.example.com") || none.TimeBoxed() != nil || none.Unstarted(targets) != nil {
+		t.Error("a nil budget skipped or reported targets")
+	}
+}
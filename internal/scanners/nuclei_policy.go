@@ -0,0 +1,262 @@
+package scanners
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// templateCatalog reads the metadata template policies are evaluated against, caching it per file
+// until the file changes, so the bundled templates are only parsed once
+type templateCatalog struct {
+	mu      sync.Mutex
+	entries map[string]catalogEntry
+}
+
+// catalogEntry is the cached metadata of a template file
+type catalogEntry struct {
+	modTime  time.Time
+	size     int64
+	template *models.TemplateInfo // nil for files that are not templates
+}
+
+func newTemplateCatalog() *templateCatalog {
+	return &templateCatalog{entries: make(map[string]catalogEntry)}
+}
+
+// evaluate applies a policy to the templates in dirs and returns the report and the IDs of the
+// templates it excludes
+func (c *templateCatalog) evaluate(dirs []string, policy *models.TemplatePolicy) (*models.TemplatePolicyReport, []string, error) {
+	report := &models.TemplatePolicyReport{Policy: *policy}
+	excluded := make(map[string]bool)
+	var excludedIDs []string
+	err := c.walk(dirs, func(template *models.TemplateInfo) {
+		report.Templates++
+		if rule, violated := policy.Violation(*template); violated && !excluded[template.ID] {
+			excluded[template.ID] = true
+			excludedIDs = append(excludedIDs, template.ID)
+			report.Violations = append(report.Violations, models.TemplateViolation{TemplateID: template.ID, Rule: rule})
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, excludedIDs, nil
+}
+
+// disallowedTemplates returns the IDs of the templates that request a path robots.txt of one of
+// the targets disallows. Nuclei runs every template against every target, so such a template is
+// left out of the whole scan.
+func (c *templateCatalog) disallowedTemplates(dirs []string, compliance *models.CompliancePolicy, targets []string) ([]string, error) {
+	var policies []*models.HostPolicy
+	seen := make(map[string]bool)
+	for _, target := range targets {
+		if policy := targetPolicy(compliance, target); policy != nil && len(policy.Disallow) > 0 && !seen[policy.Origin] {
+			seen[policy.Origin] = true
+			policies = append(policies, policy)
+		}
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	excluded := make(map[string]bool)
+	var excludedIDs []string
+	err := c.walk(dirs, func(template *models.TemplateInfo) {
+		if excluded[template.ID] {
+			return
+		}
+		for _, path := range template.Paths {
+			for _, policy := range policies {
+				if !policy.Allowed(path) {
+					excluded[template.ID] = true
+					excludedIDs = append(excludedIDs, template.ID)
+					return
+				}
+			}
+		}
+	})
+	return excludedIDs, err
+}
+
+// walk calls fn with the metadata of every template in dirs, leaving out hidden directories
+func (c *templateCatalog) walk(dirs []string, fn func(*models.TemplateInfo)) error {
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+			template, err := c.template(path, d)
+			if err != nil || template == nil {
+				return err
+			}
+			fn(template)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read templates in %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// template returns the metadata of a template file, from the cache while the file is unchanged
+func (c *templateCatalog) template(path string, d fs.DirEntry) (*models.TemplateInfo, error) {
+	info, err := d.Info()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.template, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry = catalogEntry{modTime: info.ModTime(), size: info.Size(), template: parseTemplateInfo(data)}
+	c.mu.Lock()
+	c.entries[path] = entry
+	c.mu.Unlock()
+	return entry.template, nil
+}
+
+// parseTemplateInfo reads the ID, tags, payload files and HTTP request paths of a template, or
+// returns nil when the file is not a template
+func parseTemplateInfo(data []byte) *models.TemplateInfo {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	id := mappingValue(doc, "id")
+	if id == nil || id.Value == "" {
+		return nil
+	}
+
+	template := &models.TemplateInfo{ID: id.Value}
+	if tags := mappingValue(mappingValue(doc, "info"), "tags"); tags != nil {
+		switch tags.Kind {
+		case yaml.ScalarNode:
+			for _, tag := range strings.Split(tags.Value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					template.Tags = append(template.Tags, tag)
+				}
+			}
+		case yaml.SequenceNode:
+			for _, tag := range tags.Content {
+				template.Tags = append(template.Tags, tag.Value)
+			}
+		}
+	}
+	template.Wordlists = payloadFiles(doc)
+	template.Paths = requestPaths(doc)
+	return template
+}
+
+// requestPaths returns the paths the HTTP requests of a template fetch relative to the target,
+// from its path lists and the request lines of its raw requests. Paths that do not start at the
+// target, such as a bare "{{path}}", are left out.
+func requestPaths(doc *yaml.Node) []string {
+	var paths []string
+	for _, key := range []string{"http", "requests"} {
+		requests := mappingValue(doc, key)
+		if requests == nil || requests.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, request := range requests.Content {
+			if list := mappingValue(request, "path"); list != nil {
+				for _, value := range list.Content {
+					if path := templatePath(value.Value); path != "" {
+						paths = append(paths, path)
+					}
+				}
+			}
+			if list := mappingValue(request, "raw"); list != nil {
+				for _, value := range list.Content {
+					line, _, _ := strings.Cut(strings.TrimSpace(value.Value), "\n")
+					if fields := strings.Fields(line); len(fields) >= 2 {
+						if path := templatePath(fields[1]); path != "" {
+							paths = append(paths, path)
+						}
+					}
+				}
+			}
+		}
+	}
+	return paths
+}
+
+// templatePath returns the path of a template request, e.g. "/admin" for "{{BaseURL}}/admin",
+// or "" when it does not start at the target
+func templatePath(value string) string {
+	path := strings.TrimSpace(value)
+	for _, prefix := range []string{"{{BaseURL}}", "{{RootURL}}", "{{Hostname}}"} {
+		if trimmed, ok := strings.CutPrefix(path, prefix); ok {
+			path = trimmed
+			break
+		}
+	}
+	if path == "" {
+		return "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		return ""
+	}
+	return path
+}
+
+// payloadFiles returns the payloads of the requests of a template that are read from files:
+// those given as a path instead of a list of values
+func payloadFiles(node *yaml.Node) []string {
+	var files []string
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "payloads" && node.Content[i+1].Kind == yaml.MappingNode {
+				payloads := node.Content[i+1].Content
+				for j := 1; j < len(payloads); j += 2 {
+					if payloads[j].Kind == yaml.ScalarNode && payloads[j].Value != "" {
+						files = append(files, payloads[j].Value)
+					}
+				}
+			}
+		}
+	}
+	for _, child := range node.Content {
+		files = append(files, payloadFiles(child)...)
+	}
+	return files
+}
+
+// mappingValue returns the value of a key of a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package scanners
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// TestParseTemplateInfo tests that the tags and payload files of a template are read
+func TestParseTemplateInfo(t *testing.T) {
+	template := parseTemplateInfo([]byte(`id: ftp-weak-credentials
+info:
+  name: FTP Weak Credentials
+  tags: network,ftp,default-login
+tcp:
+  - inputs:
+      - data: "USER {{username}}\r\n"
+    payloads:
+      username: helpers/wordlists/ftp-usernames.txt
+      password:
+        - admin
+        - password
+`))
+	want := &models.TemplateInfo{
+		ID:        "ftp-weak-credentials",
+		Tags:      []string{"network", "ftp", "default-login"},
+		Wordlists: []string{"helpers/wordlists/ftp-usernames.txt"},
+	}
+	if !reflect.DeepEqual(template, want) {
+		t.Errorf("parseTemplateInfo() = %+v, want %+v", template, want)
+	}
+
+	if parseTemplateInfo([]byte("name: not a template\n")) != nil {
+		t.Error("parseTemplateInfo() of a file without an id returned a template")
+	}
+}
+
+// TestTemplateCatalogEvaluate tests that a policy excludes the templates it denies and reports them
+func TestTemplateCatalogEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"dos/slowloris.yaml":      "id: slowloris\ninfo:\n  tags: [dos, network]\n",
+		"http/tech-detect.yaml":   "id: tech-detect\ninfo:\n  tags: tech\n",
+		".github/workflow.yaml":   "id: ci\ninfo:\n  tags: dos\n",
+		"helpers/wordlists/a.txt": "admin\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	catalog := newTemplateCatalog()
+	report, excluded, err := catalog.evaluate([]string{dir}, &models.TemplatePolicy{DenyTags: []string{"dos"}})
+	if err != nil {
+		t.Fatalf("evaluate() error = %v", err)
+	}
+	if !reflect.DeepEqual(excluded, []string{"slowloris"}) || report.Templates != 2 {
+		t.Errorf("evaluate() = %v of %d templates, want slowloris of 2", excluded, report.Templates)
+	}
+	if len(report.Violations) != 1 || report.Violations[0].Rule != "deny_tags: dos" {
+		t.Errorf("evaluate() violations = %+v", report.Violations)
+	}
+}
+
+// TestTemplateCatalogDisallowedTemplates tests that templates requesting a path robots.txt of a
+// target disallows are excluded
+func TestTemplateCatalogDisallowedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"http/admin-panel.yaml": "id: admin-panel\nhttp:\n  - method: GET\n    path:\n      - \"{{BaseURL}}/admin/login\"\n",
+		"http/raw-backup.yaml":  "id: raw-backup\nhttp:\n  - raw:\n      - |\n        GET /backup.zip HTTP/1.1\n        Host: {{Hostname}}\n",
+		"http/tech-detect.yaml": "id: tech-detect\nhttp:\n  - method: GET\n    path:\n      - \"{{BaseURL}}\"\n      - \"{{BaseURL}}/favicon.ico\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	compliance := &models.CompliancePolicy{Hosts: []models.HostPolicy{
+		{Origin: "https://example.com", Robots: true, Disallow: []string{"/admin", "/*.zip$"}},
+		{Origin: "https://www.example.com", Robots: true},
+	}}
+
+	catalog := newTemplateCatalog()
+	excluded, err := catalog.disallowedTemplates([]string{dir}, compliance, []string{"https://example.com", "www.example.com"})
+	if err != nil {
+		t.Fatalf("disallowedTemplates() error = %v", err)
+	}
+	slices.Sort(excluded)
+	if !reflect.DeepEqual(excluded, []string{"admin-panel", "raw-backup"}) {
+		t.Errorf("disallowedTemplates() = %v, want admin-panel and raw-backup", excluded)
+	}
+
+	excluded, err = catalog.disallowedTemplates([]string{dir}, compliance, []string{"www.example.com"})
+	if err != nil || len(excluded) != 0 {
+		t.Errorf("disallowedTemplates() = %v, %v for a target without disallow rules", excluded, err)
+	}
+}
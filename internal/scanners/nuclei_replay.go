@@ -0,0 +1,107 @@
+package scanners
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+	nuclei "github.com/projectdiscovery/nuclei/v3/lib"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+)
+
+// replayFindings runs the templates of the findings the input selects for replay again against
+// their hosts, once the replay delay has passed since the last of them was found. Each selected
+// finding is marked verified when the replay matches it again and reported to OnResult, or marked
+// unconfirmed when it does not. When the replay cannot run, the findings are reported unreplayed.
+func (s *NucleiScanner) replayFindings(ctx context.Context, input models.NucleiInput, engineOpts []nuclei.NucleiSDKOptions,
+	filters nuclei.TemplateFilters, matcherStatus bool, findings []models.NucleiVulnerability, lastFound time.Time) {
+	var selected []int
+	var replayed []models.NucleiVulnerability
+	for i, finding := range findings {
+		if input.Replay.Selects(finding) {
+			selected = append(selected, i)
+			replayed = append(replayed, finding)
+		}
+	}
+
+	confirmed, err := replayTemplates(ctx, engineOpts, filters, matcherStatus, replayed, time.Until(lastFound.Add(input.Replay.Delay)))
+	if err != nil {
+		reportUnreplayed(input, findings, fmt.Sprintf("as the replay failed: %v", err))
+		return
+	}
+
+	verified := 0
+	for _, i := range selected {
+		if !confirmed[models.ReplayKey(findings[i])] {
+			findings[i].Verification = models.FindingUnconfirmed
+			continue
+		}
+		findings[i].Verification = models.FindingVerified
+		verified++
+		if input.OnResult != nil {
+			input.OnResult(findings[i])
+		}
+	}
+	gologger.Info().Msgf("Replayed %d nuclei findings of %s: %d verified, %d unconfirmed", len(selected), input.Domain, verified, len(selected)-verified)
+}
+
+// reportUnreplayed marks the findings selected for replay that were not replayed and reports them
+// to OnResult, so that a replay that cannot run does not hold them back
+func reportUnreplayed(input models.NucleiInput, findings []models.NucleiVulnerability, reason string) {
+	count := 0
+	for i := range findings {
+		if !input.Replay.Selects(findings[i]) || findings[i].Verification != "" {
+			continue
+		}
+		findings[i].Verification = models.FindingUnreplayed
+		count++
+		if input.OnResult != nil {
+			input.OnResult(findings[i])
+		}
+	}
+	if count > 0 {
+		gologger.Warning().Msgf("Reporting %d nuclei findings of %s without replaying them %s", count, input.Domain, reason)
+	}
+}
+
+// replayTemplates waits for the delay, then runs the templates of the findings against their hosts
+// and returns the replay keys of the findings matched again
+func replayTemplates(ctx context.Context, engineOpts []nuclei.NucleiSDKOptions, filters nuclei.TemplateFilters,
+	matcherStatus bool, findings []models.NucleiVulnerability, delay time.Duration) (map[string]bool, error) {
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	templateIDs, hosts := models.ReplayTargets(findings)
+	filters.IDs = templateIDs
+	ne, err := nuclei.NewNucleiEngineCtx(ctx, append(engineOpts, nuclei.WithTemplateFilters(filters))...)
+	if err != nil {
+		return nil, err
+	}
+	defer ne.Close()
+	ne.LoadTargets(hosts, false)
+
+	confirmed := make(map[string]bool)
+	var mu sync.Mutex
+	err = ne.ExecuteCallbackWithCtx(ctx, func(event *output.ResultEvent) {
+		if event == nil || (matcherStatus && !event.MatcherStatus) {
+			return
+		}
+		mu.Lock()
+		confirmed[models.ReplayKey(toNucleiVulnerability(event))] = true
+		mu.Unlock()
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	return confirmed, err
+}
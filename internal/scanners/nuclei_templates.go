@@ -0,0 +1,207 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/projectdiscovery/gologger"
+)
+
+// defaultTemplateCacheDir is where custom nuclei templates are synced to unless configured otherwise
+const defaultTemplateCacheDir = "/tmp/nuclei-custom-templates"
+
+// nucleiTemplatesVersionFile holds the release of the bundled templates; the Dockerfile writes it
+const nucleiTemplatesVersionFile = nucleiTemplatesDir + "/.templates-version"
+
+// NucleiTemplatesVersion returns the release of the bundled nuclei templates, e.g. "v10.1.0", or
+// "" when the image does not record it
+func NucleiTemplatesVersion() string {
+	data, err := os.ReadFile(nucleiTemplatesVersionFile)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// templateStore is the blob storage custom nuclei templates are synced from
+type templateStore interface {
+	ListBlobs(ctx context.Context, prefix string) ([]azure.BlobInfo, error)
+	ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error)
+}
+
+// templateCacheEntry records the blob a cached template file was downloaded from
+type templateCacheEntry struct {
+	ETag string `json:"etag"`
+	MD5  string `json:"md5"`
+}
+
+// templateCache keeps local copies of tenant template directories from blob storage.
+// Cached files are reused only while their blob is unchanged and their content hash still
+// matches the one recorded at download time; everything else is downloaded again.
+type templateCache struct {
+	mu   sync.Mutex
+	root string
+}
+
+// lock returns the mutex syncs hold, for the disk janitor to hold while it evicts cached templates
+func (c *templateCache) lock() sync.Locker {
+	return &c.mu
+}
+
+// newTemplateCache creates a template cache rooted at the given directory
+func newTemplateCache(root string) *templateCache {
+	return &templateCache{root: root}
+}
+
+// sync mirrors the blobs under prefix into the cache and returns the local directory
+func (c *templateCache) sync(ctx context.Context, store templateStore, prefix string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	relDir := filepath.FromSlash(strings.Trim(prefix, "/"))
+	if !filepath.IsLocal(relDir) {
+		return "", common.NewValidationError("custom_templates", fmt.Sprintf("invalid templates prefix %q", prefix))
+	}
+	dir := filepath.Join(c.root, relDir)
+	manifestPath := dir + ".manifest.json"
+
+	blobs, err := store.ListBlobs(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	manifest := c.loadManifest(manifestPath)
+	synced := make(map[string]templateCacheEntry, len(blobs))
+	downloaded := 0
+
+	for _, blob := range blobs {
+		name := strings.TrimPrefix(blob.Name, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		if !filepath.IsLocal(filepath.FromSlash(name)) {
+			gologger.Warning().Msgf("Skipping template blob outside its directory: %s", blob.Name)
+			continue
+		}
+		localPath := filepath.Join(dir, filepath.FromSlash(name))
+
+		if entry, ok := manifest[name]; ok && entryMatchesBlob(entry, blob) && fileMD5(localPath) == entry.MD5 {
+			// The disk janitor evicts by modification time, so a reused file counts as used now
+			now := time.Now()
+			if err := os.Chtimes(localPath, now, now); err != nil {
+				gologger.Warning().Msgf("Failed to mark template %s as used: %v", localPath, err)
+			}
+			synced[name] = entry
+			continue
+		}
+
+		content, err := store.ReadFileFromBlob(ctx, blob.Name)
+		if err != nil {
+			return "", err
+		}
+		sum := md5.Sum(content)
+		if len(blob.ContentMD5) > 0 && !bytes.Equal(sum[:], blob.ContentMD5) {
+			return "", fmt.Errorf("template %s failed hash validation", blob.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create template directory: %w", err)
+		}
+		// Written aside and renamed, so a full disk never leaves half a template for nuclei to load
+		if err := os.WriteFile(localPath+".tmp", content, 0o644); err != nil {
+			os.Remove(localPath + ".tmp")
+			return "", fmt.Errorf("failed to write template %s: %w", localPath, err)
+		}
+		if err := os.Rename(localPath+".tmp", localPath); err != nil {
+			return "", fmt.Errorf("failed to write template %s: %w", localPath, err)
+		}
+		synced[name] = templateCacheEntry{ETag: blob.ETag, MD5: hex.EncodeToString(sum[:])}
+		downloaded++
+	}
+
+	if len(synced) == 0 {
+		return "", common.NewValidationError("custom_templates", fmt.Sprintf("no templates found under %s", prefix))
+	}
+
+	removed := removeStaleTemplates(dir, synced)
+	if err := c.storeManifest(manifestPath, synced); err != nil {
+		gologger.Warning().Msgf("Failed to store template cache manifest, templates will be downloaded again next time: %v", err)
+	}
+
+	gologger.Info().Msgf("Synced %d custom templates from %s (%d downloaded, %d removed)", len(synced), prefix, downloaded, removed)
+	return dir, nil
+}
+
+// entryMatchesBlob reports whether a cache entry was downloaded from the current version of a blob
+func entryMatchesBlob(entry templateCacheEntry, blob azure.BlobInfo) bool {
+	if len(blob.ContentMD5) > 0 {
+		return hex.EncodeToString(blob.ContentMD5) == entry.MD5
+	}
+	return blob.ETag != "" && blob.ETag == entry.ETag
+}
+
+// fileMD5 returns the hex MD5 of a file, or an empty string when it cannot be read
+func fileMD5(path string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := md5.Sum(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// removeStaleTemplates deletes cached files whose blobs no longer exist and returns how many were removed
+func removeStaleTemplates(dir string, synced map[string]templateCacheEntry) int {
+	removed := 0
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		if _, ok := synced[filepath.ToSlash(rel)]; !ok {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	return removed
+}
+
+// loadManifest reads the cache manifest of a template directory, returning an empty one when missing
+func (c *templateCache) loadManifest(path string) map[string]templateCacheEntry {
+	manifest := make(map[string]templateCacheEntry)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return manifest
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		gologger.Warning().Msgf("Ignoring corrupt template cache manifest %s: %v", path, err)
+		return make(map[string]templateCacheEntry)
+	}
+	return manifest
+}
+
+// storeManifest writes the cache manifest of a template directory
+func (c *templateCache) storeManifest(path string, manifest map[string]templateCacheEntry) error {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
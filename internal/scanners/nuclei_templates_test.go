@@ -0,0 +1,121 @@
+package scanners
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/common"
+)
+
+// fakeTemplateStore serves templates from memory and counts downloads
+type fakeTemplateStore struct {
+	blobs     map[string]string
+	badMD5    map[string]bool
+	downloads int
+}
+
+func (f *fakeTemplateStore) ListBlobs(ctx context.Context, prefix string) ([]azure.BlobInfo, error) {
+	var blobs []azure.BlobInfo
+	for name, content := range f.blobs {
+		if strings.HasPrefix(name, prefix) {
+			sum := md5.Sum([]byte(content))
+			if f.badMD5[name] {
+				sum[0]++
+			}
+			blobs = append(blobs, azure.BlobInfo{Name: name, ContentMD5: sum[:], Size: int64(len(content))})
+		}
+	}
+	return blobs, nil
+}
+
+func (f *fakeTemplateStore) ReadFileFromBlob(ctx context.Context, blobPath string) ([]byte, error) {
+	f.downloads++
+	return []byte(f.blobs[blobPath]), nil
+}
+
+// TestTemplateCacheSync tests that unchanged templates are reused and changed or tampered ones are downloaded again
+func TestTemplateCacheSync(t *testing.T) {
+	prefix := "tenant-a/nuclei-templates/private/"
+	store := &fakeTemplateStore{blobs: map[string]string{
+		prefix + "login.yaml":       "id: login",
+		prefix + "cves/custom.yaml": "id: custom-cve",
+	}}
+	cache := newTemplateCache(t.TempDir())
+
+	dir, err := cache.sync(context.Background(), store, prefix)
+	if err != nil {
+		t.Fatalf("First sync failed: %v", err)
+	}
+	if store.downloads != 2 {
+		t.Errorf("Expected 2 downloads on first sync, got %d", store.downloads)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "cves", "custom.yaml")); string(content) != "id: custom-cve" {
+		t.Errorf("Unexpected cached template content: %q", content)
+	}
+
+	// Unchanged blobs are served from the cache, and count as used for the disk janitor
+	stale := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(filepath.Join(dir, "login.yaml"), stale, stale)
+	store.downloads = 0
+	if _, err := cache.sync(context.Background(), store, prefix); err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if store.downloads != 0 {
+		t.Errorf("Expected no downloads for unchanged templates, got %d", store.downloads)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "login.yaml")); err != nil || time.Since(info.ModTime()) > time.Hour {
+		t.Errorf("Expected a reused template to be marked as used, got %v", err)
+	}
+
+	// A tampered local file, a changed blob and a deleted blob are all reconciled
+	os.WriteFile(filepath.Join(dir, "login.yaml"), []byte("id: tampered"), 0o644)
+	store.blobs[prefix+"cves/custom.yaml"] = "id: custom-cve-v2"
+	store.blobs[prefix+"new.yaml"] = "id: new"
+	delete(store.blobs, prefix+"login.yaml")
+	store.blobs[prefix+"other.yaml"] = "id: other"
+	os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("id: tampered"), 0o644)
+
+	store.downloads = 0
+	if _, err := cache.sync(context.Background(), store, prefix); err != nil {
+		t.Fatalf("Third sync failed: %v", err)
+	}
+	if store.downloads != 3 {
+		t.Errorf("Expected 3 downloads, got %d", store.downloads)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "login.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected the template of a deleted blob to be removed")
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "other.yaml")); string(content) != "id: other" {
+		t.Errorf("Expected tampered template to be replaced, got %q", content)
+	}
+}
+
+// TestTemplateCacheSyncHashMismatch tests that a download not matching its blob hash is rejected
+func TestTemplateCacheSyncHashMismatch(t *testing.T) {
+	prefix := "nuclei-templates/private/"
+	store := &fakeTemplateStore{
+		blobs:  map[string]string{prefix + "login.yaml": "id: login"},
+		badMD5: map[string]bool{prefix + "login.yaml": true},
+	}
+
+	if _, err := newTemplateCache(t.TempDir()).sync(context.Background(), store, prefix); err == nil {
+		t.Error("Expected hash validation to fail")
+	}
+}
+
+// TestTemplateCacheSyncEmpty tests that a missing templates directory is a validation error
+func TestTemplateCacheSyncEmpty(t *testing.T) {
+	store := &fakeTemplateStore{blobs: map[string]string{}}
+	_, err := newTemplateCache(t.TempDir()).sync(context.Background(), store, "nuclei-templates/missing/")
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeValidation {
+		t.Errorf("Expected a validation error for an empty templates directory, got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package scanners
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model"
+	"github.com/projectdiscovery/nuclei/v3/pkg/model/types/stringslice"
+	"github.com/projectdiscovery/nuclei/v3/pkg/output"
+	"github.com/projectdiscovery/nuclei/v3/pkg/types"
+)
+
+// TestSnapshotResumeState tests that a snapshot resumes from the templates' current progress
+func TestSnapshotResumeState(t *testing.T) {
+	cfg := types.NewResumeCfg()
+	cfg.Current["cves/2021/CVE-2021-44228.yaml"] = &types.ResumeInfo{InFlight: map[uint32]struct{}{3: {}, 5: {}}}
+	cfg.Current["misconfig/open-redirect.yaml"] = &types.ResumeInfo{Completed: true}
+
+	state, err := snapshotResumeState(cfg)
+	if err != nil {
+		t.Fatalf("Failed to snapshot resume state: %v", err)
+	}
+
+	restored := types.NewResumeCfg()
+	if err := json.Unmarshal(state, restored); err != nil {
+		t.Fatalf("Failed to read resume state back: %v", err)
+	}
+	restored.Compile()
+
+	if info := restored.ResumeFrom["cves/2021/CVE-2021-44228.yaml"]; info == nil || info.Completed || info.SkipUnder != 3 || info.DoAbove != 5 {
+		t.Errorf("Unexpected progress for the running template: %+v", info)
+	}
+	if info := restored.ResumeFrom["misconfig/open-redirect.yaml"]; info == nil || !info.Completed {
+		t.Errorf("Expected the finished template to be marked completed: %+v", info)
+	}
+}
+
+// TestInteractshOptions tests server selection, token scoping and disabling of OOB interactions
+func TestInteractshOptions(t *testing.T) {
+	scanner := NewNucleiScanner()
+	scanner.SetInteractsh("oast.internal.example", "secret", false, []string{"oast.tenant.example"})
+
+	opts := scanner.interactshOptions(models.NucleiInput{Domain: "example.com"})
+	if opts.NoInteractsh || opts.ServerURL != "oast.internal.example" || opts.Authorization != "secret" {
+		t.Errorf("Expected the worker's server and token, got %q/%q", opts.ServerURL, opts.Authorization)
+	}
+	if opts.CacheSize == 0 || opts.PollDuration == 0 {
+		t.Error("Expected nuclei's interactsh defaults to be kept")
+	}
+
+	opts = scanner.interactshOptions(models.NucleiInput{Domain: "example.com", InteractshServer: "oast.tenant.example"})
+	if opts.ServerURL != "oast.tenant.example" || opts.Authorization != "" {
+		t.Errorf("Expected the task's server without the worker's token, got %q/%q", opts.ServerURL, opts.Authorization)
+	}
+
+	if err := scanner.checkInteractshServer("https://OAST.tenant.example/"); err != nil {
+		t.Errorf("Expected an allowed server to be accepted, got %v", err)
+	}
+	if err := scanner.checkInteractshServer("oast.internal.example"); err != nil {
+		t.Errorf("Expected the worker's own server to be accepted, got %v", err)
+	}
+	var appErr *common.AppError
+	if err := scanner.checkInteractshServer("exfil.attacker.example"); !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeValidation {
+		t.Errorf("Expected a server outside the allowlist to be rejected, got %v", err)
+	}
+
+	if opts := scanner.interactshOptions(models.NucleiInput{Domain: "example.com", DisableInteractsh: true}); !opts.NoInteractsh {
+		t.Error("Expected the task to disable interactsh")
+	}
+
+	scanner.SetInteractsh("", "", true, nil)
+	if opts := scanner.interactshOptions(models.NucleiInput{Domain: "example.com", InteractshServer: "oast.tenant.example"}); !opts.NoInteractsh {
+		t.Error("Expected the worker-wide setting to disable interactsh for every task")
+	}
+}
+
+// TestToNucleiVulnerability tests that findings carry their classification and a curl command
+func TestToNucleiVulnerability(t *testing.T) {
+	event := &output.ResultEvent{
+		TemplateID: "CVE-2021-44228",
+		Type:       "http",
+		Host:       "https://example.com",
+		Matched:    "https://example.com/api/login",
+		Request:    "POST /api/login HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/json\r\nContent-Length: 19\r\n\r\n{\"user\":\"o'brien\"}\n",
+		Info: model.Info{
+			Name: "Log4j RCE",
+			Classification: &model.Classification{
+				CVEID:       stringslice.StringSlice{Value: "cve-2021-44228"},
+				CWEID:       stringslice.StringSlice{Value: []string{"cwe-502", "917"}},
+				CVSSScore:   10,
+				CVSSMetrics: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H",
+			},
+		},
+	}
+
+	vuln := toNucleiVulnerability(event)
+	if len(vuln.CVEIDs) != 1 || vuln.CVEIDs[0] != "CVE-2021-44228" {
+		t.Errorf("Unexpected CVE IDs: %v", vuln.CVEIDs)
+	}
+	if len(vuln.CWEIDs) != 2 || vuln.CWEIDs[0] != "CWE-502" || vuln.CWEIDs[1] != "CWE-917" {
+		t.Errorf("Unexpected CWE IDs: %v", vuln.CWEIDs)
+	}
+	if vuln.CVSSScore != 10 || vuln.CVSSMetrics == "" {
+		t.Errorf("Unexpected CVSS: %v %s", vuln.CVSSScore, vuln.CVSSMetrics)
+	}
+
+	want := `curl -X POST -H 'Content-Type: application/json' -d '{"user":"o'\''brien"}` + "\n" + `' 'https://example.com/api/login'`
+	if vuln.CurlCommand != want {
+		t.Errorf("Unexpected curl command:\n got: %s\nwant: %s", vuln.CurlCommand, want)
+	}
+}
+
+func TestResponseStatus(t *testing.T) {
+	tests := map[string]int{
+		"HTTP/1.1 403 Forbidden\r\nServer: cloudflare\r\n\r\n": 403,
+		"HTTP/2 429\r\n\r\n":  429,
+		"":                    0,
+		"SSH-2.0-OpenSSH_9.6": 0,
+	}
+	for raw, want := range tests {
+		if got := responseStatus(raw); got != want {
+			t.Errorf("responseStatus(%q) = %d, want %d", raw, got, want)
+		}
+	}
+}
+
+func TestBanError(t *testing.T) {
+	blocks := []models.BlockEvent{{Group: "example.com", Signal: models.BlockSignalRateLimited, Responses: 25, Answered: 140, Action: models.BlockActionBackoff}}
+	retrying := &models.TaskContext{BlockPolicy: models.BlockPolicy{Window: 25, RetryAfter: 2 * time.Hour}}
+
+	err := banError(retrying, "nuclei", blocks)
+	if common.RetryAfter(err) != 2*time.Hour {
+		t.Fatalf("banError() = %v, want a rate-limit error retried in 2h", err)
+	}
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeRateLimited {
+		t.Errorf("banError() = %v, want a rate-limit error", err)
+	}
+
+	if err := banError(retrying, "nuclei", nil); err != nil {
+		t.Errorf("banError() = %v for a scan that was not banned", err)
+	}
+	completing := &models.TaskContext{BlockPolicy: models.BlockPolicy{Window: 25}}
+	if err := banError(completing, "nuclei", blocks); err != nil {
+		t.Errorf("banError() = %v with retries turned off", err)
+	}
+	if err := banError(nil, "httpx", blocks); err != nil {
+		t.Errorf("banError() = %v without a task context", err)
+	}
+}
@@ -0,0 +1,459 @@
+package scanners
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/quota"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	defaultPassiveMaxRequests = 20               // Requests a source may make per scan
+	defaultPassiveRetries     = 3                // Retries of a request failing with 429, 5xx or a network error
+	passiveSourceTimeout      = 2 * time.Minute  // Time a source may take per scan
+	passiveRetryBaseDelay     = time.Second      // First retry delay, doubled on every retry
+	passiveMaxRetryDelay      = 30 * time.Second // Longest wait between retries, including Retry-After
+	passiveQuotaTimeout       = 10 * time.Second // Time a quota reservation or release may take
+	virusTotalPageSize        = 40
+)
+
+// errPassiveBudgetExhausted is returned once a source has used its request budget for the scan
+var errPassiveBudgetExhausted = errors.New("request budget exhausted")
+
+// PassiveSource enumerates subdomains of a domain from a third-party dataset
+type PassiveSource interface {
+	Name() string
+	QuotaKey() string // Identifies the API key whose quota the source uses
+	Enumerate(ctx context.Context, client *PassiveClient, domain string) ([]string, error)
+}
+
+// passiveQuotaKey identifies an API key by source name and key hash, so keys never reach storage
+func passiveQuotaKey(source, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return source + "-" + hex.EncodeToString(sum[:8])
+}
+
+// PassiveStatsStore persists the running totals of the passive sources, shared by all workers
+type PassiveStatsStore interface {
+	UpdatePassiveSourceTotals(ctx context.Context, source string, update func(*models.PassiveSourceTotals) error) error
+}
+
+// PassiveClient sends the requests of one source during one scan, retrying transient failures
+// and enforcing the source's request budget
+type PassiveClient struct {
+	httpClient *http.Client
+	maxRetries int
+	baseDelay  time.Duration
+
+	mu       sync.Mutex
+	budget   int
+	requests int
+	retries  int
+}
+
+// newPassiveClient creates a client allowed to make maxRequests requests
+func newPassiveClient(httpClient *http.Client, maxRequests int) *PassiveClient {
+	return &PassiveClient{httpClient: httpClient, maxRetries: defaultPassiveRetries, baseDelay: passiveRetryBaseDelay, budget: maxRequests}
+}
+
+// GetJSON sends a GET request with the given headers and decodes the JSON response into out.
+// Every attempt, including retries, counts against the budget.
+func (c *PassiveClient) GetJSON(ctx context.Context, requestURL string, headers map[string]string, out interface{}) error {
+	var lastErr error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if !c.take(attempt > 0) {
+			if lastErr != nil {
+				return fmt.Errorf("%w after: %v", errPassiveBudgetExhausted, lastErr)
+			}
+			return errPassiveBudgetExhausted
+		}
+
+		retryAfter, err := c.get(ctx, requestURL, headers, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if retryAfter < 0 || attempt == c.maxRetries {
+			break
+		}
+
+		delay := c.baseDelay << attempt
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+		if delay > passiveMaxRetryDelay {
+			delay = passiveMaxRetryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	return lastErr
+}
+
+// take uses one request of the budget, reporting false when none is left
+func (c *PassiveClient) take(retry bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.requests >= c.budget {
+		return false
+	}
+	c.requests++
+	if retry {
+		c.retries++
+	}
+	return true
+}
+
+// get sends one request. The returned delay is negative when the failure is not worth retrying,
+// and positive when the server asked to wait before retrying.
+func (c *PassiveClient) get(ctx context.Context, requestURL string, headers map[string]string, out interface{}) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return 0, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return retryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("rate limited (status %d)", resp.StatusCode)
+	case resp.StatusCode >= 500:
+		return 0, fmt.Errorf("API returned status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return -1, fmt.Errorf("API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return -1, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	return 0, nil
+}
+
+// retryAfter parses a Retry-After header given in seconds, returning 0 when it is absent or invalid
+func retryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// newConfiguredPassiveSources creates the passive sources that have an API key in the environment
+func newConfiguredPassiveSources() []PassiveSource {
+	var sources []PassiveSource
+	if key := os.Getenv("SUBDOMAIN_API_KEY"); key != "" {
+		sources = append(sources, &SubbdomSource{apiKey: key, baseURL: "https://api.subbdom.com/v1/search"})
+	}
+	if key := os.Getenv("SECURITYTRAILS_API_KEY"); key != "" {
+		sources = append(sources, &SecurityTrailsSource{apiKey: key, baseURL: "https://api.securitytrails.com/v1"})
+	}
+	if key := os.Getenv("VIRUSTOTAL_API_KEY"); key != "" {
+		sources = append(sources, &VirusTotalSource{apiKey: key, baseURL: "https://www.virustotal.com/api/v3"})
+	}
+	if key := os.Getenv("CHAOS_API_KEY"); key != "" {
+		sources = append(sources, &ChaosSource{apiKey: key, baseURL: "https://dns.projectdiscovery.io/dns"})
+	}
+	return sources
+}
+
+// passiveMaxRequests returns the per-source request budget from PASSIVE_SOURCE_MAX_REQUESTS
+func passiveMaxRequests() int {
+	if value, err := strconv.Atoi(os.Getenv("PASSIVE_SOURCE_MAX_REQUESTS")); err == nil && value > 0 {
+		return value
+	}
+	return defaultPassiveMaxRequests
+}
+
+// runPassiveSources queries every source concurrently and returns the subdomains found with the
+// sources that found them, and statistics per source. A failing source only loses its own results.
+// Each source's budget is reserved from its API key quota first; a source whose quota is exhausted
+// is skipped.
+func runPassiveSources(ctx context.Context, httpClient *http.Client, tracker *quota.Tracker, sources []PassiveSource, domain string, maxRequests int) (subdomainSources, map[string]models.PassiveSourceStats) {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		subdomains = make(subdomainSources)
+		stats      = make(map[string]models.PassiveSourceStats, len(sources))
+	)
+
+	for _, source := range sources {
+		wg.Add(1)
+		go func(source PassiveSource) {
+			defer wg.Done()
+
+			budget, reserved := reservePassiveQuota(ctx, tracker, source, maxRequests)
+			if budget == 0 {
+				gologger.Warning().Msgf("Skipping passive source %s for domain %s: API key quota exhausted", source.Name(), domain)
+				mu.Lock()
+				stats[source.Name()] = models.PassiveSourceStats{Duration: "0s", QuotaLimited: true, Skipped: true}
+				mu.Unlock()
+				return
+			}
+
+			sourceCtx, cancel := context.WithTimeout(ctx, passiveSourceTimeout)
+			defer cancel()
+
+			client := newPassiveClient(httpClient, budget)
+			started := time.Now()
+			found, err := source.Enumerate(sourceCtx, client, domain)
+			found = filterSubdomains(found, domain)
+
+			if reserved && client.requests < budget {
+				releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), passiveQuotaTimeout)
+				if releaseErr := tracker.Release(releaseCtx, source.Name(), source.QuotaKey(), budget-client.requests); releaseErr != nil {
+					gologger.Warning().Msgf("Failed to return unused quota of passive source %s: %v", source.Name(), releaseErr)
+				}
+				cancelRelease()
+			}
+
+			sourceStats := models.PassiveSourceStats{
+				Results:      len(found),
+				Requests:     client.requests,
+				Retries:      client.retries,
+				Duration:     time.Since(started).Round(time.Millisecond).String(),
+				QuotaLimited: budget < maxRequests,
+			}
+			if err != nil {
+				sourceStats.Error = err.Error()
+				sourceStats.BudgetExhausted = errors.Is(err, errPassiveBudgetExhausted)
+				gologger.Warning().Msgf("Passive source %s failed for domain %s after %d results: %v", source.Name(), domain, len(found), err)
+			} else {
+				gologger.Info().Msgf("Passive source %s found %d subdomains for domain: %s", source.Name(), len(found), domain)
+			}
+
+			mu.Lock()
+			for _, subdomain := range found {
+				subdomains.add(subdomain, source.Name())
+			}
+			stats[source.Name()] = sourceStats
+			mu.Unlock()
+		}(source)
+	}
+	wg.Wait()
+
+	return subdomains, stats
+}
+
+// recordPassiveStats adds the statistics of a scan to the persisted totals of each source. The
+// totals are only reported, so failures are logged rather than failing the scan.
+func recordPassiveStats(ctx context.Context, store PassiveStatsStore, stats map[string]models.PassiveSourceStats) {
+	if store == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passiveQuotaTimeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	for source, sourceStats := range stats {
+		err := store.UpdatePassiveSourceTotals(ctx, source, func(totals *models.PassiveSourceTotals) error {
+			totals.Add(sourceStats, now)
+			return nil
+		})
+		if err != nil {
+			gologger.Warning().Msgf("Failed to record statistics of passive source %s: %v", source, err)
+		}
+	}
+}
+
+// reservePassiveQuota reserves the source's request budget from its API key quota. When the
+// quota cannot be read the source keeps its full budget, since losing results is worse than
+// overspending a little; reserved then reports false so nothing is released afterwards.
+func reservePassiveQuota(ctx context.Context, tracker *quota.Tracker, source PassiveSource, maxRequests int) (budget int, reserved bool) {
+	if tracker == nil {
+		return maxRequests, false
+	}
+
+	reserveCtx, cancel := context.WithTimeout(ctx, passiveQuotaTimeout)
+	defer cancel()
+
+	budget, err := tracker.Reserve(reserveCtx, source.Name(), source.QuotaKey(), maxRequests)
+	if err != nil {
+		gologger.Warning().Msgf("Quota check failed for passive source %s, using the full budget: %v", source.Name(), err)
+		return maxRequests, false
+	}
+	if budget < maxRequests {
+		gologger.Info().Msgf("Passive source %s limited to %d requests by its API key quota", source.Name(), budget)
+	}
+	return budget, true
+}
+
+// filterSubdomains normalizes source results and drops names outside the domain
+func filterSubdomains(names []string, domain string) []string {
+	domain = strings.ToLower(domain)
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), "."), "*.")
+		if name == domain || strings.HasSuffix(name, "."+domain) {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// qualifyLabels turns subdomain labels relative to a domain into host names
+func qualifyLabels(labels []string, domain string) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label = strings.Trim(label, "."); label != "" {
+			names = append(names, label+"."+domain)
+		}
+	}
+	return names
+}
+
+// SubbdomSource queries the subbdom API
+type SubbdomSource struct {
+	apiKey  string
+	baseURL string
+}
+
+// Name returns the source name
+func (s *SubbdomSource) Name() string {
+	return "subbdom"
+}
+
+// QuotaKey identifies the source's API key
+func (s *SubbdomSource) QuotaKey() string {
+	return passiveQuotaKey(s.Name(), s.apiKey)
+}
+
+// Enumerate returns the subdomains subbdom knows for the domain
+func (s *SubbdomSource) Enumerate(ctx context.Context, client *PassiveClient, domain string) ([]string, error) {
+	var subdomains []string
+	err := client.GetJSON(ctx, s.baseURL+"?z="+url.QueryEscape(domain), map[string]string{"x-api-key": s.apiKey}, &subdomains)
+	return subdomains, err
+}
+
+// SecurityTrailsSource queries the SecurityTrails subdomain list
+type SecurityTrailsSource struct {
+	apiKey  string
+	baseURL string
+}
+
+// Name returns the source name
+func (s *SecurityTrailsSource) Name() string {
+	return "securitytrails"
+}
+
+// QuotaKey identifies the source's API key
+func (s *SecurityTrailsSource) QuotaKey() string {
+	return passiveQuotaKey(s.Name(), s.apiKey)
+}
+
+// Enumerate returns the subdomains SecurityTrails knows for the domain
+func (s *SecurityTrailsSource) Enumerate(ctx context.Context, client *PassiveClient, domain string) ([]string, error) {
+	var response struct {
+		Subdomains []string `json:"subdomains"`
+	}
+	requestURL := fmt.Sprintf("%s/domain/%s/subdomains?children_only=false", s.baseURL, url.PathEscape(domain))
+	if err := client.GetJSON(ctx, requestURL, map[string]string{"APIKEY": s.apiKey}, &response); err != nil {
+		return nil, err
+	}
+	return qualifyLabels(response.Subdomains, domain), nil
+}
+
+// VirusTotalSource queries the VirusTotal domain relationships, following the result cursor
+type VirusTotalSource struct {
+	apiKey  string
+	baseURL string
+}
+
+// Name returns the source name
+func (s *VirusTotalSource) Name() string {
+	return "virustotal"
+}
+
+// QuotaKey identifies the source's API key
+func (s *VirusTotalSource) QuotaKey() string {
+	return passiveQuotaKey(s.Name(), s.apiKey)
+}
+
+// Enumerate returns the subdomains VirusTotal knows for the domain. Pages are fetched until the
+// cursor runs out or the budget is used, keeping the subdomains of the pages already fetched.
+func (s *VirusTotalSource) Enumerate(ctx context.Context, client *PassiveClient, domain string) ([]string, error) {
+	var subdomains []string
+	cursor := ""
+	for {
+		requestURL := fmt.Sprintf("%s/domains/%s/subdomains?limit=%d", s.baseURL, url.PathEscape(domain), virusTotalPageSize)
+		if cursor != "" {
+			requestURL += "&cursor=" + url.QueryEscape(cursor)
+		}
+
+		var page struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+			Meta struct {
+				Cursor string `json:"cursor"`
+			} `json:"meta"`
+		}
+		if err := client.GetJSON(ctx, requestURL, map[string]string{"x-apikey": s.apiKey}, &page); err != nil {
+			return subdomains, err
+		}
+		for _, item := range page.Data {
+			subdomains = append(subdomains, item.ID)
+		}
+
+		if page.Meta.Cursor == "" || len(page.Data) == 0 {
+			return subdomains, nil
+		}
+		cursor = page.Meta.Cursor
+	}
+}
+
+// ChaosSource queries the ProjectDiscovery Chaos dataset
+type ChaosSource struct {
+	apiKey  string
+	baseURL string
+}
+
+// Name returns the source name
+func (s *ChaosSource) Name() string {
+	return "chaos"
+}
+
+// QuotaKey identifies the source's API key
+func (s *ChaosSource) QuotaKey() string {
+	return passiveQuotaKey(s.Name(), s.apiKey)
+}
+
+// Enumerate returns the subdomains Chaos knows for the domain
+func (s *ChaosSource) Enumerate(ctx context.Context, client *PassiveClient, domain string) ([]string, error) {
+	var response struct {
+		Subdomains []string `json:"subdomains"`
+	}
+	requestURL := fmt.Sprintf("%s/%s/subdomains", s.baseURL, url.PathEscape(domain))
+	if err := client.GetJSON(ctx, requestURL, map[string]string{"Authorization": s.apiKey}, &response); err != nil {
+		return nil, err
+	}
+	return qualifyLabels(response.Subdomains, domain), nil
+}
@@ -0,0 +1,202 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/quota"
+)
+
+func TestPassiveClient_RetriesTransientFailures(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`["www.example.com"]`))
+		}
+	}))
+	defer server.Close()
+
+	client := newPassiveClient(server.Client(), 10)
+	client.baseDelay = time.Millisecond
+
+	var subdomains []string
+	if err := client.GetJSON(context.Background(), server.URL, nil, &subdomains); err != nil {
+		t.Fatalf("GetJSON() error = %v", err)
+	}
+	if len(subdomains) != 1 || client.requests != 3 || client.retries != 2 {
+		t.Errorf("Expected success on the third attempt, got %v after %d requests and %d retries", subdomains, client.requests, client.retries)
+	}
+}
+
+func TestPassiveClient_DoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := newPassiveClient(server.Client(), 10)
+	client.baseDelay = time.Millisecond
+
+	var out []string
+	if err := client.GetJSON(context.Background(), server.URL, nil, &out); err == nil {
+		t.Fatal("Expected an error for an unauthorized request")
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retries of a 401, got %d calls", calls)
+	}
+}
+
+func TestVirusTotalSource_PaginatesWithinBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-apikey") != "vt-key" {
+			t.Errorf("Expected VirusTotal API key header, got %q", r.Header.Get("x-apikey"))
+		}
+		page := 0
+		fmt.Sscanf(r.URL.Query().Get("cursor"), "page-%d", &page)
+		fmt.Fprintf(w, `{"data":[{"id":"host%d.example.com"}],"meta":{"cursor":"page-%d"}}`, page, page+1)
+	}))
+	defer server.Close()
+
+	source := &VirusTotalSource{apiKey: "vt-key", baseURL: server.URL}
+	client := newPassiveClient(server.Client(), 3)
+
+	subdomains, err := source.Enumerate(context.Background(), client, "example.com")
+	if !errors.Is(err, errPassiveBudgetExhausted) {
+		t.Fatalf("Expected the budget to stop pagination, got %v", err)
+	}
+	if len(subdomains) != 3 {
+		t.Errorf("Expected the subdomains of the 3 fetched pages, got %v", subdomains)
+	}
+}
+
+func TestRunPassiveSources(t *testing.T) {
+	chaos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/example.com/subdomains" || r.Header.Get("Authorization") != "chaos-key" {
+			t.Errorf("Unexpected Chaos request %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"domain":"example.com","subdomains":["api","*.dev","www"]}`))
+	}))
+	defer chaos.Close()
+
+	subbdom := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`["WWW.example.com.","mail.example.com","unrelated.org"]`))
+	}))
+	defer subbdom.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer broken.Close()
+
+	sources := []PassiveSource{
+		&ChaosSource{apiKey: "chaos-key", baseURL: chaos.URL},
+		&SubbdomSource{apiKey: "key", baseURL: subbdom.URL},
+		&SecurityTrailsSource{apiKey: "key", baseURL: broken.URL},
+	}
+
+	subdomains, stats := runPassiveSources(context.Background(), http.DefaultClient, nil, sources, "example.com", 5)
+
+	expected := map[string][]string{
+		"api.example.com":  {"chaos"},
+		"dev.example.com":  {"chaos"},
+		"mail.example.com": {"subbdom"},
+		"www.example.com":  {"chaos", "subbdom"},
+	}
+	if fmt.Sprint(subdomains.sorted()) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, subdomains.sorted())
+	}
+	if stats["chaos"].Results != 3 || stats["subbdom"].Results != 2 || stats["subbdom"].Requests != 1 {
+		t.Errorf("Unexpected source statistics: %+v", stats)
+	}
+	if stats["securitytrails"].Error == "" || stats["securitytrails"].Results != 0 {
+		t.Errorf("Expected the failing source to report its error, got %+v", stats["securitytrails"])
+	}
+}
+
+// memoryQuotaStore keeps quota states in memory
+type memoryQuotaStore map[string]models.QuotaState
+
+func (s memoryQuotaStore) UpdateQuotaState(ctx context.Context, key string, update func(*models.QuotaState) error) error {
+	state := s[key]
+	if err := update(&state); err != nil {
+		return err
+	}
+	s[key] = state
+	return nil
+}
+
+func TestRunPassiveSources_QuotaExhausted(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`["www.example.com"]`))
+	}))
+	defer server.Close()
+
+	sources := []PassiveSource{&SubbdomSource{apiKey: "key", baseURL: server.URL}}
+	store := memoryQuotaStore{}
+	tracker := quota.NewTracker(store, map[string]int{"subbdom": 3})
+
+	_, stats := runPassiveSources(context.Background(), server.Client(), tracker, sources, "example.com", 2)
+	if calls != 1 || stats["subbdom"].QuotaLimited || stats["subbdom"].Skipped {
+		t.Fatalf("Expected one request within the quota, got %d calls and %+v", calls, stats["subbdom"])
+	}
+	if tokens := store[sources[0].QuotaKey()].Tokens; tokens < 1.99 || tokens > 2.01 {
+		t.Errorf("Expected the unused request returned to the quota, got %.2f tokens", tokens)
+	}
+
+	// The second scan leaves one token, which limits the third and is used by it
+	runPassiveSources(context.Background(), server.Client(), tracker, sources, "example.com", 2)
+	if _, stats = runPassiveSources(context.Background(), server.Client(), tracker, sources, "example.com", 2); !stats["subbdom"].QuotaLimited {
+		t.Errorf("Expected the third scan limited by the quota, got %+v", stats["subbdom"])
+	}
+	_, stats = runPassiveSources(context.Background(), server.Client(), tracker, sources, "example.com", 2)
+	if calls != 3 || !stats["subbdom"].Skipped {
+		t.Errorf("Expected the source skipped once its quota ran out, got %d calls and %+v", calls, stats["subbdom"])
+	}
+}
+
+// memoryStatsStore keeps passive source totals in memory
+type memoryStatsStore map[string]models.PassiveSourceTotals
+
+func (s memoryStatsStore) UpdatePassiveSourceTotals(ctx context.Context, source string, update func(*models.PassiveSourceTotals) error) error {
+	totals := s[source]
+	if err := update(&totals); err != nil {
+		return err
+	}
+	s[source] = totals
+	return nil
+}
+
+func TestRecordPassiveStats(t *testing.T) {
+	store := memoryStatsStore{}
+	recordPassiveStats(context.Background(), store, map[string]models.PassiveSourceStats{
+		"chaos":      {Results: 3, Requests: 1},
+		"virustotal": {Results: 40, Requests: 2, Retries: 1, Error: "request budget exhausted", BudgetExhausted: true},
+	})
+	recordPassiveStats(context.Background(), store, map[string]models.PassiveSourceStats{
+		"chaos": {Results: 2, Requests: 1},
+	})
+
+	if chaos := store["chaos"]; chaos.Scans != 2 || chaos.Results != 5 || chaos.Requests != 2 || chaos.Failures != 0 {
+		t.Errorf("Expected the chaos statistics added up over two scans, got %+v", chaos)
+	}
+	virusTotal := store["virustotal"]
+	if virusTotal.Failures != 1 || virusTotal.BudgetExhausted != 1 || virusTotal.Retries != 1 || virusTotal.LastError == "" {
+		t.Errorf("Expected the virustotal failure recorded, got %+v", virusTotal)
+	}
+}
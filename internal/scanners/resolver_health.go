@@ -0,0 +1,154 @@
+package scanners
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+	"github.com/projectdiscovery/retryabledns"
+)
+
+const (
+	resolverErrorThreshold = 0.5              // Error rate above which a resolver is evicted
+	resolverMinSamples     = 20               // Queries needed before a resolver's error rate is judged
+	resolverEvictionPeriod = 30 * time.Second // How long an evicted resolver is left out
+)
+
+// resolverState tracks one upstream resolver during a DNSX run
+type resolverState struct {
+	name     string
+	resolver retryabledns.Resolver
+
+	// Counters since the resolver was last judged
+	windowQueries int
+	windowErrors  int
+
+	stats        models.ResolverHealth
+	evictedUntil time.Time
+}
+
+// resolverHealth spreads queries over the healthy resolvers of a pool and evicts resolvers
+// whose error rate exceeds resolverErrorThreshold for resolverEvictionPeriod
+type resolverHealth struct {
+	mu        sync.Mutex
+	resolvers []*resolverState
+	next      int
+	now       func() time.Time
+}
+
+// newResolverHealth creates a health tracker for the resolvers of a pool
+func newResolverHealth(pool dnsResolverPool) *resolverHealth {
+	health := &resolverHealth{now: time.Now}
+	seen := make(map[string]bool, len(pool.resolvers))
+	for _, spec := range pool.resolvers {
+		resolver := parseNetworkResolver(spec)
+		if resolver == nil {
+			gologger.Warning().Msgf("Ignoring invalid DNS resolver: %s", spec)
+			continue
+		}
+		address := resolverAddress(resolver)
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+		health.resolvers = append(health.resolvers, &resolverState{
+			name:     address,
+			resolver: resolver,
+			stats:    models.ResolverHealth{Resolver: address},
+		})
+	}
+	return health
+}
+
+// resolverAddress returns the "protocol:host:port" address a resolver's health is kept under.
+// The protocol is part of it, as the same server answers both passes, over UDP and over TCP.
+func resolverAddress(resolver *retryabledns.NetworkResolver) string {
+	return string(resolver.Protocol) + ":" + resolver.String()
+}
+
+// parseNetworkResolver parses a "protocol:host:port" resolver, defaulting to UDP
+func parseNetworkResolver(spec string) *retryabledns.NetworkResolver {
+	protocol := retryabledns.UDP
+	if rest, ok := strings.CutPrefix(spec, "tcp:"); ok {
+		protocol, spec = retryabledns.TCP, rest
+	} else {
+		spec = strings.TrimPrefix(spec, "udp:")
+	}
+
+	host, port, err := net.SplitHostPort(spec)
+	if err != nil || host == "" {
+		return nil
+	}
+	return &retryabledns.NetworkResolver{Protocol: protocol, Host: host, Port: port}
+}
+
+// pick returns the next healthy resolver in round-robin order. When every resolver is
+// evicted, the one whose eviction ends first is used so that lookups never stall.
+func (h *resolverHealth) pick() *resolverState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	var fallback *resolverState
+	for range h.resolvers {
+		state := h.resolvers[h.next%len(h.resolvers)]
+		h.next++
+		if !now.Before(state.evictedUntil) {
+			return state
+		}
+		if fallback == nil || state.evictedUntil.Before(fallback.evictedUntil) {
+			fallback = state
+		}
+	}
+	return fallback
+}
+
+// record counts the outcome of a query sent to a resolver and evicts it once its error rate is too high
+func (h *resolverHealth) record(state *resolverState, status models.DNSStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state.stats.Queries++
+	state.windowQueries++
+	switch status {
+	case models.DNSStatusTimeout:
+		state.stats.Timeouts++
+		state.windowErrors++
+	case models.DNSStatusError, models.DNSStatusRefused:
+		state.stats.Errors++
+		state.windowErrors++
+	}
+
+	if state.windowQueries < resolverMinSamples {
+		return
+	}
+
+	if float64(state.windowErrors)/float64(state.windowQueries) > resolverErrorThreshold {
+		state.evictedUntil = h.now().Add(resolverEvictionPeriod)
+		state.stats.Evictions++
+		gologger.Warning().Msgf("Evicting DNS resolver %s for %s: %d/%d recent queries failed",
+			state.name, resolverEvictionPeriod, state.windowErrors, state.windowQueries)
+	}
+	state.windowQueries, state.windowErrors = 0, 0
+}
+
+// Stats returns the health of every resolver in the pool
+func (h *resolverHealth) Stats() []models.ResolverHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	stats := make([]models.ResolverHealth, 0, len(h.resolvers))
+	for _, state := range h.resolvers {
+		health := state.stats
+		if health.Queries > 0 {
+			health.ErrorRate = float64(health.Errors+health.Timeouts) / float64(health.Queries)
+		}
+		health.Evicted = now.Before(state.evictedUntil)
+		stats = append(stats, health)
+	}
+	return stats
+}
@@ -0,0 +1,85 @@
+package scanners
+
+import (
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// TestParseNetworkResolver tests resolver parsing with and without a protocol
+func TestParseNetworkResolver(t *testing.T) {
+	if r := parseNetworkResolver("udp:1.1.1.1:53"); r == nil || r.Protocol != "udp" || r.String() != "1.1.1.1:53" {
+		t.Errorf("Unexpected UDP resolver: %+v", r)
+	}
+	if r := parseNetworkResolver("tcp:8.8.8.8:53"); r == nil || r.Protocol != "tcp" {
+		t.Errorf("Unexpected TCP resolver: %+v", r)
+	}
+	if r := parseNetworkResolver("9.9.9.9"); r != nil {
+		t.Errorf("Expected resolver without port to be rejected, got %+v", r)
+	}
+}
+
+// TestResolverHealthKeyedByAddress tests that the passes over UDP and TCP to the same server
+// report separate health, and that a resolver listed twice is tracked once
+func TestResolverHealthKeyedByAddress(t *testing.T) {
+	primary := newResolverHealth(dnsResolverPool{name: "primary", resolvers: []string{"udp:1.1.1.1:53", "1.1.1.1:53"}})
+	retry := newResolverHealth(dnsResolverPool{name: "retry", resolvers: []string{"tcp:1.1.1.1:53"}})
+	primary.record(primary.pick(), models.DNSStatusResolved)
+
+	stats := append(primary.Stats(), retry.Stats()...)
+	if len(stats) != 2 || stats[0].Resolver != "udp:1.1.1.1:53" || stats[1].Resolver != "tcp:1.1.1.1:53" {
+		t.Fatalf("Expected one entry per protocol, got %+v", stats)
+	}
+	if stats[0].Queries != 1 || stats[1].Queries != 0 {
+		t.Errorf("Expected the query counted for the UDP resolver only, got %+v", stats)
+	}
+}
+
+// TestResolverHealthEviction tests that a failing resolver is evicted and load moves to healthy ones
+func TestResolverHealthEviction(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	health := newResolverHealth(dnsResolverPool{name: "test", resolvers: []string{"udp:192.0.2.1:53", "udp:192.0.2.2:53"}})
+	health.now = func() time.Time { return now }
+	bad, good := health.resolvers[0], health.resolvers[1]
+
+	for i := 0; i < resolverMinSamples; i++ {
+		health.record(bad, models.DNSStatusTimeout)
+		health.record(good, models.DNSStatusResolved)
+	}
+
+	for i := 0; i < 4; i++ {
+		if picked := health.pick(); picked != good {
+			t.Fatalf("Expected only the healthy resolver to be picked, got %s", picked.name)
+		}
+	}
+
+	stats := health.Stats()
+	if !stats[0].Evicted || stats[0].Evictions != 1 || stats[0].Timeouts != resolverMinSamples || stats[0].ErrorRate != 1 {
+		t.Errorf("Unexpected stats for the failing resolver: %+v", stats[0])
+	}
+	if stats[1].Evicted || stats[1].ErrorRate != 0 {
+		t.Errorf("Unexpected stats for the healthy resolver: %+v", stats[1])
+	}
+
+	// The resolver returns once its eviction period is over
+	now = now.Add(resolverEvictionPeriod)
+	picked := map[*resolverState]bool{health.pick(): true, health.pick(): true}
+	if !picked[bad] || !picked[good] {
+		t.Error("Expected the evicted resolver to be picked again after the eviction period")
+	}
+}
+
+// TestResolverHealthAllEvicted tests that lookups fall back to the resolver that recovers first
+func TestResolverHealthAllEvicted(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	health := newResolverHealth(dnsResolverPool{name: "test", resolvers: []string{"udp:192.0.2.1:53", "udp:192.0.2.2:53"}})
+	health.now = func() time.Time { return now }
+
+	health.resolvers[0].evictedUntil = now.Add(20 * time.Second)
+	health.resolvers[1].evictedUntil = now.Add(10 * time.Second)
+
+	if picked := health.pick(); picked != health.resolvers[1] {
+		t.Errorf("Expected the resolver whose eviction ends first, got %s", picked.name)
+	}
+}
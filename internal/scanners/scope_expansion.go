@@ -0,0 +1,370 @@
+package scanners
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/utils"
+	"github.com/projectdiscovery/gologger"
+	"golang.org/x/exp/maps"
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	scopeExpansionMaxHosts    = 500              // Hosts whose certificates are read per scan
+	scopeExpansionConcurrency = 20               // Certificates read at once
+	scopeExpansionTimeout     = 10 * time.Second // Bound of a single certificate, RDAP or ASN lookup
+	defaultRDAPBaseURL        = "https://rdap.org"
+)
+
+// ScopeExpansionScanner suggests apex domains related to the scanned domain. Candidates are the
+// apexes named in the certificates of the domain's hosts; each is backed by the evidence found
+// for it: the certificate names themselves, a registrant matching the domain's in RDAP and hosting
+// in an ASN the domain's hosts are in. Only the domain's own hosts are contacted, the candidates
+// are only looked up in DNS and RDAP.
+type ScopeExpansionScanner struct {
+	*BaseScanner
+	certificateNames func(ctx context.Context, host string) ([]string, error)
+	registrant       func(ctx context.Context, apex string) (string, error)
+	asns             func(ctx context.Context, host string) ([]string, error)
+}
+
+// NewScopeExpansionScanner creates a scope expansion scanner that reads certificates over TLS,
+// registrants from rdap.org and ASNs from the Team Cymru DNS service
+func NewScopeExpansionScanner() *ScopeExpansionScanner {
+	rdap := &rdapClient{baseURL: defaultRDAPBaseURL, httpClient: utils.NewTracingClient(scopeExpansionTimeout)}
+	return &ScopeExpansionScanner{
+		BaseScanner:      NewBaseScanner(),
+		certificateNames: fetchCertificateNames,
+		registrant:       rdap.registrant,
+		asns:             lookupASNs,
+	}
+}
+
+func (s *ScopeExpansionScanner) GetName() string {
+	return "scope_expansion"
+}
+
+func (s *ScopeExpansionScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
+	scopeInput, ok := input.(models.ScopeExpansionInput)
+	if !ok {
+		return nil, common.NewValidationError("input", "invalid input type, expected ScopeExpansionInput")
+	}
+	if err := s.ValidateInput(scopeInput); err != nil {
+		return nil, err
+	}
+
+	domainApex, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(scopeInput.Domain))
+	if err != nil {
+		return nil, common.NewValidationError("domain", fmt.Sprintf("cannot find the apex of %s: %v", scopeInput.Domain, err))
+	}
+
+	hosts := scopeInput.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{scopeInput.Domain, "www." + scopeInput.Domain}
+	}
+	hosts, dropped := taskCtx.FilterScope(hosts)
+	if dropped > 0 {
+		taskCtx.Warning().Msgf("Skipping %d hosts outside the scan scope", dropped)
+	}
+	hosts, excluded, err := filterExcluded(ctx, scopeInput.Exclude, hosts)
+	if err != nil {
+		return nil, err
+	}
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d hosts from certificate reads", excluded)
+	}
+	if len(hosts) > scopeExpansionMaxHosts {
+		taskCtx.Info().Msgf("Reading the certificates of the first %d of %d hosts", scopeExpansionMaxHosts, len(hosts))
+		hosts = hosts[:scopeExpansionMaxHosts]
+	}
+
+	// 1. Candidates are the other apexes named in the certificates of the domain's hosts
+	candidates, hostASNs := s.readCertificates(ctx, taskCtx, hosts, domainApex)
+	result := models.ScopeExpansionResult{Domain: scopeInput.Domain, HostsProbed: len(hosts), Suggestions: []models.ScopeSuggestion{}}
+	if ctx.Err() != nil {
+		result.Partial = true
+		return result, common.NewTimeoutError("scope expansion cancelled while reading certificates", ctx.Err())
+	}
+
+	// 2. Each candidate gains evidence from a matching registrant and shared hosting
+	domainRegistrant := s.lookupRegistrant(ctx, domainApex)
+	apexes := maps.Keys(candidates)
+	sort.Strings(apexes)
+	for _, apex := range apexes {
+		suggestion := models.ScopeSuggestion{Apex: apex, Evidence: []models.ScopeEvidence{{
+			Kind:   models.ScopeEvidenceCertificateSAN,
+			Detail: "named in the certificate of " + strings.Join(candidates[apex], ", "),
+		}}}
+
+		if domainRegistrant != "" && strings.EqualFold(s.lookupRegistrant(ctx, apex), domainRegistrant) {
+			suggestion.Evidence = append(suggestion.Evidence, models.ScopeEvidence{
+				Kind:   models.ScopeEvidenceWhoisRegistrant,
+				Detail: "registered to " + domainRegistrant,
+			})
+		}
+		if shared := s.sharedASNs(ctx, apex, hostASNs); len(shared) > 0 {
+			suggestion.Evidence = append(suggestion.Evidence, models.ScopeEvidence{
+				Kind:   models.ScopeEvidenceASNColocation,
+				Detail: "hosted in AS" + strings.Join(shared, ", AS"),
+			})
+		}
+		result.Suggestions = append(result.Suggestions, suggestion)
+	}
+
+	// Best supported suggestions first
+	sort.SliceStable(result.Suggestions, func(i, j int) bool {
+		return len(result.Suggestions[i].Evidence) > len(result.Suggestions[j].Evidence)
+	})
+
+	if ctx.Err() != nil {
+		result.Partial = true
+		return result, common.NewTimeoutError("scope expansion cancelled while gathering evidence", ctx.Err())
+	}
+	taskCtx.Info().Msgf("Suggested %d apex domains related to %s from %d hosts", len(result.Suggestions), scopeInput.Domain, len(hosts))
+	return result, nil
+}
+
+// readCertificates reads the certificate of every host and returns the foreign apexes named in
+// them with the hosts that named them, and the ASNs the hosts are in
+func (s *ScopeExpansionScanner) readCertificates(ctx context.Context, taskCtx *models.TaskContext, hosts []string, domainApex string) (map[string][]string, map[string]bool) {
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		candidates = make(map[string][]string)
+		hostASNs   = make(map[string]bool)
+		slots      = make(chan struct{}, scopeExpansionConcurrency)
+		done       int
+	)
+
+	for _, host := range hosts {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			names, err := s.certificateNames(ctx, host)
+			if err != nil {
+				gologger.Debug().Msgf("No certificate read from %s: %v", host, err)
+			}
+			asns, err := s.asns(ctx, host)
+			if err != nil {
+				gologger.Debug().Msgf("No ASN found for %s: %v", host, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, name := range names {
+				apex, err := publicsuffix.EffectiveTLDPlusOne(strings.ToLower(strings.TrimPrefix(name, "*.")))
+				if err != nil || apex == domainApex {
+					continue
+				}
+				if !containsString(candidates[apex], host) {
+					candidates[apex] = append(candidates[apex], host)
+				}
+			}
+			for _, asn := range asns {
+				hostASNs[asn] = true
+			}
+			done++
+			taskCtx.ReportProgress("certificates", done, len(hosts))
+		}(host)
+	}
+	wg.Wait()
+
+	for _, namedBy := range candidates {
+		sort.Strings(namedBy)
+	}
+	return candidates, hostASNs
+}
+
+// lookupRegistrant returns the registrant of an apex, or "" when it is unknown or redacted
+func (s *ScopeExpansionScanner) lookupRegistrant(ctx context.Context, apex string) string {
+	registrant, err := s.registrant(ctx, apex)
+	if err != nil {
+		gologger.Debug().Msgf("No registrant found for %s: %v", apex, err)
+		return ""
+	}
+	return registrant
+}
+
+// sharedASNs returns the ASNs of an apex that the scanned hosts are in as well
+func (s *ScopeExpansionScanner) sharedASNs(ctx context.Context, apex string, hostASNs map[string]bool) []string {
+	asns, err := s.asns(ctx, apex)
+	if err != nil {
+		gologger.Debug().Msgf("No ASN found for %s: %v", apex, err)
+		return nil
+	}
+	var shared []string
+	for _, asn := range asns {
+		if hostASNs[asn] && !containsString(shared, asn) {
+			shared = append(shared, asn)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
+
+// fetchCertificateNames returns the names in the certificate a host serves on port 443. The
+// certificate is not verified, since expired and self-signed certificates name related domains too.
+func fetchCertificateNames(ctx context.Context, host string) ([]string, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: scopeExpansionTimeout},
+		Config:    &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, scopeExpansionTimeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(dialCtx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificate presented")
+	}
+	leaf := certificates[0]
+	names := append([]string{}, leaf.DNSNames...)
+	if leaf.Subject.CommonName != "" {
+		names = append(names, leaf.Subject.CommonName)
+	}
+	return names, nil
+}
+
+// lookupASNs returns the ASNs announcing the IPv4 addresses of a host, from the Team Cymru
+// origin.asn.cymru.com DNS service
+func lookupASNs(ctx context.Context, host string) ([]string, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, scopeExpansionTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(lookupCtx, "ip4", host)
+	if err != nil {
+		return nil, err
+	}
+	var asns []string
+	for _, ip := range ips {
+		ip4 := ip.To4()
+		records, err := net.DefaultResolver.LookupTXT(lookupCtx, fmt.Sprintf("%d.%d.%d.%d.origin.asn.cymru.com", ip4[3], ip4[2], ip4[1], ip4[0]))
+		if err != nil {
+			continue
+		}
+		for _, record := range records {
+			for _, asn := range parseCymruOrigin(record) {
+				if !containsString(asns, asn) {
+					asns = append(asns, asn)
+				}
+			}
+		}
+	}
+	return asns, nil
+}
+
+// parseCymruOrigin returns the ASNs of a Team Cymru origin record, e.g.
+// "15169 | 8.8.8.0/24 | US | arin | 2023-12-28"; an address announced by several ASNs lists them all
+func parseCymruOrigin(record string) []string {
+	asnField, _, _ := strings.Cut(record, "|")
+	return strings.Fields(asnField)
+}
+
+// rdapClient looks up domain registrants over RDAP
+type rdapClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// rdapDomain is the part of an RDAP domain response that names its entities
+type rdapDomain struct {
+	Entities []rdapEntity `json:"entities"`
+}
+
+// rdapEntity is a contact of an RDAP domain, described by a jCard
+type rdapEntity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+	Entities   []rdapEntity      `json:"entities"`
+}
+
+// registrant returns the organization, or else the name, of the registrant of an apex
+func (c *rdapClient) registrant(ctx context.Context, apex string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/domain/"+apex, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("RDAP returned status %d", resp.StatusCode)
+	}
+
+	var domain rdapDomain
+	if err := json.NewDecoder(resp.Body).Decode(&domain); err != nil {
+		return "", fmt.Errorf("failed to parse RDAP response: %w", err)
+	}
+	return rdapRegistrant(domain.Entities), nil
+}
+
+// rdapRegistrant finds the registrant among the entities and returns its organization or name.
+// Redacted contacts, which most registrars publish for privacy, count as unknown.
+func rdapRegistrant(entities []rdapEntity) string {
+	for _, entity := range entities {
+		if containsString(entity.Roles, "registrant") {
+			properties := map[string]string{}
+			if len(entity.VCardArray) == 2 {
+				var vcard [][]json.RawMessage
+				json.Unmarshal(entity.VCardArray[1], &vcard)
+				for _, property := range vcard {
+					var name, value string
+					if len(property) == 4 && json.Unmarshal(property[0], &name) == nil && json.Unmarshal(property[3], &value) == nil {
+						properties[name] = strings.TrimSpace(value)
+					}
+				}
+			}
+			for _, field := range []string{"org", "fn"} {
+				value := properties[field]
+				lower := strings.ToLower(value)
+				if value != "" && !strings.Contains(lower, "redacted") && !strings.Contains(lower, "privacy") {
+					return value
+				}
+			}
+		}
+		if registrant := rdapRegistrant(entity.Entities); registrant != "" {
+			return registrant
+		}
+	}
+	return ""
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// TestScopeExpansionEvidence tests that certificate candidates gain registrant and ASN evidence
+func TestScopeExpansionEvidence(t *testing.T) {
+	scanner := NewScopeExpansionScanner()
+	probed := make(map[string]bool)
+	scanner.certificateNames = func(ctx context.Context, host string) ([]string, error) {
+		probed[host] = true
+		switch host {
+		case "www.example.com":
+			return []string{"www.example.com", "*.example-cdn.net", "shop.example.org"}, nil
+		case "api.example.com":
+			return []string{"api.example.com", "example.org"}, nil
+		}
+		return nil, fmt.Errorf("connection refused")
+	}
+	scanner.registrant = func(ctx context.Context, apex string) (string, error) {
+		switch apex {
+		case "example.com", "example.org":
+			return "Example Inc", nil
+		}
+		return "Someone Else", nil
+	}
+	scanner.asns = func(ctx context.Context, host string) ([]string, error) {
+		if host == "example-cdn.net" {
+			return []string{"64500"}, nil
+		}
+		return []string{"64496"}, nil
+	}
+
+	taskCtx := models.NewTaskContext(&models.TaskMessage{Domain: "example.com", Task: models.TaskScopeExpansion}, time.Time{})
+	input := models.ScopeExpansionInput{Domain: "example.com", Hosts: []string{"www.example.com", "api.example.com", "other.net"}}
+	result, err := scanner.Execute(context.Background(), taskCtx, input)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	expansion := result.(models.ScopeExpansionResult)
+
+	if probed["other.net"] {
+		t.Errorf("Expected hosts outside the scan scope not to be contacted")
+	}
+	if expansion.HostsProbed != 2 || len(expansion.Suggestions) != 2 {
+		t.Fatalf("Expected 2 suggestions from 2 hosts, got %+v", expansion)
+	}
+
+	// example.org has a matching registrant and shares the hosts' ASN, so it comes first
+	org := expansion.Suggestions[0]
+	if org.Apex != "example.org" || len(org.Evidence) != 3 {
+		t.Fatalf("Expected example.org with 3 kinds of evidence first, got %+v", org)
+	}
+	if org.Evidence[0].Detail != "named in the certificate of api.example.com, www.example.com" {
+		t.Errorf("Unexpected certificate evidence %q", org.Evidence[0].Detail)
+	}
+	if org.Evidence[1].Kind != models.ScopeEvidenceWhoisRegistrant || org.Evidence[2].Kind != models.ScopeEvidenceASNColocation {
+		t.Errorf("Expected registrant and ASN evidence, got %+v", org.Evidence)
+	}
+	if cdn := expansion.Suggestions[1]; cdn.Apex != "example-cdn.net" || len(cdn.Evidence) != 1 {
+		t.Errorf("Expected example-cdn.net with only certificate evidence, got %+v", cdn)
+	}
+}
+
+// TestScopeExpansionCancelled tests that a cancelled scan returns a partial result
+func TestScopeExpansionCancelled(t *testing.T) {
+	scanner := NewScopeExpansionScanner()
+	scanner.certificateNames = func(ctx context.Context, host string) ([]string, error) { return nil, ctx.Err() }
+	scanner.asns = func(ctx context.Context, host string) ([]string, error) { return nil, ctx.Err() }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := scanner.Execute(ctx, nil, models.ScopeExpansionInput{Domain: "example.com"})
+	if err == nil || !result.(models.ScopeExpansionResult).Partial {
+		t.Errorf("Expected a partial result and an error, got %+v, %v", result, err)
+	}
+}
+
+func TestParseCymruOrigin(t *testing.T) {
+	tests := map[string][]string{
+		"15169 | 8.8.8.0/24 | US | arin | 2023-12-28":         {"15169"},
+		"64496 64497 | 192.0.2.0/24 | US | arin | 2020-01-01": {"64496", "64497"},
+		"": nil,
+	}
+	for record, expected := range tests {
+		if got := parseCymruOrigin(record); fmt.Sprint(got) != fmt.Sprint(expected) {
+			t.Errorf("parseCymruOrigin(%q) = %v, expected %v", record, got, expected)
+		}
+	}
+}
+
+func TestRDAPRegistrant(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected string
+	}{
+		{
+			name:     "organization preferred over name",
+			response: `{"entities":[{"roles":["registrant"],"vcardArray":["vcard",[["version",{},"text","4.0"],["fn",{},"text","Jane Doe"],["org",{},"text","Example Inc"]]]}]}`,
+			expected: "Example Inc",
+		},
+		{
+			name:     "redacted organization falls back to name",
+			response: `{"entities":[{"roles":["registrant"],"vcardArray":["vcard",[["org",{},"text","REDACTED FOR PRIVACY"],["fn",{},"text","Jane Doe"]]]}]}`,
+			expected: "Jane Doe",
+		},
+		{
+			name:     "registrant nested under the registrar",
+			response: `{"entities":[{"roles":["registrar"],"entities":[{"roles":["registrant"],"vcardArray":["vcard",[["org",{},"text","Example Inc"]]]}]}]}`,
+			expected: "Example Inc",
+		},
+		{
+			name:     "privacy service",
+			response: `{"entities":[{"roles":["registrant"],"vcardArray":["vcard",[["org",{},"text","Domains By Proxy, Privacy Service"]]]}]}`,
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var domain rdapDomain
+			if err := json.Unmarshal([]byte(tt.response), &domain); err != nil {
+				t.Fatalf("Invalid fixture: %v", err)
+			}
+			if got := rdapRegistrant(domain.Entities); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package scanners
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/allsafeASM/api/internal/models"
+	"github.com/projectdiscovery/gologger"
+)
+
+const (
+	nucleiTemplatesDir      = "/root/nuclei-templates"
+	subfinderConfigDir      = "/root/.config/subfinder"
+	subfinderProviderConfig = subfinderConfigDir + "/provider-config.yaml"
+)
+
+// selfTester is implemented by scanners that depend on capabilities or files of the host
+type selfTester interface {
+	SelfTest() error
+}
+
+// SelfTest checks that the host has what each scanner needs and disables the task types whose
+// scanner cannot run, so their tasks fail with the reason up front instead of midway through a
+// scan. It returns why each disabled task type was disabled.
+func (factory *ScannerFactory) SelfTest() map[models.Task]error {
+	if factory.disabled == nil {
+		factory.disabled = make(map[models.Task]error)
+	}
+	for task, scanner := range factory.scanners {
+		tester, ok := scanner.(selfTester)
+		if !ok {
+			continue
+		}
+		if err := tester.SelfTest(); err != nil {
+			factory.disabled[task] = err
+		}
+	}
+
+	disabled := make(map[models.Task]error, len(factory.disabled))
+	for task, err := range factory.disabled {
+		disabled[task] = err
+	}
+	return disabled
+}
+
+// Enable keeps only the scanners of the given task types. The others are not registered, and
+// their task types are reported as disabled.
+func (factory *ScannerFactory) Enable(tasks []models.Task) {
+	enabled := make(map[models.Task]bool, len(tasks))
+	for _, task := range tasks {
+		enabled[task] = true
+	}
+	if factory.disabled == nil {
+		factory.disabled = make(map[models.Task]error)
+	}
+	for task := range factory.scanners {
+		if !enabled[task] {
+			delete(factory.scanners, task)
+			factory.disabled[task] = errors.New("not in ENABLED_TASKS")
+		}
+	}
+}
+
+// DisabledReason returns why a task type is disabled on this worker, or nil when it can run
+func (factory *ScannerFactory) DisabledReason(task models.Task) error {
+	return factory.disabled[task]
+}
+
+// SelfTest checks that SYN scans can open raw sockets. Without them the scanner is not disabled
+// but switched to connect scans, which are slower and skip host discovery.
+func (s *NaabuScanner) SelfTest() error {
+	if err := checkRawSockets(); err != nil {
+		s.connectOnly = true
+		gologger.Warning().Msgf("Naabu falls back to connect scans without host discovery: SYN scans need raw sockets; run the container with --cap-add=NET_RAW or as root: %v", err)
+	}
+	return nil
+}
+
+// DetectCapabilities returns the capabilities the host has, of those tasks can require
+func DetectCapabilities() []string {
+	var capabilities []string
+	if checkRawSockets() == nil {
+		capabilities = append(capabilities, models.CapabilityNetRaw)
+	}
+	return capabilities
+}
+
+// checkRawSockets opens and closes a raw socket
+func checkRawSockets() error {
+	conn, err := net.ListenIP("ip4:tcp", &net.IPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// SelfTest checks that the bundled templates are present
+func (s *NucleiScanner) SelfTest() error {
+	entries, err := os.ReadDir(nucleiTemplatesDir)
+	if err != nil {
+		return fmt.Errorf("nuclei templates are missing; copy them to %s as the Dockerfile does: %w", nucleiTemplatesDir, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("nuclei templates are missing; %s is empty", nucleiTemplatesDir)
+	}
+	return nil
+}
+
+// SelfTest checks that subfinder can read its provider configuration and write its config directory
+func (s *SubfinderScanner) SelfTest() error {
+	return checkConfigDir(subfinderConfigDir, subfinderProviderConfig)
+}
+
+// checkConfigDir checks that a config directory is writable and that its config file, if any, is readable
+func checkConfigDir(dir, configFile string) error {
+	probe, err := os.CreateTemp(dir, ".selftest-*")
+	if err != nil {
+		return fmt.Errorf("config directory %s is not writable; create it as the Dockerfile does: %w", dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if file, err := os.Open(configFile); err == nil {
+		file.Close()
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("cannot read %s: %w", filepath.Base(configFile), err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// selfTestingScanner is a scanner whose self-test returns a fixed error
+type selfTestingScanner struct {
+	*BaseScanner
+	err error
+}
+
+func (s *selfTestingScanner) GetName() string { return "self_testing" }
+
+func (s *selfTestingScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
+	return nil, nil
+}
+
+func (s *selfTestingScanner) SelfTest() error { return s.err }
+
+func TestScannerFactorySelfTest(t *testing.T) {
+	factory := &ScannerFactory{scanners: map[models.Task]models.Scanner{
+		models.TaskNaabu:      &selfTestingScanner{BaseScanner: NewBaseScanner(), err: errors.New("no raw sockets")},
+		models.TaskNuclei:     &selfTestingScanner{BaseScanner: NewBaseScanner()},
+		models.TaskDNSResolve: NewDNSXScanner(),
+	}}
+
+	disabled := factory.SelfTest()
+	if len(disabled) != 1 || disabled[models.TaskNaabu] == nil {
+		t.Fatalf("Expected only naabu to be disabled, got %v", disabled)
+	}
+	if _, err := factory.GetScanner(models.TaskNaabu); err == nil || !strings.Contains(err.Error(), "no raw sockets") {
+		t.Errorf("Expected the disabled scanner to be refused with its reason, got %v", err)
+	}
+	if _, err := factory.GetScanner(models.TaskNuclei); err != nil {
+		t.Errorf("Expected nuclei to stay enabled, got %v", err)
+	}
+	if available := factory.GetAvailableScanners(); len(available) != 2 {
+		t.Errorf("Expected 2 available scanners, got %v", available)
+	}
+}
+
+func TestScannerFactoryEnable(t *testing.T) {
+	factory := NewScannerFactory()
+	factory.Enable([]models.Task{models.TaskSubfinder, models.TaskDNSResolve})
+
+	if available := factory.GetAvailableScanners(); len(available) != 2 {
+		t.Errorf("Expected only the enabled scanners to be registered, got %v", available)
+	}
+	if reason := factory.DisabledReason(models.TaskNaabu); reason == nil || !strings.Contains(reason.Error(), "ENABLED_TASKS") {
+		t.Errorf("Expected naabu to be disabled by ENABLED_TASKS, got %v", reason)
+	}
+	if reason := factory.DisabledReason(models.TaskDNSResolve); reason != nil {
+		t.Errorf("Expected DNSX to stay enabled, got %v", reason)
+	}
+}
+
+func TestCheckConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "provider-config.yaml")
+	if err := checkConfigDir(dir, configFile); err != nil {
+		t.Errorf("Expected a writable directory without a config file to pass, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the write probe to be removed, found %d entries", len(entries))
+	}
+
+	if err := checkConfigDir(filepath.Join(dir, "missing"), configFile); err == nil {
+		t.Error("Expected a missing directory to fail")
+	}
+}
+
+func TestNaabuSelfTestFallsBackToConnectScans(t *testing.T) {
+	scanner := NewNaabuScanner(nil)
+	if err := scanner.SelfTest(); err != nil {
+		t.Fatalf("Expected naabu never to be disabled, got %v", err)
+	}
+	if scanner.connectOnly != (checkRawSockets() != nil) {
+		t.Errorf("Expected connect scans only without raw sockets, got connectOnly=%v", scanner.connectOnly)
+	}
+}
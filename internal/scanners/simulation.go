@@ -0,0 +1,141 @@
+package scanners
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// SimulationDomainPlaceholder is replaced with the task's domain in fixture results
+const SimulationDomainPlaceholder = "{{domain}}"
+
+// simulationFixtures are the fixture results used when no fixture blob overrides them
+//
+//go:embed testdata/simulation/*.json
+var simulationFixtures embed.FS
+
+// simulationDecoders decode each task's fixture into its result type
+var simulationDecoders = map[models.Task]func(content []byte) (models.ScannerResult, error){
+	models.TaskSubfinder:      decodeFixture[models.SubfinderResult],
+	models.TaskDNSResolve:     decodeFixture[models.DNSXResult],
+	models.TaskNaabu:          decodeFixture[models.NaabuResult],
+	models.TaskHttpx:          decodeFixture[models.HttpxResult],
+	models.TaskNuclei:         decodeFixture[models.NucleiResult],
+	models.TaskScopeExpansion: decodeFixture[models.ScopeExpansionResult],
+	models.TaskCloudDNS:       decodeFixture[models.CloudDNSResult],
+}
+
+// decodeFixture decodes a fixture into a result of type T
+func decodeFixture[T models.ScannerResult](content []byte) (models.ScannerResult, error) {
+	var result T
+	if err := json.Unmarshal(content, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SimulatedScanner returns a fixture result instead of scanning, so the orchestration, storage
+// and notification flow can run in demos and staging without any network activity toward
+// targets. The same task and domain always give the same result.
+type SimulatedScanner struct {
+	*BaseScanner
+	task       models.Task
+	blobClient *azure.BlobStorageClient
+	blobPrefix string
+}
+
+// NewSimulatedScanner creates a simulated scanner for a task type. Fixtures are read from
+// "<blobPrefix>/<task>.json" when blobPrefix is set and the blob exists, and from the built-in
+// fixtures otherwise.
+func NewSimulatedScanner(task models.Task, blobClient *azure.BlobStorageClient, blobPrefix string) *SimulatedScanner {
+	return &SimulatedScanner{
+		BaseScanner: NewBaseScanner(),
+		task:        task,
+		blobClient:  blobClient,
+		blobPrefix:  strings.Trim(blobPrefix, "/"),
+	}
+}
+
+func (s *SimulatedScanner) GetName() string {
+	return string(s.task)
+}
+
+// Execute returns the task's fixture result for the input's domain
+func (s *SimulatedScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
+	scannerInput, ok := input.(models.ScannerInput)
+	if !ok {
+		return nil, fmt.Errorf("invalid input type for %s simulation", s.task)
+	}
+	domain := scannerInput.GetDomain()
+
+	fixture, source, err := s.loadFixture(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fixture = []byte(strings.ReplaceAll(string(fixture), SimulationDomainPlaceholder, domain))
+
+	decode, ok := simulationDecoders[s.task]
+	if !ok {
+		return nil, fmt.Errorf("no simulation fixture for task type: %s", s.task)
+	}
+	result, err := decode(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s simulation fixture %s: %w", s.task, source, err)
+	}
+
+	// Findings reach the handler's callback as they would from a real nuclei scan
+	nucleiResult, isNuclei := result.(models.NucleiResult)
+	if nucleiInput, ok := input.(models.NucleiInput); ok && isNuclei && nucleiInput.OnResult != nil {
+		for _, finding := range nucleiResult.Vulnerabilities {
+			nucleiInput.OnResult(finding)
+		}
+	}
+
+	if taskCtx != nil {
+		taskCtx.Info().Msgf("Simulated %s for %s from fixture %s", s.task, domain, source)
+	}
+	// A fixture marked partial plays a scan cut short by its timeout
+	if models.IsPartialResult(result) {
+		return result, common.NewTimeoutError(fmt.Sprintf("simulated %s scan timed out", s.task), context.DeadlineExceeded)
+	}
+	return result, nil
+}
+
+// loadFixture reads the task's fixture from the fixture blob, falling back to the built-in one
+// when the blob does not exist. It also returns where the fixture was read from.
+func (s *SimulatedScanner) loadFixture(ctx context.Context) ([]byte, string, error) {
+	name := string(s.task) + ".json"
+	if s.blobPrefix != "" && s.blobClient != nil {
+		blobPath := s.blobPrefix + "/" + name
+		content, err := s.blobClient.ReadFileFromBlob(ctx, blobPath)
+		if err == nil {
+			return content, blobPath, nil
+		}
+		if !bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, "", fmt.Errorf("failed to read simulation fixture %s: %w", blobPath, err)
+		}
+	}
+
+	builtIn := path.Join("testdata/simulation", name)
+	content, err := simulationFixtures.ReadFile(builtIn)
+	if err != nil {
+		return nil, "", fmt.Errorf("no simulation fixture for task type: %s", s.task)
+	}
+	return content, builtIn, nil
+}
+
+// Simulate replaces every scanner with a SimulatedScanner. Fixture blobs are read from blobPrefix
+// when it is set.
+func (factory *ScannerFactory) Simulate(blobPrefix string) {
+	for task := range factory.scanners {
+		factory.scanners[task] = NewSimulatedScanner(task, factory.blobClient, blobPrefix)
+	}
+}
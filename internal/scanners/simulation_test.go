@@ -0,0 +1,80 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/azure"
+	"github.com/allsafeASM/api/internal/azure/azuretest"
+	"github.com/allsafeASM/api/internal/common"
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestSimulatedScanner_Fixtures(t *testing.T) {
+	factory := NewScannerFactory()
+	factory.Simulate("")
+
+	inputs := map[models.Task]models.ScannerInput{
+		models.TaskSubfinder:      models.SubfinderInput{Domain: "example.com"},
+		models.TaskDNSResolve:     models.DNSXInput{Domain: "example.com"},
+		models.TaskNaabu:          models.NaabuInput{Domain: "example.com"},
+		models.TaskHttpx:          models.HttpxInput{Domain: "example.com"},
+		models.TaskNuclei:         models.NucleiInput{Domain: "example.com"},
+		models.TaskScopeExpansion: models.ScopeExpansionInput{Domain: "example.com"},
+		models.TaskCloudDNS:       models.CloudDNSInput{Domain: "example.com"},
+	}
+	for task, input := range inputs {
+		scanner, err := factory.GetScanner(task)
+		if err != nil {
+			t.Fatalf("GetScanner(%s) error = %v", task, err)
+		}
+		result, err := scanner.Execute(context.Background(), nil, input)
+		if err != nil {
+			t.Fatalf("Execute(%s) error = %v", task, err)
+		}
+		if result.GetDomain() != "example.com" || result.GetCount() == 0 {
+			t.Errorf("Execute(%s) = %+v, want fixture results for example.com", task, result)
+		}
+	}
+}
+
+func TestSimulatedScanner_NucleiFindings(t *testing.T) {
+	var findings []models.NucleiVulnerability
+	input := models.NucleiInput{Domain: "example.org", OnResult: func(finding models.NucleiVulnerability) {
+		findings = append(findings, finding)
+	}}
+
+	result, err := NewSimulatedScanner(models.TaskNuclei, nil, "").Execute(context.Background(), nil, input)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if len(findings) != result.GetCount() {
+		t.Errorf("OnResult received %d findings, want %d", len(findings), result.GetCount())
+	}
+	for _, finding := range findings {
+		if !strings.Contains(finding.Host, "example.org") {
+			t.Errorf("Finding host %q does not name the task's domain", finding.Host)
+		}
+	}
+}
+
+func TestSimulatedScanner_PartialFixture(t *testing.T) {
+	server := azuretest.NewServer()
+	defer server.Close()
+	blobClient, err := azure.NewBlobStorageClient(server.ConnectionString(), "scans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.PutBlob("scans", "fixtures/httpx.json", []byte(`{"domain": "{{domain}}", "output": [{"host": "www.{{domain}}", "status_code": 200}], "partial": true}`))
+
+	result, err := NewSimulatedScanner(models.TaskHttpx, blobClient, "fixtures").Execute(context.Background(), nil, models.HttpxInput{Domain: "example.com"})
+	var appErr *common.AppError
+	if !errors.As(err, &appErr) || appErr.Type != common.ErrorTypeTimeout {
+		t.Fatalf("Execute() error = %v, want a timeout for a partial fixture", err)
+	}
+	if !models.IsPartialResult(result) || result.GetCount() != 1 {
+		t.Errorf("Execute() = %+v, want the partial fixture result", result)
+	}
+}
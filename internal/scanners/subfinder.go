@@ -3,17 +3,17 @@ package scanners
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/allsafeASM/api/internal/common"
 	"github.com/allsafeASM/api/internal/models"
+	"github.com/allsafeASM/api/internal/quota"
+	"github.com/allsafeASM/api/internal/utils"
 	"github.com/projectdiscovery/gologger"
 	"github.com/projectdiscovery/subfinder/v2/pkg/runner"
 	"github.com/projectdiscovery/subfinder/v2/pkg/subscraping"
@@ -23,19 +23,39 @@ import (
 // SubfinderScanner implements the Scanner interface for subfinder
 type SubfinderScanner struct {
 	*BaseScanner
-	apiKey string
+	passiveSources     []PassiveSource
+	passiveHTTPClient  *http.Client
+	passiveMaxRequests int
+	quotaTracker       *quota.Tracker
+	statsStore         PassiveStatsStore
 }
 
-// NewSubfinderScanner creates a new subfinder scanner
+// NewSubfinderScanner creates a new subfinder scanner with the passive sources configured in the environment
 func NewSubfinderScanner() *SubfinderScanner {
-	apiKey := os.Getenv("SUBDOMAIN_API_KEY")
 	return &SubfinderScanner{
-		BaseScanner: NewBaseScanner(),
-		apiKey:      apiKey,
+		BaseScanner:        NewBaseScanner(),
+		passiveSources:     newConfiguredPassiveSources(),
+		passiveHTTPClient:  utils.NewTracingClient(30 * time.Second),
+		passiveMaxRequests: passiveMaxRequests(),
 	}
 }
 
-func (s *SubfinderScanner) Execute(ctx context.Context, input interface{}) (models.ScannerResult, error) {
+// SetPassiveSources replaces the passive sources queried next to subfinder
+func (s *SubfinderScanner) SetPassiveSources(sources ...PassiveSource) {
+	s.passiveSources = sources
+}
+
+// SetQuotaTracker sets the tracker that limits passive sources to their API key quotas
+func (s *SubfinderScanner) SetQuotaTracker(tracker *quota.Tracker) {
+	s.quotaTracker = tracker
+}
+
+// SetPassiveStatsStore sets the store the passive source statistics of every scan are added to
+func (s *SubfinderScanner) SetPassiveStatsStore(store PassiveStatsStore) {
+	s.statsStore = store
+}
+
+func (s *SubfinderScanner) Execute(ctx context.Context, taskCtx *models.TaskContext, input interface{}) (models.ScannerResult, error) {
 	// Type assert and validate input
 	subfinderInput, ok := input.(models.SubfinderInput)
 	if !ok {
@@ -46,88 +66,84 @@ func (s *SubfinderScanner) Execute(ctx context.Context, input interface{}) (mode
 	if err := s.ValidateInput(subfinderInput); err != nil {
 		return nil, err
 	}
+	excluder, err := subfinderInput.Exclude.Excluder()
+	if err != nil {
+		return nil, common.NewValidationError("exclude", err.Error())
+	}
+	filter, err := subfinderInput.Filter.Compile()
+	if err != nil {
+		return nil, common.NewValidationError("filter", err.Error())
+	}
 
-	// Collect subdomains from multiple sources
-	var allSubdomains []string
+	// Collect subdomains from multiple sources, remembering which sources found each
+	allSubdomains := make(subdomainSources)
 
-	// 1. Get subdomains from API if API key is available
-	if s.apiKey != "" {
-		apiSubdomains, err := s.fetchSubdomainsFromAPI(ctx, subfinderInput.Domain)
-		if err != nil {
-			gologger.Warning().Msgf("Failed to fetch subdomains from API: %v", err)
-		} else {
-			allSubdomains = append(allSubdomains, apiSubdomains...)
-			gologger.Info().Msgf("API found %d subdomains for domain: %s", len(apiSubdomains), subfinderInput.Domain)
-		}
+	// 1. Get subdomains from the passive sources that have an API key
+	var sourceStats map[string]models.PassiveSourceStats
+	if len(s.passiveSources) > 0 {
+		var passiveSubdomains subdomainSources
+		passiveSubdomains, sourceStats = runPassiveSources(ctx, s.passiveHTTPClient, s.quotaTracker, s.passiveSources, subfinderInput.Domain, s.passiveMaxRequests)
+		recordPassiveStats(ctx, s.statsStore, sourceStats)
+		allSubdomains.merge(passiveSubdomains)
 	}
 
+	taskCtx.ReportProgress("passive_sources", 1, 2)
+
 	// 2. Get subdomains from subfinder tool
 	subfinderSubdomains, err := s.runSubfinder(ctx, subfinderInput.Domain)
 	if err != nil {
 		gologger.Warning().Msgf("Failed to run subfinder: %v", err)
 	} else {
-		allSubdomains = append(allSubdomains, subfinderSubdomains...)
+		allSubdomains.merge(subfinderSubdomains)
 		gologger.Info().Msgf("Subfinder found %d subdomains for domain: %s", len(subfinderSubdomains), subfinderInput.Domain)
 	}
 
-	// Remove duplicates and sort
-	uniqueSubdomains := s.removeDuplicates(allSubdomains)
-	sort.Strings(uniqueSubdomains)
+	taskCtx.ReportProgress("subfinder", 2, 2)
 
 	// Ensure the main domain is included
-	if !s.contains(uniqueSubdomains, subfinderInput.Domain) {
-		uniqueSubdomains = append(uniqueSubdomains, subfinderInput.Domain)
-		sort.Strings(uniqueSubdomains)
+	allSubdomains.add(subfinderInput.Domain, models.SubdomainSourceInput)
+
+	// Excluded names are left out of the result so later stages never receive them
+	excluded := 0
+	for subdomain := range allSubdomains {
+		if excluder.Excludes(subdomain) {
+			delete(allSubdomains, subdomain)
+			excluded++
+		}
 	}
-
-	gologger.Info().Msgf("Total unique subdomains found: %d for domain: %s", len(uniqueSubdomains), subfinderInput.Domain)
-
-	return models.SubfinderResult{
-		Domain:     subfinderInput.Domain,
-		Subdomains: uniqueSubdomains,
-	}, nil
-}
-
-// fetchSubdomainsFromAPI makes an HTTP request to the subdomain API endpoint
-func (s *SubfinderScanner) fetchSubdomainsFromAPI(ctx context.Context, domain string) ([]string, error) {
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	if excluded > 0 {
+		taskCtx.Info().Msgf("Excluded %d subdomains of %s", excluded, subfinderInput.Domain)
 	}
 
-	// Create request
-	url := fmt.Sprintf("https://api.subbdom.com/v1/search?z=%s", domain)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	// The include and exclude patterns narrow the discovered names; the scanned domain is kept
+	filtered := 0
+	for subdomain := range allSubdomains {
+		if subdomain != subfinderInput.Domain && !filter.Keep(subdomain) {
+			delete(allSubdomains, subdomain)
+			filtered++
+		}
 	}
-
-	// Add API key header
-	req.Header.Set("x-api-key", s.apiKey)
-
-	// Make the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+	if filtered > 0 {
+		taskCtx.Info().Msgf("Filtered out %d subdomains of %s by pattern", filtered, subfinderInput.Domain)
 	}
-	defer resp.Body.Close()
 
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API returned non-200 status: %d", resp.StatusCode)
-	}
+	uniqueSubdomains := maps.Keys(allSubdomains)
+	sort.Strings(uniqueSubdomains)
 
-	// Parse JSON response
-	var subdomains []string
-	if err := json.NewDecoder(resp.Body).Decode(&subdomains); err != nil {
-		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
-	}
+	gologger.Info().Msgf("Total unique subdomains found: %d for domain: %s", len(uniqueSubdomains), subfinderInput.Domain)
 
-	return subdomains, nil
+	return models.SubfinderResult{
+		Domain:           subfinderInput.Domain,
+		Subdomains:       uniqueSubdomains,
+		Sources:          sourceStats,
+		SubdomainSources: allSubdomains.sorted(),
+		Filtered:         filtered,
+	}, nil
 }
 
-// runSubfinder executes the subfinder tool and returns the results
-func (s *SubfinderScanner) runSubfinder(ctx context.Context, domain string) ([]string, error) {
+// runSubfinder executes the subfinder tool and returns the subdomains with the subfinder sources
+// that found them
+func (s *SubfinderScanner) runSubfinder(ctx context.Context, domain string) (subdomainSources, error) {
 	// Configure Subfinder options with optimized settings
 	subfinderOpts := &runner.Options{
 		Threads:            10,
@@ -135,7 +151,7 @@ func (s *SubfinderScanner) runSubfinder(ctx context.Context, domain string) ([]s
 		MaxEnumerationTime: 30, // 30 seconds max enumeration time
 		RateLimit:          1000,
 		All:                true,
-		ProviderConfig:     "/root/.config/subfinder/provider-config.yaml",
+		ProviderConfig:     subfinderProviderConfig,
 		//ExcludeSources:     []string{"bufferover", "crtsh", "dnsdumpster", "hackertarget", "rapiddns", "threatcrowd", "virustotal", "zoomeye"},
 	}
 
@@ -149,7 +165,8 @@ func (s *SubfinderScanner) runSubfinder(ctx context.Context, domain string) ([]s
 	output := &bytes.Buffer{}
 
 	// Run subfinder with context
-	if _, err = subfinder.EnumerateSingleDomainWithCtx(ctx, domain, []io.Writer{output}); err != nil {
+	sourceMap, err := subfinder.EnumerateSingleDomainWithCtx(ctx, domain, []io.Writer{output})
+	if err != nil {
 		// Check if context was cancelled
 		select {
 		case <-ctx.Done():
@@ -159,8 +176,14 @@ func (s *SubfinderScanner) runSubfinder(ctx context.Context, domain string) ([]s
 		}
 	}
 
-	// Process output to extract subdomains
-	subdomains := s.processSubfinderOutput(output.Bytes())
+	// Process output to extract subdomains; subfinder only reports sources for names it found itself
+	subdomains := make(subdomainSources)
+	for _, subdomain := range s.processSubfinderOutput(output.Bytes()) {
+		subdomains.add(subdomain, "")
+		for source := range sourceMap[subdomain] {
+			subdomains.add(subdomain, models.SubfinderSourcePrefix+source)
+		}
+	}
 
 	// Print the scan statistics
 	stats := subfinder.GetStatistics()
@@ -189,29 +212,37 @@ func (s *SubfinderScanner) processSubfinderOutput(output []byte) []string {
 	return subdomains
 }
 
-// removeDuplicates removes duplicate subdomains from the slice
-func (s *SubfinderScanner) removeDuplicates(subdomains []string) []string {
-	seen := make(map[string]bool)
-	var result []string
+// subdomainSources maps each subdomain found to the names of the sources that found it
+type subdomainSources map[string]map[string]struct{}
 
-	for _, subdomain := range subdomains {
-		if !seen[subdomain] {
-			seen[subdomain] = true
-			result = append(result, subdomain)
-		}
+// add records that source found subdomain; an empty source only records the subdomain
+func (s subdomainSources) add(subdomain, source string) {
+	if s[subdomain] == nil {
+		s[subdomain] = make(map[string]struct{})
+	}
+	if source != "" {
+		s[subdomain][source] = struct{}{}
 	}
-
-	return result
 }
 
-// contains checks if a slice contains a specific string
-func (s *SubfinderScanner) contains(slice []string, item string) bool {
-	for _, s := range slice {
-		if s == item {
-			return true
+// merge adds the subdomains and sources of other
+func (s subdomainSources) merge(other subdomainSources) {
+	for subdomain, sources := range other {
+		s.add(subdomain, "")
+		for source := range sources {
+			s.add(subdomain, source)
 		}
 	}
-	return false
+}
+
+// sorted returns the sources of each subdomain in alphabetical order
+func (s subdomainSources) sorted() map[string][]string {
+	sorted := make(map[string][]string, len(s))
+	for subdomain, sources := range s {
+		sorted[subdomain] = maps.Keys(sources)
+		sort.Strings(sorted[subdomain])
+	}
+	return sorted
 }
 
 func (s *SubfinderScanner) GetName() string {
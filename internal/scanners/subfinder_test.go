@@ -136,7 +136,7 @@ func TestSubfinderScannerWithAPI(t *testing.T) {
 	ctx := context.Background()
 
 	// Execute the scanner
-	result, err := scanner.Execute(ctx, input)
+	result, err := scanner.Execute(ctx, nil, input)
 	if err != nil {
 		t.Fatalf("Failed to execute subfinder scanner: %v", err)
 	}
@@ -196,7 +196,7 @@ func TestSubfinderScannerWithoutAPI(t *testing.T) {
 	defer cancel()
 
 	// Execute the scanner
-	result, err := scanner.Execute(ctx, input)
+	result, err := scanner.Execute(ctx, nil, input)
 	if err != nil {
 		t.Fatalf("Failed to execute subfinder scanner: %v", err)
 	}
@@ -286,14 +286,14 @@ func TestSubfinderScannerValidation(t *testing.T) {
 	scanner := NewSubfinderScanner()
 
 	// Test with invalid input type
-	_, err := scanner.Execute(context.Background(), "invalid input")
+	_, err := scanner.Execute(context.Background(), nil, "invalid input")
 	if err == nil {
 		t.Error("Expected error for invalid input type")
 	}
 
 	// Test with empty domain
 	input := models.SubfinderInput{Domain: ""}
-	_, err = scanner.Execute(context.Background(), input)
+	_, err = scanner.Execute(context.Background(), nil, input)
 	if err == nil {
 		t.Error("Expected error for empty domain")
 	}
@@ -301,7 +301,7 @@ func TestSubfinderScannerValidation(t *testing.T) {
 	// Test with valid input
 	input = models.SubfinderInput{Domain: "example.com"}
 	// This might fail due to missing subfinder configuration, but should not panic
-	_, err = scanner.Execute(context.Background(), input)
+	_, err = scanner.Execute(context.Background(), nil, input)
 	// We don't check for specific errors here as the test environment might not have subfinder configured
 }
 
@@ -315,45 +315,6 @@ func TestSubfinderScannerName(t *testing.T) {
 	}
 }
 
-// TestRemoveDuplicates tests the duplicate removal functionality
-func TestRemoveDuplicates(t *testing.T) {
-	scanner := NewSubfinderScanner()
-
-	input := []string{"a", "b", "a", "c", "b", "d"}
-	expected := []string{"a", "b", "c", "d"}
-
-	result := scanner.removeDuplicates(input)
-
-	if len(result) != len(expected) {
-		t.Errorf("Expected %d unique items, got %d", len(expected), len(result))
-	}
-
-	for i, item := range expected {
-		if i >= len(result) || result[i] != item {
-			t.Errorf("Expected item '%s' at index %d, got '%s'", item, i, result[i])
-		}
-	}
-}
-
-// TestContains tests the contains functionality
-func TestContains(t *testing.T) {
-	scanner := NewSubfinderScanner()
-
-	slice := []string{"a", "b", "c"}
-
-	if !scanner.contains(slice, "a") {
-		t.Error("Expected slice to contain 'a'")
-	}
-
-	if !scanner.contains(slice, "b") {
-		t.Error("Expected slice to contain 'b'")
-	}
-
-	if scanner.contains(slice, "d") {
-		t.Error("Expected slice to not contain 'd'")
-	}
-}
-
 // TestSubfinderScannerPSUEduEg tests the subfinder scanner on the specific domain psu.edu.eg
 func TestSubfinderScannerPSUEduEg(t *testing.T) {
 	// Create a subfinder scanner
@@ -371,7 +332,7 @@ func TestSubfinderScannerPSUEduEg(t *testing.T) {
 	t.Logf("Starting subfinder scan for domain: %s", input.Domain)
 
 	// Execute the scanner
-	result, err := scanner.Execute(ctx, input)
+	result, err := scanner.Execute(ctx, nil, input)
 	if err != nil {
 		t.Fatalf("Failed to execute subfinder scanner: %v", err)
 	}
@@ -0,0 +1,94 @@
+package scanners
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/projectdiscovery/naabu/v2/pkg/port"
+	"github.com/projectdiscovery/naabu/v2/pkg/protocol"
+	"github.com/projectdiscovery/naabu/v2/pkg/result"
+)
+
+// syntheticDomain is the zone the synthetic targets live in
+const syntheticDomain = "bench.example"
+
+// syntheticSubdomains returns n distinct names under the synthetic zone
+func syntheticSubdomains(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("host-%d.%s", i, syntheticDomain)
+	}
+	return names
+}
+
+// syntheticHostResults returns naabu results for n hosts with portsPerHost open TCP ports each
+func syntheticHostResults(n, portsPerHost int) []*result.HostResult {
+	hosts := make([]*result.HostResult, n)
+	for i := range hosts {
+		ports := make([]*port.Port, portsPerHost)
+		for j := range ports {
+			ports[j] = &port.Port{Port: 1 + (i*7+j*131)%65535, Protocol: protocol.TCP}
+		}
+		hosts[i] = &result.HostResult{IP: fmt.Sprintf("10.%d.%d.%d", i>>16&0xff, i>>8&0xff, i&0xff), Ports: ports}
+	}
+	return hosts
+}
+
+// startSyntheticDNSServer serves the synthetic zone on a local UDP port and returns the resolver
+// address. resolvedPercent of the names answer with an A record, every tenth of those through a
+// CNAME; the rest are NXDOMAIN. Answers are stable per name.
+func startSyntheticDNSServer(tb testing.TB, resolvedPercent int) string {
+	tb.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("Failed to listen for DNS: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{
+		PacketConn:        conn,
+		Handler:           dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) { w.WriteMsg(syntheticAnswer(r, resolvedPercent)) }),
+		NotifyStartedFunc: func() { close(started) },
+	}
+	go server.ActivateAndServe()
+	<-started
+	tb.Cleanup(func() { server.Shutdown() })
+
+	return "udp:" + conn.LocalAddr().String()
+}
+
+// syntheticAnswer answers a question about the synthetic zone
+func syntheticAnswer(r *dns.Msg, resolvedPercent int) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	if len(r.Question) == 0 {
+		return m
+	}
+	question := r.Question[0]
+	name := strings.ToLower(question.Name)
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := int(h.Sum32())
+	if !strings.HasPrefix(name, "edge-") && sum%100 >= resolvedPercent {
+		m.Rcode = dns.RcodeNameError
+		return m
+	}
+
+	header := dns.RR_Header{Name: question.Name, Class: dns.ClassINET, Ttl: 300}
+	if !strings.HasPrefix(name, "edge-") && sum%10 == 0 {
+		header.Rrtype = dns.TypeCNAME
+		m.Answer = append(m.Answer, &dns.CNAME{Hdr: header, Target: "edge-" + name})
+		return m
+	}
+	if question.Qtype == dns.TypeA {
+		header.Rrtype = dns.TypeA
+		m.Answer = append(m.Answer, &dns.A{Hdr: header, A: net.IPv4(10, byte(sum>>16), byte(sum>>8), byte(sum))})
+	}
+	return m
+}
@@ -0,0 +1,35 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// FuzzParseWindows checks that scan window rules from the environment or a task's config cannot
+// crash the worker, and that accepted windows survive a round trip through String
+func FuzzParseWindows(f *testing.F) {
+	for _, seed := range []string{"*=22:00-06:00", "tenant:acme=09:00-17:00@Europe/Berlin;example.com=00:00-24:00",
+		"*.example.com=01:00-02:00@America/New_York", "*=24:00-00:00", "*=9-17", "*=22:00-06:00@../../etc/passwd", "=;="} {
+		f.Add(seed)
+	}
+
+	at := time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, spec string) {
+		set, err := ParseWindows(spec)
+		if err != nil {
+			return
+		}
+
+		window := set.Lookup("acme", "www.example.com")
+		if window == nil {
+			return
+		}
+		reparsed, err := ParseWindow(window.String())
+		if err != nil || reparsed.Start != window.Start || reparsed.End != window.End || reparsed.Location.String() != window.Location.String() {
+			t.Fatalf("Window %s does not round trip: %+v, %v", window, reparsed, err)
+		}
+		if next := window.NextOpen(at); next.Before(at) {
+			t.Fatalf("Window %s opens at %s, before %s", window, next, at)
+		}
+	})
+}
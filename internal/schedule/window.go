@@ -0,0 +1,185 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Runtime images ship without zoneinfo, so embed it for target-local windows
+	_ "time/tzdata"
+)
+
+// Window is a daily time range, in a given time zone, during which scanning is allowed.
+// A window whose end is before its start spans midnight (e.g. 22:00-06:00).
+type Window struct {
+	Start    time.Duration // Offset from local midnight
+	End      time.Duration // Offset from local midnight
+	Location *time.Location
+}
+
+// ParseWindow parses a window in the form "HH:MM-HH:MM[@Time/Zone]". The zone defaults to UTC.
+func ParseWindow(spec string) (*Window, error) {
+	spec = strings.TrimSpace(spec)
+	rangeSpec, zone, hasZone := strings.Cut(spec, "@")
+
+	location := time.UTC
+	if hasZone {
+		loc, err := time.LoadLocation(strings.TrimSpace(zone))
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone in scan window %q: %w", spec, err)
+		}
+		location = loc
+	}
+
+	startSpec, endSpec, ok := strings.Cut(rangeSpec, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid scan window %q: expected HH:MM-HH:MM", spec)
+	}
+
+	start, err := parseClock(startSpec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan window %q: %w", spec, err)
+	}
+	end, err := parseClock(endSpec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scan window %q: %w", spec, err)
+	}
+
+	return &Window{Start: start, End: end, Location: location}, nil
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(clock string) (time.Duration, error) {
+	hourSpec, minuteSpec, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q: expected HH:MM", clock)
+	}
+
+	hour, err := strconv.Atoi(hourSpec)
+	if err != nil || hour < 0 || hour > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", clock)
+	}
+	minute, err := strconv.Atoi(minuteSpec)
+	if err != nil || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid minute in %q", clock)
+	}
+
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// Contains reports whether scanning is allowed at the given instant
+func (w *Window) Contains(t time.Time) bool {
+	if w.Start == w.End {
+		return true
+	}
+
+	local := t.In(w.Location)
+	offset := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns the earliest instant at or after t when the window is open
+func (w *Window) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+
+	local := t.In(w.Location)
+	hour, minute := int(w.Start/time.Hour), int((w.Start%time.Hour)/time.Minute)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, w.Location)
+	if !next.After(t) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, w.Location)
+	}
+	return next
+}
+
+// String returns the window in the form accepted by ParseWindow
+func (w *Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int((d%time.Hour)/time.Minute))
+	}
+	return fmt.Sprintf("%s-%s@%s", format(w.Start), format(w.End), w.Location)
+}
+
+// WindowSet maps scopes to scan windows
+type WindowSet struct {
+	defaultWindow *Window
+	tenants       map[string]*Window
+	domains       map[string]*Window
+}
+
+// ParseWindows parses a semicolon separated list of "scope=window" rules.
+// A scope is "*" (default), "tenant:<tenant_id>", or a domain that also covers its subdomains.
+func ParseWindows(spec string) (*WindowSet, error) {
+	set := &WindowSet{
+		tenants: make(map[string]*Window),
+		domains: make(map[string]*Window),
+	}
+
+	for _, rule := range strings.Split(spec, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		scope, windowSpec, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid scan window rule %q: expected scope=window", rule)
+		}
+
+		window, err := ParseWindow(windowSpec)
+		if err != nil {
+			return nil, err
+		}
+
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		switch {
+		case scope == "*":
+			set.defaultWindow = window
+		case strings.HasPrefix(scope, "tenant:"):
+			set.tenants[strings.TrimPrefix(scope, "tenant:")] = window
+		case scope != "":
+			set.domains[strings.TrimPrefix(scope, "*.")] = window
+		default:
+			return nil, fmt.Errorf("invalid scan window rule %q: empty scope", rule)
+		}
+	}
+
+	return set, nil
+}
+
+// Lookup returns the window that applies to a task, or nil when scanning is always allowed.
+// The most specific domain rule wins, then the tenant rule, then the default.
+func (s *WindowSet) Lookup(tenantID, domain string) *Window {
+	if s == nil {
+		return nil
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for candidate := domain; candidate != ""; {
+		if window, ok := s.domains[candidate]; ok {
+			return window
+		}
+		_, parent, found := strings.Cut(candidate, ".")
+		if !found {
+			break
+		}
+		candidate = parent
+	}
+
+	if window, ok := s.tenants[strings.ToLower(tenantID)]; ok && tenantID != "" {
+		return window
+	}
+
+	return s.defaultWindow
+}
+
+// Empty reports whether the set has no rules
+func (s *WindowSet) Empty() bool {
+	return s == nil || (s.defaultWindow == nil && len(s.tenants) == 0 && len(s.domains) == 0)
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindowContainsOvernight(t *testing.T) {
+	window, err := ParseWindow("22:00-06:00@Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to parse window: %v", err)
+	}
+
+	berlin := window.Location
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2024, 3, 4, 23, 30, 0, 0, berlin), true},
+		{time.Date(2024, 3, 4, 2, 0, 0, 0, berlin), true},
+		{time.Date(2024, 3, 4, 6, 0, 0, 0, berlin), false},
+		{time.Date(2024, 3, 4, 12, 0, 0, 0, berlin), false},
+		{time.Date(2024, 3, 4, 22, 0, 0, 0, berlin), true},
+	}
+
+	for _, tt := range tests {
+		if got := window.Contains(tt.at); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestWindowNextOpen(t *testing.T) {
+	window, err := ParseWindow("22:00-06:00@America/New_York")
+	if err != nil {
+		t.Fatalf("Failed to parse window: %v", err)
+	}
+
+	noon := time.Date(2024, 3, 4, 12, 0, 0, 0, window.Location)
+	want := time.Date(2024, 3, 4, 22, 0, 0, 0, window.Location)
+	if got := window.NextOpen(noon); !got.Equal(want) {
+		t.Errorf("NextOpen(%s) = %s, want %s", noon, got, want)
+	}
+
+	inside := time.Date(2024, 3, 4, 23, 0, 0, 0, window.Location)
+	if got := window.NextOpen(inside); !got.Equal(inside) {
+		t.Errorf("NextOpen inside the window should be the same instant, got %s", got)
+	}
+
+	daytime, _ := ParseWindow("09:00-17:00")
+	evening := time.Date(2024, 3, 4, 18, 0, 0, 0, time.UTC)
+	want = time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	if got := daytime.NextOpen(evening); !got.Equal(want) {
+		t.Errorf("NextOpen(%s) = %s, want %s", evening, got, want)
+	}
+}
+
+func TestParseWindowInvalid(t *testing.T) {
+	for _, spec := range []string{"", "22:00", "25:00-06:00", "22:00-06:60", "22:00-06:00@Mars/Base", "10-12"} {
+		if _, err := ParseWindow(spec); err == nil {
+			t.Errorf("Expected ParseWindow(%q) to fail", spec)
+		}
+	}
+}
+
+func TestWindowSetLookup(t *testing.T) {
+	set, err := ParseWindows("*=00:00-06:00; tenant:acme=20:00-23:00@Europe/London; example.com=22:00-06:00@Asia/Tokyo; shop.example.com=01:00-02:00")
+	if err != nil {
+		t.Fatalf("Failed to parse windows: %v", err)
+	}
+
+	tests := []struct {
+		tenant, domain, want string
+	}{
+		{"", "api.example.com", "22:00-06:00@Asia/Tokyo"},
+		{"acme", "example.com", "22:00-06:00@Asia/Tokyo"},
+		{"", "cdn.shop.example.com", "01:00-02:00@UTC"},
+		{"acme", "other.org", "20:00-23:00@Europe/London"},
+		{"", "other.org", "00:00-06:00@UTC"},
+	}
+
+	for _, tt := range tests {
+		window := set.Lookup(tt.tenant, tt.domain)
+		if window == nil || window.String() != tt.want {
+			t.Errorf("Lookup(%q, %q) = %v, want %s", tt.tenant, tt.domain, window, tt.want)
+		}
+	}
+
+	if (&WindowSet{}).Lookup("", "example.com") != nil {
+		t.Error("Expected no window from an empty set")
+	}
+}
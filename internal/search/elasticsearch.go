@@ -0,0 +1,254 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/allsafeASM/api/internal/exporters"
+	"github.com/allsafeASM/api/internal/utils"
+)
+
+// MaxWindow is the deepest result a search can page to, Elasticsearch's default result window
+const MaxWindow = 10000
+
+// Facets returned with every search, by name, with the document field they count and their size
+var facetFields = []struct {
+	name  string
+	field string
+	size  int
+}{
+	{"severity", "severity", 10},
+	{"technology", "technologies", 25},
+	{"asn", "asn", 25},
+}
+
+// textFields are matched by the free text of a query
+var textFields = []string{"title", "template_name", "host", "url", "web_server", "technologies", "tags", "matched_at"}
+
+// Request is a search of one tenant's documents
+type Request struct {
+	Query    Query
+	TenantID string // Documents of scans without a tenant when empty
+	Offset   int
+	Limit    int
+}
+
+// Facet is the number of matching documents with one value of a field
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Result is a page of matching documents with the facets of all matches
+type Result struct {
+	Total  int                `json:"total"`
+	Hits   []json.RawMessage  `json:"hits"`
+	Facets map[string][]Facet `json:"facets"`
+}
+
+// Elasticsearch searches the indices the Elasticsearch exporter writes. String fields are matched
+// on their keyword sub-field, ".keyword" with Elasticsearch's dynamic mapping; index templates
+// that map them as keyword directly use an empty suffix.
+type Elasticsearch struct {
+	config        exporters.ElasticsearchConfig
+	keywordSuffix string
+	httpClient    *http.Client
+}
+
+// NewElasticsearch creates a searcher for the cluster and index prefix the exporter writes to
+func NewElasticsearch(config exporters.ElasticsearchConfig, keywordSuffix string, timeout time.Duration) *Elasticsearch {
+	config.URL = strings.TrimSuffix(config.URL, "/")
+	if config.IndexPrefix == "" {
+		config.IndexPrefix = "asm"
+	}
+	return &Elasticsearch{config: config, keywordSuffix: keywordSuffix, httpClient: utils.NewTracingClient(timeout)}
+}
+
+// searchResponse is the part of a search response the result is built from
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations map[string]struct {
+		Buckets []struct {
+			Key      any `json:"key"`
+			DocCount int `json:"doc_count"`
+		} `json:"buckets"`
+	} `json:"aggregations"`
+}
+
+// Search runs a search over all kinds and months of indexed documents, newest first
+func (e *Elasticsearch) Search(ctx context.Context, request Request) (*Result, error) {
+	body, err := json.Marshal(e.Body(request))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode search: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/%s-*/_search", e.config.URL, e.config.IndexPrefix)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch {
+	case e.config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+e.config.APIKey)
+	case e.config.Username != "":
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send search request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("search request failed with status %d", resp.StatusCode)
+	}
+
+	var parsed searchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to decode search response: %w", err)
+	}
+
+	result := &Result{Total: parsed.Hits.Total.Value, Hits: []json.RawMessage{}, Facets: make(map[string][]Facet)}
+	for _, hit := range parsed.Hits.Hits {
+		result.Hits = append(result.Hits, hit.Source)
+	}
+	for _, facet := range facetFields {
+		result.Facets[facet.name] = []Facet{}
+		for _, bucket := range parsed.Aggregations[facet.name].Buckets {
+			result.Facets[facet.name] = append(result.Facets[facet.name], Facet{Value: fmt.Sprint(bucket.Key), Count: bucket.DocCount})
+		}
+	}
+	return result, nil
+}
+
+// Body returns the search request body of a request
+func (e *Elasticsearch) Body(request Request) map[string]any {
+	var filter, mustNot []any
+	if request.TenantID != "" {
+		filter = append(filter, e.term("tenant_id", request.TenantID))
+	} else {
+		mustNot = append(mustNot, map[string]any{"exists": map[string]any{"field": "tenant_id"}})
+	}
+
+	terms, fields := request.Query.byField()
+	for _, field := range fields {
+		var should []any
+		for _, term := range terms[field] {
+			clause := e.clause(term)
+			if term.Negate {
+				mustNot = append(mustNot, clause)
+			} else {
+				should = append(should, clause)
+			}
+		}
+		if len(should) > 0 {
+			filter = append(filter, anyOf(should...))
+		}
+	}
+
+	boolQuery := map[string]any{"filter": filter}
+	if len(mustNot) > 0 {
+		boolQuery["must_not"] = mustNot
+	}
+	if len(request.Query.Text) > 0 {
+		boolQuery["must"] = []any{map[string]any{"multi_match": map[string]any{
+			"query":    strings.Join(request.Query.Text, " "),
+			"fields":   textFields,
+			"operator": "and",
+		}}}
+	}
+
+	aggs := make(map[string]any)
+	for _, facet := range facetFields {
+		aggs[facet.name] = map[string]any{"terms": map[string]any{"field": facet.field + e.keywordSuffix, "size": facet.size}}
+	}
+	return map[string]any{
+		"from":             request.Offset,
+		"size":             request.Limit,
+		"track_total_hits": true,
+		"sort":             []any{map[string]any{"@timestamp": "desc"}},
+		"query":            map[string]any{"bool": boolQuery},
+		"aggs":             aggs,
+	}
+}
+
+// clause returns the query clause matching a term
+func (e *Elasticsearch) clause(term Term) any {
+	value := term.Value
+	switch term.Field {
+	case FieldStatus:
+		switch strings.ToLower(value) {
+		case StatusAlive:
+			return anyOf(e.term("kind", exporters.KindHTTPService), e.term("kind", exporters.KindPort))
+		case StatusDangling:
+			return map[string]any{"term": map[string]any{"dangling": true}}
+		}
+		if code, err := strconv.Atoi(value); err == nil {
+			return map[string]any{"term": map[string]any{"status_code": code}}
+		}
+		return e.term("dns_status", value)
+	case FieldTech:
+		return e.term("technologies", value)
+	case FieldPort:
+		port, _ := strconv.Atoi(value)
+		return map[string]any{"term": map[string]any{"port": port}}
+	case FieldDomain:
+		if strings.Contains(value, "*") {
+			return e.wildcard("host", value)
+		}
+		return anyOf(e.term("domain", value), e.term("host", value))
+	case FieldHost:
+		if strings.Contains(value, "*") {
+			return e.wildcard("host", value)
+		}
+		return e.term("host", value)
+	case FieldIP:
+		return anyOf(e.term("ip", value), e.term("a", value), e.term("aaaa", value))
+	case FieldSeverity:
+		return e.term("severity", value)
+	case FieldTemplate:
+		return e.term("template_id", value)
+	case FieldCVE:
+		return e.term("cve_ids", value)
+	case FieldASN:
+		return e.term("asn", value)
+	case FieldKind:
+		return e.term("kind", value)
+	case FieldScan:
+		scanID, _ := strconv.Atoi(value)
+		return map[string]any{"term": map[string]any{"scan_id": scanID}}
+	}
+	return e.term(term.Field, value)
+}
+
+// term matches a string field exactly, ignoring case
+func (e *Elasticsearch) term(field, value string) any {
+	return map[string]any{"term": map[string]any{field + e.keywordSuffix: map[string]any{"value": value, "case_insensitive": true}}}
+}
+
+// wildcard matches a string field against a pattern with '*', ignoring case
+func (e *Elasticsearch) wildcard(field, pattern string) any {
+	return map[string]any{"wildcard": map[string]any{field + e.keywordSuffix: map[string]any{"value": pattern, "case_insensitive": true}}}
+}
+
+// anyOf matches documents matching any of the clauses
+func anyOf(clauses ...any) any {
+	if len(clauses) == 1 {
+		return clauses[0]
+	}
+	return map[string]any{"bool": map[string]any{"should": clauses, "minimum_should_match": 1}}
+}
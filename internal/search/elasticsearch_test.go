@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/exporters"
+)
+
+func TestElasticsearch_Search(t *testing.T) {
+	var body map[string]any
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Invalid search body: %v", err)
+		}
+		w.Write([]byte(`{
+			"hits": {"total": {"value": 2}, "hits": [{"_source": {"kind": "http_service", "host": "blog.corp.com"}}]},
+			"aggregations": {
+				"severity": {"buckets": []},
+				"technology": {"buckets": [{"key": "WordPress", "doc_count": 2}]},
+				"asn": {"buckets": [{"key": "AS13335", "doc_count": 1}]}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	searcher := NewElasticsearch(exporters.ElasticsearchConfig{URL: server.URL + "/", APIKey: "secret"}, ".keyword", 5*time.Second)
+	query, _ := ParseQuery("status:alive tech:wordpress port:443 port:8443 domain:*.corp.com -severity:info")
+	result, err := searcher.Search(context.Background(), Request{Query: query, TenantID: "acme", Offset: 10, Limit: 5})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if path != "/asm-*/_search" || auth != "ApiKey secret" {
+		t.Errorf("Unexpected request to %s with auth %q", path, auth)
+	}
+	if body["from"] != float64(10) || body["size"] != float64(5) {
+		t.Errorf("Expected from 10 and size 5, got %v and %v", body["from"], body["size"])
+	}
+	encoded, _ := json.Marshal(body["query"])
+	for _, clause := range []string{
+		`{"term":{"tenant_id.keyword":{"case_insensitive":true,"value":"acme"}}}`,
+		`{"term":{"technologies.keyword":{"case_insensitive":true,"value":"wordpress"}}}`,
+		`{"bool":{"minimum_should_match":1,"should":[{"term":{"port":443}},{"term":{"port":8443}}]}}`,
+		`{"wildcard":{"host.keyword":{"case_insensitive":true,"value":"*.corp.com"}}}`,
+		`"must_not":[{"term":{"severity.keyword":{"case_insensitive":true,"value":"info"}}}]`,
+		`{"term":{"kind.keyword":{"case_insensitive":true,"value":"http_service"}}}`,
+	} {
+		if !strings.Contains(string(encoded), clause) {
+			t.Errorf("Expected query to contain %s, got %s", clause, encoded)
+		}
+	}
+
+	if result.Total != 2 || len(result.Hits) != 1 || !strings.Contains(string(result.Hits[0]), "blog.corp.com") {
+		t.Errorf("Unexpected hits: %+v", result)
+	}
+	if facets := result.Facets["technology"]; len(facets) != 1 || facets[0] != (Facet{Value: "WordPress", Count: 2}) {
+		t.Errorf("Unexpected technology facets: %+v", facets)
+	}
+	if facets, ok := result.Facets["severity"]; !ok || len(facets) != 0 {
+		t.Errorf("Expected an empty severity facet, got %+v", facets)
+	}
+}
+
+func TestElasticsearch_BodyWithoutTenant(t *testing.T) {
+	searcher := NewElasticsearch(exporters.ElasticsearchConfig{URL: "http://localhost:9200"}, "", time.Second)
+	body := searcher.Body(Request{Query: Query{Text: []string{"login"}}, Limit: 10})
+	encoded, _ := json.Marshal(body["query"])
+	if !strings.Contains(string(encoded), `"must_not":[{"exists":{"field":"tenant_id"}}]`) {
+		t.Errorf("Expected documents with a tenant to be excluded, got %s", encoded)
+	}
+	if !strings.Contains(string(encoded), `"multi_match":{"fields":["title"`) {
+		t.Errorf("Expected free text to be matched, got %s", encoded)
+	}
+}
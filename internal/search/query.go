@@ -0,0 +1,133 @@
+// Package search answers queries over the findings indexed by the Elasticsearch export, such as
+// "status:alive tech:wordpress port:443 domain:*.corp.com", with facets for drilling down.
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query fields. Terms of the same field are ORed, different fields are ANDed.
+const (
+	FieldStatus   = "status"   // alive, resolved, dangling, a DNS status or an HTTP status code
+	FieldTech     = "tech"     // Technology detected by httpx
+	FieldPort     = "port"     // Open port or port of an HTTP service
+	FieldDomain   = "domain"   // Scanned domain or host; "*" matches any labels, e.g. *.corp.com
+	FieldHost     = "host"     // Host name; "*" allowed
+	FieldIP       = "ip"       // IP address
+	FieldSeverity = "severity" // Severity of a vulnerability
+	FieldTemplate = "template" // Nuclei template ID
+	FieldCVE      = "cve"      // CVE ID of a vulnerability
+	FieldASN      = "asn"      // ASN of an HTTP service
+	FieldKind     = "kind"     // Document kind: subdomain, port, http_service or vulnerability
+	FieldScan     = "scan"     // Scan ID
+)
+
+// Fields lists the valid query fields
+var Fields = []string{FieldStatus, FieldTech, FieldPort, FieldDomain, FieldHost, FieldIP, FieldSeverity, FieldTemplate, FieldCVE, FieldASN, FieldKind, FieldScan}
+
+// Status values beyond DNS statuses and HTTP status codes
+const (
+	StatusAlive    = "alive"    // An open port or an answering HTTP service
+	StatusResolved = "resolved" // A host that resolved
+	StatusDangling = "dangling" // A CNAME chain ending in NXDOMAIN
+)
+
+// Term is one field:value filter; a negated term excludes its matches
+type Term struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// Query is a parsed search query
+type Query struct {
+	Terms []Term
+	Text  []string // Free text, matched against titles, names, hosts and URLs
+}
+
+// ParseQuery parses a query of field:value terms and free text separated by spaces. Values with
+// spaces are quoted, e.g. tech:"google analytics", and a leading '-' negates a term.
+func ParseQuery(raw string) (Query, error) {
+	var query Query
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return query, err
+	}
+	for _, token := range tokens {
+		field, value, ok := strings.Cut(token, ":")
+		negate := strings.HasPrefix(field, "-")
+		field = strings.ToLower(strings.TrimPrefix(field, "-"))
+		if !ok || !isField(field) {
+			query.Text = append(query.Text, token)
+			continue
+		}
+		if value == "" {
+			return query, fmt.Errorf("%s needs a value", field)
+		}
+		if (field == FieldPort || field == FieldScan) && !isNumber(value) {
+			return query, fmt.Errorf("%s must be a number, got %q", field, value)
+		}
+		query.Terms = append(query.Terms, Term{Field: field, Value: value, Negate: negate})
+	}
+	return query, nil
+}
+
+// byField groups the terms by field and returns the fields the query filters on, sorted
+func (q Query) byField() (map[string][]Term, []string) {
+	terms := make(map[string][]Term)
+	for _, term := range q.Terms {
+		terms[term.Field] = append(terms[term.Field], term)
+	}
+	fields := make([]string, 0, len(terms))
+	for field := range terms {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return terms, fields
+}
+
+// tokenize splits a query at spaces outside double quotes and drops the quotes
+func tokenize(raw string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+	)
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in query")
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+	return tokens, nil
+}
+
+func isField(field string) bool {
+	for _, known := range Fields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
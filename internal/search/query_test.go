@@ -0,0 +1,37 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	query, err := ParseQuery(`status:alive Tech:"Google Analytics" port:443 -severity:info domain:*.corp.com login page`)
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	expected := []Term{
+		{Field: FieldStatus, Value: "alive"},
+		{Field: FieldTech, Value: "Google Analytics"},
+		{Field: FieldPort, Value: "443"},
+		{Field: FieldSeverity, Value: "info", Negate: true},
+		{Field: FieldDomain, Value: "*.corp.com"},
+	}
+	if !reflect.DeepEqual(query.Terms, expected) {
+		t.Errorf("Terms = %+v, want %+v", query.Terms, expected)
+	}
+	if !reflect.DeepEqual(query.Text, []string{"login", "page"}) {
+		t.Errorf("Text = %v, want [login page]", query.Text)
+	}
+
+	query, err = ParseQuery("https://example.com/admin")
+	if err != nil || len(query.Terms) != 0 || len(query.Text) != 1 {
+		t.Errorf("Expected an unknown field to be free text, got %+v, %v", query, err)
+	}
+
+	for _, raw := range []string{"port:https", "scan:", `tech:"wordpress`} {
+		if _, err := ParseQuery(raw); err == nil {
+			t.Errorf("ParseQuery(%q) expected an error", raw)
+		}
+	}
+}
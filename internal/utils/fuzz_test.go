@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// FuzzParseHostsFile checks that no input blob, compressed or not, can crash the worker
+func FuzzParseHostsFile(f *testing.F) {
+	f.Add("hosts.txt", []byte("www.example.com\n# comment\napi.example.com\n"))
+	f.Add("hosts.json", []byte(`["www.example.com", "api.example.com"]`))
+	f.Add("result.json", []byte(`{"task":"dns_resolve","data":{"domain":"example.com","output":{"www.example.com":{"status":"resolved","A":["192.0.2.1"]}}}}`))
+	f.Add("result.json", []byte(`{"data":{"output":{"192.0.2.1":[{"port":443,"protocol":"tcp"}]}}}`))
+	f.Add("result.json", []byte(`{"data":{"data":{"data":{"subdomains":["a.example.com"]}}}}`))
+	f.Add("hosts.txt.gz", []byte{0x1f, 0x8b, 0x08, 0x00})
+	f.Add("hosts.json", []byte(`{"output": 5}`))
+
+	tasks := []models.Task{models.TaskSubfinder, models.TaskDNSResolve, models.TaskNaabu, models.TaskHttpx, models.TaskNuclei}
+	f.Fuzz(func(t *testing.T, name string, content []byte) {
+		file, err := ParseHostsFile(name, content)
+		if err != nil {
+			return
+		}
+		for _, task := range tasks {
+			targets, err := file.TargetsFor(task)
+			if err == nil && targets == nil {
+				t.Fatalf("%s targets of a %s file are nil instead of empty", task, file.Format)
+			}
+		}
+		file.IPs()
+	})
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// Hosts file formats recognized by ParseHostsFile
+const (
+	HostsFormatText  = "text"  // One host per line, '#' comments allowed
+	HostsFormatJSON  = "json"  // JSON array of strings
+	HostsFormatDNSX  = "dnsx"  // DNSX result, bare or wrapped in a task result
+	HostsFormatNaabu = "naabu" // Naabu result, bare or wrapped in a task result
+	HostsFormatList  = "list"  // Subfinder JSON result with a subdomains list
+)
+
+// maxHostsFileSize bounds the decompressed size of a hosts file
+const maxHostsFileSize = 512 << 20
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// HostsFile is a parsed hosts file
+type HostsFile struct {
+	Format     string
+	Compressed bool
+	Hosts      []string // Host names or addresses to scan
+	Addresses  []string // Resolved addresses, set only for DNSX results
+	Endpoints  []string // Open "ip:port" pairs, set only for naabu results
+	// RecordsBlob is the records blob of a streamed DNSX result; its hosts and addresses are
+	// read from it with ReadDNSRecordLines
+	RecordsBlob string
+}
+
+// IsTaskResult reports whether the file is the output of an earlier stage: a stored result, or
+// a plain text list of subdomains such as subfinder's own -o output
+func (f *HostsFile) IsTaskResult() bool {
+	return f.Format == HostsFormatDNSX || f.Format == HostsFormatNaabu || f.Format == HostsFormatList || f.Format == HostsFormatText
+}
+
+// TargetsFor returns the targets a task takes from an earlier stage's result: DNSX resolves
+// subfinder subdomains, JSON or text, or DNSX names, scope expansion reads the certificates of the same names,
+// naabu scans the unique A records of a DNSX result or the IPs of a naabu result, and httpx and
+// nuclei probe the open ports of a naabu result or the resolved names of a DNSX result.
+func (f *HostsFile) TargetsFor(task models.Task) ([]string, error) {
+	switch {
+	case (task == models.TaskDNSResolve || task == models.TaskScopeExpansion) && (f.Format == HostsFormatList || f.Format == HostsFormatText || f.Format == HostsFormatDNSX):
+		return nonNil(f.Hosts), nil
+	case task == models.TaskNaabu && f.Format == HostsFormatDNSX:
+		return uniqueIPv4(f.Addresses), nil
+	case task == models.TaskNaabu && f.Format == HostsFormatNaabu:
+		return nonNil(f.Hosts), nil
+	case (task == models.TaskHttpx || task == models.TaskNuclei) && f.Format == HostsFormatNaabu:
+		return nonNil(f.Endpoints), nil
+	case (task == models.TaskHttpx || task == models.TaskNuclei) && f.Format == HostsFormatDNSX:
+		return nonNil(f.Hosts), nil
+	}
+	return nil, fmt.Errorf("%s cannot take targets from a %s result", task, f.Format)
+}
+
+// IPs returns the addresses to port scan: the resolved addresses of a DNSX result, otherwise the hosts
+func (f *HostsFile) IPs() []string {
+	if f.Format == HostsFormatDNSX {
+		return f.Addresses
+	}
+	return f.Hosts
+}
+
+// ParseHostsFile parses a hosts file, detecting gzip compression by magic bytes or a .gz
+// extension and JSON by a .json extension or a leading '[' or '{'. DNSX results yield their
+// resolved names as hosts and their A and AAAA records as addresses.
+func ParseHostsFile(name string, content []byte) (*HostsFile, error) {
+	file := &HostsFile{Format: HostsFormatText}
+
+	if bytes.HasPrefix(content, gzipMagic) || strings.EqualFold(path.Ext(name), ".gz") {
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip hosts file %s: %w", name, err)
+		}
+		defer reader.Close()
+
+		content, err = io.ReadAll(io.LimitReader(reader, maxHostsFileSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress hosts file %s: %w", name, err)
+		}
+		if len(content) > maxHostsFileSize {
+			return nil, fmt.Errorf("hosts file %s exceeds %d bytes once decompressed", name, maxHostsFileSize)
+		}
+		file.Compressed = true
+		name = strings.TrimSuffix(name, path.Ext(name))
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	isJSON := strings.EqualFold(path.Ext(name), ".json") || bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{"))
+	if !isJSON {
+		file.Hosts = ReadSubdomainsFromString(string(content))
+		return file, nil
+	}
+
+	if err := parseJSONHosts(trimmed, file); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON hosts file %s: %w", name, err)
+	}
+	return file, nil
+}
+
+// hostsDocument covers the task results that can be handed to a later stage, bare or wrapped in a TaskResult
+type hostsDocument struct {
+	Data        *hostsDocument  `json:"data"`
+	Output      json.RawMessage `json:"output"`
+	Subdomains  []string        `json:"subdomains"`
+	RecordsBlob string          `json:"records_blob"`
+}
+
+// parseJSONHosts fills the file from a JSON array of strings or a previous task result
+func parseJSONHosts(content []byte, file *HostsFile) error {
+	if bytes.HasPrefix(content, []byte("[")) {
+		var hosts []string
+		if err := json.Unmarshal(content, &hosts); err != nil {
+			return fmt.Errorf("expected an array of strings: %w", err)
+		}
+		file.Format = HostsFormatJSON
+		file.Hosts = cleanHosts(hosts)
+		return nil
+	}
+
+	var document hostsDocument
+	if err := json.Unmarshal(content, &document); err != nil {
+		return err
+	}
+	if document.Data != nil {
+		document = *document.Data
+	}
+
+	if len(document.Subdomains) > 0 {
+		file.Format = HostsFormatList
+		file.Hosts = cleanHosts(document.Subdomains)
+		return nil
+	}
+	if document.RecordsBlob != "" {
+		file.Format = HostsFormatDNSX
+		file.RecordsBlob = document.RecordsBlob
+		return nil
+	}
+	if len(document.Output) == 0 || string(document.Output) == "null" {
+		return fmt.Errorf("no hosts found: expected a DNSX, naabu or subfinder result")
+	}
+
+	var records map[string]models.ResolutionInfo
+	if err := json.Unmarshal(document.Output, &records); err == nil {
+		file.Format = HostsFormatDNSX
+		for _, name := range sortedHostKeys(records) {
+			record := records[name]
+			if record.Status != "" && record.Status != models.DNSStatusResolved {
+				continue
+			}
+			file.Hosts = append(file.Hosts, name)
+			file.Addresses = append(file.Addresses, record.A...)
+			file.Addresses = append(file.Addresses, record.AAAA...)
+		}
+		return nil
+	}
+
+	var ports map[string][]models.PortInfo
+	if err := json.Unmarshal(document.Output, &ports); err == nil {
+		file.Format = HostsFormatNaabu
+		file.Hosts = sortedHostKeys(ports)
+		for _, host := range file.Hosts {
+			for _, port := range ports[host] {
+				file.Endpoints = append(file.Endpoints, net.JoinHostPort(host, strconv.Itoa(port.Port)))
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unrecognized task result output")
+}
+
+// ReadDNSRecordLines fills a streamed DNSX result's hosts and addresses from its gzipped NDJSON
+// records, in the same host order as a DNSX result with inline records
+func ReadDNSRecordLines(reader io.Reader, file *HostsFile) error {
+	addresses := make(map[string][]string)
+	err := ForEachDNSRecord(reader, func(line models.DNSRecordLine) {
+		if line.Host == "" || (line.Status != "" && line.Status != models.DNSStatusResolved) {
+			return
+		}
+		addresses[line.Host] = append(append(addresses[line.Host], line.A...), line.AAAA...)
+	})
+	if err != nil {
+		return err
+	}
+
+	file.Hosts = sortedHostKeys(addresses)
+	for _, host := range file.Hosts {
+		file.Addresses = append(file.Addresses, addresses[host]...)
+	}
+	return nil
+}
+
+// ReadDNSRecords reads the records of a streamed DNSX result into the records map of a DNSX
+// result with inline records
+func ReadDNSRecords(reader io.Reader) (map[string]models.ResolutionInfo, error) {
+	records := make(map[string]models.ResolutionInfo)
+	err := ForEachDNSRecord(reader, func(line models.DNSRecordLine) {
+		if line.Host != "" {
+			records[line.Host] = line.ResolutionInfo
+		}
+	})
+	return records, err
+}
+
+// ForEachDNSRecord calls fn with every line of gzipped NDJSON DNSX records
+func ForEachDNSRecord(reader io.Reader, fn func(line models.DNSRecordLine)) error {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("failed to open DNSX records: %w", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(gz)
+	for {
+		var line models.DNSRecordLine
+		if err := decoder.Decode(&line); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to parse DNSX records: %w", err)
+		}
+		fn(line)
+	}
+}
+
+// uniqueIPv4 returns the IPv4 addresses in order of first appearance without duplicates
+func uniqueIPv4(addresses []string) []string {
+	seen := make(map[string]bool, len(addresses))
+	unique := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if ip := net.ParseIP(address); ip != nil && ip.To4() != nil && !seen[address] {
+			seen[address] = true
+			unique = append(unique, address)
+		}
+	}
+	return unique
+}
+
+// nonNil returns an empty slice for nil, so an empty result yields no targets rather than defaults
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// cleanHosts trims hosts and drops empty entries
+func cleanHosts(hosts []string) []string {
+	cleaned := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if host = strings.TrimSpace(host); host != "" {
+			cleaned = append(cleaned, host)
+		}
+	}
+	return cleaned
+}
+
+// sortedHostKeys returns the keys of a map in sorted order
+func sortedHostKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestParseHostsFile(t *testing.T) {
+	dnsxResult := `{"task":"dns_resolve","data":{"domain":"example.com","output":{
+		"www.example.com":{"status":"resolved","A":["192.0.2.1"],"AAAA":["2001:db8::1"]},
+		"api.example.com":{"status":"resolved","A":["192.0.2.2"]},
+		"old.example.com":{"status":"nxdomain"}}}}`
+
+	tests := []struct {
+		name       string
+		file       string
+		content    []byte
+		format     string
+		compressed bool
+		hosts      []string
+		ips        []string
+	}{
+		{"plain text", "hosts.txt", []byte("# comment\nwww.example.com\n\n api.example.com \n"), HostsFormatText, false,
+			[]string{"www.example.com", "api.example.com"}, []string{"www.example.com", "api.example.com"}},
+		{"gzip by magic bytes", "hosts", gzipped(t, "www.example.com\n"), HostsFormatText, true,
+			[]string{"www.example.com"}, []string{"www.example.com"}},
+		{"gzip JSON array", "hosts.json.gz", gzipped(t, `["a.example.com", " ", "b.example.com"]`), HostsFormatJSON, true,
+			[]string{"a.example.com", "b.example.com"}, []string{"a.example.com", "b.example.com"}},
+		{"DNSX result", "out/x.json", []byte(dnsxResult), HostsFormatDNSX, false,
+			[]string{"api.example.com", "www.example.com"}, []string{"192.0.2.2", "192.0.2.1", "2001:db8::1"}},
+		{"naabu result", "out/x.json", []byte(`{"output":{"192.0.2.9":[{"port":22}],"192.0.2.8":[{"port":80}]}}`), HostsFormatNaabu, false,
+			[]string{"192.0.2.8", "192.0.2.9"}, []string{"192.0.2.8", "192.0.2.9"}},
+		{"subfinder result", "out/x.json", []byte(`{"domain":"example.com","subdomains":["www.example.com"]}`), HostsFormatList, false,
+			[]string{"www.example.com"}, []string{"www.example.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ParseHostsFile(tt.file, tt.content)
+			if err != nil {
+				t.Fatalf("ParseHostsFile() error = %v", err)
+			}
+			if file.Format != tt.format || file.Compressed != tt.compressed {
+				t.Errorf("Expected format %s (compressed: %t), got %s (compressed: %t)", tt.format, tt.compressed, file.Format, file.Compressed)
+			}
+			if fmt.Sprint(file.Hosts) != fmt.Sprint(tt.hosts) || fmt.Sprint(file.IPs()) != fmt.Sprint(tt.ips) {
+				t.Errorf("Expected hosts %v and IPs %v, got %v and %v", tt.hosts, tt.ips, file.Hosts, file.IPs())
+			}
+		})
+	}
+}
+
+func TestParseHostsFile_Errors(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"hosts.txt.gz": []byte("not gzip"),
+		"hosts.json":   []byte(`[1, 2]`),
+		"result.json":  []byte(`{"output": [{"host": "x"}]}`),
+	} {
+		if _, err := ParseHostsFile(name, content); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestHostsFile_TargetsFor(t *testing.T) {
+	dnsx, _ := ParseHostsFile("x.json", []byte(`{"output":{
+		"a.example.com":{"status":"resolved","A":["192.0.2.1"],"AAAA":["2001:db8::1"]},
+		"b.example.com":{"status":"resolved","A":["192.0.2.1","192.0.2.2"]}}}`))
+	naabu, _ := ParseHostsFile("x.json", []byte(`{"output":{"192.0.2.1":[{"port":443},{"port":8080}]}}`))
+	subfinder, _ := ParseHostsFile("x.json", []byte(`{"subdomains":["a.example.com"]}`))
+	subfinderText, _ := ParseHostsFile("subs.txt", []byte("a.example.com\nb.example.com\n"))
+
+	tests := []struct {
+		name    string
+		file    *HostsFile
+		task    models.Task
+		want    []string
+		wantErr bool
+	}{
+		{"dnsx from subfinder", subfinder, models.TaskDNSResolve, []string{"a.example.com"}, false},
+		{"naabu from dnsx", dnsx, models.TaskNaabu, []string{"192.0.2.1", "192.0.2.2"}, false},
+		{"httpx from naabu", naabu, models.TaskHttpx, []string{"192.0.2.1:443", "192.0.2.1:8080"}, false},
+		{"nuclei from dnsx", dnsx, models.TaskNuclei, []string{"a.example.com", "b.example.com"}, false},
+		{"dnsx from subfinder text output", subfinderText, models.TaskDNSResolve, []string{"a.example.com", "b.example.com"}, false},
+		{"naabu from subfinder", subfinder, models.TaskNaabu, nil, true},
+		{"dnsx from naabu", naabu, models.TaskDNSResolve, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.file.TargetsFor(tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TargetsFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) && !tt.wantErr {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadDNSRecordLines(t *testing.T) {
+	file, err := ParseHostsFile("out/x.json", []byte(`{"task":"dns_resolve","data":{"domain":"example.com","records_blob":"scans/artifacts/records.ndjson.gz"}}`))
+	if err != nil {
+		t.Fatalf("ParseHostsFile() error = %v", err)
+	}
+	if file.Format != HostsFormatDNSX || file.RecordsBlob != "scans/artifacts/records.ndjson.gz" {
+		t.Fatalf("Expected a DNSX file with a records blob, got %s %q", file.Format, file.RecordsBlob)
+	}
+
+	lines := `{"host":"www.example.com","status":"resolved","A":["192.0.2.1"],"AAAA":["2001:db8::1"]}
+{"host":"old.example.com","status":"nxdomain"}
+{"host":"api.example.com","status":"resolved","A":["192.0.2.2"]}
+`
+	if err := ReadDNSRecordLines(bytes.NewReader(gzipped(t, lines)), file); err != nil {
+		t.Fatalf("ReadDNSRecordLines() error = %v", err)
+	}
+	if fmt.Sprint(file.Hosts) != "[api.example.com www.example.com]" || fmt.Sprint(file.IPs()) != "[192.0.2.2 192.0.2.1 2001:db8::1]" {
+		t.Errorf("Expected resolved hosts and their IPs, got %v and %v", file.Hosts, file.IPs())
+	}
+
+	if err := ReadDNSRecordLines(bytes.NewReader(gzipped(t, "{not json")), file); err == nil {
+		t.Error("Expected an error for malformed record lines")
+	}
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// DefaultRecordsChunkLines is the number of lines per gzip member of an indexed records blob
+const DefaultRecordsChunkLines = 1000
+
+// maxNDJSONLine bounds a single line read from a records blob
+const maxNDJSONLine = 16 << 20
+
+// IndexedNDJSONWriter writes gzipped NDJSON as a series of gzip members of a fixed number of
+// lines and records where each member starts. Gzip readers read the members as one stream, so
+// the blob stays readable as a plain gzipped NDJSON file.
+type IndexedNDJSONWriter struct {
+	out     *countingWriter
+	gz      *gzip.Writer
+	index   models.RecordsIndex
+	inChunk int
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// NewIndexedNDJSONWriter writes to w with chunkLines lines per gzip member
+func NewIndexedNDJSONWriter(w io.Writer, chunkLines int) *IndexedNDJSONWriter {
+	return &IndexedNDJSONWriter{
+		out:   &countingWriter{w: w},
+		index: models.RecordsIndex{ChunkLines: max(chunkLines, 1)},
+	}
+}
+
+// Encode writes a value as one line, starting a new gzip member when the current one is full
+func (w *IndexedNDJSONWriter) Encode(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if w.gz != nil && w.inChunk == w.index.ChunkLines {
+		if err := w.gz.Close(); err != nil {
+			return err
+		}
+		w.gz = nil
+	}
+	if w.gz == nil {
+		w.index.Chunks = append(w.index.Chunks, models.RecordsChunk{Line: w.index.Lines, Offset: w.out.n})
+		w.gz = gzip.NewWriter(w.out)
+		w.inChunk = 0
+	}
+	if _, err := w.gz.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	w.inChunk++
+	w.index.Lines++
+	return nil
+}
+
+// Close completes the last gzip member. An empty stream is written as one empty member, so it
+// is still valid gzip.
+func (w *IndexedNDJSONWriter) Close() error {
+	if w.gz == nil {
+		w.gz = gzip.NewWriter(w.out)
+	}
+	return w.gz.Close()
+}
+
+// Index returns the index of the lines written so far
+func (w *IndexedNDJSONWriter) Index() models.RecordsIndex {
+	return w.index
+}
+
+// ReadNDJSONLines reads limit lines of gzipped NDJSON after skipping skip lines. It stops early
+// at the end of the stream.
+func ReadNDJSONLines(reader io.Reader, skip, limit int) ([]json.RawMessage, error) {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open records: %w", err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+	lines := []json.RawMessage{}
+	for n := 0; len(lines) < limit && scanner.Scan(); n++ {
+		if n < skip {
+			continue
+		}
+		line := append(json.RawMessage{}, scanner.Bytes()...)
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("invalid record on line %d", n)
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read records: %w", err)
+	}
+	return lines, nil
+}
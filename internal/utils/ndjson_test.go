@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestIndexedNDJSONWriter(t *testing.T) {
+	var blob bytes.Buffer
+	writer := NewIndexedNDJSONWriter(&blob, 3)
+	for i := 0; i < 7; i++ {
+		if err := writer.Encode(map[string]int{"n": i}); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	index := writer.Index()
+	if index.Lines != 7 || len(index.Chunks) != 3 || index.Chunks[2].Line != 6 {
+		t.Fatalf("Unexpected index %+v", index)
+	}
+
+	// The members read as one gzip stream
+	gz, err := gzip.NewReader(bytes.NewReader(blob.Bytes()))
+	if err != nil {
+		t.Fatalf("Blob is not gzipped: %v", err)
+	}
+	content, _ := io.ReadAll(gz)
+	if lines := bytes.Count(content, []byte("\n")); lines != 7 {
+		t.Errorf("Expected 7 lines, got %d", lines)
+	}
+
+	// Reading from the member that holds line 4 skips the lines before it in that member
+	chunk, ok := index.Locate(4)
+	if !ok || chunk.Line != 3 {
+		t.Fatalf("Expected line 4 in the member starting at line 3, got %+v", chunk)
+	}
+	lines, err := ReadNDJSONLines(bytes.NewReader(blob.Bytes()[chunk.Offset:]), 4-chunk.Line, 3)
+	if err != nil {
+		t.Fatalf("ReadNDJSONLines failed: %v", err)
+	}
+	var got []int
+	for _, line := range lines {
+		var value map[string]int
+		json.Unmarshal(line, &value)
+		got = append(got, value["n"])
+	}
+	if len(got) != 3 || got[0] != 4 || got[2] != 6 {
+		t.Errorf("Expected lines 4 to 6, got %v", got)
+	}
+
+	if _, ok := index.Locate(7); ok {
+		t.Error("Expected no member for a line past the end")
+	}
+}
+
+func TestIndexedNDJSONWriterEmpty(t *testing.T) {
+	var blob bytes.Buffer
+	writer := NewIndexedNDJSONWriter(&blob, 10)
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	lines, err := ReadNDJSONLines(&blob, 0, 10)
+	if err != nil || len(lines) != 0 {
+		t.Errorf("Expected an empty valid stream, got %v, %v", lines, err)
+	}
+}
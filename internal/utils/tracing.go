@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// CorrelationIDHeader carries the correlation ID of the task a request is made for
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// TracingTransport adds the W3C traceparent and correlation ID headers to requests whose context
+// carries a correlation ID, so the receiving service can tie them to the task
+type TracingTransport struct {
+	Base http.RoundTripper
+}
+
+// NewTracingClient returns an HTTP client with the timeout whose requests carry the tracing headers
+func NewTracingClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: &TracingTransport{}}
+}
+
+// RoundTrip adds the tracing headers unless the request already has them
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	correlationID := models.CorrelationIDFromContext(req.Context())
+	if correlationID == "" || req.Header.Get("traceparent") != "" {
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("traceparent", models.Traceparent(correlationID))
+	req.Header.Set(CorrelationIDHeader, correlationID)
+	return base.RoundTrip(req)
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+func TestTracingTransport(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+	}))
+	defer server.Close()
+
+	client := NewTracingClient(5 * time.Second)
+	ctx := models.WithCorrelationID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	for _, requestCtx := range []context.Context{ctx, context.Background()} {
+		req, _ := http.NewRequestWithContext(requestCtx, http.MethodPost, server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if headers[0].Get(CorrelationIDHeader) != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		models.TraceIDFromTraceparent(headers[0].Get("traceparent")) != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected tracing headers for the correlated request, got %v", headers[0])
+	}
+	if headers[1].Get("traceparent") != "" || headers[1].Get(CorrelationIDHeader) != "" {
+		t.Errorf("Expected no tracing headers without a correlation ID, got %v", headers[1])
+	}
+}
@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/allsafeASM/api/internal/models"
+)
+
+// FuzzValidateDomain checks that every accepted domain is a plain host name
+func FuzzValidateDomain(f *testing.F) {
+	for _, seed := range []string{"example.com", "a-b.example.co.uk", "_dmarc.example.com", "xn--bcher-kva.example",
+		"not a domain", "example.com/../x", "exa\x00mple.com", "bücher.example", "-example.com", "example..com", strings.Repeat("a", 64) + ".com"} {
+		f.Add(seed)
+	}
+
+	v := NewValidator()
+	f.Fuzz(func(t *testing.T, domain string) {
+		if v.ValidateDomain(domain) != nil {
+			return
+		}
+		if len(domain) > 253 {
+			t.Fatalf("Accepted a domain of %d characters", len(domain))
+		}
+		for _, label := range strings.Split(domain, ".") {
+			if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+				t.Fatalf("Accepted %q with an invalid label %q", domain, label)
+			}
+		}
+		if strings.ContainsFunc(domain, func(r rune) bool {
+			return !isAlphanumeric(r) && r != '-' && r != '_' && r != '.'
+		}) {
+			t.Fatalf("Accepted %q with a character outside [A-Za-z0-9._-]", domain)
+		}
+	})
+}
+
+// FuzzTaskMessage checks that malformed queue payloads are rejected without panicking and that
+// an accepted task cannot escape its blob prefix
+func FuzzTaskMessage(f *testing.F) {
+	for _, seed := range []string{
+		`{"task": "subfinder", "scan_id": 1, "domain": "example.com"}`,
+		`{"task": "dns_resolve", "scan_id": 2, "domain": "example.com", "tenant_id": "acme", "input_blob_path": "acme/example.com-2/subfinder/out/attempt-1.json"}`,
+		`{"task": "port_scan", "scan_id": 3, "domain": "example.com", "config": {"ports": [80, "443"], "rate_limit": -1}}`,
+		`{"task": "httpx", "scan_id": 3, "domain": "https://example.com/../x"}`,
+		`{"task": "nuclei", "scan_id": 3, "domain": "example.com:8443"}`,
+		`{"task": "nuclei", "scan_id": -4, "domain": "../../etc", "tenant_id": "../x"}`,
+		`{"action": "pause", "scan_id": 5}`,
+		`{"task": 7, "scan_id": "x", "config": null}`,
+		`[]`,
+	} {
+		f.Add([]byte(seed))
+	}
+
+	v := NewValidator()
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		var taskMsg models.TaskMessage
+		if json.Unmarshal(payload, &taskMsg) != nil {
+			return
+		}
+		if taskMsg.Action != "" {
+			v.ValidateControlMessage(&taskMsg)
+			return
+		}
+		if v.ValidateTaskMessage(&taskMsg) != nil {
+			return
+		}
+
+		prefix := models.ScanBlobPrefix(taskMsg.TenantID, taskMsg.Domain, taskMsg.ScanID)
+		if strings.HasPrefix(prefix, "/") || path.Clean(prefix)+"/" != prefix {
+			t.Fatalf("Accepted task %+v has the unsafe blob prefix %q", taskMsg, prefix)
+		}
+		if taskMsg.FilePath != "" {
+			v.ValidateBlobPath(taskMsg.FilePath, prefix)
+		}
+	})
+}
+
+// FuzzValidateBlobPath checks that accepted blob paths are canonical and stay inside the scan prefix
+func FuzzValidateBlobPath(f *testing.F) {
+	for _, seed := range []string{"example.com-1/subfinder/out/a.json", "example.com-1/../x.json", "/abs.txt",
+		"example.com-1/hosts.txt.gz", "example.com-1\\x.txt", "example.com-1/./x.txt", "example.com-1/x.exe"} {
+		f.Add(seed, "example.com-1/")
+	}
+
+	v := NewValidator()
+	f.Fuzz(func(t *testing.T, blobPath, prefix string) {
+		if v.ValidateBlobPath(blobPath, prefix) != nil {
+			return
+		}
+		if path.Clean(blobPath) != blobPath || strings.HasPrefix(blobPath, "/") || strings.Contains(blobPath, "\\") {
+			t.Fatalf("Accepted the non-canonical blob path %q", blobPath)
+		}
+		for _, segment := range strings.Split(blobPath, "/") {
+			if segment == ".." {
+				t.Fatalf("Accepted the traversing blob path %q", blobPath)
+			}
+		}
+		if !strings.HasPrefix(blobPath, prefix) {
+			t.Fatalf("Accepted %q outside of %q", blobPath, prefix)
+		}
+	})
+}
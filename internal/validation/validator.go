@@ -1,14 +1,27 @@
 package validation
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/allsafeASM/api/internal/common"
 	"github.com/allsafeASM/api/internal/models"
 )
 
+// allowedBlobExtensions lists the file extensions accepted for input blobs
+var allowedBlobExtensions = map[string]bool{
+	".txt":  true,
+	".json": true,
+}
+
 // Validator provides all validation functionality
 type Validator struct{}
 
@@ -17,7 +30,9 @@ func NewValidator() *Validator {
 	return &Validator{}
 }
 
-// ValidateDomain performs basic domain format validation
+// ValidateDomain checks that a domain is an ASCII host name: dot separated labels of letters,
+// digits, hyphens and underscores, each at most 63 characters. Anything else could end up in
+// blob paths, scanner arguments or notifications, so it is rejected rather than cleaned up.
 func (v *Validator) ValidateDomain(domain string) error {
 	if domain == "" {
 		return fmt.Errorf("domain is required")
@@ -37,10 +52,149 @@ func (v *Validator) ValidateDomain(domain string) error {
 	}
 
 	// Must start and end with alphanumeric
-	if len(domain) == 0 || !isAlphanumeric(rune(domain[0])) || !isAlphanumeric(rune(domain[len(domain)-1])) {
+	if !isAlphanumeric(rune(domain[0])) || !isAlphanumeric(rune(domain[len(domain)-1])) {
 		return fmt.Errorf("domain must start and end with alphanumeric character: %s", domain)
 	}
 
+	for _, label := range strings.Split(domain, ".") {
+		if len(label) > 63 {
+			return fmt.Errorf("domain label longer than 63 characters: %s", domain)
+		}
+	}
+	for _, char := range domain {
+		if !isAlphanumeric(char) && char != '-' && char != '_' && char != '.' {
+			return fmt.Errorf("domain contains invalid character %q, internationalized names must use punycode: %s", char, domain)
+		}
+	}
+
+	return nil
+}
+
+// TargetType identifies the form of a scan target
+type TargetType string
+
+const (
+	TargetTypeDomain   TargetType = "domain"
+	TargetTypeIP       TargetType = "ip"
+	TargetTypeURL      TargetType = "url"
+	TargetTypeHostPort TargetType = "host_port"
+)
+
+// DetectTargetType determines whether a target is a URL, an IP, a host:port pair or a domain
+func DetectTargetType(target string) TargetType {
+	target = strings.TrimSpace(target)
+
+	if strings.Contains(target, "://") {
+		return TargetTypeURL
+	}
+	if net.ParseIP(target) != nil {
+		return TargetTypeIP
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return TargetTypeHostPort
+	}
+	return TargetTypeDomain
+}
+
+// ValidateTarget validates a target using the validation path for its detected type
+func (v *Validator) ValidateTarget(target string) error {
+	switch DetectTargetType(target) {
+	case TargetTypeURL:
+		return v.ValidateURL(target)
+	case TargetTypeHostPort:
+		return v.ValidateHostPort(target)
+	case TargetTypeIP:
+		return nil
+	default:
+		return v.ValidateDomain(target)
+	}
+}
+
+// ValidateURL validates an http(s) URL target such as httpx input or a nuclei target
+func (v *Validator) ValidateURL(rawURL string) error {
+	if rawURL == "" {
+		return common.NewValidationError("url", "url is required")
+	}
+
+	if len(rawURL) > 2048 {
+		return common.NewValidationError("url", "url cannot exceed 2048 characters")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return common.NewValidationError("url", fmt.Sprintf("invalid url: %v", err))
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return common.NewValidationError("url", fmt.Sprintf("unsupported url scheme %q: must be http or https", parsed.Scheme))
+	}
+
+	if parsed.User != nil {
+		return common.NewValidationError("url", "url must not contain credentials")
+	}
+
+	if parsed.Hostname() == "" {
+		return common.NewValidationError("url", fmt.Sprintf("url has no host: %s", rawURL))
+	}
+
+	if err := v.validateHost(parsed.Hostname()); err != nil {
+		return err
+	}
+
+	if port := parsed.Port(); port != "" {
+		if err := validatePort(port); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateHostPort validates a host:port target, where host is a domain or an IP ("[::1]:443" for IPv6)
+func (v *Validator) ValidateHostPort(hostPort string) error {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return common.NewValidationError("host_port", fmt.Sprintf("invalid host:port %q: %v", hostPort, err))
+	}
+
+	if err := v.validateHost(host); err != nil {
+		return err
+	}
+
+	return validatePort(port)
+}
+
+// validateHost validates the host part of a URL or host:port target
+func (v *Validator) validateHost(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+	return v.ValidateDomain(host)
+}
+
+// validatePort validates a numeric port string
+func validatePort(port string) error {
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 1 || portNum > 65535 {
+		return common.NewValidationError("port", fmt.Sprintf("port must be between 1 and 65535, got: %s", port))
+	}
+	return nil
+}
+
+// validatePortRange checks a port range such as "1-1000" or "22,80-90,443": every entry is a
+// port or an ascending start-end pair of ports
+func validatePortRange(portRange string) error {
+	for _, entry := range strings.Split(portRange, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(entry), "-")
+		if !isRange {
+			end = start
+		}
+		startPort, startErr := strconv.Atoi(strings.TrimSpace(start))
+		endPort, endErr := strconv.Atoi(strings.TrimSpace(end))
+		if startErr != nil || endErr != nil || startPort < 1 || endPort > 65535 || startPort > endPort {
+			return common.NewValidationError("port_range", fmt.Sprintf("port range must be ports or 'start-end' ranges between 1 and 65535 (e.g., '1-1000'), got: %q", entry))
+		}
+	}
 	return nil
 }
 
@@ -50,7 +204,12 @@ func (v *Validator) ValidateTaskMessage(taskMsg *models.TaskMessage) error {
 		return fmt.Errorf("domain is required for task processing")
 	}
 
-	if err := v.ValidateDomain(taskMsg.Domain); err != nil {
+	// httpx and nuclei probe URLs and host:port targets as well, other tasks work on host names
+	validateDomain := v.ValidateDomain
+	if taskMsg.Task == models.TaskHttpx || taskMsg.Task == models.TaskNuclei {
+		validateDomain = v.ValidateTarget
+	}
+	if err := validateDomain(taskMsg.Domain); err != nil {
 		return err
 	}
 
@@ -62,16 +221,233 @@ func (v *Validator) ValidateTaskMessage(taskMsg *models.TaskMessage) error {
 		return fmt.Errorf("task type is required")
 	}
 
+	if taskMsg.TenantID != "" {
+		if err := v.ValidateTenantID(taskMsg.TenantID); err != nil {
+			return err
+		}
+	}
+
 	// Validate task type
 	if !v.isValidTaskType(models.Task(taskMsg.Task)) {
 		return fmt.Errorf("invalid task type: %s", taskMsg.Task)
 	}
 
+	// The config is decoded like the handler will, so unknown keys and bad values fail up front
+	if _, err := models.ParseTaskConfig(taskMsg.Task, taskMsg.Config); err != nil {
+		return common.NewValidationError("config", err.Error())
+	}
+
+	// Profiles may be defined per tenant in storage, so only the worker knows whether the name exists
+	if taskMsg.Profile != "" && !models.ValidScanProfileName(taskMsg.Profile) {
+		return common.NewValidationError("profile", fmt.Sprintf("invalid scan profile name %q", taskMsg.Profile))
+	}
+
+	for _, capability := range taskMsg.Requires {
+		if err := v.ValidateCapability(capability); err != nil {
+			return common.NewValidationError("requires", err.Error())
+		}
+	}
+
+	return v.ValidateTaskMode(taskMsg)
+}
+
+// ValidateTaskMode checks the task's mode. A verify task must be of a type that takes an earlier
+// stage's result, and needs that result and a baseline result of an earlier scan of the domain.
+func (v *Validator) ValidateTaskMode(taskMsg *models.TaskMessage) error {
+	switch taskMsg.Mode {
+	case "":
+		if taskMsg.BaselineResultPath != "" {
+			return common.NewValidationError("baseline_result_path", "baseline_result_path requires the verify mode")
+		}
+		return nil
+	case models.TaskModeVerify:
+	default:
+		return common.NewValidationError("mode", fmt.Sprintf("invalid mode: %s", taskMsg.Mode))
+	}
+
+	if !slices.Contains(models.VerifyTasks, taskMsg.Task) {
+		return common.NewValidationError("mode", fmt.Sprintf("%s tasks cannot run in verify mode", taskMsg.Task))
+	}
+	if taskMsg.InputResultPath == "" || taskMsg.BaselineResultPath == "" {
+		return common.NewValidationError("mode", "verify tasks need input_result_path and baseline_result_path")
+	}
+
+	// The baseline is a result of any scan of the same domain and tenant, "[<tenant_id>/]<domain>-<scan_id>/..."
+	domainPrefix := models.DomainScansPrefix(taskMsg.TenantID, taskMsg.Domain)
+	if err := v.ValidateBlobPath(taskMsg.BaselineResultPath, domainPrefix); err != nil {
+		var appErr *common.AppError
+		if errors.As(err, &appErr) {
+			return common.NewValidationError("baseline_result_path", appErr.Message)
+		}
+		return err
+	}
+	scanDir, _, _ := strings.Cut(strings.TrimPrefix(taskMsg.BaselineResultPath, domainPrefix), "/")
+	if _, err := strconv.Atoi(scanDir); err != nil {
+		return common.NewValidationError("baseline_result_path", fmt.Sprintf("%s is not a result of a scan of %s", taskMsg.BaselineResultPath, taskMsg.Domain))
+	}
+	return nil
+}
+
+// ValidateCapability checks that a worker capability label is 1 to 32 lowercase letters, digits
+// and underscores, so it can be part of a message property name
+func (v *Validator) ValidateCapability(capability string) error {
+	if capability == "" || len(capability) > 32 {
+		return fmt.Errorf("capability %q must be 1 to 32 characters", capability)
+	}
+	for _, r := range capability {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return fmt.Errorf("capability %q may only contain lowercase letters, digits and underscores", capability)
+		}
+	}
+	return nil
+}
+
+// ParseCapabilities parses capability labels separated by ','
+func (v *Validator) ParseCapabilities(spec string) ([]string, error) {
+	var capabilities []string
+	for _, entry := range strings.Split(spec, ",") {
+		capability := strings.TrimSpace(entry)
+		if capability == "" {
+			continue
+		}
+		if err := v.ValidateCapability(capability); err != nil {
+			return nil, err
+		}
+		capabilities = append(capabilities, capability)
+	}
+	return capabilities, nil
+}
+
+// ValidateControlMessage validates a control message. Pause and resume messages only need a scan
+// to act on, compact messages a domain.
+func (v *Validator) ValidateControlMessage(taskMsg *models.TaskMessage) error {
+	switch taskMsg.Action {
+	case models.TaskActionPause, models.TaskActionResume:
+		if taskMsg.ScanID == 0 {
+			return fmt.Errorf("scan_id is required")
+		}
+	case models.TaskActionCompact:
+		if err := v.ValidateDomain(taskMsg.Domain); err != nil {
+			return err
+		}
+		if taskMsg.OlderThanDays < 0 {
+			return common.NewValidationError("older_than_days", "older_than_days cannot be negative")
+		}
+	default:
+		return common.NewValidationError("action", fmt.Sprintf("invalid action: %s", taskMsg.Action))
+	}
+
+	if taskMsg.TenantID != "" {
+		if err := v.ValidateTenantID(taskMsg.TenantID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateTenantID validates that a tenant ID is safe to use as a blob path segment
+func (v *Validator) ValidateTenantID(tenantID string) error {
+	if len(tenantID) > 63 {
+		return common.NewValidationError("tenant_id", "tenant_id cannot exceed 63 characters")
+	}
+	for _, char := range tenantID {
+		if !isAlphanumeric(char) && char != '-' && char != '_' {
+			return common.NewValidationError("tenant_id", fmt.Sprintf("invalid tenant_id: contains invalid character '%c'", char))
+		}
+	}
+	return nil
+}
+
+// ValidateBlobPath validates an input blob path received from the queue.
+// The path must be relative to the container, free of traversal sequences,
+// located under scanPrefix and use one of the allowed file extensions.
+func (v *Validator) ValidateBlobPath(blobPath, scanPrefix string) error {
+	if strings.TrimSpace(blobPath) == "" {
+		return common.NewValidationError("input_blob_path", "blob path cannot be empty")
+	}
+
+	if len(blobPath) > 1024 {
+		return common.NewValidationError("input_blob_path", "blob path cannot exceed 1024 characters")
+	}
+
+	for _, char := range blobPath {
+		if char < 0x20 || char == 0x7f {
+			return common.NewValidationError("input_blob_path", "invalid blob path: contains control characters")
+		}
+	}
+
+	if strings.Contains(blobPath, "\\") || strings.Contains(blobPath, "://") || strings.HasPrefix(blobPath, "/") {
+		return common.NewValidationError("input_blob_path", fmt.Sprintf("invalid blob path: must be a relative path inside the container: %s", blobPath))
+	}
+
+	for _, segment := range strings.Split(blobPath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return common.NewValidationError("input_blob_path", fmt.Sprintf("invalid blob path: contains empty or traversal segment: %s", blobPath))
+		}
+	}
+
+	// Clean must be a no-op for an already canonical path
+	if path.Clean(blobPath) != blobPath {
+		return common.NewValidationError("input_blob_path", fmt.Sprintf("invalid blob path: path is not canonical: %s", blobPath))
+	}
+
+	if scanPrefix != "" && !strings.HasPrefix(blobPath, scanPrefix) {
+		return common.NewValidationError("input_blob_path", fmt.Sprintf("invalid blob path: %s is outside of the scan prefix %s", blobPath, scanPrefix))
+	}
+
+	// Compressed inputs keep the extension of their content, e.g. hosts.txt.gz
+	contentPath := blobPath
+	if strings.EqualFold(path.Ext(contentPath), ".gz") {
+		contentPath = strings.TrimSuffix(contentPath, path.Ext(contentPath))
+	}
+	if !allowedBlobExtensions[strings.ToLower(path.Ext(contentPath))] {
+		return common.NewValidationError("input_blob_path", fmt.Sprintf("invalid blob path: unsupported file extension %q", path.Ext(blobPath)))
+	}
+
+	return nil
+}
+
+// ValidateExclusions validates the inline entries of a task's exclusions and the blob they may be
+// read from, which must belong to the task's tenant
+func (v *Validator) ValidateExclusions(exclusions models.TargetExclusions, tenantID string) error {
+	if _, err := models.NewExcluder(exclusions.Targets); err != nil {
+		return common.NewValidationError("exclude", err.Error())
+	}
+	if exclusions.BlobPath == "" {
+		return nil
+	}
+
+	tenantPrefix := ""
+	if tenantID != "" {
+		tenantPrefix = tenantID + "/"
+	}
+	if err := v.ValidateBlobPath(exclusions.BlobPath, tenantPrefix); err != nil {
+		var appErr *common.AppError
+		if errors.As(err, &appErr) {
+			return common.NewValidationError("exclude_blob_path", appErr.Message)
+		}
+		return err
+	}
 	return nil
 }
 
 // ValidateScannerInput validates any scanner input
 func (v *Validator) ValidateScannerInput(input models.ScannerInput) error {
+	// httpx and nuclei accept URLs and host:port pairs as well as domains
+	switch in := input.(type) {
+	case models.HttpxInput:
+		return v.ValidateHttpxInput(in)
+	case models.NucleiInput:
+		return v.ValidateNucleiInput(in)
+	case models.CloudDNSInput:
+		return v.ValidateCloudDNSInput(in)
+	case models.SubfinderInput:
+		if _, err := in.Filter.Compile(); err != nil {
+			return common.NewValidationError("filter", err.Error())
+		}
+	}
+
 	if input.GetDomain() == "" {
 		return fmt.Errorf("domain is required for %s scanner", input.GetScannerName())
 	}
@@ -88,9 +464,67 @@ func (v *Validator) ValidateSubfinderInput(input models.SubfinderInput) error {
 	return v.ValidateScannerInput(input)
 }
 
-// ValidateHttpxInput validates httpx input
+// ValidateHttpxInput validates httpx input, which may target a URL or host:port pair
 func (v *Validator) ValidateHttpxInput(input models.HttpxInput) error {
-	return v.ValidateScannerInput(input)
+	if input.TLSFingerprint != "" && !models.IsValidTLSFingerprint(input.TLSFingerprint) {
+		return fmt.Errorf("invalid TLS fingerprint %q: must be %s, %s or %s", input.TLSFingerprint,
+			models.TLSFingerprintGo, models.TLSFingerprintRandom, models.TLSFingerprintZTLS)
+	}
+	return v.validateTargetInput(input)
+}
+
+// ValidateNucleiInput validates nuclei input, which may target a URL or host:port pair
+func (v *Validator) ValidateNucleiInput(input models.NucleiInput) error {
+	if input.CustomTemplates != "" {
+		if err := validateTemplatesName(input.CustomTemplates); err != nil {
+			return err
+		}
+	}
+	if input.InteractshServer != "" {
+		if err := validateInteractshServer(input.InteractshServer); err != nil {
+			return err
+		}
+	}
+	return v.validateTargetInput(input)
+}
+
+// validateInteractshServer checks that an interactsh server is a host name or an http(s) URL
+func validateInteractshServer(server string) error {
+	value := server
+	if !strings.Contains(value, "://") {
+		value = "https://" + value
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return common.NewValidationError("interactsh_server", fmt.Sprintf("interactsh server must be a host name or an http(s) URL, got: %s", server))
+	}
+	return nil
+}
+
+// validateTemplatesName checks that a custom templates directory name is a single path segment
+func validateTemplatesName(name string) error {
+	if len(name) > 64 || name == "." || name == ".." {
+		return common.NewValidationError("custom_templates", fmt.Sprintf("invalid custom templates name: %s", name))
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-') {
+			return common.NewValidationError("custom_templates", fmt.Sprintf("invalid custom templates name %q: only letters, digits, '.', '_' and '-' are allowed", name))
+		}
+	}
+	return nil
+}
+
+// validateTargetInput validates scanner input whose domain may be any supported target type
+func (v *Validator) validateTargetInput(input models.ScannerInput) error {
+	if input.GetDomain() == "" {
+		return fmt.Errorf("target is required for %s scanner", input.GetScannerName())
+	}
+
+	if err := v.ValidateTarget(input.GetDomain()); err != nil {
+		return fmt.Errorf("invalid %s target for %s: %w", DetectTargetType(input.GetDomain()), input.GetScannerName(), err)
+	}
+
+	return nil
 }
 
 // ValidateDNSXInput validates dnsx input
@@ -122,6 +556,114 @@ func (v *Validator) ValidateDNSXInput(input models.DNSXInput) error {
 	return nil
 }
 
+// ValidateCloudDNSInput validates the domain and zone sources of a cloud DNS ingestion. The
+// subscription, resource group and project end up in provider API paths, so only the characters
+// the providers allow in them are accepted.
+func (v *Validator) ValidateCloudDNSInput(input models.CloudDNSInput) error {
+	if err := v.ValidateDomain(input.Domain); err != nil {
+		return fmt.Errorf("invalid domain format for cloud_dns: %w", err)
+	}
+	if len(input.Sources) == 0 {
+		return common.NewValidationError("sources", "at least one zone source is required")
+	}
+
+	for i, source := range input.Sources {
+		switch source.Provider {
+		case models.CloudDNSProviderRoute53:
+		case models.CloudDNSProviderAzure:
+			if !azureSubscriptionPattern.MatchString(source.SubscriptionID) {
+				return common.NewValidationError("sources", fmt.Sprintf("source %d needs the subscription_id of the zones as a GUID", i))
+			}
+			if !azureResourceGroupPattern.MatchString(source.ResourceGroup) || strings.HasSuffix(source.ResourceGroup, ".") {
+				return common.NewValidationError("sources", fmt.Sprintf("source %d needs a valid resource_group", i))
+			}
+		case models.CloudDNSProviderGoogle:
+			if !googleProjectPattern.MatchString(source.Project) {
+				return common.NewValidationError("sources", fmt.Sprintf("source %d needs a valid Google Cloud project ID", i))
+			}
+		default:
+			return common.NewValidationError("sources", fmt.Sprintf("unknown cloud DNS provider %q", source.Provider))
+		}
+	}
+	return nil
+}
+
+var (
+	azureSubscriptionPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	azureResourceGroupPattern = regexp.MustCompile(`^[-\w.()]{1,90}$`)
+	googleProjectPattern      = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+)
+
+const (
+	// maxDNSLookupTime bounds the worst-case time a single name can take across all questions and retries
+	maxDNSLookupTime = 60 * time.Second
+
+	// MinDNSTimeoutMs and MaxDNSTimeoutMs bound the per-attempt DNS timeout
+	MinDNSTimeoutMs = 100
+	MaxDNSTimeoutMs = 30000
+
+	// dnsRetryTimeoutFactor scales the per-attempt timeout of the retry pass
+	dnsRetryTimeoutFactor = 2
+)
+
+// DNSRetryTimeoutMs returns the per-attempt timeout of the retry pass for a first pass timeout
+func DNSRetryTimeoutMs(timeoutMs int) int {
+	return min(timeoutMs*dnsRetryTimeoutFactor, MaxDNSTimeoutMs)
+}
+
+// ValidateDNSSettings validates effective DNS settings, including combinations of retries, timeout and question types
+func (v *Validator) ValidateDNSSettings(settings models.DNSSettings) error {
+	if settings.Retries < 1 || settings.Retries > 10 {
+		return common.NewValidationError("retries", fmt.Sprintf("retries must be between 1 and 10, got: %d", settings.Retries))
+	}
+
+	if settings.TimeoutMs < MinDNSTimeoutMs || settings.TimeoutMs > MaxDNSTimeoutMs {
+		return common.NewValidationError("timeout_ms", fmt.Sprintf("timeout_ms must be between %d and %d, got: %d", MinDNSTimeoutMs, MaxDNSTimeoutMs, settings.TimeoutMs))
+	}
+
+	if len(settings.QuestionTypes) == 0 {
+		return common.NewValidationError("question_types", "at least one question type is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, questionType := range settings.QuestionTypes {
+		name := strings.ToUpper(strings.TrimSpace(questionType))
+		if _, ok := models.DNSQuestionTypes[name]; !ok {
+			return common.NewValidationError("question_types", fmt.Sprintf("unsupported question type: %s", questionType))
+		}
+		if seen[name] {
+			return common.NewValidationError("question_types", fmt.Sprintf("duplicate question type: %s", questionType))
+		}
+		seen[name] = true
+	}
+
+	// PTR questions take IPs while every other type takes names, so they cannot share a run
+	if seen["PTR"] && len(seen) > 1 {
+		return common.NewValidationError("question_types", "PTR cannot be combined with other question types")
+	}
+
+	// The hosts file only provides addresses
+	if settings.UsesHostsfile() && !settings.QueriesAddresses() {
+		return common.NewValidationError("hostsfile", "hostsfile requires an A or AAAA question type")
+	}
+
+	worstCase := time.Duration(settings.Retries*settings.TimeoutMs*len(settings.QuestionTypes)) * time.Millisecond
+	if worstCase > maxDNSLookupTime {
+		return common.NewValidationError("timeout_ms", fmt.Sprintf("retries x timeout x question types allows %s per name, exceeding %s", worstCase, maxDNSLookupTime))
+	}
+
+	// The retry pass raises the timeout, so its worst case has to fit as well
+	if settings.UsesRetryPass() {
+		retryTimeoutMs := DNSRetryTimeoutMs(settings.TimeoutMs)
+		retryWorstCase := time.Duration(settings.Retries*retryTimeoutMs*len(settings.QuestionTypes)) * time.Millisecond
+		if retryWorstCase > maxDNSLookupTime {
+			return common.NewValidationError("retry_pass", fmt.Sprintf("the retry pass timeout of %dms allows %s per name, exceeding %s; lower timeout_ms or turn off retry_pass", retryTimeoutMs, retryWorstCase, maxDNSLookupTime))
+		}
+	}
+
+	return nil
+}
+
 // ValidateNaabuInput validates naabu input
 func (v *Validator) ValidateNaabuInput(input models.NaabuInput) error {
 	// Validate domain
@@ -159,9 +701,9 @@ func (v *Validator) ValidateNaabuInput(input models.NaabuInput) error {
 		if strings.TrimSpace(input.PortRange) == "" {
 			return common.NewValidationError("port_range", "port range cannot be empty")
 		}
-		// Basic validation for port range format (e.g., "1-1000")
-		if !strings.Contains(input.PortRange, "-") {
-			return common.NewValidationError("port_range", "port range must be in format 'start-end' (e.g., '1-1000')")
+		// A range naabu cannot parse must fail the task rather than scan some other set of ports
+		if err := validatePortRange(input.PortRange); err != nil {
+			return err
 		}
 	}
 
@@ -194,6 +736,37 @@ func (v *Validator) ValidateNaabuInput(input models.NaabuInput) error {
 		}
 	}
 
+	// Validate host discovery probes if provided
+	if len(input.DiscoveryProbes) > 0 && !input.HostDiscovery && !input.PreFilter {
+		return common.NewValidationError("discovery_probes", "discovery probes require host_discovery or pre_filter")
+	}
+	for i, probe := range input.DiscoveryProbes {
+		if err := validateDiscoveryProbe(probe); err != nil {
+			return common.NewValidationError(fmt.Sprintf("discovery_probes[%d]", i), err.Error())
+		}
+	}
+
+	// Validate the network the probes leave from
+	if input.SourcePort < 0 || input.SourcePort > 65535 {
+		return common.NewValidationError("source_port", fmt.Sprintf("source port must be between 1 and 65535, got: %d", input.SourcePort))
+	}
+	if input.SourceIP != "" && net.ParseIP(input.SourceIP) == nil {
+		return common.NewValidationError("source_ip", fmt.Sprintf("invalid source IP address: %s", input.SourceIP))
+	}
+	if input.Interface != "" && !interfaceNamePattern.MatchString(input.Interface) {
+		return common.NewValidationError("interface", fmt.Sprintf("invalid network interface name: %s", input.Interface))
+	}
+
+	// Validate the ports allowed on CDN and WAF IPs
+	if input.ScanCDN && len(input.CDNPorts) > 0 {
+		return common.NewValidationError("cdn_ports", "cdn_ports cannot be combined with scan_cdn")
+	}
+	for i, port := range input.CDNPorts {
+		if port < 1 || port > 65535 {
+			return common.NewValidationError(fmt.Sprintf("cdn_ports[%d]", i), fmt.Sprintf("port must be between 1 and 65535, got: %d", port))
+		}
+	}
+
 	// Ensure at least one source of IPs is provided
 	if len(input.IPs) == 0 && input.HostsFileLocation == "" {
 		return common.NewValidationError("ips", "either IPs or hosts file location must be provided")
@@ -202,6 +775,42 @@ func (v *Validator) ValidateNaabuInput(input models.NaabuInput) error {
 	return nil
 }
 
+// interfaceNamePattern matches network interface names, which Linux limits to 15 characters
+var interfaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,14}$`)
+
+// discoveryProbes lists the naabu host discovery probes; the TCP probes take a port list
+var discoveryProbes = map[string]bool{
+	"icmp-echo":         false,
+	"icmp-timestamp":    false,
+	"icmp-address-mask": false,
+	"arp":               false,
+	"nd":                false,
+	"tcp-syn":           true,
+	"tcp-ack":           true,
+}
+
+// validateDiscoveryProbe validates a probe in the form "name" or "tcp-syn:80,443"
+func validateDiscoveryProbe(probe string) error {
+	name, ports, hasPorts := strings.Cut(strings.ToLower(strings.TrimSpace(probe)), ":")
+	takesPorts, ok := discoveryProbes[name]
+	if !ok {
+		return fmt.Errorf("unsupported discovery probe: %s", probe)
+	}
+	if hasPorts && !takesPorts {
+		return fmt.Errorf("discovery probe %s does not take ports", name)
+	}
+	if !hasPorts {
+		return nil
+	}
+
+	for _, port := range strings.Split(ports, ",") {
+		if validatePort(strings.TrimSpace(port)) != nil {
+			return fmt.Errorf("invalid port %q in discovery probe %s", port, probe)
+		}
+	}
+	return nil
+}
+
 // isValidIP performs basic IP validation
 func (v *Validator) isValidIP(ip string) bool {
 	// Basic validation - you might want to use net.ParseIP for more robust validation
@@ -219,16 +828,25 @@ func (v *Validator) isValidIP(ip string) bool {
 	return true
 }
 
+// ParseTaskTypes parses task types separated by ',', rejecting unknown ones
+func (v *Validator) ParseTaskTypes(spec string) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, entry := range strings.Split(spec, ",") {
+		task := models.Task(strings.ToLower(strings.TrimSpace(entry)))
+		if task == "" {
+			continue
+		}
+		if !v.isValidTaskType(task) {
+			return nil, fmt.Errorf("invalid task type: %s", task)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
 // isValidTaskType checks if the task type is supported
 func (v *Validator) isValidTaskType(taskType models.Task) bool {
-	validTasks := map[models.Task]bool{
-		models.TaskSubfinder:  true,
-		models.TaskHttpx:      true,
-		models.TaskDNSResolve: true,
-		models.TaskNaabu:      true,
-		models.TaskNuclei:     true,
-	}
-	return validTasks[taskType]
+	return slices.Contains(models.KnownTasks, taskType)
 }
 
 // isAlphanumeric checks if a character is alphanumeric
@@ -2,6 +2,7 @@ package main
 
 import (
 	"github.com/allsafeASM/api/internal/app"
+	"github.com/allsafeASM/api/internal/buildinfo"
 	"github.com/allsafeASM/api/internal/config"
 	"github.com/projectdiscovery/gologger"
 )
@@ -14,7 +15,7 @@ func main() {
 	}
 
 	logConfiguration(cfg)
-	gologger.Info().Msg("Starting AllSafe ASM Worker")
+	gologger.Info().Msgf("Starting AllSafe ASM Worker %s", buildinfo.Get())
 
 	// Create and initialize application
 	application, err := app.NewApplication()
@@ -34,7 +35,14 @@ func main() {
 
 func logConfiguration(cfg *config.Config) {
 	gologger.Info().Msg("Configuration:")
-	gologger.Info().Msgf("  Service Bus: %s/%s", cfg.Azure.ServiceBusNamespace, cfg.Azure.QueueName)
+	if scanners := buildinfo.Get().Scanners; len(scanners) > 0 {
+		gologger.Info().Msgf("  Scanner Libraries: %v", scanners)
+	}
+	if cfg.Azure.DevMode {
+		gologger.Info().Msgf("  Dev Queue: %s", cfg.Azure.DevQueueDir)
+	} else {
+		gologger.Info().Msgf("  Service Bus: %s/%s", cfg.Azure.ServiceBusNamespace, cfg.Azure.QueueName)
+	}
 	gologger.Info().Msgf("  Blob Storage: %s", cfg.Azure.BlobContainerName)
 	gologger.Info().Msgf("  Scanner Timeout: %ds", cfg.App.ScannerTimeout)
 	gologger.Info().Msgf("  Poll Interval: %ds", cfg.App.PollInterval)